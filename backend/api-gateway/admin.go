@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+)
+
+// adminBackend maps an /admin/{name} prefix to the service handling it
+type adminBackend struct {
+	name string
+	url  string
+}
+
+// adminBackends lists the services reachable through the admin API. A request
+// to /admin/{name}/rest is forwarded to that service as /rest, e.g.
+//
+//	/admin/safety/api/v1/documents/...  -> safety-service /api/v1/documents/...  (verification review)
+//	/admin/matching/exclusions          -> matching-service /exclusions
+//	/admin/pricing/config/preview       -> pricing-service /config/preview        (rate config)
+//	/admin/rides/rides/{id}/complete    -> ride-service /rides/{id}/complete      (force-complete)
+//	/admin/users/users/{id}             -> user-service /users/{id}
+func (gw *APIGateway) adminBackends() []adminBackend {
+	return []adminBackend{
+		{"users", gw.config.UserServiceURL},
+		{"rides", gw.config.RideServiceURL},
+		{"pricing", gw.config.PricingServiceURL},
+		{"matching", gw.config.MatchingServiceURL},
+		{"safety", gw.config.SafetyServiceURL},
+	}
+}
+
+// setupAdminRoutes registers the /admin API. Every /admin path, including
+// unknown ones, goes through authenticate and requireRole(RoleAdmin), so the
+// admin role check lives in one place and non-admins always get 403.
+func (gw *APIGateway) setupAdminRoutes() {
+	admin := gw.router.PathPrefix("/admin").Subrouter()
+	admin.Use(gw.authenticate, gw.requireRole(RoleAdmin))
+
+	for _, backend := range gw.adminBackends() {
+		prefix := "/admin/" + backend.name
+		proxy := http.StripPrefix(prefix, gw.newProxy(backend.name, backend.url))
+		admin.PathPrefix("/" + backend.name).Handler(gw.forwardIdentity(proxy))
+	}
+
+	admin.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gw.writeError(w, "Unknown admin endpoint", http.StatusNotFound)
+	})
+}
+
+// forwardIdentity passes the authenticated user to the backend in X-User-ID
+// and X-User-Role, replacing any client-supplied values.
+func (gw *APIGateway) forwardIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("X-User-ID")
+		r.Header.Del("X-User-Role")
+		if claims, ok := claimsFromContext(r.Context()); ok {
+			r.Header.Set("X-User-ID", claims.Subject)
+			r.Header.Set("X-User-Role", claims.Role)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
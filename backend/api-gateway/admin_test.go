@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newAdminTestGateway(t *testing.T, backendURL string) *APIGateway {
+	t.Helper()
+	gw := NewAPIGateway(ServiceConfig{
+		AuthServiceURL:     backendURL,
+		UserServiceURL:     backendURL,
+		RideServiceURL:     backendURL,
+		PricingServiceURL:  backendURL,
+		MatchingServiceURL: backendURL,
+		SafetyServiceURL:   backendURL,
+		JWTSecret:          testJWTSecret,
+	})
+	gw.setupRoutes()
+	return gw
+}
+
+func adminRequest(t *testing.T, gw *APIGateway, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminRoutesRequireAdminRole(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("backend reached by unauthorised request: %s", r.URL.Path)
+	}))
+	defer backend.Close()
+	gw := newAdminTestGateway(t, backend.URL)
+
+	if rec := adminRequest(t, gw, "/admin/rides/rides/1", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", rec.Code)
+	}
+
+	riderToken := signTestToken(t, testJWTSecret, validClaims("rider"))
+	for _, path := range []string{"/admin/rides/rides/1", "/admin/pricing/config/preview", "/admin/unknown"} {
+		if rec := adminRequest(t, gw, path, riderToken); rec.Code != http.StatusForbidden {
+			t.Errorf("rider on %s: status = %d, want 403", path, rec.Code)
+		}
+	}
+}
+
+func TestAdminRoutesProxyToService(t *testing.T) {
+	var gotPath, gotUser, gotRole string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotUser, gotRole = r.URL.Path, r.Header.Get("X-User-ID"), r.Header.Get("X-User-Role")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gw := newAdminTestGateway(t, backend.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/matching/exclusions?rider_id=r1", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, testJWTSecret, validClaims(RoleAdmin)))
+	req.Header.Set("X-User-Role", "spoofed")
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if gotPath != "/exclusions" {
+		t.Errorf("backend path = %q, want /exclusions", gotPath)
+	}
+	if gotUser != "user-1" || gotRole != RoleAdmin {
+		t.Errorf("identity headers = %q/%q, want user-1/admin", gotUser, gotRole)
+	}
+
+	rec = adminRequest(t, gw, "/admin/unknown", signTestToken(t, testJWTSecret, validClaims(RoleAdmin)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown admin endpoint: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// RoleAdmin is the role claim required for the /admin API
+const RoleAdmin = "admin"
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid token")
+	errTokenExpired = errors.New("token expired")
+)
+
+// Claims are the JWT claims the gateway relies on. Tokens are issued by the
+// auth-service and signed with HS256 using JWT_SECRET.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type claimsKey struct{}
+
+// claimsFromContext returns the claims stored by authenticate
+func claimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// parseJWT verifies an HS256 token and returns its claims. Tokens without an
+// exp claim are rejected.
+func parseJWT(token string, secret []byte, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil || h.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return nil, errInvalidToken
+	}
+	if claims.ExpiresAt == 0 || !now.Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, errTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer ..." header
+func bearerToken(r *http.Request) (string, error) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) || strings.TrimSpace(auth[len(prefix):]) == "" {
+		return "", errMissingToken
+	}
+	return strings.TrimSpace(auth[len(prefix):]), nil
+}
+
+// authenticate validates the bearer token and stores its claims in the
+// request context. Missing or invalid tokens are rejected with 401.
+func (gw *APIGateway) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := bearerToken(r)
+		if err != nil {
+			gw.writeError(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := parseJWT(token, []byte(gw.config.JWTSecret), timeutil.Now())
+		if err != nil {
+			gw.logger.Printf("[AUTH] rejected token for %s %s: %v", r.Method, r.URL.Path, err)
+			gw.writeError(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+	})
+}
+
+// protect requires a valid bearer token and passes the authenticated user to
+// the backend in X-User-ID and X-User-Role.
+func (gw *APIGateway) protect(next http.Handler) http.Handler {
+	return gw.authenticate(gw.forwardIdentity(next))
+}
+
+// requireRole rejects authenticated requests whose role claim is not role
+// with 403. It must run after authenticate.
+func (gw *APIGateway) requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := claimsFromContext(r.Context())
+			if !ok || claims.Role != role {
+				subject := ""
+				if ok {
+					subject = claims.Subject
+				}
+				gw.logger.Printf("[AUTH] forbidden: user %q requires role %q for %s %s", subject, role, r.Method, r.URL.Path)
+				gw.writeError(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
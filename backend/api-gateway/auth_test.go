@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "test-jwt-secret"
+
+// signTestToken builds an HS256 token the way the auth-service does.
+func signTestToken(t *testing.T, secret string, claims Claims) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %v", err)
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validClaims(role string) Claims {
+	return Claims{Subject: "user-1", Role: role, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+}
+
+func TestParseJWT(t *testing.T) {
+	now := time.Now()
+
+	claims, err := parseJWT(signTestToken(t, testJWTSecret, validClaims(RoleAdmin)), []byte(testJWTSecret), now)
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Role != RoleAdmin {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	expired := validClaims(RoleAdmin)
+	expired.ExpiresAt = now.Add(-time.Minute).Unix()
+	if _, err := parseJWT(signTestToken(t, testJWTSecret, expired), []byte(testJWTSecret), now); err != errTokenExpired {
+		t.Errorf("expired token: err = %v, want errTokenExpired", err)
+	}
+
+	cases := map[string]string{
+		"bad signature": signTestToken(t, "other-secret", validClaims(RoleAdmin)),
+		"malformed":     "not.a.jwt",
+		"two parts":     "abc.def",
+		"alg none":      base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.",
+	}
+	for name, token := range cases {
+		if _, err := parseJWT(token, []byte(testJWTSecret), now); err != errInvalidToken {
+			t.Errorf("%s: err = %v, want errInvalidToken", name, err)
+		}
+	}
+}
+
+func TestServiceRoutesRequireToken(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("backend reached by unauthenticated request: %s", r.URL.Path)
+	}))
+	defer backend.Close()
+	gw := newAdminTestGateway(t, backend.URL)
+
+	expired := validClaims("rider")
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	tokens := map[string]string{
+		"no token":      "",
+		"expired":       signTestToken(t, testJWTSecret, expired),
+		"bad signature": signTestToken(t, "other-secret", validClaims("rider")),
+	}
+	for name, token := range tokens {
+		for _, path := range []string{"/users/1", "/matching/match", "/pricing/price", "/rides/1", "/safety/sos"} {
+			if rec := adminRequest(t, gw, path, token); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s on %s: status = %d, want 401", name, path, rec.Code)
+			}
+		}
+	}
+}
+
+func TestServiceRoutesForwardIdentity(t *testing.T) {
+	var gotUser, gotRole string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotRole = r.Header.Get("X-User-ID"), r.Header.Get("X-User-Role")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gw := newAdminTestGateway(t, backend.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/rides/1", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, testJWTSecret, validClaims("driver")))
+	req.Header.Set("X-User-ID", "someone-else")
+	req.Header.Set("X-User-Role", RoleAdmin)
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if gotUser != "user-1" || gotRole != "driver" {
+		t.Errorf("identity headers = %q/%q, want user-1/driver", gotUser, gotRole)
+	}
+}
+
+func TestPublicRoutesSkipAuthentication(t *testing.T) {
+	var gotUser string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = r.Header.Get("X-User-ID")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gw := newAdminTestGateway(t, backend.URL)
+
+	if rec := adminRequest(t, gw, "/health", ""); rec.Code != http.StatusOK {
+		t.Errorf("/health: status = %d, want 200", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	req.Header.Set("X-User-ID", "spoofed")
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/auth/login: status = %d, want 200", rec.Code)
+	}
+	if gotUser != "" {
+		t.Errorf("/auth/login forwarded X-User-ID %q, want it stripped", gotUser)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/rides/shared/share-token", nil)
+	req.Header.Set("X-User-ID", "spoofed")
+	rec = httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/rides/shared/{token}: status = %d, want 200", rec.Code)
+	}
+	if gotUser != "" {
+		t.Errorf("/rides/shared/{token} forwarded X-User-ID %q, want it stripped", gotUser)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/safety/api/v1/verify/identity/callback", nil)
+	rec = httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("POSTIDENT callback: status = %d, want 200", rec.Code)
+	}
+	if rec := adminRequest(t, gw, "/safety/api/v1/verify/identity/case-1", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("identity case lookup: status = %d, want 401", rec.Code)
+	}
+
+	// Only reading a share link is public; creating one is not
+	if rec := adminRequest(t, gw, "/rides/shared", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("/rides/shared: status = %d, want 401", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/rides/shared/share-token", nil)
+	rec = httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST /rides/shared/{token}: status = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned by the proxy transport while a backend's circuit
+// breaker is open; the request is not sent.
+var errCircuitOpen = errors.New("circuit breaker open")
+
+const (
+	defaultProxyTimeout     = 10 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ProxyConfig configures backend isolation for the reverse proxies. Timeouts
+// is keyed by service name (auth, users, matching, pricing, rides, safety);
+// services without an entry use defaultProxyTimeout.
+type ProxyConfig struct {
+	Timeouts map[string]time.Duration
+	// BreakerThreshold is the number of consecutive failures that opens a
+	// backend's circuit
+	BreakerThreshold int
+	// BreakerCooldown is how long an open circuit rejects requests before a
+	// single trial request is let through
+	BreakerCooldown time.Duration
+	// RetryAttempts is the number of tries for a GET or HEAD request that
+	// fails transiently; 1 disables retries
+	RetryAttempts int
+	// RetryBackoff is the wait before the first retry; it doubles after each
+	RetryBackoff time.Duration
+}
+
+// proxyTimeoutEnv maps service names to the variable holding their timeout,
+// following the *_SERVICE_URL naming
+var proxyTimeoutEnv = map[string]string{
+	"auth":     "AUTH_SERVICE_TIMEOUT",
+	"users":    "USER_SERVICE_TIMEOUT",
+	"matching": "MATCHING_SERVICE_TIMEOUT",
+	"pricing":  "PRICING_SERVICE_TIMEOUT",
+	"rides":    "RIDE_SERVICE_TIMEOUT",
+	"safety":   "SAFETY_SERVICE_TIMEOUT",
+}
+
+// loadProxyConfig reads per-service timeouts (e.g. PRICING_SERVICE_TIMEOUT=3s),
+// the breaker settings CIRCUIT_BREAKER_THRESHOLD and CIRCUIT_BREAKER_COOLDOWN
+// and the retry settings PROXY_RETRY_ATTEMPTS and PROXY_RETRY_BACKOFF from the
+// environment.
+func loadProxyConfig() (ProxyConfig, error) {
+	cfg := ProxyConfig{
+		Timeouts:         make(map[string]time.Duration),
+		BreakerThreshold: defaultBreakerThreshold,
+		BreakerCooldown:  defaultBreakerCooldown,
+		RetryAttempts:    defaultRetryAttempts,
+		RetryBackoff:     defaultRetryBackoff,
+	}
+
+	for name, env := range proxyTimeoutEnv {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("%s must be a positive duration, got %q", env, v)
+		}
+		cfg.Timeouts[name] = d
+	}
+
+	if v := os.Getenv("CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("CIRCUIT_BREAKER_THRESHOLD must be a positive integer, got %q", v)
+		}
+		cfg.BreakerThreshold = n
+	}
+	if v := os.Getenv("CIRCUIT_BREAKER_COOLDOWN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("CIRCUIT_BREAKER_COOLDOWN must be a positive duration, got %q", v)
+		}
+		cfg.BreakerCooldown = d
+	}
+
+	if v := os.Getenv("PROXY_RETRY_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRetryAttempts {
+			return cfg, fmt.Errorf("PROXY_RETRY_ATTEMPTS must be between 1 and %d, got %q", maxRetryAttempts, v)
+		}
+		cfg.RetryAttempts = n
+	}
+	if v := os.Getenv("PROXY_RETRY_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("PROXY_RETRY_BACKOFF must be a non-negative duration, got %q", v)
+		}
+		cfg.RetryBackoff = d
+	}
+
+	return cfg, nil
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker opens after threshold consecutive failures and rejects
+// requests for cooldown. It then lets one trial request through: success
+// closes the circuit, failure opens it for another cooldown.
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	logger    *log.Logger
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration, logger *log.Logger) *circuitBreaker {
+	if threshold < 1 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &circuitBreaker{name: name, threshold: threshold, cooldown: cooldown, logger: logger, now: time.Now}
+}
+
+// allow reports whether a request may be sent to the backend
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(circuitHalfOpen, "cool-down elapsed, sending trial request")
+		b.probing = true
+		return true
+	case circuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record reports the outcome of a request let through by allow
+func (b *circuitBreaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		if b.state != circuitClosed {
+			b.setState(circuitClosed, "trial request succeeded")
+		}
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == circuitHalfOpen:
+		b.openedAt = b.now()
+		b.setState(circuitOpen, "trial request failed")
+	case b.state == circuitClosed && b.failures >= b.threshold:
+		b.openedAt = b.now()
+		b.setState(circuitOpen, fmt.Sprintf("%d consecutive failures", b.failures))
+	}
+}
+
+// abandon releases a request let through by allow without an outcome
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) currentState() circuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) setState(state circuitState, reason string) {
+	b.logger.Printf("[BREAKER] %s: %s -> %s (%s)", b.name, b.state, state, reason)
+	b.state = state
+}
+
+// breakerFor returns the circuit breaker for a backend. Breakers are shared by
+// every proxy to the same service, e.g. /pricing and /admin/pricing.
+func (gw *APIGateway) breakerFor(name string) *circuitBreaker {
+	gw.breakersMu.Lock()
+	defer gw.breakersMu.Unlock()
+
+	b, ok := gw.breakers[name]
+	if !ok {
+		b = newCircuitBreaker(name, gw.config.Proxy.BreakerThreshold, gw.config.Proxy.BreakerCooldown, gw.logger)
+		gw.breakers[name] = b
+	}
+	return b
+}
+
+func (gw *APIGateway) proxyTimeout(name string) time.Duration {
+	if d, ok := gw.config.Proxy.Timeouts[name]; ok && d > 0 {
+		return d
+	}
+	return defaultProxyTimeout
+}
+
+// breakerTransport applies the per-service timeout and circuit breaker to
+// proxied requests. Transport errors and 5xx responses count as failures;
+// requests cancelled by the client do not. Upgrade requests (e.g. WebSocket)
+// are not bound by the timeout, since the connection outlives the request.
+type breakerTransport struct {
+	base    http.RoundTripper
+	breaker *circuitBreaker
+	timeout time.Duration
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+
+	if isUpgradeRequest(req) {
+		// A 101 response's body is the upgraded connection, which
+		// ReverseProxy needs as an io.ReadWriteCloser, so it is returned
+		// unwrapped and without a deadline
+		resp, err := t.base.RoundTrip(req)
+		t.observe(req, resp, err)
+		return resp, err
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	t.observe(req, resp, err)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout also bounds reading the body, so it is cancelled on close
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// observe records the outcome of a round trip with the breaker
+func (t *breakerTransport) observe(req *http.Request, resp *http.Response, err error) {
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// The client went away; this says nothing about the backend
+		t.breaker.abandon()
+	case err != nil:
+		t.breaker.record(false)
+	default:
+		t.breaker.record(resp.StatusCode < 500)
+	}
+}
+
+// isUpgradeRequest reports whether req asks to switch protocols, i.e. carries
+// the "upgrade" token in its Connection header.
+func isUpgradeRequest(req *http.Request) bool {
+	for _, v := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// writeProxyError answers a request the proxy could not complete: 503 while
+// the circuit is open, 504 on timeout, 502 otherwise.
+func (gw *APIGateway) writeProxyError(w http.ResponseWriter, name string, err error) {
+	gw.logger.Printf("[PROXY] %s request failed: %v request_id=%s", name, err, w.Header().Get(requestIDHeader))
+	switch {
+	case errors.Is(err, errCircuitOpen):
+		w.Header().Set("Retry-After", strconv.Itoa(int(gw.breakerFor(name).cooldown.Seconds())))
+		gw.writeError(w, name+" service temporarily unavailable", http.StatusServiceUnavailable)
+	case errors.Is(err, context.DeadlineExceeded):
+		gw.writeError(w, name+" service timed out", http.StatusGatewayTimeout)
+	default:
+		gw.writeError(w, name+" service unavailable", http.StatusBadGateway)
+	}
+}
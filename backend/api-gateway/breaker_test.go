@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newBreakerTestGateway(t *testing.T, backendURL string, proxy ProxyConfig) *APIGateway {
+	t.Helper()
+	gw := NewAPIGateway(ServiceConfig{RideServiceURL: backendURL, JWTSecret: testJWTSecret, Proxy: proxy})
+	gw.setupRoutes()
+	return gw
+}
+
+func TestCircuitBreakerOpensOnFailingBackend(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+	gw := newBreakerTestGateway(t, backend.URL, ProxyConfig{BreakerThreshold: 3, BreakerCooldown: time.Minute})
+	token := signTestToken(t, testJWTSecret, validClaims("rider"))
+
+	for i := 0; i < 3; i++ {
+		if rec := adminRequest(t, gw, "/rides/1", token); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("request %d: status = %d, want the backend's 500", i+1, rec.Code)
+		}
+	}
+	if state := gw.breakerFor("rides").currentState(); state != circuitOpen {
+		t.Fatalf("breaker state = %s after 3 failures, want open", state)
+	}
+
+	rec := adminRequest(t, gw, "/rides/1", token)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("open circuit: status = %d, want 503", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Retry-After = %q, want 60", rec.Header().Get("Retry-After"))
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("backend hit %d times, want 3: an open circuit must not forward requests", n)
+	}
+}
+
+func TestCircuitBreakerTrialRequest(t *testing.T) {
+	b := newCircuitBreaker("pricing", 2, time.Minute, log.New(io.Discard, "", 0))
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.record(false)
+	b.record(false)
+	if b.allow() {
+		t.Fatal("open circuit allowed a request")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("trial request rejected after cool-down")
+	}
+	if b.allow() {
+		t.Error("second request allowed while the trial is in flight")
+	}
+	b.record(false)
+	if b.currentState() != circuitOpen || b.allow() {
+		t.Fatal("failed trial should reopen the circuit")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("trial request rejected after second cool-down")
+	}
+	b.record(true)
+	if b.currentState() != circuitClosed || !b.allow() {
+		t.Error("successful trial should close the circuit")
+	}
+}
+
+func TestProxyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+	gw := newBreakerTestGateway(t, backend.URL, ProxyConfig{Timeouts: map[string]time.Duration{"rides": 50 * time.Millisecond}})
+
+	start := time.Now()
+	rec := adminRequest(t, gw, "/rides/1", signTestToken(t, testJWTSecret, validClaims("rider")))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %s, want it cut off at the rides timeout", elapsed)
+	}
+}
+
+func TestProxyUpgradeOutlivesTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" || r.Header.Get("X-User-Role") != "rider" {
+			t.Errorf("upgrade not forwarded with identity: %v", r.Header)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		rw.WriteString(line)
+		rw.Flush()
+	}))
+	defer backend.Close()
+	gw := newBreakerTestGateway(t, backend.URL, ProxyConfig{Timeouts: map[string]time.Duration{"rides": 50 * time.Millisecond}})
+	server := httptest.NewServer(gw.router)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	token := signTestToken(t, testJWTSecret, validClaims("rider"))
+	io.WriteString(conn, "GET /rides/1/stream HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: echo\r\nAuthorization: Bearer "+token+"\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read upgrade response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+
+	// Well past the rides timeout, the upgraded connection still carries data
+	time.Sleep(150 * time.Millisecond)
+	io.WriteString(conn, "ping\n")
+	line, err := br.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ping" {
+		t.Errorf("echo = %q, %v; want ping", line, err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/quotetoken"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// errPricingUnavailable signals that the pricing service answered with a
+// gateway/availability error and the fallback should be considered.
+var errPricingUnavailable = errors.New("pricing service unavailable")
+
+// PricingFallbackConfig configures the degraded-mode fare estimate served by
+// the gateway when the pricing service cannot be reached. It is opt-in via
+// PRICING_FALLBACK_ENABLED=true.
+//
+// The default rates mirror the pricing service tariff without surge. The
+// estimate always respects MinimumFareEUR (PBefG §51).
+//
+// QuoteSigner signs estimates with the pricing service's QUOTE_TOKEN_SECRET,
+// so a rider can book at the estimated fare; the ride-service records such
+// rides as booked on an estimate.
+type PricingFallbackConfig struct {
+	Enabled           bool
+	BaseRateEUR       float64
+	PricePerKmEUR     float64
+	PricePerMinuteEUR float64
+	MinimumFareEUR    float64
+	// AverageSpeedKmh estimates the duration when the quote request does not
+	// include duration_min.
+	AverageSpeedKmh float64
+	QuoteSigner     *quotetoken.Signer
+}
+
+// FallbackQuote is the estimated fare returned in degraded mode. It carries
+// the same fields as the pricing service's PriceResponse plus Estimate=true,
+// including the quote token to book it with.
+type FallbackQuote struct {
+	BasePrice       float64 `json:"base_price"`
+	DistancePrice   float64 `json:"distance_price"`
+	TimePrice       float64 `json:"time_price"`
+	SurgeMultiplier float64 `json:"surge_multiplier"`
+	Subtotal        float64 `json:"subtotal"`
+	FinalPrice      float64 `json:"final_price"`
+	Currency        string  `json:"currency"`
+	ComplianceNote  string  `json:"compliance_note,omitempty"`
+	Estimate        bool    `json:"estimate"`
+	DistanceKm      float64 `json:"distance_km"`
+	DurationMin     float64 `json:"duration_min"`
+	QuoteToken      string  `json:"quote_token,omitempty"`
+	QuoteExpiresAt  string  `json:"quote_expires_at,omitempty"`
+}
+
+func defaultPricingFallbackConfig() PricingFallbackConfig {
+	return PricingFallbackConfig{
+		BaseRateEUR:       3.50,
+		PricePerKmEUR:     1.80,
+		PricePerMinuteEUR: 0.35,
+		MinimumFareEUR:    5.00,
+		AverageSpeedKmh:   30,
+	}
+}
+
+// loadPricingFallbackConfig reads the fallback configuration from the environment.
+func loadPricingFallbackConfig() (PricingFallbackConfig, error) {
+	cfg := defaultPricingFallbackConfig()
+	cfg.Enabled = os.Getenv("PRICING_FALLBACK_ENABLED") == "true"
+
+	rates := []struct {
+		env string
+		dst *float64
+	}{
+		{"PRICING_FALLBACK_BASE_RATE_EUR", &cfg.BaseRateEUR},
+		{"PRICING_FALLBACK_PRICE_PER_KM_EUR", &cfg.PricePerKmEUR},
+		{"PRICING_FALLBACK_PRICE_PER_MINUTE_EUR", &cfg.PricePerMinuteEUR},
+		{"PRICING_FALLBACK_MINIMUM_FARE_EUR", &cfg.MinimumFareEUR},
+		{"PRICING_FALLBACK_AVERAGE_SPEED_KMH", &cfg.AverageSpeedKmh},
+	}
+	for _, rate := range rates {
+		v := os.Getenv(rate.env)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return cfg, fmt.Errorf("%s must be a non-negative number, got %q", rate.env, v)
+		}
+		*rate.dst = f
+	}
+	if cfg.AverageSpeedKmh <= 0 {
+		return cfg, errors.New("PRICING_FALLBACK_AVERAGE_SPEED_KMH must be greater than 0")
+	}
+
+	return cfg, nil
+}
+
+// estimate computes a rough fare from the quote's query parameters and signs
+// it if a QuoteSigner is configured. The distance is taken from distance_km
+// or, if absent, computed from pickup_lat/pickup_lng/dropoff_lat/dropoff_lng.
+func (cfg PricingFallbackConfig) estimate(query url.Values) (*FallbackQuote, error) {
+	distanceKm, err := fallbackDistance(query)
+	if err != nil {
+		return nil, err
+	}
+
+	durationMin := distanceKm / cfg.AverageSpeedKmh * 60
+	if v := query.Get("duration_min"); v != "" {
+		durationMin, err = strconv.ParseFloat(v, 64)
+		if err != nil || durationMin < 0 {
+			return nil, fmt.Errorf("invalid duration_min parameter")
+		}
+	}
+
+	distancePrice := distanceKm * cfg.PricePerKmEUR
+	timePrice := durationMin * cfg.PricePerMinuteEUR
+	subtotal := cfg.BaseRateEUR + distancePrice + timePrice
+
+	finalPrice := subtotal
+	note := "Estimated fare: pricing service unavailable, surge not applied"
+	if finalPrice < cfg.MinimumFareEUR {
+		finalPrice = cfg.MinimumFareEUR
+		note = "Estimated fare: pricing service unavailable, adjusted to minimum fare per PBefG §51"
+	}
+
+	quote := &FallbackQuote{
+		BasePrice:       roundCents(cfg.BaseRateEUR),
+		DistancePrice:   roundCents(distancePrice),
+		TimePrice:       roundCents(timePrice),
+		SurgeMultiplier: 1.0,
+		Subtotal:        roundCents(subtotal),
+		FinalPrice:      roundCents(finalPrice),
+		Currency:        "EUR",
+		ComplianceNote:  note,
+		Estimate:        true,
+		DistanceKm:      math.Round(distanceKm*1000) / 1000,
+		DurationMin:     math.Round(durationMin*10) / 10,
+	}
+	if cfg.QuoteSigner != nil {
+		token, signed, err := cfg.QuoteSigner.Issue(quotetoken.Quote{
+			FareEUR:         quote.FinalPrice,
+			Currency:        quote.Currency,
+			DistanceKm:      quote.DistanceKm,
+			DurationMin:     quote.DurationMin,
+			SurgeMultiplier: quote.SurgeMultiplier,
+			Estimate:        true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign estimate: %w", err)
+		}
+		quote.QuoteToken = token
+		quote.QuoteExpiresAt = timeutil.Format(signed.ExpiresAt)
+	}
+	return quote, nil
+}
+
+func fallbackDistance(query url.Values) (float64, error) {
+	if v := query.Get("distance_km"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid distance_km parameter")
+		}
+		return d, nil
+	}
+
+	var coords [4]float64
+	for i, key := range []string{"pickup_lat", "pickup_lng", "dropoff_lat", "dropoff_lng"} {
+		f, err := strconv.ParseFloat(query.Get(key), 64)
+		if err != nil {
+			return 0, fmt.Errorf("distance_km or pickup/dropoff coordinates are required")
+		}
+		coords[i] = f
+	}
+	return distance.HaversineKm(coords[0], coords[1], coords[2], coords[3]), nil
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// isFareQuote reports whether r is a fare quote request to the pricing service.
+func isFareQuote(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/price")
+}
+
+// newPricingProxy wraps the pricing proxy with the opt-in fare fallback.
+func (gw *APIGateway) newPricingProxy(target string) *httputil.ReverseProxy {
+	proxy := gw.newProxy("pricing", target)
+
+	cfg := gw.config.PricingFallback
+	if !cfg.Enabled {
+		return proxy
+	}
+
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if isFareQuote(resp.Request) {
+				return errPricingUnavailable
+			}
+		}
+		return nil
+	}
+
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if !isFareQuote(r) {
+			gw.writeProxyError(w, "pricing", err)
+			return
+		}
+
+		quote, qerr := cfg.estimate(r.URL.Query())
+		if qerr != nil {
+			gw.logger.Printf("[FALLBACK] pricing unavailable (%v), cannot estimate fare: %v", err, qerr)
+			gw.writeError(w, "Pricing service unavailable: "+qerr.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		gw.logger.Printf("[FALLBACK] pricing unavailable (%v), serving estimated fare %.2f EUR for %.3fkm", err, quote.FinalPrice, quote.DistanceKm)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Fare-Estimate", "true")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(quote)
+	}
+
+	return proxy
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/quotetoken"
+)
+
+func newTestGateway(t *testing.T, pricingURL string, fallback PricingFallbackConfig) *APIGateway {
+	t.Helper()
+	gw := NewAPIGateway(ServiceConfig{PricingServiceURL: pricingURL, PricingFallback: fallback, JWTSecret: testJWTSecret})
+	gw.setupRoutes()
+	return gw
+}
+
+// priceRequest is an authenticated fare quote request
+func priceRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/pricing/price?distance_km=10&duration_min=20", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, testJWTSecret, validClaims("rider")))
+	return req
+}
+
+func enabledFallback() PricingFallbackConfig {
+	cfg := defaultPricingFallbackConfig()
+	cfg.Enabled = true
+	return cfg
+}
+
+// downServerURL returns the URL of a server that has already been shut down.
+func downServerURL() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestFallbackEstimateFromDistance(t *testing.T) {
+	cfg := defaultPricingFallbackConfig()
+
+	quote, err := cfg.estimate(url.Values{"distance_km": {"10"}, "duration_min": {"20"}})
+	if err != nil {
+		t.Fatalf("estimate failed: %v", err)
+	}
+
+	// 3.50 + 10*1.80 + 20*0.35 = 28.50
+	if quote.FinalPrice != 28.50 {
+		t.Errorf("final price = %.2f, want 28.50", quote.FinalPrice)
+	}
+	if !quote.Estimate {
+		t.Error("fallback quote must be flagged as estimate")
+	}
+}
+
+func TestFallbackEstimateRespectsMinimumFare(t *testing.T) {
+	cfg := defaultPricingFallbackConfig()
+
+	quote, err := cfg.estimate(url.Values{"distance_km": {"0.3"}, "duration_min": {"1"}})
+	if err != nil {
+		t.Fatalf("estimate failed: %v", err)
+	}
+	if quote.FinalPrice != cfg.MinimumFareEUR {
+		t.Errorf("final price = %.2f, want minimum fare %.2f", quote.FinalPrice, cfg.MinimumFareEUR)
+	}
+}
+
+func TestFallbackEstimateFromCoordinates(t *testing.T) {
+	cfg := defaultPricingFallbackConfig()
+
+	// Berlin Hbf to Alexanderplatz, roughly 3.5km.
+	quote, err := cfg.estimate(url.Values{
+		"pickup_lat": {"52.5251"}, "pickup_lng": {"13.3694"},
+		"dropoff_lat": {"52.5219"}, "dropoff_lng": {"13.4132"},
+	})
+	if err != nil {
+		t.Fatalf("estimate failed: %v", err)
+	}
+	if quote.DistanceKm < 2.5 || quote.DistanceKm > 3.5 {
+		t.Errorf("distance = %.3fkm, want ~3km", quote.DistanceKm)
+	}
+	if quote.DurationMin <= 0 {
+		t.Error("duration should be estimated when not supplied")
+	}
+}
+
+func TestFallbackEstimateRequiresDistance(t *testing.T) {
+	cfg := defaultPricingFallbackConfig()
+
+	if _, err := cfg.estimate(url.Values{}); err == nil {
+		t.Error("expected an error without distance or coordinates")
+	}
+}
+
+func TestPricingProxyServesFallbackWhenBackendDown(t *testing.T) {
+	gw := newTestGateway(t, downServerURL(), enabledFallback())
+
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, priceRequest(t))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if rec.Header().Get("X-Fare-Estimate") != "true" {
+		t.Error("missing X-Fare-Estimate header")
+	}
+
+	var quote FallbackQuote
+	if err := json.NewDecoder(rec.Body).Decode(&quote); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !quote.Estimate || quote.FinalPrice != 28.50 {
+		t.Errorf("unexpected quote %+v", quote)
+	}
+}
+
+func TestPricingProxyServesFallbackOnServiceUnavailable(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	gw := newTestGateway(t, backend.URL, enabledFallback())
+
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, priceRequest(t))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Fare-Estimate") != "true" {
+		t.Fatalf("expected fallback quote, got status %d", rec.Code)
+	}
+}
+
+func TestPricingProxyPassesThroughHealthyBackend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"final_price":30.00}`))
+	}))
+	defer backend.Close()
+
+	gw := newTestGateway(t, backend.URL, enabledFallback())
+
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, priceRequest(t))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Fare-Estimate") != "" {
+		t.Fatalf("expected proxied response, got status %d estimate=%q", rec.Code, rec.Header().Get("X-Fare-Estimate"))
+	}
+}
+
+func TestPricingProxyFallbackDisabledByDefault(t *testing.T) {
+	gw := newTestGateway(t, downServerURL(), defaultPricingFallbackConfig())
+
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, priceRequest(t))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502 when fallback is disabled", rec.Code)
+	}
+}
+
+func TestFallbackEstimateCanBeBooked(t *testing.T) {
+	signer, err := quotetoken.NewSigner([]byte("test-quote-token-secret"), time.Minute)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	cfg := enabledFallback()
+	cfg.QuoteSigner = signer
+	gw := newTestGateway(t, downServerURL(), cfg)
+
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, priceRequest(t))
+	var quote FallbackQuote
+	if err := json.NewDecoder(rec.Body).Decode(&quote); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if quote.QuoteToken == "" || quote.QuoteExpiresAt == "" {
+		t.Fatalf("estimate carries no quote token: %+v", quote)
+	}
+
+	// The ride-service verifies the token with the shared secret
+	signed, err := signer.Verify(quote.QuoteToken)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !signed.Estimate || signed.FareEUR != quote.FinalPrice || signed.DistanceKm != 10 {
+		t.Errorf("signed quote %+v does not match the estimate %+v", signed, quote)
+	}
+}
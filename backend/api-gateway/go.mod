@@ -2,4 +2,11 @@ module github.com/rideshare/api-gateway
 
 go 1.21
 
-require github.com/gorilla/mux v1.8.1
\ No newline at end of file
+require (
+	github.com/gorilla/mux v1.8.1
+	github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
+)
+
+require github.com/google/uuid v1.6.0
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
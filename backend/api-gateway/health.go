@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// defaultHealthCheckTimeout bounds each backend check when
+// ServiceConfig.HealthCheckTimeout is not set
+const defaultHealthCheckTimeout = 2 * time.Second
+
+const (
+	healthUp   = "up"
+	healthDown = "down"
+)
+
+// ServiceHealth is the result of checking one backend's /health. The endpoint
+// is public, so why a service is down is only logged.
+type ServiceHealth struct {
+	Status string `json:"status"`
+}
+
+// ServicesHealthResponse is returned by GET /health/services. Status is "down"
+// if any backend is down.
+type ServicesHealthResponse struct {
+	Status    string                   `json:"status"`
+	Services  map[string]ServiceHealth `json:"services"`
+	Timestamp string                   `json:"timestamp"`
+}
+
+// loadHealthCheckTimeout reads HEALTH_CHECK_TIMEOUT from the environment; an
+// unset value leaves the default in place.
+func loadHealthCheckTimeout() (time.Duration, error) {
+	v := os.Getenv("HEALTH_CHECK_TIMEOUT")
+	if v == "" {
+		return defaultHealthCheckTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("HEALTH_CHECK_TIMEOUT must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// healthBackends lists the services checked by /health/services. Services
+// without a configured URL are left out.
+func (gw *APIGateway) healthBackends() []adminBackend {
+	backends := append([]adminBackend{{"auth", gw.config.AuthServiceURL}}, gw.adminBackends()...)
+	configured := backends[:0]
+	for _, backend := range backends {
+		if backend.url != "" {
+			configured = append(configured, backend)
+		}
+	}
+	return configured
+}
+
+// servicesHealthHandler checks every backend concurrently. Each check has its
+// own timeout, so a hung service is reported down instead of delaying the
+// report beyond that timeout.
+func (gw *APIGateway) servicesHealthHandler(w http.ResponseWriter, r *http.Request) {
+	timeout := gw.config.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	backends := gw.healthBackends()
+	errs := make([]error, len(backends))
+	var wg sync.WaitGroup
+	for i, backend := range backends {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			errs[i] = checkServiceHealth(r.Context(), url, timeout)
+		}(i, backend.url)
+	}
+	wg.Wait()
+
+	resp := ServicesHealthResponse{
+		Status:    healthUp,
+		Services:  make(map[string]ServiceHealth, len(backends)),
+		Timestamp: timeutil.Format(timeutil.Now()),
+	}
+	for i, backend := range backends {
+		if errs[i] != nil {
+			resp.Services[backend.name] = ServiceHealth{Status: healthDown}
+			resp.Status = healthDown
+			gw.logger.Printf("[HEALTH] %s is down: %v", backend.name, errs[i])
+			continue
+		}
+		resp.Services[backend.name] = ServiceHealth{Status: healthUp}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// checkServiceHealth calls baseURL/health; any 2xx answer within timeout
+// counts as up, otherwise the returned error says why the service is down
+func checkServiceHealth(ctx context.Context, baseURL string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("no response within %s", timeout)
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
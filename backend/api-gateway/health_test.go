@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServicesHealth(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("unexpected health check path %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hung.Close()
+	defer close(release)
+
+	gw := NewAPIGateway(ServiceConfig{
+		UserServiceURL:     up.URL,
+		RideServiceURL:     up.URL,
+		PricingServiceURL:  failing.URL,
+		MatchingServiceURL: hung.URL,
+		HealthCheckTimeout: 100 * time.Millisecond,
+	})
+	var logs bytes.Buffer
+	gw.logger = log.New(&logs, "", 0)
+	gw.setupRoutes()
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/services", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("report took %s, a hung service should only cost one timeout", elapsed)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	body := rec.Body.String()
+	for _, leak := range []string{"127.0.0.1", "health check", "no response", "error"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("report contains %q: %s", leak, body)
+		}
+	}
+	if !strings.Contains(logs.String(), "pricing is down: health check returned 500") {
+		t.Errorf("failure details not logged: %s", logs.String())
+	}
+
+	var resp ServicesHealthResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != healthDown {
+		t.Errorf("overall status = %q, want down", resp.Status)
+	}
+	want := map[string]string{"users": healthUp, "rides": healthUp, "pricing": healthDown, "matching": healthDown}
+	if len(resp.Services) != len(want) {
+		t.Errorf("services = %+v, want only the configured ones", resp.Services)
+	}
+	for name, status := range want {
+		if got := resp.Services[name].Status; got != status {
+			t.Errorf("%s: status = %q, want %q", name, got, status)
+		}
+	}
+}
+
+func TestServicesHealthAllUp(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	gw := newAdminTestGateway(t, up.URL)
+
+	rec := adminRequest(t, gw, "/health/services", "")
+	var resp ServicesHealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != healthUp || len(resp.Services) != 6 {
+		t.Errorf("unexpected report %+v", resp)
+	}
+}
+
+func TestLoadHealthCheckTimeout(t *testing.T) {
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "")
+	if d, err := loadHealthCheckTimeout(); err != nil || d != defaultHealthCheckTimeout {
+		t.Errorf("unset: got %s, %v", d, err)
+	}
+
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "500ms")
+	if d, err := loadHealthCheckTimeout(); err != nil || d != 500*time.Millisecond {
+		t.Errorf("500ms: got %s, %v", d, err)
+	}
+
+	for _, v := range []string{"0s", "-1s", "soon"} {
+		t.Setenv("HEALTH_CHECK_TIMEOUT", v)
+		if _, err := loadHealthCheckTimeout(); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
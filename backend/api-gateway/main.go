@@ -5,19 +5,21 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/quotetoken"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 )
 
 // ServiceConfig holds the configuration for backend services
@@ -28,6 +30,15 @@ type ServiceConfig struct {
 	PricingServiceURL string
 	RideServiceURL string
 	SafetyServiceURL string
+	PricingFallback PricingFallbackConfig
+	// JWTSecret is the HS256 secret shared with the auth-service
+	JWTSecret string
+	// HealthCheckTimeout bounds each backend check in /health/services
+	HealthCheckTimeout time.Duration
+	// Proxy holds the per-service timeouts and circuit breaker settings
+	Proxy ProxyConfig
+	// RateLimit is the per-client request limit
+	RateLimit RateLimitConfig
 }
 
 // APIGateway represents the main gateway instance
@@ -36,6 +47,8 @@ type APIGateway struct {
 	router *mux.Router
 	requestCounter uint64
 	logger *log.Logger
+	breakersMu sync.Mutex
+	breakers map[string]*circuitBreaker
 }
 
 // HealthCheckResponse represents the health check response structure
@@ -56,72 +69,196 @@ type ErrorResponse struct {
 
 // NewAPIGateway creates a new API Gateway instance
 func NewAPIGateway(config ServiceConfig) *APIGateway {
-	logger := log.New(os.Stdout, "[API-GATEWAY] ", log.LstdFlags|log.Lmicroseconds)
-	
+	logger := timeutil.NewLogger(os.Stdout, "[API-GATEWAY] ", 0)
+
 	return &APIGateway{
 		config: config,
 		router: mux.NewRouter(),
 		logger: logger,
+		breakers: make(map[string]*circuitBreaker),
 	}
 }
 
-// setupRoutes configures all routes and their handlers
+// setupRoutes configures all routes and their handlers. Only /health,
+// /health/services, /version, /auth (login, token refresh), trip share links
+// (GET /rides/shared/{token}) and the POSTIDENT callback are public; every
+// other route requires a valid bearer token.
 func (gw *APIGateway) setupRoutes() {
-	gw.router.HandleFunc(\"/health\", gw.healthCheckHandler).Methods(\"GET\")
-
-	// Proxy routes to microservices
-	gw.router.PathPrefix(\"/auth\").Handler(gw.newProxy(gw.config.AuthServiceURL))
-	gw.router.PathPrefix(\"/users\").Handler(gw.newProxy(gw.config.UserServiceURL))
-	gw.router.PathPrefix(\"/matching\").Handler(gw.newProxy(gw.config.MatchingServiceURL,{\"action\":\"find\", \"status\":\"active\"}))
-	gw.router.PathPrefix(\"/pricing\").Handler(gw.newProxy(gw.config.PricingServiceURL))
-	gw.router.PathPrefix(\"/rides\").Handler(gw.newProxy(gw.config.RideServiceURL))
-	gw.router.PathPrefix(\"/safety\").Handler(gw.newProxy(gw.config.SafetyServiceURL))
+	gw.router.Use(gw.withRequestID, gw.rateLimit())
+
+	gw.router.HandleFunc("/health", gw.healthCheckHandler).Methods("GET")
+	gw.router.HandleFunc("/health/services", gw.servicesHealthHandler).Methods("GET")
+	gw.router.HandleFunc("/version", buildinfo.Handler("api-gateway")).Methods("GET")
+
+	gw.setupAdminRoutes()
+
+	// Proxy routes to microservices. forwardIdentity also runs on /auth so
+	// that client-supplied identity headers never reach a backend.
+	gw.router.PathPrefix("/auth").Handler(gw.forwardIdentity(gw.newProxy("auth", gw.config.AuthServiceURL)))
+	gw.router.PathPrefix("/users").Handler(gw.protect(gw.newProxy("users", gw.config.UserServiceURL)))
+	gw.router.PathPrefix("/matching").Handler(gw.protect(gw.newProxy("matching", gw.config.MatchingServiceURL)))
+	gw.router.PathPrefix("/pricing").Handler(gw.protect(gw.newPricingProxy(gw.config.PricingServiceURL)))
+	rides := gw.newProxy("rides", gw.config.RideServiceURL)
+	// A trip share link is opened by a trusted contact without an account;
+	// the token in the path is the credential.
+	gw.router.Handle("/rides/shared/{token}", gw.forwardIdentity(rides)).Methods("GET")
+	gw.router.PathPrefix("/rides").Handler(gw.protect(rides))
+	safety := gw.newProxy("safety", gw.config.SafetyServiceURL)
+	// Deutsche Post cannot send a user token; the safety-service checks the
+	// callback's HMAC signature instead.
+	gw.router.Handle("/safety/api/v1/verify/identity/callback", gw.forwardIdentity(safety)).Methods("POST")
+	gw.router.PathPrefix("/safety").Handler(gw.protect(safety))
 }
 
-// newProxy creates a reverse proxy for a given target URL
-func (gw *APIGateway) newProxy(target String) *httputil.ReverseProxy {
+// newProxy creates a reverse proxy for a given target URL. Requests are bounded
+// by the service's timeout and go through its circuit breaker; idempotent
+// requests that fail transiently are retried.
+func (gw *APIGateway) newProxy(name, target string) *httputil.ReverseProxy {
 	url, herr := url.Parse(target)
 	if herr != nil {
-		gw.logger.Pratalf(\"Failed to parse target URL: %v\", herr)
+		gw.logger.Fatalf("Failed to parse target URL: %v", herr)
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(url)
 
 	// Custom director to handle request transformations and logging
-origDirector := proxy.Director
+	origDirector := proxy.Director
 	proxy.Director = func(req *http.Request) {
 		origDirector(req)
-		gw.logger.Printf(\"[PROXY] %s	S\", req.Method, req.URL.Path)
+		requestID := requestIDFromContext(req.Context())
+		if requestID != "" {
+			req.Header.Set(requestIDHeader, requestID)
+		}
+		gw.logger.Printf("[PROXY] %s %s request_id=%s", req.Method, req.URL.Path, requestID)
 		atomic.AddUint64(&gw.requestCounter, 1)
 	}
+	proxy.Transport = &retryTransport{
+		base: &breakerTransport{
+			base: http.DefaultTransport,
+			breaker: gw.breakerFor(name),
+			timeout: gw.proxyTimeout(name),
+		},
+		name: name,
+		attempts: gw.config.Proxy.RetryAttempts,
+		backoff: gw.config.Proxy.RetryBackoff,
+		logger: gw.logger,
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		gw.writeProxyError(w, name, err)
+	}
 
 	return proxy
 }
 
 // healthCheckHandler returns the current status of the gateway
-func (gw *AuditLogger) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	count := atomic.LoadUint64(&gw.requestCounter)
-	resp := HealthCheckResponse {
-	Status: \"OK\",
-	Service: \"API-GATEWAY\",
-	Timestamp: time.Now().UTCString(),
-	Version: \"1.0.0\",
-	}
-	w.setHeader(\"Content-Type\", \"application/json\")
+func (gw *APIGateway) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	resp := HealthCheckResponse{
+		Status: "OK",
+		Service: "API-GATEWAY",
+		Timestamp: timeutil.Format(timeutil.Now()),
+		Version: buildinfo.Version,
+	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// writeError writes a standard JSON error response. The request ID is taken
+// from the X-Request-ID response header set by withRequestID.
+func (gw *APIGateway) writeError(w http.ResponseWriter, message string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+		Code: code,
+		Timestamp: timeutil.Format(timeutil.Now()),
+		RequestID: w.Header().Get(requestIDHeader),
+	})
+}
+
 func main() {
-	config := ServiceConfig {
-	AuthServiceURL:  os.Getenv(\"AUTH_SERVICE_URL\"),
-	UserServiceURL:  os.Getenv(\"USER_SERVICE_URL\"),
-	MatchingServiceURL: os.Getenv(\"MATCHING_SERVICE_URL\"),
-	PricingServiceURL: os.Getenv(\"PRICING_SERVICE_URL\"),
-	RideServiceURL:  os.Getenv(\"RIDE_SERVICE_URL\"),
-	SafetyServiceURL: os.Getenv(\"SAFETY_SERVICE_URL\"),
+	config := ServiceConfig{
+		AuthServiceURL: os.Getenv("AUTH_SERVICE_URL"),
+		UserServiceURL: os.Getenv("USER_SERVICE_URL"),
+		MatchingServiceURL: os.Getenv("MATCHING_SERVICE_URL"),
+		PricingServiceURL: os.Getenv("PRICING_SERVICE_URL"),
+		RideServiceURL: os.Getenv("RIDE_SERVICE_URL"),
+		SafetyServiceURL: os.Getenv("SAFETY_SERVICE_URL"),
+		JWTSecret: os.Getenv("JWT_SECRET"),
+	}
+	if config.JWTSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+
+	fallback, err := loadPricingFallbackConfig()
+	if err != nil {
+		log.Fatalf("Invalid pricing fallback configuration: %v", err)
+	}
+	if fallback.Enabled {
+		// Estimates are signed with the secret the pricing-service and
+		// ride-service share, so degraded-mode quotes can be booked
+		signer, usedDevSecret, err := quotetoken.NewSignerFromEnv()
+		if err != nil {
+			log.Fatalf("Invalid quote token configuration: %v", err)
+		}
+		if usedDevSecret {
+			log.Println("WARNING: Using development quote token secret. Set QUOTE_TOKEN_SECRET in production.")
+		}
+		fallback.QuoteSigner = signer
+	}
+	config.PricingFallback = fallback
+
+	proxyConfig, err := loadProxyConfig()
+	if err != nil {
+		log.Fatalf("Invalid proxy configuration: %v", err)
 	}
+	config.Proxy = proxyConfig
+
+	rateLimit, err := loadRateLimitConfig()
+	if err != nil {
+		log.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+	config.RateLimit = rateLimit
+
+	healthCheckTimeout, err := loadHealthCheckTimeout()
+	if err != nil {
+		log.Fatalf("Invalid health check configuration: %v", err)
+	}
+	config.HealthCheckTimeout = healthCheckTimeout
 
 	gw := NewAPIGateway(config)
 	gw.setupRoutes()
 
-	srv :
\ No newline at end of file
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	srv := &http.Server{
+		Addr: ":" + port,
+		Handler: gw.router,
+		ReadTimeout: 15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout: 60 * time.Second,
+	}
+
+	go func() {
+		gw.logger.Printf("Starting api-gateway on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			gw.logger.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	gw.logger.Println("Shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		gw.logger.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	gw.logger.Println("Server exited")
+}
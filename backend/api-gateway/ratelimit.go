@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRateLimitRPS   = 10.0
+	defaultRateLimitBurst = 20
+	// rateLimitPruneInterval is how often idle buckets are dropped
+	rateLimitPruneInterval = time.Minute
+)
+
+// RateLimitConfig configures the per-client token bucket. A zero
+// RequestsPerSecond disables rate limiting.
+type RateLimitConfig struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// loadRateLimitConfig reads RATE_LIMIT_RPS and RATE_LIMIT_BURST from the
+// environment. RATE_LIMIT_RPS=0 turns the limiter off.
+func loadRateLimitConfig() (RateLimitConfig, error) {
+	cfg := RateLimitConfig{RequestsPerSecond: defaultRateLimitRPS, Burst: defaultRateLimitBurst}
+
+	if v := os.Getenv("RATE_LIMIT_RPS"); v != "" {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil || rps < 0 || math.IsInf(rps, 0) {
+			return cfg, fmt.Errorf("RATE_LIMIT_RPS must be a non-negative number, got %q", v)
+		}
+		cfg.RequestsPerSecond = rps
+	}
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil || burst < 1 {
+			return cfg, fmt.Errorf("RATE_LIMIT_BURST must be a positive integer, got %q", v)
+		}
+		cfg.Burst = burst
+	}
+
+	return cfg, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket per client key. A bucket that has been idle
+// long enough to refill completely is indistinguishable from a new one, so
+// such buckets are pruned.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		rate:      cfg.RequestsPerSecond,
+		burst:     float64(cfg.Burst),
+		now:       time.Now,
+		buckets:   make(map[string]*tokenBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// allow takes a token for key. If none is left it returns false and how long
+// until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= rateLimitPruneInterval {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *rateLimiter) prune(now time.Time) {
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// clientIP returns the address rate limits are keyed by. Behind the ingress
+// the client is the last X-Forwarded-For entry, the one our own proxy
+// appended; earlier entries are client-supplied and could be forged to dodge
+// the limit.
+func clientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit returns middleware rejecting clients that exceed their token
+// bucket with 429 and a Retry-After header. The limiter is created here, once,
+// because the router rebuilds the middleware chain on every request. The
+// liveness probe /health is not limited, so probes from a shared node address
+// are never throttled. /health/services fans out to every backend and is
+// limited like any other route.
+func (gw *APIGateway) rateLimit() func(http.Handler) http.Handler {
+	cfg := gw.config.RateLimit
+	if cfg.RequestsPerSecond <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	limiter := newRateLimiter(cfg)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/health" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := clientIP(r)
+			ok, wait := limiter.allow(ip)
+			if !ok {
+				gw.logger.Printf("[RATELIMIT] %s exceeded %.4g req/s on %s %s", ip, cfg.RequestsPerSecond, r.Method, r.URL.Path)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				gw.writeError(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
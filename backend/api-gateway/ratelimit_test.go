@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPerClient(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gw := NewAPIGateway(ServiceConfig{
+		MatchingServiceURL: backend.URL,
+		JWTSecret:          testJWTSecret,
+		RateLimit:          RateLimitConfig{RequestsPerSecond: 1, Burst: 2},
+	})
+	gw.setupRoutes()
+	token := signTestToken(t, testJWTSecret, validClaims("rider"))
+
+	request := func(forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/matching/match", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		gw.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("203.0.113.7"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: status = %d", i+1, rec.Code)
+		}
+	}
+	rec := request("203.0.113.7")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: status = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", rec.Header().Get("Retry-After"))
+	}
+
+	// A forged leading entry does not give the client a fresh bucket
+	if rec := request("198.51.100.1, 203.0.113.7"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For: status = %d, want 429", rec.Code)
+	}
+	if rec := request("203.0.113.8"); rec.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", rec.Code)
+	}
+	if rec := adminRequest(t, gw, "/health", ""); rec.Code != http.StatusOK {
+		t.Errorf("/health: status = %d, want it exempt", rec.Code)
+	}
+
+	// Burst is 2, so the third report from one client is refused
+	for i := 0; i < 3; i++ {
+		request := httptest.NewRequest(http.MethodGet, "/health/services", nil)
+		request.Header.Set("X-Forwarded-For", "203.0.113.9")
+		rec := httptest.NewRecorder()
+		gw.router.ServeHTTP(rec, request)
+		if want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}[i]; rec.Code != want {
+			t.Errorf("/health/services request %d: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+}
+
+func TestRateLimiterRefillAndPrune(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{RequestsPerSecond: 2, Burst: 1})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.allow("a"); !ok {
+		t.Fatal("first request rejected")
+	}
+	ok, wait := l.allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("empty bucket: ok = %v, wait = %s, want rejection with 500ms", ok, wait)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("request rejected after the bucket refilled")
+	}
+
+	now = now.Add(rateLimitPruneInterval)
+	l.allow("b")
+	if _, ok := l.buckets["a"]; ok || len(l.buckets) != 1 {
+		t.Errorf("idle bucket not pruned: %d buckets", len(l.buckets))
+	}
+}
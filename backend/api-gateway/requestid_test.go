@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	var gotID string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = r.Header.Get(requestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gw := newAdminTestGateway(t, backend.URL)
+	token := signTestToken(t, testJWTSecret, validClaims("rider"))
+
+	req := httptest.NewRequest(http.MethodGet, "/rides/1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(requestIDHeader, "trace-abc-123")
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	if gotID != "trace-abc-123" || rec.Header().Get(requestIDHeader) != "trace-abc-123" {
+		t.Errorf("incoming ID not passed through: backend got %q, response %q", gotID, rec.Header().Get(requestIDHeader))
+	}
+
+	rec = adminRequest(t, gw, "/rides/1", token)
+	generated := rec.Header().Get(requestIDHeader)
+	if len(generated) != 36 || gotID != generated {
+		t.Errorf("generated ID: backend got %q, response %q, want the same UUID", gotID, generated)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/rides/1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(requestIDHeader, "bad id\nwith newline")
+	rec = httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	if id := rec.Header().Get(requestIDHeader); id == "bad id\nwith newline" || len(id) != 36 {
+		t.Errorf("invalid incoming ID should be replaced, got %q", id)
+	}
+}
+
+func TestErrorResponseIncludesRequestID(t *testing.T) {
+	gw := newAdminTestGateway(t, "http://127.0.0.1:1")
+
+	req := httptest.NewRequest(http.MethodGet, "/rides/1", nil)
+	req.Header.Set(requestIDHeader, "trace-401")
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if rec.Code != http.StatusUnauthorized || resp.RequestID != "trace-401" {
+		t.Errorf("status %d, request_id %q, want 401 with trace-401", rec.Code, resp.RequestID)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 100 * time.Millisecond
+
+	// maxRetryAttempts caps PROXY_RETRY_ATTEMPTS so a flapping backend cannot
+	// hold a request for long
+	maxRetryAttempts = 5
+)
+
+// retryTransport retries idempotent proxied requests (GET and HEAD without a
+// body) that fail with a transport error, 502 or 503, as when a backend has
+// just restarted. Attempt n waits backoff * 2^(n-2) first. Other methods are
+// never retried, so a payment or booking cannot be sent twice.
+//
+// Retrying here rather than from the proxy's ModifyResponse and ErrorHandler
+// keeps those hooks, including the pricing fallback, seeing only the final
+// outcome. Each attempt goes through base, i.e. the circuit breaker and the
+// per-service timeout; an open circuit or a timeout ends the retries.
+type retryTransport struct {
+	base     http.RoundTripper
+	name     string
+	attempts int
+	backoff  time.Duration
+	logger   *log.Logger
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if t.attempts <= 1 || !idempotent || (req.Body != nil && req.Body != http.NoBody) {
+		return t.base.RoundTrip(req)
+	}
+
+	wait := t.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt == t.attempts || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.logger.Printf("[PROXY] %s retry %d/%d in %s after %s request_id=%s", t.name, attempt+1, t.attempts, wait, reason, req.Header.Get(requestIDHeader))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		wait *= 2
+	}
+}
+
+// retryable reports whether a failed attempt is worth repeating: the backend
+// refused or dropped the connection, or answered 502 or 503. Timeouts, an
+// open circuit and requests the client gave up on are final.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, errCircuitOpen) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProxyRetriesIdempotentRequests(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gw := newBreakerTestGateway(t, backend.URL, ProxyConfig{BreakerThreshold: 10, RetryAttempts: 3, RetryBackoff: time.Millisecond})
+
+	rec := adminRequest(t, gw, "/rides/1", signTestToken(t, testJWTSecret, validClaims("rider")))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 after two retries", rec.Code)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("backend hit %d times, want 3", n)
+	}
+}
+
+func TestProxyRetriesGiveUpAfterMaxAttempts(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+	gw := newBreakerTestGateway(t, backend.URL, ProxyConfig{BreakerThreshold: 10, RetryAttempts: 2, RetryBackoff: time.Millisecond})
+
+	if rec := adminRequest(t, gw, "/rides/1", signTestToken(t, testJWTSecret, validClaims("rider"))); rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want the backend's 502", rec.Code)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("backend hit %d times, want 2", n)
+	}
+}
+
+func TestProxyNeverRetriesNonIdempotentRequests(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+	gw := newBreakerTestGateway(t, backend.URL, ProxyConfig{BreakerThreshold: 10, RetryAttempts: 3, RetryBackoff: time.Millisecond})
+	token := signTestToken(t, testJWTSecret, validClaims("rider"))
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		atomic.StoreInt32(&hits, 0)
+		req := httptest.NewRequest(method, "/rides/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		gw.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable || atomic.LoadInt32(&hits) != 1 {
+			t.Errorf("%s: status = %d after %d backend hits, want a single attempt", method, rec.Code, hits)
+		}
+	}
+}
+
+func TestLoadProxyConfigRetry(t *testing.T) {
+	cfg, err := loadProxyConfig()
+	if err != nil || cfg.RetryAttempts != defaultRetryAttempts || cfg.RetryBackoff != defaultRetryBackoff {
+		t.Errorf("defaults: got %d attempts, %s backoff (%v)", cfg.RetryAttempts, cfg.RetryBackoff, err)
+	}
+
+	t.Setenv("PROXY_RETRY_ATTEMPTS", "1")
+	t.Setenv("PROXY_RETRY_BACKOFF", "250ms")
+	if cfg, err := loadProxyConfig(); err != nil || cfg.RetryAttempts != 1 || cfg.RetryBackoff != 250*time.Millisecond {
+		t.Errorf("got %d attempts, %s backoff (%v)", cfg.RetryAttempts, cfg.RetryBackoff, err)
+	}
+
+	for _, v := range []string{"0", "6", "many"} {
+		t.Setenv("PROXY_RETRY_ATTEMPTS", v)
+		if _, err := loadProxyConfig(); err == nil {
+			t.Errorf("PROXY_RETRY_ATTEMPTS=%q: expected an error", v)
+		}
+	}
+	t.Setenv("PROXY_RETRY_ATTEMPTS", "")
+	t.Setenv("PROXY_RETRY_BACKOFF", "soon")
+	if _, err := loadProxyConfig(); err == nil {
+		t.Error("invalid PROXY_RETRY_BACKOFF: expected an error")
+	}
+}
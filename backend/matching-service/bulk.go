@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxBulkDrivers caps the number of drivers per POST /api/v1/drivers/bulk
+const maxBulkDrivers = 1000
+
+// maxBulkDriverBytes caps the request body of a bulk import, generous for
+// maxBulkDrivers drivers
+const maxBulkDriverBytes = 1 << 20
+
+// BulkDriverResult reports the import of the driver at Index in the request
+type BulkDriverResult struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// BulkDriverResponse is the response of POST /api/v1/drivers/bulk
+type BulkDriverResponse struct {
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+	Results  []BulkDriverResult `json:"results"`
+}
+
+// registerDriversBulk registers a JSON array of drivers, e.g. when a
+// partner fleet is onboarded. Each driver is validated as for POST
+// /api/v1/drivers; an invalid one fails on its own and the valid ones are
+// indexed together. If an ID appears more than once, the first occurrence
+// is imported and the others fail.
+func registerDriversBulk(index *SpatialIndex, w http.ResponseWriter, r *http.Request) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBulkDriverBytes)).Decode(&items); err != nil {
+		msg := "request body must be a JSON array of drivers"
+		if errors.Is(err, io.EOF) {
+			msg = "request body is required"
+		}
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "at least one driver is required", http.StatusBadRequest)
+		return
+	}
+	if len(items) > maxBulkDrivers {
+		http.Error(w, fmt.Sprintf("At most %d drivers per request, got %d", maxBulkDrivers, len(items)), http.StatusBadRequest)
+		return
+	}
+
+	resp := BulkDriverResponse{Results: make([]BulkDriverResult, len(items))}
+	valid := make([]Driver, 0, len(items))
+	seen := make(map[string]int, len(items))
+	for i, item := range items {
+		result := &resp.Results[i]
+		result.Index = i
+
+		var d Driver
+		if err := json.Unmarshal(item, &d); err != nil {
+			result.Error = "invalid driver: " + err.Error()
+			continue
+		}
+		result.ID = d.ID
+		if err := validateDriver(d); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		if first, ok := seen[d.ID]; ok {
+			result.Error = fmt.Sprintf("duplicate id, already imported at index %d", first)
+			continue
+		}
+		seen[d.ID] = i
+		valid = append(valid, d)
+		result.OK = true
+	}
+
+	index.RegisterDrivers(valid)
+	resp.Imported = len(valid)
+	resp.Failed = len(items) - len(valid)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRegisterDriversBulk(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_old", berlinMitte, true})
+
+	body := `[
+		{"id": "driver_mitte", "lat": 52.52, "lng": 13.405, "available": true},
+		{"id": "driver_hamburg", "lat": 53.5528, "lng": 10.0067, "available": true, "vehicle_type": "xl"},
+		{"id": "", "lat": 52.52, "lng": 13.405},
+		{"id": "driver_bad_lat", "lat": 91, "lng": 13.405},
+		{"id": "driver_mitte", "lat": 48.1374, "lng": 11.5755, "available": true},
+		{"id": 42},
+		{"id": "driver_old", "lat": 50.1071, "lng": 8.6638, "available": true}
+	]`
+	rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers/bulk", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp BulkDriverResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Imported != 3 || resp.Failed != 4 || len(resp.Results) != 7 {
+		t.Fatalf("summary imported=%d failed=%d results=%d, want 3, 4, 7", resp.Imported, resp.Failed, len(resp.Results))
+	}
+	wantOK := []bool{true, true, false, false, false, false, true}
+	for i, result := range resp.Results {
+		if result.Index != i || result.OK != wantOK[i] || (result.OK == (result.Error != "")) {
+			t.Errorf("result %d = %+v, want ok=%v", i, result, wantOK[i])
+		}
+	}
+	if !strings.Contains(resp.Results[4].Error, "duplicate") {
+		t.Errorf("duplicate id error = %q", resp.Results[4].Error)
+	}
+
+	// The first occurrence of a duplicate id wins, and re-imports replace
+	assertMatch(t, index, berlinMitte, 1.0, "driver_mitte")
+	assertMatch(t, index, frankfurtHbf, 1.0, "driver_old")
+	if d, ok := index.Driver("driver_hamburg"); !ok || d.VehicleType != VehicleXL || d.LastSeen.IsZero() {
+		t.Errorf("driver_hamburg = %+v, %v", d, ok)
+	}
+	if d, _ := index.Driver("driver_mitte"); d.VehicleType != VehicleStandard {
+		t.Errorf("vehicle type = %q, want the standard default", d.VehicleType)
+	}
+}
+
+func TestRegisterDriversBulkLimits(t *testing.T) {
+	index := NewSpatialIndex()
+
+	var drivers []string
+	for i := 0; i <= maxBulkDrivers; i++ {
+		drivers = append(drivers, fmt.Sprintf(`{"id":"d%d","lat":52.52,"lng":13.405}`, i))
+	}
+	tests := map[string]string{
+		"too many":  "[" + strings.Join(drivers, ",") + "]",
+		"empty":     "[]",
+		"no array":  `{"id": "d1", "lat": 52.52, "lng": 13.405}`,
+		"no body":   "",
+		"not json":  "not json",
+		"truncated": "[" + drivers[0],
+	}
+	for name, body := range tests {
+		if rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers/bulk", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+	if n := len(index.drivers); n != 0 {
+		t.Errorf("%d drivers indexed from rejected requests", n)
+	}
+
+	if rec := serveDrivers(t, index, http.MethodGet, "/api/v1/drivers/bulk", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET bulk: status = %d, want 405", rec.Code)
+	}
+
+	rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers/bulk", "["+strings.Join(drivers[:maxBulkDrivers], ",")+"]")
+	if rec.Code != http.StatusOK || len(index.drivers) != maxBulkDrivers {
+		t.Errorf("full batch: status = %d, %d drivers indexed", rec.Code, len(index.drivers))
+	}
+}
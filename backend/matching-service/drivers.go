@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Defaults and limits for GET /api/v1/drivers/nearby
+const (
+	defaultNearbyRadiusKm = 5.0
+	defaultNearbyLimit    = 5
+	maxNearbyLimit        = 50
+)
+
+// Driver returns a snapshot of the indexed driver with the given ID
+func (s *SpatialIndex) Driver(id string) (Driver, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.drivers[id]
+	if !ok {
+		return Driver{}, false
+	}
+	return *d, true
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return !math.IsNaN(lat) && !math.IsNaN(lng) && lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// validateDriver checks a driver posted for registration
+func validateDriver(d Driver) error {
+	if strings.TrimSpace(d.ID) == "" {
+		return errors.New("id is required")
+	}
+	if !validCoordinates(d.Lat, d.Lng) {
+		return errors.New("lat must be within [-90, 90] and lng within [-180, 180]")
+	}
+	if d.VehicleType != "" && !validVehicleType(d.VehicleType) {
+		return fmt.Errorf("vehicle_type must be one of %s, %s, %s", VehicleStandard, VehicleXL, VehicleWheelchair)
+	}
+	if !validDriverRating(d.Rating) {
+		return errors.New("rating must be between 1 and 5")
+	}
+	return nil
+}
+
+// driversHandler serves the driver API:
+//
+//	POST   /api/v1/drivers                    register a driver, or replace an existing one
+//	POST   /api/v1/drivers/bulk               register up to maxBulkDrivers drivers at once
+//	GET    /api/v1/drivers/nearby             nearest available drivers (lat, lng, radius, limit)
+//	GET    /api/v1/drivers/{id}               current state of a driver
+//	DELETE /api/v1/drivers/{id}               deregister a driver
+//	PUT    /api/v1/drivers/{id}/location      move a driver {lat, lng}
+//	PUT    /api/v1/drivers/{id}/availability  go on or off duty {available}
+//	GET    /api/v1/drivers/{id}/stream        WebSocket of location updates, see streamDriverLocation
+func driversHandler(index *SpatialIndex, stream StreamConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/drivers"), "/")
+
+		switch {
+		case path == "" && r.Method == http.MethodPost:
+			registerDriver(index, w, r)
+		case path == "bulk":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			registerDriversBulk(index, w, r)
+		case path == "nearby" && r.Method == http.MethodGet:
+			nearbyDrivers(index, w, r)
+		case path != "" && !strings.Contains(path, "/") && r.Method == http.MethodGet:
+			getDriver(index, w, path)
+		case path != "" && path != "nearby" && !strings.Contains(path, "/") && r.Method == http.MethodDelete:
+			deleteDriver(index, w, path)
+		case strings.HasSuffix(path, "/location") && strings.Count(path, "/") == 1:
+			if r.Method != http.MethodPut {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			updateDriverLocation(index, w, r, strings.TrimSuffix(path, "/location"))
+		case strings.HasSuffix(path, "/availability") && strings.Count(path, "/") == 1:
+			if r.Method != http.MethodPut {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			updateDriverAvailability(index, w, r, strings.TrimSuffix(path, "/availability"))
+		case strings.HasSuffix(path, "/stream") && strings.Count(path, "/") == 1:
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			streamDriverLocation(index, stream, w, r, strings.TrimSuffix(path, "/stream"))
+		case path == "" || !strings.Contains(path, "/"):
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}
+}
+
+// registerDriver indexes the posted driver. Re-posting an existing ID moves
+// it to the cell of the new position.
+func registerDriver(index *SpatialIndex, w http.ResponseWriter, r *http.Request) {
+	var d Driver
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if err := validateDriver(d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	index.RegisterDriver(d)
+	stored, _ := index.Driver(d.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stored)
+}
+
+func getDriver(index *SpatialIndex, w http.ResponseWriter, id string) {
+	d, ok := index.Driver(id)
+	if !ok {
+		http.Error(w, "Driver not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// deleteDriver deregisters a driver so it is never matched again until it
+// registers anew
+func deleteDriver(index *SpatialIndex, w http.ResponseWriter, id string) {
+	if !index.RemoveDriver(id) {
+		http.Error(w, "Driver not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateDriverAvailability takes a driver on or off duty, keeping its
+// registration
+func updateDriverAvailability(index *SpatialIndex, w http.ResponseWriter, r *http.Request, id string) {
+	var req struct {
+		Available *bool `json:"available"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Available == nil {
+		http.Error(w, "available is required", http.StatusBadRequest)
+		return
+	}
+
+	d, ok := index.SetAvailability(id, *req.Available)
+	if !ok {
+		http.Error(w, "Driver not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// updateDriverLocation refreshes a driver's position and last-seen time and
+// re-indexes it in the S2 cell of the new position
+func updateDriverLocation(index *SpatialIndex, w http.ResponseWriter, r *http.Request, id string) {
+	var req struct {
+		Lat *float64 `json:"lat"`
+		Lng *float64 `json:"lng"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Lat == nil || req.Lng == nil || !validCoordinates(*req.Lat, *req.Lng) {
+		http.Error(w, "lat and lng are required; lat must be within [-90, 90] and lng within [-180, 180]", http.StatusBadRequest)
+		return
+	}
+
+	d, ok := index.UpdateLocation(id, *req.Lat, *req.Lng)
+	if !ok {
+		http.Error(w, "Driver not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// nearbyDrivers serves GET /api/v1/drivers/nearby?lat=&lng=&radius=&limit=
+func nearbyDrivers(index *SpatialIndex, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	lat, errLat := strconv.ParseFloat(query.Get("lat"), 64)
+	lng, errLng := strconv.ParseFloat(query.Get("lng"), 64)
+	if errLat != nil || errLng != nil || !validCoordinates(lat, lng) {
+		http.Error(w, "lat and lng are required; lat must be within [-90, 90] and lng within [-180, 180]", http.StatusBadRequest)
+		return
+	}
+
+	radius := defaultNearbyRadiusKm
+	if v := query.Get("radius"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(f > 0 && f <= maxSearchRadiusKm) {
+			http.Error(w, fmt.Sprintf("radius must be a number of kilometres in (0, %g]", maxSearchRadiusKm), http.StatusBadRequest)
+			return
+		}
+		radius = f
+	}
+
+	limit := defaultNearbyLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxNearbyLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxNearbyLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(index.findNearestDrivers(lat, lng, radius, limit, nil))
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveDrivers(t *testing.T, index *SpatialIndex, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	driversHandler(index, defaultStreamConfig())(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestGetDriver(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mitte", berlinMitte, true})
+
+	rec := serveDrivers(t, index, http.MethodGet, "/api/v1/drivers/driver_mitte", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var d Driver
+	if err := json.NewDecoder(rec.Body).Decode(&d); err != nil {
+		t.Fatalf("failed to decode driver: %v", err)
+	}
+	if d.ID != "driver_mitte" || !d.Available || d.Lat != berlinMitte.Lat {
+		t.Errorf("unexpected driver %+v", d)
+	}
+
+	if rec := serveDrivers(t, index, http.MethodGet, "/api/v1/drivers/unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown driver: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestRegisterDriver(t *testing.T) {
+	index := NewSpatialIndex()
+
+	rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "available": true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var d Driver
+	if err := json.NewDecoder(rec.Body).Decode(&d); err != nil {
+		t.Fatalf("failed to decode driver: %v", err)
+	}
+	if d.ID != "driver_new" || d.LastSeen.IsZero() {
+		t.Errorf("unexpected stored driver %+v", d)
+	}
+	assertMatch(t, index, berlinMitte, 5.0, "driver_new")
+}
+
+func TestRegisterDriverRepostMovesCell(t *testing.T) {
+	index := NewSpatialIndex()
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "available": true}`)
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 53.5528, "lng": 10.0067, "available": true}`)
+
+	assertMatch(t, index, hamburgHbf, 5.0, "driver_new")
+	index.LinearFallback = false
+	assertMatch(t, index, berlinMitte, 5.0, "")
+	if n := len(index.cells); n != 1 {
+		t.Errorf("driver indexed in %d cells, want 1", n)
+	}
+}
+
+func TestRegisterDriverValidation(t *testing.T) {
+	index := NewSpatialIndex()
+	for _, body := range []string{
+		`{"id": "", "lat": 52.52, "lng": 13.405}`,
+		`{"id": "d1", "lat": 91, "lng": 13.405}`,
+		`{"id": "d1", "lat": 52.52, "lng": -181}`,
+		`not json`,
+	} {
+		if rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestUpdateDriverLocation(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mobile", berlinMitte, true})
+	before, _ := index.Driver("driver_mobile")
+
+	rec := serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/driver_mobile/location", `{"lat": 53.5528, "lng": 10.0067}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var d Driver
+	if err := json.NewDecoder(rec.Body).Decode(&d); err != nil {
+		t.Fatalf("failed to decode driver: %v", err)
+	}
+	if !d.Available || d.Lat != hamburgHbf.Lat || d.LastSeen.Before(before.LastSeen) {
+		t.Errorf("unexpected driver after move %+v", d)
+	}
+
+	// The old cell entry must be gone, not just shadowed by the new one
+	index.LinearFallback = false
+	assertMatch(t, index, berlinMitte, 5.0, "")
+	assertMatch(t, index, hamburgHbf, 5.0, "driver_mobile")
+	if _, stale := index.cells[cellFor(berlinMitte.Lat, berlinMitte.Lng)]; stale {
+		t.Error("old cell still indexed after the move")
+	}
+}
+
+func TestUpdateDriverLocationErrors(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mobile", berlinMitte, true})
+
+	if rec := serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/unknown/location", `{"lat": 52.5, "lng": 13.4}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown driver: status = %d, want 404", rec.Code)
+	}
+	if rec := serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/driver_mobile/location", `{"lat": 52.5}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing lng: status = %d, want 400", rec.Code)
+	}
+	if rec := serveDrivers(t, index, http.MethodGet, "/api/v1/drivers/driver_mobile/location", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET location: status = %d, want 405", rec.Code)
+	}
+}
+
+func TestNearbyDriversEndpoint(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_mitte", berlinMitte, true},
+		driverFixture{"driver_neukoelln", berlinNeukoelln, true},
+		driverFixture{"driver_hamburg", hamburgHbf, true},
+	)
+
+	rec := serveDrivers(t, index, http.MethodGet, "/api/v1/drivers/nearby?lat=52.5219&lng=13.4132&radius=10&limit=5", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var nearby []DriverDistance
+	if err := json.NewDecoder(rec.Body).Decode(&nearby); err != nil {
+		t.Fatalf("failed to decode drivers: %v", err)
+	}
+	if len(nearby) != 2 || nearby[0].Driver.ID != "driver_mitte" || nearby[1].Driver.ID != "driver_neukoelln" {
+		t.Errorf("unexpected nearby drivers %+v", nearby)
+	}
+
+	for _, query := range []string{"lat=52.5", "lat=52.5&lng=13.4&limit=0", "lat=52.5&lng=13.4&radius=-1", "lat=52.5&lng=13.4&radius=NaN", "lat=52.5&lng=13.4&radius=20000"} {
+		if rec := serveDrivers(t, index, http.MethodGet, "/api/v1/drivers/nearby?"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestRegisterDriverAcceptsPSchein(t *testing.T) {
+	index := NewSpatialIndex()
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "available": true, "p_schein_verified": true}`)
+	if d, _ := index.Driver("driver_new"); !d.PScheinVerified {
+		t.Error("p_schein_verified not stored on registration")
+	}
+
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "available": true}`)
+	if d, _ := index.Driver("driver_new"); d.PScheinVerified {
+		t.Error("re-registration without p_schein_verified kept the old verification")
+	}
+}
+
+func TestRegisterDriverVehicleType(t *testing.T) {
+	index := NewSpatialIndex()
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405}`)
+	if d, _ := index.Driver("driver_new"); d.VehicleType != VehicleStandard {
+		t.Errorf("vehicle_type = %q, want standard by default", d.VehicleType)
+	}
+
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "vehicle_type": "wheelchair"}`)
+	if d, _ := index.Driver("driver_new"); d.VehicleType != VehicleWheelchair {
+		t.Errorf("vehicle_type = %q, want wheelchair", d.VehicleType)
+	}
+
+	rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "vehicle_type": "limousine"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown vehicle type: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestDeleteDriver(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_mitte", berlinMitte, true},
+		driverFixture{"driver_alex", berlinAlexanderplatz, true},
+	)
+
+	if rec := serveDrivers(t, index, http.MethodDelete, "/api/v1/drivers/driver_mitte", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveDrivers(t, index, http.MethodDelete, "/api/v1/drivers/driver_mitte", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want 404", rec.Code)
+	}
+	if _, ok := index.Driver("driver_mitte"); ok {
+		t.Error("deregistered driver is still known")
+	}
+
+	// Neither the cell lookup nor the linear fallback may return it
+	assertMatch(t, index, berlinMitte, 5.0, "driver_alex")
+	index.RemoveDriver("driver_alex")
+	assertMatch(t, index, berlinMitte, 5.0, "")
+	if nearby := index.findNearestDrivers(berlinMitte.Lat, berlinMitte.Lng, 5.0, 5, nil); len(nearby) != 0 {
+		t.Errorf("nearby drivers after deregistration: %+v", nearby)
+	}
+	if len(index.cells) != 0 {
+		t.Errorf("%d cells still indexed", len(index.cells))
+	}
+}
+
+func TestUpdateDriverAvailability(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mitte", berlinMitte, true})
+
+	rec := serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/driver_mitte/availability", `{"available": false}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"available":false`) {
+		t.Fatalf("off duty: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	assertMatch(t, index, berlinMitte, 5.0, "")
+	if _, indexed := index.cells[cellFor(berlinMitte.Lat, berlinMitte.Lng)]; indexed {
+		t.Error("off-duty driver still indexed in its cell")
+	}
+
+	serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/driver_mitte/availability", `{"available": true}`)
+	index.LinearFallback = false
+	assertMatch(t, index, berlinMitte, 5.0, "driver_mitte")
+
+	if rec := serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/unknown/availability", `{"available": true}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown driver: status = %d, want 404", rec.Code)
+	}
+	if rec := serveDrivers(t, index, http.MethodPut, "/api/v1/drivers/driver_mitte/availability", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing available: status = %d, want 400", rec.Code)
+	}
+}
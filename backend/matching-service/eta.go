@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+)
+
+// Ranking modes for POST /match?rank_by=
+const (
+	RankByDistance = "distance"
+	RankByETA      = "eta"
+	RankByScore    = "score"
+)
+
+// etaCandidates is how many of the nearest drivers are ranked by ETA. The
+// nearest by road is almost always among the nearest few in a straight line.
+const etaCandidates = 5
+
+// defaultUrbanSpeedKmh is the average driving speed assumed by
+// speedETAProvider, typical for German inner-city traffic
+const defaultUrbanSpeedKmh = 25.0
+
+// ETAProvider estimates how long a driver needs to reach the rider. A
+// routing engine (OSRM, Valhalla or a maps API) plugs in here to account for
+// rivers, one-way streets and traffic.
+type ETAProvider interface {
+	ETA(ctx context.Context, fromLat, fromLng, toLat, toLng float64) (time.Duration, error)
+}
+
+// speedETAProvider is the stub ETAProvider: the straight-line distance at a
+// fixed average speed. It ranks drivers exactly like distance does.
+type speedETAProvider struct {
+	SpeedKmh float64
+}
+
+func (p speedETAProvider) ETA(ctx context.Context, fromLat, fromLng, toLat, toLng float64) (time.Duration, error) {
+	hours := distance.HaversineKm(fromLat, fromLng, toLat, toLng) / p.SpeedKmh
+	return time.Duration(hours * float64(time.Hour)), nil
+}
+
+// loadETAProvider returns the stub provider with the speed from
+// MATCHING_URBAN_SPEED_KMH
+func loadETAProvider() (ETAProvider, error) {
+	speed := defaultUrbanSpeedKmh
+	if v := os.Getenv("MATCHING_URBAN_SPEED_KMH"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("MATCHING_URBAN_SPEED_KMH must be a positive number, got %q", v)
+		}
+		speed = f
+	}
+	return speedETAProvider{SpeedKmh: speed}, nil
+}
+
+// rankedDriver is a match candidate with its ETA, if one could be
+// estimated, and its matchScore when ranked by score
+type rankedDriver struct {
+	DriverDistance
+	ETA    time.Duration
+	HasETA bool
+	Score  float64
+}
+
+// etaMinutes is the ETA rounded to a tenth of a minute for responses
+func (c rankedDriver) etaMinutes() float64 {
+	return math.Round(c.ETA.Minutes()*10) / 10
+}
+
+// rankByETA orders candidates by ETA, then distance, then ID. Candidates
+// whose ETA cannot be estimated keep their distance order after all others.
+func rankByETA(ctx context.Context, eta ETAProvider, riderLat, riderLng float64, candidates []DriverDistance) []rankedDriver {
+	ranked := make([]rankedDriver, 0, len(candidates))
+	for _, c := range candidates {
+		d, err := eta.ETA(ctx, c.Driver.Lat, c.Driver.Lng, riderLat, riderLng)
+		ranked = append(ranked, rankedDriver{DriverDistance: c, ETA: d, HasETA: err == nil})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.HasETA != b.HasETA {
+			return a.HasETA
+		}
+		if a.HasETA && a.ETA != b.ETA {
+			return a.ETA < b.ETA
+		}
+		if a.DistanceKm != b.DistanceKm {
+			return a.DistanceKm < b.DistanceKm
+		}
+		return a.Driver.ID < b.Driver.ID
+	})
+	return ranked
+}
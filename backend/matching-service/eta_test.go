@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fixedETAProvider returns a fixed ETA per driver location, e.g. a driver
+// across the Spree who is near in a straight line but far by road
+type fixedETAProvider map[location]time.Duration
+
+func (p fixedETAProvider) ETA(ctx context.Context, fromLat, fromLng, toLat, toLng float64) (time.Duration, error) {
+	for at, d := range p {
+		if at.Lat == fromLat && at.Lng == fromLng {
+			return d, nil
+		}
+	}
+	return 0, context.DeadlineExceeded
+}
+
+func serveMatchWithETA(t *testing.T, index *SpatialIndex, eta ETAProvider, query string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/match"+query, strings.NewReader(`{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`))
+	matchHandler(index, NewExclusionStore(), NewAuditLogger(), newMatchMetrics(prometheus.NewRegistry(), index), eta, defaultMatchConfig())(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestSpeedETAProvider(t *testing.T) {
+	eta, _ := speedETAProvider{SpeedKmh: 30}.ETA(context.Background(), berlinMitte.Lat, berlinMitte.Lng, berlinNeukoelln.Lat, berlinNeukoelln.Lng)
+
+	want := time.Duration(distanceKm(berlinMitte, berlinNeukoelln) / 30 * float64(time.Hour))
+	if eta != want {
+		t.Errorf("ETA = %s, want %s", eta, want)
+	}
+}
+
+func TestLoadETAProvider(t *testing.T) {
+	if p, err := loadETAProvider(); err != nil || p != (speedETAProvider{SpeedKmh: defaultUrbanSpeedKmh}) {
+		t.Errorf("unset: got %+v (%v), want the default speed", p, err)
+	}
+	t.Setenv("MATCHING_URBAN_SPEED_KMH", "18")
+	if p, err := loadETAProvider(); err != nil || p != (speedETAProvider{SpeedKmh: 18}) {
+		t.Errorf("got %+v (%v), want 18 km/h", p, err)
+	}
+	for _, v := range []string{"0", "-5", "slow", "NaN", "Inf"} {
+		t.Setenv("MATCHING_URBAN_SPEED_KMH", v)
+		if _, err := loadETAProvider(); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
+
+func TestMatchRanksByETA(t *testing.T) {
+	across := offsetKm(berlinMitte, 0.5, 0)
+	downTheRoad := offsetKm(berlinMitte, 0, 1.5)
+	newIndex := func() *SpatialIndex {
+		index := NewSpatialIndex()
+		index.RegisterDriver(Driver{ID: "driver_across_river", Lat: across.Lat, Lng: across.Lng, Available: true, PScheinVerified: true})
+		index.RegisterDriver(Driver{ID: "driver_down_the_road", Lat: downTheRoad.Lat, Lng: downTheRoad.Lng, Available: true, PScheinVerified: true})
+		return index
+	}
+	eta := fixedETAProvider{across: 10 * time.Minute, downTheRoad: 4 * time.Minute}
+
+	if _, body := serveMatchWithETA(t, newIndex(), eta, ""); !strings.Contains(body, `"driver_id":"driver_across_river"`) || !strings.Contains(body, `"eta_minutes":10`) {
+		t.Errorf("default ranking: %s, want the nearest driver with its ETA", body)
+	}
+	if _, body := serveMatchWithETA(t, newIndex(), eta, "?rank_by=eta"); !strings.Contains(body, `"driver_id":"driver_down_the_road"`) || !strings.Contains(body, `"eta_minutes":4`) {
+		t.Errorf("rank_by=eta: %s, want the driver with the shorter ETA", body)
+	}
+}
+
+func TestMatchRankByETAFallsBackToDistanceWithoutETA(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_mitte", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true, PScheinVerified: true})
+	index.RegisterDriver(Driver{ID: "driver_alex", Lat: berlinAlexanderplatz.Lat, Lng: berlinAlexanderplatz.Lng, Available: true, PScheinVerified: true})
+
+	code, body := serveMatchWithETA(t, index, fixedETAProvider{}, "?rank_by=eta")
+	if code != http.StatusOK || !strings.Contains(body, `"driver_id":"driver_mitte"`) || strings.Contains(body, "eta_minutes") {
+		t.Errorf("got %d %s, want the nearest driver without an ETA", code, body)
+	}
+}
+
+func TestMatchRejectsUnknownRanking(t *testing.T) {
+	if code, _ := serveMatchWithETA(t, NewSpatialIndex(), speedETAProvider{SpeedKmh: defaultUrbanSpeedKmh}, "?rank_by=time"); code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", code)
+	}
+}
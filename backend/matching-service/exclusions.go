@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// ExclusionStore keeps per-rider lists of drivers the rider does not want to
+// be matched with again, e.g. after a bad experience.
+//
+// Precedence: exclusions are applied after the availability filter and
+// before the radius and nearest-driver selection. An excluded driver is never
+// returned, even if it is the only or the nearest available driver; matching
+// then falls through to the next nearest driver within the radius. Stored
+// exclusions and the exclude_driver_ids of a match request are combined.
+type ExclusionStore struct {
+	mu sync.RWMutex
+	// byRider maps rider ID -> driver ID -> expiry. A zero expiry never expires.
+	byRider map[string]map[string]time.Time
+}
+
+// DriverExclusion is the API representation of a stored exclusion
+type DriverExclusion struct {
+	RiderID   string     `json:"rider_id"`
+	DriverID  string     `json:"driver_id"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func NewExclusionStore() *ExclusionStore {
+	return &ExclusionStore{byRider: make(map[string]map[string]time.Time)}
+}
+
+// Add excludes driverID for riderID until expiresAt. A zero expiresAt keeps the
+// exclusion until it is removed. Adding an existing exclusion replaces its expiry.
+func (s *ExclusionStore) Add(riderID, driverID string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drivers, ok := s.byRider[riderID]
+	if !ok {
+		drivers = make(map[string]time.Time)
+		s.byRider[riderID] = drivers
+	}
+	drivers[driverID] = expiresAt
+}
+
+// Remove deletes a stored exclusion
+func (s *ExclusionStore) Remove(riderID, driverID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byRider[riderID], driverID)
+	if len(s.byRider[riderID]) == 0 {
+		delete(s.byRider, riderID)
+	}
+}
+
+// Excluded returns the set of drivers excluded for riderID at now. Expired
+// entries are skipped here and dropped on the next List.
+func (s *ExclusionStore) Excluded(riderID string, now time.Time) map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	excluded := make(map[string]bool, len(s.byRider[riderID]))
+	for driverID, expiresAt := range s.byRider[riderID] {
+		if expiresAt.IsZero() || now.Before(expiresAt) {
+			excluded[driverID] = true
+		}
+	}
+	return excluded
+}
+
+// List returns the active exclusions for riderID and prunes expired ones
+func (s *ExclusionStore) List(riderID string, now time.Time) []DriverExclusion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []DriverExclusion{}
+	for driverID, expiresAt := range s.byRider[riderID] {
+		if !expiresAt.IsZero() && !now.Before(expiresAt) {
+			delete(s.byRider[riderID], driverID)
+			continue
+		}
+		exclusion := DriverExclusion{RiderID: riderID, DriverID: driverID}
+		if !expiresAt.IsZero() {
+			expires := expiresAt
+			exclusion.ExpiresAt = &expires
+		}
+		list = append(list, exclusion)
+	}
+	return list
+}
+
+// exclusionsHandler serves /exclusions:
+//
+//	GET    /exclusions?rider_id=R                 list active exclusions
+//	POST   /exclusions {rider_id, driver_id, expires_in_hours}
+//	DELETE /exclusions?rider_id=R&driver_id=D
+func exclusionsHandler(store *ExclusionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			riderID := r.URL.Query().Get("rider_id")
+			if riderID == "" {
+				http.Error(w, "rider_id is required", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(store.List(riderID, timeutil.Now()))
+
+		case http.MethodPost:
+			var req struct {
+				RiderID  string `json:"rider_id"`
+				DriverID string `json:"driver_id"`
+				// ExpiresInHours is optional; 0 keeps the exclusion until removed
+				ExpiresInHours float64 `json:"expires_in_hours"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+			if req.RiderID == "" || req.DriverID == "" || req.ExpiresInHours < 0 {
+				http.Error(w, "rider_id and driver_id are required, expires_in_hours must not be negative", http.StatusBadRequest)
+				return
+			}
+
+			exclusion := DriverExclusion{RiderID: req.RiderID, DriverID: req.DriverID}
+			var expiresAt time.Time
+			if req.ExpiresInHours > 0 {
+				expiresAt = timeutil.Now().Add(time.Duration(req.ExpiresInHours * float64(time.Hour)))
+				exclusion.ExpiresAt = &expiresAt
+			}
+			store.Add(req.RiderID, req.DriverID, expiresAt)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(exclusion)
+
+		case http.MethodDelete:
+			riderID, driverID := r.URL.Query().Get("rider_id"), r.URL.Query().Get("driver_id")
+			if riderID == "" || driverID == "" {
+				http.Error(w, "rider_id and driver_id are required", http.StatusBadRequest)
+				return
+			}
+			store.Remove(riderID, driverID)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindMatchSkipsExcludedNearestDriver(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_mitte", berlinMitte, true},
+		driverFixture{"driver_neukoelln", berlinNeukoelln, true},
+	)
+
+	driver, _ := index.findMatchExcluding(berlinAlexanderplatz.Lat, berlinAlexanderplatz.Lng, 10.0, map[string]bool{"driver_mitte": true})
+	if driver == nil || driver.ID != "driver_neukoelln" {
+		t.Errorf("got %v, want driver_neukoelln", driver)
+	}
+
+	// With the only driver in range excluded there is no match.
+	driver, _ = index.findMatchExcluding(berlinAlexanderplatz.Lat, berlinAlexanderplatz.Lng, 2.0, map[string]bool{"driver_mitte": true})
+	if driver != nil {
+		t.Errorf("got %s, want no match", driver.ID)
+	}
+}
+
+func TestExclusionStoreExpiry(t *testing.T) {
+	store := NewExclusionStore()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Add("rider_1", "driver_permanent", time.Time{})
+	store.Add("rider_1", "driver_temporary", now.Add(time.Hour))
+
+	if excluded := store.Excluded("rider_1", now); !excluded["driver_permanent"] || !excluded["driver_temporary"] {
+		t.Errorf("expected both drivers excluded, got %v", excluded)
+	}
+
+	later := now.Add(2 * time.Hour)
+	if excluded := store.Excluded("rider_1", later); !excluded["driver_permanent"] || excluded["driver_temporary"] {
+		t.Errorf("expected only the permanent exclusion after expiry, got %v", excluded)
+	}
+	if list := store.List("rider_1", later); len(list) != 1 || list[0].DriverID != "driver_permanent" {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	// Exclusions are per rider.
+	if excluded := store.Excluded("rider_2", now); len(excluded) != 0 {
+		t.Errorf("rider_2 has exclusions %v", excluded)
+	}
+
+	store.Remove("rider_1", "driver_permanent")
+	if excluded := store.Excluded("rider_1", now); excluded["driver_permanent"] {
+		t.Error("removed exclusion still applied")
+	}
+}
+
+func TestExclusionsHandler(t *testing.T) {
+	store := NewExclusionStore()
+	handler := exclusionsHandler(store)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/exclusions",
+		strings.NewReader(`{"rider_id": "rider_1", "driver_id": "driver_mitte", "expires_in_hours": 24}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/exclusions?rider_id=rider_1", nil))
+	var list []DriverExclusion
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(list) != 1 || list[0].DriverID != "driver_mitte" || list[0].ExpiresAt == nil {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodDelete, "/exclusions?rider_id=rider_1&driver_id=driver_mitte", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d", rec.Code)
+	}
+	if excluded := store.Excluded("rider_1", time.Now()); len(excluded) != 0 {
+		t.Errorf("exclusion not removed: %v", excluded)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/exclusions", strings.NewReader(`{"rider_id": "rider_1"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing driver_id: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestLinearFallbackNotNeededOutsideImmediateRing(t *testing.T) {
+	// The ring search reaches drivers beyond the rider's edge neighbours, so
+	// the fallback is left for a corrupted cell index.
+	driverAt := offsetKm(berlinMitte, 2.0, 0)
+
+	index := newTestIndex(t, driverFixture{"driver_outside_ring", driverAt, true})
+	assertMatch(t, index, berlinMitte, 10.0, "driver_outside_ring")
+	if got := index.Fallbacks(); got != 0 {
+		t.Errorf("fallbacks = %d, want 0", got)
+	}
+}
+
+func TestLinearFallbackOnCorruptedCellIndex(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mitte", berlinMitte, true})
+
+	// Simulate the S2 path losing the driver, e.g. after indexing under a
+	// cell computed from malformed coordinates.
+	index.mu.Lock()
+	index.cells = make(map[s2.CellID]map[string]*Driver)
+	index.mu.Unlock()
+
+	assertMatch(t, index, berlinAlexanderplatz, 5.0, "driver_mitte")
+	if got := index.Fallbacks(); got != 1 {
+		t.Errorf("fallbacks = %d, want 1", got)
+	}
+}
+
+func TestLinearFallbackOnDriverIndexedUnderWrongCell(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mitte", berlinMitte, true})
+
+	// The driver's entry sits under a cell far from its position
+	wrong := cellFor(potsdam.Lat, potsdam.Lng)
+	index.mu.Lock()
+	d := index.drivers["driver_mitte"]
+	delete(index.cells, d.cell)
+	d.cell = wrong
+	index.cells[wrong] = map[string]*Driver{d.ID: d}
+	index.mu.Unlock()
+
+	assertMatch(t, index, berlinAlexanderplatz, 5.0, "driver_mitte")
+	if got := index.Fallbacks(); got != 1 {
+		t.Errorf("fallbacks = %d, want 1", got)
+	}
+}
+
+func TestLinearFallbackNotUsedWithoutDriversNearby(t *testing.T) {
+	// An ordinary "no driver nearby": the index is intact, so the covered
+	// rings are conclusive and no linear scan is needed.
+	index := newTestIndex(t, driverFixture{"driver_potsdam", potsdam, true})
+
+	assertMatch(t, index, berlinMitte, 5.0, "")
+	if got := index.Fallbacks(); got != 0 {
+		t.Errorf("fallbacks = %d, want 0", got)
+	}
+}
+
+func TestLinearFallbackCountedOncePerMatchRequest(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mitte", berlinMitte, true})
+	index.mu.Lock()
+	index.cells = make(map[s2.CellID]map[string]*Driver)
+	index.mu.Unlock()
+
+	// The driver has no verified P-Schein, so the match fails and
+	// noMatchMessage searches twice more to explain why.
+	resp := serveMatch(t, index, "", `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`)
+	if resp.Success || !strings.Contains(resp.Message, "P-Schein") {
+		t.Fatalf("expected an unverified P-Schein miss, got %+v", resp)
+	}
+	if got := index.Fallbacks(); got != 1 {
+		t.Errorf("fallbacks = %d, want 1 for one request", got)
+	}
+}
+
+func TestLinearFallbackNotUsedWhenCellsHaveCandidates(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_mitte", berlinMitte, true},
+		driverFixture{"driver_potsdam", potsdam, true},
+	)
+
+	assertMatch(t, index, berlinMitte, 50.0, "driver_mitte")
+	if got := index.Fallbacks(); got != 0 {
+		t.Errorf("fallbacks = %d, want 0", got)
+	}
+}
+
+func TestLinearFallbackNotUsedOnEmptyIndex(t *testing.T) {
+	index := newTestIndex(t)
+
+	assertMatch(t, index, berlinMitte, 10.0, "")
+	if got := index.Fallbacks(); got != 0 {
+		t.Errorf("fallbacks = %d, want 0", got)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+)
+
+// location is a named coordinate used to place riders and drivers in tests.
+type location struct {
+	Name string
+	Lat  float64
+	Lng  float64
+}
+
+// Well-known German locations used as test fixtures.
+var (
+	berlinAlexanderplatz = location{"Berlin Alexanderplatz", 52.5219, 13.4132}
+	berlinMitte          = location{"Berlin Mitte", 52.5200, 13.4050}
+	berlinNeukoelln      = location{"Berlin Neukölln", 52.4800, 13.4200}
+	berlinSpandau        = location{"Berlin Spandau", 52.5351, 13.1975}
+	potsdam              = location{"Potsdam", 52.3906, 13.0645}
+	hamburgHbf           = location{"Hamburg Hbf", 53.5528, 10.0067}
+	munichMarienplatz    = location{"München Marienplatz", 48.1374, 11.5755}
+	frankfurtHbf         = location{"Frankfurt Hbf", 50.1071, 8.6638}
+)
+
+// driverFixture describes a driver to be placed into a test index.
+type driverFixture struct {
+	ID        string
+	At        location
+	Available bool
+}
+
+// newTestIndex returns a SpatialIndex populated with the given drivers.
+func newTestIndex(t *testing.T, drivers ...driverFixture) *SpatialIndex {
+	t.Helper()
+	index := NewSpatialIndex()
+	for _, d := range drivers {
+		index.UpdateDriver(d.ID, d.At.Lat, d.At.Lng, d.Available)
+	}
+	return index
+}
+
+// offsetKm returns a location displaced from origin by the given north/east
+// distances in kilometres (small-distance approximation).
+func offsetKm(origin location, northKm, eastKm float64) location {
+	const kmPerDegLat = 111.32
+	kmPerDegLng := kmPerDegLat * math.Cos(origin.Lat*math.Pi/180)
+	return location{
+		Name: origin.Name + " (offset)",
+		Lat:  origin.Lat + northKm/kmPerDegLat,
+		Lng:  origin.Lng + eastKm/kmPerDegLng,
+	}
+}
+
+// distanceKm returns the great-circle distance between two fixtures using the
+// same Earth radius as the matching code.
+func distanceKm(a, b location) float64 {
+	return distance.HaversineKm(a.Lat, a.Lng, b.Lat, b.Lng)
+}
+
+// assertMatch fails the test unless the nearest driver to rider within
+// radiusKm is wantID. An empty wantID asserts that no driver is found.
+func assertMatch(t *testing.T, index *SpatialIndex, rider location, radiusKm float64, wantID string) {
+	t.Helper()
+	driver, dist := index.findMatch(rider.Lat, rider.Lng, radiusKm)
+	if wantID == "" {
+		if driver != nil {
+			t.Fatalf("expected no driver near %s within %.1fkm, got %s at %.3fkm", rider.Name, radiusKm, driver.ID, dist)
+		}
+		return
+	}
+	if driver == nil {
+		t.Fatalf("expected driver %s near %s within %.1fkm, got none", wantID, rider.Name, radiusKm)
+	}
+	if driver.ID != wantID {
+		t.Fatalf("expected driver %s near %s, got %s at %.3fkm", wantID, rider.Name, driver.ID, dist)
+	}
+}
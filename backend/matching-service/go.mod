@@ -2,4 +2,25 @@ module github.com/khiamazizi2802-design/ride-share-platform-germany/backend/matc
 
 go 1.21
 
-require github.com/golang/geo v0.0.0-20230701194427-e16545b63ce3
+require (
+	github.com/golang/geo v0.0.0-20230421003525-6adc56603217
+	github.com/gorilla/websocket v1.5.3
+	github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
+	github.com/lib/pq v1.10.9
+	github.com/prometheus/client_golang v1.17.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.4 // indirect
+	github.com/prometheus/client_model v0.4.1-0.20230718164431-9a2bf3000d16 // indirect
+	github.com/prometheus/common v0.44.0 // indirect
+	github.com/prometheus/procfs v0.11.1 // indirect
+	golang.org/x/sys v0.11.0 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+)
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
@@ -8,93 +8,514 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/geo/s2"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// AuditLogger handles compliant logging for German regulations (GDPR, audit trails)
+// AuditLogger handles compliant logging for German regulations (GDPR, audit trails).
+// Timestamps follow the platform policy in timeutil (UTC, RFC 3339).
 type AuditLogger struct {
 	logger *log.Logger
 }
 
 func NewAuditLogger() *AuditLogger {
 	return &AuditLogger{
-		logger: log.New(os.Stdout, "[AUDIT] ", log.LstdFlags|log.Lmicroseconds|log.LUTC),
+		logger: timeutil.NewLogger(os.Stdout, "[AUDIT] ", 0),
 	}
 }
 
 func (a *AuditLogger) LogMatchRequest(riderID, sessionID string, lat, lng float64) {
-	a.logger.Printf("MATCH_REQUEST rider_id=%s session_id=%s lat=%.6f lng=%.6f timestamp=%s", riderID, sessionID, lat, lng, time.Now().UTC().Format(time.RFC3339))
+	a.logger.Printf("MATCH_REQUEST rider_id=%s session_id=%s lat=%.6f lng=%.6f timestamp=%s", riderID, sessionID, lat, lng, timeutil.Format(timeutil.Now()))
 }
 
 func (a *AuditLogger) LogMatchResult(riderID, driverID, sessionID string, distance float64, success bool) {
-	a.logger.Printf("MATCH_RESULT rider_id=%s driver_id=%s session_id=%s distance_km=%.3f success=%t timestamp=%s", riderID, driverID, sessionID, distance, success, time.Now().UTC().Format(time.RFC3339))
+	a.logger.Printf("MATCH_RESULT rider_id=%s driver_id=%s session_id=%s distance_km=%.3f success=%t timestamp=%s", riderID, driverID, sessionID, distance, success, timeutil.Format(timeutil.Now()))
 }
 
 func (a *AuditLogger) LogError(action, riderID, sessionID, errMsg string) {
-	a.logger.Printf("ERROR action=%s rider_id=%s session_id=%s error=%s timestamp=%s", action, riderID, sessionID, errMsg, time.Now().UTC().Format(time.RFC3339))
+	a.logger.Printf("ERROR action=%s rider_id=%s session_id=%s error=%s timestamp=%s", action, riderID, sessionID, errMsg, timeutil.Format(timeutil.Now()))
 }
 
 // Driver represents a real-time driver state
 type Driver struct {
-	ID        string
-	Lat       float64
-	Lng       float64
-	Available bool
-	LastSeen  time.Time
+	ID        string    `json:"id"`
+	Lat       float64   `json:"lat"`
+	Lng       float64   `json:"lng"`
+	Available bool      `json:"available"`
+	LastSeen  time.Time `json:"last_seen"`
+	// PScheinVerified is set for drivers whose passenger transport licence
+	// (Personenbeförderungsschein) has been verified
+	PScheinVerified bool `json:"p_schein_verified"`
+	// VehicleType is one of the Vehicle* constants
+	VehicleType string `json:"vehicle_type"`
+	// Rating is the driver's average rider rating (1-5); 0 if not yet rated
+	Rating float64 `json:"rating,omitempty"`
+
+	// cell is the S2 cell the driver is currently indexed under
+	cell s2.CellID
+}
+
+// Vehicle types a driver can offer and a rider can ask for
+const (
+	VehicleStandard   = "standard"
+	VehicleXL         = "xl"
+	VehicleWheelchair = "wheelchair"
+)
+
+func validVehicleType(t string) bool {
+	switch t {
+	case VehicleStandard, VehicleXL, VehicleWheelchair:
+		return true
+	}
+	return false
 }
 
-// SpatialIndex manages real-time geospatial driver tracking using S2
+// cellLevel is the S2 level used to bucket drivers. Level-13 cells are
+// roughly 1km across.
+const cellLevel = 13
+
+// SpatialIndex manages real-time geospatial driver tracking using S2. Drivers
+// are bucketed by their level-13 cell; a match searches outward from the
+// rider's cell in rings of neighbouring cells until the radius is covered.
 type SpatialIndex struct {
 	mu      sync.RWMutex
 	drivers map[string]*Driver
+	cells   map[s2.CellID]map[string]*Driver
+
+	// LinearFallback scans all drivers when the cell lookup finds no
+	// candidates and cannot be trusted: the rider's cell is invalid or the
+	// cell index has lost drivers.
+	LinearFallback bool
+	fallbackLog    *log.Logger
+	fallbacks      uint64
+
+	// Store, if set, receives every driver change after it is indexed, see
+	// store.go. The index stays the source of truth for matching.
+	Store    DriverStore
+	storeLog *log.Logger
 }
 
 func NewSpatialIndex() *SpatialIndex {
 	return &SpatialIndex{
-		drivers: make(map[string]*Driver),
+		drivers:        make(map[string]*Driver),
+		cells:          make(map[s2.CellID]map[string]*Driver),
+		LinearFallback: true,
+		fallbackLog:    timeutil.NewLogger(os.Stdout, "[MATCHING] ", 0),
+		storeLog:       timeutil.NewLogger(os.Stdout, "[STORE] ", 0),
 	}
 }
 
+func cellFor(lat, lng float64) s2.CellID {
+	return s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)).Parent(cellLevel)
+}
+
+// UpdateDriver moves a driver and sets its availability. A known driver
+// keeps its P-Schein verification and vehicle type; a new one gets a
+// standard vehicle.
 func (s *SpatialIndex) UpdateDriver(id string, lat, lng float64, available bool) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.drivers[id] = &Driver{
-		ID:        id,
-		Lat:       lat,
-		Lng:       lng,
-		Available: available,
-		LastSeen:  time.Now(),
+	d := Driver{ID: id, Lat: lat, Lng: lng, Available: available, VehicleType: VehicleStandard}
+	if old, ok := s.drivers[id]; ok {
+		d.PScheinVerified = old.PScheinVerified
+		d.VehicleType = old.VehicleType
+		d.Rating = old.Rating
+	}
+	stored := s.updateDriverLocked(d)
+	s.mu.Unlock()
+
+	s.persist(stored)
+}
+
+// RegisterDriver indexes d, replacing any driver with the same ID. A driver
+// without a vehicle type gets a standard vehicle.
+func (s *SpatialIndex) RegisterDriver(d Driver) {
+	s.mu.Lock()
+	if d.VehicleType == "" {
+		d.VehicleType = VehicleStandard
+	}
+	stored := s.updateDriverLocked(d)
+	s.mu.Unlock()
+
+	s.persist(stored)
+}
+
+// RegisterDrivers indexes all of ds under a single write lock, as
+// RegisterDriver does for one driver
+func (s *SpatialIndex) RegisterDrivers(ds []Driver) {
+	s.mu.Lock()
+	stored := make([]Driver, 0, len(ds))
+	for _, d := range ds {
+		if d.VehicleType == "" {
+			d.VehicleType = VehicleStandard
+		}
+		stored = append(stored, s.updateDriverLocked(d))
+	}
+	s.mu.Unlock()
+
+	for _, d := range stored {
+		s.persist(d)
+	}
+}
+
+// UpdateLocation moves a known driver, keeping its availability. It reports
+// false if the driver is not indexed.
+func (s *SpatialIndex) UpdateLocation(id string, lat, lng float64) (Driver, bool) {
+	s.mu.Lock()
+	old, ok := s.drivers[id]
+	if !ok {
+		s.mu.Unlock()
+		return Driver{}, false
+	}
+	d := *old
+	d.Lat, d.Lng = lat, lng
+	stored := s.updateDriverLocked(d)
+	s.mu.Unlock()
+
+	s.persist(stored)
+	return stored, true
+}
+
+// SetAvailability takes a known driver on or off duty without forgetting it.
+// An available driver is indexed in the cell of its last position; an
+// unavailable one is removed from the cells. It reports false if the driver
+// is not indexed.
+func (s *SpatialIndex) SetAvailability(id string, available bool) (Driver, bool) {
+	s.mu.Lock()
+	old, ok := s.drivers[id]
+	if !ok {
+		s.mu.Unlock()
+		return Driver{}, false
+	}
+	var stored Driver
+	if !available {
+		old.Available = false
+		s.unindexLocked(old)
+		stored = *old
+	} else {
+		d := *old
+		d.Available = true
+		stored = s.updateDriverLocked(d)
 	}
+	s.mu.Unlock()
+
+	s.persist(stored)
+	return stored, true
+}
+
+// MarkStale takes a driver off duty if it has not been seen since lastSeen,
+// e.g. after its location stream disconnected. It reports whether the driver
+// was marked stale.
+func (s *SpatialIndex) MarkStale(id string, lastSeen time.Time) bool {
+	s.mu.Lock()
+	d, ok := s.drivers[id]
+	if !ok || d.LastSeen.After(lastSeen) || !d.Available {
+		s.mu.Unlock()
+		return false
+	}
+	d.Available = false
+	s.unindexLocked(d)
+	stale := *d
+	s.mu.Unlock()
+
+	s.persist(stale)
+	return true
+}
+
+// RemoveDriver deregisters a driver, e.g. at the end of a shift. It reports
+// false if the driver is not indexed.
+func (s *SpatialIndex) RemoveDriver(id string) bool {
+	s.mu.Lock()
+	d, ok := s.drivers[id]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+	s.unindexLocked(d)
+	delete(s.drivers, id)
+	s.mu.Unlock()
+
+	s.forget(id)
+	return true
+}
+
+// unindexLocked removes d from the cell it is indexed under. Callers must
+// hold s.mu.
+func (s *SpatialIndex) unindexLocked(d *Driver) {
+	if _, ok := s.cells[d.cell][d.ID]; ok {
+		delete(s.cells[d.cell], d.ID)
+	}
+	if len(s.cells[d.cell]) == 0 {
+		delete(s.cells, d.cell)
+	}
+}
+
+// updateDriverLocked stores d as the driver's new state, stamped with the
+// current time, re-indexes it and returns a snapshot of the stored driver.
+// Callers must hold s.mu.
+func (s *SpatialIndex) updateDriverLocked(d Driver) Driver {
+	d.LastSeen = timeutil.Now()
+	s.indexDriverLocked(d)
+	return *s.drivers[d.ID]
+}
+
+// indexDriverLocked stores d as the driver's state and indexes it in the cell
+// of its position. The old cell entry is removed using the cell stored with
+// the driver, i.e. computed from its current indexed position, never from
+// the incoming one. Callers must hold s.mu.
+func (s *SpatialIndex) indexDriverLocked(d Driver) {
+	if old, ok := s.drivers[d.ID]; ok {
+		s.unindexLocked(old)
+	}
+
+	d.cell = cellFor(d.Lat, d.Lng)
+	s.drivers[d.ID] = &d
+	if s.cells[d.cell] == nil {
+		s.cells[d.cell] = make(map[string]*Driver)
+	}
+	s.cells[d.cell][d.ID] = &d
+}
+
+// cellsLostDriversLocked reports whether an available driver is missing
+// from the cell of its position, so the ring search cannot reach it.
+// Callers must hold s.mu.
+func (s *SpatialIndex) cellsLostDriversLocked() bool {
+	for _, d := range s.drivers {
+		if d.Available && (d.cell != cellFor(d.Lat, d.Lng) || s.cells[d.cell][d.ID] != d) {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableDrivers returns the number of drivers currently available
+func (s *SpatialIndex) AvailableDrivers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for _, d := range s.drivers {
+		if d.Available {
+			n++
+		}
+	}
+	return n
+}
+
+// Fallbacks returns the number of requests that used the linear fallback
+func (s *SpatialIndex) Fallbacks() uint64 {
+	return atomic.LoadUint64(&s.fallbacks)
+}
+
+// recordFallback counts and logs one request whose search used the linear
+// fallback, however many searches it ran
+func (s *SpatialIndex) recordFallback(riderLat, riderLng float64) {
+	s.mu.RLock()
+	drivers := len(s.drivers)
+	s.mu.RUnlock()
+
+	atomic.AddUint64(&s.fallbacks, 1)
+	s.fallbackLog.Printf("FALLBACK linear_scan lat=%.6f lng=%.6f cell_valid=%t drivers=%d", riderLat, riderLng, cellFor(riderLat, riderLng).IsValid(), drivers)
+}
+
+// ReserveDriver marks an available driver as taken and removes it from its
+// cell so concurrent matches cannot hand it out again. It reports false if
+// the driver is unknown or was already taken. The next update of the driver
+// re-indexes it.
+func (s *SpatialIndex) ReserveDriver(driverID string) bool {
+	s.mu.Lock()
+	d, ok := s.drivers[driverID]
+	if !ok || !d.Available {
+		s.mu.Unlock()
+		return false
+	}
+	d.Available = false
+	s.unindexLocked(d)
+	reserved := *d
+	s.mu.Unlock()
+
+	s.persist(reserved)
+	return true
 }
 
 // findMatch implements the core matching algorithm
 func (s *SpatialIndex) findMatch(riderLat, riderLng float64, radiusKm float64) (*Driver, float64) {
+	return s.findMatchExcluding(riderLat, riderLng, radiusKm, nil)
+}
+
+// MatchFilter restricts which available drivers a match may return
+type MatchFilter struct {
+	// Excluded drivers are never returned
+	Excluded map[string]bool
+	// RequirePSchein skips drivers without a verified P-Schein
+	RequirePSchein bool
+	// VehicleType, if set, skips drivers with another vehicle type
+	VehicleType string
+}
+
+func (f MatchFilter) allows(d *Driver) bool {
+	if f.Excluded[d.ID] {
+		return false
+	}
+	if f.VehicleType != "" && d.VehicleType != f.VehicleType {
+		return false
+	}
+	return !f.RequirePSchein || d.PScheinVerified
+}
+
+// findMatchExcluding is findMatch but never returns a driver in excluded
+func (s *SpatialIndex) findMatchExcluding(riderLat, riderLng float64, radiusKm float64, excluded map[string]bool) (*Driver, float64) {
+	return s.findMatchFiltered(riderLat, riderLng, radiusKm, MatchFilter{Excluded: excluded})
+}
+
+// findMatchFiltered is findMatch restricted to drivers the filter allows
+func (s *SpatialIndex) findMatchFiltered(riderLat, riderLng float64, radiusKm float64, filter MatchFilter) (*Driver, float64) {
+	s.mu.RLock()
+	nearest, fellBack := s.nearestLocked(riderLat, riderLng, radiusKm, 1, filter)
+	s.mu.RUnlock()
+
+	if fellBack {
+		s.recordFallback(riderLat, riderLng)
+	}
+	if len(nearest) == 0 {
+		return nil, radiusKm
+	}
+	return nearest[0].driver, nearest[0].distanceKm
+}
+
+// DriverDistance is a candidate driver with its distance from the rider
+type DriverDistance struct {
+	Driver     Driver  `json:"driver"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// findNearestDrivers returns up to n available drivers within radiusKm,
+// nearest first. Drivers at the same distance are ordered by ID.
+func (s *SpatialIndex) findNearestDrivers(riderLat, riderLng float64, radiusKm float64, n int, excluded map[string]bool) []DriverDistance {
+	return s.findNearestFiltered(riderLat, riderLng, radiusKm, n, MatchFilter{Excluded: excluded})
+}
+
+// findNearestFiltered is findNearestDrivers restricted to drivers the filter
+// allows
+func (s *SpatialIndex) findNearestFiltered(riderLat, riderLng float64, radiusKm float64, n int, filter MatchFilter) []DriverDistance {
+	nearby, fellBack := s.searchNearest(riderLat, riderLng, radiusKm, n, filter)
+	if fellBack {
+		s.recordFallback(riderLat, riderLng)
+	}
+	return nearby
+}
+
+// searchNearest is findNearestFiltered for callers that run several searches
+// per request. It reports whether the linear fallback was used instead of
+// recording it, so the request can be counted once.
+func (s *SpatialIndex) searchNearest(riderLat, riderLng float64, radiusKm float64, n int, filter MatchFilter) ([]DriverDistance, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	riderLatLng := s2.LatLngFromDegrees(riderLat, riderLng)
-	var bestDriver *Driver
-	minDist := radiusKm
+	found, fellBack := s.nearestLocked(riderLat, riderLng, radiusKm, n, filter)
+	nearby := []DriverDistance{}
+	for _, c := range found {
+		nearby = append(nearby, DriverDistance{Driver: *c.driver, DistanceKm: c.distanceKm})
+	}
+	return nearby, fellBack
+}
 
-	for _, d := range s.drivers {
-		if !d.Available {
-			continue
+type candidate struct {
+	driver     *Driver
+	distanceKm float64
+}
+
+// minCellWidthKm is the minimum width of a cellLevel cell. After k rings
+// around the rider's cell every unsearched cell is at least k times this
+// far from the rider.
+var minCellWidthKm = s2.MinWidthMetric.Value(cellLevel) * distance.EarthRadiusKm
+
+// nearestLocked returns up to n available drivers the filter allows within
+// radiusKm, sorted by distance. It searches the rider's cell, then rings of
+// neighbouring cells (CellID.AllNeighbors) at increasing range. The search
+// stops once n drivers are known to be nearer than every unsearched cell, or
+// the searched rings cover radiusKm. If the rings yield nothing and the
+// rider's cell is invalid or the cell index has lost drivers, the linear
+// fallback scans all drivers; the second result reports whether it did.
+// Callers must hold s.mu.
+func (s *SpatialIndex) nearestLocked(riderLat, riderLng float64, radiusKm float64, n int, filter MatchFilter) ([]candidate, bool) {
+	eligible := func(d *Driver) bool { return d.Available && filter.allows(d) }
+
+	found := []candidate{}
+	consider := func(d *Driver) {
+		if !eligible(d) {
+			return
+		}
+		if dist := distanceToKm(riderLat, riderLng, d); dist < radiusKm {
+			found = append(found, candidate{driver: d, distanceKm: dist})
 		}
+	}
+	sortFound := func() {
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].distanceKm != found[j].distanceKm {
+				return found[i].distanceKm < found[j].distanceKm
+			}
+			return found[i].driver.ID < found[j].driver.ID
+		})
+	}
+
+	riderCell := cellFor(riderLat, riderLng)
+	if riderCell.IsValid() {
+		visited := map[s2.CellID]bool{riderCell: true}
+		ring := []s2.CellID{riderCell}
+		for k := 0; len(ring) > 0; k++ {
+			for _, cell := range ring {
+				for _, d := range s.cells[cell] {
+					consider(d)
+				}
+			}
+			sortFound()
 
-		// Calculate Haversine distance using S2
-		driverLatLng := s2.LatLngFromDegrees(d.Lat, d.Lng)
-		dist := riderLatLng.Distance(driverLatLng).Radians() * 6371.0 // Earth radius in km
+			covered := float64(k) * minCellWidthKm
+			if covered >= radiusKm || (len(found) >= n && found[n-1].distanceKm <= covered) {
+				break
+			}
 
-		if dist < minDist {
-			minDist = dist
-			bestDriver = d
+			next := []s2.CellID{}
+			for _, cell := range ring {
+				for _, neighbour := range cell.AllNeighbors(cellLevel) {
+					if !visited[neighbour] {
+						visited[neighbour] = true
+						next = append(next, neighbour)
+					}
+				}
+			}
+			ring = next
 		}
 	}
 
-	return bestDriver, minDist
+	fellBack := len(found) == 0 && len(s.drivers) > 0 && s.LinearFallback &&
+		(!riderCell.IsValid() || s.cellsLostDriversLocked())
+	if fellBack {
+		for _, d := range s.drivers {
+			consider(d)
+		}
+		sortFound()
+	}
+
+	if len(found) > n {
+		found = found[:n]
+	}
+	return found, fellBack
+}
+
+// distanceToKm is the distance from the rider to d, computed with the
+// platform's shared great-circle formula so matching and trip validation
+// agree near radius cutoffs
+func distanceToKm(riderLat, riderLng float64, d *Driver) float64 {
+	return distance.HaversineKm(riderLat, riderLng, d.Lat, d.Lng)
 }
 
 type MatchRequest struct {
@@ -102,34 +523,99 @@ type MatchRequest struct {
 	SessionID string  `json:"session_id"`
 	Lat       float64 `json:"lat"`
 	Lng       float64 `json:"lng"`
+	// ExcludeDriverIDs are skipped for this request in addition to the
+	// rider's stored exclusions
+	ExcludeDriverIDs []string `json:"exclude_driver_ids,omitempty"`
+	// VehicleType is the vehicle the rider needs; standard if omitted
+	VehicleType string `json:"vehicle_type,omitempty"`
+	// MaxRadiusKm narrows the search radius for this request. It is capped
+	// at the configured maximum radius.
+	MaxRadiusKm *float64 `json:"max_radius_km,omitempty"`
 }
 
 type MatchResponse struct {
 	Success  bool    `json:"success"`
 	DriverID string  `json:"driver_id,omitempty"`
 	Distance float64 `json:"distance_km,omitempty"`
-	Message  string  `json:"message,omitempty"`
+	// ETAMinutes is the estimated time for the driver to reach the rider
+	ETAMinutes float64 `json:"eta_minutes,omitempty"`
+	Message    string  `json:"message,omitempty"`
 }
 
-func main() {
-	audit := NewAuditLogger()
-	index := NewSpatialIndex()
+// defaultMatchRadiusKm is the radius within which /match looks for a driver
+// unless MATCH_MAX_RADIUS_KM is set
+const defaultMatchRadiusKm = 5.0
 
-	// Mock data for demonstration
-	index.UpdateDriver("driver_berlin_01", 52.5200, 13.4050, true)  // Mitte
-	index.UpdateDriver("driver_berlin_02", 52.5300, 13.3800, true)  // Wedding
-	index.UpdateDriver("driver_berlin_03", 52.4800, 13.4200, true)  // Neukölln
+// maxSearchRadiusKm bounds every driver search, /match and /nearby alike.
+// nearestLocked visits every cell covering the radius, so the cost of a
+// search grows with the square of the radius.
+const maxSearchRadiusKm = 50.0
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+// MatchConfig configures /match. MaxRadiusKm is the largest radius searched
+// and the default for requests that set none; Score weighs rank_by=score.
+type MatchConfig struct {
+	MaxRadiusKm float64
+	Score       ScoreWeights
+}
+
+func defaultMatchConfig() MatchConfig {
+	return MatchConfig{MaxRadiusKm: defaultMatchRadiusKm, Score: defaultScoreWeights()}
+}
 
-	http.HandleFunc("/match", func(w http.ResponseWriter, r *http.Request) {
+// loadMatchConfig reads MATCH_MAX_RADIUS_KM and the score weights
+func loadMatchConfig() (MatchConfig, error) {
+	cfg := defaultMatchConfig()
+	if v := os.Getenv("MATCH_MAX_RADIUS_KM"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(f > 0 && f <= maxSearchRadiusKm) {
+			return cfg, fmt.Errorf("MATCH_MAX_RADIUS_KM must be a number of kilometres in (0, %g], got %q", maxSearchRadiusKm, v)
+		}
+		cfg.MaxRadiusKm = f
+	}
+	weights, err := loadScoreWeights()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Score = weights
+	return cfg, nil
+}
+
+// matchHandler serves POST /match. Only drivers with the requested vehicle
+// type and, unless the request sets require_pschein=false, a verified
+// P-Schein are matched, within cfg.MaxRadiusKm or the smaller max_radius_km
+// of the request. Drivers are ranked by straight-line distance, with
+// rank_by=eta by the ETA of the nearest etaCandidates, or with rank_by=score
+// by the matchScore of the nearest scoreCandidates. The matched driver is
+// reserved; if a concurrent match took it first, the next best candidate is
+// tried.
+func matchHandler(index *SpatialIndex, exclusions *ExclusionStore, audit *AuditLogger, metrics *matchMetrics, eta ETAProvider, cfg MatchConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		started := time.Now()
+		metrics.requests.Inc()
+
+		filter := MatchFilter{RequirePSchein: true}
+		if v := r.URL.Query().Get("require_pschein"); v != "" {
+			required, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "require_pschein must be true or false", http.StatusBadRequest)
+				return
+			}
+			filter.RequirePSchein = required
+		}
+
+		rankBy := r.URL.Query().Get("rank_by")
+		switch rankBy {
+		case "":
+			rankBy = RankByDistance
+		case RankByDistance, RankByETA, RankByScore:
+		default:
+			http.Error(w, fmt.Sprintf("rank_by must be %s, %s or %s", RankByDistance, RankByETA, RankByScore), http.StatusBadRequest)
+			return
+		}
 
 		var req MatchRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -138,26 +624,183 @@ func main() {
 			return
 		}
 
+		if req.VehicleType == "" {
+			req.VehicleType = VehicleStandard
+		}
+		if !validVehicleType(req.VehicleType) {
+			http.Error(w, fmt.Sprintf("vehicle_type must be one of %s, %s, %s", VehicleStandard, VehicleXL, VehicleWheelchair), http.StatusBadRequest)
+			return
+		}
+		filter.VehicleType = req.VehicleType
+
+		radiusKm := cfg.MaxRadiusKm
+		if req.MaxRadiusKm != nil {
+			if *req.MaxRadiusKm <= 0 || math.IsNaN(*req.MaxRadiusKm) {
+				http.Error(w, "max_radius_km must be greater than 0", http.StatusBadRequest)
+				return
+			}
+			radiusKm = math.Min(*req.MaxRadiusKm, cfg.MaxRadiusKm)
+		}
+
 		audit.LogMatchRequest(req.RiderID, req.SessionID, req.Lat, req.Lng)
 
-		// Find closest driver within 5km
-		driver, dist := index.findMatch(req.Lat, req.Lng, 5.0)
+		filter.Excluded = exclusions.Excluded(req.RiderID, timeutil.Now())
+		for _, id := range req.ExcludeDriverIDs {
+			filter.Excluded[id] = true
+		}
 
-		resp := MatchResponse{Success: driver != nil}
-		if driver != nil {
-			resp.DriverID = driver.ID
-			resp.Distance = dist
+		n := 1
+		switch rankBy {
+		case RankByETA:
+			n = etaCandidates
+		case RankByScore:
+			n = scoreCandidates
+		}
+		var (
+			matched  *rankedDriver
+			fellBack bool
+		)
+		for matched == nil {
+			candidates, usedFallback := index.searchNearest(req.Lat, req.Lng, radiusKm, n, filter)
+			fellBack = fellBack || usedFallback
+			if len(candidates) == 0 {
+				break
+			}
+			var ranked []rankedDriver
+			switch rankBy {
+			case RankByETA:
+				ranked = rankByETA(r.Context(), eta, req.Lat, req.Lng, candidates)
+			case RankByScore:
+				ranked = rankByScore(candidates, radiusKm, cfg.Score)
+			default:
+				for _, c := range candidates {
+					ranked = append(ranked, rankedDriver{DriverDistance: c})
+				}
+			}
+			for i := range ranked {
+				if index.ReserveDriver(ranked[i].Driver.ID) {
+					matched = &ranked[i]
+					break
+				}
+				filter.Excluded[ranked[i].Driver.ID] = true
+			}
+		}
+
+		metrics.observeMatch(matched != nil, started)
+
+		resp := MatchResponse{Success: matched != nil}
+		if matched != nil {
+			if !matched.HasETA {
+				d, err := eta.ETA(r.Context(), matched.Driver.Lat, matched.Driver.Lng, req.Lat, req.Lng)
+				matched.ETA, matched.HasETA = d, err == nil
+			}
+			resp.DriverID = matched.Driver.ID
+			resp.Distance = matched.DistanceKm
+			if matched.HasETA {
+				resp.ETAMinutes = matched.etaMinutes()
+			}
 			resp.Message = "Driver found and dispatched"
-			audit.LogMatchResult(req.RiderID, driver.ID, req.SessionID, dist, true)
+			audit.LogMatchResult(req.RiderID, matched.Driver.ID, req.SessionID, matched.DistanceKm, true)
 		} else {
-			resp.Message = "No drivers available within 5km"
+			message, usedFallback := noMatchMessage(index, req.Lat, req.Lng, radiusKm, filter)
+			fellBack = fellBack || usedFallback
+			resp.Message = message
 			audit.LogMatchResult(req.RiderID, "", req.SessionID, 0, false)
 		}
+		if fellBack {
+			index.recordFallback(req.Lat, req.Lng)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// noMatchMessage explains a failed match. If relaxing one criterion of
+// filter would have found a driver, the message names that criterion. It
+// reports whether any of its searches used the linear fallback.
+func noMatchMessage(index *SpatialIndex, lat, lng, radiusKm float64, filter MatchFilter) (string, bool) {
+	anyVehicle := filter
+	anyVehicle.VehicleType = ""
+	found, fellBack := index.searchNearest(lat, lng, radiusKm, 1, anyVehicle)
+	if len(found) > 0 {
+		return fmt.Sprintf("No %s vehicles available within %gkm; nearby drivers have other vehicle types", filter.VehicleType, radiusKm), fellBack
+	}
+	if filter.RequirePSchein {
+		unverified := filter
+		unverified.RequirePSchein = false
+		found, usedFallback := index.searchNearest(lat, lng, radiusKm, 1, unverified)
+		fellBack = fellBack || usedFallback
+		if len(found) > 0 {
+			return fmt.Sprintf("No drivers with a verified P-Schein within %gkm; available drivers nearby have not been verified", radiusKm), fellBack
+		}
+	}
+	return fmt.Sprintf("No drivers available within %gkm", radiusKm), fellBack
+}
+
+func main() {
+	audit := NewAuditLogger()
+	index := NewSpatialIndex()
+	if v := os.Getenv("MATCHING_LINEAR_FALLBACK"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("MATCHING_LINEAR_FALLBACK must be true or false, got %q", v)
+		}
+		index.LinearFallback = enabled
+	}
+	eta, err := loadETAProvider()
+	if err != nil {
+		log.Fatalf("Invalid ETA configuration: %v", err)
+	}
+	matchConfig, err := loadMatchConfig()
+	if err != nil {
+		log.Fatalf("Invalid match configuration: %v", err)
+	}
+	streamConfig, err := loadStreamConfig()
+	if err != nil {
+		log.Fatalf("Invalid driver stream configuration: %v", err)
+	}
+	exclusions := NewExclusionStore()
+	registry := prometheus.NewRegistry()
+	metrics := newMatchMetrics(registry, index)
+
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		store, err := openPostgresDriverStore(dsn)
+		if err != nil {
+			log.Fatalf("Failed to open driver database: %v", err)
+		}
+		defer store.Close()
+		index.Store = store
+		n, err := index.LoadDrivers(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to load drivers: %v", err)
+		}
+		log.Printf("Storing drivers in PostgreSQL, %d loaded", n)
+	} else {
+		log.Println("WARNING: DATABASE_URL not set, drivers are kept in memory and lost on restart.")
+
+		// Mock data for demonstration
+		index.RegisterDriver(Driver{ID: "driver_berlin_01", Lat: 52.5200, Lng: 13.4050, Available: true, PScheinVerified: true}) // Mitte
+		index.RegisterDriver(Driver{ID: "driver_berlin_02", Lat: 52.5300, Lng: 13.3800, Available: true, PScheinVerified: true}) // Wedding
+		index.RegisterDriver(Driver{ID: "driver_berlin_03", Lat: 52.4800, Lng: 13.4200, Available: true, PScheinVerified: true}) // Neukölln
+	}
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
 	})
 
+	http.HandleFunc("/version", buildinfo.Handler("matching-service"))
+
+	http.HandleFunc("/exclusions", exclusionsHandler(exclusions))
+
+	http.HandleFunc("/api/v1/drivers", driversHandler(index, streamConfig))
+	http.HandleFunc("/api/v1/drivers/", driversHandler(index, streamConfig))
+
+	http.HandleFunc("/match", matchHandler(index, exclusions, audit, metrics, eta, matchConfig))
+
+	http.Handle("/metrics", metricsHandler(registry))
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
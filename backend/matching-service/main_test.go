@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+)
+
+func TestFindMatchReturnsNearestDriver(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_mitte", berlinMitte, true},
+		driverFixture{"driver_neukoelln", berlinNeukoelln, true},
+		driverFixture{"driver_spandau", berlinSpandau, true},
+	)
+
+	assertMatch(t, index, berlinAlexanderplatz, 5.0, "driver_mitte")
+}
+
+func TestFindMatchReportsDistance(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_neukoelln", berlinNeukoelln, true})
+
+	driver, dist := index.findMatch(berlinMitte.Lat, berlinMitte.Lng, 10.0)
+	if driver == nil {
+		t.Fatal("expected a driver to be found")
+	}
+
+	want := distanceKm(berlinMitte, berlinNeukoelln)
+	if math.Abs(dist-want) > 0.001 {
+		t.Errorf("distance = %.4fkm, want %.4fkm", dist, want)
+	}
+	// Mitte to Neukölln is roughly 4.5km as the crow flies.
+	if dist < 4.0 || dist > 5.0 {
+		t.Errorf("distance %.3fkm outside plausible range for Mitte-Neukölln", dist)
+	}
+}
+
+func TestFindMatchSkipsUnavailableDrivers(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_busy", berlinMitte, false},
+		driverFixture{"driver_free", berlinNeukoelln, true},
+	)
+
+	assertMatch(t, index, berlinAlexanderplatz, 10.0, "driver_free")
+}
+
+func TestFindMatchRespectsRadius(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_potsdam", potsdam, true})
+
+	assertMatch(t, index, berlinMitte, 5.0, "")
+	assertMatch(t, index, berlinMitte, 30.0, "driver_potsdam")
+}
+
+func TestFindMatchEmptyIndex(t *testing.T) {
+	index := newTestIndex(t)
+
+	assertMatch(t, index, frankfurtHbf, 10.0, "")
+}
+
+func TestFindMatchAcrossCellBoundary(t *testing.T) {
+	// Place rider and driver a few metres apart on opposite sides of a
+	// level-13 S2 cell vertex so they fall into different cells.
+	const level = 13
+	cellID := s2.CellIDFromLatLng(s2.LatLngFromDegrees(berlinMitte.Lat, berlinMitte.Lng)).Parent(level)
+	vertex := s2.LatLngFromPoint(s2.CellFromCellID(cellID).Vertex(0))
+	corner := location{"cell vertex", vertex.Lat.Degrees(), vertex.Lng.Degrees()}
+
+	rider := offsetKm(corner, 0.01, 0.01)
+	driverAt := offsetKm(corner, -0.01, -0.01)
+
+	riderCell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(rider.Lat, rider.Lng)).Parent(level)
+	driverCell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(driverAt.Lat, driverAt.Lng)).Parent(level)
+	if riderCell == driverCell {
+		t.Fatalf("fixture error: rider and driver share cell %v", riderCell)
+	}
+
+	index := newTestIndex(t, driverFixture{"driver_boundary", driverAt, true})
+	assertMatch(t, index, rider, 1.0, "driver_boundary")
+}
+
+func TestFindMatchBeyondImmediateCellRing(t *testing.T) {
+	// A driver ~2km away sits outside the rider's level-13 cell and its
+	// edge neighbours. A cell-ring based search must still find them as long
+	// as they are within the requested radius.
+	const level = 13
+	driverAt := offsetKm(berlinMitte, 2.0, 0)
+
+	riderCell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(berlinMitte.Lat, berlinMitte.Lng)).Parent(level)
+	driverCell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(driverAt.Lat, driverAt.Lng)).Parent(level)
+	ring := map[s2.CellID]bool{riderCell: true}
+	for _, n := range riderCell.EdgeNeighbors() {
+		ring[n] = true
+	}
+	if ring[driverCell] {
+		t.Fatalf("fixture error: driver cell %v is inside the immediate ring", driverCell)
+	}
+
+	index := newTestIndex(t, driverFixture{"driver_outside_ring", driverAt, true})
+	index.LinearFallback = false
+	assertMatch(t, index, berlinMitte, 10.0, "driver_outside_ring")
+}
+
+func TestFindMatchTwoCellRingsAway(t *testing.T) {
+	riderCell := cellFor(berlinMitte.Lat, berlinMitte.Lng)
+	first := map[s2.CellID]bool{riderCell: true}
+	for _, n := range riderCell.AllNeighbors(cellLevel) {
+		first[n] = true
+	}
+	var driverCell s2.CellID
+	for _, n := range riderCell.AllNeighbors(cellLevel) {
+		for _, nn := range n.AllNeighbors(cellLevel) {
+			if !first[nn] {
+				driverCell = nn
+			}
+		}
+	}
+	if driverCell == 0 {
+		t.Fatal("fixture error: no cell two rings away")
+	}
+	centre := driverCell.LatLng()
+	driverAt := location{"two rings away", centre.Lat.Degrees(), centre.Lng.Degrees()}
+
+	index := newTestIndex(t, driverFixture{"driver_two_rings", driverAt, true})
+	index.LinearFallback = false
+	assertMatch(t, index, berlinMitte, 10.0, "driver_two_rings")
+	if got := index.Fallbacks(); got != 0 {
+		t.Errorf("fallbacks = %d, want 0", got)
+	}
+
+	// The rings stop once they cover the radius
+	assertMatch(t, index, berlinMitte, 0.5, "")
+}
+
+func TestUpdateDriverReindexesOnMove(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mobile", munichMarienplatz, true})
+	assertMatch(t, index, munichMarienplatz, 5.0, "driver_mobile")
+
+	index.UpdateDriver("driver_mobile", hamburgHbf.Lat, hamburgHbf.Lng, true)
+
+	assertMatch(t, index, munichMarienplatz, 5.0, "")
+	assertMatch(t, index, hamburgHbf, 5.0, "driver_mobile")
+}
+
+func TestUpdateDriverTogglesAvailability(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_toggle", berlinMitte, true})
+
+	index.UpdateDriver("driver_toggle", berlinMitte.Lat, berlinMitte.Lng, false)
+	assertMatch(t, index, berlinMitte, 5.0, "")
+
+	index.UpdateDriver("driver_toggle", berlinMitte.Lat, berlinMitte.Lng, true)
+	assertMatch(t, index, berlinMitte, 5.0, "driver_toggle")
+}
+
+func TestConcurrentUpdateAndFindMatch(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_anchor", berlinMitte, true})
+
+	const workers = 32
+	const iterations = 200
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				at := offsetKm(berlinMitte, float64(i%10)*0.1, float64(w%10)*0.1)
+				index.UpdateDriver(fmt.Sprintf("driver_%d", w), at.Lat, at.Lng, i%2 == 0)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if driver, _ := index.findMatch(berlinMitte.Lat, berlinMitte.Lng, 5.0); driver == nil {
+					t.Error("expected the anchor driver to always be matchable")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	if got := len(index.drivers); got != workers+1 {
+		t.Errorf("index holds %d drivers, want %d", got, workers+1)
+	}
+}
+
+func TestFindNearestDriversSortedAndLimited(t *testing.T) {
+	index := newTestIndex(t,
+		driverFixture{"driver_far", offsetKm(berlinMitte, 0.6, 0), true},
+		driverFixture{"driver_near", offsetKm(berlinMitte, 0.2, 0), true},
+		driverFixture{"driver_mid", offsetKm(berlinMitte, 0, 0.4), true},
+		driverFixture{"driver_busy", offsetKm(berlinMitte, 0.1, 0), false},
+	)
+
+	got := index.findNearestDrivers(berlinMitte.Lat, berlinMitte.Lng, 5.0, 2, nil)
+	if len(got) != 2 || got[0].Driver.ID != "driver_near" || got[1].Driver.ID != "driver_mid" {
+		t.Fatalf("unexpected nearest drivers %+v", got)
+	}
+	if got[0].DistanceKm > got[1].DistanceKm {
+		t.Errorf("drivers not sorted by distance: %+v", got)
+	}
+
+	all := index.findNearestDrivers(berlinMitte.Lat, berlinMitte.Lng, 5.0, 10, nil)
+	if len(all) != 3 {
+		t.Errorf("got %d drivers, want the 3 available ones", len(all))
+	}
+
+	// The single-result match agrees with the head of the list
+	assertMatch(t, index, berlinMitte, 5.0, "driver_near")
+}
+
+// TestHaversineAgreesWithS2Distance documents how the shared Haversine
+// formula compares with S2's angular distance on the same sphere. Both are
+// great-circle distances, so they differ only by rounding; neither models
+// road distance, which is typically 20-40% longer.
+func TestHaversineAgreesWithS2Distance(t *testing.T) {
+	pairs := []struct{ a, b location }{
+		{berlinMitte, berlinAlexanderplatz},
+		{berlinMitte, potsdam},
+		{berlinMitte, hamburgHbf},
+		{berlinMitte, munichMarienplatz},
+		{hamburgHbf, munichMarienplatz},
+	}
+	for _, p := range pairs {
+		haversine := distance.HaversineKm(p.a.Lat, p.a.Lng, p.b.Lat, p.b.Lng)
+		s2Km := s2.LatLngFromDegrees(p.a.Lat, p.a.Lng).Distance(s2.LatLngFromDegrees(p.b.Lat, p.b.Lng)).Radians() * distance.EarthRadiusKm
+		if diff := math.Abs(haversine - s2Km); diff > 1e-6*s2Km+1e-9 {
+			t.Errorf("%s to %s: haversine %.6fkm, s2 %.6fkm", p.a.Name, p.b.Name, haversine, s2Km)
+		}
+		t.Logf("%s to %s: %.3fkm", p.a.Name, p.b.Name, haversine)
+	}
+}
+
+func TestMatchDistanceUsesSharedFormula(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_potsdam", potsdam, true})
+	_, dist := index.findMatch(berlinMitte.Lat, berlinMitte.Lng, 50.0)
+	if want := distance.HaversineKm(berlinMitte.Lat, berlinMitte.Lng, potsdam.Lat, potsdam.Lng); dist != want {
+		t.Errorf("match distance = %v, want %v", dist, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestMatchHandler(index *SpatialIndex) http.HandlerFunc {
+	return matchHandler(index, NewExclusionStore(), NewAuditLogger(), newMatchMetrics(prometheus.NewRegistry(), index), speedETAProvider{SpeedKmh: defaultUrbanSpeedKmh}, defaultMatchConfig())
+}
+
+func serveMatch(t *testing.T, index *SpatialIndex, query, body string) MatchResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/match"+query, strings.NewReader(body))
+	newTestMatchHandler(index)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp MatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode match response: %v", err)
+	}
+	return resp
+}
+
+func TestMatchRequiresVerifiedPSchein(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_unverified", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true})
+	index.RegisterDriver(Driver{ID: "driver_verified", Lat: berlinNeukoelln.Lat, Lng: berlinNeukoelln.Lng, Available: true, PScheinVerified: true})
+
+	body := `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`
+	resp := serveMatch(t, index, "", body)
+	if !resp.Success || resp.DriverID != "driver_verified" {
+		t.Errorf("got %+v, want the verified driver although the unverified one is nearer", resp)
+	}
+
+	resp = serveMatch(t, index, "?require_pschein=false", body)
+	if resp.DriverID != "driver_unverified" {
+		t.Errorf("require_pschein=false: got %+v, want the nearest driver", resp)
+	}
+}
+
+func TestMatchExplainsPScheinFilter(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_unverified", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true})
+
+	resp := serveMatch(t, index, "", `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`)
+	if resp.Success || !strings.Contains(resp.Message, "P-Schein") {
+		t.Errorf("got %+v, want a message naming the P-Schein filter", resp)
+	}
+
+	empty := serveMatch(t, NewSpatialIndex(), "", `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`)
+	if empty.Success || strings.Contains(empty.Message, "P-Schein") {
+		t.Errorf("no drivers at all: got %+v", empty)
+	}
+}
+
+func TestMatchRejectsInvalidRequirePSchein(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/match?require_pschein=maybe", strings.NewReader(`{}`))
+	newTestMatchHandler(NewSpatialIndex())(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestUpdateDriverKeepsPSchein(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_1", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true, PScheinVerified: true})
+
+	index.UpdateDriver("driver_1", berlinNeukoelln.Lat, berlinNeukoelln.Lng, false)
+	if d, _ := index.Driver("driver_1"); !d.PScheinVerified {
+		t.Error("P-Schein verification lost on update")
+	}
+}
+
+func TestReserveDriver(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_mitte", berlinMitte, true})
+
+	if !index.ReserveDriver("driver_mitte") {
+		t.Fatal("first reservation failed")
+	}
+	if index.ReserveDriver("driver_mitte") {
+		t.Error("driver reserved twice")
+	}
+	if index.ReserveDriver("unknown") {
+		t.Error("reserved an unknown driver")
+	}
+	assertMatch(t, index, berlinMitte, 5.0, "")
+
+	index.UpdateDriver("driver_mitte", berlinMitte.Lat, berlinMitte.Lng, true)
+	assertMatch(t, index, berlinMitte, 5.0, "driver_mitte")
+}
+
+func TestConcurrentMatchesNeverShareADriver(t *testing.T) {
+	const drivers, riders = 10, 50
+
+	index := NewSpatialIndex()
+	for i := 0; i < drivers; i++ {
+		at := offsetKm(berlinMitte, 0.1*float64(i), 0)
+		index.RegisterDriver(Driver{ID: fmt.Sprintf("driver_%02d", i), Lat: at.Lat, Lng: at.Lng, Available: true, PScheinVerified: true})
+	}
+	handler := newTestMatchHandler(index)
+
+	var wg sync.WaitGroup
+	results := make(chan MatchResponse, riders)
+	for i := 0; i < riders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			body := fmt.Sprintf(`{"rider_id": "rider_%02d", "lat": 52.5200, "lng": 13.4050}`, i)
+			handler(rec, httptest.NewRequest(http.MethodPost, "/match", strings.NewReader(body)))
+			var resp MatchResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			results <- resp
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	assigned := map[string]int{}
+	for resp := range results {
+		if resp.Success {
+			assigned[resp.DriverID]++
+		}
+	}
+	for id, n := range assigned {
+		if n > 1 {
+			t.Errorf("%s handed out %d times", id, n)
+		}
+	}
+	if len(assigned) != drivers {
+		t.Errorf("%d drivers assigned, want all %d", len(assigned), drivers)
+	}
+}
+
+func TestMatchFiltersByVehicleType(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_standard", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true, PScheinVerified: true})
+	index.RegisterDriver(Driver{ID: "driver_xl", Lat: berlinNeukoelln.Lat, Lng: berlinNeukoelln.Lng, Available: true, PScheinVerified: true, VehicleType: VehicleXL})
+
+	resp := serveMatch(t, index, "", `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050, "vehicle_type": "xl"}`)
+	if resp.DriverID != "driver_xl" {
+		t.Errorf("xl request: got %+v, want driver_xl", resp)
+	}
+
+	// Omitting vehicle_type asks for a standard vehicle
+	resp = serveMatch(t, index, "", `{"rider_id": "rider_2", "lat": 52.4800, "lng": 13.4200}`)
+	if resp.DriverID != "driver_standard" {
+		t.Errorf("default request: got %+v, want driver_standard", resp)
+	}
+}
+
+func TestMatchExplainsVehicleTypeFilter(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_standard", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true, PScheinVerified: true})
+
+	resp := serveMatch(t, index, "", `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050, "vehicle_type": "wheelchair"}`)
+	if resp.Success || !strings.Contains(resp.Message, "wheelchair") {
+		t.Errorf("got %+v, want a message naming the vehicle type", resp)
+	}
+}
+
+func TestMatchRejectsUnknownVehicleType(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/match", strings.NewReader(`{"vehicle_type": "limousine"}`))
+	newTestMatchHandler(NewSpatialIndex())(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func serveMatchWithRadius(t *testing.T, index *SpatialIndex, maxRadiusKm float64, body string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/match", strings.NewReader(body))
+	matchHandler(index, NewExclusionStore(), NewAuditLogger(), newMatchMetrics(prometheus.NewRegistry(), index), speedETAProvider{SpeedKmh: defaultUrbanSpeedKmh}, MatchConfig{MaxRadiusKm: maxRadiusKm, Score: defaultScoreWeights()})(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestMatchRequestRadius(t *testing.T) {
+	fiveKm := offsetKm(berlinMitte, 5, 0)
+	newIndex := func() *SpatialIndex {
+		index := NewSpatialIndex()
+		index.RegisterDriver(Driver{ID: "driver_5km", Lat: fiveKm.Lat, Lng: fiveKm.Lng, Available: true, PScheinVerified: true})
+		return index
+	}
+	rider := `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050`
+
+	if _, body := serveMatchWithRadius(t, newIndex(), 10, rider+`}`); !strings.Contains(body, `"driver_id":"driver_5km"`) {
+		t.Errorf("configured radius: %s, want the driver at 5km", body)
+	}
+	if _, body := serveMatchWithRadius(t, newIndex(), 10, rider+`, "max_radius_km": 2}`); !strings.Contains(body, `"success":false`) || !strings.Contains(body, "within 2km") {
+		t.Errorf("max_radius_km=2: %s, want no match within 2km", body)
+	}
+	// A request cannot widen the radius beyond the configured maximum
+	if _, body := serveMatchWithRadius(t, newIndex(), 3, rider+`, "max_radius_km": 50}`); !strings.Contains(body, `"success":false`) || !strings.Contains(body, "within 3km") {
+		t.Errorf("max_radius_km=50 with a 3km maximum: %s, want no match within 3km", body)
+	}
+
+	for _, radius := range []string{"0", "-1"} {
+		if code, _ := serveMatchWithRadius(t, newIndex(), 10, rider+`, "max_radius_km": `+radius+`}`); code != http.StatusBadRequest {
+			t.Errorf("max_radius_km=%s: status = %d, want 400", radius, code)
+		}
+	}
+}
+
+func TestLoadMatchConfigRadius(t *testing.T) {
+	if cfg, err := loadMatchConfig(); err != nil || cfg.MaxRadiusKm != defaultMatchRadiusKm {
+		t.Errorf("unset: got %v (%v), want %v", cfg.MaxRadiusKm, err, defaultMatchRadiusKm)
+	}
+	t.Setenv("MATCH_MAX_RADIUS_KM", "25")
+	if cfg, err := loadMatchConfig(); err != nil || cfg.MaxRadiusKm != 25 {
+		t.Errorf("got %v (%v), want 25", cfg.MaxRadiusKm, err)
+	}
+	for _, v := range []string{"0", "-3", "far", "NaN", "Inf", "20000"} {
+		t.Setenv("MATCH_MAX_RADIUS_KM", v)
+		if _, err := loadMatchConfig(); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
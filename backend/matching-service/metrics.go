@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Match outcomes recorded in the result label of matching_matches_total
+const (
+	matchResultFound    = "found"
+	matchResultNotFound = "not_found"
+)
+
+// matchMetrics holds the Prometheus collectors for /match. They are
+// registered once, in newMatchMetrics, before any handler runs; the
+// collectors themselves are safe for concurrent use.
+type matchMetrics struct {
+	requests prometheus.Counter
+	results  *prometheus.CounterVec
+	latency  prometheus.Histogram
+}
+
+// newMatchMetrics registers the match collectors and an available-driver
+// gauge read from index on reg
+func newMatchMetrics(reg prometheus.Registerer, index *SpatialIndex) *matchMetrics {
+	m := &matchMetrics{
+		requests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "matching_match_requests_total",
+			Help: "Match requests received.",
+		}),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "matching_matches_total",
+			Help: "Completed match requests by result (found, not_found).",
+		}, []string{"result"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "matching_match_duration_seconds",
+			Help:    "Time to find and reserve a driver for a match request.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 12),
+		}),
+	}
+	reg.MustRegister(
+		m.requests,
+		m.results,
+		m.latency,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "matching_available_drivers",
+			Help: "Drivers currently available for matching.",
+		}, func() float64 { return float64(index.AvailableDrivers()) }),
+	)
+	return m
+}
+
+// observeMatch records the outcome and duration of a completed match
+func (m *matchMetrics) observeMatch(found bool, started time.Time) {
+	result := matchResultNotFound
+	if found {
+		result = matchResultFound
+	}
+	m.results.WithLabelValues(result).Inc()
+	m.latency.Observe(time.Since(started).Seconds())
+}
+
+// metricsHandler serves the collectors registered on reg
+func metricsHandler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
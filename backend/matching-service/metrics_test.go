@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMatchMetrics(t *testing.T) {
+	index := NewSpatialIndex()
+	index.RegisterDriver(Driver{ID: "driver_mitte", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true, PScheinVerified: true})
+	index.RegisterDriver(Driver{ID: "driver_neukoelln", Lat: berlinNeukoelln.Lat, Lng: berlinNeukoelln.Lng, Available: true, PScheinVerified: true})
+	registry := prometheus.NewRegistry()
+	metrics := newMatchMetrics(registry, index)
+	handler := matchHandler(index, NewExclusionStore(), NewAuditLogger(), metrics, speedETAProvider{SpeedKmh: defaultUrbanSpeedKmh}, defaultMatchConfig())
+
+	match := func(body string) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/match", strings.NewReader(body)))
+	}
+	match(`{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`)
+	match(`{"rider_id": "rider_2", "lat": 53.5528, "lng": 10.0067}`)
+	match(`not json`)
+
+	if got := testutil.ToFloat64(metrics.requests); got != 3 {
+		t.Errorf("requests = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(metrics.results.WithLabelValues(matchResultFound)); got != 1 {
+		t.Errorf("found = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.results.WithLabelValues(matchResultNotFound)); got != 1 {
+		t.Errorf("not found = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(metrics.latency); got != 1 {
+		t.Errorf("latency series = %d, want 1", got)
+	}
+
+	rec := httptest.NewRecorder()
+	metricsHandler(registry).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	// One of the two drivers was reserved by the successful match
+	if !strings.Contains(body, "matching_available_drivers 1") {
+		t.Errorf("available driver gauge missing from /metrics:\n%s", body)
+	}
+	if !strings.Contains(body, "matching_match_duration_seconds_count 2") {
+		t.Errorf("latency histogram missing from /metrics:\n%s", body)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+//go:embed schema.sql
+var driverSchema string
+
+// PostgresDriverStore is the DriverStore backed by PostgreSQL with PostGIS,
+// see schema.sql
+type PostgresDriverStore struct {
+	db *sql.DB
+}
+
+func NewPostgresDriverStore(db *sql.DB) *PostgresDriverStore {
+	return &PostgresDriverStore{db: db}
+}
+
+// openPostgresDriverStore connects to dsn and applies schema.sql
+func openPostgresDriverStore(dsn string) (*PostgresDriverStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, driverSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying schema: %w", err)
+	}
+	return NewPostgresDriverStore(db), nil
+}
+
+func (p *PostgresDriverStore) Close() error {
+	return p.db.Close()
+}
+
+// driverPoint is the geography of the point given by the placeholders
+// $lng, $lat
+const driverPoint = `ST_SetSRID(ST_MakePoint(%s, %s), 4326)::geography`
+
+func (p *PostgresDriverStore) SaveDriver(ctx context.Context, d Driver) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO drivers (id, location, available, last_seen, data)
+		VALUES ($1, `+fmt.Sprintf(driverPoint, "$2", "$3")+`, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE
+		SET location = EXCLUDED.location, available = EXCLUDED.available,
+			last_seen = EXCLUDED.last_seen, data = EXCLUDED.data
+		WHERE drivers.last_seen <= EXCLUDED.last_seen`,
+		d.ID, d.Lng, d.Lat, d.Available, d.LastSeen, string(data))
+	return err
+}
+
+func (p *PostgresDriverStore) DeleteDriver(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM drivers WHERE id = $1`, id)
+	return err
+}
+
+func (p *PostgresDriverStore) LoadDrivers(ctx context.Context) ([]Driver, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT data FROM drivers ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drivers := []Driver{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		d, err := decodeDriver(data)
+		if err != nil {
+			return nil, err
+		}
+		drivers = append(drivers, d)
+	}
+	return drivers, rows.Err()
+}
+
+func decodeDriver(data []byte) (Driver, error) {
+	var d Driver
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Driver{}, fmt.Errorf("decoding stored driver: %w", err)
+	}
+	return d, nil
+}
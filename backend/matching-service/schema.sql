@@ -0,0 +1,18 @@
+-- Driver storage for PostgresDriverStore. The statements are idempotent and
+-- applied on start-up; they need the PostGIS extension to be installable.
+--
+-- data holds the full driver as JSON and is the source of truth; the other
+-- columns copy fields for querying the table, and are written in the same
+-- statement as data. Matching runs on the in-memory S2 index, not here.
+
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS drivers (
+    id        TEXT PRIMARY KEY,
+    -- Longitude/latitude on WGS 84
+    location  geography(Point, 4326) NOT NULL,
+    available BOOLEAN NOT NULL,
+    -- Saves of an older state are ignored, see DriverStore.SaveDriver
+    last_seen TIMESTAMPTZ NOT NULL,
+    data      JSONB NOT NULL
+);
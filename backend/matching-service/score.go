@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Driver ratings are on the rider-facing 1-5 star scale
+const (
+	minDriverRating = 1.0
+	maxDriverRating = 5.0
+
+	// unratedDriverRating is assumed for drivers without a rating yet, so new
+	// drivers are neither favoured nor buried
+	unratedDriverRating = 4.0
+)
+
+// scoreCandidates is how many of the nearest drivers are scored with
+// rank_by=score
+const scoreCandidates = 50
+
+// ScoreWeights balance distance against rating in matchScore
+type ScoreWeights struct {
+	Distance float64
+	Rating   float64
+}
+
+func defaultScoreWeights() ScoreWeights {
+	return ScoreWeights{Distance: 0.7, Rating: 0.3}
+}
+
+// loadScoreWeights reads MATCH_SCORE_DISTANCE_WEIGHT and
+// MATCH_SCORE_RATING_WEIGHT
+func loadScoreWeights() (ScoreWeights, error) {
+	w := defaultScoreWeights()
+	for name, weight := range map[string]*float64{
+		"MATCH_SCORE_DISTANCE_WEIGHT": &w.Distance,
+		"MATCH_SCORE_RATING_WEIGHT":   &w.Rating,
+	} {
+		if v := os.Getenv(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+				return w, fmt.Errorf("%s must be a non-negative number, got %q", name, v)
+			}
+			*weight = f
+		}
+	}
+	if w.Distance+w.Rating == 0 {
+		return w, fmt.Errorf("MATCH_SCORE_DISTANCE_WEIGHT and MATCH_SCORE_RATING_WEIGHT cannot both be 0")
+	}
+	return w, nil
+}
+
+func validDriverRating(rating float64) bool {
+	return rating == 0 || (rating >= minDriverRating && rating <= maxDriverRating)
+}
+
+// matchScore rates a candidate between 0 (worst) and 1 (best):
+//
+//	distanceScore = 1 - distanceKm/radiusKm
+//	ratingScore   = (rating - 1) / 4
+//	score         = (wd*distanceScore + wr*ratingScore) / (wd + wr)
+//
+// with wd and wr the distance and rating weights. A driver at the rider's
+// location has distanceScore 1 and one at the edge of the radius 0; a 5-star
+// driver has ratingScore 1 and a 1-star driver 0. Unrated drivers count as
+// unratedDriverRating. With the default weights, a 5-star driver beats a
+// 4-star one that is less than about 0.11 of the radius (540m of 5km)
+// closer.
+func matchScore(distanceKm, rating, radiusKm float64, w ScoreWeights) float64 {
+	distanceScore := math.Max(0, 1-distanceKm/radiusKm)
+	if rating == 0 {
+		rating = unratedDriverRating
+	}
+	ratingScore := (rating - minDriverRating) / (maxDriverRating - minDriverRating)
+	return (w.Distance*distanceScore + w.Rating*ratingScore) / (w.Distance + w.Rating)
+}
+
+// rankByScore orders candidates by matchScore, best first, then distance,
+// then ID
+func rankByScore(candidates []DriverDistance, radiusKm float64, w ScoreWeights) []rankedDriver {
+	ranked := make([]rankedDriver, 0, len(candidates))
+	for _, c := range candidates {
+		ranked = append(ranked, rankedDriver{DriverDistance: c, Score: matchScore(c.DistanceKm, c.Driver.Rating, radiusKm, w)})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.DistanceKm != b.DistanceKm {
+			return a.DistanceKm < b.DistanceKm
+		}
+		return a.Driver.ID < b.Driver.ID
+	})
+	return ranked
+}
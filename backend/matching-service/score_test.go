@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMatchScore(t *testing.T) {
+	w := defaultScoreWeights()
+	if got := matchScore(0, 5, 5, w); got != 1 {
+		t.Errorf("5-star driver at the pickup: score = %v, want 1", got)
+	}
+	if got := matchScore(5, 1, 5, w); got != 0 {
+		t.Errorf("1-star driver at the edge of the radius: score = %v, want 0", got)
+	}
+	if unrated, four := matchScore(1, 0, 5, w), matchScore(1, 4, 5, w); unrated != four {
+		t.Errorf("unrated driver: score = %v, want %v as for a 4-star driver", unrated, four)
+	}
+	// 0.7 * (1 - 1/5) + 0.3 * (4.5-1)/4
+	if got := matchScore(1, 4.5, 5, w); math.Abs(got-0.8225) > 1e-9 {
+		t.Errorf("score = %v, want 0.8225", got)
+	}
+}
+
+func TestMatchRanksByScore(t *testing.T) {
+	near := offsetKm(berlinMitte, 0.5, 0)
+	farther := offsetKm(berlinMitte, 1.0, 0)
+	newIndex := func() *SpatialIndex {
+		index := NewSpatialIndex()
+		index.RegisterDriver(Driver{ID: "driver_near", Lat: near.Lat, Lng: near.Lng, Available: true, PScheinVerified: true, Rating: 2.0})
+		index.RegisterDriver(Driver{ID: "driver_top_rated", Lat: farther.Lat, Lng: farther.Lng, Available: true, PScheinVerified: true, Rating: 5.0})
+		return index
+	}
+	rider := `{"rider_id": "rider_1", "lat": 52.5200, "lng": 13.4050}`
+
+	if resp := serveMatch(t, newIndex(), "", rider); resp.DriverID != "driver_near" {
+		t.Errorf("default ranking: got %+v, want the nearest driver", resp)
+	}
+	if resp := serveMatch(t, newIndex(), "?rank_by=score", rider); resp.DriverID != "driver_top_rated" {
+		t.Errorf("rank_by=score: got %+v, want the farther, higher-rated driver", resp)
+	}
+}
+
+func TestLoadScoreWeights(t *testing.T) {
+	if w, err := loadScoreWeights(); err != nil || w != defaultScoreWeights() {
+		t.Errorf("unset: got %+v (%v), want the defaults", w, err)
+	}
+	t.Setenv("MATCH_SCORE_DISTANCE_WEIGHT", "1")
+	t.Setenv("MATCH_SCORE_RATING_WEIGHT", "0")
+	if w, err := loadScoreWeights(); err != nil || w != (ScoreWeights{Distance: 1, Rating: 0}) {
+		t.Errorf("got %+v (%v), want distance only", w, err)
+	}
+	t.Setenv("MATCH_SCORE_DISTANCE_WEIGHT", "0")
+	if _, err := loadScoreWeights(); err == nil {
+		t.Error("both weights 0: expected an error")
+	}
+	t.Setenv("MATCH_SCORE_DISTANCE_WEIGHT", "1")
+	for _, v := range []string{"-1", "NaN", "Inf", "-Inf"} {
+		t.Setenv("MATCH_SCORE_RATING_WEIGHT", v)
+		if _, err := loadScoreWeights(); err == nil {
+			t.Errorf("rating weight %q: expected an error", v)
+		}
+	}
+}
+
+func TestRegisterDriverRating(t *testing.T) {
+	index := NewSpatialIndex()
+	serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "rating": 4.8}`)
+	if d, _ := index.Driver("driver_new"); d.Rating != 4.8 {
+		t.Errorf("rating = %v, want 4.8", d.Rating)
+	}
+	index.UpdateDriver("driver_new", 52.53, 13.41, true)
+	if d, _ := index.Driver("driver_new"); d.Rating != 4.8 {
+		t.Errorf("rating after a location update = %v, want 4.8", d.Rating)
+	}
+
+	for _, rating := range []string{"0.5", "5.1"} {
+		rec := serveDrivers(t, index, http.MethodPost, "/api/v1/drivers", `{"id": "driver_new", "lat": 52.52, "lng": 13.405, "rating": `+rating+`}`)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "rating") {
+			t.Errorf("rating %s: status = %d, want 400", rating, rec.Code)
+		}
+	}
+}
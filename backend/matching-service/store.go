@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DriverStore keeps drivers across restarts. The SpatialIndex writes every
+// change through to its Store and loads the stored drivers on start-up;
+// matching itself runs on the index.
+//
+// MemoryDriverStore keeps drivers in memory and PostgresDriverStore in
+// PostgreSQL with PostGIS. store_test.go runs the same contract tests against
+// both.
+type DriverStore interface {
+	// SaveDriver inserts or replaces the driver. A driver last seen before
+	// the stored one is not written, so out-of-order saves keep the newest
+	// state.
+	SaveDriver(ctx context.Context, d Driver) error
+	// DeleteDriver removes the driver; deleting an unknown driver is not an
+	// error
+	DeleteDriver(ctx context.Context, id string) error
+	// LoadDrivers returns all stored drivers
+	LoadDrivers(ctx context.Context) ([]Driver, error)
+}
+
+// storeTimeout bounds a single write-through to the Store
+const storeTimeout = 5 * time.Second
+
+// persist writes d through to the Store. Failures are logged rather than
+// returned: the index has already changed and stays authoritative while the
+// service runs.
+func (s *SpatialIndex) persist(d Driver) {
+	if s.Store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := s.Store.SaveDriver(ctx, d); err != nil {
+		s.storeLog.Printf("SAVE_FAILED driver_id=%s error=%v", d.ID, err)
+	}
+}
+
+// forget deletes a deregistered driver from the Store, logging failures like
+// persist
+func (s *SpatialIndex) forget(id string) {
+	if s.Store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := s.Store.DeleteDriver(ctx, id); err != nil {
+		s.storeLog.Printf("DELETE_FAILED driver_id=%s error=%v", id, err)
+	}
+}
+
+// LoadDrivers indexes every driver in the Store, keeping its stored last-seen
+// time, and returns how many were loaded
+func (s *SpatialIndex) LoadDrivers(ctx context.Context) (int, error) {
+	if s.Store == nil {
+		return 0, nil
+	}
+	drivers, err := s.Store.LoadDrivers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range drivers {
+		s.indexDriverLocked(d)
+	}
+	return len(drivers), nil
+}
+
+// MemoryDriverStore is the in-memory DriverStore. Drivers are lost on restart;
+// set DATABASE_URL to keep them in PostgreSQL instead.
+type MemoryDriverStore struct {
+	mu      sync.RWMutex
+	drivers map[string]Driver
+}
+
+func NewMemoryDriverStore() *MemoryDriverStore {
+	return &MemoryDriverStore{drivers: make(map[string]Driver)}
+}
+
+func (m *MemoryDriverStore) SaveDriver(ctx context.Context, d Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.drivers[d.ID]; ok && d.LastSeen.Before(old.LastSeen) {
+		return nil
+	}
+	m.drivers[d.ID] = d
+	return nil
+}
+
+func (m *MemoryDriverStore) DeleteDriver(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.drivers, id)
+	return nil
+}
+
+func (m *MemoryDriverStore) LoadDrivers(ctx context.Context) ([]Driver, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	drivers := make([]Driver, 0, len(m.drivers))
+	for _, d := range m.drivers {
+		drivers = append(drivers, d)
+	}
+	return drivers, nil
+}
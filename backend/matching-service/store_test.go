@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// The contract tests run against every DriverStore. The PostgreSQL
+// implementation is tested when MATCHING_TEST_DATABASE_URL points at a
+// scratch database with PostGIS available; its drivers table is emptied
+// before each test.
+
+func TestMemoryDriverStoreContract(t *testing.T) {
+	testDriverStoreContract(t, func(t *testing.T) DriverStore {
+		return NewMemoryDriverStore()
+	})
+}
+
+func TestPostgresDriverStoreContract(t *testing.T) {
+	dsn := os.Getenv("MATCHING_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("MATCHING_TEST_DATABASE_URL not set")
+	}
+	testDriverStoreContract(t, func(t *testing.T) DriverStore {
+		store, err := openPostgresDriverStore(dsn)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		if _, err := store.db.Exec(`TRUNCATE drivers`); err != nil {
+			t.Fatalf("failed to empty drivers: %v", err)
+		}
+		return store
+	})
+}
+
+func testDriverStoreContract(t *testing.T, newStore func(t *testing.T) DriverStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store DriverStore)
+	}{
+		{"SaveAndLoad", testStoreSaveAndLoad},
+		{"StaleSaveIgnored", testStoreStaleSaveIgnored},
+		{"DeleteDriver", testStoreDeleteDriver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// storeTestTime is a fixed last-seen time. PostgreSQL keeps microseconds, so
+// the tests never use finer timestamps.
+var storeTestTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func storeTestDriver(id string, at location, available bool) Driver {
+	return Driver{ID: id, Lat: at.Lat, Lng: at.Lng, Available: available, LastSeen: storeTestTime, PScheinVerified: true, VehicleType: VehicleStandard}
+}
+
+func mustSave(t *testing.T, store DriverStore, d Driver) {
+	t.Helper()
+	if err := store.SaveDriver(context.Background(), d); err != nil {
+		t.Fatalf("SaveDriver %s failed: %v", d.ID, err)
+	}
+}
+
+func loadedDrivers(t *testing.T, store DriverStore) map[string]Driver {
+	t.Helper()
+	drivers, err := store.LoadDrivers(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDrivers failed: %v", err)
+	}
+	byID := make(map[string]Driver, len(drivers))
+	for _, d := range drivers {
+		byID[d.ID] = d
+	}
+	return byID
+}
+
+func testStoreSaveAndLoad(t *testing.T, store DriverStore) {
+	d := storeTestDriver("driver_mitte", berlinMitte, true)
+	d.VehicleType = VehicleWheelchair
+	mustSave(t, store, d)
+	mustSave(t, store, storeTestDriver("driver_hamburg", hamburgHbf, false))
+
+	drivers := loadedDrivers(t, store)
+	if len(drivers) != 2 {
+		t.Fatalf("loaded %d drivers, want 2", len(drivers))
+	}
+	got := drivers["driver_mitte"]
+	if got.Lat != d.Lat || got.Lng != d.Lng || !got.Available || !got.PScheinVerified || got.VehicleType != VehicleWheelchair || !got.LastSeen.Equal(d.LastSeen) {
+		t.Errorf("loaded %+v, want %+v", got, d)
+	}
+
+	// Saving again replaces the driver
+	moved := storeTestDriver("driver_mitte", berlinNeukoelln, false)
+	moved.LastSeen = storeTestTime.Add(time.Minute)
+	mustSave(t, store, moved)
+	if got := loadedDrivers(t, store)["driver_mitte"]; got.Lat != berlinNeukoelln.Lat || got.Available || !got.LastSeen.Equal(moved.LastSeen) {
+		t.Errorf("after replace loaded %+v, want %+v", got, moved)
+	}
+}
+
+func testStoreStaleSaveIgnored(t *testing.T, store DriverStore) {
+	current := storeTestDriver("driver_1", berlinNeukoelln, true)
+	current.LastSeen = storeTestTime.Add(time.Minute)
+	mustSave(t, store, current)
+	mustSave(t, store, storeTestDriver("driver_1", berlinMitte, true))
+
+	if got := loadedDrivers(t, store)["driver_1"]; got.Lat != berlinNeukoelln.Lat || !got.LastSeen.Equal(current.LastSeen) {
+		t.Errorf("stale save overwrote the driver: %+v", got)
+	}
+}
+
+func testStoreDeleteDriver(t *testing.T, store DriverStore) {
+	mustSave(t, store, storeTestDriver("driver_1", berlinMitte, true))
+	mustSave(t, store, storeTestDriver("driver_2", berlinMitte, true))
+
+	for _, id := range []string{"driver_1", "driver_1", "missing"} {
+		if err := store.DeleteDriver(context.Background(), id); err != nil {
+			t.Errorf("DeleteDriver %s failed: %v", id, err)
+		}
+	}
+	drivers := loadedDrivers(t, store)
+	if _, ok := drivers["driver_1"]; ok || len(drivers) != 1 {
+		t.Errorf("after delete loaded %v, want only driver_2", drivers)
+	}
+}
+
+func TestSpatialIndexWritesThroughToStore(t *testing.T) {
+	store := NewMemoryDriverStore()
+	index := NewSpatialIndex()
+	index.Store = store
+
+	index.RegisterDriver(Driver{ID: "driver_1", Lat: berlinMitte.Lat, Lng: berlinMitte.Lng, Available: true, PScheinVerified: true})
+	index.UpdateLocation("driver_1", berlinNeukoelln.Lat, berlinNeukoelln.Lng)
+	index.UpdateDriver("driver_2", hamburgHbf.Lat, hamburgHbf.Lng, true)
+	if !index.ReserveDriver("driver_2") {
+		t.Fatal("driver_2 could not be reserved")
+	}
+	index.UpdateDriver("driver_3", munichMarienplatz.Lat, munichMarienplatz.Lng, true)
+	index.SetAvailability("driver_3", false)
+	index.UpdateDriver("driver_4", frankfurtHbf.Lat, frankfurtHbf.Lng, true)
+	index.RemoveDriver("driver_4")
+	index.UpdateDriver("driver_7", berlinMitte.Lat, berlinMitte.Lng, true)
+	if d, _ := index.Driver("driver_7"); !index.MarkStale("driver_7", d.LastSeen) {
+		t.Fatal("driver_7 could not be marked stale")
+	}
+	index.RegisterDrivers([]Driver{
+		{ID: "driver_5", Lat: potsdam.Lat, Lng: potsdam.Lng, Available: true},
+		{ID: "driver_6", Lat: berlinSpandau.Lat, Lng: berlinSpandau.Lng, Available: true},
+	})
+
+	drivers := loadedDrivers(t, store)
+	if got := drivers["driver_1"]; got.Lat != berlinNeukoelln.Lat || !got.Available || !got.PScheinVerified || got.VehicleType != VehicleStandard {
+		t.Errorf("stored driver_1 = %+v, want moved to Neukölln", got)
+	}
+	if got, ok := drivers["driver_2"]; !ok || got.Available {
+		t.Errorf("stored driver_2 = %+v, want reserved", got)
+	}
+	if got, ok := drivers["driver_3"]; !ok || got.Available {
+		t.Errorf("stored driver_3 = %+v, want off duty", got)
+	}
+	if _, ok := drivers["driver_4"]; ok {
+		t.Error("deregistered driver_4 is still stored")
+	}
+	if got, ok := drivers["driver_7"]; !ok || got.Available {
+		t.Errorf("stored driver_7 = %+v, want off duty after going stale", got)
+	}
+	if _, ok := drivers["driver_5"]; !ok {
+		t.Error("bulk registered driver_5 was not stored")
+	}
+	if _, ok := drivers["driver_6"]; !ok {
+		t.Error("bulk registered driver_6 was not stored")
+	}
+}
+
+func TestSpatialIndexLoadsDriversFromStore(t *testing.T) {
+	store := NewMemoryDriverStore()
+	mustSave(t, store, storeTestDriver("driver_mitte", berlinMitte, true))
+	mustSave(t, store, storeTestDriver("driver_hamburg", hamburgHbf, true))
+
+	index := NewSpatialIndex()
+	index.Store = store
+	n, err := index.LoadDrivers(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("LoadDrivers = %d, %v, want 2", n, err)
+	}
+
+	assertMatch(t, index, berlinAlexanderplatz, 5.0, "driver_mitte")
+	assertMatch(t, index, hamburgHbf, 5.0, "driver_hamburg")
+	if d, _ := index.Driver("driver_mitte"); !d.LastSeen.Equal(storeTestTime) {
+		t.Errorf("LastSeen = %s, want the stored %s", d.LastSeen, storeTestTime)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Defaults for GET /api/v1/drivers/{id}/stream
+const (
+	defaultStreamMinInterval = time.Second
+	defaultStreamStaleTTL    = 30 * time.Second
+)
+
+// maxStreamMessageBytes caps a single location message
+const maxStreamMessageBytes = 1024
+
+// StreamConfig configures the driver location stream. Updates closer together
+// than MinInterval are coalesced, so a chatty app cannot thrash the index; a
+// driver that sends nothing for StaleTTL is taken off duty.
+type StreamConfig struct {
+	MinInterval time.Duration
+	StaleTTL    time.Duration
+}
+
+func defaultStreamConfig() StreamConfig {
+	return StreamConfig{MinInterval: defaultStreamMinInterval, StaleTTL: defaultStreamStaleTTL}
+}
+
+// loadStreamConfig reads DRIVER_STREAM_MIN_INTERVAL and
+// DRIVER_STREAM_STALE_TTL, e.g. "2s" and "1m"
+func loadStreamConfig() (StreamConfig, error) {
+	cfg := defaultStreamConfig()
+	if v := os.Getenv("DRIVER_STREAM_MIN_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("DRIVER_STREAM_MIN_INTERVAL must be a non-negative duration, got %q", v)
+		}
+		cfg.MinInterval = d
+	}
+	if v := os.Getenv("DRIVER_STREAM_STALE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("DRIVER_STREAM_STALE_TTL must be a positive duration, got %q", v)
+		}
+		cfg.StaleTTL = d
+	}
+	if cfg.MinInterval >= cfg.StaleTTL {
+		return cfg, fmt.Errorf("DRIVER_STREAM_MIN_INTERVAL (%s) must be shorter than DRIVER_STREAM_STALE_TTL (%s)", cfg.MinInterval, cfg.StaleTTL)
+	}
+	return cfg, nil
+}
+
+// StreamLocation is a location update sent by the driver's app
+type StreamLocation struct {
+	Lat *float64 `json:"lat"`
+	Lng *float64 `json:"lng"`
+}
+
+// StreamError is sent back for a message that was not applied
+type StreamError struct {
+	Error string `json:"error"`
+}
+
+// The app connects from a native client, so the default origin check, which
+// admits requests without an Origin header, is enough.
+var streamUpgrader = websocket.Upgrader{ReadBufferSize: maxStreamMessageBytes, WriteBufferSize: maxStreamMessageBytes}
+
+// streamDriverLocation serves GET /api/v1/drivers/{id}/stream, a WebSocket on
+// which a registered driver's app sends its position instead of polling
+// PUT /api/v1/drivers/{id}/location.
+//
+// Each text message from the app is a JSON object {"lat": 52.52, "lng":
+// 13.405}, validated as for PUT location. Updates are applied at most once
+// per MinInterval; when they come faster, the latest one is applied once the
+// interval has passed and the others are dropped. The server only writes
+// {"error": "..."} for a message it could not apply, and keeps the
+// connection open.
+//
+// The connection is closed if the app sends no valid location for StaleTTL.
+// Once the connection is gone, the driver is taken off duty if no newer
+// location arrives, over a new stream or PUT location, within StaleTTL of the
+// last one.
+func streamDriverLocation(index *SpatialIndex, cfg StreamConfig, w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := index.Driver(id); !ok {
+		http.Error(w, "Driver not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already answered the request
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxStreamMessageBytes)
+
+	updates := make(chan StreamLocation)
+	go readStreamLocations(conn, cfg.StaleTTL, updates)
+
+	var (
+		lastApplied time.Time
+		pending     *StreamLocation
+		flush       <-chan time.Time
+	)
+	apply := func(u StreamLocation) {
+		lastApplied = timeutil.Now()
+		index.UpdateLocation(id, *u.Lat, *u.Lng)
+	}
+
+	for {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				if pending != nil {
+					apply(*pending)
+				}
+				markStaleAfter(index, id, cfg.StaleTTL)
+				return
+			}
+			if wait := cfg.MinInterval - timeutil.Now().Sub(lastApplied); wait > 0 {
+				pending = &u
+				if flush == nil {
+					flush = time.After(wait)
+				}
+				continue
+			}
+			apply(u)
+		case <-flush:
+			flush = nil
+			if pending != nil {
+				apply(*pending)
+				pending = nil
+			}
+		}
+	}
+}
+
+// readStreamLocations sends the valid location messages on conn to updates
+// and answers invalid ones with a StreamError. It closes updates when the
+// connection fails, is closed, or sends no valid location for ttl.
+func readStreamLocations(conn *websocket.Conn, ttl time.Duration, updates chan<- StreamLocation) {
+	defer close(updates)
+	conn.SetReadDeadline(time.Now().Add(ttl))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var u StreamLocation
+		if err := json.Unmarshal(data, &u); err != nil {
+			conn.WriteJSON(StreamError{Error: "message must be a JSON object {lat, lng}"})
+			continue
+		}
+		if u.Lat == nil || u.Lng == nil || !validCoordinates(*u.Lat, *u.Lng) {
+			conn.WriteJSON(StreamError{Error: "lat and lng are required; lat must be within [-90, 90] and lng within [-180, 180]"})
+			continue
+		}
+		conn.SetReadDeadline(time.Now().Add(ttl))
+		updates <- u
+	}
+}
+
+// markStaleAfter takes the driver off duty once its current location is ttl
+// old, unless it has been updated by then
+func markStaleAfter(index *SpatialIndex, id string, ttl time.Duration) {
+	d, ok := index.Driver(id)
+	if !ok {
+		return
+	}
+	wait := ttl - timeutil.Now().Sub(d.LastSeen)
+	if wait < 0 {
+		wait = 0
+	}
+	time.AfterFunc(wait, func() { index.MarkStale(id, d.LastSeen) })
+}
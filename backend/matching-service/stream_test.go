@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialStream starts a server for the driver API with cfg and opens the
+// location stream of driverID
+func dialStream(t *testing.T, index *SpatialIndex, cfg StreamConfig, driverID string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	server := httptest.NewServer(driversHandler(index, cfg))
+	t.Cleanup(server.Close)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/drivers/" + driverID + "/stream"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func sendLocation(t *testing.T, conn *websocket.Conn, at location) {
+	t.Helper()
+	if err := conn.WriteJSON(map[string]float64{"lat": at.Lat, "lng": at.Lng}); err != nil {
+		t.Fatalf("send location: %v", err)
+	}
+}
+
+// waitForDriver polls the index until cond holds for the driver, or fails
+// after a second
+func waitForDriver(t *testing.T, index *SpatialIndex, id string, what string, cond func(Driver) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		d, _ := index.Driver(id)
+		if cond(d) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("driver %s: timed out waiting for %s, driver is %+v", id, what, d)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func at(l location) func(Driver) bool {
+	return func(d Driver) bool { return d.Lat == l.Lat && d.Lng == l.Lng }
+}
+
+func TestStreamDriverLocationUpdatesIndex(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_1", berlinMitte, true})
+	conn, _, err := dialStream(t, index, StreamConfig{MinInterval: 0, StaleTTL: time.Minute}, "driver_1")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	sendLocation(t, conn, hamburgHbf)
+	waitForDriver(t, index, "driver_1", "Hamburg", at(hamburgHbf))
+	assertMatch(t, index, hamburgHbf, 1.0, "driver_1")
+	index.LinearFallback = false
+	assertMatch(t, index, berlinMitte, 5.0, "")
+}
+
+func TestStreamDriverLocationCoalescesFastUpdates(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_1", berlinMitte, true})
+	conn, _, err := dialStream(t, index, StreamConfig{MinInterval: 200 * time.Millisecond, StaleTTL: time.Minute}, "driver_1")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	sendLocation(t, conn, hamburgHbf)
+	sendLocation(t, conn, munichMarienplatz)
+	sendLocation(t, conn, frankfurtHbf)
+	waitForDriver(t, index, "driver_1", "the first update", at(hamburgHbf))
+	// The latest update is applied once the interval has passed
+	waitForDriver(t, index, "driver_1", "the latest update", at(frankfurtHbf))
+}
+
+func TestStreamDriverLocationRejectsInvalidMessages(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_1", berlinMitte, true})
+	conn, _, err := dialStream(t, index, StreamConfig{MinInterval: 0, StaleTTL: time.Minute}, "driver_1")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	for _, msg := range []string{`not json`, `{"lat": 52.52}`, `{"lat": 91, "lng": 13.405}`} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("send %s: %v", msg, err)
+		}
+		var reply StreamError
+		if err := conn.ReadJSON(&reply); err != nil || reply.Error == "" {
+			t.Errorf("%s: reply %+v, %v, want an error message", msg, reply, err)
+		}
+	}
+
+	// The connection stays usable
+	sendLocation(t, conn, hamburgHbf)
+	waitForDriver(t, index, "driver_1", "Hamburg", at(hamburgHbf))
+}
+
+func TestStreamDriverLocationUnknownDriver(t *testing.T) {
+	_, resp, err := dialStream(t, NewSpatialIndex(), defaultStreamConfig(), "unknown")
+	if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("dial unknown driver: %v, want a 404 handshake failure", err)
+	}
+}
+
+func TestStreamDriverLocationMarksDriverStaleAfterDisconnect(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_1", berlinMitte, true})
+	conn, _, err := dialStream(t, index, StreamConfig{MinInterval: 0, StaleTTL: 100 * time.Millisecond}, "driver_1")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	sendLocation(t, conn, hamburgHbf)
+	waitForDriver(t, index, "driver_1", "Hamburg", at(hamburgHbf))
+	conn.Close()
+
+	waitForDriver(t, index, "driver_1", "going off duty", func(d Driver) bool { return !d.Available })
+	if d, ok := index.Driver("driver_1"); !ok || !at(hamburgHbf)(d) {
+		t.Errorf("stale driver = %+v, %v, want it registered at its last location", d, ok)
+	}
+	assertMatch(t, index, hamburgHbf, 1.0, "")
+}
+
+func TestStreamDriverLocationClosesSilentConnection(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_1", berlinMitte, true})
+	conn, _, err := dialStream(t, index, StreamConfig{MinInterval: 0, StaleTTL: 100 * time.Millisecond}, "driver_1")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	var netErr net.Error
+	if err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatalf("read = %v, want the server to close the connection", err)
+	}
+	waitForDriver(t, index, "driver_1", "going off duty", func(d Driver) bool { return !d.Available })
+}
+
+func TestMarkStaleKeepsUpdatedDriver(t *testing.T) {
+	index := newTestIndex(t, driverFixture{"driver_1", berlinMitte, true})
+	d, _ := index.Driver("driver_1")
+
+	// A newer location, e.g. from a reconnected stream, keeps the driver on duty
+	time.Sleep(time.Millisecond)
+	index.UpdateLocation("driver_1", hamburgHbf.Lat, hamburgHbf.Lng)
+	if index.MarkStale("driver_1", d.LastSeen) {
+		t.Error("updated driver marked stale")
+	}
+
+	d, _ = index.Driver("driver_1")
+	if !index.MarkStale("driver_1", d.LastSeen) {
+		t.Error("driver not marked stale")
+	}
+	if index.MarkStale("unknown", d.LastSeen) {
+		t.Error("unknown driver marked stale")
+	}
+}
+
+func TestLoadStreamConfig(t *testing.T) {
+	if cfg, err := loadStreamConfig(); err != nil || cfg != defaultStreamConfig() {
+		t.Errorf("default = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("DRIVER_STREAM_MIN_INTERVAL", "2s")
+	t.Setenv("DRIVER_STREAM_STALE_TTL", "1m")
+	if cfg, err := loadStreamConfig(); err != nil || cfg.MinInterval != 2*time.Second || cfg.StaleTTL != time.Minute {
+		t.Errorf("2s/1m = %+v, %v", cfg, err)
+	}
+
+	for _, tt := range []struct{ interval, ttl string }{
+		{"-1s", "30s"},
+		{"often", "30s"},
+		{"1s", "0"},
+		{"1m", "30s"},
+	} {
+		t.Setenv("DRIVER_STREAM_MIN_INTERVAL", tt.interval)
+		t.Setenv("DRIVER_STREAM_STALE_TTL", tt.ttl)
+		if _, err := loadStreamConfig(); err == nil {
+			t.Errorf("%s/%s: expected an error", tt.interval, tt.ttl)
+		}
+	}
+}
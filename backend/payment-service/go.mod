@@ -0,0 +1,13 @@
+module github.com/khiamazizi2802-design/ride-share-platform-germany/backend/payment-service
+
+go 1.21
+
+require (
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/gorilla/mux v1.8.1
+	github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
+	github.com/lib/pq v1.10.9
+	github.com/stripe/stripe-go/v76 v76.25.0
+)
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
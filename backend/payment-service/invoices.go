@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/vat"
+)
+
+// OperatorDetails identify the transport operator on invoices, as required
+// by §14 Abs. 4 UStG
+type OperatorDetails struct {
+	Name    string
+	Address string
+	VATID   string
+}
+
+// loadOperatorDetails reads OPERATOR_NAME, OPERATOR_ADDRESS and
+// OPERATOR_VAT_ID. It returns nil unless all three are set, since an invoice
+// without them is not valid.
+func loadOperatorDetails() *OperatorDetails {
+	op := &OperatorDetails{
+		Name:    strings.TrimSpace(os.Getenv("OPERATOR_NAME")),
+		Address: strings.TrimSpace(os.Getenv("OPERATOR_ADDRESS")),
+		VATID:   strings.TrimSpace(os.Getenv("OPERATOR_VAT_ID")),
+	}
+	if op.Name == "" || op.Address == "" || op.VATID == "" {
+		return nil
+	}
+	return op
+}
+
+// Invoice is the invoice number and date assigned to a receipted payment
+type Invoice struct {
+	Number   string
+	IssuedAt time.Time
+}
+
+// InvoiceRepository assigns invoice numbers from a monotonic sequence, once
+// per payment. Downloading an invoice again returns the same number, so the
+// sequence has no duplicates. §14 Abs. 4 UStG requires the numbers to be
+// unique for good, so the sequence must be durable; see
+// PostgresInvoiceRepository.
+type InvoiceRepository interface {
+	// Issue returns the invoice for the payment, numbering it on first use
+	Issue(ctx context.Context, paymentID string, now time.Time) (Invoice, error)
+}
+
+// formatInvoiceNumber renders the nth invoice number of the sequence
+func formatInvoiceNumber(n int64) string {
+	return fmt.Sprintf("INV-%08d", n)
+}
+
+// formatEUR formats cents the German way, e.g. 2240 as "22,40 €"
+func formatEUR(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d,%02d €", sign, cents/100, cents%100)
+}
+
+// renderInvoicePDF lays out the invoice for receipt. The fare lines and the
+// net/VAT split are taken from the receipt, which uses the same gross fare
+// components and the backend/pkg/vat rule as the pricing-service quote.
+func renderInvoicePDF(op OperatorDetails, inv Invoice, receipt Receipt) ([]byte, error) {
+	pdf := newPDFDocument()
+	const left, amounts, right = 50.0, 430.0, 545.0
+	y := 790.0
+	next := func(step float64) float64 {
+		y -= step
+		return y
+	}
+
+	pdf.Text(left, y, 11, true, op.Name)
+	pdf.Text(left, next(14), 10, false, op.Address)
+	pdf.Text(left, next(14), 10, false, "USt-IdNr.: "+op.VATID)
+
+	pdf.Text(left, next(50), 18, true, "Rechnung")
+	pdf.Text(left, next(24), 10, false, "Rechnungsnummer: "+inv.Number)
+	pdf.Text(left, next(14), 10, false, "Rechnungsdatum: "+inv.IssuedAt.In(timeutil.Berlin()).Format("02.01.2006"))
+	rideDate := receipt.IssuedAt
+	if receipt.RideDate != nil {
+		rideDate = *receipt.RideDate
+	}
+	pdf.Text(left, next(14), 10, false, "Fahrtdatum: "+rideDate.In(timeutil.Berlin()).Format("02.01.2006"))
+	if receipt.RiderName != "" {
+		pdf.Text(left, next(14), 10, false, "Fahrgast: "+receipt.RiderName)
+	}
+	pdf.Text(left, next(14), 10, false, "Fahrt: "+receipt.RideID)
+	pdf.Text(left, next(14), 10, false, "Beleg: "+receipt.ReceiptNumber)
+
+	pdf.Text(left, next(36), 10, true, "Leistung")
+	pdf.Text(amounts, y, 10, true, "Betrag (brutto)")
+	pdf.Line(left, right, next(6))
+	for _, line := range receipt.Lines {
+		pdf.Text(left, next(16), 10, false, line.Description)
+		pdf.Text(amounts, y, 10, false, formatEUR(line.AmountCents))
+	}
+	pdf.Line(left, right, next(8))
+
+	pdf.Text(left, next(16), 10, false, "Nettobetrag")
+	pdf.Text(amounts, y, 10, false, formatEUR(receipt.NetCents))
+	pdf.Text(left, next(16), 10, false, fmt.Sprintf("USt. %.0f %%", receipt.VATRatePercent))
+	pdf.Text(amounts, y, 10, false, formatEUR(receipt.VATCents))
+	pdf.Text(left, next(16), 10, true, "Gesamtbetrag")
+	pdf.Text(amounts, y, 10, true, formatEUR(receipt.TotalCents))
+
+	if receipt.VATRatePercent == vat.ReducedPercent {
+		pdf.Text(left, next(36), 9, false, "Ermäßigter Steuersatz für Personenbeförderung bis 50 km (§12 Abs. 2 Nr. 10 UStG).")
+	}
+	pdf.Text(left, next(14), 9, false, "Bezahlt per Kartenzahlung, Zahlungs-ID "+receipt.PaymentID+".")
+
+	return pdf.Bytes()
+}
+
+// invoiceHandler serves GET /payments/{id}/invoice as a PDF download.
+// Invoices are issued for receipted payments only.
+func invoiceHandler(op *OperatorDetails, receipts *ReceiptStore, invoices InvoiceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if op == nil || receipts == nil || invoices == nil {
+			http.Error(w, "Invoices are not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		paymentID := mux.Vars(r)["id"]
+		receipt, ok, err := receipts.Get(r.Context(), paymentID)
+		if err != nil {
+			log.Printf("ERROR: loading receipt for payment %s: %v", paymentID, err)
+			http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "Payment has no receipt; invoices are issued for completed, receipted payments", http.StatusNotFound)
+			return
+		}
+
+		inv, err := invoices.Issue(r.Context(), paymentID, timeutil.Now())
+		if err != nil {
+			log.Printf("ERROR: issuing invoice for payment %s: %v", paymentID, err)
+			http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+			return
+		}
+		body, err := renderInvoicePDF(*op, inv, receipt)
+		if err != nil {
+			log.Printf("ERROR: rendering invoice %s: %v", inv.Number, err)
+			http.Error(w, "Failed to render invoice", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Served invoice %s for payment %s (ride %s)", inv.Number, paymentID, receipt.RideID)
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, inv.Number))
+		w.Write(body)
+	}
+}
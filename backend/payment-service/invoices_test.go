@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go/v76"
+)
+
+var testOperator = &OperatorDetails{Name: "Stadtfahrt GmbH", Address: "Musterstraße 1, 10115 Berlin", VATID: "DE123456789"}
+
+// memoryInvoices is an InvoiceRepository for tests; production invoices are
+// numbered by PostgresInvoiceRepository
+type memoryInvoices struct {
+	mu        sync.Mutex
+	last      int64
+	byPayment map[string]Invoice
+	err       error
+}
+
+func newMemoryInvoices() *memoryInvoices {
+	return &memoryInvoices{byPayment: make(map[string]Invoice)}
+}
+
+func (m *memoryInvoices) Issue(ctx context.Context, paymentID string, now time.Time) (Invoice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return Invoice{}, m.err
+	}
+	if inv, ok := m.byPayment[paymentID]; ok {
+		return inv, nil
+	}
+	m.last++
+	inv := Invoice{Number: formatInvoiceNumber(m.last), IssuedAt: now}
+	m.byPayment[paymentID] = inv
+	return inv, nil
+}
+
+// The contract test runs against every InvoiceRepository. The PostgreSQL
+// implementation is tested when PAYMENT_TEST_DATABASE_URL points at a scratch
+// database; its invoice tables are reset before the test.
+
+func TestMemoryInvoicesContract(t *testing.T) {
+	testInvoiceRepositoryContract(t, newMemoryInvoices())
+}
+
+// openTestDatabase opens PAYMENT_TEST_DATABASE_URL with schema.sql applied,
+// or skips the test
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("PAYMENT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("PAYMENT_TEST_DATABASE_URL not set")
+	}
+	db, err := openPostgres(dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPostgresInvoiceRepositoryContract(t *testing.T) {
+	db := openTestDatabase(t)
+	if _, err := db.Exec(`TRUNCATE invoices; UPDATE invoice_sequence SET last_number = 0`); err != nil {
+		t.Fatalf("failed to reset invoices: %v", err)
+	}
+	testInvoiceRepositoryContract(t, NewPostgresInvoiceRepository(db))
+
+	// The sequence survives reopening the database
+	reopened := NewPostgresInvoiceRepository(openTestDatabase(t))
+	if inv, err := reopened.Issue(context.Background(), "pi_after_restart", time.Now().UTC()); err != nil || inv.Number != formatInvoiceNumber(12) {
+		t.Errorf("after reopening: %+v, %v; want %s", inv, err, formatInvoiceNumber(12))
+	}
+}
+
+func testInvoiceRepositoryContract(t *testing.T, repo InvoiceRepository) {
+	ctx := context.Background()
+	issuedAt := time.Date(2024, 12, 31, 23, 30, 0, 0, time.UTC)
+
+	first, err := repo.Issue(ctx, "pi_first", issuedAt)
+	if err != nil || first.Number != "INV-00000001" || !first.IssuedAt.Equal(issuedAt) {
+		t.Fatalf("first invoice: %+v, %v", first, err)
+	}
+	again, err := repo.Issue(ctx, "pi_first", issuedAt.Add(time.Hour))
+	if err != nil || again.Number != first.Number || !again.IssuedAt.Equal(issuedAt) {
+		t.Errorf("issuing again: %+v, %v; want the first invoice", again, err)
+	}
+
+	// Concurrent first downloads still get distinct, consecutive numbers
+	var wg sync.WaitGroup
+	numbers := make([]string, 20)
+	for i := range numbers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paymentID := fmt.Sprintf("pi_%d", i%10)
+			inv, err := repo.Issue(ctx, paymentID, issuedAt)
+			if err != nil {
+				t.Errorf("%s: %v", paymentID, err)
+			}
+			numbers[i] = inv.Number
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		if numbers[i] != numbers[i+10] {
+			t.Errorf("pi_%d issued twice: %s and %s", i, numbers[i], numbers[i+10])
+		}
+		seen[numbers[i]] = true
+	}
+	for n := int64(2); n <= 11; n++ {
+		if !seen[formatInvoiceNumber(n)] {
+			t.Errorf("%s not issued; got %v", formatInvoiceNumber(n), numbers[:10])
+		}
+	}
+}
+
+// pdfContent returns the page content of a rendered PDF, inflating
+// compressed streams
+func pdfContent(t *testing.T, body []byte) string {
+	t.Helper()
+	var content strings.Builder
+	for rest := body; ; {
+		start := bytes.Index(rest, []byte("stream\n"))
+		if start < 0 {
+			break
+		}
+		rest = rest[start+len("stream\n"):]
+		end := bytes.Index(rest, []byte("\nendstream"))
+		if end < 0 {
+			t.Fatal("unterminated PDF stream")
+		}
+		stream := rest[:end]
+		rest = rest[end+len("\nendstream"):]
+		if r, err := zlib.NewReader(bytes.NewReader(stream)); err == nil {
+			if inflated, err := io.ReadAll(r); err == nil {
+				stream = inflated
+			}
+		}
+		content.Write(stream)
+	}
+	return content.String()
+}
+
+func getInvoice(t *testing.T, op *OperatorDetails, receipts *ReceiptStore, invoices InvoiceRepository, paymentID string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/payments/{id}/invoice", invoiceHandler(op, receipts, invoices)).Methods("GET")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payments/"+paymentID+"/invoice", nil))
+	return rec
+}
+
+func TestInvoicePDF(t *testing.T) {
+	receipts, _ := newTestReceiptStore(t)
+	invoices := newMemoryInvoices()
+	if rec := postReceipt(paidIntentAPI(), receipts, testReceiptBody); rec.Code != http.StatusCreated {
+		t.Fatalf("receipt: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	second := &fakeStripe{existing: &stripe.PaymentIntent{ID: "pi_second", Amount: 2240, Status: stripe.PaymentIntentStatusSucceeded}}
+	postReceipt(second, receipts, strings.Replace(testReceiptBody, "pi_paid", "pi_second", 1))
+
+	rec := getInvoice(t, testOperator, receipts, invoices, "pi_paid")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="INV-00000001.pdf"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	body := rec.Body.Bytes()
+	if !bytes.HasPrefix(body, []byte("%PDF-")) || !bytes.Contains(body[len(body)-16:], []byte("%%EOF")) {
+		t.Fatalf("not a PDF: %q", body)
+	}
+	// 22,40 gross at 7%: 20,93 net and 1,47 VAT. The standard fonts use
+	// cp1252, where ß is 0xdf and € is 0x80.
+	content := pdfContent(t, body)
+	for _, want := range []string{"INV-00000001", "DE123456789", "Musterstra\xdfe", "20,93 \x80", "1,47 \x80", "22,40 \x80"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("invoice does not contain %q", want)
+		}
+	}
+
+	if cd := getInvoice(t, testOperator, receipts, invoices, "pi_second").Header().Get("Content-Disposition"); !strings.Contains(cd, "INV-00000002") {
+		t.Errorf("second payment: %q, want the next invoice number", cd)
+	}
+	if cd := getInvoice(t, testOperator, receipts, invoices, "pi_paid").Header().Get("Content-Disposition"); !strings.Contains(cd, "INV-00000001") {
+		t.Errorf("downloading again: %q, want the same invoice number", cd)
+	}
+}
+
+func TestInvoiceDatesAreBerlinDays(t *testing.T) {
+	// 23:30 UTC on 31 December is already New Year's Day in Berlin
+	issued := time.Date(2024, 12, 31, 23, 30, 0, 0, time.UTC)
+	ride := time.Date(2024, 7, 14, 22, 15, 0, 0, time.UTC)
+	receipt := Receipt{PaymentID: "pi_paid", RideID: "ride-1", RideDate: &ride, IssuedAt: issued, TotalCents: 2240, NetCents: 2093, VATRatePercent: 7, VATCents: 147}
+
+	body, err := renderInvoicePDF(*testOperator, Invoice{Number: "INV-00000001", IssuedAt: issued}, receipt)
+	if err != nil {
+		t.Fatalf("renderInvoicePDF: %v", err)
+	}
+	content := pdfContent(t, body)
+	for _, want := range []string{"Rechnungsdatum: 01.01.2025", "Fahrtdatum: 15.07.2024"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("invoice does not contain %q", want)
+		}
+	}
+}
+
+func TestInvoiceRequiresReceiptAndOperator(t *testing.T) {
+	receipts, _ := newTestReceiptStore(t)
+	if rec := getInvoice(t, testOperator, receipts, newMemoryInvoices(), "pi_unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("no receipt: status = %d, want 404", rec.Code)
+	}
+	if rec := getInvoice(t, nil, receipts, newMemoryInvoices(), "pi_paid"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("no operator details: status = %d, want 503", rec.Code)
+	}
+	if rec := getInvoice(t, testOperator, receipts, nil, "pi_paid"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("no invoice database: status = %d, want 503", rec.Code)
+	}
+}
+
+func TestInvoiceSequenceUnavailable(t *testing.T) {
+	receipts, _ := newTestReceiptStore(t)
+	postReceipt(paidIntentAPI(), receipts, testReceiptBody)
+	invoices := newMemoryInvoices()
+	invoices.err = errors.New("connection refused")
+
+	if rec := getInvoice(t, testOperator, receipts, invoices, "pi_paid"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
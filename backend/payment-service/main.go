@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
 	"os"
 )
 
@@ -15,15 +17,71 @@ func main() {
 		port = "8080"
 	}
 
+	commissionPercent, err := loadCommissionPercent()
+	if err != nil {
+		log.Fatal(err)
+	}
+	payments := newStripeAPI()
+	if payments == nil {
+		log.Println("WARNING: STRIPE_SECRET_KEY is not set. POST /payments will respond 503.")
+	}
+
+	tse, generated, err := loadSoftwareTSE()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if generated {
+		log.Println("WARNING: TSE_SIGNING_KEY is not set. The software TSE signs with a key generated for this process.")
+	}
+	log.Printf("WARNING: Signing receipts with software TSE %s. It is not certified for KassenSichV; connect a hardware or cloud TSE in production.", tse.serial)
+
+	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		log.Println("WARNING: STRIPE_WEBHOOK_SECRET is not set. POST /webhooks/stripe will respond 503.")
+	}
+	statuses := NewPaymentStatusStore()
+
+	operator := loadOperatorDetails()
+	if operator == nil {
+		log.Println("WARNING: OPERATOR_NAME, OPERATOR_ADDRESS or OPERATOR_VAT_ID is not set. GET /payments/{id}/invoice will respond 503.")
+	}
+	// Invoice numbers and receipt transaction counters must continue across
+	// restarts, so both are only issued from the database
+	var invoices InvoiceRepository
+	var receipts *ReceiptStore
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		db, err := openPostgres(dsn)
+		if err != nil {
+			log.Fatalf("Failed to open payment database: %v", err)
+		}
+		defer db.Close()
+		invoices = NewPostgresInvoiceRepository(db)
+
+		receiptRepo := NewPostgresReceiptRepository(db)
+		if tse.counter, err = receiptRepo.LastSignatureCounter(context.Background(), tse.serial); err != nil {
+			log.Fatalf("Failed to read the TSE signature counter: %v", err)
+		}
+		receipts = NewReceiptStore(tse, receiptRepo)
+	} else {
+		log.Println("WARNING: DATABASE_URL is not set. POST /receipts and GET /payments/{id}/invoice will respond 503.")
+	}
+
 	router := mux.NewRouter()
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Payment Service is healthy")
 	})
+	router.HandleFunc("/version", buildinfo.Handler("payment-service")).Methods("GET")
 	router.HandleFunc("/accounts", createStripeAccountHandler).Methods("POST")
 	router.HandleFunc("/accounts/{id}/onboarding", getStripeOnboardingLinkHandler).Methods("GET")
+	router.HandleFunc("/payments", createPaymentHandler(payments, commissionPercent)).Methods("POST")
+	router.HandleFunc("/payments/{id}", paymentStatusHandler(statuses)).Methods("GET")
+	router.HandleFunc("/payments/{id}/refund", refundPaymentHandler(payments, NewRefundLedger())).Methods("POST")
+	router.HandleFunc("/payments/{id}/invoice", invoiceHandler(operator, receipts, invoices)).Methods("GET")
+	router.HandleFunc("/webhooks/stripe", stripeWebhookHandler(webhookSecret, statuses)).Methods("POST")
+	router.HandleFunc("/receipts", createReceiptHandler(payments, receipts)).Methods("POST")
 
 	// TODO: Implement Stripe Connect handlers
-	// TODO: Implement TSE (Technical Security Device) integration
+	// TODO: Replace the software TSE with a certified TSE (Technical Security Device)
 
 	log.Printf("Payment Service starting on port %s...", port)
 	if err := http.ListenAndServe(":"+port, router); err != nil {
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/paymentintent"
+	"github.com/stripe/stripe-go/v76/refund"
+)
+
+// defaultCommissionPercent is the platform commission taken from each ride
+// payment when PLATFORM_COMMISSION_PERCENT is not set
+const defaultCommissionPercent = 15.0
+
+// minAmountCents is Stripe's minimum charge amount for EUR
+const minAmountCents = 50
+
+// stripeAPI is the part of the Stripe SDK used for payments. stripeClient
+// implements it; tests substitute a fake.
+type stripeAPI interface {
+	NewPaymentIntent(params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error)
+	GetPaymentIntent(id string, params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error)
+	NewRefund(params *stripe.RefundParams) (*stripe.Refund, error)
+}
+
+type stripeClient struct {
+	intents *paymentintent.Client
+	refunds *refund.Client
+}
+
+func (c *stripeClient) NewPaymentIntent(params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error) {
+	return c.intents.New(params)
+}
+
+func (c *stripeClient) GetPaymentIntent(id string, params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error) {
+	return c.intents.Get(id, params)
+}
+
+func (c *stripeClient) NewRefund(params *stripe.RefundParams) (*stripe.Refund, error) {
+	return c.refunds.New(params)
+}
+
+// newStripeAPI returns the Stripe client for STRIPE_SECRET_KEY, or nil when no
+// key is configured
+func newStripeAPI() stripeAPI {
+	key := os.Getenv("STRIPE_SECRET_KEY")
+	if key == "" {
+		return nil
+	}
+	backend := stripe.GetBackend(stripe.APIBackend)
+	return &stripeClient{
+		intents: &paymentintent.Client{B: backend, Key: key},
+		refunds: &refund.Client{B: backend, Key: key},
+	}
+}
+
+// loadCommissionPercent reads PLATFORM_COMMISSION_PERCENT, e.g. "15" or "12.5"
+func loadCommissionPercent() (float64, error) {
+	v := os.Getenv("PLATFORM_COMMISSION_PERCENT")
+	if v == "" {
+		return defaultCommissionPercent, nil
+	}
+	pct, err := strconv.ParseFloat(v, 64)
+	if err != nil || pct < 0 || pct >= 100 {
+		return 0, fmt.Errorf("PLATFORM_COMMISSION_PERCENT must be a number in [0, 100), got %q", v)
+	}
+	return pct, nil
+}
+
+type createPaymentRequest struct {
+	RideID          string `json:"ride_id"`
+	AmountCents     int64  `json:"amount_cents"`
+	DriverAccountID string `json:"driver_account_id"`
+}
+
+type createPaymentResponse struct {
+	PaymentIntentID     string `json:"payment_intent_id"`
+	ClientSecret        string `json:"client_secret"`
+	Status              string `json:"status"`
+	AmountCents         int64  `json:"amount_cents"`
+	ApplicationFeeCents int64  `json:"application_fee_cents"`
+	Currency            string `json:"currency"`
+}
+
+// createPaymentHandler serves POST /payments. It creates a PaymentIntent in
+// EUR that pays the driver's connected account as a destination charge and
+// keeps the platform commission as the application fee. The client secret is
+// returned so the app can confirm the payment with the Stripe SDK.
+func createPaymentHandler(api stripeAPI, commissionPercent float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api == nil {
+			http.Error(w, "Payments are not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		var input createPaymentRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if input.RideID == "" {
+			http.Error(w, "ride_id is required", http.StatusBadRequest)
+			return
+		}
+		if input.AmountCents < minAmountCents {
+			http.Error(w, fmt.Sprintf("amount_cents must be at least %d", minAmountCents), http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(input.DriverAccountID, "acct_") {
+			http.Error(w, "driver_account_id must be a Stripe connected account ID", http.StatusBadRequest)
+			return
+		}
+
+		fee := int64(math.Round(float64(input.AmountCents) * commissionPercent / 100))
+
+		params := &stripe.PaymentIntentParams{
+			Amount:               stripe.Int64(input.AmountCents),
+			Currency:             stripe.String(string(stripe.CurrencyEUR)),
+			ApplicationFeeAmount: stripe.Int64(fee),
+			TransferData: &stripe.PaymentIntentTransferDataParams{
+				Destination: stripe.String(input.DriverAccountID),
+			},
+			AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+				Enabled: stripe.Bool(true),
+			},
+		}
+		params.AddMetadata("ride_id", input.RideID)
+		// A retried request for the same ride returns the existing intent
+		// instead of charging the rider twice
+		params.SetIdempotencyKey("ride-payment-" + input.RideID)
+
+		pi, err := api.NewPaymentIntent(params)
+		if err != nil {
+			status, message := stripeErrorResponse(err)
+			log.Printf("Stripe PaymentIntent for ride %s failed (responding %d): %v", input.RideID, status, err)
+			http.Error(w, message, status)
+			return
+		}
+
+		log.Printf("Created PaymentIntent %s for ride %s: %d cents, fee %d cents, destination %s", pi.ID, input.RideID, input.AmountCents, fee, input.DriverAccountID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(createPaymentResponse{
+			PaymentIntentID:     pi.ID,
+			ClientSecret:        pi.ClientSecret,
+			Status:              string(pi.Status),
+			AmountCents:         input.AmountCents,
+			ApplicationFeeCents: fee,
+			Currency:            string(stripe.CurrencyEUR),
+		})
+	}
+}
+
+// stripeErrorResponse maps a Stripe error to the status and message returned
+// to the caller. Raw Stripe errors are only logged; card decline messages are
+// passed through because Stripe writes them for end users.
+func stripeErrorResponse(err error) (int, string) {
+	var stripeErr *stripe.Error
+	if !errors.As(err, &stripeErr) {
+		return http.StatusBadGateway, "Payment provider unavailable"
+	}
+
+	switch {
+	case stripeErr.Type == stripe.ErrorTypeCard:
+		if stripeErr.Msg != "" {
+			return http.StatusPaymentRequired, stripeErr.Msg
+		}
+		return http.StatusPaymentRequired, "The payment method was declined"
+	case stripeErr.HTTPStatusCode == http.StatusUnauthorized || stripeErr.HTTPStatusCode == http.StatusForbidden:
+		// Our API key is wrong or lacks permissions; not the caller's fault
+		return http.StatusBadGateway, "Payment provider unavailable"
+	case stripeErr.HTTPStatusCode == http.StatusTooManyRequests:
+		return http.StatusServiceUnavailable, "Payment provider is busy, please retry"
+	case stripeErr.Type == stripe.ErrorTypeIdempotency:
+		return http.StatusConflict, "A different payment for this ride is already in progress"
+	case stripeErr.Type == stripe.ErrorTypeInvalidRequest:
+		if stripeErr.Param == "transfer_data[destination]" {
+			return http.StatusBadRequest, "The driver's payout account is not ready to receive payments"
+		}
+		return http.StatusBadRequest, "The payment request was rejected by the payment provider"
+	default:
+		return http.StatusBadGateway, "Payment provider unavailable"
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stripe/stripe-go/v76"
+)
+
+// fakeStripe records the params it is called with and returns a fixed intent
+// or error. GetPaymentIntent returns existing.
+type fakeStripe struct {
+	params       *stripe.PaymentIntentParams
+	existing     *stripe.PaymentIntent
+	refundParams []*stripe.RefundParams
+	err          error
+	refundErr    error
+}
+
+func (f *fakeStripe) NewPaymentIntent(params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error) {
+	f.params = params
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &stripe.PaymentIntent{
+		ID:           "pi_test_1",
+		ClientSecret: "pi_test_1_secret_abc",
+		Status:       stripe.PaymentIntentStatusRequiresPaymentMethod,
+	}, nil
+}
+
+func (f *fakeStripe) GetPaymentIntent(id string, params *stripe.PaymentIntentParams) (*stripe.PaymentIntent, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.existing == nil || f.existing.ID != id {
+		return nil, &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusNotFound, Msg: "No such payment_intent: '" + id + "'"}
+	}
+	return f.existing, nil
+}
+
+func (f *fakeStripe) NewRefund(params *stripe.RefundParams) (*stripe.Refund, error) {
+	f.refundParams = append(f.refundParams, params)
+	if f.refundErr != nil {
+		return nil, f.refundErr
+	}
+	return &stripe.Refund{
+		ID:     fmt.Sprintf("re_test_%d", len(f.refundParams)),
+		Amount: *params.Amount,
+		Status: stripe.RefundStatusSucceeded,
+	}, nil
+}
+
+func postPayment(api stripeAPI, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	createPaymentHandler(api, 15)(rec, httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body)))
+	return rec
+}
+
+func TestCreatePayment(t *testing.T) {
+	fake := &fakeStripe{}
+	rec := postPayment(fake, `{"ride_id": "ride-1", "amount_cents": 2350, "driver_account_id": "acct_123"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp createPaymentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ClientSecret != "pi_test_1_secret_abc" || resp.ApplicationFeeCents != 353 || resp.Currency != "eur" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	p := fake.params
+	if *p.Amount != 2350 || *p.Currency != "eur" || *p.ApplicationFeeAmount != 353 || *p.TransferData.Destination != "acct_123" {
+		t.Errorf("unexpected params: amount %d, currency %s, fee %d, destination %s", *p.Amount, *p.Currency, *p.ApplicationFeeAmount, *p.TransferData.Destination)
+	}
+	if p.IdempotencyKey == nil || *p.IdempotencyKey != "ride-payment-ride-1" {
+		t.Errorf("idempotency key = %v, want ride-payment-ride-1", p.IdempotencyKey)
+	}
+}
+
+func TestCreatePaymentValidation(t *testing.T) {
+	for _, body := range []string{
+		`{"amount_cents": 2350, "driver_account_id": "acct_123"}`,
+		`{"ride_id": "ride-1", "amount_cents": 10, "driver_account_id": "acct_123"}`,
+		`{"ride_id": "ride-1", "amount_cents": 2350, "driver_account_id": "driver-7"}`,
+		`not json`,
+	} {
+		fake := &fakeStripe{}
+		if rec := postPayment(fake, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+		if fake.params != nil {
+			t.Errorf("%s: Stripe was called for an invalid request", body)
+		}
+	}
+}
+
+func TestCreatePaymentNotConfigured(t *testing.T) {
+	if rec := postPayment(nil, `{"ride_id": "ride-1", "amount_cents": 2350, "driver_account_id": "acct_123"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestCreatePaymentMapsStripeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  *stripe.Error
+		want int
+	}{
+		{&stripe.Error{Type: stripe.ErrorTypeCard, Msg: "Your card was declined.", HTTPStatusCode: 402}, http.StatusPaymentRequired},
+		{&stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Param: "transfer_data[destination]", Msg: "No such destination: 'acct_123'", HTTPStatusCode: 400}, http.StatusBadRequest},
+		{&stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Msg: "Invalid API Key provided: sk_live_****", HTTPStatusCode: 401}, http.StatusBadGateway},
+		{&stripe.Error{Type: stripe.ErrorTypeInvalidRequest, HTTPStatusCode: 429}, http.StatusServiceUnavailable},
+		{&stripe.Error{Type: stripe.ErrorTypeIdempotency, HTTPStatusCode: 400}, http.StatusConflict},
+		{&stripe.Error{Type: stripe.ErrorTypeAPI, Msg: "internal", HTTPStatusCode: 500}, http.StatusBadGateway},
+	} {
+		rec := postPayment(&fakeStripe{err: tc.err}, `{"ride_id": "ride-1", "amount_cents": 2350, "driver_account_id": "acct_123"}`)
+		if rec.Code != tc.want {
+			t.Errorf("%s/%d: status = %d, want %d", tc.err.Type, tc.err.HTTPStatusCode, rec.Code, tc.want)
+		}
+		if body := rec.Body.String(); strings.Contains(body, "acct_123") || strings.Contains(body, "sk_live") {
+			t.Errorf("%s/%d: response leaks Stripe error details: %s", tc.err.Type, tc.err.HTTPStatusCode, body)
+		}
+	}
+}
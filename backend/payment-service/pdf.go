@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// pdfDocument is a single A4 page for invoices, drawn with fpdf. It only
+// places text in the standard Helvetica fonts, which every PDF reader
+// provides, so no font data has to be embedded.
+type pdfDocument struct {
+	pdf *fpdf.Fpdf
+	// tr converts UTF-8 to the cp1252 encoding of the standard fonts, so
+	// umlauts and the euro sign are shown
+	tr func(string) string
+}
+
+// A4 page size in PostScript points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+)
+
+func newPDFDocument() *pdfDocument {
+	pdf := fpdf.New("P", "pt", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	return &pdfDocument{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+// Text writes s at (x, y), measured in points from the bottom left corner
+func (d *pdfDocument) Text(x, y, size float64, bold bool, s string) {
+	style := ""
+	if bold {
+		style = "B"
+	}
+	d.pdf.SetFont("Helvetica", style, size)
+	d.pdf.Text(x, pdfPageHeight-y, d.tr(s))
+}
+
+// Line draws a horizontal rule from x1 to x2 at y
+func (d *pdfDocument) Line(x1, x2, y float64) {
+	d.pdf.SetLineWidth(0.5)
+	d.pdf.Line(x1, pdfPageHeight-y, x2, pdfPageHeight-y)
+}
+
+// Bytes renders the document. fpdf collects drawing errors, e.g. an unknown
+// font, and reports the first one here.
+func (d *pdfDocument) Bytes() ([]byte, error) {
+	var out bytes.Buffer
+	if err := d.pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
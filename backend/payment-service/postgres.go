@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+//go:embed schema.sql
+var schema string
+
+// PostgresInvoiceRepository is the InvoiceRepository backed by PostgreSQL,
+// see schema.sql
+type PostgresInvoiceRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresInvoiceRepository(db *sql.DB) *PostgresInvoiceRepository {
+	return &PostgresInvoiceRepository{db: db}
+}
+
+// openPostgres connects to dsn and applies schema.sql. Invoices and receipts
+// share the database.
+func openPostgres(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying schema: %w", err)
+	}
+	return db, nil
+}
+
+// Issue locks the sequence row before looking for an existing invoice, so
+// concurrent first downloads of one payment cannot both take a number
+func (p *PostgresInvoiceRepository) Issue(ctx context.Context, paymentID string, now time.Time) (Invoice, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Invoice{}, err
+	}
+	defer tx.Rollback()
+
+	var last int64
+	if err := tx.QueryRowContext(ctx, `SELECT last_number FROM invoice_sequence FOR UPDATE`).Scan(&last); err != nil {
+		return Invoice{}, fmt.Errorf("locking invoice sequence: %w", err)
+	}
+
+	var number int64
+	var issuedAt time.Time
+	err = tx.QueryRowContext(ctx, `SELECT number, issued_at FROM invoices WHERE payment_id = $1`, paymentID).Scan(&number, &issuedAt)
+	if err == nil {
+		return Invoice{Number: formatInvoiceNumber(number), IssuedAt: issuedAt.UTC()}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Invoice{}, err
+	}
+
+	number = last + 1
+	if _, err := tx.ExecContext(ctx, `UPDATE invoice_sequence SET last_number = $1`, number); err != nil {
+		return Invoice{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO invoices (payment_id, number, issued_at) VALUES ($1, $2, $3)`, paymentID, number, now); err != nil {
+		return Invoice{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Invoice{}, err
+	}
+	return Invoice{Number: formatInvoiceNumber(number), IssuedAt: now}, nil
+}
+
+// PostgresReceiptRepository is the ReceiptRepository backed by PostgreSQL,
+// see schema.sql. Receipts are stored as JSON as signed.
+type PostgresReceiptRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReceiptRepository(db *sql.DB) *PostgresReceiptRepository {
+	return &PostgresReceiptRepository{db: db}
+}
+
+func (p *PostgresReceiptRepository) Get(ctx context.Context, paymentID string) (Receipt, bool, error) {
+	var data []byte
+	err := p.db.QueryRowContext(ctx, `SELECT data FROM receipts WHERE payment_id = $1`, paymentID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Receipt{}, false, nil
+	}
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	var receipt Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return Receipt{}, false, fmt.Errorf("decoding receipt for payment %s: %w", paymentID, err)
+	}
+	return receipt, true, nil
+}
+
+// Issue locks the sequence row before looking for an existing receipt, so
+// concurrent requests for one payment cannot both take a counter
+func (p *PostgresReceiptRepository) Issue(ctx context.Context, paymentID string, build func(counter uint64) (Receipt, error)) (Receipt, bool, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	defer tx.Rollback()
+
+	var last int64
+	if err := tx.QueryRowContext(ctx, `SELECT last_counter FROM receipt_sequence FOR UPDATE`).Scan(&last); err != nil {
+		return Receipt{}, false, fmt.Errorf("locking receipt sequence: %w", err)
+	}
+
+	var data []byte
+	err = tx.QueryRowContext(ctx, `SELECT data FROM receipts WHERE payment_id = $1`, paymentID).Scan(&data)
+	if err == nil {
+		var existing Receipt
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return Receipt{}, false, fmt.Errorf("decoding receipt for payment %s: %w", paymentID, err)
+		}
+		return existing, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Receipt{}, false, err
+	}
+
+	counter := last + 1
+	receipt, err := build(uint64(counter))
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	if data, err = json.Marshal(receipt); err != nil {
+		return Receipt{}, false, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE receipt_sequence SET last_counter = $1`, counter); err != nil {
+		return Receipt{}, false, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO receipts (payment_id, transaction_counter, data) VALUES ($1, $2, $3)`, paymentID, counter, string(data)); err != nil {
+		return Receipt{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Receipt{}, false, err
+	}
+	return receipt, true, nil
+}
+
+// LastSignatureCounter returns the highest TSE signature counter stored for
+// the TSE serial number, so a software TSE resumes its count after a restart
+func (p *PostgresReceiptRepository) LastSignatureCounter(ctx context.Context, serial string) (uint64, error) {
+	var last int64
+	err := p.db.QueryRowContext(ctx, `SELECT COALESCE(MAX((data->'tse'->>'signature_counter')::BIGINT), 0) FROM receipts WHERE data->'tse'->>'serial_number' = $1`, serial).Scan(&last)
+	return uint64(last), err
+}
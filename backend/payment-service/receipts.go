@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/vat"
+	"github.com/stripe/stripe-go/v76"
+)
+
+// TSESignature is the signature a technical security device (TSE) issues for
+// a transaction, as printed on the receipt under KassenSichV.
+type TSESignature struct {
+	SerialNumber     string    `json:"serial_number"`
+	Algorithm        string    `json:"algorithm"`
+	SignatureCounter uint64    `json:"signature_counter"`
+	Timestamp        time.Time `json:"timestamp"`
+	Value            string    `json:"value"`
+}
+
+// TSESigner signs receipt data. The software implementation below is a
+// stand-in; a certified hardware or cloud TSE (BSI TR-03153) implements the
+// same interface later.
+type TSESigner interface {
+	Sign(data []byte) (TSESignature, error)
+}
+
+// softwareTSE signs with an Ed25519 key from TSE_SIGNING_KEY. It is not a
+// certified TSE and must not be used for real fiscal receipts.
+type softwareTSE struct {
+	mu      sync.Mutex
+	key     ed25519.PrivateKey
+	serial  string
+	counter uint64
+}
+
+// newSoftwareTSE returns a software TSE with a freshly generated key
+func newSoftwareTSE() (*softwareTSE, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate software TSE key: %w", err)
+	}
+	return newSoftwareTSEFromKey(key), nil
+}
+
+func newSoftwareTSEFromKey(key ed25519.PrivateKey) *softwareTSE {
+	pub := key.Public().(ed25519.PublicKey)
+	return &softwareTSE{key: key, serial: "SOFT-TSE-" + hex.EncodeToString(pub[:8])}
+}
+
+// loadSoftwareTSE reads the base64-encoded 32-byte Ed25519 seed in
+// TSE_SIGNING_KEY. Without it a key is generated, so the serial number and
+// signing key change on every restart; generated reports that case.
+func loadSoftwareTSE() (tse *softwareTSE, generated bool, err error) {
+	v := os.Getenv("TSE_SIGNING_KEY")
+	if v == "" {
+		tse, err = newSoftwareTSE()
+		return tse, true, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, false, fmt.Errorf("TSE_SIGNING_KEY must be a base64-encoded %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	return newSoftwareTSEFromKey(ed25519.NewKeyFromSeed(seed)), false, nil
+}
+
+func (t *softwareTSE) Sign(data []byte) (TSESignature, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counter++
+	return TSESignature{
+		SerialNumber:     t.serial,
+		Algorithm:        "Ed25519",
+		SignatureCounter: t.counter,
+		Timestamp:        timeutil.Now(),
+		Value:            base64.StdEncoding.EncodeToString(ed25519.Sign(t.key, data)),
+	}, nil
+}
+
+// ReceiptLine is one fare component, gross (VAT included)
+type ReceiptLine struct {
+	Description string `json:"description"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+// Receipt is the fiscal receipt for a ride payment. Everything except IssuedAt
+// and TSE follows from the request and the transaction counter.
+type Receipt struct {
+	ReceiptNumber      string        `json:"receipt_number"`
+	TransactionCounter uint64        `json:"transaction_counter"`
+	PaymentID          string        `json:"payment_id"`
+	RideID             string        `json:"ride_id"`
+	RiderName          string        `json:"rider_name,omitempty"`
+	RideDate           *time.Time    `json:"ride_date,omitempty"`
+	DistanceKm         float64       `json:"distance_km"`
+	Lines              []ReceiptLine `json:"lines"`
+	Currency           string        `json:"currency"`
+	TotalCents         int64         `json:"total_cents"`
+	NetCents           int64         `json:"net_cents"`
+	VATRatePercent     float64       `json:"vat_rate_percent"`
+	VATCents           int64         `json:"vat_cents"`
+	IssuedAt           time.Time     `json:"issued_at"`
+	TSE                *TSESignature `json:"tse,omitempty"`
+}
+
+// createReceiptRequest is the payload for POST /receipts. RiderName and
+// RideDate are optional and printed on the invoice.
+type createReceiptRequest struct {
+	PaymentID  string        `json:"payment_id"`
+	RideID     string        `json:"ride_id"`
+	RiderName  string        `json:"rider_name,omitempty"`
+	RideDate   *time.Time    `json:"ride_date,omitempty"`
+	DistanceKm float64       `json:"distance_km"`
+	Lines      []ReceiptLine `json:"lines"`
+}
+
+// buildReceipt derives the receipt body from the request and counter. It is
+// deterministic; IssuedAt and TSE are set by the caller.
+func buildReceipt(req createReceiptRequest, counter uint64) Receipt {
+	var total int64
+	for _, line := range req.Lines {
+		total += line.AmountCents
+	}
+
+	// Fares are gross; the split rounds like the pricing-service quote, so the
+	// receipt shows the net and VAT the rider was quoted
+	percent := vat.Percent(req.DistanceKm)
+	net, vatCents := vat.Split(total, percent)
+
+	return Receipt{
+		ReceiptNumber:      fmt.Sprintf("R-%08d", counter),
+		TransactionCounter: counter,
+		PaymentID:          req.PaymentID,
+		RideID:             req.RideID,
+		RiderName:          req.RiderName,
+		RideDate:           req.RideDate,
+		DistanceKm:         req.DistanceKm,
+		Lines:              req.Lines,
+		Currency:           "EUR",
+		TotalCents:         total,
+		NetCents:           net,
+		VATRatePercent:     float64(percent),
+		VATCents:           vatCents,
+	}
+}
+
+// errReceiptMismatch is returned when a payment that already has a receipt is
+// submitted again with different details
+var errReceiptMismatch = errors.New("payment already has a receipt with different details")
+
+// errSigningFailed wraps TSE errors, which leave the receipt unissued
+var errSigningFailed = errors.New("TSE signing failed")
+
+// ReceiptRepository keeps receipts by payment ID and assigns their
+// transaction counters. KassenSichV requires the counter to continue across
+// restarts, so the sequence must be durable; see PostgresReceiptRepository.
+type ReceiptRepository interface {
+	// Get returns the receipt issued for the payment
+	Get(ctx context.Context, paymentID string) (Receipt, bool, error)
+	// Issue returns the payment's receipt with created false if it has one.
+	// Otherwise it calls build with the next transaction counter and stores
+	// the result; the counter only advances if build succeeds.
+	Issue(ctx context.Context, paymentID string, build func(counter uint64) (Receipt, error)) (receipt Receipt, created bool, err error)
+}
+
+// ReceiptStore signs receipts and keeps them in a ReceiptRepository, so a
+// payment is receipted at most once
+type ReceiptStore struct {
+	signer TSESigner
+	repo   ReceiptRepository
+}
+
+func NewReceiptStore(signer TSESigner, repo ReceiptRepository) *ReceiptStore {
+	return &ReceiptStore{signer: signer, repo: repo}
+}
+
+// Get returns the receipt issued for the payment
+func (s *ReceiptStore) Get(ctx context.Context, paymentID string) (Receipt, bool, error) {
+	return s.repo.Get(ctx, paymentID)
+}
+
+// Issue returns the receipt for the payment, creating and signing it on first
+// use. created is false when the payment already had a receipt.
+func (s *ReceiptStore) Issue(ctx context.Context, req createReceiptRequest) (receipt Receipt, created bool, err error) {
+	// The repository only advances the counter once the receipt is signed, so
+	// a TSE outage leaves no gap in the sequence
+	receipt, created, err = s.repo.Issue(ctx, req.PaymentID, func(counter uint64) (Receipt, error) {
+		receipt := buildReceipt(req, counter)
+		receipt.IssuedAt = timeutil.Now()
+
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return Receipt{}, err
+		}
+		sig, err := s.signer.Sign(data)
+		if err != nil {
+			return Receipt{}, fmt.Errorf("%w: %v", errSigningFailed, err)
+		}
+		receipt.TSE = &sig
+		return receipt, nil
+	})
+	if err != nil || created {
+		return receipt, created, err
+	}
+
+	want := buildReceipt(req, receipt.TransactionCounter)
+	want.IssuedAt, want.TSE = receipt.IssuedAt, receipt.TSE
+	if !reflect.DeepEqual(want, receipt) {
+		return receipt, false, errReceiptMismatch
+	}
+	return receipt, false, nil
+}
+
+// createReceiptHandler serves POST /receipts. The payment must have succeeded
+// at Stripe and its amount must equal the sum of the fare lines.
+func createReceiptHandler(api stripeAPI, receipts *ReceiptStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api == nil {
+			http.Error(w, "Payments are not configured", http.StatusServiceUnavailable)
+			return
+		}
+		if receipts == nil {
+			http.Error(w, "Receipts are not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		var input createReceiptRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if input.PaymentID == "" || input.RideID == "" {
+			http.Error(w, "payment_id and ride_id are required", http.StatusBadRequest)
+			return
+		}
+		if input.DistanceKm < 0 || math.IsNaN(input.DistanceKm) || math.IsInf(input.DistanceKm, 0) {
+			http.Error(w, "distance_km must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		if len(input.Lines) == 0 {
+			http.Error(w, "lines must contain at least one fare component", http.StatusBadRequest)
+			return
+		}
+		var total int64
+		for _, line := range input.Lines {
+			if strings.TrimSpace(line.Description) == "" || line.AmountCents < 0 {
+				http.Error(w, "each line needs a description and a non-negative amount_cents", http.StatusBadRequest)
+				return
+			}
+			total += line.AmountCents
+		}
+
+		pi, err := api.GetPaymentIntent(input.PaymentID, nil)
+		if err != nil {
+			status, message := stripeErrorResponse(err)
+			log.Printf("Stripe lookup of PaymentIntent %s failed (responding %d): %v", input.PaymentID, status, err)
+			http.Error(w, message, status)
+			return
+		}
+		if pi.Status != stripe.PaymentIntentStatusSucceeded {
+			http.Error(w, fmt.Sprintf("Payment is %s, receipts are only issued for completed payments", pi.Status), http.StatusConflict)
+			return
+		}
+		if pi.Amount != total {
+			http.Error(w, fmt.Sprintf("Fare lines add up to %d cents but the payment was %d cents", total, pi.Amount), http.StatusBadRequest)
+			return
+		}
+
+		receipt, created, err := receipts.Issue(r.Context(), input)
+		if errors.Is(err, errReceiptMismatch) {
+			http.Error(w, "Payment already has a receipt with different details", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errSigningFailed) {
+			log.Printf("TSE signing of receipt for payment %s failed: %v", input.PaymentID, err)
+			http.Error(w, "Receipt could not be signed", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR: storing receipt for payment %s: %v", input.PaymentID, err)
+			http.Error(w, "Failed to issue receipt", http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusCreated
+		if created {
+			log.Printf("Issued receipt %s for payment %s (ride %s), TSE counter %d", receipt.ReceiptNumber, receipt.PaymentID, receipt.RideID, receipt.TSE.SignatureCounter)
+		} else {
+			status = http.StatusOK
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(receipt)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stripe/stripe-go/v76"
+)
+
+const testReceiptBody = `{"payment_id": "pi_paid", "ride_id": "ride-1", "distance_km": 12.4,
+	"lines": [{"description": "Grundpreis", "amount_cents": 450}, {"description": "Strecke 12,4 km", "amount_cents": 1790}]}`
+
+func paidIntentAPI() *fakeStripe {
+	return &fakeStripe{existing: &stripe.PaymentIntent{ID: "pi_paid", Amount: 2240, Status: stripe.PaymentIntentStatusSucceeded}}
+}
+
+// memoryReceipts is a ReceiptRepository for tests; production receipts are
+// stored by PostgresReceiptRepository
+type memoryReceipts struct {
+	mu        sync.Mutex
+	counter   uint64
+	byPayment map[string]Receipt
+}
+
+func newMemoryReceipts() *memoryReceipts {
+	return &memoryReceipts{byPayment: make(map[string]Receipt)}
+}
+
+func (m *memoryReceipts) Get(ctx context.Context, paymentID string) (Receipt, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	receipt, ok := m.byPayment[paymentID]
+	return receipt, ok, nil
+}
+
+func (m *memoryReceipts) Issue(ctx context.Context, paymentID string, build func(counter uint64) (Receipt, error)) (Receipt, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.byPayment[paymentID]; ok {
+		return existing, false, nil
+	}
+	receipt, err := build(m.counter + 1)
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	m.counter++
+	m.byPayment[paymentID] = receipt
+	return receipt, true, nil
+}
+
+func newTestReceiptStore(t *testing.T) (*ReceiptStore, *softwareTSE) {
+	t.Helper()
+	tse, err := newSoftwareTSE()
+	if err != nil {
+		t.Fatalf("failed to create software TSE: %v", err)
+	}
+	return NewReceiptStore(tse, newMemoryReceipts()), tse
+}
+
+func postReceipt(api stripeAPI, receipts *ReceiptStore, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	createReceiptHandler(api, receipts)(rec, httptest.NewRequest(http.MethodPost, "/receipts", strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateReceipt(t *testing.T) {
+	receipts, tse := newTestReceiptStore(t)
+
+	rec := postReceipt(paidIntentAPI(), receipts, testReceiptBody)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var receipt Receipt
+	if err := json.NewDecoder(rec.Body).Decode(&receipt); err != nil {
+		t.Fatalf("failed to decode receipt: %v", err)
+	}
+
+	// 22.40 EUR gross at 7%: 1.47 EUR VAT
+	if receipt.TotalCents != 2240 || receipt.VATRatePercent != 7 || receipt.VATCents != 147 || receipt.NetCents != 2093 {
+		t.Errorf("unexpected amounts %+v", receipt)
+	}
+	if receipt.TransactionCounter != 1 || receipt.ReceiptNumber != "R-00000001" {
+		t.Errorf("counter %d, number %s, want 1 and R-00000001", receipt.TransactionCounter, receipt.ReceiptNumber)
+	}
+	if receipt.TSE == nil || receipt.TSE.SerialNumber != tse.serial || receipt.TSE.SignatureCounter != 1 {
+		t.Fatalf("unexpected TSE signature %+v", receipt.TSE)
+	}
+
+	// The signature covers the receipt without the TSE block
+	sig, err := base64.StdEncoding.DecodeString(receipt.TSE.Value)
+	if err != nil {
+		t.Fatalf("signature is not base64: %v", err)
+	}
+	unsigned := receipt
+	unsigned.TSE = nil
+	data, _ := json.Marshal(unsigned)
+	if !ed25519.Verify(tse.key.Public().(ed25519.PublicKey), data, sig) {
+		t.Error("TSE signature does not verify")
+	}
+}
+
+func TestCreateReceiptIsIdempotentPerPayment(t *testing.T) {
+	receipts, _ := newTestReceiptStore(t)
+	api := paidIntentAPI()
+
+	first := postReceipt(api, receipts, testReceiptBody)
+	again := postReceipt(api, receipts, testReceiptBody)
+	if again.Code != http.StatusOK || first.Body.String() != again.Body.String() {
+		t.Errorf("repeat: status %d, body %s, want the first receipt %s", again.Code, again.Body.String(), first.Body.String())
+	}
+
+	changed := strings.Replace(testReceiptBody, `"amount_cents": 450}, {"description": "Strecke 12,4 km", "amount_cents": 1790`, `"amount_cents": 440}, {"description": "Strecke 12,4 km", "amount_cents": 1800`, 1)
+	if rec := postReceipt(api, receipts, changed); rec.Code != http.StatusConflict {
+		t.Errorf("different lines for a receipted payment: status = %d, want 409", rec.Code)
+	}
+}
+
+func TestBuildReceiptIsDeterministic(t *testing.T) {
+	req := createReceiptRequest{PaymentID: "pi_1", RideID: "ride-1", DistanceKm: 80, Lines: []ReceiptLine{{"Fahrpreis", 11900}}}
+
+	a, b := buildReceipt(req, 42), buildReceipt(req, 42)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("receipts differ: %+v vs %+v", a, b)
+	}
+	// Beyond 50 km the standard rate applies: 119.00 EUR gross is 19.00 EUR VAT
+	if a.VATRatePercent != 19 || a.VATCents != 1900 || a.NetCents != 10000 {
+		t.Errorf("unexpected VAT for a long trip %+v", a)
+	}
+}
+
+func TestReceiptCounterIsMonotonic(t *testing.T) {
+	receipts, _ := newTestReceiptStore(t)
+
+	var last uint64
+	for _, id := range []string{"pi_a", "pi_b", "pi_c"} {
+		receipt, created, err := receipts.Issue(context.Background(), createReceiptRequest{PaymentID: id, RideID: "ride", Lines: []ReceiptLine{{"Fahrpreis", 1000}}})
+		if err != nil || !created {
+			t.Fatalf("%s: created %v, err %v", id, created, err)
+		}
+		if receipt.TransactionCounter != last+1 {
+			t.Errorf("%s: counter %d, want %d", id, receipt.TransactionCounter, last+1)
+		}
+		last = receipt.TransactionCounter
+	}
+}
+
+type failingTSE struct{}
+
+func (failingTSE) Sign([]byte) (TSESignature, error) {
+	return TSESignature{}, errors.New("TSE offline")
+}
+
+func TestReceiptCounterSkipsNothingWhenSigningFails(t *testing.T) {
+	receipts := NewReceiptStore(failingTSE{}, newMemoryReceipts())
+	if _, _, err := receipts.Issue(context.Background(), createReceiptRequest{PaymentID: "pi_a", Lines: []ReceiptLine{{"Fahrpreis", 1000}}}); err == nil {
+		t.Fatal("expected signing error")
+	}
+
+	tse, _ := newSoftwareTSE()
+	receipts.signer = tse
+	receipt, _, err := receipts.Issue(context.Background(), createReceiptRequest{PaymentID: "pi_a", Lines: []ReceiptLine{{"Fahrpreis", 1000}}})
+	if err != nil || receipt.TransactionCounter != 1 {
+		t.Errorf("counter %d, err %v, want 1", receipt.TransactionCounter, err)
+	}
+}
+
+func TestCreateReceiptRejections(t *testing.T) {
+	receipts, _ := newTestReceiptStore(t)
+
+	pending := &fakeStripe{existing: &stripe.PaymentIntent{ID: "pi_paid", Amount: 2240, Status: stripe.PaymentIntentStatusRequiresPaymentMethod}}
+	if rec := postReceipt(pending, receipts, testReceiptBody); rec.Code != http.StatusConflict {
+		t.Errorf("incomplete payment: status = %d, want 409", rec.Code)
+	}
+
+	wrongAmount := &fakeStripe{existing: &stripe.PaymentIntent{ID: "pi_paid", Amount: 2500, Status: stripe.PaymentIntentStatusSucceeded}}
+	if rec := postReceipt(wrongAmount, receipts, testReceiptBody); rec.Code != http.StatusBadRequest {
+		t.Errorf("amount mismatch: status = %d, want 400", rec.Code)
+	}
+
+	if rec := postReceipt(&fakeStripe{}, receipts, testReceiptBody); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown payment: status = %d, want 400", rec.Code)
+	}
+
+	for _, body := range []string{
+		`{"ride_id": "ride-1", "lines": [{"description": "Fahrpreis", "amount_cents": 100}]}`,
+		`{"payment_id": "pi_paid", "ride_id": "ride-1", "lines": []}`,
+		`{"payment_id": "pi_paid", "ride_id": "ride-1", "lines": [{"description": "", "amount_cents": 100}]}`,
+		`{"payment_id": "pi_paid", "ride_id": "ride-1", "distance_km": -1, "lines": [{"description": "Fahrpreis", "amount_cents": 100}]}`,
+	} {
+		if rec := postReceipt(paidIntentAPI(), receipts, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestCreateReceiptRequiresRepository(t *testing.T) {
+	if rec := postReceipt(paidIntentAPI(), nil, testReceiptBody); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestLoadSoftwareTSE(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	t.Setenv("TSE_SIGNING_KEY", base64.StdEncoding.EncodeToString(seed))
+	first, generated, err := loadSoftwareTSE()
+	if err != nil || generated {
+		t.Fatalf("configured key: generated %v, err %v", generated, err)
+	}
+	again, _, _ := loadSoftwareTSE()
+	if again.serial != first.serial || !again.key.Equal(ed25519.NewKeyFromSeed(seed)) {
+		t.Error("the configured key does not survive a restart")
+	}
+
+	t.Setenv("TSE_SIGNING_KEY", "c2hvcnQ=")
+	if _, _, err := loadSoftwareTSE(); err == nil {
+		t.Error("short seed accepted")
+	}
+
+	t.Setenv("TSE_SIGNING_KEY", "")
+	if tse, generated, err := loadSoftwareTSE(); err != nil || !generated || tse == nil {
+		t.Errorf("unset key: generated %v, err %v", generated, err)
+	}
+}
+
+// The contract test runs against every ReceiptRepository, as for invoices
+
+func TestMemoryReceiptsContract(t *testing.T) {
+	testReceiptRepositoryContract(t, newMemoryReceipts())
+}
+
+func TestPostgresReceiptRepositoryContract(t *testing.T) {
+	db := openTestDatabase(t)
+	if _, err := db.Exec(`TRUNCATE receipts; UPDATE receipt_sequence SET last_counter = 0`); err != nil {
+		t.Fatalf("failed to reset receipts: %v", err)
+	}
+	repo := NewPostgresReceiptRepository(db)
+	testReceiptRepositoryContract(t, repo)
+
+	// The counter and receipts survive reopening the database
+	reopened := NewPostgresReceiptRepository(openTestDatabase(t))
+	if receipt, ok, err := reopened.Get(context.Background(), "pi_first"); err != nil || !ok || receipt.TransactionCounter != 1 {
+		t.Errorf("after reopening: %+v, %v, %v", receipt, ok, err)
+	}
+	tse, _ := newSoftwareTSE()
+	receipt, _, err := NewReceiptStore(tse, reopened).Issue(context.Background(), createReceiptRequest{PaymentID: "pi_after_restart", Lines: []ReceiptLine{{"Fahrpreis", 1000}}})
+	if err != nil || receipt.TransactionCounter != 12 {
+		t.Errorf("after reopening: counter %d, err %v, want 12", receipt.TransactionCounter, err)
+	}
+	if last, err := reopened.LastSignatureCounter(context.Background(), tse.serial); err != nil || last != 1 {
+		t.Errorf("last signature counter %d, err %v, want 1", last, err)
+	}
+}
+
+func testReceiptRepositoryContract(t *testing.T, repo ReceiptRepository) {
+	ctx := context.Background()
+	receipts, _ := newTestReceiptStore(t)
+	receipts.repo = repo
+	issue := func(paymentID string) (Receipt, bool, error) {
+		return receipts.Issue(ctx, createReceiptRequest{PaymentID: paymentID, RideID: "ride-1", Lines: []ReceiptLine{{"Fahrpreis", 1000}}})
+	}
+
+	first, created, err := issue("pi_first")
+	if err != nil || !created || first.TransactionCounter != 1 {
+		t.Fatalf("first receipt: %+v, created %v, %v", first, created, err)
+	}
+	again, created, err := issue("pi_first")
+	if err != nil || created || !reflect.DeepEqual(again, first) {
+		t.Errorf("issuing again: %+v, created %v, %v; want the first receipt", again, created, err)
+	}
+	if got, ok, err := repo.Get(ctx, "pi_first"); err != nil || !ok || !reflect.DeepEqual(got, first) {
+		t.Errorf("Get: %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := repo.Get(ctx, "pi_unknown"); err != nil || ok {
+		t.Errorf("unknown payment: found %v, err %v", ok, err)
+	}
+
+	// A failed build takes no counter
+	if _, _, err := repo.Issue(ctx, "pi_failed", func(uint64) (Receipt, error) { return Receipt{}, errors.New("TSE offline") }); err == nil {
+		t.Error("expected the build error")
+	}
+
+	// Concurrent first requests still get distinct, consecutive counters
+	var wg sync.WaitGroup
+	counters := make([]uint64, 20)
+	for i := range counters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paymentID := fmt.Sprintf("pi_%d", i%10)
+			receipt, _, err := issue(paymentID)
+			if err != nil {
+				t.Errorf("%s: %v", paymentID, err)
+			}
+			counters[i] = receipt.TransactionCounter
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	for i := 0; i < 10; i++ {
+		if counters[i] != counters[i+10] {
+			t.Errorf("pi_%d receipted twice: %d and %d", i, counters[i], counters[i+10])
+		}
+		seen[counters[i]] = true
+	}
+	for n := uint64(2); n <= 11; n++ {
+		if !seen[n] {
+			t.Errorf("counter %d not issued; got %v", n, counters[:10])
+		}
+	}
+}
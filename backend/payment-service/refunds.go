@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go/v76"
+)
+
+// RefundLedger tracks how much of each payment has been refunded, so that
+// refunds never exceed the original charge, and the refund requests made
+// under each client Idempotency-Key, so that a retried request does not
+// refund twice. Amounts are reserved before Stripe is called and released if
+// the call fails.
+type RefundLedger struct {
+	mu       sync.Mutex
+	refunded map[string]int64
+	requests map[refundRequestKey]*refundRequest
+}
+
+// refundRequestKey identifies a refund request by payment and client key
+type refundRequestKey struct {
+	paymentID, key string
+}
+
+// refundRequest is a refund request seen under an Idempotency-Key. response
+// is nil while the request is being processed.
+type refundRequest struct {
+	amount   int64
+	response *refundResponse
+}
+
+func NewRefundLedger() *RefundLedger {
+	return &RefundLedger{refunded: make(map[string]int64), requests: make(map[refundRequestKey]*refundRequest)}
+}
+
+var (
+	errFullyRefunded  = errors.New("payment is already fully refunded")
+	errRefundTooLarge = errors.New("refund exceeds the refundable balance")
+
+	errRefundInProgress   = errors.New("a refund with this idempotency key is in progress")
+	errIdempotencyKeyUsed = errors.New("idempotency key was used for a different refund")
+)
+
+// Begin records a refund request of amount cents (zero for the remaining
+// balance) under the client's key. A request already completed under the
+// key returns its response, which the caller repeats instead of refunding
+// again.
+func (l *RefundLedger) Begin(paymentID, key string, amount int64) (*refundResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := refundRequestKey{paymentID, key}
+	if req, ok := l.requests[id]; ok {
+		switch {
+		case req.amount != amount:
+			return nil, errIdempotencyKeyUsed
+		case req.response == nil:
+			return nil, errRefundInProgress
+		}
+		resp := *req.response
+		return &resp, nil
+	}
+	l.requests[id] = &refundRequest{amount: amount}
+	return nil, nil
+}
+
+// Finish stores the response of a refund request, or forgets the request if
+// resp is nil so that the client can retry it
+func (l *RefundLedger) Finish(paymentID, key string, resp *refundResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := refundRequestKey{paymentID, key}
+	if resp == nil {
+		delete(l.requests, id)
+		return
+	}
+	l.requests[id].response = resp
+}
+
+// Reserve books a refund of amount cents against a payment of charged cents.
+// A zero amount refunds the whole remaining balance. It returns the amount
+// booked and what was refunded before.
+func (l *RefundLedger) Reserve(paymentID string, amount, charged int64) (booked, before int64, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	before = l.refunded[paymentID]
+	remaining := charged - before
+	if remaining <= 0 {
+		return 0, before, errFullyRefunded
+	}
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return 0, before, errRefundTooLarge
+	}
+
+	l.refunded[paymentID] = before + amount
+	return amount, before, nil
+}
+
+// Release returns a reserved amount after a failed refund
+func (l *RefundLedger) Release(paymentID string, amount int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refunded[paymentID] -= amount
+}
+
+type refundResponse struct {
+	PaymentID       string `json:"payment_id"`
+	RefundID        string `json:"refund_id"`
+	Status          string `json:"status"`
+	AmountCents     int64  `json:"amount_cents"`
+	RefundedCents   int64  `json:"refunded_cents"`
+	RefundableCents int64  `json:"refundable_cents"`
+}
+
+// refundPaymentHandler serves POST /payments/{id}/refund with an optional
+// {"amount_cents": n}; without it the remaining balance is refunded. The
+// driver's transfer and the platform fee are reversed proportionally.
+//
+// Every request needs an Idempotency-Key header. Retrying with the same key
+// returns the original refund instead of refunding again; a new refund needs
+// a new key.
+func refundPaymentHandler(api stripeAPI, ledger *RefundLedger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api == nil {
+			http.Error(w, "Payments are not configured", http.StatusServiceUnavailable)
+			return
+		}
+		paymentID := mux.Vars(r)["id"]
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			http.Error(w, "Idempotency-Key header is required", http.StatusBadRequest)
+			return
+		}
+
+		var input struct {
+			AmountCents *int64 `json:"amount_cents"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		var amount int64
+		if input.AmountCents != nil {
+			if *input.AmountCents <= 0 {
+				http.Error(w, "amount_cents must be positive", http.StatusBadRequest)
+				return
+			}
+			amount = *input.AmountCents
+		}
+
+		prior, err := ledger.Begin(paymentID, key, amount)
+		switch {
+		case errors.Is(err, errIdempotencyKeyUsed):
+			http.Error(w, "Idempotency-Key was already used for a different refund of this payment", http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, errRefundInProgress):
+			http.Error(w, "A refund with this Idempotency-Key is in progress", http.StatusConflict)
+			return
+		case prior != nil:
+			log.Printf("Refund request %s for PaymentIntent %s already processed (refund %s), repeating the response", key, paymentID, prior.RefundID)
+			writeRefund(w, *prior)
+			return
+		}
+		// Until a response is stored, failures let the client retry the key
+		var resp *refundResponse
+		defer func() { ledger.Finish(paymentID, key, resp) }()
+
+		pi, err := api.GetPaymentIntent(paymentID, nil)
+		if err != nil {
+			status, message := stripeErrorResponse(err)
+			log.Printf("Stripe lookup of PaymentIntent %s failed (responding %d): %v", paymentID, status, err)
+			http.Error(w, message, status)
+			return
+		}
+		if pi.Status != stripe.PaymentIntentStatusSucceeded {
+			http.Error(w, fmt.Sprintf("Payment is %s, only completed payments can be refunded", pi.Status), http.StatusConflict)
+			return
+		}
+		charged := pi.AmountReceived
+
+		booked, before, err := ledger.Reserve(paymentID, amount, charged)
+		switch {
+		case errors.Is(err, errFullyRefunded):
+			http.Error(w, "Payment is already fully refunded", http.StatusConflict)
+			return
+		case errors.Is(err, errRefundTooLarge):
+			http.Error(w, fmt.Sprintf("amount_cents exceeds the refundable balance of %d cents", charged-before), http.StatusBadRequest)
+			return
+		}
+
+		params := &stripe.RefundParams{
+			PaymentIntent:        stripe.String(paymentID),
+			Amount:               stripe.Int64(booked),
+			ReverseTransfer:      stripe.Bool(true),
+			RefundApplicationFee: stripe.Bool(true),
+		}
+		// A retry after a lost response is deduplicated by Stripe as well
+		params.SetIdempotencyKey(fmt.Sprintf("refund-%s-%s", paymentID, key))
+
+		re, err := api.NewRefund(params)
+		if err != nil {
+			ledger.Release(paymentID, booked)
+			status, message := stripeErrorResponse(err)
+			log.Printf("Stripe refund of %d cents for PaymentIntent %s failed (responding %d): %v", booked, paymentID, status, err)
+			http.Error(w, message, status)
+			return
+		}
+
+		refunded := before + booked
+		log.Printf("Refunded %d cents of PaymentIntent %s (refund %s, %s); %d of %d cents refunded", booked, paymentID, re.ID, re.Status, refunded, charged)
+
+		resp = &refundResponse{
+			PaymentID:       paymentID,
+			RefundID:        re.ID,
+			Status:          string(re.Status),
+			AmountCents:     booked,
+			RefundedCents:   refunded,
+			RefundableCents: charged - refunded,
+		}
+		writeRefund(w, *resp)
+	}
+}
+
+func writeRefund(w http.ResponseWriter, resp refundResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go/v76"
+)
+
+func paidStripe(amount int64) *fakeStripe {
+	return &fakeStripe{existing: &stripe.PaymentIntent{ID: "pi_paid", Amount: amount, AmountReceived: amount, Status: stripe.PaymentIntentStatusSucceeded}}
+}
+
+func postRefund(t *testing.T, api stripeAPI, ledger *RefundLedger, paymentID, key, body string) (int, refundResponse) {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/payments/{id}/refund", refundPaymentHandler(api, ledger)).Methods("POST")
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/"+paymentID+"/refund", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var resp refundResponse
+	if rec.Code == http.StatusCreated {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode refund: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestRefundDefaultsToFullAmount(t *testing.T) {
+	api := paidStripe(2240)
+	ledger := NewRefundLedger()
+
+	code, resp := postRefund(t, api, ledger, "pi_paid", "refund-full", "")
+	if code != http.StatusCreated {
+		t.Fatalf("status = %d", code)
+	}
+	if resp.AmountCents != 2240 || resp.RefundedCents != 2240 || resp.RefundableCents != 0 || resp.Status != "succeeded" {
+		t.Errorf("unexpected refund %+v", resp)
+	}
+
+	p := api.refundParams[0]
+	if *p.PaymentIntent != "pi_paid" || *p.Amount != 2240 || !*p.ReverseTransfer || !*p.RefundApplicationFee {
+		t.Errorf("unexpected refund params: intent %s, amount %d", *p.PaymentIntent, *p.Amount)
+	}
+
+	if code, _ := postRefund(t, api, ledger, "pi_paid", "refund-full-again", ""); code != http.StatusConflict {
+		t.Errorf("second full refund: status = %d, want 409", code)
+	}
+	if len(api.refundParams) != 1 {
+		t.Errorf("Stripe was called %d times, want 1", len(api.refundParams))
+	}
+}
+
+func TestPartialRefunds(t *testing.T) {
+	api := paidStripe(2240)
+	ledger := NewRefundLedger()
+
+	code, resp := postRefund(t, api, ledger, "pi_paid", "refund-partial", `{"amount_cents": 1000}`)
+	if code != http.StatusCreated || resp.RefundedCents != 1000 || resp.RefundableCents != 1240 {
+		t.Fatalf("first partial refund: status %d, %+v", code, resp)
+	}
+
+	if code, _ := postRefund(t, api, ledger, "pi_paid", "refund-too-large", `{"amount_cents": 1241}`); code != http.StatusBadRequest {
+		t.Errorf("refund above balance: status = %d, want 400", code)
+	}
+
+	code, resp = postRefund(t, api, ledger, "pi_paid", "refund-rest", "")
+	if code != http.StatusCreated || resp.AmountCents != 1240 || resp.RefundableCents != 0 {
+		t.Errorf("remaining refund: status %d, %+v", code, resp)
+	}
+
+	first, second := api.refundParams[0].IdempotencyKey, api.refundParams[1].IdempotencyKey
+	if *first == *second {
+		t.Errorf("partial refunds share idempotency key %s", *first)
+	}
+}
+
+func TestRefundFailureReleasesBalance(t *testing.T) {
+	api := paidStripe(2240)
+	api.refundErr = &stripe.Error{Type: stripe.ErrorTypeAPI, HTTPStatusCode: 500}
+	ledger := NewRefundLedger()
+
+	if code, _ := postRefund(t, api, ledger, "pi_paid", "refund-1", ""); code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", code)
+	}
+
+	// The client retries with the same key
+	api.refundErr = nil
+	if code, resp := postRefund(t, api, ledger, "pi_paid", "refund-1", ""); code != http.StatusCreated || resp.AmountCents != 2240 {
+		t.Errorf("retry after failure: status %d, %+v", code, resp)
+	}
+}
+
+func TestRefundRejections(t *testing.T) {
+	ledger := NewRefundLedger()
+
+	pending := &fakeStripe{existing: &stripe.PaymentIntent{ID: "pi_paid", Amount: 2240, Status: stripe.PaymentIntentStatusProcessing}}
+	if code, _ := postRefund(t, pending, ledger, "pi_paid", "refund-1", ""); code != http.StatusConflict {
+		t.Errorf("incomplete payment: status = %d, want 409", code)
+	}
+
+	for _, body := range []string{`{"amount_cents": 0}`, `{"amount_cents": -5}`, `not json`} {
+		if code, _ := postRefund(t, paidStripe(2240), ledger, "pi_paid", "refund-"+body, body); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, code)
+		}
+	}
+
+	if code, _ := postRefund(t, paidStripe(2240), ledger, "pi_other", "refund-1", ""); code != http.StatusBadRequest {
+		t.Errorf("unknown payment: status = %d, want 400", code)
+	}
+}
+
+func TestRefundRetryWithSameKey(t *testing.T) {
+	api := paidStripe(2240)
+	ledger := NewRefundLedger()
+
+	code, first := postRefund(t, api, ledger, "pi_paid", "refund-1", `{"amount_cents": 1000}`)
+	if code != http.StatusCreated {
+		t.Fatalf("first request: status = %d", code)
+	}
+	// The response was lost and the client retries
+	code, retry := postRefund(t, api, ledger, "pi_paid", "refund-1", `{"amount_cents": 1000}`)
+	if code != http.StatusCreated || retry != first {
+		t.Errorf("retry: status %d, %+v, want the first refund %+v", code, retry, first)
+	}
+	if len(api.refundParams) != 1 {
+		t.Fatalf("Stripe was called %d times, want 1", len(api.refundParams))
+	}
+
+	// The same amount under a new key is a second refund
+	code, second := postRefund(t, api, ledger, "pi_paid", "refund-2", `{"amount_cents": 1000}`)
+	if code != http.StatusCreated || second.RefundedCents != 2000 || second.RefundableCents != 240 {
+		t.Errorf("second refund: status %d, %+v", code, second)
+	}
+	if first, second := api.refundParams[0].IdempotencyKey, api.refundParams[1].IdempotencyKey; *first == *second {
+		t.Errorf("refunds share Stripe idempotency key %s", *first)
+	}
+
+	if code, _ := postRefund(t, api, ledger, "pi_paid", "refund-1", `{"amount_cents": 240}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused for another amount: status = %d, want 422", code)
+	}
+	if code, _ := postRefund(t, api, ledger, "pi_paid", "", ""); code != http.StatusBadRequest {
+		t.Errorf("no Idempotency-Key: status = %d, want 400", code)
+	}
+	if len(api.refundParams) != 2 {
+		t.Errorf("Stripe was called %d times, want 2", len(api.refundParams))
+	}
+}
+
+func TestRefundRetryAfterFailureKeepsStripeKey(t *testing.T) {
+	api := paidStripe(2240)
+	api.refundErr = &stripe.Error{Type: stripe.ErrorTypeAPI, HTTPStatusCode: 500}
+	ledger := NewRefundLedger()
+
+	postRefund(t, api, ledger, "pi_paid", "refund-1", "")
+	api.refundErr = nil
+	if code, _ := postRefund(t, api, ledger, "pi_paid", "refund-1", ""); code != http.StatusCreated {
+		t.Fatalf("retry: status = %d", code)
+	}
+	// Stripe deduplicates the retry if the failed call did refund
+	if first, retry := api.refundParams[0].IdempotencyKey, api.refundParams[1].IdempotencyKey; *first != *retry {
+		t.Errorf("retry used Stripe key %s, first attempt %s", *retry, *first)
+	}
+}
@@ -0,0 +1,37 @@
+-- Invoice and receipt storage for PostgresInvoiceRepository and
+-- PostgresReceiptRepository. The statements are idempotent and applied on
+-- start-up.
+--
+-- invoice_sequence holds the last invoice number issued in its single row.
+-- Issuing locks that row, so numbers are allocated one at a time, without
+-- duplicates or gaps, across restarts and replicas. receipt_sequence does the
+-- same for receipt transaction counters.
+
+CREATE TABLE IF NOT EXISTS invoice_sequence (
+    id          BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_number BIGINT NOT NULL
+);
+
+INSERT INTO invoice_sequence (id, last_number) VALUES (TRUE, 0)
+    ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS invoices (
+    payment_id TEXT PRIMARY KEY,
+    number     BIGINT NOT NULL UNIQUE,
+    issued_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS receipt_sequence (
+    id           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_counter BIGINT NOT NULL
+);
+
+INSERT INTO receipt_sequence (id, last_counter) VALUES (TRUE, 0)
+    ON CONFLICT (id) DO NOTHING;
+
+-- data is the receipt JSON including its TSE signature
+CREATE TABLE IF NOT EXISTS receipts (
+    payment_id          TEXT PRIMARY KEY,
+    transaction_counter BIGINT NOT NULL UNIQUE,
+    data                JSONB NOT NULL
+);
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// maxWebhookBodyBytes bounds the webhook payload; Stripe events are far smaller
+const maxWebhookBodyBytes = 64 << 10
+
+// PaymentStatus is the last known state of a PaymentIntent, as reported by
+// Stripe webhooks
+type PaymentStatus struct {
+	PaymentIntentID string    `json:"payment_intent_id"`
+	RideID          string    `json:"ride_id,omitempty"`
+	Status          string    `json:"status"`
+	AmountCents     int64     `json:"amount_cents"`
+	FailureMessage  string    `json:"failure_message,omitempty"`
+	EventID         string    `json:"event_id"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// PaymentStatusStore keeps payment states and the IDs of applied webhook
+// events, so a redelivered event is recognised and ignored
+type PaymentStatusStore struct {
+	mu        sync.RWMutex
+	payments  map[string]PaymentStatus
+	processed map[string]bool
+}
+
+func NewPaymentStatusStore() *PaymentStatusStore {
+	return &PaymentStatusStore{
+		payments:  make(map[string]PaymentStatus),
+		processed: make(map[string]bool),
+	}
+}
+
+// Apply records the status carried by a webhook event. It returns false if
+// the event was already processed. Events may arrive out of order, so a
+// status older than the stored one does not overwrite it.
+func (s *PaymentStatusStore) Apply(eventID string, status PaymentStatus) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.processed[eventID] {
+		return false
+	}
+	s.processed[eventID] = true
+
+	if current, ok := s.payments[status.PaymentIntentID]; ok && status.UpdatedAt.Before(current.UpdatedAt) {
+		return true
+	}
+	status.EventID = eventID
+	s.payments[status.PaymentIntentID] = status
+	return true
+}
+
+func (s *PaymentStatusStore) Get(paymentIntentID string) (PaymentStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.payments[paymentIntentID]
+	return p, ok
+}
+
+// stripeWebhookHandler serves POST /webhooks/stripe. The Stripe-Signature
+// header is verified against the endpoint secret before the event is read.
+func stripeWebhookHandler(secret string, statuses *PaymentStatusStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" {
+			http.Error(w, "Webhooks are not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		event, err := webhook.ConstructEvent(payload, r.Header.Get("Stripe-Signature"), secret)
+		if err != nil {
+			log.Printf("Rejected Stripe webhook: %v", err)
+			http.Error(w, "Invalid signature", http.StatusBadRequest)
+			return
+		}
+
+		var status string
+		switch event.Type {
+		case "payment_intent.succeeded":
+			status = string(stripe.PaymentIntentStatusSucceeded)
+		case "payment_intent.payment_failed":
+			status = "failed"
+		default:
+			// Acknowledge events we do not act on, or Stripe keeps retrying them
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil || pi.ID == "" {
+			log.Printf("Stripe webhook %s (%s): malformed PaymentIntent: %v", event.ID, event.Type, err)
+			http.Error(w, "Invalid event payload", http.StatusBadRequest)
+			return
+		}
+
+		update := PaymentStatus{
+			PaymentIntentID: pi.ID,
+			RideID:          pi.Metadata["ride_id"],
+			Status:          status,
+			AmountCents:     pi.Amount,
+			UpdatedAt:       time.Unix(event.Created, 0).UTC(),
+		}
+		if pi.LastPaymentError != nil {
+			update.FailureMessage = pi.LastPaymentError.Msg
+		}
+
+		if statuses.Apply(event.ID, update) {
+			log.Printf("Stripe webhook %s: PaymentIntent %s for ride %s is %s", event.ID, pi.ID, update.RideID, status)
+		} else {
+			log.Printf("Stripe webhook %s already processed, ignoring redelivery", event.ID)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// paymentStatusHandler serves GET /payments/{id}
+func paymentStatusHandler(statuses *PaymentStatusStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, ok := statuses.Get(mux.Vars(r)["id"])
+		if !ok {
+			http.Error(w, "Payment not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+func stripeEvent(id, eventType, paymentID string, created time.Time) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"id":          id,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"created":     created.Unix(),
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":       paymentID,
+				"object":   "payment_intent",
+				"amount":   2240,
+				"metadata": map[string]string{"ride_id": "ride-1"},
+			},
+		},
+	})
+	return payload
+}
+
+func deliver(statuses *PaymentStatusStore, payload []byte, secret string) int {
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: secret, Timestamp: time.Now()})
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(signed.Payload))
+	req.Header.Set("Stripe-Signature", signed.Header)
+
+	rec := httptest.NewRecorder()
+	stripeWebhookHandler(testWebhookSecret, statuses)(rec, req)
+	return rec.Code
+}
+
+func TestStripeWebhookUpdatesStatus(t *testing.T) {
+	statuses := NewPaymentStatusStore()
+	now := time.Now()
+
+	if code := deliver(statuses, stripeEvent("evt_1", "payment_intent.payment_failed", "pi_1", now), testWebhookSecret); code != http.StatusOK {
+		t.Fatalf("failed event: status = %d", code)
+	}
+	if p, _ := statuses.Get("pi_1"); p.Status != "failed" || p.RideID != "ride-1" {
+		t.Errorf("after failure: %+v", p)
+	}
+
+	if code := deliver(statuses, stripeEvent("evt_2", "payment_intent.succeeded", "pi_1", now.Add(time.Minute)), testWebhookSecret); code != http.StatusOK {
+		t.Fatalf("succeeded event: status = %d", code)
+	}
+	p, _ := statuses.Get("pi_1")
+	if p.Status != "succeeded" || p.EventID != "evt_2" || p.AmountCents != 2240 {
+		t.Errorf("after success: %+v", p)
+	}
+
+	// Lookup over HTTP
+	router := mux.NewRouter()
+	router.HandleFunc("/payments/{id}", paymentStatusHandler(statuses)).Methods("GET")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payments/pi_1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /payments/pi_1: status = %d", rec.Code)
+	}
+}
+
+func TestStripeWebhookIsIdempotent(t *testing.T) {
+	statuses := NewPaymentStatusStore()
+	now := time.Now()
+
+	succeeded := stripeEvent("evt_ok", "payment_intent.succeeded", "pi_1", now)
+	deliver(statuses, succeeded, testWebhookSecret)
+	deliver(statuses, stripeEvent("evt_late_failure", "payment_intent.payment_failed", "pi_1", now.Add(-time.Minute)), testWebhookSecret)
+
+	// The redelivery is acknowledged but changes nothing
+	if code := deliver(statuses, succeeded, testWebhookSecret); code != http.StatusOK {
+		t.Errorf("redelivery: status = %d, want 200", code)
+	}
+	if p, _ := statuses.Get("pi_1"); p.Status != "succeeded" || p.EventID != "evt_ok" {
+		t.Errorf("status after redelivery and out-of-order event: %+v", p)
+	}
+}
+
+func TestStripeWebhookRejectsBadSignatures(t *testing.T) {
+	statuses := NewPaymentStatusStore()
+	payload := stripeEvent("evt_1", "payment_intent.succeeded", "pi_1", time.Now())
+
+	if code := deliver(statuses, payload, "whsec_wrong"); code != http.StatusBadRequest {
+		t.Errorf("wrong secret: status = %d, want 400", code)
+	}
+
+	rec := httptest.NewRecorder()
+	stripeWebhookHandler(testWebhookSecret, statuses)(rec, httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unsigned: status = %d, want 400", rec.Code)
+	}
+
+	if _, ok := statuses.Get("pi_1"); ok {
+		t.Error("rejected events must not update the store")
+	}
+}
+
+func TestStripeWebhookIgnoresOtherEvents(t *testing.T) {
+	statuses := NewPaymentStatusStore()
+	payload := []byte(fmt.Sprintf(`{"id": "evt_c", "object": "event", "type": "charge.refunded", "api_version": %q, "data": {"object": {"id": "ch_1"}}}`, stripe.APIVersion))
+	if code := deliver(statuses, payload, testWebhookSecret); code != http.StatusOK {
+		t.Errorf("status = %d, want 200", code)
+	}
+}
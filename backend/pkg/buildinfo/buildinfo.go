@@ -0,0 +1,88 @@
+// Package buildinfo exposes the name, version and build metadata of a
+// service at GET /version.
+//
+// Version, Commit and BuildTime are injected at build time:
+//
+//	go build -ldflags "-X github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo.Version=1.4.0 \
+//	  -X github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without ldflags the commit and build time fall back to the VCS metadata
+// embedded by the Go toolchain, and otherwise to "unknown".
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Unknown is reported for metadata that was neither injected nor embedded.
+const Unknown = "unknown"
+
+// Set via -ldflags -X.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info is the response body of GET /version.
+type Info struct {
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info for service.
+func Get(service string) Info {
+	info := Info{
+		Service:   service,
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = Unknown
+	}
+	return info
+}
+
+// Handler serves Get(service) as JSON.
+func Handler(service string) http.HandlerFunc {
+	info := Get(service)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
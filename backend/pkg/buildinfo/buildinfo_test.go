@@ -0,0 +1,54 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetDefaultsWithoutLdflags(t *testing.T) {
+	info := Get("ride-service")
+
+	if info.Service != "ride-service" {
+		t.Errorf("service = %q", info.Service)
+	}
+	if info.Version != "dev" {
+		t.Errorf("version = %q, want dev", info.Version)
+	}
+	if info.Commit == "" || info.BuildTime == "" || info.GoVersion == "" {
+		t.Errorf("empty fields in %+v", info)
+	}
+}
+
+func TestGetUsesInjectedValues(t *testing.T) {
+	defer func(v, c, b string) { Version, Commit, BuildTime = v, c, b }(Version, Commit, BuildTime)
+	Version, Commit, BuildTime = "1.4.0", "abc123", "2024-05-01T13:04:05Z"
+
+	info := Get("pricing-service")
+	if info.Version != "1.4.0" || info.Commit != "abc123" || info.BuildTime != "2024-05-01T13:04:05Z" {
+		t.Errorf("injected values not used: %+v", info)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler("matching-service")(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var info Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if info.Service != "matching-service" {
+		t.Errorf("service = %q", info.Service)
+	}
+
+	rec = httptest.NewRecorder()
+	Handler("matching-service")(rec, httptest.NewRequest(http.MethodPost, "/version", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,26 @@
+// Package distance provides the great-circle distance calculation shared by
+// the backend services.
+package distance
+
+import "math"
+
+// EarthRadiusKm is the IUGG mean Earth radius R1 (6371.0088km) rounded to
+// the kilometre, used for all distance calculations. Treating the Earth as a
+// sphere of this radius is off by at most about 0.5% against the WGS84
+// ellipsoid, well below the difference between straight-line and road
+// distance. Services must not use another radius, or matching, trip
+// validation and fare fallbacks would disagree near their thresholds.
+const EarthRadiusKm = 6371.0
+
+// HaversineKm returns the great-circle distance in kilometres between two
+// points given in decimal degrees.
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
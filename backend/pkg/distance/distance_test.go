@@ -0,0 +1,54 @@
+package distance
+
+import (
+	"math"
+	"testing"
+)
+
+type station struct {
+	name     string
+	lat, lon float64
+}
+
+var (
+	berlinHbf    = station{"Berlin Hbf", 52.5251, 13.3694}
+	hamburgHbf   = station{"Hamburg Hbf", 53.5530, 10.0069}
+	muenchenHbf  = station{"München Hbf", 48.1402, 11.5600}
+	koelnHbf     = station{"Köln Hbf", 50.9430, 6.9589}
+	frankfurtHbf = station{"Frankfurt (Main) Hbf", 50.1070, 8.6638}
+	stuttgartHbf = station{"Stuttgart Hbf", 48.7838, 9.1816}
+	dresdenHbf   = station{"Dresden Hbf", 51.0403, 13.7320}
+	leipzigHbf   = station{"Leipzig Hbf", 51.3455, 12.3821}
+)
+
+// TestHaversineKm checks straight-line distances between main stations
+// against known great-circle values. The tolerance of 1% covers the
+// rounding of the reference values and of the station coordinates.
+func TestHaversineKm(t *testing.T) {
+	cases := []struct {
+		from, to station
+		wantKm   float64
+	}{
+		{berlinHbf, hamburgHbf, 252},
+		{berlinHbf, muenchenHbf, 504},
+		{hamburgHbf, muenchenHbf, 612},
+		{koelnHbf, frankfurtHbf, 152},
+		{frankfurtHbf, stuttgartHbf, 152},
+		{dresdenHbf, leipzigHbf, 100},
+	}
+	for _, c := range cases {
+		got := HaversineKm(c.from.lat, c.from.lon, c.to.lat, c.to.lon)
+		if math.Abs(got-c.wantKm) > 0.01*c.wantKm {
+			t.Errorf("HaversineKm(%s, %s) = %.1fkm, want %.0fkm ±1%%", c.from.name, c.to.name, got, c.wantKm)
+		}
+		if back := HaversineKm(c.to.lat, c.to.lon, c.from.lat, c.from.lon); back != got {
+			t.Errorf("HaversineKm(%s, %s) = %.6fkm, reverse %.6fkm", c.from.name, c.to.name, got, back)
+		}
+	}
+}
+
+func TestHaversineKmSamePoint(t *testing.T) {
+	if got := HaversineKm(52.52, 13.405, 52.52, 13.405); got != 0 {
+		t.Errorf("HaversineKm of identical points = %v, want 0", got)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg
+
+go 1.21
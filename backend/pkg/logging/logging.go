@@ -0,0 +1,51 @@
+// Package logging provides the structured JSON logger shared by the services
+// that log with log/slog, and a per-request logger that carries the
+// correlation ID assigned by the api-gateway.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// RequestIDHeader carries the correlation ID the api-gateway assigns to every
+// proxied request.
+const RequestIDHeader = "X-Request-ID"
+
+// New returns a JSON logger writing to w, with timestamps in the
+// timeutil.Layout and the service name on every record.
+func New(w io.Writer, service string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       slog.LevelInfo,
+		ReplaceAttr: timeutil.SlogReplaceAttr,
+	})).With("service", service)
+}
+
+type requestLoggerKey struct{}
+
+// Middleware stores a logger derived from base and carrying the request ID,
+// method and path in the request context, for FromContext.
+func Middleware(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := base.With("method", r.Method, "path", r.URL.Path)
+			if id := r.Header.Get(RequestIDHeader); id != "" {
+				l = l.With("request_id", id)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestLoggerKey{}, l)))
+		})
+	}
+}
+
+// FromContext returns the logger Middleware stored in ctx, or fallback
+// outside of a request.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(requestLoggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return fallback
+}
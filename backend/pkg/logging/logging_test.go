@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewLogsServiceAndLayout(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, "ride-service").Info("Ride created")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output is not JSON: %v (%q)", err, buf.String())
+	}
+	if record["service"] != "ride-service" || record["msg"] != "Ride created" {
+		t.Errorf("record = %v, want service and message", record)
+	}
+	if ts, _ := record["time"].(string); len(ts) != len("2024-05-01T13:04:05.123Z") {
+		t.Errorf("time = %q, want the timeutil layout", ts)
+	}
+}
+
+func TestFromContextCarriesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/rides/ride_1", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	Middleware(base)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context(), nil).Info("Ride created", "ride_id", "ride_1")
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output is not JSON: %v (%q)", err, buf.String())
+	}
+	if record["msg"] != "Ride created" || record["ride_id"] != "ride_1" || record["request_id"] != "req-123" || record["path"] != "/rides/ride_1" {
+		t.Errorf("record = %v, want message, ride_id and request fields", record)
+	}
+}
+
+func TestFromContextFallsBackOutsideRequests(t *testing.T) {
+	fallback := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	if got := FromContext(context.Background(), fallback); got != fallback {
+		t.Error("FromContext outside the middleware should return the fallback")
+	}
+}
@@ -0,0 +1,119 @@
+package piiaudit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewEventTakesActorFromGatewayHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users/u-1", nil)
+	r.Header.Set("X-User-ID", "admin-7")
+	r.Header.Set("X-User-Role", "admin")
+
+	event := NewEvent(r, Read, "u-1", "email", "name")
+
+	if event.ActorID != "admin-7" || event.ActorRole != "admin" {
+		t.Errorf("actor = %q/%q", event.ActorID, event.ActorRole)
+	}
+	if event.SubjectID != "u-1" || event.Action != Read || event.Resource != "GET /users/u-1" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if strings.Join(event.Fields, ",") != "email,name" {
+		t.Errorf("fields = %v", event.Fields)
+	}
+	if event.Time.IsZero() {
+		t.Error("time not set")
+	}
+}
+
+func TestNewEventWithoutHeadersRecordsUnknownActor(t *testing.T) {
+	event := NewEvent(httptest.NewRequest("DELETE", "/users/u-1", nil), Delete, "u-1")
+
+	if event.ActorID != UnknownActor || event.ActorRole != "" {
+		t.Errorf("actor = %q/%q, want %q", event.ActorID, event.ActorRole, UnknownActor)
+	}
+	if event.Fields != nil {
+		t.Errorf("fields = %v, want none", event.Fields)
+	}
+}
+
+func TestRecordWritesJSONLinesAndKeepsPerSubject(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out)
+
+	l.Record(Event{ActorID: "a", SubjectID: "u-1", Action: Read, Resource: "GET /users/u-1"})
+	l.Record(Event{ActorID: "a", SubjectID: "u-2", Action: Update, Resource: "PUT /users/u-2"})
+	l.Record(Event{ActorID: "b", SubjectID: "u-1", Action: Delete, Resource: "DELETE /users/u-1"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrote %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	var first Event
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if first.SubjectID != "u-1" || first.Action != Read {
+		t.Errorf("first line = %+v", first)
+	}
+
+	events := l.ForSubject("u-1")
+	if len(events) != 2 || events[0].Action != Read || events[1].Action != Delete {
+		t.Errorf("u-1 events = %+v", events)
+	}
+	if len(l.ForSubject("unknown-subject")) != 0 {
+		t.Error("events returned for a subject never accessed")
+	}
+}
+
+func TestForSubjectReturnsCopy(t *testing.T) {
+	l := New(nil)
+	l.Record(Event{SubjectID: "u-1", Action: Read})
+
+	l.ForSubject("u-1")[0].Action = Delete
+
+	if got := l.ForSubject("u-1")[0].Action; got != Read {
+		t.Errorf("stored event modified through returned slice: %q", got)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestRecordKeepsEventWhenWriteFails(t *testing.T) {
+	l := New(failingWriter{})
+
+	if err := l.Record(Event{SubjectID: "u-1", Action: Read}); err == nil {
+		t.Error("expected write error")
+	}
+	if len(l.ForSubject("u-1")) != 1 {
+		t.Error("event dropped after failed write")
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("PII_ACCESS_LOG", "off")
+	if l, err := NewFromEnv(); err != nil || l.out != nil {
+		t.Errorf("off: log %+v, err %v", l, err)
+	}
+
+	path := t.TempDir() + "/pii-access.log"
+	t.Setenv("PII_ACCESS_LOG", path)
+	l, err := NewFromEnv()
+	if err != nil {
+		t.Fatalf("file: %v", err)
+	}
+	if err := l.Record(Event{SubjectID: "u-1", Action: Read}); err != nil {
+		t.Errorf("record to file: %v", err)
+	}
+
+	t.Setenv("PII_ACCESS_LOG", t.TempDir()+"/missing/dir/pii.log")
+	if _, err := NewFromEnv(); err == nil {
+		t.Error("expected error for unwritable path")
+	}
+}
@@ -0,0 +1,172 @@
+// Package quotetoken issues and verifies HMAC-signed fare quote tokens.
+//
+// A token binds a booking to the exact quote the rider saw: the pricing
+// service issues one with every quote and the ride-service only creates a
+// ride when presented with a valid, unexpired token. While the pricing
+// service is down, the api-gateway issues tokens for its fare estimates,
+// marked with Estimate.
+//
+// Token format: base64url(JSON payload) "." base64url(HMAC-SHA256(payload)).
+package quotetoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformed is returned for tokens that cannot be decoded.
+	ErrMalformed = errors.New("malformed quote token")
+	// ErrInvalidSignature is returned for tokens whose signature does not match.
+	ErrInvalidSignature = errors.New("invalid quote token signature")
+	// ErrExpired is returned for tokens past their expiry.
+	ErrExpired = errors.New("quote token expired")
+)
+
+// Quote is the signed payload of a token.
+type Quote struct {
+	ID              string    `json:"id"`
+	FareEUR         float64   `json:"fare_eur"`
+	Currency        string    `json:"currency"`
+	DistanceKm      float64   `json:"distance_km"`
+	DurationMin     float64   `json:"duration_min"`
+	SurgeMultiplier float64   `json:"surge_multiplier"`
+	IssuedAt        time.Time `json:"iat"`
+	ExpiresAt       time.Time `json:"exp"`
+	// Estimate marks a degraded-mode fare estimate issued by the api-gateway
+	// instead of a pricing-service quote
+	Estimate bool `json:"estimate,omitempty"`
+}
+
+// DevSecret is used when QUOTE_TOKEN_SECRET is unset. It must never be used
+// in production.
+const DevSecret = "dev-quote-token-secret-change-me"
+
+// DefaultTTL is the validity of issued tokens when QUOTE_TOKEN_TTL is unset.
+const DefaultTTL = 5 * time.Minute
+
+// Signer issues and verifies tokens with a shared secret.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSigner creates a Signer. ttl is the validity of issued tokens.
+func NewSigner(secret []byte, ttl time.Duration) (*Signer, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("quote token secret must be at least 16 bytes")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("quote token validity must be positive")
+	}
+	return &Signer{secret: secret, ttl: ttl, now: time.Now}, nil
+}
+
+// NewSignerFromEnv builds a Signer from QUOTE_TOKEN_SECRET and QUOTE_TOKEN_TTL
+// (a Go duration such as "5m"). usedDevSecret reports whether DevSecret was
+// used because no secret is configured.
+func NewSignerFromEnv() (signer *Signer, usedDevSecret bool, err error) {
+	secret := os.Getenv("QUOTE_TOKEN_SECRET")
+	if secret == "" {
+		secret = DevSecret
+		usedDevSecret = true
+	}
+
+	ttl := DefaultTTL
+	if v := os.Getenv("QUOTE_TOKEN_TTL"); v != "" {
+		ttl, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, usedDevSecret, fmt.Errorf("invalid QUOTE_TOKEN_TTL %q: %w", v, err)
+		}
+	}
+
+	signer, err = NewSigner([]byte(secret), ttl)
+	return signer, usedDevSecret, err
+}
+
+// TTL returns the validity of issued tokens.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue assigns q an ID and validity window and returns the signed token.
+func (s *Signer) Issue(q Quote) (string, Quote, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", Quote{}, fmt.Errorf("failed to generate quote id: %w", err)
+	}
+
+	now := s.now().UTC()
+	q.ID = hex.EncodeToString(id)
+	q.IssuedAt = now
+	q.ExpiresAt = now.Add(s.ttl)
+
+	payload, err := json.Marshal(q)
+	if err != nil {
+		return "", Quote{}, fmt.Errorf("failed to encode quote: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload)), q, nil
+}
+
+// Verify checks the token's signature and expiry and returns its quote.
+func (s *Signer) Verify(token string) (Quote, error) {
+	q, err := s.VerifySignature(token)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	if !s.now().Before(q.ExpiresAt) {
+		return Quote{}, ErrExpired
+	}
+
+	return q, nil
+}
+
+// VerifySignature checks only the token's signature. It is meant for tokens
+// that were accepted before they expired, e.g. re-checking the quote bound to
+// a ride at completion.
+func (s *Signer) VerifySignature(token string) (Quote, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Quote{}, ErrMalformed
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(encPayload)
+	if err != nil {
+		return Quote{}, ErrMalformed
+	}
+	sig, err := enc.DecodeString(encSig)
+	if err != nil {
+		return Quote{}, ErrMalformed
+	}
+
+	if !hmac.Equal(sig, s.sign(payload)) {
+		return Quote{}, ErrInvalidSignature
+	}
+
+	var q Quote
+	if err := json.Unmarshal(payload, &q); err != nil {
+		return Quote{}, ErrMalformed
+	}
+
+	return q, nil
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,110 @@
+package quotetoken
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret-at-least-16-bytes")
+
+func TestIssueAndVerify(t *testing.T) {
+	signer, err := NewSigner(testSecret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	token, issued, err := signer.Issue(Quote{FareEUR: 28.50, Currency: "EUR", DistanceKm: 10, DurationMin: 20})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if issued.ID == "" || !issued.ExpiresAt.Equal(issued.IssuedAt.Add(5*time.Minute)) {
+		t.Errorf("unexpected issued quote %+v", issued)
+	}
+
+	q, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if q.ID != issued.ID || q.FareEUR != 28.50 {
+		t.Errorf("verified quote %+v does not match issued %+v", q, issued)
+	}
+}
+
+func TestVerifyRejectsExpired(t *testing.T) {
+	signer, _ := NewSigner(testSecret, time.Minute)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	signer.now = func() time.Time { return now }
+
+	token, _, _ := signer.Issue(Quote{FareEUR: 10})
+
+	now = now.Add(time.Minute)
+	if _, err := signer.Verify(token); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	// The signature of an expired token can still be checked.
+	if q, err := signer.VerifySignature(token); err != nil || q.FareEUR != 10 {
+		t.Errorf("VerifySignature = %+v, %v", q, err)
+	}
+}
+
+func TestVerifyRejectsTampered(t *testing.T) {
+	signer, _ := NewSigner(testSecret, time.Minute)
+	token, _, _ := signer.Issue(Quote{FareEUR: 28.50})
+
+	// Pair a valid signature with the payload of a cheaper quote.
+	other, _, _ := signer.Issue(Quote{FareEUR: 1.00})
+	payload, _, _ := strings.Cut(other, ".")
+	_, sig, _ := strings.Cut(token, ".")
+
+	if _, err := signer.Verify(payload + "." + sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	wrongKey, _ := NewSigner([]byte("another-secret-of-16-bytes"), time.Minute)
+	if _, err := wrongKey.Verify(token); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for a different secret, got %v", err)
+	}
+}
+
+func TestVerifyRejectsMalformed(t *testing.T) {
+	signer, _ := NewSigner(testSecret, time.Minute)
+
+	for _, token := range []string{"", "no-dot", "!!!.???", "e30.AAAA"} {
+		if _, err := signer.Verify(token); err == nil {
+			t.Errorf("Verify(%q) should fail", token)
+		}
+	}
+}
+
+func TestNewSignerValidation(t *testing.T) {
+	if _, err := NewSigner([]byte("short"), time.Minute); err == nil {
+		t.Error("expected an error for a short secret")
+	}
+	if _, err := NewSigner(testSecret, 0); err == nil {
+		t.Error("expected an error for a non-positive validity")
+	}
+}
+
+func TestNewSignerFromEnv(t *testing.T) {
+	t.Setenv("QUOTE_TOKEN_SECRET", "")
+	t.Setenv("QUOTE_TOKEN_TTL", "90s")
+
+	signer, usedDev, err := NewSignerFromEnv()
+	if err != nil {
+		t.Fatalf("NewSignerFromEnv failed: %v", err)
+	}
+	if !usedDev {
+		t.Error("expected the development secret to be used")
+	}
+	if signer.TTL() != 90*time.Second {
+		t.Errorf("TTL = %v, want 90s", signer.TTL())
+	}
+
+	t.Setenv("QUOTE_TOKEN_TTL", "soon")
+	if _, _, err := NewSignerFromEnv(); err == nil {
+		t.Error("expected an error for an invalid TTL")
+	}
+}
@@ -0,0 +1,77 @@
+// Package timeutil defines the platform-wide timestamp policy.
+//
+// All timestamps are taken and stored in UTC and rendered as RFC 3339 with an
+// explicit zone designator and millisecond precision, e.g.
+// "2024-05-01T13:04:05.123Z". Every service uses the same fixed-width layout
+// for structured logs and audit entries so that events can be correlated and
+// sorted across services when reconstructing compliance timelines.
+package timeutil
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"time"
+	_ "time/tzdata" // Berlin works regardless of the host zone database
+)
+
+// Layout is the RFC 3339 layout used for all rendered timestamps.
+const Layout = "2006-01-02T15:04:05.000Z07:00"
+
+var berlin = mustLoadLocation("Europe/Berlin")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Berlin returns the Europe/Berlin location. Local-time rules such as
+// calendar days and night hours are evaluated in Berlin time.
+func Berlin() *time.Location {
+	return berlin
+}
+
+// Now returns the current time in UTC.
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Format renders t in UTC using Layout.
+func Format(t time.Time) string {
+	return t.UTC().Format(Layout)
+}
+
+// NewLogger returns a stdlib logger whose lines start with a Layout
+// timestamp. Date and time flags in flag are ignored; other flags such as
+// log.Lshortfile are kept.
+func NewLogger(out io.Writer, prefix string, flag int) *log.Logger {
+	flag &^= log.Ldate | log.Ltime | log.Lmicroseconds | log.LUTC
+	return log.New(&timestampWriter{out: out}, prefix, flag)
+}
+
+type timestampWriter struct {
+	out io.Writer
+}
+
+func (w *timestampWriter) Write(p []byte) (int, error) {
+	line := make([]byte, 0, len(Layout)+1+len(p))
+	line = append(line, Format(time.Now())...)
+	line = append(line, ' ')
+	line = append(line, p...)
+	if _, err := w.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SlogReplaceAttr is a slog.HandlerOptions.ReplaceAttr function that renders
+// the record time and any time.Time attributes using Layout.
+func SlogReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindTime {
+		a.Value = slog.StringValue(Format(a.Value.Time()))
+	}
+	return a
+}
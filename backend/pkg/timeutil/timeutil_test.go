@@ -0,0 +1,78 @@
+package timeutil
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var layoutPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z$`)
+
+func TestFormatConvertsToUTC(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	ts := time.Date(2024, 5, 1, 15, 4, 5, 123456789, berlin)
+
+	got := Format(ts)
+	if got != "2024-05-01T13:04:05.123Z" {
+		t.Errorf("Format = %q, want %q", got, "2024-05-01T13:04:05.123Z")
+	}
+}
+
+func TestNowIsUTC(t *testing.T) {
+	if loc := Now().Location(); loc != time.UTC {
+		t.Errorf("Now location = %v, want UTC", loc)
+	}
+}
+
+func TestNewLoggerPrefixesTimestamp(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, "[TEST] ", log.LstdFlags)
+
+	logger.Println("hello")
+
+	line := strings.TrimSuffix(buf.String(), "\n")
+	ts, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		t.Fatalf("unexpected log line %q", line)
+	}
+	if !layoutPattern.MatchString(ts) {
+		t.Errorf("timestamp %q does not match policy layout", ts)
+	}
+	if rest != "[TEST] hello" {
+		t.Errorf("log message = %q, want %q", rest, "[TEST] hello")
+	}
+}
+
+func TestSlogReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: SlogReplaceAttr}))
+
+	logger.Info("event", "at", time.Date(2024, 5, 1, 15, 4, 5, 0, time.FixedZone("CEST", 2*60*60)))
+
+	out := buf.String()
+	if !strings.Contains(out, `"at":"2024-05-01T13:04:05.000Z"`) {
+		t.Errorf("time attribute not normalised: %s", out)
+	}
+	if !regexp.MustCompile(`"time":"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z"`).MatchString(out) {
+		t.Errorf("record time not normalised: %s", out)
+	}
+}
+
+func TestBerlin(t *testing.T) {
+	// Berlin is on summer time (UTC+2) in July and winter time (UTC+1) in January
+	for _, tt := range []struct {
+		utc  time.Time
+		hour int
+	}{
+		{time.Date(2024, 7, 1, 20, 0, 0, 0, time.UTC), 22},
+		{time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), 21},
+	} {
+		if got := tt.utc.In(Berlin()).Hour(); got != tt.hour {
+			t.Errorf("%s in Berlin: hour %d, want %d", tt.utc, got, tt.hour)
+		}
+	}
+}
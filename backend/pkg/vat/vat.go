@@ -0,0 +1,37 @@
+// Package vat provides the VAT rule for passenger transport shared by the
+// pricing-service quotes and the payment-service receipts and invoices.
+package vat
+
+import "math"
+
+// VAT rates for passenger transport in percent. §12 Abs. 2 Nr. 10 UStG
+// applies the reduced rate to trips of at most ReducedRateMaxKm; longer trips
+// are charged the standard rate.
+const (
+	ReducedPercent   = 7
+	StandardPercent  = 19
+	ReducedRateMaxKm = 50.0
+)
+
+// Percent returns the VAT rate in percent for a trip of distanceKm.
+func Percent(distanceKm float64) int {
+	if distanceKm <= ReducedRateMaxKm {
+		return ReducedPercent
+	}
+	return StandardPercent
+}
+
+// Rate returns the VAT rate for a trip of distanceKm as a fraction, e.g.
+// 0.07.
+func Rate(distanceKm float64) float64 {
+	return float64(Percent(distanceKm)) / 100
+}
+
+// Split splits a gross amount in cents into net and VAT at percent. The net
+// amount is rounded to the cent and the VAT is the remainder, so net + VAT
+// always equals the gross exactly. A quote and the receipt for the same fare
+// therefore show the same breakdown.
+func Split(grossCents int64, percent int) (netCents, vatCents int64) {
+	netCents = int64(math.Round(float64(grossCents) * 100 / float64(100+percent)))
+	return netCents, grossCents - netCents
+}
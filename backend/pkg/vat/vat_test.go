@@ -0,0 +1,43 @@
+package vat
+
+import "testing"
+
+func TestPercentByDistance(t *testing.T) {
+	tests := []struct {
+		distanceKm float64
+		want       int
+	}{
+		{0, ReducedPercent},
+		{10, ReducedPercent},
+		{50, ReducedPercent},
+		{50.1, StandardPercent},
+		{120, StandardPercent},
+	}
+	for _, tt := range tests {
+		if got := Percent(tt.distanceKm); got != tt.want {
+			t.Errorf("Percent(%v) = %d, want %d", tt.distanceKm, got, tt.want)
+		}
+	}
+	if Rate(10) != 0.07 || Rate(120) != 0.19 {
+		t.Errorf("Rate = %v / %v, want 0.07 / 0.19", Rate(10), Rate(120))
+	}
+}
+
+func TestSplit(t *testing.T) {
+	// 22,40 at 7%: 20,93 net and 1,47 VAT; 100,00 at 19%: 84,03 net and 15,97 VAT
+	if net, v := Split(2240, ReducedPercent); net != 2093 || v != 147 {
+		t.Errorf("Split(2240, 7) = %d, %d", net, v)
+	}
+	if net, v := Split(10000, StandardPercent); net != 8403 || v != 1597 {
+		t.Errorf("Split(10000, 19) = %d, %d", net, v)
+	}
+
+	for gross := int64(0); gross <= 50000; gross++ {
+		for _, percent := range []int{ReducedPercent, StandardPercent} {
+			net, v := Split(gross, percent)
+			if net+v != gross || v < 0 {
+				t.Fatalf("Split(%d, %d) = %d, %d", gross, percent, net, v)
+			}
+		}
+	}
+}
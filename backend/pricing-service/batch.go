@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxBatchPriceRequests caps the number of quotes per POST /price/batch
+const MaxBatchPriceRequests = 50
+
+// BatchPriceResult is one element of the batch response: the price fields of
+// a PriceResponse, or only error for an invalid entry
+type BatchPriceResult struct {
+	*PriceResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// handlePriceBatch prices a JSON array of PriceRequest objects. Results are
+// returned in request order; an invalid entry yields an error object in its
+// position without failing the rest of the batch.
+func handlePriceBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := requireJSON(r); err != nil {
+		responseError(w, err.Error(), "INVALID_REQUEST", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var items []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPriceRequestBytes)).Decode(&items); err != nil {
+		msg := "request body must be a JSON array of price requests"
+		if errors.Is(err, io.EOF) {
+			msg = "request body is required"
+		}
+		responseError(w, msg, "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	if len(items) == 0 {
+		responseError(w, "at least one price request is required", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	if len(items) > MaxBatchPriceRequests {
+		responseError(w, fmt.Sprintf("too many price requests (max %d)", MaxBatchPriceRequests), "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+
+	lang := responseLanguage(w, r)
+	results := make([]BatchPriceResult, len(items))
+	for i, item := range items {
+		// Demand and supply default to 10 when omitted, as for /price
+		req := &PriceRequest{Demand: 10, Supply: 10}
+		if err := json.Unmarshal(item, req); err != nil {
+			results[i].Error = &ErrorResponse{Error: "invalid price request: " + err.Error(), Code: "INVALID_REQUEST"}
+			continue
+		}
+		resp, errResp, _ := quotePrice(req)
+		if resp != nil {
+			resp.localize(lang)
+		}
+		results[i] = BatchPriceResult{PriceResponse: resp, Error: errResp}
+	}
+
+	logger.Info("Batch price calculated", "requests", len(items))
+
+	responseJSON(w, results, http.StatusOK)
+}
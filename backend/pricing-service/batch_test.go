@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func postBatch(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/price/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handlePriceBatch(rec, req)
+	return rec
+}
+
+func TestHandlePriceBatchKeepsOrderAndReportsItemErrors(t *testing.T) {
+	rec := postBatch(t, `[
+		{"distance_km": 10, "duration_min": 20},
+		{"distance_km": 0, "duration_min": 20},
+		"not a request",
+		{"distance_km": 80, "duration_min": 60}
+	]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var results []struct {
+		FinalPrice float64        `json:"final_price"`
+		QuoteToken string         `json:"quote_token"`
+		Error      *ErrorResponse `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+		t.Fatalf("failed to decode results: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+
+	if results[0].Error != nil || results[0].FinalPrice != 28.50 || results[0].QuoteToken == "" {
+		t.Errorf("result 0 = %+v, want a 28.50 quote", results[0])
+	}
+	if results[1].Error == nil || results[1].Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("result 1 = %+v, want a validation error", results[1])
+	}
+	if results[2].Error == nil || results[2].Error.Code != "INVALID_REQUEST" {
+		t.Errorf("result 2 = %+v, want an invalid request error", results[2])
+	}
+	if results[3].Error != nil || results[3].FinalPrice <= results[0].FinalPrice {
+		t.Errorf("result 3 = %+v, want a longer-trip quote", results[3])
+	}
+}
+
+func TestHandlePriceBatchRejectsOversizedBatch(t *testing.T) {
+	items := make([]string, MaxBatchPriceRequests+1)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"distance_km": %d, "duration_min": 10}`, i+1)
+	}
+
+	rec := postBatch(t, "["+strings.Join(items, ",")+"]")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+	if resp.Code != "INVALID_REQUEST" {
+		t.Errorf("code = %q, want INVALID_REQUEST", resp.Code)
+	}
+
+	if rec := postBatch(t, `[]`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+)
+
+const (
+	// CancellationGraceMinutes is the window after matching in which a rider
+	// can cancel for free
+	CancellationGraceMinutes = 2.0
+
+	// MaxCancellationFeeEUR caps the cancellation fee
+	MaxCancellationFeeEUR = 15.00
+)
+
+// CancellationFeeResponse is the response of GET /cancellation-fee
+type CancellationFeeResponse struct {
+	MinutesSinceMatch float64 `json:"minutes_since_match"`
+	DriverDistanceKm  float64 `json:"driver_distance_km"`
+	Fee               float64 `json:"fee"`
+	Currency          string  `json:"currency"`
+	GracePeriod       bool    `json:"grace_period"`
+	Capped            bool    `json:"capped"`
+}
+
+// calculateCancellationFee is free within the grace window (up to and
+// including CancellationGraceMinutes) and otherwise the base rate plus the
+// distance the driver already travelled, capped at MaxCancellationFeeEUR.
+func calculateCancellationFee(minutesSinceMatch, driverDistanceKm float64, cfg PricingConfig) CancellationFeeResponse {
+	resp := CancellationFeeResponse{
+		MinutesSinceMatch: minutesSinceMatch,
+		DriverDistanceKm:  driverDistanceKm,
+		Currency:          "EUR",
+	}
+	if minutesSinceMatch <= CancellationGraceMinutes {
+		resp.GracePeriod = true
+		return resp
+	}
+
+	fee := cfg.BaseRateEUR + driverDistanceKm*cfg.PricePerKmEUR
+	if fee > MaxCancellationFeeEUR {
+		fee = MaxCancellationFeeEUR
+		resp.Capped = true
+	}
+	resp.Fee = math.Round(fee*100) / 100
+	return resp
+}
+
+// handleCancellationFee serves GET /cancellation-fee?minutes_since_match=&driver_distance_km=
+func handleCancellationFee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	locale := query.Get("locale")
+
+	minutes, err := parseDecimal(query.Get("minutes_since_match"), locale)
+	if err != nil {
+		responseError(w, fmt.Sprintf("invalid minutes_since_match parameter: %v", err), "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	distance, err := parseDecimal(query.Get("driver_distance_km"), locale)
+	if err != nil {
+		responseError(w, fmt.Sprintf("invalid driver_distance_km parameter: %v", err), "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+
+	if minutes < 0 || math.IsNaN(minutes) || math.IsInf(minutes, 0) {
+		responseError(w, "minutes_since_match cannot be negative", "VALIDATION_ERROR", http.StatusBadRequest)
+		return
+	}
+	if distance < 0 || math.IsNaN(distance) || math.IsInf(distance, 0) {
+		responseError(w, "driver_distance_km cannot be negative", "VALIDATION_ERROR", http.StatusBadRequest)
+		return
+	}
+
+	resp := calculateCancellationFee(minutes, distance, activeConfig)
+
+	logger.Info("Cancellation fee calculated",
+		"minutes_since_match", minutes,
+		"driver_distance_km", distance,
+		"fee", resp.Fee,
+	)
+
+	responseJSON(w, resp, http.StatusOK)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCancellationFeeGracePeriodBoundary(t *testing.T) {
+	cfg := defaultPricingConfig()
+
+	if resp := calculateCancellationFee(CancellationGraceMinutes, 1.0, cfg); resp.Fee != 0 || !resp.GracePeriod {
+		t.Errorf("at %.0f minutes: fee = %.2f, want free", CancellationGraceMinutes, resp.Fee)
+	}
+
+	// 3.50 base + 1km at 1.80
+	resp := calculateCancellationFee(CancellationGraceMinutes+0.01, 1.0, cfg)
+	if resp.Fee != 5.30 || resp.GracePeriod {
+		t.Errorf("just after the grace period: fee = %.2f, want 5.30", resp.Fee)
+	}
+	if resp.Currency != "EUR" {
+		t.Errorf("currency = %q, want EUR", resp.Currency)
+	}
+}
+
+func TestCancellationFeeIsCapped(t *testing.T) {
+	resp := calculateCancellationFee(10, 20, defaultPricingConfig())
+	if resp.Fee != MaxCancellationFeeEUR || !resp.Capped {
+		t.Errorf("fee = %.2f, want the cap %.2f", resp.Fee, MaxCancellationFeeEUR)
+	}
+}
+
+func TestHandleCancellationFeeRejectsInvalidInput(t *testing.T) {
+	for _, query := range []string{
+		"minutes_since_match=-1&driver_distance_km=1",
+		"minutes_since_match=5&driver_distance_km=-0.5",
+		"minutes_since_match=5",
+		"driver_distance_km=1",
+	} {
+		rec := httptest.NewRecorder()
+		handleCancellationFee(rec, httptest.NewRequest(http.MethodGet, "/cancellation-fee?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
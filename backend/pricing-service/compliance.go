@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Compliance note codes. The codes are stable, so clients can map them to
+// their own texts; the note wording may change.
+const (
+	ComplianceMinimumFare      = "MINIMUM_FARE"
+	ComplianceMinimumPerKmRate = "MINIMUM_PER_KM_RATE"
+	ComplianceNightTariff      = "NIGHT_TARIFF"
+	ComplianceZoneSurcharge    = "ZONE_SURCHARGE"
+	ComplianceFixedTariff      = "FIXED_TARIFF"
+	CompliancePoolMinimumFare  = "POOL_MINIMUM_FARE"
+	CompliancePromoMinimumFare = "PROMO_MINIMUM_FARE"
+)
+
+// Languages compliance notes are available in. German is the default for
+// the German market.
+const (
+	languageGerman  = "de"
+	languageEnglish = "en"
+
+	defaultLanguage = languageGerman
+)
+
+// complianceTexts holds the note per code and language. Amounts are passed
+// as float64 and formatted for the language, hence %s.
+var complianceTexts = map[string]map[string]string{
+	ComplianceMinimumFare: {
+		languageGerman:  "Preis auf den Mindestfahrpreis nach § 51 PBefG angehoben",
+		languageEnglish: "Price adjusted to minimum fare per PBefG §51",
+	},
+	ComplianceMinimumPerKmRate: {
+		languageGerman:  "Preis auf den Mindestkilometerpreis nach § 39 PBefG angehoben",
+		languageEnglish: "Price adjusted to minimum per-km rate per PBefG §39",
+	},
+	ComplianceNightTariff: {
+		languageGerman:  "Nachttarif (22:00-06:00) angewendet",
+		languageEnglish: "Night tariff (22:00-06:00) applied",
+	},
+	ComplianceZoneSurcharge: {
+		languageGerman:  "Zonenzuschlag von %s EUR für die Abholzone %s berechnet",
+		languageEnglish: "Zone surcharge of %s EUR applied for pickup zone %s",
+	},
+	ComplianceFixedTariff: {
+		languageGerman:  "Festpreis für die Strecke %s nach %s angewendet",
+		languageEnglish: "Fixed tariff (Festpreis) applied for route %s to %s",
+	},
+	CompliancePoolMinimumFare: {
+		languageGerman:  "Anteil an der Sammelfahrt auf den Mindestfahrpreis nach § 51 PBefG angehoben",
+		languageEnglish: "Pool share adjusted to minimum fare per PBefG §51",
+	},
+	CompliancePromoMinimumFare: {
+		languageGerman:  "Aktionsrabatt durch den Mindestfahrpreis nach § 51 PBefG begrenzt",
+		languageEnglish: "Promo discount limited by minimum fare per PBefG §51",
+	},
+}
+
+// complianceNotice is one adjustment or tariff rule applied to a price
+type complianceNotice struct {
+	code string
+	args []interface{}
+}
+
+func (n complianceNotice) text(lang string) string {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		if f, ok := arg.(float64); ok {
+			arg = formatAmount(f, lang)
+		}
+		args[i] = arg
+	}
+	return fmt.Sprintf(complianceTexts[n.code][lang], args...)
+}
+
+// Compliance is the compliance note of a price response. ComplianceNote
+// joins the notices with "; " in the language the response was localized
+// to, and ComplianceCode joins their codes with ";" in the same order.
+type Compliance struct {
+	ComplianceNote string `json:"compliance_note,omitempty"`
+	ComplianceCode string `json:"compliance_code,omitempty"`
+	notices        []complianceNotice
+}
+
+// addCompliance appends a notice and renders the note in defaultLanguage
+func (c *Compliance) addCompliance(code string, args ...interface{}) {
+	// Copy before appending: a Compliance copied from another response
+	// shares its notices
+	c.notices = append(c.notices[:len(c.notices):len(c.notices)], complianceNotice{code: code, args: args})
+	c.localize(defaultLanguage)
+}
+
+// localize renders the note in lang
+func (c *Compliance) localize(lang string) {
+	notes := make([]string, len(c.notices))
+	codes := make([]string, len(c.notices))
+	for i, n := range c.notices {
+		notes[i] = n.text(lang)
+		codes[i] = n.code
+	}
+	c.ComplianceNote = strings.Join(notes, "; ")
+	c.ComplianceCode = strings.Join(codes, ";")
+}
+
+// formatAmount formats a EUR amount with the decimal separator of lang
+func formatAmount(v float64, lang string) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	if lang == languageGerman {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// negotiateLanguage picks the compliance note language from an
+// Accept-Language header: the supported language with the highest quality,
+// the first listed on a tie, or defaultLanguage if none is supported.
+func negotiateLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		lang := strings.SplitN(tag, "-", 2)[0]
+		if lang != languageGerman && lang != languageEnglish {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultLanguage
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// responseLanguage negotiates the language for r and announces it on w
+func responseLanguage(w http.ResponseWriter, r *http.Request) string {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	return lang
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                          languageGerman,
+		"de-DE":                     languageGerman,
+		"en":                        languageEnglish,
+		"EN-gb,en;q=0.9":            languageEnglish,
+		"fr-FR, en;q=0.8, de;q=0.7": languageEnglish,
+		"en;q=0.5, de;q=0.9":        languageGerman,
+		"de, en":                    languageGerman,
+		"en;q=0, de;q=0.1":          languageGerman,
+		"fr, it":                    languageGerman,
+		"*":                         languageGerman,
+	}
+	for header, want := range cases {
+		if got := negotiateLanguage(header); got != want {
+			t.Errorf("negotiateLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+// nightPriceWithLanguage prices a night ride from the BER zone, which gets
+// both the night tariff and the zone surcharge notes
+func nightPriceWithLanguage(t *testing.T, acceptLanguage string) (PriceResponse, string) {
+	t.Helper()
+	useZoneSurcharges(t, ZoneSurcharge{"BER": 5.00})
+
+	req := httptest.NewRequest(http.MethodGet, "/price?distance_km=10&duration_min=20&pickup_time=2024-03-01T23:30:00%2B01:00&pickup_zone=BER", nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	rec := httptest.NewRecorder()
+	handlePrice(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp PriceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp, rec.Header().Get("Content-Language")
+}
+
+func TestComplianceNoteInGerman(t *testing.T) {
+	resp, lang := nightPriceWithLanguage(t, "de-DE,de;q=0.9")
+
+	want := "Nachttarif (22:00-06:00) angewendet; Zonenzuschlag von 5,00 EUR für die Abholzone BER berechnet"
+	if resp.ComplianceNote != want || lang != languageGerman {
+		t.Errorf("note = %q (%s), want %q", resp.ComplianceNote, lang, want)
+	}
+}
+
+func TestComplianceNoteInEnglish(t *testing.T) {
+	resp, lang := nightPriceWithLanguage(t, "en-US")
+
+	want := "Night tariff (22:00-06:00) applied; Zone surcharge of 5.00 EUR applied for pickup zone BER"
+	if resp.ComplianceNote != want || lang != languageEnglish {
+		t.Errorf("note = %q (%s), want %q", resp.ComplianceNote, lang, want)
+	}
+}
+
+func TestComplianceNoteDefaultsToGerman(t *testing.T) {
+	german, _ := nightPriceWithLanguage(t, "de")
+	for _, header := range []string{"", "fr-FR"} {
+		resp, lang := nightPriceWithLanguage(t, header)
+		if resp.ComplianceNote != german.ComplianceNote || lang != languageGerman {
+			t.Errorf("Accept-Language %q: note = %q (%s), want the German note", header, resp.ComplianceNote, lang)
+		}
+	}
+}
+
+func TestComplianceCodeIsLanguageIndependent(t *testing.T) {
+	german, _ := nightPriceWithLanguage(t, "de")
+	english, _ := nightPriceWithLanguage(t, "en")
+
+	want := ComplianceNightTariff + ";" + ComplianceZoneSurcharge
+	if german.ComplianceCode != want || english.ComplianceCode != want {
+		t.Errorf("codes = %q (de), %q (en), want %q", german.ComplianceCode, english.ComplianceCode, want)
+	}
+}
+
+func TestEveryComplianceCodeHasBothLanguages(t *testing.T) {
+	for code, texts := range complianceTexts {
+		for _, lang := range []string{languageGerman, languageEnglish} {
+			if texts[lang] == "" {
+				t.Errorf("%s has no %s text", code, lang)
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// PricingConfig holds the tariff parameters used by calculatePriceWithConfig.
+type PricingConfig struct {
+	BaseRateEUR           float64 `json:"base_rate_eur"`
+	PricePerKmEUR         float64 `json:"price_per_km_eur"`
+	PricePerMinuteEUR     float64 `json:"price_per_minute_eur"`
+	PricePerWaitMinuteEUR float64 `json:"price_per_wait_minute_eur"`
+	MinimumFareEUR        float64 `json:"minimum_fare_eur"`
+	MinPricePerKmEUR      float64 `json:"min_price_per_km_eur"`
+	MaxSurgeMultiplier    float64 `json:"max_surge_multiplier"`
+	NightMultiplier       float64 `json:"night_multiplier"`
+	// Version names the tariff, e.g. after the municipal regulation it
+	// implements, and is echoed on every quote
+	Version string `json:"version,omitempty"`
+}
+
+// defaultTariffVersion names the tariff defined by the PBefG constants
+const defaultTariffVersion = "pbefg-default"
+
+// TariffVersion identifies the tariff a quote was priced with, including the
+// regulated parameters, so an audit can tie the quote to them
+type TariffVersion struct {
+	Version          string  `json:"version"`
+	BaseRateEUR      float64 `json:"base_rate_eur"`
+	PricePerKmEUR    float64 `json:"price_per_km_eur"`
+	MinimumFareEUR   float64 `json:"minimum_fare_eur"`
+	MinPricePerKmEUR float64 `json:"min_price_per_km_eur"`
+}
+
+func (c PricingConfig) tariffVersion() TariffVersion {
+	return TariffVersion{
+		Version:          c.Version,
+		BaseRateEUR:      c.BaseRateEUR,
+		PricePerKmEUR:    c.PricePerKmEUR,
+		MinimumFareEUR:   c.MinimumFareEUR,
+		MinPricePerKmEUR: c.MinPricePerKmEUR,
+	}
+}
+
+// activeConfig is the tariff applied to live price requests. It is only ever
+// read by request handlers; previews work on copies.
+var activeConfig = defaultPricingConfig()
+
+// defaultPricingConfig returns the tariff defined by the PBefG constants.
+func defaultPricingConfig() PricingConfig {
+	return PricingConfig{
+		BaseRateEUR:           BaseRateEUR,
+		PricePerKmEUR:         PricePerKmEUR,
+		PricePerMinuteEUR:     PricePerMinuteEUR,
+		PricePerWaitMinuteEUR: PricePerWaitMinuteEUR,
+		MinimumFareEUR:        MinimumFareEUR,
+		MinPricePerKmEUR:      MinPricePerKmEUR,
+		MaxSurgeMultiplier:    MaxSurgeMultiplier,
+		NightMultiplier:       NightMultiplier,
+		Version:               defaultTariffVersion,
+	}
+}
+
+// loadPricingConfig overrides the regulated tariff parameters, which change
+// with regulation updates and differ by municipality, from
+// PRICING_BASE_RATE_EUR, PRICING_PRICE_PER_KM_EUR, PRICING_MINIMUM_FARE_EUR
+// and PRICING_MIN_PRICE_PER_KM_EUR. PRICING_TARIFF_VERSION names the result.
+func loadPricingConfig() (PricingConfig, error) {
+	cfg := defaultPricingConfig()
+
+	params := []struct {
+		env   string
+		value *float64
+	}{
+		{"PRICING_BASE_RATE_EUR", &cfg.BaseRateEUR},
+		{"PRICING_PRICE_PER_KM_EUR", &cfg.PricePerKmEUR},
+		{"PRICING_MINIMUM_FARE_EUR", &cfg.MinimumFareEUR},
+		{"PRICING_MIN_PRICE_PER_KM_EUR", &cfg.MinPricePerKmEUR},
+	}
+	for _, p := range params {
+		v := os.Getenv(p.env)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("%s must be a number, got %q", p.env, v)
+		}
+		*p.value = f
+	}
+	if v := os.Getenv("PRICING_TARIFF_VERSION"); v != "" {
+		cfg.Version = v
+	}
+
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("invalid tariff: %w", err)
+	}
+	return cfg, nil
+}
+
+// validate ensures the tariff parameters are usable
+func (c PricingConfig) validate() error {
+	values := map[string]float64{
+		"base_rate_eur":             c.BaseRateEUR,
+		"price_per_km_eur":          c.PricePerKmEUR,
+		"price_per_minute_eur":      c.PricePerMinuteEUR,
+		"price_per_wait_minute_eur": c.PricePerWaitMinuteEUR,
+		"minimum_fare_eur":          c.MinimumFareEUR,
+		"min_price_per_km_eur":      c.MinPricePerKmEUR,
+		"max_surge_multiplier":      c.MaxSurgeMultiplier,
+		"night_multiplier":          c.NightMultiplier,
+	}
+	for name, v := range values {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s must be a non-negative number", name)
+		}
+	}
+
+	if c.MaxSurgeMultiplier < 1.0 {
+		return errors.New("max_surge_multiplier must be at least 1.0")
+	}
+
+	if c.NightMultiplier < 1.0 || c.NightMultiplier > c.MaxSurgeMultiplier {
+		return errors.New("night_multiplier must be between 1.0 and max_surge_multiplier")
+	}
+
+	// PBefG §51: the minimum fare covers at least the base rate
+	if c.MinimumFareEUR < c.BaseRateEUR {
+		return errors.New("minimum_fare_eur must be at least base_rate_eur")
+	}
+
+	// PBefG §39: the per-km floor cannot exceed the regular per-km rate
+	if c.MinPricePerKmEUR > c.PricePerKmEUR {
+		return errors.New("min_price_per_km_eur must not exceed price_per_km_eur")
+	}
+
+	return nil
+}
+
+// MaxPreviewSamples caps the number of sample rides per preview request
+const MaxPreviewSamples = 100
+
+// ConfigPreviewRequest is the payload for POST /config/preview. Fields omitted
+// from ProposedConfig keep their current value.
+type ConfigPreviewRequest struct {
+	ProposedConfig json.RawMessage `json:"proposed_config"`
+	Samples        []PriceRequest  `json:"samples"`
+}
+
+// ConfigPreviewResult compares one sample ride under both tariffs
+type ConfigPreviewResult struct {
+	Request       PriceRequest   `json:"request"`
+	Current       *PriceResponse `json:"current"`
+	Proposed      *PriceResponse `json:"proposed"`
+	DifferenceEUR float64        `json:"difference_eur"`
+}
+
+// ConfigPreviewResponse is the response from POST /config/preview
+type ConfigPreviewResponse struct {
+	CurrentConfig  PricingConfig         `json:"current_config"`
+	ProposedConfig PricingConfig         `json:"proposed_config"`
+	Results        []ConfigPreviewResult `json:"results"`
+}
+
+// handleConfigPreview computes sample fares under the active and a proposed
+// tariff side by side. The active tariff is never modified.
+func handleConfigPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ConfigPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		responseError(w, "Invalid JSON body", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := previewConfig(activeConfig, req)
+	if err != nil {
+		logger.Warn("Config preview validation failed", "error", err)
+		responseError(w, err.Error(), "VALIDATION_ERROR", http.StatusBadRequest)
+		return
+	}
+
+	lang := responseLanguage(w, r)
+	for _, result := range resp.Results {
+		result.Current.localize(lang)
+		result.Proposed.localize(lang)
+	}
+
+	logger.Info("Config preview computed", "samples", len(resp.Results))
+
+	responseJSON(w, resp, http.StatusOK)
+}
+
+// previewConfig applies req.ProposedConfig on top of current and prices all
+// samples under both. current is passed by value so it cannot be mutated.
+func previewConfig(current PricingConfig, req ConfigPreviewRequest) (*ConfigPreviewResponse, error) {
+	if len(req.Samples) == 0 {
+		return nil, errors.New("at least one sample ride is required")
+	}
+	if len(req.Samples) > MaxPreviewSamples {
+		return nil, fmt.Errorf("too many samples (max %d)", MaxPreviewSamples)
+	}
+
+	proposed := current
+	if len(req.ProposedConfig) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(req.ProposedConfig))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&proposed); err != nil {
+			return nil, fmt.Errorf("invalid proposed_config: %w", err)
+		}
+	}
+	if err := proposed.validate(); err != nil {
+		return nil, fmt.Errorf("invalid proposed_config: %w", err)
+	}
+
+	results := make([]ConfigPreviewResult, 0, len(req.Samples))
+	for i := range req.Samples {
+		sample := req.Samples[i]
+		if err := validatePriceRequest(&sample); err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+
+		currentPrice, err := calculatePriceWithConfig(&sample, current)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+		proposedPrice, err := calculatePriceWithConfig(&sample, proposed)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+
+		results = append(results, ConfigPreviewResult{
+			Request:       sample,
+			Current:       currentPrice,
+			Proposed:      proposedPrice,
+			DifferenceEUR: math.Round((proposedPrice.FinalPrice-currentPrice.FinalPrice)*100) / 100,
+		})
+	}
+
+	return &ConfigPreviewResponse{
+		CurrentConfig:  current,
+		ProposedConfig: proposed,
+		Results:        results,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfigPreviewComparesTariffs(t *testing.T) {
+	before := activeConfig
+
+	body := `{
+		"proposed_config": {"price_per_km_eur": 2.00},
+		"samples": [{"distance_km": 10, "duration_min": 20, "demand": 10, "supply": 10}]
+	}`
+	rec := httptest.NewRecorder()
+	handleConfigPreview(rec, httptest.NewRequest(http.MethodPost, "/config/preview", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp ConfigPreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("got %d results, want 1", len(resp.Results))
+	}
+
+	result := resp.Results[0]
+	// 3.50 + 10*1.80 + 20*0.35 = 28.50 vs 3.50 + 10*2.00 + 20*0.35 = 30.50
+	if result.Current.FinalPrice != 28.50 {
+		t.Errorf("current price = %.2f, want 28.50", result.Current.FinalPrice)
+	}
+	if result.Proposed.FinalPrice != 30.50 {
+		t.Errorf("proposed price = %.2f, want 30.50", result.Proposed.FinalPrice)
+	}
+	if result.DifferenceEUR != 2.00 {
+		t.Errorf("difference = %.2f, want 2.00", result.DifferenceEUR)
+	}
+
+	// Fields omitted from the proposal keep their current value.
+	if resp.ProposedConfig.BaseRateEUR != before.BaseRateEUR {
+		t.Errorf("proposed base rate = %.2f, want unchanged %.2f", resp.ProposedConfig.BaseRateEUR, before.BaseRateEUR)
+	}
+
+	if activeConfig != before {
+		t.Errorf("preview mutated the active config: %+v", activeConfig)
+	}
+}
+
+func TestConfigPreviewRejectsInvalidProposal(t *testing.T) {
+	cases := map[string]string{
+		"negative rate":       `{"proposed_config": {"price_per_km_eur": -1}, "samples": [{"distance_km": 5, "duration_min": 10}]}`,
+		"surge below 1":       `{"proposed_config": {"max_surge_multiplier": 0.5}, "samples": [{"distance_km": 5, "duration_min": 10}]}`,
+		"unknown field":       `{"proposed_config": {"price_per_mile": 3}, "samples": [{"distance_km": 5, "duration_min": 10}]}`,
+		"no samples":          `{"proposed_config": {}, "samples": []}`,
+		"invalid sample":      `{"proposed_config": {}, "samples": [{"distance_km": 0, "duration_min": 10}]}`,
+		"malformed JSON":      `{"proposed_config": `,
+		"minimum below base":  `{"proposed_config": {"minimum_fare_eur": 3.00}, "samples": [{"distance_km": 5, "duration_min": 10}]}`,
+		"km floor above rate": `{"proposed_config": {"min_price_per_km_eur": 2.00}, "samples": [{"distance_km": 5, "duration_min": 10}]}`,
+	}
+	for name, body := range cases {
+		rec := httptest.NewRecorder()
+		handleConfigPreview(rec, httptest.NewRequest(http.MethodPost, "/config/preview", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestConfigPreviewCapsSamples(t *testing.T) {
+	samples := make([]PriceRequest, MaxPreviewSamples+1)
+	for i := range samples {
+		samples[i] = PriceRequest{DistanceKm: 5, DurationMin: 10}
+	}
+
+	if _, err := previewConfig(activeConfig, ConfigPreviewRequest{Samples: samples}); err == nil {
+		t.Error("expected an error for too many samples")
+	}
+}
+
+func TestConfigPreviewMethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleConfigPreview(rec, httptest.NewRequest(http.MethodGet, "/config/preview", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
+
+func TestLoadPricingConfig(t *testing.T) {
+	t.Setenv("PRICING_MINIMUM_FARE_EUR", "6.50")
+	t.Setenv("PRICING_MIN_PRICE_PER_KM_EUR", "1.60")
+	t.Setenv("PRICING_TARIFF_VERSION", "berlin-2024-03")
+
+	cfg, err := loadPricingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MinimumFareEUR != 6.50 || cfg.MinPricePerKmEUR != 1.60 || cfg.Version != "berlin-2024-03" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.BaseRateEUR != BaseRateEUR || cfg.PricePerKmEUR != PricePerKmEUR {
+		t.Errorf("unset parameters changed: %+v", cfg)
+	}
+}
+
+func TestLoadPricingConfigDefaults(t *testing.T) {
+	cfg, err := loadPricingConfig()
+	if err != nil || cfg != defaultPricingConfig() {
+		t.Errorf("got %+v, %v, want the default tariff", cfg, err)
+	}
+}
+
+func TestLoadPricingConfigRejectsInvalidTariff(t *testing.T) {
+	cases := map[string]map[string]string{
+		"minimum fare below base rate":   {"PRICING_MINIMUM_FARE_EUR": "3.00"},
+		"raised base rate above minimum": {"PRICING_BASE_RATE_EUR": "5.50"},
+		"km floor above km rate":         {"PRICING_MIN_PRICE_PER_KM_EUR": "1.90"},
+		"lowered km rate below floor":    {"PRICING_PRICE_PER_KM_EUR": "1.20"},
+		"negative minimum fare":          {"PRICING_MINIMUM_FARE_EUR": "-5"},
+		"not a number":                   {"PRICING_MINIMUM_FARE_EUR": "five"},
+	}
+	for name, env := range cases {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+			if _, err := loadPricingConfig(); err == nil {
+				t.Error("expected a validation error")
+			}
+		})
+	}
+}
+
+func TestPriceResponseCarriesTariffVersion(t *testing.T) {
+	cfg := defaultPricingConfig()
+	cfg.Version = "munich-2025-01"
+	cfg.MinimumFareEUR = 7.00
+
+	resp, err := calculatePriceWithConfig(&PriceRequest{DistanceKm: 5, DurationMin: 10, Demand: 1, Supply: 1}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := TariffVersion{Version: "munich-2025-01", BaseRateEUR: BaseRateEUR, PricePerKmEUR: PricePerKmEUR, MinimumFareEUR: 7.00, MinPricePerKmEUR: MinPricePerKmEUR}
+	if resp.TariffVersion != want {
+		t.Errorf("tariff_version = %+v, want %+v", resp.TariffVersion, want)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MaxGuaranteeWindow bounds the accounting period of one guarantee computation
+const MaxGuaranteeWindow = 31 * 24 * time.Hour
+
+// EarningsGuaranteeConfig configures the minimum hourly earnings guarantee.
+//
+// Time counting: online time is the union of the driver's online sessions
+// within the window, so overlapping sessions are counted once. Active time is
+// the union of trip intervals within the window; idle time is online minus
+// active. With CountIdleTime (the default) every online hour is guaranteed,
+// whether the driver was on a trip or waiting for one. Without it only
+// active hours are guaranteed.
+type EarningsGuaranteeConfig struct {
+	MinimumHourlyEUR float64 `json:"minimum_hourly_eur"`
+	CountIdleTime    bool    `json:"count_idle_time"`
+}
+
+func defaultEarningsGuaranteeConfig() EarningsGuaranteeConfig {
+	return EarningsGuaranteeConfig{MinimumHourlyEUR: 12.82, CountIdleTime: true}
+}
+
+var guaranteeConfig = defaultEarningsGuaranteeConfig()
+
+// loadEarningsGuaranteeConfig reads EARNINGS_GUARANTEE_MIN_HOURLY_EUR and
+// EARNINGS_GUARANTEE_COUNT_IDLE_TIME.
+func loadEarningsGuaranteeConfig() (EarningsGuaranteeConfig, error) {
+	cfg := defaultEarningsGuaranteeConfig()
+
+	if v := os.Getenv("EARNINGS_GUARANTEE_MIN_HOURLY_EUR"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			return cfg, fmt.Errorf("EARNINGS_GUARANTEE_MIN_HOURLY_EUR must be a non-negative number, got %q", v)
+		}
+		cfg.MinimumHourlyEUR = f
+	}
+	if v := os.Getenv("EARNINGS_GUARANTEE_COUNT_IDLE_TIME"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("EARNINGS_GUARANTEE_COUNT_IDLE_TIME must be true or false, got %q", v)
+		}
+		cfg.CountIdleTime = b
+	}
+
+	return cfg, nil
+}
+
+// TimeInterval is a closed-open interval [Start, End)
+type TimeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// GuaranteeTrip is a completed trip attributed to the driver. Fares count
+// towards the window in which the trip ended.
+type GuaranteeTrip struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	FareEUR float64   `json:"fare_eur"`
+}
+
+// EarningsGuaranteeRequest is the payload for POST /earnings/guarantee
+type EarningsGuaranteeRequest struct {
+	DriverID       string          `json:"driver_id"`
+	WindowStart    time.Time       `json:"window_start"`
+	WindowEnd      time.Time       `json:"window_end"`
+	OnlineSessions []TimeInterval  `json:"online_sessions"`
+	Trips          []GuaranteeTrip `json:"trips"`
+}
+
+// EarningsGuaranteeResponse is the result of a guarantee computation. TopUpEUR
+// is paid by the operator to the driver; it is never charged to riders.
+type EarningsGuaranteeResponse struct {
+	DriverID         string    `json:"driver_id"`
+	WindowStart      time.Time `json:"window_start"`
+	WindowEnd        time.Time `json:"window_end"`
+	OnlineHours      float64   `json:"online_hours"`
+	ActiveHours      float64   `json:"active_hours"`
+	IdleHours        float64   `json:"idle_hours"`
+	CountedHours     float64   `json:"counted_hours"`
+	EarnedEUR        float64   `json:"earned_eur"`
+	MinimumHourlyEUR float64   `json:"minimum_hourly_eur"`
+	GuaranteedEUR    float64   `json:"guaranteed_eur"`
+	TopUpEUR         float64   `json:"top_up_eur"`
+	CountIdleTime    bool      `json:"count_idle_time"`
+}
+
+// handleEarningsGuarantee computes the top-up needed to bring a driver's
+// earnings in a window up to the configured minimum hourly rate
+func handleEarningsGuarantee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req EarningsGuaranteeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		responseError(w, "Invalid JSON body", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := computeEarningsGuarantee(req, guaranteeConfig)
+	if err != nil {
+		logger.Warn("Earnings guarantee validation failed", "error", err)
+		responseError(w, err.Error(), "VALIDATION_ERROR", http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Earnings guarantee computed",
+		"driver_id", resp.DriverID,
+		"counted_hours", resp.CountedHours,
+		"earned_eur", resp.EarnedEUR,
+		"top_up_eur", resp.TopUpEUR,
+	)
+
+	responseJSON(w, resp, http.StatusOK)
+}
+
+func computeEarningsGuarantee(req EarningsGuaranteeRequest, cfg EarningsGuaranteeConfig) (*EarningsGuaranteeResponse, error) {
+	if req.DriverID == "" {
+		return nil, errors.New("driver_id is required")
+	}
+	if !req.WindowEnd.After(req.WindowStart) {
+		return nil, errors.New("window_end must be after window_start")
+	}
+	if req.WindowEnd.Sub(req.WindowStart) > MaxGuaranteeWindow {
+		return nil, fmt.Errorf("window must not exceed %d days", int(MaxGuaranteeWindow.Hours()/24))
+	}
+
+	window := TimeInterval{Start: req.WindowStart, End: req.WindowEnd}
+
+	for i, s := range req.OnlineSessions {
+		if !s.End.After(s.Start) {
+			return nil, fmt.Errorf("online session %d: end must be after start", i)
+		}
+	}
+
+	earned := 0.0
+	trips := make([]TimeInterval, 0, len(req.Trips))
+	for i, t := range req.Trips {
+		if !t.End.After(t.Start) {
+			return nil, fmt.Errorf("trip %d: end must be after start", i)
+		}
+		if t.FareEUR < 0 || math.IsNaN(t.FareEUR) || math.IsInf(t.FareEUR, 0) {
+			return nil, fmt.Errorf("trip %d: fare_eur must be a non-negative number", i)
+		}
+		trips = append(trips, TimeInterval{Start: t.Start, End: t.End})
+		if !t.End.Before(window.Start) && t.End.Before(window.End) {
+			earned += t.FareEUR
+		}
+	}
+
+	online := unionDuration(req.OnlineSessions, window)
+	active := unionDuration(trips, window)
+	idle := online - active
+	if idle < 0 {
+		idle = 0
+	}
+
+	counted := active
+	if cfg.CountIdleTime {
+		counted = online
+	}
+
+	guaranteed := counted.Hours() * cfg.MinimumHourlyEUR
+	topUp := math.Max(0, guaranteed-earned)
+
+	return &EarningsGuaranteeResponse{
+		DriverID:         req.DriverID,
+		WindowStart:      req.WindowStart.UTC(),
+		WindowEnd:        req.WindowEnd.UTC(),
+		OnlineHours:      roundHours(online),
+		ActiveHours:      roundHours(active),
+		IdleHours:        roundHours(idle),
+		CountedHours:     roundHours(counted),
+		EarnedEUR:        math.Round(earned*100) / 100,
+		MinimumHourlyEUR: cfg.MinimumHourlyEUR,
+		GuaranteedEUR:    math.Round(guaranteed*100) / 100,
+		TopUpEUR:         math.Round(topUp*100) / 100,
+		CountIdleTime:    cfg.CountIdleTime,
+	}, nil
+}
+
+// unionDuration returns the total time covered by intervals within window,
+// counting overlaps once
+func unionDuration(intervals []TimeInterval, window TimeInterval) time.Duration {
+	clipped := make([]TimeInterval, 0, len(intervals))
+	for _, iv := range intervals {
+		if iv.Start.Before(window.Start) {
+			iv.Start = window.Start
+		}
+		if iv.End.After(window.End) {
+			iv.End = window.End
+		}
+		if iv.End.After(iv.Start) {
+			clipped = append(clipped, iv)
+		}
+	}
+	sort.Slice(clipped, func(i, j int) bool { return clipped[i].Start.Before(clipped[j].Start) })
+
+	var total time.Duration
+	var current *TimeInterval
+	for i := range clipped {
+		iv := clipped[i]
+		if current != nil && !iv.Start.After(current.End) {
+			if iv.End.After(current.End) {
+				current.End = iv.End
+			}
+			continue
+		}
+		if current != nil {
+			total += current.End.Sub(current.Start)
+		}
+		current = &iv
+	}
+	if current != nil {
+		total += current.End.Sub(current.Start)
+	}
+	return total
+}
+
+func roundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var guaranteeDay = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func at(hour, minute int) time.Time {
+	return guaranteeDay.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func TestEarningsGuaranteeTopUp(t *testing.T) {
+	req := EarningsGuaranteeRequest{
+		DriverID:    "driver-1",
+		WindowStart: guaranteeDay,
+		WindowEnd:   guaranteeDay.Add(24 * time.Hour),
+		// 8h online, overlapping sessions are counted once
+		OnlineSessions: []TimeInterval{{at(8, 0), at(12, 0)}, {at(11, 0), at(16, 0)}},
+		Trips: []GuaranteeTrip{
+			{Start: at(9, 0), End: at(10, 0), FareEUR: 30},
+			{Start: at(13, 0), End: at(14, 0), FareEUR: 25},
+		},
+	}
+	cfg := EarningsGuaranteeConfig{MinimumHourlyEUR: 12.50, CountIdleTime: true}
+
+	resp, err := computeEarningsGuarantee(req, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OnlineHours != 8 || resp.ActiveHours != 2 || resp.IdleHours != 6 {
+		t.Errorf("hours = online %.2f / active %.2f / idle %.2f, want 8/2/6", resp.OnlineHours, resp.ActiveHours, resp.IdleHours)
+	}
+	// 8h * 12.50 = 100.00 guaranteed, 55.00 earned
+	if resp.GuaranteedEUR != 100 || resp.EarnedEUR != 55 || resp.TopUpEUR != 45 {
+		t.Errorf("guaranteed %.2f earned %.2f top-up %.2f, want 100/55/45", resp.GuaranteedEUR, resp.EarnedEUR, resp.TopUpEUR)
+	}
+
+	// Counting only active time: 2h * 12.50 = 25.00 < 55.00 earned
+	cfg.CountIdleTime = false
+	resp, err = computeEarningsGuarantee(req, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.CountedHours != 2 || resp.TopUpEUR != 0 {
+		t.Errorf("active-only: counted %.2f top-up %.2f, want 2/0", resp.CountedHours, resp.TopUpEUR)
+	}
+}
+
+func TestEarningsGuaranteeClipsToWindow(t *testing.T) {
+	req := EarningsGuaranteeRequest{
+		DriverID:       "driver-1",
+		WindowStart:    at(10, 0),
+		WindowEnd:      at(12, 0),
+		OnlineSessions: []TimeInterval{{at(9, 0), at(11, 0)}},
+		Trips: []GuaranteeTrip{
+			// Ended inside the window: fare counts.
+			{Start: at(9, 30), End: at(10, 30), FareEUR: 20},
+			// Ended after the window: fare belongs to the next window.
+			{Start: at(11, 30), End: at(12, 30), FareEUR: 40},
+		},
+	}
+
+	resp, err := computeEarningsGuarantee(req, EarningsGuaranteeConfig{MinimumHourlyEUR: 12, CountIdleTime: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OnlineHours != 1 || resp.EarnedEUR != 20 {
+		t.Errorf("online %.2f earned %.2f, want 1/20", resp.OnlineHours, resp.EarnedEUR)
+	}
+}
+
+func TestEarningsGuaranteeValidation(t *testing.T) {
+	base := EarningsGuaranteeRequest{DriverID: "driver-1", WindowStart: at(0, 0), WindowEnd: at(8, 0)}
+	cfg := defaultEarningsGuaranteeConfig()
+
+	cases := map[string]func(r *EarningsGuaranteeRequest){
+		"missing driver": func(r *EarningsGuaranteeRequest) { r.DriverID = "" },
+		"empty window":   func(r *EarningsGuaranteeRequest) { r.WindowEnd = r.WindowStart },
+		"window too long": func(r *EarningsGuaranteeRequest) {
+			r.WindowEnd = r.WindowStart.Add(MaxGuaranteeWindow + time.Hour)
+		},
+		"inverted session": func(r *EarningsGuaranteeRequest) {
+			r.OnlineSessions = []TimeInterval{{at(5, 0), at(4, 0)}}
+		},
+		"negative fare": func(r *EarningsGuaranteeRequest) {
+			r.Trips = []GuaranteeTrip{{Start: at(1, 0), End: at(2, 0), FareEUR: -5}}
+		},
+	}
+	for name, mutate := range cases {
+		req := base
+		mutate(&req)
+		if _, err := computeEarningsGuarantee(req, cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestHandleEarningsGuarantee(t *testing.T) {
+	body := `{
+		"driver_id": "driver-1",
+		"window_start": "2024-05-01T00:00:00Z",
+		"window_end": "2024-05-02T00:00:00Z",
+		"online_sessions": [{"start": "2024-05-01T08:00:00Z", "end": "2024-05-01T10:00:00Z"}],
+		"trips": []
+	}`
+	rec := httptest.NewRecorder()
+	handleEarningsGuarantee(rec, httptest.NewRequest(http.MethodPost, "/earnings/guarantee", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handleEarningsGuarantee(rec, httptest.NewRequest(http.MethodGet, "/earnings/guarantee", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+}
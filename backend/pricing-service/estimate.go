@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+)
+
+const (
+	// DefaultEstimateUncertaintyPct is the spread applied to distance and
+	// time when GET /price/estimate gets no uncertainty_pct
+	DefaultEstimateUncertaintyPct = 15.0
+
+	// MaxEstimateUncertaintyPct keeps the low end of the range above zero
+	MaxEstimateUncertaintyPct = 90.0
+)
+
+// PriceEstimateResponse is a fare range for a ride whose actual distance and
+// duration are not yet known
+type PriceEstimateResponse struct {
+	MinPrice        float64 `json:"min_price"`
+	MaxPrice        float64 `json:"max_price"`
+	Currency        string  `json:"currency"`
+	UncertaintyPct  float64 `json:"uncertainty_pct"`
+	SurgeMultiplier float64 `json:"surge_multiplier"`
+	NightMultiplier float64 `json:"night_multiplier,omitempty"`
+	FixedRoute      bool    `json:"fixed_route,omitempty"`
+	Compliance
+}
+
+// handlePriceEstimate returns a fare range for GET /price/estimate. It takes
+// the /price query parameters plus uncertainty_pct. Distance and duration are
+// scaled down and up by that percentage and each bound is priced like a
+// quote: the current surge multiplier applies to both bounds, and the minimum
+// fare and per-km floor (PBefG §39, §51) are enforced on each, so the low end
+// never falls below the minimum fare. Estimates carry no quote token and
+// cannot be booked.
+func handlePriceEstimate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := parsePriceRequest(r)
+	if err != nil {
+		responseError(w, err.Error(), "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	if err := validatePriceRequest(req); err != nil {
+		responseError(w, err.Error(), "VALIDATION_ERROR", http.StatusBadRequest)
+		return
+	}
+
+	uncertainty := DefaultEstimateUncertaintyPct
+	if v := r.URL.Query().Get("uncertainty_pct"); v != "" {
+		uncertainty, err = parseDecimal(v, r.URL.Query().Get("locale"))
+		if err != nil || math.IsNaN(uncertainty) || uncertainty < 0 || uncertainty > MaxEstimateUncertaintyPct {
+			responseError(w, fmt.Sprintf("uncertainty_pct must be a number between 0 and %.0f", MaxEstimateUncertaintyPct), "VALIDATION_ERROR", http.StatusBadRequest)
+			return
+		}
+	}
+
+	low, high := *req, *req
+	spread := uncertainty / 100
+	low.DistanceKm, low.DurationMin = req.DistanceKm*(1-spread), req.DurationMin*(1-spread)
+	high.DistanceKm, high.DurationMin = req.DistanceKm*(1+spread), req.DurationMin*(1+spread)
+
+	minResp, err := calculatePrice(&low)
+	if err != nil {
+		logger.Error("Price estimate calculation error", "error", err)
+		responseError(w, "Failed to calculate price", "CALCULATION_ERROR", http.StatusInternalServerError)
+		return
+	}
+	maxResp, err := calculatePrice(&high)
+	if err != nil {
+		logger.Error("Price estimate calculation error", "error", err)
+		responseError(w, "Failed to calculate price", "CALCULATION_ERROR", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Price estimated",
+		"distance_km", req.DistanceKm,
+		"duration_min", req.DurationMin,
+		"uncertainty_pct", uncertainty,
+		"min_price", minResp.FinalPrice,
+		"max_price", maxResp.FinalPrice,
+	)
+
+	resp := PriceEstimateResponse{
+		MinPrice:        minResp.FinalPrice,
+		MaxPrice:        math.Max(minResp.FinalPrice, maxResp.FinalPrice),
+		Currency:        minResp.Currency,
+		UncertaintyPct:  uncertainty,
+		SurgeMultiplier: maxResp.SurgeMultiplier,
+		NightMultiplier: maxResp.NightMultiplier,
+		FixedRoute:      maxResp.FixedRoute,
+		Compliance:      minResp.Compliance,
+	}
+	resp.localize(responseLanguage(w, r))
+	responseJSON(w, resp, http.StatusOK)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getEstimate(t *testing.T, query string) (*httptest.ResponseRecorder, PriceEstimateResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handlePriceEstimate(rec, httptest.NewRequest(http.MethodGet, "/price/estimate?"+query, nil))
+	var resp PriceEstimateResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode estimate: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func TestPriceEstimateRange(t *testing.T) {
+	rec, resp := getEstimate(t, "distance_km=10&duration_min=20")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// 3.50 base + (10km * 1.80 + 20min * 0.35) * (1 ± 0.15)
+	if resp.MinPrice != 24.75 || resp.MaxPrice != 32.25 {
+		t.Errorf("range = %.2f-%.2f, want 24.75-32.25", resp.MinPrice, resp.MaxPrice)
+	}
+	if resp.UncertaintyPct != DefaultEstimateUncertaintyPct || resp.SurgeMultiplier != 1.0 {
+		t.Errorf("unexpected estimate %+v", resp)
+	}
+
+	_, surged := getEstimate(t, "distance_km=10&duration_min=20&demand=20&supply=10&uncertainty_pct=0")
+	if surged.MinPrice != surged.MaxPrice || surged.SurgeMultiplier != 1.5 || surged.MinPrice != 42.75 {
+		t.Errorf("surge not applied to both bounds: %+v", surged)
+	}
+}
+
+func TestPriceEstimateMinimumFare(t *testing.T) {
+	_, resp := getEstimate(t, "distance_km=0.5&duration_min=2&uncertainty_pct=50")
+	if resp.MinPrice != MinimumFareEUR {
+		t.Errorf("min_price = %.2f, want the minimum fare %.2f", resp.MinPrice, MinimumFareEUR)
+	}
+	if resp.MaxPrice < resp.MinPrice {
+		t.Errorf("max_price %.2f below min_price %.2f", resp.MaxPrice, resp.MinPrice)
+	}
+}
+
+func TestPriceEstimateValidation(t *testing.T) {
+	for _, query := range []string{
+		"distance_km=10&duration_min=20&uncertainty_pct=-1",
+		"distance_km=10&duration_min=20&uncertainty_pct=95",
+		"distance_km=10&duration_min=20&uncertainty_pct=abc",
+		"distance_km=10&duration_min=20&uncertainty_pct=NaN",
+		"distance_km=10&duration_min=20&uncertainty_pct=Inf",
+		"distance_km=10&duration_min=20&uncertainty_pct=NaN&locale=de",
+		"duration_min=20",
+	} {
+		if rec, _ := getEstimate(t, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FixedRoute is a guaranteed flat fare between two zones, e.g. Berlin Hbf to
+// BER airport.
+//
+// Ties with surge: a fixed route always wins. When a price request names a
+// registered zone pair the flat fare replaces the whole metered calculation,
+// so surge, the night tariff, waiting time and pickup zone surcharges are
+// not applied. Only the PBefG minimum fare is still enforced.
+type FixedRoute struct {
+	PickupZone  string  `json:"pickup_zone"`
+	DropoffZone string  `json:"dropoff_zone"`
+	FareEUR     float64 `json:"fare_eur"`
+}
+
+type zonePair struct {
+	pickup, dropoff string
+}
+
+// FixedRouteStore holds fixed routes keyed by (pickup zone, dropoff zone).
+// Routes are directional; the return trip needs its own entry.
+type FixedRouteStore struct {
+	mu     sync.RWMutex
+	routes map[zonePair]FixedRoute
+}
+
+func NewFixedRouteStore() *FixedRouteStore {
+	return &FixedRouteStore{routes: make(map[zonePair]FixedRoute)}
+}
+
+var fixedRoutes = NewFixedRouteStore()
+
+func normalizeZone(zone string) string {
+	return strings.ToUpper(strings.TrimSpace(zone))
+}
+
+// Set registers or replaces the fixed route for its zone pair
+func (s *FixedRouteStore) Set(route FixedRoute) FixedRoute {
+	route.PickupZone = normalizeZone(route.PickupZone)
+	route.DropoffZone = normalizeZone(route.DropoffZone)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[zonePair{route.PickupZone, route.DropoffZone}] = route
+	return route
+}
+
+// Lookup returns the fixed route from pickupZone to dropoffZone
+func (s *FixedRouteStore) Lookup(pickupZone, dropoffZone string) (FixedRoute, bool) {
+	if pickupZone == "" || dropoffZone == "" {
+		return FixedRoute{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	route, ok := s.routes[zonePair{normalizeZone(pickupZone), normalizeZone(dropoffZone)}]
+	return route, ok
+}
+
+// List returns all fixed routes ordered by zone pair
+func (s *FixedRouteStore) List() []FixedRoute {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	routes := make([]FixedRoute, 0, len(s.routes))
+	for _, route := range s.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].PickupZone != routes[j].PickupZone {
+			return routes[i].PickupZone < routes[j].PickupZone
+		}
+		return routes[i].DropoffZone < routes[j].DropoffZone
+	})
+	return routes
+}
+
+// fixedRoutePrice prices a request on a fixed route
+func fixedRoutePrice(req *PriceRequest, route FixedRoute, cfg PricingConfig) *PriceResponse {
+	fare := math.Round(route.FareEUR*100) / 100
+	var compliance Compliance
+	compliance.addCompliance(ComplianceFixedTariff, route.PickupZone, route.DropoffZone)
+	finalPrice := fare
+	if finalPrice < cfg.MinimumFareEUR {
+		logger.Info("Minimum fare enforced on fixed route",
+			"fixed_fare", fare,
+			"minimum_fare", cfg.MinimumFareEUR,
+		)
+		finalPrice = cfg.MinimumFareEUR
+		compliance.addCompliance(ComplianceMinimumFare)
+	}
+
+	rate := vatRate(req.DistanceKm)
+	netPrice, vatAmount := splitVAT(finalPrice, rate)
+
+	return &PriceResponse{
+		SurgeMultiplier: 1.0,
+		Subtotal:        fare,
+		FinalPrice:      finalPrice,
+		NetPrice:        netPrice,
+		VATRate:         rate,
+		VATAmount:       vatAmount,
+		Currency:        "EUR",
+		Compliance:      compliance,
+		FixedRoute:      true,
+		TariffVersion:   cfg.tariffVersion(),
+	}
+}
+
+// handleFixedRoutes serves /fixed-routes: GET lists the routes, POST
+// registers {pickup_zone, dropoff_zone, fare_eur}. POST is an admin operation
+// and requires the X-User-Role: admin header set by the gateway.
+func handleFixedRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		responseJSON(w, fixedRoutes.List(), http.StatusOK)
+
+	case http.MethodPost:
+		if r.Header.Get("X-User-Role") != "admin" {
+			responseError(w, "Admin role required", "FORBIDDEN", http.StatusForbidden)
+			return
+		}
+
+		var route FixedRoute
+		if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+			responseError(w, "Invalid JSON body", "INVALID_REQUEST", http.StatusBadRequest)
+			return
+		}
+		if normalizeZone(route.PickupZone) == "" || normalizeZone(route.DropoffZone) == "" {
+			responseError(w, "pickup_zone and dropoff_zone are required", "VALIDATION_ERROR", http.StatusBadRequest)
+			return
+		}
+		if route.FareEUR <= 0 || math.IsNaN(route.FareEUR) || math.IsInf(route.FareEUR, 0) {
+			responseError(w, "fare_eur must be a positive number", "VALIDATION_ERROR", http.StatusBadRequest)
+			return
+		}
+
+		route = fixedRoutes.Set(route)
+		logger.Info("Fixed route registered",
+			"pickup_zone", route.PickupZone,
+			"dropoff_zone", route.DropoffZone,
+			"fare_eur", route.FareEUR,
+			"actor", r.Header.Get("X-User-ID"),
+		)
+		responseJSON(w, route, http.StatusCreated)
+
+	default:
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+	}
+}
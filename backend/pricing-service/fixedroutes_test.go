@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func useFixedRoutes(t *testing.T, routes ...FixedRoute) {
+	t.Helper()
+	prev := fixedRoutes
+	fixedRoutes = NewFixedRouteStore()
+	for _, route := range routes {
+		fixedRoutes.Set(route)
+	}
+	t.Cleanup(func() { fixedRoutes = prev })
+}
+
+func TestFixedRouteBypassesSurge(t *testing.T) {
+	useFixedRoutes(t, FixedRoute{PickupZone: "BER-HBF", DropoffZone: "BER-AIRPORT", FareEUR: 45})
+
+	// Maximum surge would otherwise apply
+	req := &PriceRequest{DistanceKm: 27, DurationMin: 35, Demand: 30, Supply: 10, PickupZone: "ber-hbf", DropoffZone: "BER-AIRPORT"}
+	resp, err := calculatePrice(req)
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if !resp.FixedRoute || resp.FinalPrice != 45 || resp.SurgeMultiplier != 1.0 {
+		t.Errorf("unexpected fixed route price %+v", resp)
+	}
+	if resp.ComplianceCode != ComplianceFixedTariff || !strings.Contains(resp.ComplianceNote, "Festpreis") {
+		t.Errorf("compliance %q (%s) does not mention the fixed tariff", resp.ComplianceNote, resp.ComplianceCode)
+	}
+	if resp.NetPrice+resp.VATAmount != resp.FinalPrice {
+		t.Errorf("net %.2f + vat %.2f != gross %.2f", resp.NetPrice, resp.VATAmount, resp.FinalPrice)
+	}
+
+	// Routes are directional
+	reverse := *req
+	reverse.PickupZone, reverse.DropoffZone = req.DropoffZone, req.PickupZone
+	metered, err := calculatePrice(&reverse)
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if metered.FixedRoute {
+		t.Error("reverse direction must use the metered tariff")
+	}
+}
+
+func TestFixedRouteEnforcesMinimumFare(t *testing.T) {
+	useFixedRoutes(t, FixedRoute{PickupZone: "A", DropoffZone: "B", FareEUR: 2})
+
+	resp, err := calculatePrice(&PriceRequest{DistanceKm: 1, DurationMin: 3, PickupZone: "A", DropoffZone: "B"})
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if resp.FinalPrice != MinimumFareEUR {
+		t.Errorf("final price = %.2f, want minimum fare %.2f", resp.FinalPrice, MinimumFareEUR)
+	}
+}
+
+func TestHandleFixedRoutesRequiresAdmin(t *testing.T) {
+	useFixedRoutes(t)
+
+	post := func(role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/fixed-routes", strings.NewReader(body))
+		if role != "" {
+			req.Header.Set("X-User-Role", role)
+		}
+		rec := httptest.NewRecorder()
+		handleFixedRoutes(rec, req)
+		return rec
+	}
+
+	body := `{"pickup_zone": "BER-HBF", "dropoff_zone": "BER-AIRPORT", "fare_eur": 45}`
+	if rec := post("", body); rec.Code != http.StatusForbidden {
+		t.Errorf("without role: status = %d, want 403", rec.Code)
+	}
+	if rec := post("admin", `{"pickup_zone": "BER-HBF", "dropoff_zone": "", "fare_eur": 45}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing zone: status = %d, want 400", rec.Code)
+	}
+	if rec := post("admin", body); rec.Code != http.StatusCreated {
+		t.Fatalf("admin: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	handleFixedRoutes(rec, httptest.NewRequest(http.MethodGet, "/fixed-routes", nil))
+	var routes []FixedRoute
+	if err := json.NewDecoder(rec.Body).Decode(&routes); err != nil {
+		t.Fatalf("failed to decode routes: %v", err)
+	}
+	if len(routes) != 1 || routes[0].FareEUR != 45 {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+}
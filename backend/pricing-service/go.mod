@@ -1,3 +1,7 @@
 module github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pricing-service
 
 go 1.21
+
+require github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
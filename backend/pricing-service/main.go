@@ -5,14 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"math"
+	"mime"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
 	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/quotetoken"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 )
 
 // German PBefG (Personenbeförderungsgesetz) compliance constants
@@ -34,17 +40,33 @@ const (
 	// PricePerMinuteEUR is the cost per minute of ride time
 	PricePerMinuteEUR = 0.35
 
+	// PricePerWaitMinuteEUR is the cost per minute of standstill at pickup
+	PricePerWaitMinuteEUR = 0.30
+
+	// MaxWaitingMin caps the chargeable waiting time at pickup
+	MaxWaitingMin = 120
+
 	// MinPricePerKmEUR ensures compliance with §39 PBefG regarding minimum cost coverage
 	// Price per km cannot effectively fall below this after surge is applied
 	MinPricePerKmEUR = 1.50
+
+	// NightMultiplier raises the distance and time components for pickups
+	// between 22:00 and 06:00 (Nachttarif). Combined with surge it stays
+	// within MaxSurgeMultiplier.
+	NightMultiplier = 1.15
 )
 
 // PriceRequest represents the incoming pricing calculation request
 type PriceRequest struct {
 	DistanceKm float64 `json:"distance_km"`
 	DurationMin float64 `json:"duration_min"`
+	WaitingMin float64 `json:"waiting_min"` // Optional standstill time at pickup
 	Demand int `json:"demand"` // Current demand in area (e.g., active ride requests)
 	Supply int `json:"supply"` // Current supply in area (e.g., available drivers)
+	PickupTime *time.Time `json:"pickup_time,omitempty"` // Optional RFC3339 pickup time; selects the night tariff
+	PickupZone string `json:"pickup_zone,omitempty"` // Optional zone identifiers; select a fixed route or a pickup zone surcharge
+	DropoffZone string `json:"dropoff_zone,omitempty"`
+	PromoCode string `json:"promo_code,omitempty"` // Optional promo code; discounts the final price
 }
 
 // PriceResponse represents the pricing calculation response
@@ -52,11 +74,24 @@ type PriceResponse struct {
 	BasePrice float64 `json:"base_price"`
 	DistancePrice float64 `json:"distance_price"`
 	TimePrice float64 `json:"time_price"`
+	WaitingPrice float64 `json:"waiting_price"`
+	ZoneSurcharge float64 `json:"zone_surcharge,omitempty"` // Flat pickup zone surcharge, before surge
 	SurgeMultiplier float64 `json:"surge_multiplier"`
+	NightMultiplier float64 `json:"night_multiplier,omitempty"` // Set when the night tariff was applied
 	Subtotal float64 `json:"subtotal"`
-	FinalPrice float64 `json:"final_price"`
+	FinalPrice float64 `json:"final_price"` // Gross amount including VAT
+	NetPrice float64 `json:"net_price"`
+	VATRate float64 `json:"vat_rate"`
+	VATAmount float64 `json:"vat_amount"`
 	Currency string `json:"currency"`
-	ComplianceNote string `json:"compliance_note,omitempty"`
+	Compliance
+	TariffVersion TariffVersion `json:"tariff_version"` // Tariff the quote was priced with
+	FixedRoute bool `json:"fixed_route,omitempty"` // Set when a fixed-route flat fare was used
+	PromoCode string `json:"promo_code,omitempty"` // Set when a promo discount was applied
+	DiscountEUR float64 `json:"discount_eur,omitempty"`
+	UndiscountedPrice float64 `json:"undiscounted_price,omitempty"` // Final price before the promo discount
+	QuoteToken string `json:"quote_token,omitempty"` // Signed confirmation token required to book this quote
+	QuoteExpiresAt string `json:"quote_expires_at,omitempty"`
 }
 
 // ErrorResponse represents an error response
@@ -65,6 +100,13 @@ type ErrorResponse struct {
 	Code string `json:"code"`
 }
 
+// PromoErrorResponse rejects a promo code and carries the undiscounted quote,
+// which can still be booked, so the client can show both
+type PromoErrorResponse struct {
+	ErrorResponse
+	Price *PriceResponse `json:"price"`
+}
+
 // HealthResponse represents health check response
 type HealthResponse struct {
 	Status string `json:"status"`
@@ -72,22 +114,72 @@ type HealthResponse struct {
 	Service string `json:"service"`
 }
 
-var logger *slog.Logger
+var (
+	logger *slog.Logger
+	quoteSigner *quotetoken.Signer
+)
 
 func init() {
 	// Initialize structured logger
 	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
+		ReplaceAttr: timeutil.SlogReplaceAttr,
 	}))
 	slog.SetDefault(logger)
+
+	// Quote tokens are verified by the ride-service with the same secret
+	signer, usedDevSecret, err := quotetoken.NewSignerFromEnv()
+	if err != nil {
+		logger.Error("Invalid quote token configuration", "error", err)
+		os.Exit(1)
+	}
+	if usedDevSecret {
+		logger.Warn("Using development quote token secret. Set QUOTE_TOKEN_SECRET in production.")
+	}
+	quoteSigner = signer
+
+	guaranteeConfig, err = loadEarningsGuaranteeConfig()
+	if err != nil {
+		logger.Error("Invalid earnings guarantee configuration", "error", err)
+		os.Exit(1)
+	}
+
+	poolSharePct, err = loadPoolSharePct()
+	if err != nil {
+		logger.Error("Invalid pool pricing configuration", "error", err)
+		os.Exit(1)
+	}
+
+	zoneSurcharges, err = loadZoneSurcharges()
+	if err != nil {
+		logger.Error("Invalid zone surcharge configuration", "error", err)
+		os.Exit(1)
+	}
+
+	activeConfig, err = loadPricingConfig()
+	if err != nil {
+		logger.Error("Invalid tariff configuration", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Tariff loaded", "tariff_version", activeConfig.Version, "minimum_fare_eur", activeConfig.MinimumFareEUR, "min_price_per_km_eur", activeConfig.MinPricePerKmEUR)
 }
 
 func main() {
-	logger.Info("Starting pricing-service", "version", "1.0.0")
+	logger.Info("Starting pricing-service", "version", buildinfo.Version)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/price", handlePrice)
+	mux.HandleFunc("/price/batch", handlePriceBatch)
+	mux.HandleFunc("/price/estimate", handlePriceEstimate)
+	mux.HandleFunc("/price/pool", handlePoolPrice)
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/version", buildinfo.Handler("pricing-service"))
+	mux.HandleFunc("/config/preview", handleConfigPreview)
+	mux.HandleFunc("/earnings/guarantee", handleEarningsGuarantee)
+	mux.HandleFunc("/fixed-routes", handleFixedRoutes)
+	mux.HandleFunc("/cancellation-fee", handleCancellationFee)
+	mux.HandleFunc("/surge-log", handleSurgeLog)
+	mux.HandleFunc("/promos", handlePromos)
 
 	// Wrap mux with logging middleware
 	handler := loggingMiddleware(mux)
@@ -155,67 +247,122 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 
 	response := HealthResponse{
 		Status: "healthy",
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Timestamp: timeutil.Format(timeutil.Now()),
 		Service: "pricing-service",
 	}
 
 	responseJSON(w, response, http.StatusOK)
 }
 
-// handlePrice calculates the ride price based on distance, time, and surge
+// handlePrice calculates the ride price based on distance, time, and surge.
+// GET reads query parameters; POST reads a JSON PriceRequest body.
 func handlePrice(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	var req *PriceRequest
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		req, err = parsePriceRequest(r)
+	case http.MethodPost:
+		req, err = decodePriceRequest(w, r)
+	default:
 		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
 		return
 	}
-
-	// Parse query parameters
-	req, err := parsePriceRequest(r)
 	if err != nil {
-		logger.Warn("Invalid price request", "error", err)
-		responseError(w, err.Error(), "INVALID_REQUEST", http.StatusBadRequest)
+		logger.Warn("Invalid price request", "method", r.Method, "error", err)
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedContentType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		responseError(w, err.Error(), "INVALID_REQUEST", status)
 		return
 	}
 
+	resp, errResp, status := quotePrice(req)
+	if resp != nil {
+		resp.localize(responseLanguage(w, r))
+	}
+	if errResp != nil && resp != nil {
+		// A rejected promo code still returns the undiscounted quote
+		responseJSON(w, PromoErrorResponse{ErrorResponse: *errResp, Price: resp}, status)
+		return
+	}
+	if errResp != nil {
+		responseError(w, errResp.Error, errResp.Code, status)
+		return
+	}
+
+	responseJSON(w, resp, http.StatusOK)
+}
+
+// quotePrice validates, prices and signs a single request. On failure it
+// returns the error response and HTTP status to report. If only the promo
+// code is rejected, the undiscounted quote is returned with the error.
+func quotePrice(req *PriceRequest) (*PriceResponse, *ErrorResponse, int) {
 	// Validate request
 	if err := validatePriceRequest(req); err != nil {
 		logger.Warn("Price request validation failed", "error", err)
-		responseError(w, err.Error(), "VALIDATION_ERROR", http.StatusBadRequest)
-		return
+		return nil, &ErrorResponse{Error: err.Error(), Code: "VALIDATION_ERROR"}, http.StatusBadRequest
 	}
 
 	// Calculate price
 	resp, err := calculatePrice(req)
 	if err != nil {
 		logger.Error("Price calculation error", "error", err)
-		responseError(w, "Failed to calculate price", "CALCULATION_ERROR", http.StatusInternalServerError)
-		return
+		return nil, &ErrorResponse{Error: "Failed to calculate price", Code: "CALCULATION_ERROR"}, http.StatusInternalServerError
+	}
+
+	var promoErr *ErrorResponse
+	if req.PromoCode != "" {
+		promoErr = applyPromo(req.PromoCode, resp, activeConfig, timeutil.Now())
 	}
 
+	if err := attachQuoteToken(req, resp); err != nil {
+		logger.Error("Quote token error", "error", err)
+		return nil, &ErrorResponse{Error: "Failed to issue quote token", Code: "CALCULATION_ERROR"}, http.StatusInternalServerError
+	}
+
+	recordSurge(req, resp)
+
 	logger.Info("Price calculated",
 		"distance_km", req.DistanceKm,
 		"duration_min", req.DurationMin,
 		"surge_multiplier", resp.SurgeMultiplier,
 		"final_price", resp.FinalPrice,
+		"promo_code", resp.PromoCode,
 	)
 
-	responseJSON(w, resp, http.StatusOK)
+	if promoErr != nil {
+		logger.Warn("Promo code rejected", "promo_code", req.PromoCode, "code", promoErr.Code)
+		return resp, promoErr, http.StatusUnprocessableEntity
+	}
+	return resp, nil, http.StatusOK
 }
 
-// parsePriceRequest extracts pricing parameters from query string
+// parsePriceRequest extracts pricing parameters from query string. An
+// optional locale parameter (e.g. locale=de) allows comma decimals.
 func parsePriceRequest(r *http.Request) (*PriceRequest, error) {
 	query := r.URL.Query()
+	locale := query.Get("locale")
 
-	distance, err := strconv.ParseFloat(query.Get("distance_km"), 64)
+	distance, err := parseDecimal(query.Get("distance_km"), locale)
 	if err != nil {
 		return nil, fmt.Errorf("invalid distance_km parameter: %w", err)
 	}
 
-	duration, err := strconv.ParseFloat(query.Get("duration_min"), 64)
+	duration, err := parseDecimal(query.Get("duration_min"), locale)
 	if err != nil {
 		return nil, fmt.Errorf("invalid duration_min parameter: %w", err)
 	}
 
+	waiting := 0.0
+	if v := query.Get("waiting_min"); v != "" {
+		waiting, err = parseDecimal(v, locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid waiting_min parameter: %w", err)
+		}
+	}
+
 	demand, err := strconv.Atoi(query.Get("demand"))
 	if err != nil {
 		demand = 10 // Default demand
@@ -226,16 +373,65 @@ func parsePriceRequest(r *http.Request) (*PriceRequest, error) {
 		supply = 10 // Default supply
 	}
 
-	return &PriceRequest{
+	req := &PriceRequest{
 		DistanceKm: distance,
 		DurationMin: duration,
+		WaitingMin: waiting,
 		Demand: demand,
 		Supply: supply,
-	}, nil
+		PickupZone: query.Get("pickup_zone"),
+		DropoffZone: query.Get("dropoff_zone"),
+		PromoCode: query.Get("promo_code"),
+	}
+
+	if v := query.Get("pickup_time"); v != "" {
+		pickup, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pickup_time parameter: must be RFC3339")
+		}
+		req.PickupTime = &pickup
+	}
+
+	return req, nil
+}
+
+// maxPriceRequestBytes bounds the size of a POST /price body
+const maxPriceRequestBytes = 1 << 20
+
+var errUnsupportedContentType = errors.New("content type must be application/json")
+
+// requireJSON rejects requests whose Content-Type is not application/json
+func requireJSON(r *http.Request) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return errUnsupportedContentType
+	}
+	return nil
+}
+
+// decodePriceRequest reads a JSON PriceRequest body. Demand and supply
+// default to 10 when omitted, as for GET.
+func decodePriceRequest(w http.ResponseWriter, r *http.Request) (*PriceRequest, error) {
+	if err := requireJSON(r); err != nil {
+		return nil, err
+	}
+
+	req := &PriceRequest{Demand: 10, Supply: 10}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPriceRequestBytes)).Decode(req); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("request body is required")
+		}
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return req, nil
 }
 
 // validatePriceRequest ensures request parameters are valid
 func validatePriceRequest(req *PriceRequest) error {
+	if math.IsNaN(req.DistanceKm) || math.IsInf(req.DistanceKm, 0) {
+		return errors.New("distance_km must be a finite number")
+	}
+
 	if req.DistanceKm <= 0 {
 		return errors.New("distance_km must be greater than 0")
 	}
@@ -244,6 +440,10 @@ func validatePriceRequest(req *PriceRequest) error {
 		return errors.New("distance_km exceeds maximum allowed (500km)")
 	}
 
+	if math.IsNaN(req.DurationMin) || math.IsInf(req.DurationMin, 0) {
+		return errors.New("duration_min must be a finite number")
+	}
+
 	if req.DurationMin <= 0 {
 		return errors.New("duration_min must be greater than 0")
 	}
@@ -252,6 +452,18 @@ func validatePriceRequest(req *PriceRequest) error {
 		return errors.New("duration_min exceeds maximum allowed (600min)")
 	}
 
+	if math.IsNaN(req.WaitingMin) || math.IsInf(req.WaitingMin, 0) {
+		return errors.New("waiting_min must be a finite number")
+	}
+
+	if req.WaitingMin < 0 {
+		return errors.New("waiting_min cannot be negative")
+	}
+
+	if req.WaitingMin > MaxWaitingMin {
+		return fmt.Errorf("waiting_min exceeds maximum allowed (%dmin)", MaxWaitingMin)
+	}
+
 	if req.Demand < 0 {
 		return errors.New("demand cannot be negative")
 	}
@@ -263,84 +475,155 @@ func validatePriceRequest(req *PriceRequest) error {
 	return nil
 }
 
-// calculatePrice computes the final price with PBefG compliance
+// calculatePrice computes the final price with PBefG compliance using the
+// active tariff. Requests on a registered fixed route get the flat fare.
 func calculatePrice(req *PriceRequest) (*PriceResponse, error) {
+	if route, ok := fixedRoutes.Lookup(req.PickupZone, req.DropoffZone); ok {
+		return fixedRoutePrice(req, route, activeConfig), nil
+	}
+	return calculatePriceWithConfig(req, activeConfig)
+}
+
+// calculatePriceWithConfig computes the final price with PBefG compliance using cfg
+func calculatePriceWithConfig(req *PriceRequest, cfg PricingConfig) (*PriceResponse, error) {
 	// Base price component
-	basePrice := BaseRateEUR
+	basePrice := cfg.BaseRateEUR
 
 	// Distance-based price component
-	distancePrice := req.DistanceKm * PricePerKmEUR
+	distancePrice := req.DistanceKm * cfg.PricePerKmEUR
 
 	// Time-based price component
-	timePrice := req.DurationMin * PricePerMinuteEUR
+	timePrice := req.DurationMin * cfg.PricePerMinuteEUR
+
+	// Waiting-time price component (standstill at pickup)
+	waitingPrice := req.WaitingMin * cfg.PricePerWaitMinuteEUR
+
+	// Flat surcharge for regulated pickup zones (airports, stadiums)
+	surchargeZone, zoneSurcharge, _ := zoneSurcharges.Lookup(req.PickupZone)
 
 	// Calculate surge multiplier based on demand/supply ratio
-	surgeMultiplier := calculateSurgeMultiplier(req.Demand, req.Supply)
+	surgeMultiplier := calculateSurgeMultiplier(req.Demand, req.Supply, cfg.MaxSurgeMultiplier)
+
+	// Night tariff raises the distance and time components. The combined
+	// night and surge multiplier may not exceed MaxSurgeMultiplier, so surge
+	// is reduced when both apply.
+	nightMultiplier := 0.0
+	if req.PickupTime != nil && isNightTime(*req.PickupTime) && cfg.NightMultiplier > 1.0 {
+		nightMultiplier = cfg.NightMultiplier
+		distancePrice *= nightMultiplier
+		timePrice *= nightMultiplier
+		if nightMultiplier*surgeMultiplier > cfg.MaxSurgeMultiplier {
+			surgeMultiplier = math.Floor(cfg.MaxSurgeMultiplier/nightMultiplier*100) / 100
+		}
+	}
 
 	// Calculate subtotal before surge
-	subtotal := basePrice + distancePrice + timePrice
+	subtotal := basePrice + distancePrice + timePrice + waitingPrice + zoneSurcharge
 
 	// Apply surge multiplier
 	finalPrice := subtotal * surgeMultiplier
+	surgedSurcharge := zoneSurcharge * surgeMultiplier
 
 	// PBefG Compliance checks and adjustments
-	complianceNote := ""
+	var compliance Compliance
 
 	// 1. Enforce minimum fare (PBefG §51 - prevents price dumping)
-	if finalPrice < MinimumFareEUR {
+	if finalPrice < cfg.MinimumFareEUR {
 		logger.Info("Minimum fare enforced",
 			"calculated_price", finalPrice,
-			"minimum_fare", MinimumFareEUR,
+			"minimum_fare", cfg.MinimumFareEUR,
 		)
-		finalPrice = MinimumFareEUR
-		complianceNote = "Price adjusted to minimum fare per PBefG §51"
+		finalPrice = cfg.MinimumFareEUR
+		compliance.addCompliance(ComplianceMinimumFare)
 	}
 
 	// 2. Ensure effective price per km meets minimum threshold (PBefG §39)
-	// This ensures operational costs are covered
-	effectivePricePerKm := (finalPrice - basePrice) / req.DistanceKm
-	if effectivePricePerKm < MinPricePerKmEUR && req.DistanceKm > 0 {
+	// This ensures operational costs are covered. The zone surcharge is not
+	// distance revenue: it is left out of the per-km rate and added to the
+	// adjusted price once, so it neither hides a shortfall nor gets charged
+	// on top of a price that already contains it.
+	effectivePricePerKm := (finalPrice - basePrice - surgedSurcharge) / req.DistanceKm
+	if effectivePricePerKm < cfg.MinPricePerKmEUR && req.DistanceKm > 0 {
 		// Adjust price to meet minimum per-km rate
-		requiredDistancePrice := req.DistanceKm * MinPricePerKmEUR
-		adjustedPrice := basePrice + requiredDistancePrice + timePrice
+		requiredDistancePrice := req.DistanceKm * cfg.MinPricePerKmEUR
+		adjustedPrice := basePrice + requiredDistancePrice + timePrice + waitingPrice + surgedSurcharge
 		if adjustedPrice > finalPrice {
 			logger.Info("Minimum per-km rate enforced",
 				"original_price", finalPrice,
 				"adjusted_price", adjustedPrice,
 			)
 			finalPrice = adjustedPrice
-			if complianceNote == "" {
-				complianceNote = "Price adjusted to minimum per-km rate per PBefG §39"
+			if len(compliance.notices) == 0 {
+				compliance.addCompliance(ComplianceMinimumPerKmRate)
 			}
 		}
 	}
 
+	if nightMultiplier > 0 {
+		compliance.addCompliance(ComplianceNightTariff)
+	}
+
+	if zoneSurcharge > 0 {
+		compliance.addCompliance(ComplianceZoneSurcharge, zoneSurcharge, surchargeZone)
+	}
+
 	// 3. Round to 2 decimal places (EUR cents)
 	finalPrice = math.Round(finalPrice*100) / 100
 	subtotal = math.Round(subtotal*100) / 100
 	distancePrice = math.Round(distancePrice*100) / 100
 	timePrice = math.Round(timePrice*100) / 100
+	waitingPrice = math.Round(waitingPrice*100) / 100
+
+	// 4. VAT breakdown of the gross fare for invoices (§ 12 UStG)
+	rate := vatRate(req.DistanceKm)
+	netPrice, vatAmount := splitVAT(finalPrice, rate)
 
 	return &PriceResponse{
 		BasePrice: basePrice,
 		DistancePrice: distancePrice,
 		TimePrice: timePrice,
+		WaitingPrice: waitingPrice,
+		ZoneSurcharge: zoneSurcharge,
 		SurgeMultiplier: surgeMultiplier,
+		NightMultiplier: nightMultiplier,
 		Subtotal: subtotal,
 		FinalPrice: finalPrice,
+		NetPrice: netPrice,
+		VATRate: rate,
+		VATAmount: vatAmount,
 		Currency: "EUR",
-		ComplianceNote: complianceNote,
+		Compliance: compliance,
+		TariffVersion: cfg.tariffVersion(),
 	}, nil
 }
 
+// attachQuoteToken signs the quoted fare and parameters so the ride-service
+// can bind a booking to exactly this quote
+func attachQuoteToken(req *PriceRequest, resp *PriceResponse) error {
+	token, quote, err := quoteSigner.Issue(quotetoken.Quote{
+		FareEUR: resp.FinalPrice,
+		Currency: resp.Currency,
+		DistanceKm: req.DistanceKm,
+		DurationMin: req.DurationMin,
+		SurgeMultiplier: resp.SurgeMultiplier,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp.QuoteToken = token
+	resp.QuoteExpiresAt = timeutil.Format(quote.ExpiresAt)
+	return nil
+}
+
 // calculateSurgeMultiplier computes surge pricing based on demand/supply
-// Capped at MaxSurgeMultiplier to comply with PBefG §39 (reasonable pricing)
-func calculateSurgeMultiplier(demand, supply int) float64 {
+// Capped at maxSurge (MaxSurgeMultiplier by default) to comply with PBefG §39 (reasonable pricing)
+func calculateSurgeMultiplier(demand, supply int, maxSurge float64) float64 {
 	// Avoid division by zero
 	if supply == 0 {
 		// High demand, no supply = maximum surge
 		logger.Warn("Zero supply detected, applying maximum surge")
-		return MaxSurgeMultiplier
+		return maxSurge
 	}
 
 	if demand == 0 {
@@ -359,14 +642,14 @@ func calculateSurgeMultiplier(demand, supply int) float64 {
 	if ratio <= 1.0 {
 		multiplier = 1.0
 	} else if ratio >= 3.0 {
-		multiplier = MaxSurgeMultiplier
+		multiplier = maxSurge
 	} else {
-		// Linear interpolation between 1.0 and MaxSurgeMultiplier
-		multiplier = 1.0 + ((ratio - 1.0) / 2.0) * (MaxSurgeMultiplier - 1.0)
+		// Linear interpolation between 1.0 and maxSurge
+		multiplier = 1.0 + ((ratio - 1.0) / 2.0) * (maxSurge - 1.0)
 	}
 
-	// Ensure we never exceed MaxSurgeMultiplier (PBefG §39 compliance)
-	multiplier = math.Min(multiplier, MaxSurgeMultiplier)
+	// Ensure we never exceed maxSurge (PBefG §39 compliance)
+	multiplier = math.Min(multiplier, maxSurge)
 
 	// Round to 2 decimal places
 	multiplier = math.Round(multiplier*100) / 100
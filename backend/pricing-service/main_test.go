@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlePriceIssuesQuoteToken(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?distance_km=10&duration_min=20", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp PriceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.QuoteToken == "" || resp.QuoteExpiresAt == "" {
+		t.Fatal("expected a quote token and expiry in the response")
+	}
+
+	quote, err := quoteSigner.Verify(resp.QuoteToken)
+	if err != nil {
+		t.Fatalf("issued token does not verify: %v", err)
+	}
+	if quote.FareEUR != resp.FinalPrice || quote.DistanceKm != 10 || quote.DurationMin != 20 {
+		t.Errorf("token quote %+v does not match response %+v", quote, resp)
+	}
+}
+
+func TestHandlePricePostMatchesGet(t *testing.T) {
+	get := httptest.NewRecorder()
+	handlePrice(get, httptest.NewRequest(http.MethodGet, "/price?distance_km=10&duration_min=20", nil))
+
+	req := httptest.NewRequest(http.MethodPost, "/price", strings.NewReader(`{"distance_km": 10, "duration_min": 20}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	post := httptest.NewRecorder()
+	handlePrice(post, req)
+
+	if post.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", post.Code, post.Body.String())
+	}
+
+	var getResp, postResp PriceResponse
+	if err := json.NewDecoder(get.Body).Decode(&getResp); err != nil {
+		t.Fatalf("failed to decode GET response: %v", err)
+	}
+	if err := json.NewDecoder(post.Body).Decode(&postResp); err != nil {
+		t.Fatalf("failed to decode POST response: %v", err)
+	}
+	if postResp.FinalPrice != getResp.FinalPrice || postResp.SurgeMultiplier != getResp.SurgeMultiplier {
+		t.Errorf("POST quote %+v differs from GET quote %+v", postResp, getResp)
+	}
+	if postResp.QuoteToken == "" {
+		t.Error("expected a quote token in the POST response")
+	}
+}
+
+func TestHandlePricePostRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"non-JSON content type", "text/plain", `{"distance_km": 10, "duration_min": 20}`, http.StatusUnsupportedMediaType, "INVALID_REQUEST"},
+		{"missing content type", "", `{"distance_km": 10, "duration_min": 20}`, http.StatusUnsupportedMediaType, "INVALID_REQUEST"},
+		{"empty body", "application/json", "", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"malformed JSON", "application/json", `{"distance_km":`, http.StatusBadRequest, "INVALID_REQUEST"},
+		{"fails validation", "application/json", `{"distance_km": 0, "duration_min": 20}`, http.StatusBadRequest, "VALIDATION_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/price", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handlePrice(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode error: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", resp.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCalculatePriceAddsWaitingTime(t *testing.T) {
+	base := &PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10}
+	waiting := *base
+	waiting.WaitingMin = 5
+
+	without, err := calculatePriceWithConfig(base, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	with, err := calculatePriceWithConfig(&waiting, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+
+	// 5 minutes at 0.30 EUR
+	if with.WaitingPrice != 1.50 || without.WaitingPrice != 0 {
+		t.Errorf("waiting price = %.2f / %.2f, want 1.50 / 0", with.WaitingPrice, without.WaitingPrice)
+	}
+	if with.Subtotal != without.Subtotal+1.50 || with.FinalPrice != without.FinalPrice+1.50 {
+		t.Errorf("waiting price not added to subtotal: %+v vs %+v", with, without)
+	}
+}
+
+func TestValidatePriceRequestWaitingTime(t *testing.T) {
+	for _, tt := range []struct {
+		waitingMin float64
+		wantErr    bool
+	}{
+		{0, false},
+		{MaxWaitingMin, false},
+		{MaxWaitingMin + 1, true},
+		{-1, true},
+		{math.NaN(), true},
+		{math.Inf(1), true},
+		{math.Inf(-1), true},
+	} {
+		err := validatePriceRequest(&PriceRequest{DistanceKm: 10, DurationMin: 20, WaitingMin: tt.waitingMin})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("waiting_min %v: err = %v, wantErr %t", tt.waitingMin, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePriceRequestRejectsNonFiniteNumbers(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		for field, req := range map[string]PriceRequest{
+			"distance_km":  {DistanceKm: v, DurationMin: 20},
+			"duration_min": {DistanceKm: 10, DurationMin: v},
+			"waiting_min":  {DistanceKm: 10, DurationMin: 20, WaitingMin: v},
+		} {
+			if err := validatePriceRequest(&req); err == nil {
+				t.Errorf("%s %v: no error", field, v)
+			}
+		}
+	}
+}
+
+func TestHandlePriceRejectsNonFiniteNumbers(t *testing.T) {
+	for _, v := range []string{"NaN", "Inf", "-Inf"} {
+		for _, query := range []string{
+			"distance_km=" + v + "&duration_min=20",
+			"distance_km=10&duration_min=" + v,
+			"distance_km=10&duration_min=20&waiting_min=" + v,
+			"distance_km=" + v + "&duration_min=20&locale=de",
+			"distance_km=10&duration_min=" + v + "&locale=de",
+		} {
+			rec := httptest.NewRecorder()
+			handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?"+query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want 400, body = %s", query, rec.Code, rec.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Night tariff window in Europe/Berlin local time: [22:00, 06:00)
+const (
+	NightStartHour = 22
+	NightEndHour   = 6
+)
+
+// isNightTime reports whether t falls in the night tariff window. 22:00 is
+// the first night minute and 06:00 the first day minute.
+func isNightTime(t time.Time) bool {
+	hour := t.In(timeutil.Berlin()).Hour()
+	return hour >= NightStartHour || hour < NightEndHour
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pickupAt(t *testing.T, value string) *time.Time {
+	t.Helper()
+	pickup, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("invalid test time %q: %v", value, err)
+	}
+	return &pickup
+}
+
+func TestNightTariffBoundaries(t *testing.T) {
+	tests := []struct {
+		pickup string
+		night  bool
+	}{
+		{"2024-01-15T21:59:00+01:00", false},
+		{"2024-01-15T22:00:00+01:00", true},
+		{"2024-01-16T05:59:00+01:00", true},
+		{"2024-01-16T06:00:00+01:00", false},
+		// Summer time: 20:00 UTC is 22:00 in Berlin.
+		{"2024-07-15T20:00:00Z", true},
+		{"2024-07-15T19:59:00Z", false},
+	}
+
+	day, err := calculatePriceWithConfig(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10}, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("day price: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pickup, func(t *testing.T) {
+			req := &PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10, PickupTime: pickupAt(t, tt.pickup)}
+			resp, err := calculatePriceWithConfig(req, defaultPricingConfig())
+			if err != nil {
+				t.Fatalf("calculate: %v", err)
+			}
+
+			if !tt.night {
+				if !reflect.DeepEqual(resp, day) {
+					t.Errorf("day pickup priced %+v, want %+v", resp, day)
+				}
+				return
+			}
+
+			if resp.NightMultiplier != NightMultiplier {
+				t.Errorf("night multiplier = %v, want %v", resp.NightMultiplier, NightMultiplier)
+			}
+			// 3.50 + (18.00 + 7.00) * 1.15 = 32.25
+			if resp.FinalPrice != 32.25 {
+				t.Errorf("final price = %.2f, want 32.25", resp.FinalPrice)
+			}
+			if resp.ComplianceCode != ComplianceNightTariff || !strings.Contains(resp.ComplianceNote, "Nachttarif") {
+				t.Errorf("compliance %q (%s) does not mention the night tariff", resp.ComplianceNote, resp.ComplianceCode)
+			}
+		})
+	}
+}
+
+func TestNightTariffWithoutPickupTimeIsUnchanged(t *testing.T) {
+	resp, err := calculatePriceWithConfig(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10}, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if resp.NightMultiplier != 0 || resp.FinalPrice != 28.50 || resp.ComplianceNote != "" {
+		t.Errorf("unexpected response without pickup_time: %+v", resp)
+	}
+}
+
+func TestNightTariffCombinedWithSurgeRespectsCap(t *testing.T) {
+	// demand/supply of 3 triggers the maximum surge
+	req := &PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 30, Supply: 10, PickupTime: pickupAt(t, "2024-01-15T23:30:00+01:00")}
+	resp, err := calculatePriceWithConfig(req, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+
+	if combined := resp.NightMultiplier * resp.SurgeMultiplier; combined > MaxSurgeMultiplier {
+		t.Errorf("combined multiplier %.4f exceeds cap %.2f", combined, MaxSurgeMultiplier)
+	}
+	// The fare can never exceed the day fare at maximum surge
+	maxFare := (BaseRateEUR + 10*PricePerKmEUR + 20*PricePerMinuteEUR) * MaxSurgeMultiplier
+	if resp.FinalPrice > math.Round(maxFare*100)/100 {
+		t.Errorf("final price %.2f exceeds the capped fare %.2f", resp.FinalPrice, maxFare)
+	}
+}
+
+func TestHandlePriceAcceptsPickupTime(t *testing.T) {
+	query := url.Values{"distance_km": {"10"}, "duration_min": {"20"}, "pickup_time": {"2024-01-15T22:00:00+01:00"}}
+	rec := httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?"+query.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp PriceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.NightMultiplier != NightMultiplier {
+		t.Errorf("night multiplier = %v, want %v", resp.NightMultiplier, NightMultiplier)
+	}
+
+	bad := httptest.NewRecorder()
+	handlePrice(bad, httptest.NewRequest(http.MethodGet, "/price?distance_km=10&duration_min=20&pickup_time=tonight", nil))
+	if bad.Code != http.StatusBadRequest {
+		t.Errorf("invalid pickup_time: status = %d, want 400", bad.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errAmbiguousNumber is returned for inputs such as "1.234" under a German
+// locale hint, which could mean 1.234 or 1234.
+var errAmbiguousNumber = errors.New("ambiguous number format")
+
+// commaDecimalLocale reports whether the locale hint (the locale query
+// parameter, e.g. "de", "de-DE" or "de_AT") uses a comma as decimal separator.
+func commaDecimalLocale(locale string) bool {
+	lang := strings.ToLower(strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0])
+	return lang == "de"
+}
+
+// parseDecimal parses s as a float. Without a comma-decimal locale hint
+// parsing is strict dot-decimal, as strconv.ParseFloat. With the hint:
+//
+//	"12,5"    -> 12.5   comma decimal
+//	"1.234,5" -> 1234.5 dot thousands separator with comma decimal
+//	"12.5"    -> 12.5   a dot not followed by exactly three digits is a decimal point
+//	"1.234"   -> error  could be 1.234 or 1234, rejected as ambiguous
+func parseDecimal(s, locale string) (float64, error) {
+	if !commaDecimalLocale(locale) {
+		return strconv.ParseFloat(s, 64)
+	}
+
+	s = strings.TrimSpace(s)
+	if strings.Count(s, ",") > 1 {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+
+	intPart, fracPart, hasComma := strings.Cut(s, ",")
+	if strings.Contains(fracPart, ".") {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+
+	if strings.Contains(intPart, ".") {
+		groups := strings.Split(intPart, ".")
+		grouped := len(groups[0]) >= 1 && len(groups[0]) <= 3
+		for _, g := range groups[1:] {
+			grouped = grouped && len(g) == 3
+		}
+
+		switch {
+		case grouped && hasComma:
+			intPart = strings.Join(groups, "")
+		case grouped:
+			return 0, fmt.Errorf("%w: %q", errAmbiguousNumber, s)
+		case hasComma:
+			return 0, fmt.Errorf("invalid number %q", s)
+		default:
+			// A plain dot decimal such as "12.5"
+			return strconv.ParseFloat(s, 64)
+		}
+	}
+
+	if hasComma {
+		return strconv.ParseFloat(intPart+"."+fracPart, 64)
+	}
+	return strconv.ParseFloat(intPart, 64)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseDecimalStrictByDefault(t *testing.T) {
+	if v, err := parseDecimal("12.5", ""); err != nil || v != 12.5 {
+		t.Errorf("12.5 = %v, %v", v, err)
+	}
+	if _, err := parseDecimal("12,5", ""); err == nil {
+		t.Error("comma decimal accepted without locale hint")
+	}
+	if _, err := parseDecimal("12,5", "en-GB"); err == nil {
+		t.Error("comma decimal accepted for en-GB")
+	}
+}
+
+func TestParseDecimalGermanLocale(t *testing.T) {
+	cases := map[string]float64{
+		"12,5":        12.5,
+		"0,75":        0.75,
+		"12":          12,
+		"12.5":        12.5,
+		"1.234,5":     1234.5,
+		"1.234.567,8": 1234567.8,
+		" 3,2 ":       3.2,
+	}
+	for _, locale := range []string{"de", "de-DE", "de_AT"} {
+		for in, want := range cases {
+			got, err := parseDecimal(in, locale)
+			if err != nil || got != want {
+				t.Errorf("parseDecimal(%q, %q) = %v, %v; want %v", in, locale, got, err, want)
+			}
+		}
+	}
+}
+
+func TestParseDecimalRejectsAmbiguousAndInvalid(t *testing.T) {
+	if _, err := parseDecimal("1.234", "de"); !errors.Is(err, errAmbiguousNumber) {
+		t.Errorf("1.234: err = %v, want ambiguous", err)
+	}
+	if _, err := parseDecimal("12.345.678", "de"); !errors.Is(err, errAmbiguousNumber) {
+		t.Errorf("12.345.678: err = %v, want ambiguous", err)
+	}
+
+	for _, in := range []string{"1,2,3", "1,2.5", "12.34,5", "abc", ""} {
+		if _, err := parseDecimal(in, "de"); err == nil {
+			t.Errorf("parseDecimal(%q, de) accepted invalid input", in)
+		}
+	}
+}
+
+func TestHandlePriceAcceptsCommaDecimalsWithLocale(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?distance_km=12,5&duration_min=20&locale=de", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("with locale: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?distance_km=12,5&duration_min=20", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("without locale: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+const (
+	// DefaultPoolSharePct is the percentage of the solo fare each rider of a
+	// pooled ride pays
+	DefaultPoolSharePct = 65.0
+
+	// MaxPoolRiders is the seat capacity of a pooled ride
+	MaxPoolRiders = 4
+)
+
+var poolSharePct = DefaultPoolSharePct
+
+// loadPoolSharePct reads PRICING_POOL_SHARE_PCT
+func loadPoolSharePct() (float64, error) {
+	v := os.Getenv("PRICING_POOL_SHARE_PCT")
+	if v == "" {
+		return DefaultPoolSharePct, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 || f > 100 || math.IsNaN(f) {
+		return 0, fmt.Errorf("PRICING_POOL_SHARE_PCT must be a percentage between 0 and 100, got %q", v)
+	}
+	return f, nil
+}
+
+// PoolPriceRequest is the payload for POST /price/pool: the whole pooled trip
+// plus the number of riders sharing it
+type PoolPriceRequest struct {
+	PriceRequest
+	RiderCount int `json:"rider_count"`
+}
+
+// PoolPriceResponse is the price of a pooled ride. PerRiderPrice is what each
+// rider pays and TotalCollected what the platform collects across all of
+// them, so it can be checked against the solo fare.
+type PoolPriceResponse struct {
+	RiderCount      int     `json:"rider_count"`
+	SoloPrice       float64 `json:"solo_price"`
+	SharePct        float64 `json:"share_pct"`
+	PerRiderPrice   float64 `json:"per_rider_price"`
+	PerRiderNet     float64 `json:"per_rider_net_price"`
+	PerRiderVAT     float64 `json:"per_rider_vat_amount"`
+	VATRate         float64 `json:"vat_rate"`
+	TotalCollected  float64 `json:"total_collected"`
+	Currency        string  `json:"currency"`
+	SurgeMultiplier float64 `json:"surge_multiplier"`
+	NightMultiplier float64 `json:"night_multiplier,omitempty"`
+	FixedRoute      bool    `json:"fixed_route,omitempty"`
+	Compliance
+}
+
+// calculatePoolPrice splits the solo fare of the trip between riders. Each
+// rider pays sharePct of the solo fare, and each share is held to the PBefG
+// §51 minimum fare on its own, since every rider is a separate passenger.
+func calculatePoolPrice(solo *PriceResponse, riderCount int, sharePct float64, cfg PricingConfig) PoolPriceResponse {
+	share := math.Round(solo.FinalPrice*sharePct) / 100
+	compliance := solo.Compliance
+	if share < cfg.MinimumFareEUR {
+		logger.Info("Minimum fare enforced on pool share",
+			"calculated_share", share,
+			"minimum_fare", cfg.MinimumFareEUR,
+		)
+		share = cfg.MinimumFareEUR
+		compliance.addCompliance(CompliancePoolMinimumFare)
+	}
+
+	net, vat := splitVAT(share, solo.VATRate)
+	return PoolPriceResponse{
+		RiderCount:      riderCount,
+		SoloPrice:       solo.FinalPrice,
+		SharePct:        sharePct,
+		PerRiderPrice:   share,
+		PerRiderNet:     net,
+		PerRiderVAT:     vat,
+		VATRate:         solo.VATRate,
+		TotalCollected:  math.Round(share*float64(riderCount)*100) / 100,
+		Currency:        solo.Currency,
+		SurgeMultiplier: solo.SurgeMultiplier,
+		NightMultiplier: solo.NightMultiplier,
+		FixedRoute:      solo.FixedRoute,
+		Compliance:      compliance,
+	}
+}
+
+// handlePoolPrice serves POST /price/pool. The trip is priced like a solo
+// quote, surge and night tariff included, and then split between riders by
+// calculatePoolPrice. Pool prices carry no quote token.
+func handlePoolPrice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := requireJSON(r); err != nil {
+		responseError(w, err.Error(), "INVALID_REQUEST", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	req := PoolPriceRequest{PriceRequest: PriceRequest{Demand: 10, Supply: 10}}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPriceRequestBytes)).Decode(&req); err != nil {
+		msg := fmt.Sprintf("invalid JSON body: %v", err)
+		if errors.Is(err, io.EOF) {
+			msg = "request body is required"
+		}
+		responseError(w, msg, "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	if err := validatePoolPriceRequest(&req); err != nil {
+		logger.Warn("Pool price request validation failed", "error", err)
+		responseError(w, err.Error(), "VALIDATION_ERROR", http.StatusBadRequest)
+		return
+	}
+
+	solo, err := calculatePrice(&req.PriceRequest)
+	if err != nil {
+		logger.Error("Pool price calculation error", "error", err)
+		responseError(w, "Failed to calculate price", "CALCULATION_ERROR", http.StatusInternalServerError)
+		return
+	}
+	resp := calculatePoolPrice(solo, req.RiderCount, poolSharePct, activeConfig)
+	resp.localize(responseLanguage(w, r))
+
+	logger.Info("Pool price calculated",
+		"distance_km", req.DistanceKm,
+		"duration_min", req.DurationMin,
+		"rider_count", resp.RiderCount,
+		"solo_price", resp.SoloPrice,
+		"per_rider_price", resp.PerRiderPrice,
+		"total_collected", resp.TotalCollected,
+	)
+
+	responseJSON(w, resp, http.StatusOK)
+}
+
+func validatePoolPriceRequest(req *PoolPriceRequest) error {
+	if err := validatePriceRequest(&req.PriceRequest); err != nil {
+		return err
+	}
+	if req.RiderCount < 2 || req.RiderCount > MaxPoolRiders {
+		return fmt.Errorf("rider_count must be between 2 and %d", MaxPoolRiders)
+	}
+	if req.PromoCode != "" {
+		return errors.New("promo_code is not supported for pooled rides")
+	}
+	return nil
+}
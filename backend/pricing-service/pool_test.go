@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func postPool(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/price/pool", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handlePoolPrice(rec, req)
+	return rec
+}
+
+func TestHandlePoolPriceSplitsFare(t *testing.T) {
+	rec := postPool(t, `{"distance_km": 10, "duration_min": 20, "rider_count": 2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp PoolPriceResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	// 65% of the 28.50 solo fare
+	if resp.SoloPrice != 28.50 || resp.PerRiderPrice != 18.53 || resp.TotalCollected != 37.06 {
+		t.Errorf("unexpected pool price %+v", resp)
+	}
+	if resp.PerRiderNet+resp.PerRiderVAT != resp.PerRiderPrice {
+		t.Errorf("net %.2f + vat %.2f != gross %.2f", resp.PerRiderNet, resp.PerRiderVAT, resp.PerRiderPrice)
+	}
+	if resp.ComplianceNote != "" {
+		t.Errorf("unexpected compliance note %q", resp.ComplianceNote)
+	}
+}
+
+func TestCalculatePoolPriceEnforcesMinimumFarePerShare(t *testing.T) {
+	solo, _ := calculatePrice(&PriceRequest{DistanceKm: 1, DurationMin: 2, Demand: 10, Supply: 10})
+
+	resp := calculatePoolPrice(solo, 3, DefaultPoolSharePct, defaultPricingConfig())
+	if resp.PerRiderPrice != MinimumFareEUR || resp.TotalCollected != 3*MinimumFareEUR {
+		t.Errorf("got %.2f per rider and %.2f total, want the minimum fare for each of 3 riders", resp.PerRiderPrice, resp.TotalCollected)
+	}
+	if resp.ComplianceCode != CompliancePoolMinimumFare || !strings.Contains(resp.ComplianceNote, "§ 51 PBefG") {
+		t.Errorf("compliance %q (%s) does not cite the minimum fare", resp.ComplianceNote, resp.ComplianceCode)
+	}
+}
+
+func TestHandlePoolPriceValidation(t *testing.T) {
+	for _, body := range []string{
+		`{"distance_km": 10, "duration_min": 20}`,
+		`{"distance_km": 10, "duration_min": 20, "rider_count": 1}`,
+		`{"distance_km": 10, "duration_min": 20, "rider_count": 5}`,
+		`{"distance_km": 0, "duration_min": 20, "rider_count": 2}`,
+		`{"distance_km": 10, "duration_min": 20, "rider_count": 2, "promo_code": "LAUNCH20"}`,
+	} {
+		if rec := postPool(t, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestLoadPoolSharePct(t *testing.T) {
+	t.Setenv("PRICING_POOL_SHARE_PCT", "70")
+	if pct, err := loadPoolSharePct(); err != nil || pct != 70 {
+		t.Errorf("got %v (%v), want 70", pct, err)
+	}
+
+	for _, v := range []string{"0", "120", "abc"} {
+		t.Setenv("PRICING_POOL_SHARE_PCT", v)
+		if _, err := loadPoolSharePct(); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Promo discount types
+const (
+	PromoPercent = "percent"
+	PromoFixed   = "fixed"
+)
+
+// Promo is a marketing discount code. Value is a percentage for percent
+// promos and an amount in EUR for fixed ones. MaxDiscountEUR caps the
+// discount; zero means no cap.
+type Promo struct {
+	Code           string    `json:"code"`
+	Type           string    `json:"type"`
+	Value          float64   `json:"value"`
+	MaxDiscountEUR float64   `json:"max_discount_eur,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+var (
+	errPromoUnknown = errors.New("unknown promo code")
+	errPromoExpired = errors.New("promo code has expired")
+)
+
+// PromoStore holds promo codes keyed by their normalized code
+type PromoStore struct {
+	mu     sync.RWMutex
+	promos map[string]Promo
+}
+
+func NewPromoStore() *PromoStore {
+	return &PromoStore{promos: make(map[string]Promo)}
+}
+
+var promos = NewPromoStore()
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Set registers or replaces a promo
+func (s *PromoStore) Set(promo Promo) Promo {
+	promo.Code = normalizePromoCode(promo.Code)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promos[promo.Code] = promo
+	return promo
+}
+
+// Lookup returns the promo for code if it is valid at now
+func (s *PromoStore) Lookup(code string, now time.Time) (Promo, error) {
+	s.mu.RLock()
+	promo, ok := s.promos[normalizePromoCode(code)]
+	s.mu.RUnlock()
+	if !ok {
+		return Promo{}, errPromoUnknown
+	}
+	if !now.Before(promo.ExpiresAt) {
+		return promo, errPromoExpired
+	}
+	return promo, nil
+}
+
+// List returns all promos ordered by code
+func (s *PromoStore) List() []Promo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Promo, 0, len(s.promos))
+	for _, promo := range s.promos {
+		list = append(list, promo)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// discountFor returns the discount the promo gives on fare, before the
+// minimum fare is considered
+func (p Promo) discountFor(fare float64) float64 {
+	discount := p.Value
+	if p.Type == PromoPercent {
+		discount = fare * p.Value / 100
+	}
+	if p.MaxDiscountEUR > 0 {
+		discount = math.Min(discount, p.MaxDiscountEUR)
+	}
+	return math.Min(discount, fare)
+}
+
+// applyPromo discounts the priced fare in resp. The discount comes after
+// surge and every other adjustment, but never takes the fare below the PBefG
+// §51 minimum fare. It returns an error response for unknown or expired
+// codes, leaving resp undiscounted.
+func applyPromo(code string, resp *PriceResponse, cfg PricingConfig, now time.Time) *ErrorResponse {
+	promo, err := promos.Lookup(code, now)
+	switch {
+	case errors.Is(err, errPromoUnknown):
+		return &ErrorResponse{Error: fmt.Sprintf("Promo code %q is not valid", normalizePromoCode(code)), Code: "PROMO_INVALID"}
+	case errors.Is(err, errPromoExpired):
+		return &ErrorResponse{Error: fmt.Sprintf("Promo code %q expired at %s", promo.Code, timeutil.Format(promo.ExpiresAt)), Code: "PROMO_EXPIRED"}
+	}
+
+	undiscounted := resp.FinalPrice
+	discounted := math.Round((undiscounted-promo.discountFor(undiscounted))*100) / 100
+	if discounted < cfg.MinimumFareEUR {
+		logger.Info("Minimum fare enforced on promo",
+			"promo_code", promo.Code,
+			"discounted_price", discounted,
+			"minimum_fare", cfg.MinimumFareEUR,
+		)
+		discounted = cfg.MinimumFareEUR
+		resp.addCompliance(CompliancePromoMinimumFare)
+	}
+	if discounted > undiscounted {
+		// The fare was already at the minimum
+		discounted = undiscounted
+	}
+
+	resp.PromoCode = promo.Code
+	resp.UndiscountedPrice = undiscounted
+	resp.DiscountEUR = math.Round((undiscounted-discounted)*100) / 100
+	resp.FinalPrice = discounted
+	resp.NetPrice, resp.VATAmount = splitVAT(discounted, resp.VATRate)
+	return nil
+}
+
+// handlePromos serves /promos: GET lists the promos, POST registers
+// {code, type, value, max_discount_eur, expires_at}. POST is an admin
+// operation and requires the X-User-Role: admin header set by the gateway.
+func handlePromos(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		responseJSON(w, promos.List(), http.StatusOK)
+
+	case http.MethodPost:
+		if r.Header.Get("X-User-Role") != "admin" {
+			responseError(w, "Admin role required", "FORBIDDEN", http.StatusForbidden)
+			return
+		}
+
+		var promo Promo
+		if err := json.NewDecoder(r.Body).Decode(&promo); err != nil {
+			responseError(w, "Invalid JSON body", "INVALID_REQUEST", http.StatusBadRequest)
+			return
+		}
+		if err := validatePromo(promo, timeutil.Now()); err != nil {
+			responseError(w, err.Error(), "VALIDATION_ERROR", http.StatusBadRequest)
+			return
+		}
+
+		promo = promos.Set(promo)
+		logger.Info("Promo registered",
+			"code", promo.Code,
+			"type", promo.Type,
+			"value", promo.Value,
+			"max_discount_eur", promo.MaxDiscountEUR,
+			"expires_at", timeutil.Format(promo.ExpiresAt),
+			"actor", r.Header.Get("X-User-ID"),
+		)
+		responseJSON(w, promo, http.StatusCreated)
+
+	default:
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+	}
+}
+
+func validatePromo(promo Promo, now time.Time) error {
+	if normalizePromoCode(promo.Code) == "" {
+		return errors.New("code is required")
+	}
+	finite := func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
+	switch promo.Type {
+	case PromoPercent:
+		if !finite(promo.Value) || promo.Value <= 0 || promo.Value > 100 {
+			return errors.New("value must be a percentage between 0 and 100 for percent promos")
+		}
+	case PromoFixed:
+		if !finite(promo.Value) || promo.Value <= 0 {
+			return errors.New("value must be a positive amount in EUR for fixed promos")
+		}
+	default:
+		return fmt.Errorf("type must be %s or %s", PromoPercent, PromoFixed)
+	}
+	if !finite(promo.MaxDiscountEUR) || promo.MaxDiscountEUR < 0 {
+		return errors.New("max_discount_eur cannot be negative")
+	}
+	if !promo.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func usePromos(t *testing.T, list ...Promo) {
+	t.Helper()
+	prev := promos
+	promos = NewPromoStore()
+	for _, promo := range list {
+		promos.Set(promo)
+	}
+	t.Cleanup(func() { promos = prev })
+}
+
+func getPrice(t *testing.T, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?"+query, nil))
+	return rec
+}
+
+func TestPromoPercentAppliedAfterSurge(t *testing.T) {
+	usePromos(t, Promo{Code: "launch20", Type: PromoPercent, Value: 20, ExpiresAt: time.Now().Add(time.Hour)})
+
+	// 28.50 at 1.5x surge is 42.75; 20% off gives 34.20
+	rec := getPrice(t, "distance_km=10&duration_min=20&demand=20&supply=10&promo_code=LAUNCH20")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp PriceResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.FinalPrice != 34.20 || resp.UndiscountedPrice != 42.75 || resp.DiscountEUR != 8.55 || resp.PromoCode != "LAUNCH20" {
+		t.Errorf("unexpected discounted price %+v", resp)
+	}
+	if resp.NetPrice+resp.VATAmount != resp.FinalPrice {
+		t.Errorf("net %.2f + vat %.2f != gross %.2f", resp.NetPrice, resp.VATAmount, resp.FinalPrice)
+	}
+
+	quote, err := quoteSigner.Verify(resp.QuoteToken)
+	if err != nil || quote.FareEUR != 34.20 {
+		t.Errorf("quote token fare = %.2f (%v), want the discounted 34.20", quote.FareEUR, err)
+	}
+}
+
+func TestPromoCapAndMinimumFare(t *testing.T) {
+	usePromos(t,
+		Promo{Code: "CAPPED", Type: PromoPercent, Value: 50, MaxDiscountEUR: 5, ExpiresAt: time.Now().Add(time.Hour)},
+		Promo{Code: "FREERIDE", Type: PromoFixed, Value: 30, ExpiresAt: time.Now().Add(time.Hour)},
+	)
+
+	capped, _, _ := quotePrice(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10, PromoCode: "CAPPED"})
+	if capped.FinalPrice != 23.50 || capped.DiscountEUR != 5 {
+		t.Errorf("capped promo: %+v, want 5.00 off 28.50", capped)
+	}
+
+	floored, errResp, _ := quotePrice(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10, PromoCode: "FREERIDE"})
+	if errResp != nil {
+		t.Fatalf("unexpected error %+v", errResp)
+	}
+	if floored.FinalPrice != MinimumFareEUR || floored.DiscountEUR != 23.50 {
+		t.Errorf("fixed promo: %+v, want the minimum fare", floored)
+	}
+	if !strings.Contains(floored.ComplianceCode, CompliancePromoMinimumFare) || !strings.Contains(floored.ComplianceNote, "Mindestfahrpreis") {
+		t.Errorf("compliance %q (%s) does not mention the minimum fare", floored.ComplianceNote, floored.ComplianceCode)
+	}
+}
+
+func TestPromoRejectedReturnsUndiscountedPrice(t *testing.T) {
+	usePromos(t, Promo{Code: "OLD", Type: PromoPercent, Value: 10, ExpiresAt: time.Now().Add(-time.Hour)})
+
+	for code, want := range map[string]string{"OLD": "PROMO_EXPIRED", "NOPE": "PROMO_INVALID"} {
+		rec := getPrice(t, "distance_km=10&duration_min=20&promo_code="+code)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: status = %d, want 422", code, rec.Code)
+		}
+		var resp PromoErrorResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp.Code != want || resp.Error == "" {
+			t.Errorf("%s: error = %q (%s), want %s", code, resp.Error, resp.Code, want)
+		}
+		if resp.Price == nil || resp.Price.FinalPrice != 28.50 || resp.Price.PromoCode != "" || resp.Price.QuoteToken == "" {
+			t.Errorf("%s: price = %+v, want the undiscounted quote", code, resp.Price)
+		}
+	}
+}
+
+func TestHandlePromosRegisters(t *testing.T) {
+	usePromos(t)
+
+	post := func(role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/promos", strings.NewReader(body))
+		req.Header.Set("X-User-Role", role)
+		rec := httptest.NewRecorder()
+		handlePromos(rec, req)
+		return rec
+	}
+	expires := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	if rec := post("rider", `{"code": "SUMMER", "type": "percent", "value": 10, "expires_at": "`+expires+`"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+	for _, body := range []string{
+		`{"code": "SUMMER", "type": "percent", "value": 120, "expires_at": "` + expires + `"}`,
+		`{"code": "SUMMER", "type": "bogo", "value": 10, "expires_at": "` + expires + `"}`,
+		`{"code": "SUMMER", "type": "fixed", "value": 5, "expires_at": "2020-01-01T00:00:00Z"}`,
+		`{"code": " ", "type": "fixed", "value": 5, "expires_at": "` + expires + `"}`,
+	} {
+		if rec := post("admin", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec := post("admin", `{"code": "summer", "type": "fixed", "value": 5, "max_discount_eur": 5, "expires_at": "`+expires+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if _, err := promos.Lookup("Summer", time.Now()); err != nil {
+		t.Errorf("registered promo not found: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// SurgeLogCapacity is the number of surge entries kept for GET /surge-log
+const SurgeLogCapacity = 1000
+
+// SurgeLogEntry documents why a quote was surged (PBefG §39). Ratio is
+// omitted when supply was zero, which always yields the maximum surge.
+type SurgeLogEntry struct {
+	Time            time.Time `json:"time"`
+	Demand          int       `json:"demand"`
+	Supply          int       `json:"supply"`
+	Ratio           *float64  `json:"ratio,omitempty"`
+	SurgeMultiplier float64   `json:"surge_multiplier"`
+	NightMultiplier float64   `json:"night_multiplier,omitempty"`
+	FinalPrice      float64   `json:"final_price"`
+}
+
+// SurgeLog is a fixed-size ring buffer of the most recent surge entries
+type SurgeLog struct {
+	mu      sync.Mutex
+	entries []SurgeLogEntry
+	next    int
+	full    bool
+}
+
+func NewSurgeLog(capacity int) *SurgeLog {
+	return &SurgeLog{entries: make([]SurgeLogEntry, capacity)}
+}
+
+var surgeLog = NewSurgeLog(SurgeLogCapacity)
+
+// Add stores an entry, overwriting the oldest once the log is full
+func (l *SurgeLog) Add(entry SurgeLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Entries returns the stored entries, oldest first
+func (l *SurgeLog) Entries() []SurgeLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]SurgeLogEntry(nil), l.entries[:l.next]...)
+	}
+	out := make([]SurgeLogEntry, 0, len(l.entries))
+	out = append(out, l.entries[l.next:]...)
+	return append(out, l.entries[:l.next]...)
+}
+
+// recordSurge adds an entry for a quote priced with a surge above 1.0
+func recordSurge(req *PriceRequest, resp *PriceResponse) {
+	if resp.SurgeMultiplier <= 1.0 {
+		return
+	}
+	entry := SurgeLogEntry{
+		Time:            timeutil.Now(),
+		Demand:          req.Demand,
+		Supply:          req.Supply,
+		SurgeMultiplier: resp.SurgeMultiplier,
+		NightMultiplier: resp.NightMultiplier,
+		FinalPrice:      resp.FinalPrice,
+	}
+	if req.Supply > 0 {
+		ratio := math.Round(float64(req.Demand)/float64(req.Supply)*100) / 100
+		entry.Ratio = &ratio
+	}
+	surgeLog.Add(entry)
+
+	logger.Info("Surge applied",
+		"demand", req.Demand,
+		"supply", req.Supply,
+		"surge_multiplier", resp.SurgeMultiplier,
+		"final_price", resp.FinalPrice,
+	)
+}
+
+// handleSurgeLog returns the recent surge entries, oldest first
+func handleSurgeLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+	responseJSON(w, surgeLog.Entries(), http.StatusOK)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSurgeLogRingBuffer(t *testing.T) {
+	l := NewSurgeLog(3)
+	for demand := 1; demand <= 5; demand++ {
+		l.Add(SurgeLogEntry{Demand: demand})
+	}
+
+	entries := l.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("len = %d, want 3", len(entries))
+	}
+	for i, want := range []int{3, 4, 5} {
+		if entries[i].Demand != want {
+			t.Errorf("entries[%d].Demand = %d, want %d", i, entries[i].Demand, want)
+		}
+	}
+}
+
+func TestHandlePriceRecordsSurge(t *testing.T) {
+	before := len(surgeLog.Entries())
+
+	rec := httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?distance_km=10&duration_min=20&demand=10&supply=10", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if n := len(surgeLog.Entries()); n != before {
+		t.Errorf("unsurged quote was logged (%d -> %d entries)", before, n)
+	}
+
+	rec = httptest.NewRecorder()
+	handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?distance_km=10&duration_min=20&demand=20&supply=10", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handleSurgeLog(rec, httptest.NewRequest(http.MethodGet, "/surge-log", nil))
+	var entries []SurgeLogEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("failed to decode surge log: %v", err)
+	}
+	if len(entries) != before+1 {
+		t.Fatalf("len = %d, want %d", len(entries), before+1)
+	}
+	last := entries[len(entries)-1]
+	if last.Demand != 20 || last.Supply != 10 || last.Ratio == nil || *last.Ratio != 2 || last.SurgeMultiplier != 1.5 {
+		t.Errorf("unexpected entry %+v", last)
+	}
+}
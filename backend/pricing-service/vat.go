@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/vat"
+)
+
+// VAT rates for passenger transport as fractions of the gross fare. The rule
+// itself lives in backend/pkg/vat, which the payment-service receipts use too.
+const (
+	VATRateReduced      = vat.ReducedPercent / 100.0
+	VATRateStandard     = vat.StandardPercent / 100.0
+	VATReducedRateMaxKm = vat.ReducedRateMaxKm
+)
+
+// vatRate returns the VAT rate for a trip of distanceKm
+func vatRate(distanceKm float64) float64 {
+	return vat.Rate(distanceKm)
+}
+
+// splitVAT splits a gross amount into net and VAT in whole cents. The VAT is
+// the remainder, so net + VAT always equals the gross exactly.
+func splitVAT(gross, rate float64) (net, vatAmount float64) {
+	netCents, vatCents := vat.Split(int64(math.Round(gross*100)), int(math.Round(rate*100)))
+	return float64(netCents) / 100, float64(vatCents) / 100
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVATRateByDistance(t *testing.T) {
+	tests := []struct {
+		distanceKm float64
+		want       float64
+	}{
+		{10, VATRateReduced},
+		{50, VATRateReduced},
+		{50.1, VATRateStandard},
+		{120, VATRateStandard},
+	}
+	for _, tt := range tests {
+		if got := vatRate(tt.distanceKm); got != tt.want {
+			t.Errorf("vatRate(%v) = %v, want %v", tt.distanceKm, got, tt.want)
+		}
+	}
+}
+
+func TestSplitVATReconstructsGross(t *testing.T) {
+	for cents := 500; cents <= 50000; cents++ {
+		gross := float64(cents) / 100
+		for _, rate := range []float64{VATRateReduced, VATRateStandard} {
+			net, vat := splitVAT(gross, rate)
+			if math.Round(net*100)+math.Round(vat*100) != float64(cents) {
+				t.Fatalf("gross %.2f at %v: net %.2f + vat %.2f does not reconstruct the gross", gross, rate, net, vat)
+			}
+			if net != math.Round(net*100)/100 || vat != math.Round(vat*100)/100 {
+				t.Fatalf("gross %.2f at %v: net %v or vat %v is not whole cents", gross, rate, net, vat)
+			}
+		}
+	}
+}
+
+func TestCalculatePriceIncludesVAT(t *testing.T) {
+	resp, err := calculatePriceWithConfig(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10}, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	// 28.50 gross at 7%: net 26.64, VAT 1.86
+	if resp.VATRate != VATRateReduced || resp.NetPrice != 26.64 || resp.VATAmount != 1.86 {
+		t.Errorf("unexpected VAT breakdown %+v", resp)
+	}
+
+	long, err := calculatePriceWithConfig(&PriceRequest{DistanceKm: 80, DurationMin: 60, Demand: 10, Supply: 10}, defaultPricingConfig())
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if long.VATRate != VATRateStandard {
+		t.Errorf("vat rate for 80km = %v, want %v", long.VATRate, VATRateStandard)
+	}
+}
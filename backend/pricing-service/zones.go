@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ZoneSurcharge maps pickup zones (airports, stadiums) to the regulated flat
+// surcharge in EUR for a pickup there. Keys are normalized zone identifiers.
+type ZoneSurcharge map[string]float64
+
+// zoneSurcharges is loaded once at startup and only read afterwards
+var zoneSurcharges = ZoneSurcharge{}
+
+// Lookup returns the surcharge for pickupZone, if it has one
+func (z ZoneSurcharge) Lookup(pickupZone string) (string, float64, bool) {
+	zone := normalizeZone(pickupZone)
+	if zone == "" {
+		return "", 0, false
+	}
+	surcharge, ok := z[zone]
+	return zone, surcharge, ok
+}
+
+// loadZoneSurcharges reads the JSON file named by PRICING_ZONE_SURCHARGES_FILE,
+// an object of zone to surcharge, e.g. {"BER": 5.00, "OLYMPIASTADION": 3.50}.
+// No file means no surcharges.
+func loadZoneSurcharges() (ZoneSurcharge, error) {
+	path := os.Getenv("PRICING_ZONE_SURCHARGES_FILE")
+	if path == "" {
+		return ZoneSurcharge{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading PRICING_ZONE_SURCHARGES_FILE: %w", err)
+	}
+	return parseZoneSurcharges(data)
+}
+
+func parseZoneSurcharges(data []byte) (ZoneSurcharge, error) {
+	var raw map[string]float64
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid zone surcharges: %w", err)
+	}
+
+	surcharges := make(ZoneSurcharge, len(raw))
+	for zone, eur := range raw {
+		key := normalizeZone(zone)
+		if key == "" {
+			return nil, fmt.Errorf("invalid zone surcharges: empty zone identifier")
+		}
+		if eur <= 0 || math.IsNaN(eur) || math.IsInf(eur, 0) {
+			return nil, fmt.Errorf("invalid zone surcharges: %s must be a positive amount", zone)
+		}
+		if _, dup := surcharges[key]; dup {
+			return nil, fmt.Errorf("invalid zone surcharges: %s is listed twice", key)
+		}
+		surcharges[key] = eur
+	}
+	return surcharges, nil
+}
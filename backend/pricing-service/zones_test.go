@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useZoneSurcharges(t *testing.T, surcharges ZoneSurcharge) {
+	t.Helper()
+	prev := zoneSurcharges
+	zoneSurcharges = surcharges
+	t.Cleanup(func() { zoneSurcharges = prev })
+}
+
+func TestZoneSurchargeAddedBeforeSurge(t *testing.T) {
+	useZoneSurcharges(t, ZoneSurcharge{"BER": 5.00})
+
+	// 28.50 plus 5.00 surcharge at 1.5x surge
+	resp, err := calculatePrice(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 20, Supply: 10, PickupZone: "ber"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ZoneSurcharge != 5.00 || resp.Subtotal != 33.50 || resp.FinalPrice != 50.25 {
+		t.Errorf("unexpected price %+v, want 33.50 subtotal and 50.25 final", resp)
+	}
+	if resp.ComplianceCode != ComplianceZoneSurcharge || !strings.Contains(resp.ComplianceNote, "Zonenzuschlag von 5,00 EUR für die Abholzone BER") {
+		t.Errorf("compliance %q (%s) does not mention the surcharge", resp.ComplianceNote, resp.ComplianceCode)
+	}
+
+	other, _ := calculatePrice(&PriceRequest{DistanceKm: 10, DurationMin: 20, Demand: 10, Supply: 10, PickupZone: "TXL"})
+	if other.ZoneSurcharge != 0 || other.FinalPrice != 28.50 {
+		t.Errorf("zone without surcharge: %+v, want the metered 28.50", other)
+	}
+}
+
+func TestZoneSurchargeExcludedFromPerKmMinimum(t *testing.T) {
+	useZoneSurcharges(t, ZoneSurcharge{"BER": 5.00})
+	cfg := defaultPricingConfig()
+	cfg.PricePerKmEUR = 1.00
+
+	// 3.50 + 10 + 3.50 = 17.00 falls short of 1.50/km; the surcharge must
+	// neither hide the shortfall nor be added twice
+	resp, err := calculatePriceWithConfig(&PriceRequest{DistanceKm: 10, DurationMin: 10, Demand: 10, Supply: 10, PickupZone: "BER"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinalPrice != 27.00 {
+		t.Errorf("final price = %.2f, want 22.00 per-km adjusted plus the 5.00 surcharge", resp.FinalPrice)
+	}
+	if resp.ComplianceCode != ComplianceMinimumPerKmRate+";"+ComplianceZoneSurcharge {
+		t.Errorf("compliance %q (%s), want the per-km adjustment and the surcharge", resp.ComplianceNote, resp.ComplianceCode)
+	}
+}
+
+func TestZoneSurchargeNotAppliedOnFixedRoute(t *testing.T) {
+	useZoneSurcharges(t, ZoneSurcharge{"BER": 5.00})
+	prev := fixedRoutes
+	fixedRoutes = NewFixedRouteStore()
+	t.Cleanup(func() { fixedRoutes = prev })
+	fixedRoutes.Set(FixedRoute{PickupZone: "BER", DropoffZone: "HBF", FareEUR: 55})
+
+	resp, _ := calculatePrice(&PriceRequest{DistanceKm: 25, DurationMin: 35, Demand: 10, Supply: 10, PickupZone: "BER", DropoffZone: "HBF"})
+	if resp.FinalPrice != 55 || resp.ZoneSurcharge != 0 {
+		t.Errorf("fixed route: %+v, want the flat 55.00 fare", resp)
+	}
+}
+
+func TestLoadZoneSurcharges(t *testing.T) {
+	if z, err := loadZoneSurcharges(); err != nil || len(z) != 0 {
+		t.Errorf("unset: got %v (%v), want no surcharges", z, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "zones.json")
+	os.WriteFile(path, []byte(`{"ber": 5.00, " Olympiastadion ": 3.50}`), 0o600)
+	t.Setenv("PRICING_ZONE_SURCHARGES_FILE", path)
+	z, err := loadZoneSurcharges()
+	if err != nil || z["BER"] != 5.00 || z["OLYMPIASTADION"] != 3.50 {
+		t.Errorf("got %v (%v)", z, err)
+	}
+
+	for _, data := range []string{`{"BER": 0}`, `{"BER": -2}`, `{"": 1}`, `{"ber": 1, "BER": 2}`, `["BER"]`} {
+		if _, err := parseZoneSurcharges([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+
+	t.Setenv("PRICING_ZONE_SURCHARGES_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := loadZoneSurcharges(); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
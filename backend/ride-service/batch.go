@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxBatchGetIDs caps the number of ride IDs per POST /rides/batch-get request
+const maxBatchGetIDs = 100
+
+// GetMany takes the store lock once so a dashboard sees a consistent view
+func (s *RideStore) GetMany(ctx context.Context, ids []string) ([]Ride, []string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rides := make([]Ride, 0, len(ids))
+	missing := []string{}
+	for _, id := range ids {
+		ride, exists := s.rides[id]
+		if !exists {
+			missing = append(missing, id)
+			continue
+		}
+		rides = append(rides, *ride)
+	}
+	return rides, missing, nil
+}
+
+// BatchGetResponse is the response of POST /rides/batch-get
+type BatchGetResponse struct {
+	Rides   []Ride   `json:"rides"`
+	Missing []string `json:"missing"`
+}
+
+// batchGetRidesHandler returns the current state of up to maxBatchGetIDs
+// rides. Duplicate IDs are returned once; unknown IDs are listed in missing.
+func batchGetRidesHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]bool, len(req.IDs))
+	ids := make([]string, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		http.Error(w, "ids must contain at least one ride ID", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > maxBatchGetIDs {
+		http.Error(w, fmt.Sprintf("At most %d ride IDs per request, got %d", maxBatchGetIDs, len(ids)), http.StatusBadRequest)
+		return
+	}
+
+	rides, missing, err := rideRepo.GetMany(r.Context(), ids)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BatchGetResponse{Rides: rides, Missing: missing})
+}
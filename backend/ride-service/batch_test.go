@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBatchGetRides(t *testing.T) {
+	resetStores(t)
+	first := createTestRide(t, "rider-1")
+	second := createTestRide(t, "rider-2")
+
+	rec := doRequest(t, http.MethodPost, "/rides/batch-get", map[string][]string{
+		"ids": {second.ID, "does-not-exist", first.ID, second.ID},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp BatchGetResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Rides) != 2 || resp.Rides[0].ID != second.ID || resp.Rides[1].ID != first.ID {
+		t.Errorf("unexpected rides %+v", resp.Rides)
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "does-not-exist" {
+		t.Errorf("missing = %v, want [does-not-exist]", resp.Missing)
+	}
+}
+
+func TestBatchGetRidesRejectsInvalidRequests(t *testing.T) {
+	resetStores(t)
+
+	tooMany := make([]string, maxBatchGetIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("ride-%d", i)
+	}
+
+	for name, ids := range map[string][]string{
+		"empty":    {},
+		"too many": tooMany,
+	} {
+		if rec := doRequest(t, http.MethodPost, "/rides/batch-get", map[string][]string{"ids": ids}); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
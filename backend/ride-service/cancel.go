@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Parties that can cancel a ride
+const (
+	CancelledByRider  = "rider"
+	CancelledByDriver = "driver"
+	CancelledBySystem = "system"
+)
+
+// Cancellation reason codes
+const (
+	CancelReasonRiderChangedPlans = "RIDER_CHANGED_PLANS"
+	CancelReasonRiderNoShow       = "RIDER_NO_SHOW"
+	CancelReasonDriverNoShow      = "DRIVER_NO_SHOW"
+	CancelReasonDriverUnavailable = "DRIVER_UNAVAILABLE"
+	CancelReasonLongWait          = "LONG_WAIT"
+	CancelReasonSafetyConcern     = "SAFETY_CONCERN"
+	CancelReasonNoDriverFound     = "NO_DRIVER_FOUND"
+	CancelReasonOther             = "OTHER"
+)
+
+var cancelReasonCodes = map[string]bool{
+	CancelReasonRiderChangedPlans: true,
+	CancelReasonRiderNoShow:       true,
+	CancelReasonDriverNoShow:      true,
+	CancelReasonDriverUnavailable: true,
+	CancelReasonLongWait:          true,
+	CancelReasonSafetyConcern:     true,
+	CancelReasonNoDriverFound:     true,
+	CancelReasonOther:             true,
+}
+
+// cancelRideHandler cancels a ride that has not started yet, including a
+// scheduled one. Started and completed rides can no longer be cancelled.
+func cancelRideHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var req struct {
+		CancelledBy string `json:"cancelled_by"`
+		ReasonCode  string `json:"reason_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	switch req.CancelledBy {
+	case CancelledByRider, CancelledByDriver, CancelledBySystem:
+	default:
+		http.Error(w, fmt.Sprintf("cancelled_by must be one of %s, %s, %s", CancelledByRider, CancelledByDriver, CancelledBySystem), http.StatusBadRequest)
+		return
+	}
+	if !cancelReasonCodes[req.ReasonCode] {
+		http.Error(w, fmt.Sprintf("Unknown reason_code: %q", req.ReasonCode), http.StatusBadRequest)
+		return
+	}
+
+	cancelled, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideScheduled && ride.Status != RideRequested && ride.Status != RideMatched {
+			return rejectUpdate(http.StatusBadRequest, "Cannot cancel ride in status: %s", ride.Status)
+		}
+		now := timeutil.Now()
+		ride.Status = RideCancelled
+		ride.CancelledAt = &now
+		ride.CancelledBy = req.CancelledBy
+		ride.CancelReasonCode = req.ReasonCode
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Ride cancelled", "ride_id", cancelled.ID, "cancelled_by", req.CancelledBy, "reason", req.ReasonCode)
+	publishRideEvent(r.Context(), EventRideCancelled, cancelled)
+	tripShares.ClearLocation(cancelled.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelled)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func cancelTestRide(t *testing.T, id string, cancelledBy, reasonCode string) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequest(t, http.MethodPut, "/rides/"+id+"/cancel", map[string]string{"cancelled_by": cancelledBy, "reason_code": reasonCode})
+}
+
+func TestCancelRequestedRide(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+
+	rec := cancelTestRide(t, ride.ID, CancelledByRider, CancelReasonRiderChangedPlans)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var cancelled Ride
+	if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if cancelled.Status != RideCancelled || cancelled.CancelledAt == nil {
+		t.Errorf("status = %s, cancelled_at = %v", cancelled.Status, cancelled.CancelledAt)
+	}
+	if cancelled.CancelledBy != CancelledByRider || cancelled.CancelReasonCode != CancelReasonRiderChangedPlans {
+		t.Errorf("cancelled_by = %q, reason = %q", cancelled.CancelledBy, cancelled.CancelReasonCode)
+	}
+
+	// A cancelled ride cannot be matched or cancelled again
+	if rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("match after cancel: status = %d, want 400", rec.Code)
+	}
+	if rec := cancelTestRide(t, ride.ID, CancelledByRider, CancelReasonOther); rec.Code != http.StatusBadRequest {
+		t.Errorf("second cancel: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCancelMatchedRide(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+
+	if rec := cancelTestRide(t, ride.ID, CancelledByDriver, CancelReasonRiderNoShow); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := memoryStore(t).rides[ride.ID].Status; got != RideCancelled {
+		t.Errorf("status = %s, want CANCELLED", got)
+	}
+}
+
+func TestCancelRejectsStartedAndCompletedRides(t *testing.T) {
+	resetStores(t)
+	started := startTestRide(t)
+	if rec := cancelTestRide(t, started.ID, CancelledByRider, CancelReasonOther); rec.Code != http.StatusBadRequest {
+		t.Errorf("started ride: status = %d, want 400", rec.Code)
+	}
+
+	completed := startTestRide(t)
+	completeTestRide(t, completed.ID, map[string]interface{}{})
+	if rec := cancelTestRide(t, completed.ID, CancelledByRider, CancelReasonOther); rec.Code != http.StatusBadRequest {
+		t.Errorf("completed ride: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCancelValidatesRequest(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+
+	if rec := cancelTestRide(t, ride.ID, "dispatcher", CancelReasonOther); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown cancelled_by: status = %d, want 400", rec.Code)
+	}
+	if rec := cancelTestRide(t, ride.ID, CancelledByRider, "BORED"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown reason_code: status = %d, want 400", rec.Code)
+	}
+	if rec := cancelTestRide(t, "missing", CancelledByRider, CancelReasonOther); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown ride: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ReturnToBaseComplianceReport sums up how a driver honored the
+// Rückkehrpflicht (§49 Abs. 4 PBefG) over the returns started in [From, To),
+// for operators to present to the Genehmigungsbehörde
+type ReturnToBaseComplianceReport struct {
+	DriverID string    `json:"driver_id"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	// Returns counts every return started in the period. Cancelled returns,
+	// where the driver took a new ride on the way, are neither compliant nor
+	// violations.
+	Returns      int `json:"returns"`
+	Compliant    int `json:"compliant"`
+	NonCompliant int `json:"non_compliant"`
+	Open         int `json:"open"`
+	Cancelled    int `json:"cancelled"`
+	// AverageReturnDurationMin averages the ended returns
+	AverageReturnDurationMin float64 `json:"average_return_duration_min"`
+	// Violations lists the non-compliant and the never-ended returns, oldest
+	// first
+	Violations []ReturnToBaseViolation `json:"violations"`
+}
+
+// ReturnToBaseViolation is a return that does not prove compliance
+type ReturnToBaseViolation struct {
+	LogID           string             `json:"log_id"`
+	RideID          string             `json:"ride_id"`
+	Status          ReturnToBaseStatus `json:"status"`
+	ReturnStartedAt time.Time          `json:"return_started_at"`
+	Reason          string             `json:"reason"`
+}
+
+// openReturnViolation is the reason given for a return that was never ended
+const openReturnViolation = "return was never ended"
+
+// buildComplianceReport aggregates the driver's logs started in [from, to).
+// An open log counts as a violation: nothing shows the driver reached the
+// base.
+func buildComplianceReport(driverID string, from, to time.Time, logs []ReturnToBaseLog) ReturnToBaseComplianceReport {
+	report := ReturnToBaseComplianceReport{DriverID: driverID, From: from.UTC(), To: to.UTC(), Violations: []ReturnToBaseViolation{}}
+
+	sort.Slice(logs, func(i, j int) bool { return logs[i].ReturnStartedAt.Before(logs[j].ReturnStartedAt) })
+
+	var ended int
+	var total time.Duration
+	for _, l := range logs {
+		if l.DriverID != driverID || l.ReturnStartedAt.Before(from) || !l.ReturnStartedAt.Before(to) {
+			continue
+		}
+		report.Returns++
+
+		switch l.Status {
+		case ReturnToBaseCancelled:
+			report.Cancelled++
+		case ReturnToBaseOpen:
+			report.Open++
+			report.Violations = append(report.Violations, violationFor(l, openReturnViolation))
+		case ReturnToBaseEnded:
+			ended++
+			if l.ReturnEndedAt != nil {
+				total += l.ReturnEndedAt.Sub(l.ReturnStartedAt)
+			}
+			if l.Compliance {
+				report.Compliant++
+				continue
+			}
+			report.NonCompliant++
+			reason := "return was not direct"
+			if l.Verdict != nil {
+				reason = l.Verdict.Reason
+			}
+			report.Violations = append(report.Violations, violationFor(l, reason))
+		}
+	}
+
+	if ended > 0 {
+		report.AverageReturnDurationMin = roundMinutes(time.Duration(math.Round(float64(total) / float64(ended))))
+	}
+	return report
+}
+
+func violationFor(l ReturnToBaseLog, reason string) ReturnToBaseViolation {
+	return ReturnToBaseViolation{LogID: l.ID, RideID: l.RideID, Status: l.Status, ReturnStartedAt: l.ReturnStartedAt, Reason: reason}
+}
+
+// returnToBaseComplianceReportHandler serves
+// GET /return-to-base/driver/{driver_id}/compliance-report?from=&to= with
+// RFC 3339 bounds
+func returnToBaseComplianceReportHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	driverID := vars["driver_id"]
+
+	from, errFrom := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	to, errTo := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if errFrom != nil || errTo != nil || !to.After(from) {
+		http.Error(w, "from and to must be RFC 3339 timestamps with to after from", http.StatusBadRequest)
+		return
+	}
+
+	returnToBaseStore.mu.RLock()
+	logs := make([]ReturnToBaseLog, 0)
+	for _, l := range returnToBaseStore.logs {
+		if l.DriverID == driverID {
+			logs = append(logs, *l)
+		}
+	}
+	returnToBaseStore.mu.RUnlock()
+
+	report := buildComplianceReport(driverID, from, to, logs)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBuildComplianceReport(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	at := func(days int, minutes int) (time.Time, *time.Time) {
+		start := from.AddDate(0, 0, days)
+		end := start.Add(time.Duration(minutes) * time.Minute)
+		return start, &end
+	}
+
+	compliantStart, compliantEnd := at(1, 20)
+	lateStart, lateEnd := at(2, 40)
+	openStart, _ := at(3, 0)
+	cancelledStart, _ := at(4, 0)
+	outsideStart, outsideEnd := at(40, 10)
+	logs := []ReturnToBaseLog{
+		{ID: "rtb_open", RideID: "ride_3", DriverID: "driver-1", Status: ReturnToBaseOpen, ReturnStartedAt: openStart, Compliance: true},
+		{ID: "rtb_ok", RideID: "ride_1", DriverID: "driver-1", Status: ReturnToBaseEnded, ReturnStartedAt: compliantStart, ReturnEndedAt: compliantEnd, Compliance: true},
+		{ID: "rtb_late", RideID: "ride_2", DriverID: "driver-1", Status: ReturnToBaseEnded, ReturnStartedAt: lateStart, ReturnEndedAt: lateEnd, Verdict: &ReturnToBaseVerdict{Reason: "return took 40 min"}},
+		{ID: "rtb_cancelled", RideID: "ride_4", DriverID: "driver-1", Status: ReturnToBaseCancelled, ReturnStartedAt: cancelledStart},
+		{ID: "rtb_outside", RideID: "ride_5", DriverID: "driver-1", Status: ReturnToBaseEnded, ReturnStartedAt: outsideStart, ReturnEndedAt: outsideEnd, Compliance: true},
+		{ID: "rtb_other", RideID: "ride_6", DriverID: "driver-2", Status: ReturnToBaseOpen, ReturnStartedAt: openStart},
+	}
+
+	report := buildComplianceReport("driver-1", from, to, logs)
+	if report.Returns != 4 || report.Compliant != 1 || report.NonCompliant != 1 || report.Open != 1 || report.Cancelled != 1 {
+		t.Errorf("counts = %+v, want 4 returns: 1 compliant, 1 non-compliant, 1 open, 1 cancelled", report)
+	}
+	if report.AverageReturnDurationMin != 30 {
+		t.Errorf("average_return_duration_min = %v, want 30", report.AverageReturnDurationMin)
+	}
+	if len(report.Violations) != 2 || report.Violations[0].LogID != "rtb_late" || report.Violations[1].LogID != "rtb_open" {
+		t.Fatalf("violations = %+v, want the late return then the open one", report.Violations)
+	}
+	if report.Violations[0].Reason != "return took 40 min" || report.Violations[1].Reason != openReturnViolation {
+		t.Errorf("reasons = %q, %q", report.Violations[0].Reason, report.Violations[1].Reason)
+	}
+}
+
+func TestComplianceReportEndpoint(t *testing.T) {
+	resetStores(t)
+	openReturnToBase(t, "driver-1")
+
+	from := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	to := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	rec := doRequest(t, http.MethodGet, "/return-to-base/driver/driver-1/compliance-report?from="+from+"&to="+to, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var report ReturnToBaseComplianceReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Returns != 1 || report.Open != 1 || len(report.Violations) != 1 {
+		t.Errorf("report = %+v, want the open return flagged", report)
+	}
+
+	if rec := doRequest(t, http.MethodGet, "/return-to-base/driver/driver-1/compliance-report?from="+to+"&to="+from, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("reversed range: status = %d, want 400", rec.Code)
+	}
+}
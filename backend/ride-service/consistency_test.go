@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// checkRideConsistent reports an error if the timestamps and fields the ride
+// carries do not fit its status.
+func checkRideConsistent(ride Ride) string {
+	switch ride.Status {
+	case RideRequested:
+		if ride.MatchedAt != nil || ride.StartedAt != nil || ride.CompletedAt != nil || ride.DriverID != "" {
+			return "requested ride carries later transition fields"
+		}
+	case RideMatched:
+		if ride.MatchedAt == nil || ride.DriverID == "" || ride.StartedAt != nil || ride.CompletedAt != nil {
+			return "matched ride without match fields or with later ones"
+		}
+	case RideStarted:
+		if ride.MatchedAt == nil || ride.StartedAt == nil || ride.CompletedAt != nil {
+			return "started ride with inconsistent timestamps"
+		}
+	case RideCompleted:
+		if ride.MatchedAt == nil || ride.StartedAt == nil || ride.CompletedAt == nil || ride.ChargedFareEUR != ride.QuotedFareEUR {
+			return "completed ride with inconsistent timestamps or fare"
+		}
+	default:
+		return "unexpected status " + string(ride.Status)
+	}
+	return ""
+}
+
+func TestConcurrentTransitionsReturnConsistentRides(t *testing.T) {
+	resetStores(t)
+	router := newRouter()
+
+	for i := 0; i < 20; i++ {
+		ride := createTestRide(t, "rider-1")
+		calls := []struct{ method, path, body string }{
+			{http.MethodPut, "/rides/" + ride.ID + "/match", `{"driver_id": "driver-1"}`},
+			{http.MethodPut, "/rides/" + ride.ID + "/start", `{}`},
+			{http.MethodPut, "/rides/" + ride.ID + "/complete", `{}`},
+			{http.MethodGet, "/rides/" + ride.ID, ""},
+			{http.MethodGet, "/rides/reference/" + ride.Reference, ""},
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan string, 200)
+		for worker := 0; worker < 8; worker++ {
+			for _, c := range calls {
+				wg.Add(1)
+				go func(method, path, body string) {
+					defer wg.Done()
+					rec := httptest.NewRecorder()
+					router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+					if rec.Code != http.StatusOK {
+						return
+					}
+					var got Ride
+					if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+						errs <- method + " " + path + ": " + err.Error()
+						return
+					}
+					if msg := checkRideConsistent(got); msg != "" {
+						errs <- method + " " + path + ": " + msg
+					}
+				}(c.method, c.path, c.body)
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for msg := range errs {
+			t.Error(msg)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DriverRideSummary is a driver's current ride and completed-ride totals for
+// a period, as shown on the driver status dashboard
+type DriverRideSummary struct {
+	DriverID       string    `json:"driver_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	ActiveRide     *Ride     `json:"active_ride,omitempty"`
+	CompletedRides int       `json:"completed_rides"`
+	EarningsEUR    float64   `json:"earnings_eur"`
+}
+
+func (s *RideStore) SummarizeDriverRides(ctx context.Context, driverID string, from, to time.Time) (DriverRideSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rides := make([]Ride, 0)
+	for _, ride := range s.rides {
+		if ride.DriverID == driverID {
+			rides = append(rides, *ride)
+		}
+	}
+	return summarizeDriverRides(driverID, from, to, rides), nil
+}
+
+// summarizeDriverRides picks the driver's matched or started ride and totals
+// the rides completed in [from, to) out of rides. Earnings are the charged
+// fares.
+func summarizeDriverRides(driverID string, from, to time.Time, rides []Ride) DriverRideSummary {
+	summary := DriverRideSummary{DriverID: driverID, From: from.UTC(), To: to.UTC()}
+	var cents int64
+	for _, ride := range rides {
+		if ride.DriverID != driverID {
+			continue
+		}
+		switch ride.Status {
+		case RideMatched, RideStarted:
+			active := ride
+			summary.ActiveRide = &active
+		case RideCompleted:
+			if ride.CompletedAt != nil && !ride.CompletedAt.Before(from) && ride.CompletedAt.Before(to) {
+				summary.CompletedRides++
+				cents += toCents(ride.ChargedFareEUR)
+			}
+		}
+	}
+	summary.EarningsEUR = float64(cents) / 100
+	return summary
+}
+
+// driverRideSummaryHandler serves GET /rides/driver/{driver_id}/summary?from=&to=
+// with RFC 3339 bounds
+func driverRideSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	driverID := vars["driver_id"]
+
+	from, errFrom := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	to, errTo := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if errFrom != nil || errTo != nil || !to.After(from) {
+		http.Error(w, "from and to must be RFC 3339 timestamps with to after from", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := rideRepo.SummarizeDriverRides(r.Context(), driverID, from, to)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
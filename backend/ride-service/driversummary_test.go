@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDriverRideSummary(t *testing.T) {
+	resetStores(t)
+
+	completed := startTestRide(t)
+	if rec := completeTestRide(t, completed.ID, map[string]interface{}{}); rec.Code != http.StatusOK {
+		t.Fatalf("complete: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	active := startTestRide(t)
+
+	now := time.Now().UTC()
+	query := url.Values{
+		"from": {now.Add(-time.Hour).Format(time.RFC3339)},
+		"to":   {now.Add(time.Hour).Format(time.RFC3339)},
+	}
+	rec := doRequest(t, http.MethodGet, "/rides/driver/driver-1/summary?"+query.Encode(), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var summary DriverRideSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+	if summary.CompletedRides != 1 || summary.EarningsEUR != 28.50 {
+		t.Errorf("completed = %d, earnings = %.2f, want 1 and 28.50", summary.CompletedRides, summary.EarningsEUR)
+	}
+	if summary.ActiveRide == nil || summary.ActiveRide.ID != active.ID {
+		t.Errorf("active ride = %+v, want %s", summary.ActiveRide, active.ID)
+	}
+}
+
+func TestDriverRideSummaryRequiresPeriod(t *testing.T) {
+	if rec := doRequest(t, http.MethodGet, "/rides/driver/driver-1/summary", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
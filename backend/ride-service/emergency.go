@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// EmergencyEvent is a rider-triggered in-ride emergency. Events hold precise
+// location data and are only readable by safety staff; the ride itself only
+// carries the emergency flag.
+type EmergencyEvent struct {
+	ID          string     `json:"id"`
+	RideID      string     `json:"ride_id"`
+	RiderID     string     `json:"rider_id"`
+	DriverID    string     `json:"driver_id,omitempty"`
+	RideStatus  RideStatus `json:"ride_status"`
+	Lat         float64    `json:"lat"`
+	Lon         float64    `json:"lon"`
+	Note        string     `json:"note,omitempty"`
+	TriggeredAt time.Time  `json:"triggered_at"`
+	Priority    string     `json:"priority"`
+	Notified    bool       `json:"notified"`
+}
+
+type EmergencyStore struct {
+	mu     sync.RWMutex
+	byRide map[string][]*EmergencyEvent
+}
+
+// SafetyNotifier alerts safety staff about an emergency
+type SafetyNotifier interface {
+	NotifyEmergency(ctx context.Context, event EmergencyEvent) error
+}
+
+// logNotifier writes the alert to the service log. It is the default when no
+// alert webhook is configured.
+type logNotifier struct{}
+
+func (logNotifier) NotifyEmergency(ctx context.Context, event EmergencyEvent) error {
+	requestLogger(ctx).Warn("Emergency on ride", "category", "alert", "priority", "high", "emergency_id", event.ID, "ride_id", event.RideID, "rider_id", event.RiderID, "lat", event.Lat, "lon", event.Lon)
+	return nil
+}
+
+// webhookNotifier posts the event as JSON to the safety team's alerting endpoint
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n webhookNotifier) NotifyEmergency(ctx context.Context, event EmergencyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Priority", "high")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+var (
+	emergencyStore                = &EmergencyStore{byRide: make(map[string][]*EmergencyEvent)}
+	safetyNotifier SafetyNotifier = logNotifier{}
+)
+
+func init() {
+	if url := os.Getenv("SAFETY_ALERT_WEBHOOK_URL"); url != "" {
+		safetyNotifier = webhookNotifier{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+	}
+}
+
+// emergencyHandler records an emergency for an active (matched or started)
+// ride, flags the ride and alerts safety staff. The event is stored before
+// the alert is sent so a failing notifier never loses it.
+func emergencyHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var req struct {
+		RiderID string   `json:"rider_id"`
+		Lat     *float64 `json:"lat"`
+		Lon     *float64 `json:"lon"`
+		Note    string   `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.RiderID == "" || req.Lat == nil || req.Lon == nil {
+		http.Error(w, "rider_id and current location are required", http.StatusBadRequest)
+		return
+	}
+	lat, lon := *req.Lat, *req.Lon
+	if !validCoordinates(lat, lon) {
+		http.Error(w, "lat must be within [-90, 90] and lon within [-180, 180]", http.StatusBadRequest)
+		return
+	}
+
+	var event *EmergencyEvent
+	_, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.RiderID != req.RiderID {
+			requestLogger(r.Context()).Warn("Rejected emergency: rider is not the rider of this ride", "category", "audit.emergency", "ride_id", id, "rider_id", req.RiderID)
+			return rejectUpdate(http.StatusForbidden, "Rider does not belong to this ride")
+		}
+		if ride.Status != RideMatched && ride.Status != RideStarted {
+			return rejectUpdate(http.StatusConflict, "Emergency can only be raised for active rides, ride is %s", ride.Status)
+		}
+
+		event = &EmergencyEvent{
+			ID:          uuid.New().String(),
+			RideID:      ride.ID,
+			RiderID:     ride.RiderID,
+			DriverID:    ride.DriverID,
+			RideStatus:  ride.Status,
+			Lat:         lat,
+			Lon:         lon,
+			Note:        req.Note,
+			TriggeredAt: timeutil.Now(),
+			Priority:    "HIGH",
+		}
+		ride.EmergencyFlagged = true
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	emergencyStore.mu.Lock()
+	emergencyStore.byRide[event.RideID] = append(emergencyStore.byRide[event.RideID], event)
+	emergencyStore.mu.Unlock()
+
+	requestLogger(r.Context()).Info("Emergency raised", "category", "audit.emergency", "emergency_id", event.ID, "ride_id", event.RideID, "rider_id", event.RiderID, "driver_id", event.DriverID, "ride_status", event.RideStatus)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	notified := true
+	if err := safetyNotifier.NotifyEmergency(ctx, *event); err != nil {
+		notified = false
+		requestLogger(r.Context()).Error("Failed to notify safety staff about emergency", "category", "audit.emergency", "emergency_id", event.ID, "error", err)
+	}
+
+	emergencyStore.mu.Lock()
+	event.Notified = notified
+	resp := *event
+	emergencyStore.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// listEmergenciesHandler returns a ride's emergency events to safety staff.
+// Requests must carry X-Safety-Staff-Token matching SAFETY_STAFF_TOKEN; the
+// endpoint is disabled when no token is configured.
+func listEmergenciesHandler(w http.ResponseWriter, r *http.Request) {
+	token := os.Getenv("SAFETY_STAFF_TOKEN")
+	given := r.Header.Get("X-Safety-Staff-Token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	emergencyStore.mu.RLock()
+	events := make([]EmergencyEvent, 0, len(emergencyStore.byRide[id]))
+	for _, e := range emergencyStore.byRide[id] {
+		events = append(events, *e)
+	}
+	emergencyStore.mu.RUnlock()
+
+	requestLogger(r.Context()).Info("Safety staff accessed emergency events", "category", "audit.emergency", "ride_id", id, "count", len(events))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordingNotifier struct {
+	events []EmergencyEvent
+	err    error
+}
+
+func (n *recordingNotifier) NotifyEmergency(ctx context.Context, event EmergencyEvent) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func useNotifier(t *testing.T, n SafetyNotifier) {
+	t.Helper()
+	prev := safetyNotifier
+	safetyNotifier = n
+	t.Cleanup(func() { safetyNotifier = prev })
+}
+
+func raiseEmergency(t *testing.T, rideID, riderID string) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequest(t, http.MethodPost, "/rides/"+rideID+"/emergency", map[string]interface{}{
+		"rider_id": riderID,
+		"lat":      52.5163,
+		"lon":      13.3777,
+		"note":     "driver behaving aggressively",
+	})
+}
+
+func TestEmergencyOnActiveRide(t *testing.T) {
+	resetStores(t)
+	notifier := &recordingNotifier{}
+	useNotifier(t, notifier)
+
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+
+	rec := raiseEmergency(t, ride.ID, "rider-1")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var event EmergencyEvent
+	if err := json.NewDecoder(rec.Body).Decode(&event); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if event.DriverID != "driver-1" || event.Priority != "HIGH" || !event.Notified || event.TriggeredAt.IsZero() {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].ID != event.ID {
+		t.Errorf("safety staff not notified: %+v", notifier.events)
+	}
+
+	if !memoryStore(t).rides[ride.ID].EmergencyFlagged {
+		t.Error("ride not flagged")
+	}
+}
+
+func TestEmergencyRequiresActiveRide(t *testing.T) {
+	resetStores(t)
+	useNotifier(t, &recordingNotifier{})
+
+	ride := createTestRide(t, "rider-1")
+
+	if rec := raiseEmergency(t, ride.ID, "rider-1"); rec.Code != http.StatusConflict {
+		t.Errorf("requested ride: status = %d, want 409", rec.Code)
+	}
+	if rec := raiseEmergency(t, "missing", "rider-1"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown ride: status = %d, want 404", rec.Code)
+	}
+
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+	if rec := raiseEmergency(t, ride.ID, "rider-2"); rec.Code != http.StatusForbidden {
+		t.Errorf("other rider: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestEmergencyStoredWhenNotificationFails(t *testing.T) {
+	resetStores(t)
+	useNotifier(t, &recordingNotifier{err: errors.New("webhook down")})
+	t.Setenv("SAFETY_STAFF_TOKEN", "staff-secret")
+
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+
+	rec := raiseEmergency(t, ride.ID, "rider-1")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/rides/"+ride.ID+"/emergencies", nil)
+	list := httptest.NewRecorder()
+	newRouter().ServeHTTP(list, req)
+	if list.Code != http.StatusForbidden {
+		t.Errorf("without staff token: status = %d, want 403", list.Code)
+	}
+
+	req.Header.Set("X-Safety-Staff-Token", "staff-secret")
+	list = httptest.NewRecorder()
+	newRouter().ServeHTTP(list, req)
+	var events []EmergencyEvent
+	if err := json.NewDecoder(list.Body).Decode(&events); err != nil {
+		t.Fatalf("failed to decode events: %v", err)
+	}
+	if len(events) != 1 || events[0].Notified {
+		t.Errorf("expected one stored, un-notified event, got %+v", events)
+	}
+}
+
+func TestEmergencyLocationValidation(t *testing.T) {
+	resetStores(t)
+	useNotifier(t, &recordingNotifier{})
+
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+
+	for _, body := range []map[string]interface{}{
+		{"rider_id": "rider-1", "lon": 13.3777},
+		{"rider_id": "rider-1", "lat": 52.5163},
+		{"rider_id": "rider-1", "lat": 91.0, "lon": 13.3777},
+		{"rider_id": "rider-1", "lat": 52.5163, "lon": 180.5},
+	} {
+		if rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/emergency", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	// A zero coordinate is a location, not a missing one
+	rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/emergency", map[string]interface{}{"rider_id": "rider-1", "lat": 51.4779, "lon": 0.0})
+	if rec.Code != http.StatusCreated {
+		t.Errorf("lon 0: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
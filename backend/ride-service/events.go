@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Ride lifecycle event types
+const (
+	EventRideMatched   = "ride.matched"
+	EventRideRejected  = "ride.rejected"
+	EventRideStarted   = "ride.started"
+	EventRideCompleted = "ride.completed"
+	EventRideCancelled = "ride.cancelled"
+)
+
+// RideEvent tells other services (pricing, payment, notifications) about a
+// ride state transition. Ride is the ride after the transition.
+type RideEvent struct {
+	Type       string    `json:"type"`
+	RideID     string    `json:"ride_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Ride       Ride      `json:"ride"`
+}
+
+// EventPublisher delivers ride events. A NATS or Kafka publisher plugs in
+// here; until one is configured events are dropped by noopEventPublisher.
+type EventPublisher interface {
+	Publish(ctx context.Context, event RideEvent) error
+}
+
+type noopEventPublisher struct{}
+
+func (noopEventPublisher) Publish(ctx context.Context, event RideEvent) error {
+	return nil
+}
+
+var errEventBufferFull = errors.New("event buffer full")
+
+// ChannelEventPublisher writes events to Events, e.g. for tests. It never
+// blocks the handler: when the buffer is full the event is rejected.
+type ChannelEventPublisher struct {
+	Events chan RideEvent
+}
+
+func NewChannelEventPublisher(buffer int) *ChannelEventPublisher {
+	return &ChannelEventPublisher{Events: make(chan RideEvent, buffer)}
+}
+
+func (p *ChannelEventPublisher) Publish(ctx context.Context, event RideEvent) error {
+	select {
+	case p.Events <- event:
+		return nil
+	default:
+		return errEventBufferFull
+	}
+}
+
+var eventPublisher EventPublisher = noopEventPublisher{}
+
+// publishRideEvent publishes the event for a transition that has already been
+// stored. A failure is logged and does not undo the transition. Publishing
+// outlives the request, so a client hanging up does not drop the event.
+func publishRideEvent(ctx context.Context, eventType string, ride Ride) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	event := RideEvent{Type: eventType, RideID: ride.ID, OccurredAt: timeutil.Now(), Ride: ride}
+	if err := eventPublisher.Publish(ctx, event); err != nil {
+		requestLogger(ctx).Error("Failed to publish ride event", "event_type", eventType, "ride_id", ride.ID, "error", err)
+	}
+}
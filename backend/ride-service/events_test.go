@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// usePublisher installs p for the test
+func usePublisher(t *testing.T, p EventPublisher) {
+	t.Helper()
+	previous := eventPublisher
+	eventPublisher = p
+	t.Cleanup(func() { eventPublisher = previous })
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event RideEvent) error {
+	return errors.New("broker unavailable")
+}
+
+func TestRideLifecyclePublishesEvents(t *testing.T) {
+	resetStores(t)
+	events := NewChannelEventPublisher(10)
+	usePublisher(t, events)
+
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/start", nil)
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/complete", map[string]interface{}{})
+	cancelled := createTestRide(t, "rider-2")
+	cancelTestRide(t, cancelled.ID, CancelledByRider, CancelReasonRiderChangedPlans)
+	close(events.Events)
+
+	want := []struct {
+		eventType string
+		rideID    string
+		status    RideStatus
+	}{
+		{EventRideMatched, ride.ID, RideMatched},
+		{EventRideStarted, ride.ID, RideStarted},
+		{EventRideCompleted, ride.ID, RideCompleted},
+		{EventRideCancelled, cancelled.ID, RideCancelled},
+	}
+	var got []RideEvent
+	for event := range events.Events {
+		got = append(got, event)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d events published, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Type != w.eventType || got[i].RideID != w.rideID || got[i].Ride.Status != w.status {
+			t.Errorf("event %d = %s for %s (%s), want %s for %s (%s)", i, got[i].Type, got[i].RideID, got[i].Ride.Status, w.eventType, w.rideID, w.status)
+		}
+	}
+	if got[0].Ride.DriverID != "driver-1" {
+		t.Errorf("matched event carries driver %q, want driver-1", got[0].Ride.DriverID)
+	}
+}
+
+func TestRejectedTransitionPublishesNothing(t *testing.T) {
+	resetStores(t)
+	events := NewChannelEventPublisher(10)
+	usePublisher(t, events)
+
+	ride := createTestRide(t, "rider-1")
+	if rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/start", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("start before match: status = %d, want 400", rec.Code)
+	}
+	if len(events.Events) != 0 {
+		t.Errorf("%d events published for a rejected transition", len(events.Events))
+	}
+}
+
+func TestPublishFailureKeepsTransition(t *testing.T) {
+	resetStores(t)
+	usePublisher(t, failingPublisher{})
+
+	ride := createTestRide(t, "rider-1")
+	rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("match: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := memoryStore(t).rides[ride.ID].Status; got != RideMatched {
+		t.Errorf("status = %s, want %s", got, RideMatched)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// defaultRideRequestTimeout is how long a ride may wait in REQUESTED for a
+// driver before it expires
+const defaultRideRequestTimeout = 5 * time.Minute
+
+// rideExpirySweepInterval is how often the sweeper looks for stale requests
+const rideExpirySweepInterval = 30 * time.Second
+
+var rideRequestTimeout = defaultRideRequestTimeout
+
+// loadRideRequestTimeout reads RIDE_REQUEST_TIMEOUT, e.g. "5m"
+func loadRideRequestTimeout() (time.Duration, error) {
+	v := os.Getenv("RIDE_REQUEST_TIMEOUT")
+	if v == "" {
+		return defaultRideRequestTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("RIDE_REQUEST_TIMEOUT must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// ExpireRequested never touches rides past REQUESTED
+func (s *RideStore) ExpireRequested(ctx context.Context, now time.Time, timeout time.Duration) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := []string{}
+	for _, ride := range s.rides {
+		if ride.Status != RideRequested || now.Sub(ride.waitingSince()) < timeout {
+			continue
+		}
+		expiredAt := now
+		ride.Status = RideExpired
+		ride.ExpiredAt = &expiredAt
+		expired = append(expired, ride.ID)
+	}
+	return expired, nil
+}
+
+// waitingSince is when the ride started waiting for a driver: the booking
+// time, or the scheduled time for pre-booked rides
+func (r *Ride) waitingSince() time.Time {
+	if r.ScheduledAt != nil {
+		return *r.ScheduledAt
+	}
+	return r.RequestedAt
+}
+
+// runRideExpirySweeper expires stale ride requests every interval until ctx
+// is cancelled
+func runRideExpirySweeper(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := rideRepo.ExpireRequested(ctx, timeutil.Now(), timeout)
+			if err != nil {
+				logger.Error("Ride expiry sweep failed", "error", err)
+			}
+			for _, id := range expired {
+				logger.Info("Ride expired, no driver matched", "ride_id", id, "timeout", timeout.String())
+			}
+		}
+	}
+}
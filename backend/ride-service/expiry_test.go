@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExpireRequestedRides(t *testing.T) {
+	resetStores(t)
+	stale := createTestRide(t, "rider-1")
+	fresh := createTestRide(t, "rider-2")
+	matched := createTestRide(t, "rider-3")
+	doRequest(t, http.MethodPut, "/rides/"+matched.ID+"/match", map[string]string{"driver_id": "driver-1"})
+
+	now := time.Now().UTC()
+	memoryStore(t).rides[stale.ID].RequestedAt = now.Add(-6 * time.Minute)
+	memoryStore(t).rides[matched.ID].RequestedAt = now.Add(-6 * time.Minute)
+	memoryStore(t).rides[fresh.ID].RequestedAt = now.Add(-4 * time.Minute)
+
+	expired, err := rideRepo.ExpireRequested(context.Background(), now, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ExpireRequested failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != stale.ID {
+		t.Fatalf("expired = %v, want only the stale request", expired)
+	}
+	if ride := memoryStore(t).rides[stale.ID]; ride.Status != RideExpired || ride.ExpiredAt == nil {
+		t.Errorf("stale ride: status = %s, expired_at = %v", ride.Status, ride.ExpiredAt)
+	}
+	if got := memoryStore(t).rides[fresh.ID].Status; got != RideRequested {
+		t.Errorf("fresh ride status = %s, want REQUESTED", got)
+	}
+	if got := memoryStore(t).rides[matched.ID].Status; got != RideMatched {
+		t.Errorf("matched ride status = %s, want MATCHED", got)
+	}
+
+	// An expired ride can no longer be matched
+	if rec := doRequest(t, http.MethodPut, "/rides/"+stale.ID+"/match", map[string]string{"driver_id": "driver-1"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("match expired ride: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRideExpirySweeperStopsOnCancel(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runRideExpirySweeper(ctx, 5*time.Millisecond, time.Nanosecond)
+	}()
+
+	deadline := time.After(time.Second)
+	for {
+		if ride, _ := rideRepo.Get(context.Background(), ride.ID); ride.Status == RideExpired {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("sweeper did not expire the ride")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper did not stop after cancel")
+	}
+}
+
+func TestLoadRideRequestTimeout(t *testing.T) {
+	t.Setenv("RIDE_REQUEST_TIMEOUT", "")
+	if d, err := loadRideRequestTimeout(); err != nil || d != defaultRideRequestTimeout {
+		t.Errorf("default: got %v, %v", d, err)
+	}
+	t.Setenv("RIDE_REQUEST_TIMEOUT", "90s")
+	if d, err := loadRideRequestTimeout(); err != nil || d != 90*time.Second {
+		t.Errorf("90s: got %v, %v", d, err)
+	}
+	for _, v := range []string{"soon", "0s", "-1m"} {
+		t.Setenv("RIDE_REQUEST_TIMEOUT", v)
+		if _, err := loadRideRequestTimeout(); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
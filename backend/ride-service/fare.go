@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Fare locking: the fare charged on completion must equal the quoted fare to
+// the cent (zero tolerance). Surge or tariff changes after booking are never
+// applied to a booked ride. A driver-reported final fare that differs is
+// rejected and recorded as a FARE_DISCREPANCY audit event; the ride can then
+// be completed at the quoted fare. Legitimate changes (e.g. a rider-requested
+// detour) go through the dispute path, POST /rides/{id}/fare-adjustments,
+// which only admins may use and which is audited as FARE_ADJUSTED.
+const (
+	FareAuditDiscrepancy       = "FARE_DISCREPANCY"
+	FareAuditQuoteIntegrity    = "QUOTE_INTEGRITY_FAILURE"
+	FareAuditAdjusted          = "FARE_ADJUSTED"
+	fareAdjustmentRequiredRole = "admin"
+)
+
+// FareAuditEvent records a fare check or change on a ride
+type FareAuditEvent struct {
+	Type             string    `json:"type"`
+	QuotedFareEUR    float64   `json:"quoted_fare_eur"`
+	RequestedFareEUR float64   `json:"requested_fare_eur,omitempty"`
+	Actor            string    `json:"actor,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+	At               time.Time `json:"at"`
+}
+
+// toCents converts a EUR amount to integer cents so fares are compared exactly
+func toCents(eur float64) int64 {
+	return int64(math.Round(eur * 100))
+}
+
+// checkQuoteIntegrityLocked re-verifies the quote token stored on the ride and
+// confirms it still matches the quoted fare. The token may have expired since
+// booking, so only its signature is checked.
+func checkQuoteIntegrity(ride *Ride) error {
+	quote, err := quoteVerifier.VerifySignature(ride.QuoteToken)
+	if err != nil {
+		return err
+	}
+	if quote.ID != ride.QuoteID || toCents(quote.FareEUR) != toCents(ride.QuotedFareEUR) {
+		return fmt.Errorf("stored quote %s does not match the signed quote %s", ride.QuoteID, quote.ID)
+	}
+	return nil
+}
+
+// appendFareAudit appends an audit event to the ride and writes it to the
+// audit log. It is called from RideRepository.Update callbacks.
+func appendFareAudit(ride *Ride, event FareAuditEvent) {
+	ride.FareAudit = append(ride.FareAudit, event)
+	logger.Info("Fare audit event",
+		"category", "audit.fare",
+		"event_type", event.Type,
+		"ride_id", ride.ID,
+		"quoted_fare_eur", event.QuotedFareEUR,
+		"requested_fare_eur", event.RequestedFareEUR,
+		"actor", event.Actor,
+		"reason", event.Reason,
+	)
+}
+
+// recordFareAudit stores an audit event for a fare that was rejected. The
+// rejected update is not stored, so the event is added in an update of its own.
+func recordFareAudit(ctx context.Context, id string, event FareAuditEvent) {
+	_, err := rideRepo.Update(ctx, id, func(ride *Ride) error {
+		appendFareAudit(ride, event)
+		return nil
+	})
+	if err != nil {
+		requestLogger(ctx).Error("Failed to store fare audit event", "category", "audit.fare", "event_type", event.Type, "ride_id", id, "error", err)
+	}
+}
+
+// fareAdjustmentHandler is the dispute path for legitimate fare changes on a
+// completed ride. The gateway injects X-User-ID and X-User-Role; only admins
+// may adjust fares.
+func fareAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	actor := r.Header.Get("X-User-ID")
+	if actor == "" || r.Header.Get("X-User-Role") != fareAdjustmentRequiredRole {
+		http.Error(w, "Only admins can adjust fares", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		FareEUR float64 `json:"fare_eur"`
+		Reason  string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.FareEUR <= 0 || req.Reason == "" {
+		http.Error(w, "fare_eur must be positive and a reason is required", http.StatusBadRequest)
+		return
+	}
+
+	adjusted, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideCompleted {
+			return rejectUpdate(http.StatusBadRequest, "Fares can only be adjusted on completed rides, ride is %s", ride.Status)
+		}
+		appendFareAudit(ride, FareAuditEvent{
+			Type:             FareAuditAdjusted,
+			QuotedFareEUR:    ride.QuotedFareEUR,
+			RequestedFareEUR: req.FareEUR,
+			Actor:            actor,
+			Reason:           req.Reason,
+			At:               timeutil.Now(),
+		})
+		ride.ChargedFareEUR = math.Round(req.FareEUR*100) / 100
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adjusted)
+}
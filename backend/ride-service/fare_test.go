@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func completeTestRide(t *testing.T, id string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequest(t, http.MethodPut, "/rides/"+id+"/complete", body)
+}
+
+func TestCompleteRideChargesQuotedFare(t *testing.T) {
+	resetStores(t)
+	ride := startTestRide(t)
+
+	rec := completeTestRide(t, ride.ID, map[string]interface{}{"final_fare_eur": 28.50})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var completed Ride
+	if err := json.NewDecoder(rec.Body).Decode(&completed); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if completed.ChargedFareEUR != 28.50 || len(completed.FareAudit) != 0 {
+		t.Errorf("charged = %.2f, audit = %+v", completed.ChargedFareEUR, completed.FareAudit)
+	}
+}
+
+func TestCompleteRideRejectsFareDiscrepancy(t *testing.T) {
+	resetStores(t)
+	ride := startTestRide(t)
+
+	// One cent off is a discrepancy.
+	rec := completeTestRide(t, ride.ID, map[string]interface{}{"final_fare_eur": 28.51})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", rec.Code)
+	}
+
+	stored := memoryStore(t).rides[ride.ID]
+	if stored.Status != RideStarted || stored.ChargedFareEUR != 0 {
+		t.Errorf("ride must stay uncharged, got status %s charged %.2f", stored.Status, stored.ChargedFareEUR)
+	}
+	if len(stored.FareAudit) != 1 || stored.FareAudit[0].Type != FareAuditDiscrepancy || stored.FareAudit[0].RequestedFareEUR != 28.51 {
+		t.Errorf("unexpected audit %+v", stored.FareAudit)
+	}
+
+	// Completing without a final fare charges the quote.
+	if rec := completeTestRide(t, ride.ID, map[string]interface{}{}); rec.Code != http.StatusOK {
+		t.Fatalf("retry: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if stored.ChargedFareEUR != 28.50 {
+		t.Errorf("charged = %.2f, want 28.50", stored.ChargedFareEUR)
+	}
+}
+
+func TestCompleteRideRejectsTamperedQuote(t *testing.T) {
+	resetStores(t)
+	ride := startTestRide(t)
+	memoryStore(t).rides[ride.ID].QuotedFareEUR = 10.00
+
+	if rec := completeTestRide(t, ride.ID, map[string]interface{}{}); rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", rec.Code)
+	}
+	if audit := memoryStore(t).rides[ride.ID].FareAudit; len(audit) != 1 || audit[0].Type != FareAuditQuoteIntegrity {
+		t.Errorf("unexpected audit %+v", audit)
+	}
+}
+
+func TestFareAdjustmentRequiresAdmin(t *testing.T) {
+	resetStores(t)
+	ride := startTestRide(t)
+	completeTestRide(t, ride.ID, map[string]interface{}{})
+
+	adjust := func(role string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"fare_eur": 31.20, "reason": "Rider requested detour"})
+		req := httptest.NewRequest(http.MethodPost, "/rides/"+ride.ID+"/fare-adjustments", bytes.NewReader(body))
+		req.Header.Set("X-User-ID", "staff-1")
+		req.Header.Set("X-User-Role", role)
+		rec := httptest.NewRecorder()
+		newRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := adjust("driver"); rec.Code != http.StatusForbidden {
+		t.Errorf("driver: status = %d, want 403", rec.Code)
+	}
+
+	rec := adjust("admin")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var adjusted Ride
+	if err := json.NewDecoder(rec.Body).Decode(&adjusted); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if adjusted.ChargedFareEUR != 31.20 || adjusted.QuotedFareEUR != 28.50 {
+		t.Errorf("charged = %.2f quoted = %.2f", adjusted.ChargedFareEUR, adjusted.QuotedFareEUR)
+	}
+	if len(adjusted.FareAudit) != 1 || adjusted.FareAudit[0].Type != FareAuditAdjusted || adjusted.FareAudit[0].Actor != "staff-1" {
+		t.Errorf("unexpected audit %+v", adjusted.FareAudit)
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrAddressNotFound is returned when no candidate in Germany matches.
+	ErrAddressNotFound = errors.New("address not found")
+	// ErrOutsideGermany is returned for coordinates outside the service area.
+	ErrOutsideGermany = errors.New("location is outside Germany")
+)
+
+// Approximate bounding box of Germany. Providers are asked for German results
+// only; the box additionally guards against stray results and bad input.
+const (
+	germanyMinLat = 47.27
+	germanyMaxLat = 55.06
+	germanyMinLon = 5.87
+	germanyMaxLon = 15.04
+)
+
+func inGermany(lat, lon float64) bool {
+	return lat >= germanyMinLat && lat <= germanyMaxLat && lon >= germanyMinLon && lon <= germanyMaxLon
+}
+
+// GeoResult is a single geocoding candidate
+type GeoResult struct {
+	DisplayName string  `json:"display_name"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Street      string  `json:"street,omitempty"`
+	HouseNumber string  `json:"house_number,omitempty"`
+	Postcode    string  `json:"postcode,omitempty"`
+	City        string  `json:"city,omitempty"`
+}
+
+// Geocoder converts addresses to coordinates and back. Geocode returns all
+// candidates; more than one means the address is ambiguous.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) ([]GeoResult, error)
+	ReverseGeocode(ctx context.Context, lat, lon float64) (*GeoResult, error)
+}
+
+// MockGeocoder resolves a fixed set of addresses, for local development and tests
+type MockGeocoder struct {
+	entries []GeoResult
+}
+
+// NewMockGeocoder returns a MockGeocoder with a few Berlin addresses. Both
+// "Hauptstraße 1" entries match "Hauptstraße", which exercises the
+// ambiguous-address path.
+func NewMockGeocoder() *MockGeocoder {
+	return &MockGeocoder{entries: []GeoResult{
+		{DisplayName: "Alexanderplatz 1, 10178 Berlin", Lat: 52.5219, Lon: 13.4132, Street: "Alexanderplatz", HouseNumber: "1", Postcode: "10178", City: "Berlin"},
+		{DisplayName: "Europaplatz 1, 10557 Berlin", Lat: 52.5251, Lon: 13.3694, Street: "Europaplatz", HouseNumber: "1", Postcode: "10557", City: "Berlin"},
+		{DisplayName: "Hauptstraße 1, 10827 Berlin", Lat: 52.4895, Lon: 13.3551, Street: "Hauptstraße", HouseNumber: "1", Postcode: "10827", City: "Berlin"},
+		{DisplayName: "Hauptstraße 1, 14467 Potsdam", Lat: 52.3989, Lon: 13.0657, Street: "Hauptstraße", HouseNumber: "1", Postcode: "14467", City: "Potsdam"},
+	}}
+}
+
+func (m *MockGeocoder) Geocode(ctx context.Context, address string) ([]GeoResult, error) {
+	query := strings.ToLower(strings.TrimSpace(address))
+	var results []GeoResult
+	for _, e := range m.entries {
+		if strings.Contains(strings.ToLower(e.DisplayName), query) {
+			results = append(results, e)
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrAddressNotFound
+	}
+	return results, nil
+}
+
+func (m *MockGeocoder) ReverseGeocode(ctx context.Context, lat, lon float64) (*GeoResult, error) {
+	if !inGermany(lat, lon) {
+		return nil, ErrOutsideGermany
+	}
+	var nearest *GeoResult
+	best := 0.0
+	for i := range m.entries {
+		e := &m.entries[i]
+		d := (e.Lat-lat)*(e.Lat-lat) + (e.Lon-lon)*(e.Lon-lon)
+		if nearest == nil || d < best {
+			nearest, best = e, d
+		}
+	}
+	if nearest == nil {
+		return nil, ErrAddressNotFound
+	}
+	result := *nearest
+	return &result, nil
+}
+
+// NominatimGeocoder queries an OpenStreetMap Nominatim instance. The public
+// instance requires an identifying User-Agent and at most one request per
+// second, so it should always be wrapped in a CachingGeocoder.
+type NominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+}
+
+func NewNominatimGeocoder(baseURL, userAgent string) *NominatimGeocoder {
+	return &NominatimGeocoder{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type nominatimPlace struct {
+	DisplayName string `json:"display_name"`
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	Address     struct {
+		Road        string `json:"road"`
+		HouseNumber string `json:"house_number"`
+		Postcode    string `json:"postcode"`
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		CountryCode string `json:"country_code"`
+	} `json:"address"`
+}
+
+func (p nominatimPlace) toResult() (GeoResult, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return GeoResult{}, fmt.Errorf("invalid latitude %q", p.Lat)
+	}
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return GeoResult{}, fmt.Errorf("invalid longitude %q", p.Lon)
+	}
+
+	city := p.Address.City
+	if city == "" {
+		city = p.Address.Town
+	}
+	if city == "" {
+		city = p.Address.Village
+	}
+
+	return GeoResult{
+		DisplayName: p.DisplayName,
+		Lat:         lat,
+		Lon:         lon,
+		Street:      p.Address.Road,
+		HouseNumber: p.Address.HouseNumber,
+		Postcode:    p.Address.Postcode,
+		City:        city,
+	}, nil
+}
+
+func (n *NominatimGeocoder) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	params.Set("format", "jsonv2")
+	params.Set("addressdetails", "1")
+	params.Set("accept-language", "de")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", n.userAgent)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nominatim request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (n *NominatimGeocoder) Geocode(ctx context.Context, address string) ([]GeoResult, error) {
+	params := url.Values{}
+	params.Set("q", address)
+	params.Set("countrycodes", "de")
+	params.Set("limit", "5")
+
+	var places []nominatimPlace
+	if err := n.get(ctx, "/search", params, &places); err != nil {
+		return nil, err
+	}
+
+	var results []GeoResult
+	for _, p := range places {
+		result, err := p.toResult()
+		if err != nil || !inGermany(result.Lat, result.Lon) {
+			continue
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, ErrAddressNotFound
+	}
+	return results, nil
+}
+
+func (n *NominatimGeocoder) ReverseGeocode(ctx context.Context, lat, lon float64) (*GeoResult, error) {
+	if !inGermany(lat, lon) {
+		return nil, ErrOutsideGermany
+	}
+
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+
+	var place nominatimPlace
+	if err := n.get(ctx, "/reverse", params, &place); err != nil {
+		return nil, err
+	}
+	if place.DisplayName == "" {
+		return nil, ErrAddressNotFound
+	}
+	if place.Address.CountryCode != "" && place.Address.CountryCode != "de" {
+		return nil, ErrOutsideGermany
+	}
+
+	result, err := place.toResult()
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type geocodeCacheEntry struct {
+	results   []GeoResult
+	expiresAt time.Time
+}
+
+// CachingGeocoder caches results of another Geocoder and spaces out calls to
+// it by at least minInterval. Callers wait for their slot, bounded by ctx.
+type CachingGeocoder struct {
+	next        Geocoder
+	ttl         time.Duration
+	minInterval time.Duration
+
+	mu    sync.Mutex
+	cache map[string]geocodeCacheEntry
+
+	limiterMu sync.Mutex
+	nextCall  time.Time
+}
+
+func NewCachingGeocoder(next Geocoder, ttl, minInterval time.Duration) *CachingGeocoder {
+	return &CachingGeocoder{
+		next:        next,
+		ttl:         ttl,
+		minInterval: minInterval,
+		cache:       make(map[string]geocodeCacheEntry),
+	}
+}
+
+func (c *CachingGeocoder) lookup(key string) ([]GeoResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.cache, key)
+		return nil, false
+	}
+	return entry.results, true
+}
+
+func (c *CachingGeocoder) store(key string, results []GeoResult) {
+	c.mu.Lock()
+	c.cache[key] = geocodeCacheEntry{results: results, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+}
+
+// wait blocks until the rate limit allows another upstream call
+func (c *CachingGeocoder) wait(ctx context.Context) error {
+	c.limiterMu.Lock()
+	now := time.Now()
+	slot := c.nextCall
+	if slot.Before(now) {
+		slot = now
+	}
+	c.nextCall = slot.Add(c.minInterval)
+	c.limiterMu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *CachingGeocoder) Geocode(ctx context.Context, address string) ([]GeoResult, error) {
+	key := "fwd:" + strings.ToLower(strings.Join(strings.Fields(address), " "))
+	if results, ok := c.lookup(key); ok {
+		return results, nil
+	}
+
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	results, err := c.next.Geocode(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, results)
+	return results, nil
+}
+
+func (c *CachingGeocoder) ReverseGeocode(ctx context.Context, lat, lon float64) (*GeoResult, error) {
+	if !inGermany(lat, lon) {
+		return nil, ErrOutsideGermany
+	}
+
+	// Five decimals is roughly one metre, close enough to share a cache entry
+	key := fmt.Sprintf("rev:%.5f,%.5f", lat, lon)
+	if results, ok := c.lookup(key); ok {
+		result := results[0]
+		return &result, nil
+	}
+
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	result, err := c.next.ReverseGeocode(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, []GeoResult{*result})
+	return result, nil
+}
+
+// newGeocoderFromEnv builds the geocoder selected by GEOCODER (mock or
+// nominatim, default mock).
+func newGeocoderFromEnv() (Geocoder, error) {
+	ttl := 24 * time.Hour
+	if v := os.Getenv("GEOCODE_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("GEOCODE_CACHE_TTL must be a positive duration, got %q", v)
+		}
+		ttl = d
+	}
+
+	switch provider := os.Getenv("GEOCODER"); provider {
+	case "", "mock":
+		return NewCachingGeocoder(NewMockGeocoder(), ttl, 0), nil
+	case "nominatim":
+		baseURL := os.Getenv("NOMINATIM_URL")
+		if baseURL == "" {
+			baseURL = "https://nominatim.openstreetmap.org"
+		}
+		userAgent := os.Getenv("NOMINATIM_USER_AGENT")
+		if userAgent == "" {
+			return nil, errors.New("NOMINATIM_USER_AGENT is required for the nominatim geocoder")
+		}
+		return NewCachingGeocoder(NewNominatimGeocoder(baseURL, userAgent), ttl, time.Second), nil
+	default:
+		return nil, fmt.Errorf("unknown GEOCODER %q", provider)
+	}
+}
+
+// GeocodeResponse is returned by GET /geocode. Ambiguous is set when the
+// address matched more than one candidate and the rider has to pick one.
+type GeocodeResponse struct {
+	Query      string      `json:"query"`
+	Ambiguous  bool        `json:"ambiguous"`
+	Candidates []GeoResult `json:"candidates"`
+}
+
+func geocodeHandler(w http.ResponseWriter, r *http.Request) {
+	address := strings.TrimSpace(r.URL.Query().Get("address"))
+	if address == "" {
+		http.Error(w, "address parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	results, err := geocoder.Geocode(r.Context(), address)
+	if err != nil {
+		writeGeocodeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GeocodeResponse{
+		Query:      address,
+		Ambiguous:  len(results) > 1,
+		Candidates: results,
+	})
+}
+
+func reverseGeocodeHandler(w http.ResponseWriter, r *http.Request) {
+	lat, errLat := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lon, errLon := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+	if errLat != nil || errLon != nil {
+		http.Error(w, "lat and lon parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := geocoder.ReverseGeocode(r.Context(), lat, lon)
+	if err != nil {
+		writeGeocodeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeGeocodeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrAddressNotFound):
+		http.Error(w, "Address not found in Germany", http.StatusNotFound)
+	case errors.Is(err, ErrOutsideGermany):
+		http.Error(w, "Location is outside Germany", http.StatusBadRequest)
+	default:
+		logger.Error("Geocoding failed", "error", err)
+		http.Error(w, "Geocoding service unavailable", http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingGeocoder counts calls that reach the wrapped geocoder.
+type countingGeocoder struct {
+	Geocoder
+	calls int32
+}
+
+func (c *countingGeocoder) Geocode(ctx context.Context, address string) ([]GeoResult, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return c.Geocoder.Geocode(ctx, address)
+}
+
+func TestGeocodeEndpoint(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/geocode?address=Alexanderplatz", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp GeocodeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Ambiguous || len(resp.Candidates) != 1 || resp.Candidates[0].Postcode != "10178" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestGeocodeEndpointReturnsCandidatesForAmbiguousAddress(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/geocode?address=Hauptstra%C3%9Fe+1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var resp GeocodeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Ambiguous || len(resp.Candidates) != 2 {
+		t.Errorf("expected two ambiguous candidates, got %+v", resp)
+	}
+}
+
+func TestReverseGeocodeEndpoint(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/geocode/reverse?lat=52.5218&lon=13.4130", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var result GeoResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Street != "Alexanderplatz" {
+		t.Errorf("street = %q, want Alexanderplatz", result.Street)
+	}
+
+	// Paris is outside the service area.
+	if rec := doRequest(t, http.MethodGet, "/geocode/reverse?lat=48.8566&lon=2.3522", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("outside Germany: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateRideFromPickupAddress(t *testing.T) {
+	resetStores(t)
+
+	rec := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":       "rider-1",
+		"pickup_address": "Alexanderplatz 1",
+		"quote_token":    issueQuoteToken(t, 12.00),
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var ride Ride
+	if err := json.NewDecoder(rec.Body).Decode(&ride); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if ride.PickupLat != 52.5219 || ride.PickupLon != 13.4132 {
+		t.Errorf("pickup = %f,%f, want Alexanderplatz", ride.PickupLat, ride.PickupLon)
+	}
+
+	ambiguous := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":       "rider-1",
+		"pickup_address": "Hauptstraße 1",
+		"quote_token":    issueQuoteToken(t, 12.00),
+	})
+	if ambiguous.Code != http.StatusUnprocessableEntity {
+		t.Errorf("ambiguous address: status = %d, want 422", ambiguous.Code)
+	}
+}
+
+func TestCachingGeocoderCachesResults(t *testing.T) {
+	upstream := &countingGeocoder{Geocoder: NewMockGeocoder()}
+	c := NewCachingGeocoder(upstream, time.Minute, 0)
+
+	for _, address := range []string{"Alexanderplatz", "  alexanderplatz ", "ALEXANDERPLATZ"} {
+		if _, err := c.Geocode(context.Background(), address); err != nil {
+			t.Fatalf("geocode %q: %v", address, err)
+		}
+	}
+	if upstream.calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", upstream.calls)
+	}
+}
+
+func TestCachingGeocoderRateLimits(t *testing.T) {
+	upstream := &countingGeocoder{Geocoder: NewMockGeocoder()}
+	c := NewCachingGeocoder(upstream, time.Minute, time.Hour)
+
+	if _, err := c.Geocode(context.Background(), "Alexanderplatz"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+
+	// The next uncached call must wait an hour for its slot.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Geocode(ctx, "Europaplatz"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+
+	// Cached lookups are not rate limited.
+	if _, err := c.Geocode(context.Background(), "Alexanderplatz"); err != nil {
+		t.Errorf("cached call: %v", err)
+	}
+}
+
+func TestNominatimGeocoderRestrictsToGermany(t *testing.T) {
+	var gotQuery map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		if r.Header.Get("User-Agent") != "ride-share-test" {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		w.Write([]byte(`[
+			{"display_name": "Marienplatz 1, 80331 München", "lat": "48.1374", "lon": "11.5755",
+			 "address": {"road": "Marienplatz", "house_number": "1", "postcode": "80331", "city": "München", "country_code": "de"}},
+			{"display_name": "Stray result", "lat": "48.8566", "lon": "2.3522", "address": {"country_code": "fr"}}
+		]`))
+	}))
+	defer server.Close()
+
+	g := NewNominatimGeocoder(server.URL, "ride-share-test")
+	results, err := g.Geocode(context.Background(), "Marienplatz 1")
+	if err != nil {
+		t.Fatalf("geocode failed: %v", err)
+	}
+
+	if got := gotQuery["countrycodes"]; len(got) != 1 || got[0] != "de" {
+		t.Errorf("countrycodes = %v, want de", got)
+	}
+	if len(results) != 1 || results[0].City != "München" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PickupGeofence is the bounding box ride pickups must lie in. A disabled
+// geofence accepts any valid coordinates.
+type PickupGeofence struct {
+	Enabled bool
+	MinLat  float64
+	MinLon  float64
+	MaxLat  float64
+	MaxLon  float64
+}
+
+// germanyGeofence is the bounding box of Germany that geocoding results are
+// checked against
+func germanyGeofence() PickupGeofence {
+	return PickupGeofence{Enabled: true, MinLat: germanyMinLat, MinLon: germanyMinLon, MaxLat: germanyMaxLat, MaxLon: germanyMaxLon}
+}
+
+// loadPickupGeofence reads PICKUP_GEOFENCE: "off" disables the check, and
+// "min_lat,min_lon,max_lat,max_lon" replaces the Germany box
+func loadPickupGeofence() (PickupGeofence, error) {
+	v := os.Getenv("PICKUP_GEOFENCE")
+	switch v {
+	case "":
+		return germanyGeofence(), nil
+	case "off":
+		return PickupGeofence{}, nil
+	}
+
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return PickupGeofence{}, fmt.Errorf("PICKUP_GEOFENCE must be \"off\" or \"min_lat,min_lon,max_lat,max_lon\", got %q", v)
+	}
+	var bounds [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return PickupGeofence{}, fmt.Errorf("PICKUP_GEOFENCE must be \"off\" or \"min_lat,min_lon,max_lat,max_lon\", got %q", v)
+		}
+		bounds[i] = f
+	}
+	g := PickupGeofence{Enabled: true, MinLat: bounds[0], MinLon: bounds[1], MaxLat: bounds[2], MaxLon: bounds[3]}
+	if !validCoordinates(g.MinLat, g.MinLon) || !validCoordinates(g.MaxLat, g.MaxLon) || g.MinLat >= g.MaxLat || g.MinLon >= g.MaxLon {
+		return PickupGeofence{}, fmt.Errorf("PICKUP_GEOFENCE must have valid coordinates with min below max, got %q", v)
+	}
+	return g, nil
+}
+
+// approxCoordinate rounds a coordinate to one decimal, about 11 km, so a
+// rejected pickup can be logged by region without the rider's exact position
+func approxCoordinate(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// contains reports whether the geofence accepts a pickup at lat, lon
+func (g PickupGeofence) contains(lat, lon float64) bool {
+	if !g.Enabled {
+		return true
+	}
+	return lat >= g.MinLat && lat <= g.MaxLat && lon >= g.MinLon && lon <= g.MaxLon
+}
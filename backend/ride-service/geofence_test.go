@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func usePickupGeofence(t *testing.T, g PickupGeofence) {
+	t.Helper()
+	prev := pickupGeofence
+	pickupGeofence = g
+	t.Cleanup(func() { pickupGeofence = prev })
+}
+
+func createRideAt(t *testing.T, pickup map[string]interface{}) int {
+	t.Helper()
+	body := map[string]interface{}{"rider_id": "rider-1", "quote_token": issueQuoteToken(t, 28.50)}
+	for k, v := range pickup {
+		body[k] = v
+	}
+	return doRequest(t, http.MethodPost, "/rides", body).Code
+}
+
+func TestCreateRideAcceptsNullIsland(t *testing.T) {
+	resetStores(t)
+	usePickupGeofence(t, PickupGeofence{})
+
+	if code := createRideAt(t, map[string]interface{}{"pickup_lat": 0, "pickup_lon": 0}); code != http.StatusCreated {
+		t.Errorf("0,0 without geofence: status = %d, want 201", code)
+	}
+
+	// With the Germany geofence 0,0 is out of area, not a missing field
+	usePickupGeofence(t, germanyGeofence())
+	rec := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":    "rider-1",
+		"pickup_lat":  0,
+		"pickup_lon":  0,
+		"quote_token": issueQuoteToken(t, 28.50),
+	})
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "outside the service area") {
+		t.Errorf("0,0 with geofence: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateRideValidatesPickupCoordinates(t *testing.T) {
+	resetStores(t)
+	usePickupGeofence(t, germanyGeofence())
+
+	tests := []struct {
+		name   string
+		pickup map[string]interface{}
+		want   int
+	}{
+		{"berlin", map[string]interface{}{"pickup_lat": 52.52, "pickup_lon": 13.405}, http.StatusCreated},
+		{"paris", map[string]interface{}{"pickup_lat": 48.8566, "pickup_lon": 2.3522}, http.StatusUnprocessableEntity},
+		{"vienna", map[string]interface{}{"pickup_lat": 48.2082, "pickup_lon": 16.3738}, http.StatusUnprocessableEntity},
+		{"copenhagen", map[string]interface{}{"pickup_lat": 55.6761, "pickup_lon": 12.5683}, http.StatusUnprocessableEntity},
+		{"lat out of range", map[string]interface{}{"pickup_lat": 91, "pickup_lon": 13.405}, http.StatusBadRequest},
+		{"lon out of range", map[string]interface{}{"pickup_lat": 52.52, "pickup_lon": -180.5}, http.StatusBadRequest},
+		{"lon missing", map[string]interface{}{"pickup_lat": 52.52}, http.StatusBadRequest},
+		{"no pickup", map[string]interface{}{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := createRideAt(t, tt.pickup); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutOfAreaPickupIsLoggedApproximately(t *testing.T) {
+	resetStores(t)
+	usePickupGeofence(t, germanyGeofence())
+	var buf bytes.Buffer
+	saved := logger
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	t.Cleanup(func() { logger = saved })
+
+	if code := createRideAt(t, map[string]interface{}{"pickup_lat": 48.8566, "pickup_lon": 2.3522}); code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", code)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output is not JSON: %v (%q)", err, buf.String())
+	}
+	if record["approx_lat"] != 48.9 || record["approx_lon"] != 2.4 {
+		t.Errorf("record = %v, want the pickup rounded to one decimal", record)
+	}
+	if strings.Contains(buf.String(), "48.8566") || strings.Contains(buf.String(), "2.3522") {
+		t.Errorf("log contains the exact pickup: %s", buf.String())
+	}
+}
+
+func TestLoadPickupGeofence(t *testing.T) {
+	if g, err := loadPickupGeofence(); err != nil || g != germanyGeofence() {
+		t.Errorf("default = %+v, %v, want the Germany box", g, err)
+	}
+
+	t.Setenv("PICKUP_GEOFENCE", "off")
+	if g, err := loadPickupGeofence(); err != nil || g.Enabled || !g.contains(-33.87, 151.21) {
+		t.Errorf("off = %+v, %v, want a disabled geofence", g, err)
+	}
+
+	t.Setenv("PICKUP_GEOFENCE", "52.3, 13.0, 52.7, 13.8")
+	g, err := loadPickupGeofence()
+	if err != nil || !g.contains(52.52, 13.405) || g.contains(53.55, 9.99) {
+		t.Errorf("Berlin box = %+v, %v, want Berlin in and Hamburg out", g, err)
+	}
+
+	for _, v := range []string{"germany", "52.3,13.0,52.7", "52.7,13.0,52.3,13.8", "52.3,13.0,95,13.8", "a,b,c,d"} {
+		t.Setenv("PICKUP_GEOFENCE", v)
+		if _, err := loadPickupGeofence(); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Paging defaults and limits for GET /rides
+const (
+	defaultRideListLimit = 20
+	maxRideListLimit     = 100
+)
+
+var rideStatuses = map[RideStatus]bool{
+	RideRequested: true,
+	RideMatched:   true,
+	RideStarted:   true,
+	RideCompleted: true,
+	RideCancelled: true,
+	RideExpired:   true,
+	RideScheduled: true,
+}
+
+// RideList is one page of a rider's rides. Total counts all rides matching
+// the filter, not only those on this page.
+type RideList struct {
+	Rides  []Ride `json:"rides"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// ListByRider has no index by rider, so it collects the rider's rides and
+// sorts them; PostgresRideRepository pages in the query.
+func (s *RideStore) ListByRider(ctx context.Context, riderID string, status RideStatus, limit, offset int) ([]Ride, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matching := []*Ride{}
+	for _, ride := range s.rides {
+		if ride.RiderID == riderID && (status == "" || ride.Status == status) {
+			matching = append(matching, ride)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if !matching[i].RequestedAt.Equal(matching[j].RequestedAt) {
+			return matching[i].RequestedAt.After(matching[j].RequestedAt)
+		}
+		return matching[i].ID < matching[j].ID
+	})
+
+	total := len(matching)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	page := make([]Ride, 0, end-offset)
+	for _, ride := range matching[offset:end] {
+		page = append(page, *ride)
+	}
+	return page, total, nil
+}
+
+// listRidesHandler serves GET /rides?rider_id=&status=&limit=&offset=, the
+// rider's trip history
+func listRidesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	riderID := query.Get("rider_id")
+	if riderID == "" {
+		http.Error(w, "rider_id is required", http.StatusBadRequest)
+		return
+	}
+
+	status := RideStatus(query.Get("status"))
+	if status != "" && !rideStatuses[status] {
+		http.Error(w, fmt.Sprintf("Unknown status: %s", status), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultRideListLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRideListLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxRideListLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	rides, total, err := rideRepo.ListByRider(r.Context(), riderID, status, limit, offset)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RideList{Rides: rides, Total: total, Limit: limit, Offset: offset})
+}
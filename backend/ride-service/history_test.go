@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// createRiderHistory books n rides for riderID, requested one hour apart,
+// and returns their IDs oldest first.
+func createRiderHistory(t *testing.T, riderID string, n int) []string {
+	t.Helper()
+	base := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	ids := make([]string, n)
+	for i := range ids {
+		ride := createTestRide(t, riderID)
+		memoryStore(t).rides[ride.ID].RequestedAt = base.Add(time.Duration(i) * time.Hour)
+		ids[i] = ride.ID
+	}
+	return ids
+}
+
+func listTestRides(t *testing.T, query string) RideList {
+	t.Helper()
+	rec := doRequest(t, http.MethodGet, "/rides?"+query, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var list RideList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode ride list: %v", err)
+	}
+	return list
+}
+
+func TestListRidesByRiderNewestFirst(t *testing.T) {
+	resetStores(t)
+	ids := createRiderHistory(t, "rider-1", 5)
+	createTestRide(t, "rider-2")
+
+	list := listTestRides(t, "rider_id=rider-1&limit=2&offset=1")
+	if list.Total != 5 || list.Limit != 2 || list.Offset != 1 {
+		t.Errorf("total = %d, limit = %d, offset = %d", list.Total, list.Limit, list.Offset)
+	}
+	if len(list.Rides) != 2 || list.Rides[0].ID != ids[3] || list.Rides[1].ID != ids[2] {
+		t.Errorf("unexpected page %+v", list.Rides)
+	}
+}
+
+func TestListRidesOffsetBeyondTotal(t *testing.T) {
+	resetStores(t)
+	createRiderHistory(t, "rider-1", 2)
+
+	list := listTestRides(t, "rider_id=rider-1&offset=1000000")
+	if list.Total != 2 || len(list.Rides) != 0 {
+		t.Errorf("total = %d, rides = %d", list.Total, len(list.Rides))
+	}
+}
+
+func TestListRidesFiltersByStatus(t *testing.T) {
+	resetStores(t)
+	ids := createRiderHistory(t, "rider-1", 3)
+	cancelTestRide(t, ids[1], CancelledByRider, CancelReasonOther)
+
+	list := listTestRides(t, "rider_id=rider-1&status=CANCELLED")
+	if list.Total != 1 || len(list.Rides) != 1 || list.Rides[0].ID != ids[1] {
+		t.Errorf("unexpected cancelled rides %+v", list)
+	}
+}
+
+func TestListRidesValidatesQuery(t *testing.T) {
+	resetStores(t)
+	for _, query := range []string{
+		"",
+		"rider_id=rider-1&status=LOST",
+		"rider_id=rider-1&limit=0",
+		"rider_id=rider-1&limit=101",
+		"rider_id=rider-1&offset=-1",
+		"rider_id=rider-1&offset=99999999999999999999",
+	} {
+		if rec := doRequest(t, http.MethodGet, "/rides?"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -3,17 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/logging"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/quotetoken"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 )
 
 type RideStatus string
@@ -23,38 +31,101 @@ const (
 	RideMatched   RideStatus = "MATCHED"
 	RideStarted   RideStatus = "STARTED"
 	RideCompleted RideStatus = "COMPLETED"
+	RideCancelled RideStatus = "CANCELLED"
+	// RideExpired rides were not matched within the request timeout
+	RideExpired RideStatus = "EXPIRED"
+	// RideScheduled rides are pre-booked and become REQUESTED at ScheduledAt
+	RideScheduled RideStatus = "SCHEDULED"
 )
 
 type Ride struct {
 	ID            string     `json:"id"`
+	Reference     string     `json:"reference"`
 	RiderID       string     `json:"rider_id"`
 	DriverID      string     `json:"driver_id,omitempty"`
 	Status        RideStatus `json:"status"`
 	PickupLat     float64    `json:"pickup_lat"`
 	PickupLon     float64    `json:"pickup_lon"`
+	PickupAddress string     `json:"pickup_address,omitempty"`
 	DropoffLat    float64    `json:"dropoff_lat,omitempty"`
 	DropoffLon    float64    `json:"dropoff_lon,omitempty"`
+	// DistanceKm is the straight-line pickup to dropoff distance, recorded
+	// on completion when dropoff coordinates are given
+	DistanceKm    float64    `json:"distance_km,omitempty"`
 	RequestedAt   time.Time  `json:"requested_at"`
+	ScheduledAt   *time.Time `json:"scheduled_at,omitempty"`
 	MatchedAt     *time.Time `json:"matched_at,omitempty"`
 	StartedAt     *time.Time `json:"started_at,omitempty"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty"`
+	ExpiredAt     *time.Time `json:"expired_at,omitempty"`
 	ReturnToBase  bool       `json:"return_to_base"`
+	QuoteID       string     `json:"quote_id,omitempty"`
+	QuotedFareEUR float64    `json:"quoted_fare_eur,omitempty"`
+	QuoteToken    string     `json:"-"`
+	// QuoteEstimate is set when the fare is an api-gateway estimate booked
+	// while the pricing service was unavailable
+	QuoteEstimate bool `json:"quote_estimate,omitempty"`
+	// EmergencyFlagged is set when the rider raised an in-ride emergency
+	EmergencyFlagged bool `json:"emergency_flagged,omitempty"`
+	// Warnings lists data quality issues found when the ride was completed
+	Warnings []string `json:"warnings,omitempty"`
+	// ChargedFareEUR is the fare charged on completion, locked to QuotedFareEUR
+	// unless adjusted through the dispute path
+	ChargedFareEUR float64          `json:"charged_fare_eur,omitempty"`
+	FareAudit      []FareAuditEvent `json:"fare_audit,omitempty"`
+	// CancelledBy and CancelReasonCode are set when the ride is cancelled
+	CancelledBy      string `json:"cancelled_by,omitempty"`
+	CancelReasonCode string `json:"cancel_reason_code,omitempty"`
+	// Rating is the rider's rating, set once after completion
+	Rating *RideRating `json:"rating,omitempty"`
+	// DriverFirstName is set on match and is the only driver detail shown on
+	// shared trip views
+	DriverFirstName string `json:"driver_first_name,omitempty"`
+	// RejectedDriverIDs lists the drivers who declined the ride; the match
+	// endpoint refuses them. The ride-service does not call matching itself:
+	// whoever rematches the ride passes this list as exclude_driver_ids.
+	RejectedDriverIDs []string `json:"rejected_driver_ids,omitempty"`
 }
 
+// ReturnToBaseStatus is the lifecycle state of a return-to-base log. A log
+// starts OPEN and moves to exactly one of ENDED (driver arrived at base) or
+// CANCELLED (driver accepted a new ride on the way, §49 PBefG).
+type ReturnToBaseStatus string
+
+const (
+	ReturnToBaseOpen      ReturnToBaseStatus = "OPEN"
+	ReturnToBaseEnded     ReturnToBaseStatus = "ENDED"
+	ReturnToBaseCancelled ReturnToBaseStatus = "CANCELLED"
+)
+
 type ReturnToBaseLog struct {
-	ID              string    `json:"id"`
-	RideID          string    `json:"ride_id"`
-	DriverID        string    `json:"driver_id"`
-	ReturnStartedAt time.Time `json:"return_started_at"`
-	ReturnEndedAt   *time.Time `json:"return_ended_at,omitempty"`
-	BaseLat         float64   `json:"base_lat"`
-	BaseLon         float64   `json:"base_lon"`
-	Compliance      bool      `json:"compliance"`
+	ID              string             `json:"id"`
+	RideID          string             `json:"ride_id"`
+	DriverID        string             `json:"driver_id"`
+	Status          ReturnToBaseStatus `json:"status"`
+	ReturnStartedAt time.Time          `json:"return_started_at"`
+	ReturnEndedAt   *time.Time         `json:"return_ended_at,omitempty"`
+	CancelledAt     *time.Time         `json:"cancelled_at,omitempty"`
+	CancelReason    string             `json:"cancel_reason,omitempty"`
+	BaseLat         float64            `json:"base_lat"`
+	BaseLon         float64            `json:"base_lon"`
+	Compliance      bool               `json:"compliance"`
+	// Verdict explains Compliance once the log is ended
+	Verdict *ReturnToBaseVerdict `json:"verdict,omitempty"`
 }
 
+// RideStore is the in-memory RideRepository. Rides are lost on restart; set
+// DATABASE_URL to keep them in PostgreSQL instead.
 type RideStore struct {
 	mu    sync.RWMutex
 	rides map[string]*Ride
+	// usedQuotes maps quote IDs to the ride booked with them so a quote
+	// token cannot book twice.
+	usedQuotes map[string]string
+	// byReference maps human-readable ride references to ride IDs
+	byReference map[string]string
+	references  *RideReferenceGenerator
 }
 
 type ReturnToBaseStore struct {
@@ -62,16 +133,122 @@ type ReturnToBaseStore struct {
 	logs map[string]*ReturnToBaseLog
 }
 
+// openLogsLocked returns the driver's return-to-base logs that are still
+// open. Callers must hold s.mu.
+func (s *ReturnToBaseStore) openLogsLocked(driverID string) []*ReturnToBaseLog {
+	var open []*ReturnToBaseLog
+	for _, l := range s.logs {
+		if l.DriverID == driverID && l.Status == ReturnToBaseOpen {
+			open = append(open, l)
+		}
+	}
+	return open
+}
+
+// defaultMaxOpenReturnToBaseLogs is the number of return-to-base logs a
+// driver may have open at once. More than one open Rückkehrpflicht record
+// per driver indicates a data error.
+const defaultMaxOpenReturnToBaseLogs = 1
+
 var (
-	rideStore         *RideStore
+	rideRepo          RideRepository
 	returnToBaseStore *ReturnToBaseStore
-	logger            *log.Logger
+	logger            *slog.Logger
+	quoteVerifier     *quotetoken.Signer
+	geocoder          Geocoder
+	rideVerifier      RideVerifier
+	rideReferences    *RideReferenceGenerator
+	shortTrips        = defaultShortTripConfig()
+	pickupGeofence    = germanyGeofence()
+
+	maxOpenReturnToBaseLogs = defaultMaxOpenReturnToBaseLogs
 )
 
+// requestLogger returns the logger for the request ctx belongs to, or the
+// service logger outside of a request
+func requestLogger(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, logger)
+}
+
 func init() {
-	rideStore = &RideStore{rides: make(map[string]*Ride)}
 	returnToBaseStore = &ReturnToBaseStore{logs: make(map[string]*ReturnToBaseLog)}
-	logger = log.New(os.Stdout, "[RIDE-SERVICE] ", log.LstdFlags|log.Lshortfile)
+	logger = logging.New(os.Stdout, "ride-service")
+	slog.SetDefault(logger)
+
+	// Quote tokens are issued by the pricing-service with the same secret
+	verifier, usedDevSecret, err := quotetoken.NewSignerFromEnv()
+	if err != nil {
+		logger.Error("Invalid quote token configuration", "error", err)
+		os.Exit(1)
+	}
+	if usedDevSecret {
+		logger.Warn("Using development quote token secret. Set QUOTE_TOKEN_SECRET in production.")
+	}
+	quoteVerifier = verifier
+
+	geocoder, err = newGeocoderFromEnv()
+	if err != nil {
+		logger.Error("Invalid geocoder configuration", "error", err)
+		os.Exit(1)
+	}
+
+	prefix := os.Getenv("RIDE_REFERENCE_PREFIX")
+	if prefix == "" {
+		prefix = "R"
+	}
+	rideReferences = NewRideReferenceGenerator(prefix, 4)
+	rideRepo = NewRideStore(rideReferences)
+
+	shortTrips, err = loadShortTripConfig()
+	if err != nil {
+		logger.Error("Invalid short trip configuration", "error", err)
+		os.Exit(1)
+	}
+
+	pickupGeofence, err = loadPickupGeofence()
+	if err != nil {
+		logger.Error("Invalid pickup geofence configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if v := os.Getenv("MAX_OPEN_RETURN_TO_BASE_LOGS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("MAX_OPEN_RETURN_TO_BASE_LOGS must be a positive integer", "value", v)
+			os.Exit(1)
+		}
+		maxOpenReturnToBaseLogs = n
+	}
+
+	returnToBaseCheck, err = loadReturnToBaseCheckConfig()
+	if err != nil {
+		logger.Error("Invalid return-to-base check configuration", "error", err)
+		os.Exit(1)
+	}
+
+	maxDailyDriving, err = loadMaxDailyDriving()
+	if err != nil {
+		logger.Error("Invalid shift configuration", "error", err)
+		os.Exit(1)
+	}
+
+	rideVerifier, err = newRideVerifierFromEnv()
+	if err != nil {
+		logger.Error("Invalid ride verifier configuration", "error", err)
+		os.Exit(1)
+	}
+
+	rideRequestTimeout, err = loadRideRequestTimeout()
+	if err != nil {
+		logger.Error("Invalid ride request timeout", "error", err)
+		os.Exit(1)
+	}
+
+	tripShare, err = loadTripShareConfig()
+	if err != nil {
+		logger.Error("Invalid trip share configuration", "error", err)
+		os.Exit(1)
+	}
 }
 
 func main() {
@@ -80,16 +257,20 @@ func main() {
 		port = "8081"
 	}
 
-	router := mux.NewRouter()
-	router.HandleFunc("/health", healthHandler).Methods("GET")
-	router.HandleFunc("/rides", createRideHandler).Methods("POST")
-	router.HandleFunc("/rides/{id}", getRideHandler).Methods("GET")
-	router.HandleFunc("/rides/{id}/match", matchRideHandler).Methods("PUT")
-	router.HandleFunc("/rides/{id}/start", startRideHandler).Methods("PUT")
-	router.HandleFunc("/rides/{id}/complete", completeRideHandler).Methods("PUT")
-	router.HandleFunc("/return-to-base", createReturnToBaseHandler).Methods("POST")
-	router.HandleFunc("/return-to-base/{id}/end", endReturnToBaseHandler).Methods("PUT")
-	router.HandleFunc("/return-to-base/driver/{driver_id}", getReturnToBaseLogsHandler).Methods("GET")
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		repo, err := openPostgresRideRepository(dsn, rideReferences)
+		if err != nil {
+			logger.Error("Failed to open ride database", "error", err)
+			os.Exit(1)
+		}
+		defer repo.Close()
+		rideRepo = repo
+		logger.Info("Storing rides in PostgreSQL")
+	} else {
+		logger.Warn("DATABASE_URL not set, rides are kept in memory and lost on restart.")
+	}
+
+	router := newRouter()
 
 	srv := &http.Server{
 		Addr:         ":" + port,
@@ -99,10 +280,23 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		runRideExpirySweeper(workersCtx, rideExpirySweepInterval, rideRequestTimeout)
+	}()
+	go func() {
+		defer workers.Done()
+		runScheduledRideDispatcher(workersCtx, scheduledDispatchInterval)
+	}()
+
 	go func() {
-		logger.Printf("Starting ride-service on port %s", port)
+		logger.Info("Starting ride-service", "port", port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("Server failed to start: %v", err)
+			logger.Error("Server failed to start", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -110,73 +304,214 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Println("Shutting down server...")
+	logger.Info("Shutting down server...")
+	stopWorkers()
+	workers.Wait()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatalf("Server forced to shutdown: %v", err)
+		logger.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
-	logger.Println("Server exited")
+	logger.Info("Server exited")
+}
+
+// newRouter registers all ride-service routes
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/health", healthHandler).Methods("GET")
+	router.HandleFunc("/version", buildinfo.Handler("ride-service")).Methods("GET")
+	router.HandleFunc("/geocode", geocodeHandler).Methods("GET")
+	router.HandleFunc("/geocode/reverse", reverseGeocodeHandler).Methods("GET")
+	router.HandleFunc("/rides", createRideHandler).Methods("POST")
+	router.HandleFunc("/rides", listRidesHandler).Methods("GET")
+	router.HandleFunc("/rides/batch-get", batchGetRidesHandler).Methods("POST")
+	router.HandleFunc("/rides/{id}", getRideHandler).Methods("GET")
+	router.HandleFunc("/rides/reference/{reference}", getRideByReferenceHandler).Methods("GET")
+	router.HandleFunc("/rides/shared/{token}", getSharedRideHandler).Methods("GET")
+	router.HandleFunc("/rides/driver/{driver_id}/summary", driverRideSummaryHandler).Methods("GET")
+	router.HandleFunc("/rides/{id}/match", matchRideHandler).Methods("PUT")
+	router.HandleFunc("/rides/{id}/reject", rejectRideHandler).Methods("PUT")
+	router.HandleFunc("/rides/{id}/start", startRideHandler).Methods("PUT")
+	router.HandleFunc("/rides/{id}/complete", completeRideHandler).Methods("PUT")
+	router.HandleFunc("/rides/{id}/cancel", cancelRideHandler).Methods("PUT")
+	router.HandleFunc("/rides/{id}/rating", rateRideHandler).Methods("POST")
+	router.HandleFunc("/rides/{id}/rating", getRideRatingHandler).Methods("GET")
+	router.HandleFunc("/rides/{id}/fare-adjustments", fareAdjustmentHandler).Methods("POST")
+	router.HandleFunc("/rides/{id}/emergency", emergencyHandler).Methods("POST")
+	router.HandleFunc("/rides/{id}/emergencies", listEmergenciesHandler).Methods("GET")
+	router.HandleFunc("/rides/{id}/share", shareRideHandler).Methods("POST")
+	router.HandleFunc("/rides/{id}/location", updateRideLocationHandler).Methods("PUT")
+	router.HandleFunc("/return-to-base", createReturnToBaseHandler).Methods("POST")
+	router.HandleFunc("/return-to-base/{id}/end", endReturnToBaseHandler).Methods("PUT")
+	router.HandleFunc("/return-to-base/{id}/cancel", cancelReturnToBaseHandler).Methods("PUT")
+	router.HandleFunc("/return-to-base/driver/{driver_id}", getReturnToBaseLogsHandler).Methods("GET")
+	router.HandleFunc("/return-to-base/driver/{driver_id}/open", getOpenReturnToBaseLogHandler).Methods("GET")
+	router.HandleFunc("/return-to-base/driver/{driver_id}/compliance-report", returnToBaseComplianceReportHandler).Methods("GET")
+	router.HandleFunc("/shifts", createShiftHandler).Methods("POST")
+	router.HandleFunc("/shifts/{id}/end", endShiftHandler).Methods("PUT")
+	router.HandleFunc("/shifts/driver/{driver_id}/today", getTodayShiftsHandler).Methods("GET")
+	router.Use(logging.Middleware(logger))
+	return router
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status": "healthy",
-		"time":   time.Now().Format(time.RFC3339),
+		"time":   timeutil.Format(timeutil.Now()),
 	})
 }
 
 func createRideHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		RiderID    string  `json:"rider_id"`
-		PickupLat  float64 `json:"pickup_lat"`
-		PickupLon  float64 `json:"pickup_lon"`
+		RiderID string `json:"rider_id"`
+		// Pointers tell a missing coordinate from 0, which is valid
+		PickupLat *float64 `json:"pickup_lat"`
+		PickupLon *float64 `json:"pickup_lon"`
+		// PickupAddress is geocoded when no pickup coordinates are given
+		PickupAddress string `json:"pickup_address"`
+		QuoteToken    string `json:"quote_token"`
+		// ScheduledAt pre-books the ride for a future time
+		ScheduledAt *time.Time `json:"scheduled_at"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Printf("Error decoding request: %v", err)
+		requestLogger(r.Context()).Warn("Error decoding request", "error", err)
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	if req.RiderID == "" || req.PickupLat == 0 || req.PickupLon == 0 {
+	if req.RiderID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if req.ScheduledAt != nil {
+		now := timeutil.Now()
+		if !req.ScheduledAt.After(now) {
+			http.Error(w, "scheduled_at must be in the future", http.StatusBadRequest)
+			return
+		}
+		if req.ScheduledAt.Sub(now) > MaxScheduleAhead {
+			http.Error(w, fmt.Sprintf("scheduled_at must be at most %d days ahead", int(MaxScheduleAhead.Hours()/24)), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.PickupLat == nil && req.PickupLon == nil && req.PickupAddress != "" {
+		candidates, err := geocoder.Geocode(r.Context(), req.PickupAddress)
+		if err != nil {
+			writeGeocodeError(w, err)
+			return
+		}
+		// Never guess between candidates; the rider picks one and retries with coordinates
+		if len(candidates) > 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(GeocodeResponse{Query: req.PickupAddress, Ambiguous: true, Candidates: candidates})
+			return
+		}
+		req.PickupLat, req.PickupLon = &candidates[0].Lat, &candidates[0].Lon
+		req.PickupAddress = candidates[0].DisplayName
+	}
+
+	if req.PickupLat == nil || req.PickupLon == nil {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
+	pickupLat, pickupLon := *req.PickupLat, *req.PickupLon
+
+	if !validCoordinates(pickupLat, pickupLon) {
+		http.Error(w, "pickup_lat must be within [-90, 90] and pickup_lon within [-180, 180]", http.StatusBadRequest)
+		return
+	}
+
+	if !pickupGeofence.contains(pickupLat, pickupLon) {
+		requestLogger(r.Context()).Info("Pickup outside the service area", "rider_id", req.RiderID, "approx_lat", approxCoordinate(pickupLat), "approx_lon", approxCoordinate(pickupLon))
+		http.Error(w, fmt.Sprintf("Pickup at %.5f, %.5f is outside the service area", pickupLat, pickupLon), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Two-step booking: the ride must be confirmed with the token from the quote the rider saw
+	if req.QuoteToken == "" {
+		http.Error(w, "Quote token required", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := quoteVerifier.Verify(req.QuoteToken)
+	if errors.Is(err, quotetoken.ErrExpired) {
+		http.Error(w, "Quote expired, please request a new quote", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		requestLogger(r.Context()).Warn("Rejected quote token", "rider_id", req.RiderID, "error", err)
+		http.Error(w, "Invalid quote token", http.StatusBadRequest)
+		return
+	}
 
 	ride := &Ride{
-		ID:          uuid.New().String(),
-		RiderID:     req.RiderID,
-		Status:      RideRequested,
-		PickupLat:   req.PickupLat,
-		PickupLon:   req.PickupLon,
-		RequestedAt: time.Now(),
+		ID:            uuid.New().String(),
+		RiderID:       req.RiderID,
+		Status:        RideRequested,
+		PickupLat:     pickupLat,
+		PickupLon:     pickupLon,
+		PickupAddress: req.PickupAddress,
+		RequestedAt:   timeutil.Now(),
+		QuoteID:       quote.ID,
+		QuotedFareEUR: quote.FareEUR,
+		QuoteToken:    req.QuoteToken,
+		QuoteEstimate: quote.Estimate,
+	}
+	if req.ScheduledAt != nil {
+		scheduledAt := req.ScheduledAt.UTC()
+		ride.Status = RideScheduled
+		ride.ScheduledAt = &scheduledAt
 	}
 
-	rideStore.mu.Lock()
-	rideStore.rides[ride.ID] = ride
-	rideStore.mu.Unlock()
+	created, err := rideRepo.Create(r.Context(), *ride)
+	var used *QuoteUsedError
+	if errors.As(err, &used) {
+		http.Error(w, fmt.Sprintf("Quote already used for ride: %s", used.RideID), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
 
-	logger.Printf("Ride created: %s for rider: %s", ride.ID, ride.RiderID)
+	requestLogger(r.Context()).Info("Ride created", "ride_id", created.ID, "reference", created.Reference, "rider_id", created.RiderID, "quote_id", quote.ID, "fare_eur", quote.FareEUR, "estimate", quote.Estimate)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(ride)
+	json.NewEncoder(w).Encode(created)
 }
 
 func getRideHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	rideStore.mu.RLock()
-	ride, exists := rideStore.rides[id]
-	rideStore.mu.RUnlock()
+	ride, err := rideRepo.Get(r.Context(), id)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
 
-	if !exists {
-		http.Error(w, "Ride not found", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ride)
+}
+
+// getRideByReferenceHandler looks a ride up by its human-readable reference
+func getRideByReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	reference := vars["reference"]
+
+	ride, err := rideRepo.GetByReference(r.Context(), reference)
+	if err != nil {
+		writeRideError(w, err)
 		return
 	}
 
@@ -190,6 +525,8 @@ func matchRideHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		DriverID string `json:"driver_id"`
+		// DriverFirstName is shown on shared trip views
+		DriverFirstName string `json:"driver_first_name"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -202,59 +539,55 @@ func matchRideHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rideStore.mu.Lock()
-	ride, exists := rideStore.rides[id]
-	if !exists {
-		rideStore.mu.Unlock()
-		http.Error(w, "Ride not found", http.StatusNotFound)
-		return
-	}
-
-	if ride.Status != RideRequested {
-		rideStore.mu.Unlock()
-		http.Error(w, fmt.Sprintf("Cannot match ride in status: %s", ride.Status), http.StatusBadRequest)
+	matched, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideRequested {
+			return rejectUpdate(http.StatusBadRequest, "Cannot match ride in status: %s", ride.Status)
+		}
+		if ride.rejectedBy(req.DriverID) {
+			return rejectUpdate(http.StatusConflict, "Driver %s has declined this ride", req.DriverID)
+		}
+		now := timeutil.Now()
+		ride.DriverID = req.DriverID
+		ride.DriverFirstName = strings.TrimSpace(req.DriverFirstName)
+		ride.Status = RideMatched
+		ride.MatchedAt = &now
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
 		return
 	}
 
-	now := time.Now()
-	ride.DriverID = req.DriverID
-	ride.Status = RideMatched
-	ride.MatchedAt = &now
-	rideStore.mu.Unlock()
-
-	logger.Printf("Ride matched: %s with driver: %s", ride.ID, req.DriverID)
+	requestLogger(r.Context()).Info("Ride matched", "ride_id", matched.ID, "driver_id", req.DriverID)
+	publishRideEvent(r.Context(), EventRideMatched, matched)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ride)
+	json.NewEncoder(w).Encode(matched)
 }
 
 func startRideHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	rideStore.mu.Lock()
-	ride, exists := rideStore.rides[id]
-	if !exists {
-		rideStore.mu.Unlock()
-		http.Error(w, "Ride not found", http.StatusNotFound)
-		return
-	}
-
-	if ride.Status != RideMatched {
-		rideStore.mu.Unlock()
-		http.Error(w, fmt.Sprintf("Cannot start ride in status: %s", ride.Status), http.StatusBadRequest)
+	started, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideMatched {
+			return rejectUpdate(http.StatusBadRequest, "Cannot start ride in status: %s", ride.Status)
+		}
+		now := timeutil.Now()
+		ride.Status = RideStarted
+		ride.StartedAt = &now
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
 		return
 	}
 
-	now := time.Now()
-	ride.Status = RideStarted
-	ride.StartedAt = &now
-	rideStore.mu.Unlock()
-
-	logger.Printf("Ride started: %s", ride.ID)
+	requestLogger(r.Context()).Info("Ride started", "ride_id", started.ID)
+	publishRideEvent(r.Context(), EventRideStarted, started)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ride)
+	json.NewEncoder(w).Encode(started)
 }
 
 func completeRideHandler(w http.ResponseWriter, r *http.Request) {
@@ -262,9 +595,11 @@ func completeRideHandler(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	var req struct {
-		DropoffLat   float64 `json:"dropoff_lat"`
-		DropoffLon   float64 `json:"dropoff_lon"`
-		ReturnToBase bool    `json:"return_to_base"`
+		DropoffLat   *float64 `json:"dropoff_lat"`
+		DropoffLon   *float64 `json:"dropoff_lon"`
+		ReturnToBase bool     `json:"return_to_base"`
+		// FinalFareEUR is optional; if set it must equal the quoted fare
+		FinalFareEUR *float64 `json:"final_fare_eur"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -272,32 +607,75 @@ func completeRideHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rideStore.mu.Lock()
-	ride, exists := rideStore.rides[id]
-	if !exists {
-		rideStore.mu.Unlock()
-		http.Error(w, "Ride not found", http.StatusNotFound)
+	// The dropoff is optional, but a coordinate of 0 is still a coordinate
+	if (req.DropoffLat == nil) != (req.DropoffLon == nil) {
+		http.Error(w, "dropoff_lat and dropoff_lon must be given together", http.StatusBadRequest)
 		return
 	}
-
-	if ride.Status != RideStarted {
-		rideStore.mu.Unlock()
-		http.Error(w, fmt.Sprintf("Cannot complete ride in status: %s", ride.Status), http.StatusBadRequest)
+	hasDropoff := req.DropoffLat != nil
+	var dropoffLat, dropoffLon float64
+	if hasDropoff {
+		dropoffLat, dropoffLon = *req.DropoffLat, *req.DropoffLon
+	}
+	if hasDropoff && !validCoordinates(dropoffLat, dropoffLon) {
+		http.Error(w, "dropoff_lat must be within [-90, 90] and dropoff_lon within [-180, 180]", http.StatusBadRequest)
 		return
 	}
 
-	now := time.Now()
-	ride.Status = RideCompleted
-	ride.CompletedAt = &now
-	ride.DropoffLat = req.DropoffLat
-	ride.DropoffLon = req.DropoffLon
-	ride.ReturnToBase = req.ReturnToBase
-	rideStore.mu.Unlock()
+	// A rejected fare is audited even though the completion is not stored
+	var rejectedFare *FareAuditEvent
+	completed, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideStarted {
+			return rejectUpdate(http.StatusBadRequest, "Cannot complete ride in status: %s", ride.Status)
+		}
+
+		if err := checkQuoteIntegrity(ride); err != nil {
+			rejectedFare = &FareAuditEvent{Type: FareAuditQuoteIntegrity, QuotedFareEUR: ride.QuotedFareEUR, Reason: err.Error(), At: timeutil.Now()}
+			return rejectUpdate(http.StatusConflict, "Stored quote failed verification, ride cannot be charged")
+		}
 
-	logger.Printf("Ride completed: %s, return-to-base: %v", ride.ID, req.ReturnToBase)
+		if req.FinalFareEUR != nil && toCents(*req.FinalFareEUR) != toCents(ride.QuotedFareEUR) {
+			rejectedFare = &FareAuditEvent{Type: FareAuditDiscrepancy, QuotedFareEUR: ride.QuotedFareEUR, RequestedFareEUR: *req.FinalFareEUR, At: timeutil.Now()}
+			return rejectUpdate(http.StatusConflict, "Final fare %.2f EUR differs from the quoted fare %.2f EUR; complete at the quoted fare and file a fare adjustment for legitimate changes", *req.FinalFareEUR, ride.QuotedFareEUR)
+		}
+
+		if hasDropoff {
+			if tooShort, meters := shortTrips.tripTooShort(ride.PickupLat, ride.PickupLon, dropoffLat, dropoffLon); tooShort {
+				if shortTrips.Policy == ShortTripReject {
+					requestLogger(r.Context()).Warn("Ride completion rejected, dropoff too close to pickup", "ride_id", ride.ID, "distance_m", math.Round(meters))
+					return rejectUpdate(http.StatusUnprocessableEntity, "Dropoff is only %.0fm from pickup (minimum %.0fm)", meters, shortTrips.MinDistanceMeters)
+				}
+				requestLogger(r.Context()).Warn("Ride completed with dropoff close to pickup", "ride_id", ride.ID, "distance_m", math.Round(meters))
+				ride.Warnings = append(ride.Warnings, WarningDropoffNearPickup)
+			}
+		}
+
+		now := timeutil.Now()
+		ride.Status = RideCompleted
+		ride.CompletedAt = &now
+		ride.DropoffLat = dropoffLat
+		ride.DropoffLon = dropoffLon
+		ride.ReturnToBase = req.ReturnToBase
+		ride.ChargedFareEUR = ride.QuotedFareEUR
+		if hasDropoff {
+			ride.DistanceKm = tripDistanceKm(ride.PickupLat, ride.PickupLon, dropoffLat, dropoffLon)
+		}
+		return nil
+	})
+	if rejectedFare != nil {
+		recordFareAudit(r.Context(), id, *rejectedFare)
+	}
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Ride completed", "ride_id", completed.ID, "return_to_base", req.ReturnToBase)
+	publishRideEvent(r.Context(), EventRideCompleted, completed)
+	tripShares.ClearLocation(completed.ID)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ride)
+	json.NewEncoder(w).Encode(completed)
 }
 
 func createReturnToBaseHandler(w http.ResponseWriter, r *http.Request) {
@@ -318,31 +696,70 @@ func createReturnToBaseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A return to base starts from a ride the driver completed
+	if err := rideVerifier.VerifyCompletedRide(r.Context(), req.RideID, req.DriverID); err != nil {
+		if errors.Is(err, errRideVerificationFailed) {
+			requestLogger(r.Context()).Error("Cannot verify ride for return-to-base", "ride_id", req.RideID, "driver_id", req.DriverID, "error", err)
+			http.Error(w, "Cannot verify the ride right now, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		writeRideError(w, err)
+		return
+	}
+
 	rtbLog := &ReturnToBaseLog{
 		ID:              uuid.New().String(),
 		RideID:          req.RideID,
 		DriverID:        req.DriverID,
-		ReturnStartedAt: time.Now(),
+		Status:          ReturnToBaseOpen,
+		ReturnStartedAt: timeutil.Now(),
 		BaseLat:         req.BaseLat,
 		BaseLon:         req.BaseLon,
 		Compliance:      true,
 	}
 
+	// The limit check and the insert share one lock so concurrent requests
+	// cannot both open a log.
 	returnToBaseStore.mu.Lock()
+	if open := returnToBaseStore.openLogsLocked(req.DriverID); len(open) >= maxOpenReturnToBaseLogs {
+		returnToBaseStore.mu.Unlock()
+		requestLogger(r.Context()).Warn("Return-to-base rejected, too many open logs", "driver_id", req.DriverID, "open_logs", len(open), "limit", maxOpenReturnToBaseLogs)
+		http.Error(w, "Driver already has an open return-to-base log", http.StatusConflict)
+		return
+	}
 	returnToBaseStore.logs[rtbLog.ID] = rtbLog
 	returnToBaseStore.mu.Unlock()
 
-	logger.Printf("Return-to-base started: %s for ride: %s, driver: %s", rtbLog.ID, req.RideID, req.DriverID)
+	requestLogger(r.Context()).Info("Return-to-base started", "return_to_base_id", rtbLog.ID, "ride_id", req.RideID, "driver_id", req.DriverID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(rtbLog)
 }
 
+// endReturnToBaseHandler ends an open log and decides from the distance
+// between the ride's dropoff and the base whether the return was direct
 func endReturnToBaseHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	// The ride is read first so the two store locks are never held together
+	returnToBaseStore.mu.RLock()
+	var rideID string
+	if rtbLog, exists := returnToBaseStore.logs[id]; exists {
+		rideID = rtbLog.RideID
+	}
+	returnToBaseStore.mu.RUnlock()
+	var ride *Ride
+	snapshot, err := rideRepo.Get(r.Context(), rideID)
+	switch {
+	case err == nil:
+		ride = &snapshot
+	case !errors.Is(err, ErrRideNotFound):
+		writeRideError(w, err)
+		return
+	}
+
 	returnToBaseStore.mu.Lock()
 	rtbLog, exists := returnToBaseStore.logs[id]
 	if !exists {
@@ -351,20 +768,75 @@ func endReturnToBaseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if rtbLog.ReturnEndedAt != nil {
+	if rtbLog.Status != ReturnToBaseOpen {
 		returnToBaseStore.mu.Unlock()
-		http.Error(w, "Return-to-base already ended", http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Return-to-base is %s, only open logs can be ended", rtbLog.Status), http.StatusBadRequest)
 		return
 	}
 
-	now := time.Now()
+	now := timeutil.Now()
+	verdict := returnToBaseCheck.assess(ride, rtbLog.BaseLat, rtbLog.BaseLon, now.Sub(rtbLog.ReturnStartedAt))
+	rtbLog.Status = ReturnToBaseEnded
 	rtbLog.ReturnEndedAt = &now
+	rtbLog.Compliance = verdict.Compliant
+	rtbLog.Verdict = &verdict
+	ended := *rtbLog
 	returnToBaseStore.mu.Unlock()
 
-	logger.Printf("Return-to-base ended: %s for driver: %s", rtbLog.ID, rtbLog.DriverID)
+	if verdict.Compliant {
+		requestLogger(r.Context()).Info("Return-to-base ended", "return_to_base_id", ended.ID, "driver_id", ended.DriverID)
+	} else {
+		requestLogger(r.Context()).Warn("Return-to-base not compliant", "return_to_base_id", ended.ID, "driver_id", ended.DriverID, "reason", verdict.Reason)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(rtbLog)
+	json.NewEncoder(w).Encode(ended)
+}
+
+// cancelReturnToBaseHandler cancels an open return-to-base, e.g. when the
+// driver is dispatched to a new ride on the way back. A cancelled return is
+// not a completed one: ReturnEndedAt stays unset.
+func cancelReturnToBaseHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		http.Error(w, "Cancellation reason is required", http.StatusBadRequest)
+		return
+	}
+
+	returnToBaseStore.mu.Lock()
+	rtbLog, exists := returnToBaseStore.logs[id]
+	if !exists {
+		returnToBaseStore.mu.Unlock()
+		http.Error(w, "Return-to-base log not found", http.StatusNotFound)
+		return
+	}
+
+	if rtbLog.Status != ReturnToBaseOpen {
+		returnToBaseStore.mu.Unlock()
+		http.Error(w, fmt.Sprintf("Return-to-base is %s, only open logs can be cancelled", rtbLog.Status), http.StatusBadRequest)
+		return
+	}
+
+	now := timeutil.Now()
+	rtbLog.Status = ReturnToBaseCancelled
+	rtbLog.CancelledAt = &now
+	rtbLog.CancelReason = req.Reason
+	cancelled := *rtbLog
+	returnToBaseStore.mu.Unlock()
+
+	requestLogger(r.Context()).Info("Return-to-base cancelled", "return_to_base_id", cancelled.ID, "driver_id", cancelled.DriverID, "reason", cancelled.CancelReason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelled)
 }
 
 func getReturnToBaseLogsHandler(w http.ResponseWriter, r *http.Request) {
@@ -372,14 +844,37 @@ func getReturnToBaseLogsHandler(w http.ResponseWriter, r *http.Request) {
 	driverID := vars["driver_id"]
 
 	returnToBaseStore.mu.RLock()
-	var logs []*ReturnToBaseLog
+	var logs []ReturnToBaseLog
 	for _, log := range returnToBaseStore.logs {
 		if log.DriverID == driverID {
-			logs = append(logs, log)
+			logs = append(logs, *log)
 		}
 	}
 	returnToBaseStore.mu.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(logs)
-}
\ No newline at end of file
+}
+
+// getOpenReturnToBaseLogHandler returns the driver's open return-to-base
+// logs, or 404 if the driver has none.
+func getOpenReturnToBaseLogHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	driverID := vars["driver_id"]
+
+	// Copy the logs under the lock; end and cancel update them in place
+	returnToBaseStore.mu.RLock()
+	open := []ReturnToBaseLog{}
+	for _, l := range returnToBaseStore.openLogsLocked(driverID) {
+		open = append(open, *l)
+	}
+	returnToBaseStore.mu.RUnlock()
+
+	if len(open) == 0 {
+		http.Error(w, "No open return-to-base log for driver", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(open)
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/quotetoken"
+)
+
+// resetStores gives each test empty ride and return-to-base stores.
+func resetStores(t *testing.T) {
+	t.Helper()
+	rideRepo = NewRideStore(rideReferences)
+	returnToBaseStore = &ReturnToBaseStore{logs: make(map[string]*ReturnToBaseLog)}
+	emergencyStore = &EmergencyStore{byRide: make(map[string][]*EmergencyEvent)}
+	tripShares = NewTripShareStore()
+	rideVerifier = stubRideVerifier{}
+	shiftStore = NewShiftStore()
+}
+
+// stubRideVerifier answers every ride verification with err, so tests can
+// open return-to-base logs without completing a ride first
+type stubRideVerifier struct {
+	err error
+}
+
+func (s stubRideVerifier) VerifyCompletedRide(ctx context.Context, rideID, driverID string) error {
+	return s.err
+}
+
+// memoryStore returns the in-memory repository so tests can set up ride state
+// that no endpoint produces, e.g. a request time in the past.
+func memoryStore(t *testing.T) *RideStore {
+	t.Helper()
+	store, ok := rideRepo.(*RideStore)
+	if !ok {
+		t.Fatalf("ride repository is %T, want *RideStore", rideRepo)
+	}
+	return store
+}
+
+// issueQuoteToken returns a valid quote token as the pricing-service would.
+func issueQuoteToken(t *testing.T, fare float64) string {
+	t.Helper()
+	token, _, err := quoteVerifier.Issue(quotetoken.Quote{FareEUR: fare, Currency: "EUR", DistanceKm: 10, DurationMin: 20})
+	if err != nil {
+		t.Fatalf("failed to issue quote token: %v", err)
+	}
+	return token
+}
+
+// doRequest sends a JSON request through the ride-service router.
+func doRequest(t *testing.T, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(method, path, &buf))
+	return rec
+}
+
+// createTestRide books a ride in Berlin Mitte and returns it.
+func createTestRide(t *testing.T, riderID string) Ride {
+	t.Helper()
+	rec := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":    riderID,
+		"pickup_lat":  52.5200,
+		"pickup_lon":  13.4050,
+		"quote_token": issueQuoteToken(t, 28.50),
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create ride: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var ride Ride
+	if err := json.NewDecoder(rec.Body).Decode(&ride); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	return ride
+}
+
+func TestCreateRideWithQuoteToken(t *testing.T) {
+	resetStores(t)
+
+	ride := createTestRide(t, "rider-1")
+
+	if ride.Status != RideRequested {
+		t.Errorf("status = %s, want %s", ride.Status, RideRequested)
+	}
+	if ride.QuoteID == "" || ride.QuotedFareEUR != 28.50 {
+		t.Errorf("ride not bound to quote: id=%q fare=%.2f", ride.QuoteID, ride.QuotedFareEUR)
+	}
+}
+
+func TestCreateRideWithEstimateToken(t *testing.T) {
+	resetStores(t)
+
+	// The api-gateway signs fare estimates while the pricing service is down
+	token, _, err := quoteVerifier.Issue(quotetoken.Quote{FareEUR: 31.20, Currency: "EUR", DistanceKm: 10, DurationMin: 20, Estimate: true})
+	if err != nil {
+		t.Fatalf("failed to issue estimate token: %v", err)
+	}
+	rec := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":    "rider-1",
+		"pickup_lat":  52.5200,
+		"pickup_lon":  13.4050,
+		"quote_token": token,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body = %s", rec.Code, rec.Body.String())
+	}
+	var ride Ride
+	if err := json.NewDecoder(rec.Body).Decode(&ride); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if !ride.QuoteEstimate || ride.QuotedFareEUR != 31.20 {
+		t.Errorf("ride not recorded as estimate booking: estimate=%v fare=%.2f", ride.QuoteEstimate, ride.QuotedFareEUR)
+	}
+
+	created := createTestRide(t, "rider-2")
+	if created.QuoteEstimate {
+		t.Error("pricing-service quote recorded as estimate")
+	}
+}
+
+func TestCreateRideRequiresQuoteToken(t *testing.T) {
+	resetStores(t)
+
+	rec := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":   "rider-1",
+		"pickup_lat": 52.5200,
+		"pickup_lon": 13.4050,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateRideRejectsTamperedAndExpiredTokens(t *testing.T) {
+	resetStores(t)
+
+	expiredSigner, err := quotetoken.NewSigner([]byte(quotetoken.DevSecret), time.Nanosecond)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	expired, _, _ := expiredSigner.Issue(quotetoken.Quote{FareEUR: 28.50})
+	time.Sleep(time.Millisecond)
+
+	otherSigner, _ := quotetoken.NewSigner([]byte("some-other-secret-value"), time.Minute)
+	forged, _, _ := otherSigner.Issue(quotetoken.Quote{FareEUR: 1.00})
+
+	for name, token := range map[string]string{"expired": expired, "forged": forged, "garbage": "not-a-token"} {
+		rec := doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+			"rider_id":    "rider-1",
+			"pickup_lat":  52.5200,
+			"pickup_lon":  13.4050,
+			"quote_token": token,
+		})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s token: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestCreateRideRejectsReusedQuoteToken(t *testing.T) {
+	resetStores(t)
+
+	body := map[string]interface{}{
+		"rider_id":    "rider-1",
+		"pickup_lat":  52.5200,
+		"pickup_lon":  13.4050,
+		"quote_token": issueQuoteToken(t, 28.50),
+	}
+	if rec := doRequest(t, http.MethodPost, "/rides", body); rec.Code != http.StatusCreated {
+		t.Fatalf("first booking: status = %d", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodPost, "/rides", body); rec.Code != http.StatusConflict {
+		t.Errorf("second booking with same token: status = %d, want 409", rec.Code)
+	}
+}
+
+func openReturnToBase(t *testing.T, driverID string) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequest(t, http.MethodPost, "/return-to-base", map[string]interface{}{
+		"ride_id":   "ride-1",
+		"driver_id": driverID,
+		"base_lat":  52.5200,
+		"base_lon":  13.4050,
+	})
+}
+
+func TestReturnToBaseRejectsSecondOpenLog(t *testing.T) {
+	resetStores(t)
+
+	first := openReturnToBase(t, "driver-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first log: status = %d", first.Code)
+	}
+	if rec := openReturnToBase(t, "driver-1"); rec.Code != http.StatusConflict {
+		t.Errorf("second open log: status = %d, want 409", rec.Code)
+	}
+
+	// Other drivers are unaffected.
+	if rec := openReturnToBase(t, "driver-2"); rec.Code != http.StatusCreated {
+		t.Errorf("other driver: status = %d, want 201", rec.Code)
+	}
+
+	// Once the first log ends the driver may open a new one.
+	var rtbLog ReturnToBaseLog
+	if err := json.NewDecoder(first.Body).Decode(&rtbLog); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	if rec := doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/end", nil); rec.Code != http.StatusOK {
+		t.Fatalf("end log: status = %d", rec.Code)
+	}
+	if rec := openReturnToBase(t, "driver-1"); rec.Code != http.StatusCreated {
+		t.Errorf("after ending: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestReturnToBaseConcurrentOpen(t *testing.T) {
+	resetStores(t)
+
+	const attempts = 20
+	codes := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- openReturnToBase(t, "driver-1").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d open logs, want exactly 1", created)
+	}
+}
+
+func TestGetOpenReturnToBaseLog(t *testing.T) {
+	resetStores(t)
+
+	if rec := doRequest(t, http.MethodGet, "/return-to-base/driver/driver-1/open", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no open log: status = %d, want 404", rec.Code)
+	}
+
+	openReturnToBase(t, "driver-1")
+
+	rec := doRequest(t, http.MethodGet, "/return-to-base/driver/driver-1/open", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var logs []ReturnToBaseLog
+	if err := json.NewDecoder(rec.Body).Decode(&logs); err != nil {
+		t.Fatalf("failed to decode logs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].DriverID != "driver-1" || logs[0].ReturnEndedAt != nil {
+		t.Errorf("unexpected open logs %+v", logs)
+	}
+}
+
+func TestGetOpenReturnToBaseLogDuringCancel(t *testing.T) {
+	resetStores(t)
+
+	var rtbLog ReturnToBaseLog
+	if err := json.NewDecoder(openReturnToBase(t, "driver-1").Body).Decode(&rtbLog); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+
+	// Run with -race: the read must not encode the log the cancel updates
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/cancel", map[string]string{"reason": "shift ended"})
+	}()
+	go func() {
+		defer wg.Done()
+		doRequest(t, http.MethodGet, "/return-to-base/driver/driver-1/open", nil)
+		doRequest(t, http.MethodGet, "/return-to-base/driver/driver-1", nil)
+	}()
+	wg.Wait()
+}
+
+func TestCancelReturnToBase(t *testing.T) {
+	resetStores(t)
+
+	var rtbLog ReturnToBaseLog
+	if err := json.NewDecoder(openReturnToBase(t, "driver-1").Body).Decode(&rtbLog); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	if rtbLog.Status != ReturnToBaseOpen {
+		t.Fatalf("new log status = %s, want OPEN", rtbLog.Status)
+	}
+
+	if rec := doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/cancel", map[string]string{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("cancel without reason: status = %d, want 400", rec.Code)
+	}
+
+	rec := doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/cancel", map[string]string{"reason": "new ride dispatched"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var cancelled ReturnToBaseLog
+	if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	if cancelled.Status != ReturnToBaseCancelled || cancelled.CancelledAt == nil || cancelled.CancelReason != "new ride dispatched" {
+		t.Errorf("unexpected cancelled log %+v", cancelled)
+	}
+	if cancelled.ReturnEndedAt != nil {
+		t.Error("cancelled return must not be marked as ended")
+	}
+
+	// Cancelled logs can be neither ended nor cancelled again, and no longer
+	// count as open.
+	if rec := doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/end", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("end after cancel: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/cancel", map[string]string{"reason": "again"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("cancel twice: status = %d, want 400", rec.Code)
+	}
+	if rec := openReturnToBase(t, "driver-1"); rec.Code != http.StatusCreated {
+		t.Errorf("open after cancel: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestCancelEndedReturnToBase(t *testing.T) {
+	resetStores(t)
+
+	var rtbLog ReturnToBaseLog
+	if err := json.NewDecoder(openReturnToBase(t, "driver-1").Body).Decode(&rtbLog); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/end", nil)
+
+	if rec := doRequest(t, http.MethodPut, "/return-to-base/"+rtbLog.ID+"/cancel", map[string]string{"reason": "too late"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("cancel after end: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodPut, "/return-to-base/missing/cancel", map[string]string{"reason": "x"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown log: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//go:embed schema.sql
+var rideSchema string
+
+// rideBookingLockKey is the transaction-scoped advisory lock taken by Create.
+// Bookings are serialised so the quote check and the daily reference number
+// stay correct across replicas.
+const rideBookingLockKey = 4907
+
+// PostgresRideRepository is the RideRepository backed by PostgreSQL, see
+// schema.sql. Every state transition runs in a transaction that locks the
+// ride row, so a crash mid-update leaves the ride as it was before.
+type PostgresRideRepository struct {
+	db         *sql.DB
+	references *RideReferenceGenerator
+}
+
+func NewPostgresRideRepository(db *sql.DB, references *RideReferenceGenerator) *PostgresRideRepository {
+	return &PostgresRideRepository{db: db, references: references}
+}
+
+// openPostgresRideRepository connects to dsn and applies schema.sql
+func openPostgresRideRepository(dsn string, references *RideReferenceGenerator) (*PostgresRideRepository, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, rideSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying schema: %w", err)
+	}
+	return NewPostgresRideRepository(db, references), nil
+}
+
+func (p *PostgresRideRepository) Close() error {
+	return p.db.Close()
+}
+
+func (p *PostgresRideRepository) Create(ctx context.Context, ride Ride) (Ride, error) {
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, rideBookingLockKey); err != nil {
+			return err
+		}
+
+		var existing string
+		err := tx.QueryRowContext(ctx, `SELECT id FROM rides WHERE quote_id = $1`, ride.QuoteID).Scan(&existing)
+		if err == nil {
+			return &QuoteUsedError{RideID: existing}
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// References count the rides booked on the same UTC day
+		day := ride.RequestedAt.UTC().Truncate(24 * time.Hour)
+		var booked int
+		err = tx.QueryRowContext(ctx, `SELECT count(*) FROM rides WHERE requested_at >= $1 AND requested_at < $2`,
+			day, day.Add(24*time.Hour)).Scan(&booked)
+		if err != nil {
+			return err
+		}
+		ride.Reference = p.references.Format(ride.RequestedAt, booked+1)
+
+		data, err := json.Marshal(ride)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO rides (id, reference, rider_id, driver_id, status, quote_id, quote_token, requested_at, scheduled_at, completed_at, data)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			ride.ID, ride.Reference, ride.RiderID, ride.DriverID, ride.Status, ride.QuoteID, ride.QuoteToken,
+			ride.RequestedAt, ride.ScheduledAt, ride.CompletedAt, string(data))
+		return err
+	})
+	if err != nil {
+		return Ride{}, err
+	}
+	return ride, nil
+}
+
+func (p *PostgresRideRepository) Get(ctx context.Context, id string) (Ride, error) {
+	return scanRide(p.db.QueryRowContext(ctx, `SELECT data, quote_token FROM rides WHERE id = $1`, id))
+}
+
+func (p *PostgresRideRepository) GetByReference(ctx context.Context, reference string) (Ride, error) {
+	return scanRide(p.db.QueryRowContext(ctx, `SELECT data, quote_token FROM rides WHERE reference = $1`, reference))
+}
+
+func (p *PostgresRideRepository) GetMany(ctx context.Context, ids []string) ([]Ride, []string, error) {
+	found, err := queryRides(ctx, p.db, `SELECT data, quote_token FROM rides WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]Ride, len(found))
+	for _, ride := range found {
+		byID[ride.ID] = ride
+	}
+
+	rides := make([]Ride, 0, len(ids))
+	missing := []string{}
+	for _, id := range ids {
+		ride, exists := byID[id]
+		if !exists {
+			missing = append(missing, id)
+			continue
+		}
+		rides = append(rides, ride)
+	}
+	return rides, missing, nil
+}
+
+func (p *PostgresRideRepository) ListByRider(ctx context.Context, riderID string, status RideStatus, limit, offset int) ([]Ride, int, error) {
+	var total int
+	err := p.db.QueryRowContext(ctx, `SELECT count(*) FROM rides WHERE rider_id = $1 AND ($2::text = '' OR status = $2)`,
+		riderID, status).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rides, err := queryRides(ctx, p.db, `
+		SELECT data, quote_token FROM rides
+		WHERE rider_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY requested_at DESC, id
+		LIMIT $3 OFFSET $4`,
+		riderID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rides, total, nil
+}
+
+func (p *PostgresRideRepository) Update(ctx context.Context, id string, fn func(ride *Ride) error) (Ride, error) {
+	var updated Ride
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		ride, err := scanRide(tx.QueryRowContext(ctx, `SELECT data, quote_token FROM rides WHERE id = $1 FOR UPDATE`, id))
+		if err != nil {
+			return err
+		}
+		if err := fn(&ride); err != nil {
+			return err
+		}
+		if err := updateRide(ctx, tx, ride); err != nil {
+			return err
+		}
+		updated = ride
+		return nil
+	})
+	if err != nil {
+		return Ride{}, err
+	}
+	return updated, nil
+}
+
+// ExpireRequested skips rides another transaction has locked; they are either
+// being matched or picked up by the next sweep
+func (p *PostgresRideRepository) ExpireRequested(ctx context.Context, now time.Time, timeout time.Duration) ([]string, error) {
+	return p.transitionWhere(ctx, `
+		SELECT data, quote_token FROM rides
+		WHERE status = 'REQUESTED' AND COALESCE(scheduled_at, requested_at) <= $1
+		FOR UPDATE SKIP LOCKED`,
+		now.Add(-timeout), func(ride *Ride) {
+			expiredAt := now
+			ride.Status = RideExpired
+			ride.ExpiredAt = &expiredAt
+		})
+}
+
+// PromoteDue locks the rides it promotes, so concurrent dispatchers on other
+// replicas skip them
+func (p *PostgresRideRepository) PromoteDue(ctx context.Context, now time.Time) ([]string, error) {
+	return p.transitionWhere(ctx, `
+		SELECT data, quote_token FROM rides
+		WHERE status = 'SCHEDULED' AND scheduled_at <= $1
+		FOR UPDATE SKIP LOCKED`,
+		now, func(ride *Ride) {
+			ride.Status = RideRequested
+		})
+}
+
+func (p *PostgresRideRepository) SummarizeDriverRides(ctx context.Context, driverID string, from, to time.Time) (DriverRideSummary, error) {
+	rides, err := queryRides(ctx, p.db, `
+		SELECT data, quote_token FROM rides
+		WHERE driver_id = $1
+		  AND (status IN ('MATCHED', 'STARTED') OR (status = 'COMPLETED' AND completed_at >= $2 AND completed_at < $3))`,
+		driverID, from, to)
+	if err != nil {
+		return DriverRideSummary{}, err
+	}
+	return summarizeDriverRides(driverID, from, to, rides), nil
+}
+
+// transitionWhere applies transition to every ride selected by query, which
+// must lock the rows it returns, and stores them in one transaction
+func (p *PostgresRideRepository) transitionWhere(ctx context.Context, query string, arg interface{}, transition func(ride *Ride)) ([]string, error) {
+	ids := []string{}
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		rides, err := queryRides(ctx, tx, query, arg)
+		if err != nil {
+			return err
+		}
+		for _, ride := range rides {
+			transition(&ride)
+			if err := updateRide(ctx, tx, ride); err != nil {
+				return err
+			}
+			ids = append(ids, ride.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// inTx runs fn in a transaction that is committed if fn returns nil and
+// rolled back otherwise
+func (p *PostgresRideRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateRide writes the ride and the columns copied from it
+func updateRide(ctx context.Context, tx *sql.Tx, ride Ride) error {
+	data, err := json.Marshal(ride)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rides SET driver_id = $2, status = $3, scheduled_at = $4, completed_at = $5, data = $6
+		WHERE id = $1`,
+		ride.ID, ride.DriverID, ride.Status, ride.ScheduledAt, ride.CompletedAt, string(data))
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type rideQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// scanRide reads a ride selected as (data, quote_token)
+func scanRide(row rowScanner) (Ride, error) {
+	var data, token string
+	if err := row.Scan(&data, &token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Ride{}, ErrRideNotFound
+		}
+		return Ride{}, err
+	}
+
+	var ride Ride
+	if err := json.Unmarshal([]byte(data), &ride); err != nil {
+		return Ride{}, fmt.Errorf("decoding stored ride: %w", err)
+	}
+	ride.QuoteToken = token
+	return ride, nil
+}
+
+func queryRides(ctx context.Context, q rideQuerier, query string, args ...interface{}) ([]Ride, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rides := []Ride{}
+	for rows.Next() {
+		ride, err := scanRide(rows)
+		if err != nil {
+			return nil, err
+		}
+		rides = append(rides, ride)
+	}
+	return rides, rows.Err()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// maxRatingCommentLength caps rating comments, counted in characters
+const maxRatingCommentLength = 1000
+
+// RideRating is the rider's rating of a completed ride
+type RideRating struct {
+	Stars   int       `json:"stars"`
+	Comment string    `json:"comment,omitempty"`
+	RatedAt time.Time `json:"rated_at"`
+}
+
+// rateRideHandler stores the rating of a completed ride. A ride is rated
+// once; a second rating is rejected.
+func rateRideHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var req struct {
+		Stars   *int   `json:"stars"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Stars == nil || *req.Stars < 1 || *req.Stars > 5 {
+		http.Error(w, "stars must be an integer from 1 to 5", http.StatusBadRequest)
+		return
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > maxRatingCommentLength {
+		http.Error(w, fmt.Sprintf("comment must be at most %d characters", maxRatingCommentLength), http.StatusBadRequest)
+		return
+	}
+
+	rating := RideRating{Stars: *req.Stars, Comment: comment, RatedAt: timeutil.Now()}
+	_, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideCompleted {
+			return rejectUpdate(http.StatusBadRequest, "Only completed rides can be rated, ride is %s", ride.Status)
+		}
+		if ride.Rating != nil {
+			return rejectUpdate(http.StatusConflict, "Ride has already been rated")
+		}
+		ride.Rating = &rating
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Ride rated", "ride_id", id, "stars", rating.Stars)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rating)
+}
+
+func getRideRatingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	ride, err := rideRepo.Get(r.Context(), id)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+	if ride.Rating == nil {
+		http.Error(w, "Ride has not been rated", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ride.Rating)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func completedTestRide(t *testing.T) Ride {
+	t.Helper()
+	ride := startTestRide(t)
+	if rec := completeTestRide(t, ride.ID, map[string]interface{}{}); rec.Code != http.StatusOK {
+		t.Fatalf("complete: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	return ride
+}
+
+func TestRateCompletedRide(t *testing.T) {
+	resetStores(t)
+	ride := completedTestRide(t)
+
+	rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/rating", map[string]interface{}{"stars": 5, "comment": "  Friendly driver  "})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, http.MethodGet, "/rides/"+ride.ID+"/rating", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get rating: status = %d", rec.Code)
+	}
+	var rating RideRating
+	if err := json.NewDecoder(rec.Body).Decode(&rating); err != nil {
+		t.Fatalf("failed to decode rating: %v", err)
+	}
+	if rating.Stars != 5 || rating.Comment != "Friendly driver" || rating.RatedAt.IsZero() {
+		t.Errorf("unexpected rating %+v", rating)
+	}
+
+	if rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/rating", map[string]interface{}{"stars": 1}); rec.Code != http.StatusConflict {
+		t.Errorf("second rating: status = %d, want 409", rec.Code)
+	}
+}
+
+func TestRateRideValidation(t *testing.T) {
+	resetStores(t)
+	ride := completedTestRide(t)
+
+	for _, body := range []map[string]interface{}{
+		{},
+		{"stars": 0},
+		{"stars": 6},
+		{"stars": 4.5},
+		{"stars": 3, "comment": strings.Repeat("ä", maxRatingCommentLength+1)},
+	} {
+		if rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/rating", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	// Exactly the limit is accepted
+	body := map[string]interface{}{"stars": 3, "comment": strings.Repeat("ä", maxRatingCommentLength)}
+	if rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/rating", body); rec.Code != http.StatusCreated {
+		t.Errorf("comment at limit: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestRateRideRequiresCompletion(t *testing.T) {
+	resetStores(t)
+	ride := startTestRide(t)
+
+	if rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/rating", map[string]interface{}{"stars": 4}); rec.Code != http.StatusBadRequest {
+		t.Errorf("started ride: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodGet, "/rides/"+ride.ID+"/rating", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unrated ride: status = %d, want 404", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodPost, "/rides/missing/rating", map[string]interface{}{"stars": 4}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown ride: status = %d, want 404", rec.Code)
+	}
+}
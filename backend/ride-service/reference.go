@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RideReferenceGenerator hands out short, human-readable ride references
+// such as "R-20240501-0042" for support calls. The counter restarts every
+// UTC day, so references are unique as long as fewer than 10^width rides
+// are booked per day; the counter simply widens beyond that.
+type RideReferenceGenerator struct {
+	mu      sync.Mutex
+	prefix  string
+	width   int
+	day     string
+	counter int
+}
+
+func NewRideReferenceGenerator(prefix string, width int) *RideReferenceGenerator {
+	return &RideReferenceGenerator{prefix: prefix, width: width}
+}
+
+// Next returns the next reference for the day of now
+func (g *RideReferenceGenerator) Next(now time.Time) string {
+	day := now.UTC().Format("20060102")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if day != g.day {
+		g.day = day
+		g.counter = 0
+	}
+	g.counter++
+	return g.Format(now, g.counter)
+}
+
+// Format returns the reference for the n-th ride booked on the day of t.
+// PostgresRideRepository numbers rides from the database instead of the
+// in-memory counter, so numbering survives restarts and works across
+// replicas.
+func (g *RideReferenceGenerator) Format(t time.Time, n int) string {
+	return fmt.Sprintf("%s-%s-%0*d", g.prefix, t.UTC().Format("20060102"), g.width, n)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRideReferenceGeneratorFormatAndRollover(t *testing.T) {
+	g := NewRideReferenceGenerator("R", 4)
+	day := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+
+	if got := g.Next(day); got != "R-20240501-0001" {
+		t.Errorf("first reference = %q", got)
+	}
+	if got := g.Next(day); got != "R-20240501-0002" {
+		t.Errorf("second reference = %q", got)
+	}
+	if got := g.Next(day.Add(2 * time.Minute)); got != "R-20240502-0001" {
+		t.Errorf("reference after midnight = %q, want counter reset", got)
+	}
+}
+
+func TestRideReferenceGeneratorConcurrent(t *testing.T) {
+	g := NewRideReferenceGenerator("R", 4)
+	now := time.Now()
+
+	const n = 500
+	refs := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			refs <- g.Next(now)
+		}()
+	}
+	wg.Wait()
+	close(refs)
+
+	seen := make(map[string]bool, n)
+	for ref := range refs {
+		if seen[ref] {
+			t.Fatalf("duplicate reference %s", ref)
+		}
+		seen[ref] = true
+	}
+	if len(seen) != n {
+		t.Errorf("got %d unique references, want %d", len(seen), n)
+	}
+}
+
+func TestGetRideByReference(t *testing.T) {
+	resetStores(t)
+
+	ride := createTestRide(t, "rider-1")
+	if ride.Reference == "" {
+		t.Fatal("ride has no reference")
+	}
+
+	rec := doRequest(t, http.MethodGet, "/rides/reference/"+ride.Reference, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var got Ride
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if got.ID != ride.ID {
+		t.Errorf("got ride %s, want %s", got.ID, ride.ID)
+	}
+
+	if rec := doRequest(t, http.MethodGet, "/rides/reference/R-19700101-9999", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown reference: status = %d, want 404", rec.Code)
+	}
+}
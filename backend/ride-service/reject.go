@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// rejectedBy reports whether driverID has declined the ride
+func (ride *Ride) rejectedBy(driverID string) bool {
+	for _, id := range ride.RejectedDriverIDs {
+		if id == driverID {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectRideHandler serves PUT /rides/{id}/reject for the matched driver
+// declining the ride. The driver is cleared and added to the ride's rejected
+// drivers, and the ride returns to REQUESTED to be matched again; the
+// rejected drivers are refused by the match endpoint. The request timeout
+// still counts from requested_at, so a ride without takers expires as usual.
+func rejectRideHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var req struct {
+		DriverID string `json:"driver_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.DriverID == "" {
+		http.Error(w, "Driver ID required", http.StatusBadRequest)
+		return
+	}
+
+	rejected, err := rideRepo.Update(r.Context(), id, func(ride *Ride) error {
+		if ride.Status != RideMatched {
+			return rejectUpdate(http.StatusBadRequest, "Cannot reject ride in status: %s", ride.Status)
+		}
+		if ride.DriverID != req.DriverID {
+			return rejectUpdate(http.StatusForbidden, "Ride is not matched to driver %s", req.DriverID)
+		}
+		// Copy before appending: the slice may be shared with the stored ride
+		ride.RejectedDriverIDs = append(append([]string(nil), ride.RejectedDriverIDs...), req.DriverID)
+		ride.DriverID = ""
+		ride.DriverFirstName = ""
+		ride.MatchedAt = nil
+		ride.Status = RideRequested
+		return nil
+	})
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Ride rejected by driver", "ride_id", rejected.ID, "driver_id", req.DriverID)
+	publishRideEvent(r.Context(), EventRideRejected, rejected)
+	tripShares.ClearLocation(rejected.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rejected)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func matchTestRide(t *testing.T, id, driverID string) int {
+	t.Helper()
+	return doRequest(t, http.MethodPut, "/rides/"+id+"/match", map[string]string{"driver_id": driverID}).Code
+}
+
+func TestRejectedDriverIsNotRematched(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+	if code := matchTestRide(t, ride.ID, "driver-1"); code != http.StatusOK {
+		t.Fatalf("match: status = %d", code)
+	}
+
+	rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/reject", map[string]string{"driver_id": "driver-1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reject: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var rejected Ride
+	if err := json.NewDecoder(rec.Body).Decode(&rejected); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if rejected.Status != RideRequested || rejected.DriverID != "" || rejected.MatchedAt != nil {
+		t.Errorf("status = %s, driver = %q, matched_at = %v, want a REQUESTED ride without driver", rejected.Status, rejected.DriverID, rejected.MatchedAt)
+	}
+	if len(rejected.RejectedDriverIDs) != 1 || rejected.RejectedDriverIDs[0] != "driver-1" {
+		t.Errorf("rejected_driver_ids = %v, want [driver-1]", rejected.RejectedDriverIDs)
+	}
+
+	if code := matchTestRide(t, ride.ID, "driver-1"); code != http.StatusConflict {
+		t.Errorf("rematch rejected driver: status = %d, want 409", code)
+	}
+	if code := matchTestRide(t, ride.ID, "driver-2"); code != http.StatusOK {
+		t.Errorf("match another driver: status = %d, want 200", code)
+	}
+}
+
+func TestRejectRequiresMatchedDriver(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+
+	reject := func(driverID string) int {
+		return doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/reject", map[string]string{"driver_id": driverID}).Code
+	}
+	if code := reject("driver-1"); code != http.StatusBadRequest {
+		t.Errorf("reject unmatched ride: status = %d, want 400", code)
+	}
+	matchTestRide(t, ride.ID, "driver-1")
+	if code := reject("driver-2"); code != http.StatusForbidden {
+		t.Errorf("reject by another driver: status = %d, want 403", code)
+	}
+	if code := reject(""); code != http.StatusBadRequest {
+		t.Errorf("reject without driver_id: status = %d, want 400", code)
+	}
+	if code := doRequest(t, http.MethodPut, "/rides/ride_missing/reject", map[string]string{"driver_id": "driver-1"}).Code; code != http.StatusNotFound {
+		t.Errorf("reject unknown ride: status = %d, want 404", code)
+	}
+}
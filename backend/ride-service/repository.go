@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrRideNotFound is returned by RideRepository methods for unknown ride IDs
+// and references
+var ErrRideNotFound = errors.New("ride not found")
+
+// QuoteUsedError is returned by RideRepository.Create when the ride's quote
+// already booked another ride
+type QuoteUsedError struct {
+	RideID string
+}
+
+func (e *QuoteUsedError) Error() string {
+	return fmt.Sprintf("quote already used for ride %s", e.RideID)
+}
+
+// RideRepository stores rides. Every method returns snapshots; a caller never
+// holds a ride that another request can change underneath it.
+//
+// RideStore keeps rides in memory and PostgresRideRepository in PostgreSQL.
+// repository_test.go runs the same contract tests against both.
+type RideRepository interface {
+	// Create stores a new ride and assigns its Reference. A quote books at
+	// most one ride; a second booking fails with *QuoteUsedError and does not
+	// consume a reference number.
+	Create(ctx context.Context, ride Ride) (Ride, error)
+	Get(ctx context.Context, id string) (Ride, error)
+	GetByReference(ctx context.Context, reference string) (Ride, error)
+	// GetMany returns the rides with the given IDs in request order, plus
+	// the IDs that do not exist
+	GetMany(ctx context.Context, ids []string) ([]Ride, []string, error)
+	// ListByRider returns up to limit of the rider's rides, newest request
+	// first, skipping offset, plus the number of matching rides. An empty
+	// status matches all rides.
+	ListByRider(ctx context.Context, riderID string, status RideStatus, limit, offset int) ([]Ride, int, error)
+	// Update reads the ride, passes a copy to fn and stores the copy if fn
+	// returns nil. The read and the write are one atomic step: concurrent
+	// updates of the same ride are serialised, and if fn fails or the write
+	// does not complete the stored ride is left unchanged.
+	Update(ctx context.Context, id string, fn func(ride *Ride) error) (Ride, error)
+	// ExpireRequested moves rides that have been REQUESTED for timeout or
+	// longer at now to EXPIRED and returns their IDs
+	ExpireRequested(ctx context.Context, now time.Time, timeout time.Duration) ([]string, error)
+	// PromoteDue moves scheduled rides whose time has come at now to
+	// REQUESTED and returns their IDs. Each ride is promoted exactly once,
+	// even with several dispatchers.
+	PromoteDue(ctx context.Context, now time.Time) ([]string, error)
+	// SummarizeDriverRides returns the driver's active ride and the rides
+	// completed in [from, to)
+	SummarizeDriverRides(ctx context.Context, driverID string, from, to time.Time) (DriverRideSummary, error)
+}
+
+// rideUpdateError rejects a ride update with the HTTP status and message the
+// handler should answer with
+type rideUpdateError struct {
+	status  int
+	message string
+}
+
+func (e *rideUpdateError) Error() string {
+	return e.message
+}
+
+// rejectUpdate is returned from a RideRepository.Update callback to abort the
+// update; writeRideError turns it into the response
+func rejectUpdate(status int, format string, args ...interface{}) error {
+	return &rideUpdateError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// writeRideError answers a request whose repository call failed
+func writeRideError(w http.ResponseWriter, err error) {
+	var rejected *rideUpdateError
+	switch {
+	case errors.Is(err, ErrRideNotFound):
+		http.Error(w, "Ride not found", http.StatusNotFound)
+	case errors.As(err, &rejected):
+		http.Error(w, rejected.message, rejected.status)
+	default:
+		logger.Error("Ride repository error", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// NewRideStore returns an empty in-memory repository that numbers rides with
+// references
+func NewRideStore(references *RideReferenceGenerator) *RideStore {
+	return &RideStore{
+		rides:       make(map[string]*Ride),
+		usedQuotes:  make(map[string]string),
+		byReference: make(map[string]string),
+		references:  references,
+	}
+}
+
+func (s *RideStore) Create(ctx context.Context, ride Ride) (Ride, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, used := s.usedQuotes[ride.QuoteID]; used {
+		return Ride{}, &QuoteUsedError{RideID: existing}
+	}
+	// Assigned under the store lock so a rejected booking does not consume a number
+	ride.Reference = s.references.Next(ride.RequestedAt)
+	s.usedQuotes[ride.QuoteID] = ride.ID
+	s.byReference[ride.Reference] = ride.ID
+	stored := ride
+	s.rides[ride.ID] = &stored
+	return ride, nil
+}
+
+// Get returns a snapshot of the ride. Handlers encode snapshots taken under
+// the lock, never the stored pointer, so a concurrent transition cannot
+// change a ride while it is being written out.
+func (s *RideStore) Get(ctx context.Context, id string) (Ride, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ride, exists := s.rides[id]
+	if !exists {
+		return Ride{}, ErrRideNotFound
+	}
+	return *ride, nil
+}
+
+func (s *RideStore) GetByReference(ctx context.Context, reference string) (Ride, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ride, exists := s.rides[s.byReference[reference]]
+	if !exists {
+		return Ride{}, ErrRideNotFound
+	}
+	return *ride, nil
+}
+
+func (s *RideStore) Update(ctx context.Context, id string, fn func(ride *Ride) error) (Ride, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.rides[id]
+	if !exists {
+		return Ride{}, ErrRideNotFound
+	}
+	updated := *stored
+	if err := fn(&updated); err != nil {
+		return Ride{}, err
+	}
+	*stored = updated
+	return updated, nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The contract tests run against every RideRepository. The PostgreSQL
+// implementation is tested when RIDE_TEST_DATABASE_URL points at a scratch
+// database; its rides table is emptied before each test.
+
+func TestRideStoreContract(t *testing.T) {
+	testRideRepositoryContract(t, func(t *testing.T) RideRepository {
+		return NewRideStore(NewRideReferenceGenerator("T", 4))
+	})
+}
+
+func TestPostgresRideRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("RIDE_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("RIDE_TEST_DATABASE_URL not set")
+	}
+	testRideRepositoryContract(t, func(t *testing.T) RideRepository {
+		repo, err := openPostgresRideRepository(dsn, NewRideReferenceGenerator("T", 4))
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		if _, err := repo.db.Exec(`TRUNCATE rides`); err != nil {
+			t.Fatalf("failed to empty rides: %v", err)
+		}
+		return repo
+	})
+}
+
+func testRideRepositoryContract(t *testing.T, newRepo func(t *testing.T) RideRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo RideRepository)
+	}{
+		{"CreateAndGet", testRepoCreateAndGet},
+		{"QuoteBooksOnce", testRepoQuoteBooksOnce},
+		{"NotFound", testRepoNotFound},
+		{"UpdateStoresChange", testRepoUpdateStoresChange},
+		{"RejectedUpdateLeavesRide", testRepoRejectedUpdateLeavesRide},
+		{"ConcurrentUpdatesSerialised", testRepoConcurrentUpdatesSerialised},
+		{"GetMany", testRepoGetMany},
+		{"ListByRider", testRepoListByRider},
+		{"ExpireRequested", testRepoExpireRequested},
+		{"PromoteDue", testRepoPromoteDue},
+		{"SummarizeDriverRides", testRepoSummarizeDriverRides},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+// repoTestTime is a fixed booking time so references and ordering are
+// predictable
+var repoTestTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newRepoTestRide(riderID string, requestedAt time.Time) Ride {
+	id := uuid.New().String()
+	return Ride{
+		ID:            id,
+		RiderID:       riderID,
+		Status:        RideRequested,
+		PickupLat:     52.5200,
+		PickupLon:     13.4050,
+		RequestedAt:   requestedAt,
+		QuoteID:       "quote-" + id,
+		QuotedFareEUR: 28.50,
+		QuoteToken:    "token-" + id,
+	}
+}
+
+func mustCreate(t *testing.T, repo RideRepository, ride Ride) Ride {
+	t.Helper()
+	created, err := repo.Create(context.Background(), ride)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return created
+}
+
+// mustUpdate applies fn and fails the test if the update is rejected
+func mustUpdate(t *testing.T, repo RideRepository, id string, fn func(ride *Ride)) Ride {
+	t.Helper()
+	updated, err := repo.Update(context.Background(), id, func(ride *Ride) error {
+		fn(ride)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	return updated
+}
+
+func testRepoCreateAndGet(t *testing.T, repo RideRepository) {
+	ctx := context.Background()
+	first := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+	second := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime.Add(time.Minute)))
+
+	if first.Reference != "T-20240501-0001" || second.Reference != "T-20240501-0002" {
+		t.Errorf("references = %q, %q, want T-20240501-0001, T-20240501-0002", first.Reference, second.Reference)
+	}
+
+	got, err := repo.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Reference != first.Reference || got.QuoteToken != first.QuoteToken || got.QuotedFareEUR != 28.50 || !got.RequestedAt.Equal(repoTestTime) {
+		t.Errorf("Get = %+v, want %+v", got, first)
+	}
+
+	byRef, err := repo.GetByReference(ctx, second.Reference)
+	if err != nil || byRef.ID != second.ID {
+		t.Errorf("GetByReference = %s, %v, want %s", byRef.ID, err, second.ID)
+	}
+}
+
+func testRepoQuoteBooksOnce(t *testing.T, repo RideRepository) {
+	first := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+
+	again := newRepoTestRide("rider-2", repoTestTime)
+	again.QuoteID = first.QuoteID
+	_, err := repo.Create(context.Background(), again)
+	var used *QuoteUsedError
+	if !errors.As(err, &used) || used.RideID != first.ID {
+		t.Fatalf("second booking with quote: err = %v, want QuoteUsedError for %s", err, first.ID)
+	}
+
+	// The rejected booking did not consume a reference number
+	next := mustCreate(t, repo, newRepoTestRide("rider-2", repoTestTime))
+	if next.Reference != "T-20240501-0002" {
+		t.Errorf("reference after rejected booking = %q, want T-20240501-0002", next.Reference)
+	}
+}
+
+func testRepoNotFound(t *testing.T, repo RideRepository) {
+	ctx := context.Background()
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, ErrRideNotFound) {
+		t.Errorf("Get: err = %v, want ErrRideNotFound", err)
+	}
+	if _, err := repo.GetByReference(ctx, "T-20240501-9999"); !errors.Is(err, ErrRideNotFound) {
+		t.Errorf("GetByReference: err = %v, want ErrRideNotFound", err)
+	}
+	called := false
+	_, err := repo.Update(ctx, "missing", func(ride *Ride) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrRideNotFound) || called {
+		t.Errorf("Update: err = %v, callback called = %v, want ErrRideNotFound without a call", err, called)
+	}
+}
+
+func testRepoUpdateStoresChange(t *testing.T, repo RideRepository) {
+	ride := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+
+	matchedAt := repoTestTime.Add(time.Minute)
+	updated := mustUpdate(t, repo, ride.ID, func(ride *Ride) {
+		ride.Status = RideMatched
+		ride.DriverID = "driver-1"
+		ride.MatchedAt = &matchedAt
+		ride.Warnings = append(ride.Warnings, WarningDropoffNearPickup)
+	})
+	if updated.Status != RideMatched || updated.DriverID != "driver-1" {
+		t.Errorf("Update returned %+v", updated)
+	}
+
+	got, err := repo.Get(context.Background(), ride.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Status != RideMatched || got.DriverID != "driver-1" || got.MatchedAt == nil || !got.MatchedAt.Equal(matchedAt) || len(got.Warnings) != 1 {
+		t.Errorf("stored ride = %+v, want matched to driver-1", got)
+	}
+	if got.QuoteToken != ride.QuoteToken {
+		t.Errorf("quote token changed to %q", got.QuoteToken)
+	}
+}
+
+func testRepoRejectedUpdateLeavesRide(t *testing.T, repo RideRepository) {
+	ride := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+
+	rejected := errors.New("rejected")
+	_, err := repo.Update(context.Background(), ride.ID, func(ride *Ride) error {
+		ride.Status = RideCancelled
+		ride.DriverID = "driver-1"
+		return rejected
+	})
+	if !errors.Is(err, rejected) {
+		t.Fatalf("Update: err = %v, want the callback's error", err)
+	}
+
+	got, _ := repo.Get(context.Background(), ride.ID)
+	if got.Status != RideRequested || got.DriverID != "" {
+		t.Errorf("rejected update was stored: %+v", got)
+	}
+}
+
+func testRepoConcurrentUpdatesSerialised(t *testing.T, repo RideRepository) {
+	ride := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+
+	// Each update appends one warning; lost updates would drop some of them
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Update(context.Background(), ride.ID, func(ride *Ride) error {
+				ride.Warnings = append(ride.Warnings, "W")
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := repo.Get(context.Background(), ride.ID)
+	if len(got.Warnings) != 10 {
+		t.Errorf("%d warnings stored, want 10", len(got.Warnings))
+	}
+}
+
+func testRepoGetMany(t *testing.T, repo RideRepository) {
+	a := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+	b := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+
+	rides, missing, err := repo.GetMany(context.Background(), []string{b.ID, "missing", a.ID})
+	if err != nil {
+		t.Fatalf("GetMany failed: %v", err)
+	}
+	if len(rides) != 2 || rides[0].ID != b.ID || rides[1].ID != a.ID {
+		t.Errorf("rides not returned in request order: %+v", rides)
+	}
+	if len(missing) != 1 || missing[0] != "missing" {
+		t.Errorf("missing = %v, want [missing]", missing)
+	}
+}
+
+func testRepoListByRider(t *testing.T, repo RideRepository) {
+	ctx := context.Background()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ride := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime.Add(time.Duration(i)*time.Hour)))
+		ids = append(ids, ride.ID)
+	}
+	mustCreate(t, repo, newRepoTestRide("rider-2", repoTestTime))
+	mustUpdate(t, repo, ids[1], func(ride *Ride) { ride.Status = RideCancelled })
+
+	page, total, err := repo.ListByRider(ctx, "rider-1", "", 2, 1)
+	if err != nil {
+		t.Fatalf("ListByRider failed: %v", err)
+	}
+	if total != 5 || len(page) != 2 || page[0].ID != ids[3] || page[1].ID != ids[2] {
+		t.Errorf("page = %v (total %d), want rides 3 and 2 of 5, newest first", rideIDs(page), total)
+	}
+
+	cancelled, total, _ := repo.ListByRider(ctx, "rider-1", RideCancelled, 10, 0)
+	if total != 1 || len(cancelled) != 1 || cancelled[0].ID != ids[1] {
+		t.Errorf("cancelled = %v (total %d), want only ride 1", rideIDs(cancelled), total)
+	}
+
+	beyond, total, _ := repo.ListByRider(ctx, "rider-1", "", 10, 10)
+	if total != 5 || len(beyond) != 0 {
+		t.Errorf("offset past the end: %d rides, total %d, want 0 of 5", len(beyond), total)
+	}
+}
+
+func testRepoExpireRequested(t *testing.T, repo RideRepository) {
+	ctx := context.Background()
+	now := repoTestTime.Add(time.Hour)
+	stale := mustCreate(t, repo, newRepoTestRide("rider-1", now.Add(-6*time.Minute)))
+	fresh := mustCreate(t, repo, newRepoTestRide("rider-1", now.Add(-4*time.Minute)))
+	matched := mustCreate(t, repo, newRepoTestRide("rider-1", now.Add(-6*time.Minute)))
+	mustUpdate(t, repo, matched.ID, func(ride *Ride) { ride.Status = RideMatched })
+
+	expired, err := repo.ExpireRequested(ctx, now, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ExpireRequested failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != stale.ID {
+		t.Errorf("expired = %v, want only %s", expired, stale.ID)
+	}
+	if got, _ := repo.Get(ctx, stale.ID); got.Status != RideExpired || got.ExpiredAt == nil {
+		t.Errorf("stale ride = %s, expired_at %v", got.Status, got.ExpiredAt)
+	}
+	if got, _ := repo.Get(ctx, fresh.ID); got.Status != RideRequested {
+		t.Errorf("fresh ride = %s, want %s", got.Status, RideRequested)
+	}
+	if again, _ := repo.ExpireRequested(ctx, now, 5*time.Minute); len(again) != 0 {
+		t.Errorf("second sweep expired %v again", again)
+	}
+}
+
+func testRepoPromoteDue(t *testing.T, repo RideRepository) {
+	ctx := context.Background()
+	newScheduled := func(at time.Time) Ride {
+		ride := newRepoTestRide("rider-1", repoTestTime)
+		ride.Status = RideScheduled
+		ride.ScheduledAt = &at
+		return mustCreate(t, repo, ride)
+	}
+	soon := newScheduled(repoTestTime.Add(time.Hour))
+	later := newScheduled(repoTestTime.Add(3 * time.Hour))
+
+	promoted, err := repo.PromoteDue(ctx, repoTestTime.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("PromoteDue failed: %v", err)
+	}
+	if len(promoted) != 1 || promoted[0] != soon.ID {
+		t.Errorf("promoted = %v, want only %s", promoted, soon.ID)
+	}
+	if got, _ := repo.Get(ctx, soon.ID); got.Status != RideRequested {
+		t.Errorf("due ride = %s, want %s", got.Status, RideRequested)
+	}
+	if got, _ := repo.Get(ctx, later.ID); got.Status != RideScheduled {
+		t.Errorf("later ride = %s, want %s", got.Status, RideScheduled)
+	}
+	if again, _ := repo.PromoteDue(ctx, repoTestTime.Add(2*time.Hour)); len(again) != 0 {
+		t.Errorf("ride promoted twice: %v", again)
+	}
+
+	// A promoted ride waits from its scheduled time, not its booking time
+	if expired, _ := repo.ExpireRequested(ctx, repoTestTime.Add(time.Hour+time.Minute), 5*time.Minute); len(expired) != 0 {
+		t.Errorf("promoted ride expired early: %v", expired)
+	}
+}
+
+func testRepoSummarizeDriverRides(t *testing.T, repo RideRepository) {
+	from, to := repoTestTime, repoTestTime.Add(24*time.Hour)
+	complete := func(at time.Time, fare float64) {
+		ride := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+		mustUpdate(t, repo, ride.ID, func(ride *Ride) {
+			ride.Status = RideCompleted
+			ride.DriverID = "driver-1"
+			ride.CompletedAt = &at
+			ride.ChargedFareEUR = fare
+		})
+	}
+	complete(from.Add(time.Hour), 20.10)
+	complete(from.Add(2*time.Hour), 15.20)
+	complete(to, 99.00)
+	active := mustCreate(t, repo, newRepoTestRide("rider-1", repoTestTime))
+	mustUpdate(t, repo, active.ID, func(ride *Ride) {
+		ride.Status = RideStarted
+		ride.DriverID = "driver-1"
+	})
+
+	summary, err := repo.SummarizeDriverRides(context.Background(), "driver-1", from, to)
+	if err != nil {
+		t.Fatalf("SummarizeDriverRides failed: %v", err)
+	}
+	if summary.CompletedRides != 2 || summary.EarningsEUR != 35.30 {
+		t.Errorf("summary = %d rides, %.2f EUR, want 2 rides, 35.30 EUR", summary.CompletedRides, summary.EarningsEUR)
+	}
+	if summary.ActiveRide == nil || summary.ActiveRide.ID != active.ID {
+		t.Errorf("active ride = %+v, want %s", summary.ActiveRide, active.ID)
+	}
+}
+
+func rideIDs(rides []Ride) []string {
+	ids := make([]string, 0, len(rides))
+	for _, ride := range rides {
+		ids = append(ids, ride.ID)
+	}
+	return ids
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ReturnToBaseCheckConfig decides whether a return to base was plausibly
+// direct (Rückkehrpflicht, §49 Abs. 4 PBefG). A return is compliant if it
+// took no longer than the straight-line distance from the dropoff to the
+// base at MinAverageSpeedKmh, plus Grace for traffic, parking and detours
+// forced by the road network.
+type ReturnToBaseCheckConfig struct {
+	MinAverageSpeedKmh float64
+	Grace              time.Duration
+}
+
+func defaultReturnToBaseCheckConfig() ReturnToBaseCheckConfig {
+	return ReturnToBaseCheckConfig{MinAverageSpeedKmh: 20, Grace: 10 * time.Minute}
+}
+
+var returnToBaseCheck = defaultReturnToBaseCheckConfig()
+
+// loadReturnToBaseCheckConfig reads RETURN_TO_BASE_MIN_SPEED_KMH and
+// RETURN_TO_BASE_GRACE
+func loadReturnToBaseCheckConfig() (ReturnToBaseCheckConfig, error) {
+	cfg := defaultReturnToBaseCheckConfig()
+
+	if v := os.Getenv("RETURN_TO_BASE_MIN_SPEED_KMH"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			return cfg, fmt.Errorf("RETURN_TO_BASE_MIN_SPEED_KMH must be a positive number, got %q", v)
+		}
+		cfg.MinAverageSpeedKmh = f
+	}
+	if v := os.Getenv("RETURN_TO_BASE_GRACE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("RETURN_TO_BASE_GRACE must be a non-negative duration, got %q", v)
+		}
+		cfg.Grace = d
+	}
+
+	return cfg, nil
+}
+
+// ReturnToBaseVerdict is the outcome of the directness check
+type ReturnToBaseVerdict struct {
+	Compliant            bool    `json:"compliant"`
+	Reason               string  `json:"reason"`
+	DistanceKm           float64 `json:"distance_km,omitempty"`
+	DurationMin          float64 `json:"duration_min"`
+	MaxDirectDurationMin float64 `json:"max_direct_duration_min,omitempty"`
+}
+
+// assess checks a return that took elapsed. ride is the completed ride the
+// driver returned from; without its dropoff location the return cannot be
+// verified and is not compliant.
+func (cfg ReturnToBaseCheckConfig) assess(ride *Ride, baseLat, baseLon float64, elapsed time.Duration) ReturnToBaseVerdict {
+	verdict := ReturnToBaseVerdict{DurationMin: roundMinutes(elapsed)}
+
+	if ride == nil || (ride.DropoffLat == 0 && ride.DropoffLon == 0) {
+		verdict.Reason = "dropoff location of the ride is unknown, return cannot be verified"
+		return verdict
+	}
+
+	km := tripDistanceKm(ride.DropoffLat, ride.DropoffLon, baseLat, baseLon)
+	maxDirect := time.Duration(km/cfg.MinAverageSpeedKmh*float64(time.Hour)) + cfg.Grace
+	verdict.DistanceKm = km
+	verdict.MaxDirectDurationMin = roundMinutes(maxDirect)
+
+	if elapsed > maxDirect {
+		verdict.Reason = fmt.Sprintf("return took %.0f min, a direct return of %.1f km takes at most %.0f min", verdict.DurationMin, km, verdict.MaxDirectDurationMin)
+		return verdict
+	}
+	verdict.Compliant = true
+	verdict.Reason = "direct return"
+	return verdict
+}
+
+func roundMinutes(d time.Duration) float64 {
+	return math.Round(d.Minutes()*10) / 10
+}
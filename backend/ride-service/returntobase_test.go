@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// returnFromCompletedRide completes a ride at Alexanderplatz, opens a
+// return to a base in Neukölln (about 4.7km away) and backdates the start
+// of the return by elapsed.
+func returnFromCompletedRide(t *testing.T, elapsed time.Duration) ReturnToBaseLog {
+	t.Helper()
+	useShortTripConfig(t, ShortTripConfig{})
+	ride := startTestRide(t)
+	completeTestRide(t, ride.ID, map[string]interface{}{"dropoff_lat": 52.5219, "dropoff_lon": 13.4132, "return_to_base": true})
+
+	rec := doRequest(t, http.MethodPost, "/return-to-base", map[string]interface{}{
+		"ride_id":   ride.ID,
+		"driver_id": "driver-1",
+		"base_lat":  52.4800,
+		"base_lon":  13.4200,
+	})
+	var rtbLog ReturnToBaseLog
+	if err := json.NewDecoder(rec.Body).Decode(&rtbLog); err != nil {
+		t.Fatalf("failed to decode return-to-base log: %v", err)
+	}
+	returnToBaseStore.logs[rtbLog.ID].ReturnStartedAt = time.Now().Add(-elapsed)
+	return rtbLog
+}
+
+func endTestReturnToBase(t *testing.T, id string) ReturnToBaseLog {
+	t.Helper()
+	rec := doRequest(t, http.MethodPut, "/return-to-base/"+id+"/end", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var ended ReturnToBaseLog
+	if err := json.NewDecoder(rec.Body).Decode(&ended); err != nil {
+		t.Fatalf("failed to decode return-to-base log: %v", err)
+	}
+	return ended
+}
+
+func TestReturnToBaseQuickReturnIsCompliant(t *testing.T) {
+	resetStores(t)
+	rtbLog := returnFromCompletedRide(t, 15*time.Minute)
+
+	ended := endTestReturnToBase(t, rtbLog.ID)
+	if !ended.Compliance || ended.Verdict == nil || !ended.Verdict.Compliant {
+		t.Fatalf("expected a compliant return, got %+v", ended.Verdict)
+	}
+	if ended.Verdict.DistanceKm < 4.5 || ended.Verdict.DistanceKm > 5 {
+		t.Errorf("distance_km = %v, want about 4.7", ended.Verdict.DistanceKm)
+	}
+	if ended.Verdict.DurationMin < 14.9 || ended.Verdict.DurationMin > 15.5 {
+		t.Errorf("duration_min = %v, want about 15", ended.Verdict.DurationMin)
+	}
+}
+
+func TestReturnToBaseLongReturnIsNotCompliant(t *testing.T) {
+	resetStores(t)
+	rtbLog := returnFromCompletedRide(t, 2*time.Hour)
+
+	ended := endTestReturnToBase(t, rtbLog.ID)
+	if ended.Compliance || ended.Verdict == nil || ended.Verdict.Compliant {
+		t.Fatalf("expected a non-compliant return, got %+v", ended.Verdict)
+	}
+	if ended.Verdict.DurationMin <= ended.Verdict.MaxDirectDurationMin {
+		t.Errorf("duration %v within the allowed %v", ended.Verdict.DurationMin, ended.Verdict.MaxDirectDurationMin)
+	}
+}
+
+func TestReturnToBaseWithoutDropoffCannotBeVerified(t *testing.T) {
+	resetStores(t)
+	var rtbLog ReturnToBaseLog
+	if err := json.NewDecoder(openReturnToBase(t, "driver-1").Body).Decode(&rtbLog); err != nil {
+		t.Fatalf("failed to decode return-to-base log: %v", err)
+	}
+
+	ended := endTestReturnToBase(t, rtbLog.ID)
+	if ended.Compliance || ended.Verdict == nil || ended.Verdict.Reason == "" {
+		t.Errorf("unverifiable return: compliance = %v, verdict = %+v", ended.Compliance, ended.Verdict)
+	}
+}
+
+func TestLoadReturnToBaseCheckConfig(t *testing.T) {
+	if cfg, err := loadReturnToBaseCheckConfig(); err != nil || cfg != defaultReturnToBaseCheckConfig() {
+		t.Errorf("default = %+v, %v", cfg, err)
+	}
+	t.Setenv("RETURN_TO_BASE_MIN_SPEED_KMH", "30")
+	t.Setenv("RETURN_TO_BASE_GRACE", "5m")
+	if cfg, err := loadReturnToBaseCheckConfig(); err != nil || cfg.MinAverageSpeedKmh != 30 || cfg.Grace != 5*time.Minute {
+		t.Errorf("configured = %+v, %v", cfg, err)
+	}
+	for _, v := range []string{"0", "-10", "fast", "NaN", "Inf"} {
+		t.Setenv("RETURN_TO_BASE_MIN_SPEED_KMH", v)
+		if _, err := loadReturnToBaseCheckConfig(); err == nil {
+			t.Errorf("RETURN_TO_BASE_MIN_SPEED_KMH=%q: expected an error", v)
+		}
+	}
+}
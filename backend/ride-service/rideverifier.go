@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// RideVerifier confirms that a return to base follows a ride the driver
+// actually completed. It returns ErrRideNotFound for an unknown ride, a
+// rejectUpdate error if the ride is not the driver's or not COMPLETED, and an
+// error wrapping errRideVerificationFailed if the ride state cannot be read.
+type RideVerifier interface {
+	VerifyCompletedRide(ctx context.Context, rideID, driverID string) error
+}
+
+var errRideVerificationFailed = errors.New("ride verification failed")
+
+// checkCompletedRide applies the verification rules to ride
+func checkCompletedRide(ride Ride, driverID string) error {
+	if ride.DriverID != driverID {
+		return rejectUpdate(http.StatusBadRequest, "Ride %s was not driven by driver %s", ride.ID, driverID)
+	}
+	if ride.Status != RideCompleted {
+		return rejectUpdate(http.StatusBadRequest, "Ride %s is %s, a return to base needs a completed ride", ride.ID, ride.Status)
+	}
+	return nil
+}
+
+// RepositoryRideVerifier checks rides in this service's own rideRepo
+type RepositoryRideVerifier struct{}
+
+func (RepositoryRideVerifier) VerifyCompletedRide(ctx context.Context, rideID, driverID string) error {
+	ride, err := rideRepo.Get(ctx, rideID)
+	if errors.Is(err, ErrRideNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRideVerificationFailed, err)
+	}
+	return checkCompletedRide(ride, driverID)
+}
+
+// HTTPRideVerifier fetches the ride from a ride-service at BaseURL, for
+// deployments that keep return-to-base logs apart from the ride store
+type HTTPRideVerifier struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewHTTPRideVerifier(baseURL string, timeout time.Duration) *HTTPRideVerifier {
+	return &HTTPRideVerifier{BaseURL: strings.TrimRight(baseURL, "/"), Client: &http.Client{Timeout: timeout}}
+}
+
+func (v *HTTPRideVerifier) VerifyCompletedRide(ctx context.Context, rideID, driverID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.BaseURL+"/rides/"+url.PathEscape(rideID), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRideVerificationFailed, err)
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: ride-service unreachable: %v", errRideVerificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrRideNotFound
+	default:
+		return fmt.Errorf("%w: ride-service answered %s", errRideVerificationFailed, resp.Status)
+	}
+
+	var ride Ride
+	if err := json.NewDecoder(resp.Body).Decode(&ride); err != nil {
+		return fmt.Errorf("%w: invalid ride from ride-service: %v", errRideVerificationFailed, err)
+	}
+	return checkCompletedRide(ride, driverID)
+}
+
+// newRideVerifierFromEnv checks rides in rideRepo unless RIDE_VERIFIER_URL
+// names a ride-service to ask instead. RIDE_VERIFIER_TIMEOUT bounds each
+// request to it.
+func newRideVerifierFromEnv() (RideVerifier, error) {
+	baseURL := os.Getenv("RIDE_VERIFIER_URL")
+	if baseURL == "" {
+		return RepositoryRideVerifier{}, nil
+	}
+	if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("RIDE_VERIFIER_URL must be an http(s) URL, got %q", baseURL)
+	}
+
+	timeout := 3 * time.Second
+	if v := os.Getenv("RIDE_VERIFIER_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("RIDE_VERIFIER_TIMEOUT must be a positive duration, got %q", v)
+		}
+		timeout = d
+	}
+	return NewHTTPRideVerifier(baseURL, timeout), nil
+}
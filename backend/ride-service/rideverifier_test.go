@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func createReturnToBase(t *testing.T, rideID, driverID string) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequest(t, http.MethodPost, "/return-to-base", map[string]interface{}{
+		"ride_id":   rideID,
+		"driver_id": driverID,
+		"base_lat":  52.4800,
+		"base_lon":  13.4200,
+	})
+}
+
+func TestReturnToBaseRequiresCompletedRideOfDriver(t *testing.T) {
+	resetStores(t)
+	rideVerifier = RepositoryRideVerifier{}
+
+	ride := startTestRide(t)
+	if rec := createReturnToBase(t, ride.ID, "driver-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("ride not completed: status = %d, want 400", rec.Code)
+	}
+
+	completeTestRide(t, ride.ID, map[string]interface{}{"return_to_base": true})
+	if rec := createReturnToBase(t, ride.ID, "driver-2"); rec.Code != http.StatusBadRequest {
+		t.Errorf("another driver's ride: status = %d, want 400", rec.Code)
+	}
+	if rec := createReturnToBase(t, "ride_missing", "driver-1"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown ride: status = %d, want 404", rec.Code)
+	}
+	if rec := createReturnToBase(t, ride.ID, "driver-1"); rec.Code != http.StatusCreated {
+		t.Errorf("completed ride: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReturnToBaseRideVerificationUnavailable(t *testing.T) {
+	resetStores(t)
+	rideVerifier = stubRideVerifier{err: fmt.Errorf("%w: ride-service unreachable", errRideVerificationFailed)}
+
+	if rec := createReturnToBase(t, "ride-1", "driver-1"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+	if len(returnToBaseStore.logs) != 0 {
+		t.Errorf("%d logs stored, want none", len(returnToBaseStore.logs))
+	}
+}
+
+func TestHTTPRideVerifier(t *testing.T) {
+	rides := map[string]Ride{
+		"ride_done":    {ID: "ride_done", DriverID: "driver-1", Status: RideCompleted},
+		"ride_started": {ID: "ride_started", DriverID: "driver-1", Status: RideStarted},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[len("/rides/"):]
+		if id == "ride_broken" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		ride, ok := rides[id]
+		if !ok {
+			http.Error(w, "Ride not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(ride)
+	}))
+	defer server.Close()
+	verifier := NewHTTPRideVerifier(server.URL+"/", 0)
+
+	if err := verifier.VerifyCompletedRide(context.Background(), "ride_done", "driver-1"); err != nil {
+		t.Errorf("completed ride: %v", err)
+	}
+	var rejected *rideUpdateError
+	if err := verifier.VerifyCompletedRide(context.Background(), "ride_started", "driver-1"); !errors.As(err, &rejected) || rejected.status != http.StatusBadRequest {
+		t.Errorf("started ride: %v, want a 400 rejection", err)
+	}
+	if err := verifier.VerifyCompletedRide(context.Background(), "ride_missing", "driver-1"); !errors.Is(err, ErrRideNotFound) {
+		t.Errorf("unknown ride: %v, want ErrRideNotFound", err)
+	}
+	if err := verifier.VerifyCompletedRide(context.Background(), "ride_broken", "driver-1"); !errors.Is(err, errRideVerificationFailed) {
+		t.Errorf("ride-service error: %v, want errRideVerificationFailed", err)
+	}
+}
+
+func TestNewRideVerifierFromEnv(t *testing.T) {
+	if v, err := newRideVerifierFromEnv(); err != nil || v != (RepositoryRideVerifier{}) {
+		t.Errorf("default = %v, %v, want the repository verifier", v, err)
+	}
+	t.Setenv("RIDE_VERIFIER_URL", "ride-service:8080")
+	if _, err := newRideVerifierFromEnv(); err == nil {
+		t.Error("expected an error for a URL without scheme")
+	}
+	t.Setenv("RIDE_VERIFIER_URL", "http://ride-service:8080")
+	if v, err := newRideVerifierFromEnv(); err != nil || v.(*HTTPRideVerifier).BaseURL != "http://ride-service:8080" {
+		t.Errorf("got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// MaxScheduleAhead is how far in advance a ride can be booked
+const MaxScheduleAhead = 30 * 24 * time.Hour
+
+// scheduledDispatchInterval is how often the dispatcher looks for due rides
+const scheduledDispatchInterval = 15 * time.Second
+
+// PromoteDue checks the status and makes the transition under one store
+// lock, so a ride is promoted at most once even with several dispatchers.
+func (s *RideStore) PromoteDue(ctx context.Context, now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promoted := []string{}
+	for _, ride := range s.rides {
+		if ride.Status != RideScheduled || ride.ScheduledAt == nil || now.Before(*ride.ScheduledAt) {
+			continue
+		}
+		ride.Status = RideRequested
+		promoted = append(promoted, ride.ID)
+	}
+	return promoted, nil
+}
+
+// runScheduledRideDispatcher promotes due scheduled rides every interval
+// until ctx is cancelled.
+//
+// The dispatcher needs no state of its own. With rides in PostgreSQL it
+// picks up every SCHEDULED ride that is due on start, including those that
+// fell due while the service was down, and PostgresRideRepository.PromoteDue
+// locks the rides it promotes so promotion stays exactly-once across
+// replicas. The in-memory RideStore loses scheduled rides on restart like
+// every other ride.
+func runScheduledRideDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			promoted, err := rideRepo.PromoteDue(ctx, timeutil.Now())
+			if err != nil {
+				logger.Error("Scheduled ride dispatch failed", "error", err)
+			}
+			for _, id := range promoted {
+				logger.Info("Scheduled ride due, requesting driver", "ride_id", id)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func createScheduledRide(t *testing.T, scheduledAt time.Time) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequest(t, http.MethodPost, "/rides", map[string]interface{}{
+		"rider_id":     "rider-1",
+		"pickup_lat":   52.5200,
+		"pickup_lon":   13.4050,
+		"quote_token":  issueQuoteToken(t, 28.50),
+		"scheduled_at": scheduledAt.Format(time.RFC3339),
+	})
+}
+
+func TestCreateScheduledRide(t *testing.T) {
+	resetStores(t)
+	at := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+
+	rec := createScheduledRide(t, at)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var ride Ride
+	if err := json.NewDecoder(rec.Body).Decode(&ride); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if ride.Status != RideScheduled || ride.ScheduledAt == nil || !ride.ScheduledAt.Equal(at) {
+		t.Errorf("status = %s, scheduled_at = %v", ride.Status, ride.ScheduledAt)
+	}
+
+	// A scheduled ride is not matched before it is due
+	if rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("match scheduled ride: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateScheduledRideRejectsPastAndFarFuture(t *testing.T) {
+	resetStores(t)
+	for _, at := range []time.Time{
+		time.Now().Add(-time.Minute),
+		time.Now().Add(MaxScheduleAhead + time.Hour),
+	} {
+		if rec := createScheduledRide(t, at); rec.Code != http.StatusBadRequest {
+			t.Errorf("scheduled_at %v: status = %d, want 400", at, rec.Code)
+		}
+	}
+	if len(memoryStore(t).rides) != 0 {
+		t.Errorf("%d rides stored, want none", len(memoryStore(t).rides))
+	}
+}
+
+func TestPromoteDueScheduledRides(t *testing.T) {
+	resetStores(t)
+	var soon, later Ride
+	json.NewDecoder(createScheduledRide(t, time.Now().Add(time.Hour)).Body).Decode(&soon)
+	json.NewDecoder(createScheduledRide(t, time.Now().Add(3*time.Hour)).Body).Decode(&later)
+
+	promoted, _ := rideRepo.PromoteDue(context.Background(), time.Now().Add(2*time.Hour))
+	if len(promoted) != 1 || promoted[0] != soon.ID {
+		t.Fatalf("promoted = %v, want only the earlier ride", promoted)
+	}
+	if got := memoryStore(t).rides[soon.ID].Status; got != RideRequested {
+		t.Errorf("due ride status = %s, want REQUESTED", got)
+	}
+	if got := memoryStore(t).rides[later.ID].Status; got != RideScheduled {
+		t.Errorf("later ride status = %s, want SCHEDULED", got)
+	}
+
+	// The expiry timeout counts from the scheduled time, not the booking
+	if expired, _ := rideRepo.ExpireRequested(context.Background(), time.Now().Add(time.Hour+time.Minute), 5*time.Minute); len(expired) != 0 {
+		t.Errorf("expired %v right after promotion", expired)
+	}
+}
+
+func TestPromoteDueNeverPromotesTwice(t *testing.T) {
+	resetStores(t)
+	for i := 0; i < 10; i++ {
+		createScheduledRide(t, time.Now().Add(time.Hour))
+	}
+
+	due := time.Now().Add(2 * time.Hour)
+	var wg sync.WaitGroup
+	results := make(chan []string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			promoted, _ := rideRepo.PromoteDue(context.Background(), due)
+			results <- promoted
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := map[string]bool{}
+	for ids := range results {
+		for _, id := range ids {
+			if seen[id] {
+				t.Errorf("ride %s promoted twice", id)
+			}
+			seen[id] = true
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("%d rides promoted, want 10", len(seen))
+	}
+}
+
+func TestCancelScheduledRide(t *testing.T) {
+	resetStores(t)
+	var ride Ride
+	json.NewDecoder(createScheduledRide(t, time.Now().Add(time.Hour)).Body).Decode(&ride)
+
+	if rec := cancelTestRide(t, ride.ID, CancelledByRider, CancelReasonRiderChangedPlans); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if promoted, _ := rideRepo.PromoteDue(context.Background(), time.Now().Add(2*time.Hour)); len(promoted) != 0 {
+		t.Errorf("cancelled ride promoted: %v", promoted)
+	}
+}
@@ -0,0 +1,32 @@
+-- Ride storage for PostgresRideRepository. The statements are idempotent and
+-- applied on start-up.
+--
+-- data holds the full ride as JSON and is the source of truth; the other
+-- columns copy the fields rides are looked up, filtered and locked by, and
+-- are written in the same statement as data. quote_token is kept out of the
+-- JSON like it is kept out of API responses.
+
+CREATE TABLE IF NOT EXISTS rides (
+    id           TEXT PRIMARY KEY,
+    reference    TEXT NOT NULL UNIQUE,
+    rider_id     TEXT NOT NULL,
+    driver_id    TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL,
+    -- A quote books at most one ride
+    quote_id     TEXT NOT NULL UNIQUE,
+    quote_token  TEXT NOT NULL DEFAULT '',
+    requested_at TIMESTAMPTZ NOT NULL,
+    scheduled_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    data         JSONB NOT NULL
+);
+
+-- Trip history: GET /rides?rider_id=
+CREATE INDEX IF NOT EXISTS rides_rider_requested_idx ON rides (rider_id, requested_at DESC, id);
+
+-- Driver dashboard: GET /rides/driver/{driver_id}/summary
+CREATE INDEX IF NOT EXISTS rides_driver_idx ON rides (driver_id) WHERE driver_id <> '';
+
+-- Expiry sweeper and scheduled ride dispatcher
+CREATE INDEX IF NOT EXISTS rides_waiting_idx ON rides (status, (COALESCE(scheduled_at, requested_at)))
+    WHERE status IN ('REQUESTED', 'SCHEDULED');
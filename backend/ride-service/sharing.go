@@ -0,0 +1,293 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// tripShareMaxLifetime bounds every share link, even for a ride that never
+// ends
+const tripShareMaxLifetime = 12 * time.Hour
+
+// tripShareETASpeedKmh is the average speed used for the ETA on a shared
+// trip, typical for German inner-city traffic
+const tripShareETASpeedKmh = 25.0
+
+// TripShareConfig controls trip share links. A link stays valid for Grace
+// after the ride completes or is cancelled, so the contact sees the arrival.
+// Links are served under BaseURL.
+type TripShareConfig struct {
+	Grace   time.Duration
+	BaseURL string
+}
+
+func defaultTripShareConfig() TripShareConfig {
+	return TripShareConfig{Grace: 30 * time.Minute}
+}
+
+var tripShare = defaultTripShareConfig()
+
+// loadTripShareConfig reads TRIP_SHARE_GRACE and TRIP_SHARE_BASE_URL
+func loadTripShareConfig() (TripShareConfig, error) {
+	cfg := defaultTripShareConfig()
+
+	if v := os.Getenv("TRIP_SHARE_GRACE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("TRIP_SHARE_GRACE must be a non-negative duration, got %q", v)
+		}
+		cfg.Grace = d
+	}
+	cfg.BaseURL = strings.TrimSuffix(os.Getenv("TRIP_SHARE_BASE_URL"), "/")
+
+	return cfg, nil
+}
+
+// TripShare is a share link for a ride. The token is opaque and random;
+// nothing about the ride or rider can be derived from it.
+type TripShare struct {
+	Token     string
+	RideID    string
+	CreatedAt time.Time
+}
+
+// expiresAt is when the link stops working: tripShareMaxLifetime after it was
+// created, or cfg.Grace after the ride ended if that is sooner
+func (s TripShare) expiresAt(ride Ride, cfg TripShareConfig) time.Time {
+	expires := s.CreatedAt.Add(tripShareMaxLifetime)
+	for _, ended := range []*time.Time{ride.CompletedAt, ride.CancelledAt, ride.ExpiredAt} {
+		if ended != nil && ended.Add(cfg.Grace).Before(expires) {
+			expires = ended.Add(cfg.Grace)
+		}
+	}
+	return expires
+}
+
+// TripLocation is the last position the driver reported for a ride
+type TripLocation struct {
+	Lat       float64   `json:"lat"`
+	Lon       float64   `json:"lon"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TripShareStore keeps share tokens and live driver locations in memory.
+// Neither outlives the ride by much, so they are not persisted.
+type TripShareStore struct {
+	mu        sync.RWMutex
+	shares    map[string]TripShare
+	locations map[string]TripLocation
+}
+
+func NewTripShareStore() *TripShareStore {
+	return &TripShareStore{shares: make(map[string]TripShare), locations: make(map[string]TripLocation)}
+}
+
+var tripShares = NewTripShareStore()
+
+// Create issues a new share token for rideID. Tokens past the maximum
+// lifetime are dropped on the way.
+func (s *TripShareStore) Create(rideID string, now time.Time) (TripShare, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return TripShare{}, err
+	}
+	share := TripShare{Token: base64.RawURLEncoding.EncodeToString(b), RideID: rideID, CreatedAt: now}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, old := range s.shares {
+		if !now.Before(old.CreatedAt.Add(tripShareMaxLifetime)) {
+			delete(s.shares, token)
+		}
+	}
+	s.shares[share.Token] = share
+	return share, nil
+}
+
+func (s *TripShareStore) Get(token string) (TripShare, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	share, ok := s.shares[token]
+	return share, ok
+}
+
+func (s *TripShareStore) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.shares, token)
+}
+
+func (s *TripShareStore) SetLocation(rideID string, loc TripLocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locations[rideID] = loc
+}
+
+// ClearLocation forgets the driver's position once the ride is over
+func (s *TripShareStore) ClearLocation(rideID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locations, rideID)
+}
+
+func (s *TripShareStore) Location(rideID string) (TripLocation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	loc, ok := s.locations[rideID]
+	return loc, ok
+}
+
+// SharedTripView is the redacted ride shown to a trusted contact. It carries
+// no rider data, ride ID or reference, and only the driver's first name.
+type SharedTripView struct {
+	Status          RideStatus    `json:"status"`
+	DriverFirstName string        `json:"driver_first_name,omitempty"`
+	Location        *TripLocation `json:"current_location,omitempty"`
+	ETAMinutes      *float64      `json:"eta_minutes,omitempty"`
+	ExpiresAt       time.Time     `json:"expires_at"`
+}
+
+// sharedTripView builds the view of ride. The location and ETA are only shown
+// while the ride is active: the ETA is to the pickup for a matched ride and
+// to the dropoff, if known, for a started one.
+func sharedTripView(ride Ride, loc *TripLocation, expires time.Time) SharedTripView {
+	view := SharedTripView{Status: ride.Status, DriverFirstName: ride.DriverFirstName, ExpiresAt: expires}
+	if loc == nil || (ride.Status != RideMatched && ride.Status != RideStarted) {
+		return view
+	}
+	view.Location = loc
+
+	targetLat, targetLon := ride.PickupLat, ride.PickupLon
+	if ride.Status == RideStarted {
+		targetLat, targetLon = ride.DropoffLat, ride.DropoffLon
+	}
+	if targetLat != 0 || targetLon != 0 {
+		hours := distance.HaversineKm(loc.Lat, loc.Lon, targetLat, targetLon) / tripShareETASpeedKmh
+		eta := roundMinutes(time.Duration(hours * float64(time.Hour)))
+		view.ETAMinutes = &eta
+	}
+	return view
+}
+
+// shareRideHandler creates a share link for an active ride. Only the rider,
+// as identified by the X-User-ID the gateway injects, can share their ride.
+func shareRideHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ride, err := rideRepo.Get(r.Context(), id)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+	if actor := r.Header.Get("X-User-ID"); actor == "" || actor != ride.RiderID {
+		http.Error(w, "Only the rider can share this ride", http.StatusForbidden)
+		return
+	}
+	if ride.Status != RideRequested && ride.Status != RideMatched && ride.Status != RideStarted {
+		http.Error(w, fmt.Sprintf("Cannot share ride in status: %s", ride.Status), http.StatusBadRequest)
+		return
+	}
+
+	share, err := tripShares.Create(ride.ID, timeutil.Now())
+	if err != nil {
+		requestLogger(r.Context()).Error("Failed to create share token", "ride_id", ride.ID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Ride shared", "category", "safety", "ride_id", ride.ID, "rider_id", ride.RiderID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      share.Token,
+		"url":        tripShare.BaseURL + "/rides/shared/" + share.Token,
+		"expires_at": timeutil.Format(share.expiresAt(ride, tripShare)),
+	})
+}
+
+// getSharedRideHandler serves the redacted view of a shared ride. It needs no
+// authentication; the token is the credential.
+func getSharedRideHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	share, ok := tripShares.Get(token)
+	if !ok {
+		http.Error(w, "Trip share link not found", http.StatusNotFound)
+		return
+	}
+	ride, err := rideRepo.Get(r.Context(), share.RideID)
+	if errors.Is(err, ErrRideNotFound) {
+		http.Error(w, "Trip share link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+
+	expires := share.expiresAt(ride, tripShare)
+	if !timeutil.Now().Before(expires) {
+		tripShares.Revoke(token)
+		http.Error(w, "Trip share link has expired", http.StatusGone)
+		return
+	}
+
+	var loc *TripLocation
+	if l, ok := tripShares.Location(ride.ID); ok {
+		loc = &l
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sharedTripView(ride, loc, expires))
+}
+
+// updateRideLocationHandler records the driver's current position on a
+// matched or started ride, for shared trip views
+func updateRideLocationHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		DriverID string   `json:"driver_id"`
+		Lat      *float64 `json:"lat"`
+		Lon      *float64 `json:"lon"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.DriverID == "" || req.Lat == nil || req.Lon == nil || !validCoordinates(*req.Lat, *req.Lon) {
+		http.Error(w, "driver_id and a valid location are required", http.StatusBadRequest)
+		return
+	}
+
+	ride, err := rideRepo.Get(r.Context(), id)
+	if err != nil {
+		writeRideError(w, err)
+		return
+	}
+	if ride.DriverID != req.DriverID {
+		http.Error(w, "Only the assigned driver can report the location", http.StatusForbidden)
+		return
+	}
+	if ride.Status != RideMatched && ride.Status != RideStarted {
+		http.Error(w, fmt.Sprintf("Cannot report location for ride in status: %s", ride.Status), http.StatusBadRequest)
+		return
+	}
+
+	tripShares.SetLocation(ride.ID, TripLocation{Lat: *req.Lat, Lon: *req.Lon, UpdatedAt: timeutil.Now()})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shareRide asks to share rideID as actor, the user the gateway
+// authenticated
+func shareRide(rideID, actor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/rides/"+rideID+"/share", nil)
+	if actor != "" {
+		req.Header.Set("X-User-ID", actor)
+	}
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+	return rec
+}
+
+func shareTestRide(t *testing.T, rideID, riderID string) string {
+	t.Helper()
+	rec := shareRide(rideID, riderID)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("share: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Token == "" || !strings.HasSuffix(resp.URL, "/rides/shared/"+resp.Token) {
+		t.Fatalf("unexpected share response %+v", resp)
+	}
+	return resp.Token
+}
+
+func TestSharedTripView(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+
+	if rec := shareRide(ride.ID, "rider-2"); rec.Code != http.StatusForbidden {
+		t.Errorf("share by another rider: status = %d, want 403", rec.Code)
+	}
+	// The body no longer identifies the caller; only the gateway header does
+	rec := doRequest(t, http.MethodPost, "/rides/"+ride.ID+"/share", map[string]string{"rider_id": "rider-1"})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("share without X-User-ID: status = %d, want 403", rec.Code)
+	}
+	token := shareTestRide(t, ride.ID, "rider-1")
+
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1", "driver_first_name": "Jonas"})
+	if rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/location", map[string]interface{}{"driver_id": "driver-2", "lat": 52.51, "lon": 13.40}); rec.Code != http.StatusForbidden {
+		t.Errorf("location from another driver: status = %d, want 403", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/location", map[string]interface{}{"driver_id": "driver-1", "lat": 52.5110, "lon": 13.4050}); rec.Code != http.StatusNoContent {
+		t.Fatalf("location: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, http.MethodGet, "/rides/shared/"+token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("shared view: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, leak := range []string{"rider-1", ride.ID, ride.Reference, "driver-1"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("shared view leaks %q: %s", leak, body)
+		}
+	}
+	var view SharedTripView
+	json.Unmarshal([]byte(body), &view)
+	// 1 km from the pickup at 25 km/h
+	if view.Status != RideMatched || view.DriverFirstName != "Jonas" || view.Location == nil || view.ETAMinutes == nil || *view.ETAMinutes != 2.4 {
+		t.Errorf("unexpected shared view %s", body)
+	}
+
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/start", nil)
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/complete", map[string]interface{}{})
+	rec = doRequest(t, http.MethodGet, "/rides/shared/"+token, nil)
+	view = SharedTripView{}
+	json.NewDecoder(rec.Body).Decode(&view)
+	if rec.Code != http.StatusOK || view.Status != RideCompleted || view.Location != nil {
+		t.Errorf("within the grace period: status = %d, view = %+v", rec.Code, view)
+	}
+
+	// The grace period after completion runs out
+	ended := memoryStore(t).rides[ride.ID].CompletedAt.Add(-tripShare.Grace - time.Second)
+	memoryStore(t).rides[ride.ID].CompletedAt = &ended
+	if rec := doRequest(t, http.MethodGet, "/rides/shared/"+token, nil); rec.Code != http.StatusGone {
+		t.Errorf("after the grace period: status = %d, want 410", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodGet, "/rides/shared/"+token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("revoked token: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestShareRejectsEndedRide(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+	cancelTestRide(t, ride.ID, CancelledByRider, CancelReasonRiderChangedPlans)
+
+	if rec := shareRide(ride.ID, "rider-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("share cancelled ride: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodGet, "/rides/shared/not-a-token", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown token: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestUpdateRideLocationValidatesCoordinates(t *testing.T) {
+	resetStores(t)
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"berlin", map[string]interface{}{"driver_id": "driver-1", "lat": 52.51, "lon": 13.40}, http.StatusNoContent},
+		{"null island", map[string]interface{}{"driver_id": "driver-1", "lat": 0, "lon": 0}, http.StatusNoContent},
+		{"lat out of range", map[string]interface{}{"driver_id": "driver-1", "lat": 91, "lon": 13.40}, http.StatusBadRequest},
+		{"lon out of range", map[string]interface{}{"driver_id": "driver-1", "lat": 52.51, "lon": 180.5}, http.StatusBadRequest},
+		{"lon missing", map[string]interface{}{"driver_id": "driver-1", "lat": 52.51}, http.StatusBadRequest},
+		{"no driver", map[string]interface{}{"lat": 52.51, "lon": 13.40}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/location", tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTripShareExpiry(t *testing.T) {
+	created := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	share := TripShare{Token: "t", RideID: "r", CreatedAt: created}
+	cfg := TripShareConfig{Grace: 30 * time.Minute}
+
+	if got := share.expiresAt(Ride{}, cfg); !got.Equal(created.Add(tripShareMaxLifetime)) {
+		t.Errorf("active ride: expires %s, want the maximum lifetime", got)
+	}
+	completed := created.Add(40 * time.Minute)
+	if got := share.expiresAt(Ride{CompletedAt: &completed}, cfg); !got.Equal(completed.Add(30 * time.Minute)) {
+		t.Errorf("completed ride: expires %s, want 30 minutes after completion", got)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// ShiftStatus is the lifecycle state of a driver shift. A shift starts OPEN
+// and is ENDED by the driver.
+type ShiftStatus string
+
+const (
+	ShiftOpen  ShiftStatus = "OPEN"
+	ShiftEnded ShiftStatus = "ENDED"
+)
+
+// Shift is a period of active driving. Compliance turns false with a Warning
+// once the driver's driving time that day exceeds the daily limit.
+type Shift struct {
+	ID         string      `json:"id"`
+	DriverID   string      `json:"driver_id"`
+	Status     ShiftStatus `json:"status"`
+	StartedAt  time.Time   `json:"started_at"`
+	EndedAt    *time.Time  `json:"ended_at,omitempty"`
+	Compliance bool        `json:"compliance"`
+	Warning    string      `json:"warning,omitempty"`
+}
+
+type ShiftStore struct {
+	mu     sync.RWMutex
+	shifts map[string]*Shift
+}
+
+func NewShiftStore() *ShiftStore {
+	return &ShiftStore{shifts: make(map[string]*Shift)}
+}
+
+// openShiftLocked returns the driver's open shift, if any. Callers must hold
+// s.mu.
+func (s *ShiftStore) openShiftLocked(driverID string) *Shift {
+	for _, shift := range s.shifts {
+		if shift.DriverID == driverID && shift.Status == ShiftOpen {
+			return shift
+		}
+	}
+	return nil
+}
+
+// drivingTimeLocked sums the driver's driving time within [from, to), with
+// open shifts counting until now. Callers must hold s.mu.
+func (s *ShiftStore) drivingTimeLocked(driverID string, from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, shift := range s.shifts {
+		if shift.DriverID != driverID {
+			continue
+		}
+		start, end := shift.StartedAt, now
+		if shift.EndedAt != nil {
+			end = *shift.EndedAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// defaultMaxDailyDriving is the longest working day allowed by §3 ArbZG
+const defaultMaxDailyDriving = 10 * time.Hour
+
+var (
+	shiftStore      = NewShiftStore()
+	maxDailyDriving = defaultMaxDailyDriving
+)
+
+// loadMaxDailyDriving reads SHIFT_MAX_DAILY_DRIVING, e.g. "9h"
+func loadMaxDailyDriving() (time.Duration, error) {
+	v := os.Getenv("SHIFT_MAX_DAILY_DRIVING")
+	if v == "" {
+		return defaultMaxDailyDriving, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("SHIFT_MAX_DAILY_DRIVING must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// berlinDay returns the Berlin calendar day containing t as [start, end)
+func berlinDay(t time.Time) (time.Time, time.Time) {
+	local := t.In(timeutil.Berlin())
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, timeutil.Berlin())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// drivingLimitWarning describes a daily driving time over the limit
+func drivingLimitWarning(driving, limit time.Duration) string {
+	return fmt.Sprintf("Daily driving time of %.0f min exceeds the limit of %.0f min", driving.Minutes(), limit.Minutes())
+}
+
+// checkDrivingLimitLocked marks shift non-compliant if the driver's driving
+// time on the day the shift started exceeds the limit. Callers must hold
+// shiftStore.mu for writing.
+func checkDrivingLimitLocked(shift *Shift, now time.Time) {
+	from, to := berlinDay(shift.StartedAt)
+	driving := shiftStore.drivingTimeLocked(shift.DriverID, from, to, now)
+	if driving > maxDailyDriving {
+		shift.Compliance = false
+		shift.Warning = drivingLimitWarning(driving, maxDailyDriving)
+	}
+}
+
+func createShiftHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DriverID string `json:"driver_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.DriverID == "" {
+		http.Error(w, "Driver ID required", http.StatusBadRequest)
+		return
+	}
+
+	shift := &Shift{
+		ID:         uuid.New().String(),
+		DriverID:   req.DriverID,
+		Status:     ShiftOpen,
+		StartedAt:  timeutil.Now(),
+		Compliance: true,
+	}
+
+	// The open shift check and the insert share one lock so concurrent
+	// requests cannot both open a shift.
+	shiftStore.mu.Lock()
+	if shiftStore.openShiftLocked(req.DriverID) != nil {
+		shiftStore.mu.Unlock()
+		http.Error(w, "Driver already has an open shift", http.StatusConflict)
+		return
+	}
+	shiftStore.shifts[shift.ID] = shift
+	checkDrivingLimitLocked(shift, shift.StartedAt)
+	created := *shift
+	shiftStore.mu.Unlock()
+
+	requestLogger(r.Context()).Info("Shift started", "shift_id", created.ID, "driver_id", created.DriverID)
+	if !created.Compliance {
+		requestLogger(r.Context()).Warn("Shift started over the daily driving limit", "shift_id", created.ID, "driver_id", created.DriverID, "warning", created.Warning)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// endShiftHandler ends an open shift and checks the driver's driving time
+// for the day against the limit
+func endShiftHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	shiftStore.mu.Lock()
+	shift, exists := shiftStore.shifts[id]
+	if !exists {
+		shiftStore.mu.Unlock()
+		http.Error(w, "Shift not found", http.StatusNotFound)
+		return
+	}
+
+	if shift.Status != ShiftOpen {
+		shiftStore.mu.Unlock()
+		http.Error(w, fmt.Sprintf("Shift is %s, only open shifts can be ended", shift.Status), http.StatusBadRequest)
+		return
+	}
+
+	now := timeutil.Now()
+	shift.Status = ShiftEnded
+	shift.EndedAt = &now
+	checkDrivingLimitLocked(shift, now)
+	ended := *shift
+	shiftStore.mu.Unlock()
+
+	if ended.Compliance {
+		requestLogger(r.Context()).Info("Shift ended", "shift_id", ended.ID, "driver_id", ended.DriverID)
+	} else {
+		requestLogger(r.Context()).Warn("Shift ended over the daily driving limit", "shift_id", ended.ID, "driver_id", ended.DriverID, "warning", ended.Warning)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ended)
+}
+
+// DriverShiftDay is a driver's driving time on the current Berlin calendar
+// day. Shifts lists the shifts that overlap the day.
+type DriverShiftDay struct {
+	DriverID   string  `json:"driver_id"`
+	Date       string  `json:"date"`
+	DrivingMin float64 `json:"driving_min"`
+	LimitMin   float64 `json:"limit_min"`
+	Compliant  bool    `json:"compliant"`
+	Warning    string  `json:"warning,omitempty"`
+	Shifts     []Shift `json:"shifts"`
+}
+
+// getTodayShiftsHandler serves GET /shifts/driver/{driver_id}/today. An open
+// shift that has run over the limit is marked non-compliant here, so the
+// violation is recorded even if the shift is never ended.
+func getTodayShiftsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	driverID := vars["driver_id"]
+
+	now := timeutil.Now()
+	from, to := berlinDay(now)
+	day := DriverShiftDay{
+		DriverID:  driverID,
+		Date:      from.Format("2006-01-02"),
+		LimitMin:  maxDailyDriving.Minutes(),
+		Compliant: true,
+		Shifts:    []Shift{},
+	}
+
+	shiftStore.mu.Lock()
+	driving := shiftStore.drivingTimeLocked(driverID, from, to, now)
+	if open := shiftStore.openShiftLocked(driverID); open != nil {
+		checkDrivingLimitLocked(open, now)
+	}
+	for _, shift := range shiftStore.shifts {
+		if shift.DriverID != driverID || !shift.StartedAt.Before(to) || (shift.EndedAt != nil && !shift.EndedAt.After(from)) {
+			continue
+		}
+		day.Shifts = append(day.Shifts, *shift)
+	}
+	shiftStore.mu.Unlock()
+
+	day.DrivingMin = roundMinutes(driving)
+	if driving > maxDailyDriving {
+		day.Compliant = false
+		day.Warning = drivingLimitWarning(driving, maxDailyDriving)
+	}
+	sort.Slice(day.Shifts, func(i, j int) bool { return day.Shifts[i].StartedAt.Before(day.Shifts[j].StartedAt) })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(day)
+}
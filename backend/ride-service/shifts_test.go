@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+func openTestShift(t *testing.T, driverID string) Shift {
+	t.Helper()
+	rec := doRequest(t, http.MethodPost, "/shifts", map[string]string{"driver_id": driverID})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("open shift: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var shift Shift
+	if err := json.NewDecoder(rec.Body).Decode(&shift); err != nil {
+		t.Fatalf("failed to decode shift: %v", err)
+	}
+	return shift
+}
+
+func endTestShift(t *testing.T, id string) Shift {
+	t.Helper()
+	rec := doRequest(t, http.MethodPut, "/shifts/"+id+"/end", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("end shift: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var shift Shift
+	if err := json.NewDecoder(rec.Body).Decode(&shift); err != nil {
+		t.Fatalf("failed to decode shift: %v", err)
+	}
+	return shift
+}
+
+func todayShifts(t *testing.T, driverID string) DriverShiftDay {
+	t.Helper()
+	rec := doRequest(t, http.MethodGet, "/shifts/driver/"+driverID+"/today", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("today: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var day DriverShiftDay
+	if err := json.NewDecoder(rec.Body).Decode(&day); err != nil {
+		t.Fatalf("failed to decode shift day: %v", err)
+	}
+	return day
+}
+
+// backdateShift moves the start of a shift back by d, keeping it on the
+// current Berlin day
+func backdateShift(t *testing.T, id string, d time.Duration) {
+	t.Helper()
+	start := time.Now().Add(-d)
+	if from, _ := berlinDay(time.Now()); start.Before(from) {
+		t.Skip("too close to Berlin midnight to backdate within the day")
+	}
+	shiftStore.shifts[id].StartedAt = start
+}
+
+func useMaxDailyDriving(t *testing.T, d time.Duration) {
+	t.Helper()
+	prev := maxDailyDriving
+	maxDailyDriving = d
+	t.Cleanup(func() { maxDailyDriving = prev })
+}
+
+func TestShiftWithinDailyLimit(t *testing.T) {
+	resetStores(t)
+	useMaxDailyDriving(t, 2*time.Hour)
+
+	shift := openTestShift(t, "driver-1")
+	if rec := doRequest(t, http.MethodPost, "/shifts", map[string]string{"driver_id": "driver-1"}); rec.Code != http.StatusConflict {
+		t.Errorf("second open shift: status = %d, want 409", rec.Code)
+	}
+	backdateShift(t, shift.ID, 30*time.Minute)
+
+	ended := endTestShift(t, shift.ID)
+	if ended.Status != ShiftEnded || ended.EndedAt == nil || !ended.Compliance || ended.Warning != "" {
+		t.Errorf("ended shift = %+v, want a compliant ended shift", ended)
+	}
+
+	day := todayShifts(t, "driver-1")
+	if day.DrivingMin < 29.9 || day.DrivingMin > 30.5 || !day.Compliant || day.LimitMin != 120 || len(day.Shifts) != 1 {
+		t.Errorf("today = %+v, want about 30 of 120 min in one shift", day)
+	}
+}
+
+func TestShiftsOverDailyLimitAreNotCompliant(t *testing.T) {
+	resetStores(t)
+	useMaxDailyDriving(t, 2*time.Hour)
+
+	morning := openTestShift(t, "driver-1")
+	backdateShift(t, morning.ID, 5*time.Hour)
+	// The morning shift ended after 90 minutes
+	ended := shiftStore.shifts[morning.ID].StartedAt.Add(90 * time.Minute)
+	shiftStore.shifts[morning.ID].Status = ShiftEnded
+	shiftStore.shifts[morning.ID].EndedAt = &ended
+
+	afternoon := openTestShift(t, "driver-1")
+	backdateShift(t, afternoon.ID, 45*time.Minute)
+
+	// The open shift crosses the limit before it ends
+	day := todayShifts(t, "driver-1")
+	if day.Compliant || day.Warning == "" || day.DrivingMin < 134.9 || len(day.Shifts) != 2 {
+		t.Errorf("today = %+v, want about 135 min flagged over the limit", day)
+	}
+	if day.Shifts[0].ID != morning.ID || !day.Shifts[0].Compliance || day.Shifts[1].Compliance {
+		t.Errorf("shifts = %+v, want only the afternoon shift marked non-compliant", day.Shifts)
+	}
+
+	if ended := endTestShift(t, afternoon.ID); ended.Compliance || ended.Warning == "" {
+		t.Errorf("ended shift = %+v, want it non-compliant with a warning", ended)
+	}
+	if day := todayShifts(t, "driver-2"); !day.Compliant || day.DrivingMin != 0 {
+		t.Errorf("other driver = %+v, want no driving time", day)
+	}
+}
+
+func TestEndShiftErrors(t *testing.T) {
+	resetStores(t)
+
+	if rec := doRequest(t, http.MethodPut, "/shifts/missing/end", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown shift: status = %d, want 404", rec.Code)
+	}
+	shift := openTestShift(t, "driver-1")
+	endTestShift(t, shift.ID)
+	if rec := doRequest(t, http.MethodPut, "/shifts/"+shift.ID+"/end", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("end twice: status = %d, want 400", rec.Code)
+	}
+	if rec := doRequest(t, http.MethodPost, "/shifts", map[string]string{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing driver_id: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestDrivingTimeIsClippedToTheDay(t *testing.T) {
+	store := NewShiftStore()
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, timeutil.Berlin())
+	to := from.AddDate(0, 0, 1)
+	start := from.Add(-2 * time.Hour)
+	end := from.Add(3 * time.Hour)
+	store.shifts["night"] = &Shift{ID: "night", DriverID: "driver-1", Status: ShiftEnded, StartedAt: start, EndedAt: &end}
+	store.shifts["open"] = &Shift{ID: "open", DriverID: "driver-1", Status: ShiftOpen, StartedAt: to.Add(-time.Hour)}
+
+	if got := store.drivingTimeLocked("driver-1", from, to, to.Add(time.Hour)); got != 4*time.Hour {
+		t.Errorf("driving time = %s, want 4h (3h after midnight plus the last hour)", got)
+	}
+}
+
+func TestLoadMaxDailyDriving(t *testing.T) {
+	if d, err := loadMaxDailyDriving(); err != nil || d != defaultMaxDailyDriving {
+		t.Errorf("default = %s, %v", d, err)
+	}
+	t.Setenv("SHIFT_MAX_DAILY_DRIVING", "9h")
+	if d, err := loadMaxDailyDriving(); err != nil || d != 9*time.Hour {
+		t.Errorf("9h = %s, %v", d, err)
+	}
+	t.Setenv("SHIFT_MAX_DAILY_DRIVING", "0")
+	if _, err := loadMaxDailyDriving(); err == nil {
+		t.Error("expected an error for a zero limit")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+)
+
+// ShortTripPolicy decides what happens when a ride is completed with the
+// dropoff (almost) at the pickup, which is usually an input error or an
+// attempt to collect the minimum fare without driving.
+type ShortTripPolicy string
+
+const (
+	// ShortTripWarn completes the ride and attaches a warning to it
+	ShortTripWarn ShortTripPolicy = "warn"
+	// ShortTripReject refuses to complete the ride
+	ShortTripReject ShortTripPolicy = "reject"
+)
+
+// WarningDropoffNearPickup is attached to rides completed under ShortTripWarn
+const WarningDropoffNearPickup = "DROPOFF_NEAR_PICKUP"
+
+// ShortTripConfig configures the pickup/dropoff distance check on completion
+type ShortTripConfig struct {
+	MinDistanceMeters float64
+	Policy            ShortTripPolicy
+}
+
+func defaultShortTripConfig() ShortTripConfig {
+	return ShortTripConfig{MinDistanceMeters: 50, Policy: ShortTripWarn}
+}
+
+// loadShortTripConfig reads MIN_TRIP_DISTANCE_METERS and SHORT_TRIP_POLICY
+func loadShortTripConfig() (ShortTripConfig, error) {
+	cfg := defaultShortTripConfig()
+
+	if v := os.Getenv("MIN_TRIP_DISTANCE_METERS"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return cfg, fmt.Errorf("MIN_TRIP_DISTANCE_METERS must be a non-negative number, got %q", v)
+		}
+		cfg.MinDistanceMeters = f
+	}
+
+	switch policy := ShortTripPolicy(os.Getenv("SHORT_TRIP_POLICY")); policy {
+	case "":
+	case ShortTripWarn, ShortTripReject:
+		cfg.Policy = policy
+	default:
+		return cfg, fmt.Errorf("SHORT_TRIP_POLICY must be %q or %q, got %q", ShortTripWarn, ShortTripReject, policy)
+	}
+
+	return cfg, nil
+}
+
+// tripTooShort reports whether the dropoff is within the minimum distance of
+// the pickup, and the distance in metres. A zero MinDistanceMeters disables
+// the check.
+func (cfg ShortTripConfig) tripTooShort(pickupLat, pickupLon, dropoffLat, dropoffLon float64) (bool, float64) {
+	meters := distance.HaversineKm(pickupLat, pickupLon, dropoffLat, dropoffLon) * 1000
+	return cfg.MinDistanceMeters > 0 && meters < cfg.MinDistanceMeters, meters
+}
+
+// validCoordinates reports whether lat and lon are within the WGS 84 ranges
+func validCoordinates(lat, lon float64) bool {
+	return !math.IsNaN(lat) && !math.IsNaN(lon) && lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// tripDistanceKm is the straight-line distance from pickup to dropoff,
+// rounded to metres
+func tripDistanceKm(pickupLat, pickupLon, dropoffLat, dropoffLon float64) float64 {
+	return math.Round(distance.HaversineKm(pickupLat, pickupLon, dropoffLat, dropoffLon)*1000) / 1000
+}
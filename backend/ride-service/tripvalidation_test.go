@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/distance"
+)
+
+func startTestRide(t *testing.T) Ride {
+	t.Helper()
+	ride := createTestRide(t, "rider-1")
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/match", map[string]string{"driver_id": "driver-1"})
+	doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/start", nil)
+	return ride
+}
+
+func useShortTripConfig(t *testing.T, cfg ShortTripConfig) {
+	t.Helper()
+	prev := shortTrips
+	shortTrips = cfg
+	t.Cleanup(func() { shortTrips = prev })
+}
+
+func TestCompleteRideWarnsOnDropoffNearPickup(t *testing.T) {
+	resetStores(t)
+	useShortTripConfig(t, ShortTripConfig{MinDistanceMeters: 50, Policy: ShortTripWarn})
+
+	ride := startTestRide(t)
+	// About 10m north of the pickup.
+	rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/complete", map[string]interface{}{
+		"dropoff_lat": ride.PickupLat + 0.00009,
+		"dropoff_lon": ride.PickupLon,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var completed Ride
+	if err := json.NewDecoder(rec.Body).Decode(&completed); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if completed.Status != RideCompleted || len(completed.Warnings) != 1 || completed.Warnings[0] != WarningDropoffNearPickup {
+		t.Errorf("unexpected ride %+v", completed)
+	}
+}
+
+func TestCompleteRideRejectsDropoffNearPickup(t *testing.T) {
+	resetStores(t)
+	useShortTripConfig(t, ShortTripConfig{MinDistanceMeters: 50, Policy: ShortTripReject})
+
+	ride := startTestRide(t)
+	rec := doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/complete", map[string]interface{}{
+		"dropoff_lat": ride.PickupLat,
+		"dropoff_lon": ride.PickupLon,
+	})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	if memoryStore(t).rides[ride.ID].Status != RideStarted {
+		t.Error("rejected ride must stay started")
+	}
+
+	// A real trip (Mitte to Alexanderplatz, ~1km) completes without warnings.
+	rec = doRequest(t, http.MethodPut, "/rides/"+ride.ID+"/complete", map[string]interface{}{
+		"dropoff_lat": 52.5219,
+		"dropoff_lon": 13.4132,
+	})
+	var completed Ride
+	if err := json.NewDecoder(rec.Body).Decode(&completed); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(completed.Warnings) != 0 {
+		t.Errorf("status = %d, warnings = %v", rec.Code, completed.Warnings)
+	}
+}
+
+func TestLoadShortTripConfig(t *testing.T) {
+	t.Setenv("MIN_TRIP_DISTANCE_METERS", "100")
+	t.Setenv("SHORT_TRIP_POLICY", "reject")
+
+	cfg, err := loadShortTripConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MinDistanceMeters != 100 || cfg.Policy != ShortTripReject {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	t.Setenv("SHORT_TRIP_POLICY", "ignore")
+	if _, err := loadShortTripConfig(); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestCompleteRideRecordsDistance(t *testing.T) {
+	resetStores(t)
+	useShortTripConfig(t, ShortTripConfig{})
+	ride := startTestRide(t)
+
+	// Berlin Mitte to Alexanderplatz
+	rec := completeTestRide(t, ride.ID, map[string]interface{}{"dropoff_lat": 52.5219, "dropoff_lon": 13.4132})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var completed Ride
+	if err := json.NewDecoder(rec.Body).Decode(&completed); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	want := distance.HaversineKm(52.5200, 13.4050, 52.5219, 13.4132)
+	if math.Abs(completed.DistanceKm-want) > 0.001 || completed.DistanceKm == 0 {
+		t.Errorf("distance_km = %v, want %.3f", completed.DistanceKm, want)
+	}
+}
+
+func TestCompleteRideRejectsInvalidDropoff(t *testing.T) {
+	resetStores(t)
+	ride := startTestRide(t)
+
+	for _, dropoff := range []map[string]interface{}{
+		{"dropoff_lat": 91.0, "dropoff_lon": 13.4},
+		{"dropoff_lat": 52.5, "dropoff_lon": -180.5},
+		{"dropoff_lat": 52.5},
+		{"dropoff_lon": 0.0},
+	} {
+		if rec := completeTestRide(t, ride.ID, dropoff); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", dropoff, rec.Code)
+		}
+	}
+	if got := memoryStore(t).rides[ride.ID].Status; got != RideStarted {
+		t.Errorf("status = %s, ride must stay started", got)
+	}
+}
+
+func TestCompleteRideWithZeroDropoffCoordinates(t *testing.T) {
+	resetStores(t)
+	useShortTripConfig(t, ShortTripConfig{})
+	ride := startTestRide(t)
+
+	// 0, 0 is a valid coordinate pair and must not be mistaken for a missing
+	// dropoff
+	rec := completeTestRide(t, ride.ID, map[string]interface{}{"dropoff_lat": 0.0, "dropoff_lon": 0.0})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var completed Ride
+	if err := json.NewDecoder(rec.Body).Decode(&completed); err != nil {
+		t.Fatalf("failed to decode ride: %v", err)
+	}
+	want := distance.HaversineKm(ride.PickupLat, ride.PickupLon, 0, 0)
+	if math.Abs(completed.DistanceKm-want) > 0.001 {
+		t.Errorf("distance_km = %v, want %.3f", completed.DistanceKm, want)
+	}
+}
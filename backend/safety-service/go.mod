@@ -1,3 +1,11 @@
 module github.com/rideshare/safety-service
 
-go 1.21
\ No newline at end of file
+go 1.21
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/mux v1.8.1
+	github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
+)
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
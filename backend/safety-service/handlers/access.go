@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Roles the gateway forwards in X-User-Role that grant access to other users'
+// safety data.
+const (
+	RoleAdmin  = "admin"
+	RoleSafety = "safety"
+)
+
+// isSafetyStaff reports whether the caller identified by the gateway has the
+// admin or safety role.
+func isSafetyStaff(r *http.Request) bool {
+	role := r.Header.Get("X-User-Role")
+	return role == RoleAdmin || role == RoleSafety
+}
+
+// mayAccessSubject reports whether the caller may read data about userID:
+// the user themselves or safety staff.
+func mayAccessSubject(r *http.Request, userID string) bool {
+	caller := r.Header.Get("X-User-ID")
+	return (caller != "" && caller == userID) || isSafetyStaff(r)
+}
+
+// writeForbidden rejects a caller that is not allowed to see the resource.
+func writeForbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": "forbidden"})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+// ClearanceHandler serves the driver clearance endpoint, the single check of
+// whether a driver may be matched to rides.
+type ClearanceHandler struct {
+	logger       *log.Logger
+	cases        *services.IdentityCaseStore
+	pscheins     services.PScheinStatusSource
+	documents    *services.DocumentStore
+	requiredDocs []string
+}
+
+// NewClearanceHandler constructs a ClearanceHandler. A nil requiredDocs uses
+// services.DefaultRequiredDocuments.
+func NewClearanceHandler(logger *log.Logger, cases *services.IdentityCaseStore, pscheins services.PScheinStatusSource, documents *services.DocumentStore, requiredDocs []string) *ClearanceHandler {
+	if requiredDocs == nil {
+		requiredDocs = services.DefaultRequiredDocuments
+	}
+	return &ClearanceHandler{
+		logger:       logger,
+		cases:        cases,
+		pscheins:     pscheins,
+		documents:    documents,
+		requiredDocs: requiredDocs,
+	}
+}
+
+// ParseRequiredDocuments parses a comma-separated list of document types, as
+// in REQUIRED_DRIVER_DOCUMENTS. Every entry must be an uploadable doc_type.
+func ParseRequiredDocuments(v string) ([]string, error) {
+	docs := []string{}
+	for _, docType := range strings.Split(v, ",") {
+		docType = strings.TrimSpace(docType)
+		if docType == "" {
+			continue
+		}
+		if !allowedDocTypes[docType] {
+			return nil, fmt.Errorf("unknown document type %q", docType)
+		}
+		docs = append(docs, docType)
+	}
+	return docs, nil
+}
+
+// GetClearance handles GET /drivers/{id}/clearance
+func (h *ClearanceHandler) GetClearance(w http.ResponseWriter, r *http.Request) {
+	driverID := mux.Vars(r)["id"]
+
+	clearance, err := services.CheckClearance(r.Context(), driverID, h.cases, h.pscheins, h.documents, h.requiredDocs, timeutil.Now())
+	if err != nil {
+		h.logger.Printf("Clearance check for driver %s failed: P-Schein status unavailable: %v", driverID, err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": "P-Schein status unavailable"})
+		return
+	}
+
+	if !clearance.ClearedToDrive {
+		h.logger.Printf("Driver %s is not cleared to drive: %d items missing", driverID, len(clearance.Missing))
+	}
+
+	json.NewEncoder(w).Encode(clearance)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+// stubPScheinSource answers every lookup with status, or with err if set.
+type stubPScheinSource struct {
+	status services.PScheinStatus
+	err    error
+}
+
+func (s *stubPScheinSource) PScheinStatus(ctx context.Context, userID string) (services.PScheinStatus, error) {
+	return s.status, s.err
+}
+
+func TestGetClearance(t *testing.T) {
+	cases := services.NewIdentityCaseStore()
+	pscheins := &stubPScheinSource{status: services.PScheinStatus{Status: "PENDING"}}
+	clearance := NewClearanceHandler(log.New(io.Discard, "", 0), cases, pscheins, services.NewDocumentStore(0), []string{})
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/drivers/{id}/clearance", clearance.GetClearance).Methods(http.MethodGet)
+
+	getClearance := func() services.Clearance {
+		t.Helper()
+		rec := serveRequest(r, http.MethodGet, "/api/v1/drivers/driver-1/clearance", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var c services.Clearance
+		if err := json.NewDecoder(rec.Body).Decode(&c); err != nil {
+			t.Fatalf("failed to decode clearance: %v", err)
+		}
+		return c
+	}
+
+	if c := getClearance(); c.ClearedToDrive || len(c.Missing) != 2 {
+		t.Errorf("pending P-Schein: %+v, want identity and P-Schein missing", c)
+	}
+
+	cases.Create("case-1", "driver-1", timeutil.Now())
+	cases.Complete("case-1", services.IdentitySuccess, timeutil.Now())
+	pscheins.status = services.PScheinStatus{Status: services.PScheinVerified}
+	if c := getClearance(); !c.ClearedToDrive || len(c.Missing) != 0 {
+		t.Errorf("verified driver: %+v, want cleared", c)
+	}
+
+	// Without the user-service the driver is not reported as cleared
+	pscheins.err = errors.New("dial tcp 10.0.0.7:8080: connection refused")
+	rec := serveRequest(r, http.MethodGet, "/api/v1/drivers/driver-1/clearance", "")
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("user-service down: status = %d, want 502", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "10.0.0.7") || strings.Contains(body, "cleared_to_drive") {
+		t.Errorf("user-service down: body = %s", body)
+	}
+}
+
+func TestParseRequiredDocuments(t *testing.T) {
+	docs, err := ParseRequiredDocuments(" ID, Insurance ,")
+	if err != nil || !reflect.DeepEqual(docs, []string{"ID", "Insurance"}) {
+		t.Errorf("got %v (%v)", docs, err)
+	}
+	if _, err := ParseRequiredDocuments("ID,Passport"); err == nil {
+		t.Error("expected an error for an unknown document type")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+const testPostidentSecret = "postident-test-secret"
+
+func newIdentityTestRouter(t *testing.T, secret string) *mux.Router {
+	t.Helper()
+	h := NewVerificationHandler(log.New(io.Discard, "", 0), testEncryption(t, testKey), services.NewDocumentStore(0), piiaudit.New(nil), services.NewIdentityCaseStore(), secret, 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/verify/identity", h.VerifyIdentity).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/verify/identity/callback", h.IdentityCallback).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/verify/identity/{case_id}", h.GetIdentityCase).Methods(http.MethodGet)
+	return r
+}
+
+func serveIdentity(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func initiateTestCase(t *testing.T, r http.Handler) string {
+	t.Helper()
+	rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity", `{"user_id": "user-1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("initiate: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp IdentityVerificationResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.CaseID
+}
+
+func callbackBody(caseID, status, signature string) string {
+	return fmt.Sprintf(`{"case_id": %q, "status": %q, "signature": %q}`, caseID, status, signature)
+}
+
+func caseStatus(t *testing.T, r http.Handler, caseID string) string {
+	t.Helper()
+	rec := serveIdentity(r, http.MethodGet, "/api/v1/verify/identity/"+caseID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get case: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var c services.IdentityCase
+	if err := json.NewDecoder(rec.Body).Decode(&c); err != nil {
+		t.Fatalf("failed to decode case: %v", err)
+	}
+	return c.Status
+}
+
+func TestIdentityCallbackUpdatesCase(t *testing.T) {
+	r := newIdentityTestRouter(t, testPostidentSecret)
+	caseID := initiateTestCase(t, r)
+
+	if got := caseStatus(t, r, caseID); got != services.IdentityInitiated {
+		t.Fatalf("status before callback = %s, want INITIATED", got)
+	}
+
+	sig := services.SignIdentityCallback(testPostidentSecret, caseID, services.IdentitySuccess)
+	rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody(caseID, services.IdentitySuccess, sig))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := caseStatus(t, r, caseID); got != services.IdentitySuccess {
+		t.Errorf("status after callback = %s, want SUCCESS", got)
+	}
+
+	// A redelivered webhook is accepted; a contradicting one is not.
+	if rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody(caseID, services.IdentitySuccess, sig)); rec.Code != http.StatusOK {
+		t.Errorf("redelivery: status = %d, want 200", rec.Code)
+	}
+	failSig := services.SignIdentityCallback(testPostidentSecret, caseID, services.IdentityFailed)
+	if rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody(caseID, services.IdentityFailed, failSig)); rec.Code != http.StatusConflict {
+		t.Errorf("contradicting result: status = %d, want 409", rec.Code)
+	}
+}
+
+func TestIdentityCallbackRejectsBadSignatures(t *testing.T) {
+	r := newIdentityTestRouter(t, testPostidentSecret)
+	caseID := initiateTestCase(t, r)
+
+	for name, sig := range map[string]string{
+		"unsigned":          "",
+		"wrong secret":      services.SignIdentityCallback("other-secret", caseID, services.IdentitySuccess),
+		"signed for FAILED": services.SignIdentityCallback(testPostidentSecret, caseID, services.IdentityFailed),
+		"not hex":           "zz",
+	} {
+		rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody(caseID, services.IdentitySuccess, sig))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", name, rec.Code)
+		}
+	}
+	if got := caseStatus(t, r, caseID); got != services.IdentityInitiated {
+		t.Errorf("status = %s after rejected callbacks, want INITIATED", got)
+	}
+}
+
+func TestIdentityCallbackWithoutSecretRejectsEverything(t *testing.T) {
+	r := newIdentityTestRouter(t, "")
+	caseID := initiateTestCase(t, r)
+
+	sig := services.SignIdentityCallback("", caseID, services.IdentitySuccess)
+	if rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody(caseID, services.IdentitySuccess, sig)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestIdentityCallbackErrors(t *testing.T) {
+	r := newIdentityTestRouter(t, testPostidentSecret)
+	caseID := initiateTestCase(t, r)
+
+	unknownSig := services.SignIdentityCallback(testPostidentSecret, "unknown", services.IdentitySuccess)
+	if rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody("unknown", services.IdentitySuccess, unknownSig)); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown case: status = %d, want 404", rec.Code)
+	}
+
+	badSig := services.SignIdentityCallback(testPostidentSecret, caseID, "APPROVED")
+	if rec := serveIdentity(r, http.MethodPost, "/api/v1/verify/identity/callback", callbackBody(caseID, "APPROVED", badSig)); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: status = %d, want 400", rec.Code)
+	}
+
+	if rec := serveIdentity(r, http.MethodGet, "/api/v1/verify/identity/unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get unknown case: status = %d, want 404", rec.Code)
+	}
+}
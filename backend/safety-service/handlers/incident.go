@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+// MaxIncidentDescriptionLength is the longest accepted incident description,
+// in characters.
+const MaxIncidentDescriptionLength = 5000
+
+// IncidentHandler serves the incident report endpoints.
+type IncidentHandler struct {
+	logger        *log.Logger
+	encryptionSvc *services.EncryptionService
+	incidents     *services.IncidentStore
+}
+
+// NewIncidentHandler constructs an IncidentHandler. Descriptions are
+// encrypted with encSvc.
+func NewIncidentHandler(logger *log.Logger, encSvc *services.EncryptionService, incidents *services.IncidentStore) *IncidentHandler {
+	return &IncidentHandler{logger: logger, encryptionSvc: encSvc, incidents: incidents}
+}
+
+// IncidentRequest is the payload for POST /incidents.
+type IncidentRequest struct {
+	RideID      string `json:"ride_id"`
+	ReporterID  string `json:"reporter_id"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+}
+
+// IncidentStatusRequest is the payload for PUT /incidents/{id}/status.
+type IncidentStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// IncidentResponse is an incident with its decrypted description.
+type IncidentResponse struct {
+	services.Incident
+	Description string `json:"description"`
+}
+
+// CreateIncident handles POST /incidents
+func (h *IncidentHandler) CreateIncident(w http.ResponseWriter, r *http.Request) {
+	var req IncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request payload"})
+		return
+	}
+
+	if req.RideID == "" || req.ReporterID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ride_id and reporter_id are required"})
+		return
+	}
+	if !services.ValidIncidentCategory(req.Category) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "category must be harassment, accident, lost_item or other"})
+		return
+	}
+	if strings.TrimSpace(req.Description) == "" || utf8.RuneCountInString(req.Description) > MaxIncidentDescriptionLength {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("description is required and may be at most %d characters", MaxIncidentDescriptionLength)})
+		return
+	}
+
+	encrypted, err := h.encryptionSvc.Encrypt([]byte(req.Description))
+	if err != nil {
+		h.logger.Printf("ERROR: failed to encrypt incident description: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to store incident"})
+		return
+	}
+	incident := h.incidents.Create(req.RideID, req.ReporterID, req.Category, encrypted, timeutil.Now())
+
+	// The description is personal data and is never logged
+	h.logger.Printf("Incident %s (%s) reported on ride %s by %s", incident.ID, incident.Category, incident.RideID, incident.ReporterID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(IncidentResponse{Incident: incident, Description: req.Description})
+}
+
+// GetIncident handles GET /incidents/{id}
+//
+// Safety staff and the reporter may read a report; nobody else sees the
+// description.
+func (h *IncidentHandler) GetIncident(w http.ResponseWriter, r *http.Request) {
+	incident, found := h.incidents.Get(mux.Vars(r)["id"])
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "incident not found"})
+		return
+	}
+	if !mayAccessSubject(r, incident.ReporterID) {
+		writeForbidden(w)
+		return
+	}
+
+	description, err := h.encryptionSvc.Decrypt(incident.EncryptedDescription)
+	if err != nil {
+		h.logger.Printf("ERROR: failed to decrypt description of incident %s: %v", incident.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read incident"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(IncidentResponse{Incident: incident, Description: string(description)})
+}
+
+// UpdateIncidentStatus handles PUT /incidents/{id}/status
+//
+// Only safety staff progress reports; the reporter cannot close their own.
+func (h *IncidentHandler) UpdateIncidentStatus(w http.ResponseWriter, r *http.Request) {
+	if !isSafetyStaff(r) {
+		writeForbidden(w)
+		return
+	}
+
+	var req IncidentStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request payload"})
+		return
+	}
+	if !services.ValidIncidentStatus(req.Status) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "status must be open, investigating, resolved or closed"})
+		return
+	}
+
+	incident, err := h.incidents.UpdateStatus(mux.Vars(r)["id"], req.Status, timeutil.Now())
+	switch {
+	case errors.Is(err, services.ErrIncidentNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "incident not found"})
+		return
+	case errors.Is(err, services.ErrInvalidIncidentTransition):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("cannot move incident from %s to %s", incident.Status, req.Status)})
+		return
+	}
+
+	h.logger.Printf("Incident %s is now %s", incident.ID, incident.Status)
+
+	json.NewEncoder(w).Encode(incident)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+func newIncidentTestRouter(t *testing.T, incidents *services.IncidentStore) *mux.Router {
+	t.Helper()
+	h := NewIncidentHandler(log.New(io.Discard, "", 0), testEncryption(t, testKey), incidents)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/incidents", h.CreateIncident).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/incidents/{id}", h.GetIncident).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/incidents/{id}/status", h.UpdateIncidentStatus).Methods(http.MethodPut)
+	return r
+}
+
+// serveAs serves a request from the caller the gateway identified as userID
+// with role
+func serveAs(r http.Handler, method, path, body, userID, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User-ID", userID)
+	req.Header.Set("X-User-Role", role)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIncidentLifecycle(t *testing.T) {
+	incidents := services.NewIncidentStore()
+	r := newIncidentTestRouter(t, incidents)
+	description := "Der Fahrer hat mich nach meiner Telefonnummer gefragt."
+
+	rec := serveRequest(r, http.MethodPost, "/api/v1/incidents", `{"ride_id": "ride-1", "reporter_id": "rider-1", "category": "harassment", "description": "`+description+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var created IncidentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode incident: %v", err)
+	}
+	if created.ID == "" || created.Status != services.IncidentOpen || created.Category != services.IncidentHarassment {
+		t.Errorf("unexpected incident %+v", created)
+	}
+
+	stored, _ := incidents.Get(created.ID)
+	if len(stored.EncryptedDescription) == 0 || bytes.Contains(stored.EncryptedDescription, []byte(description)) {
+		t.Error("description is not encrypted at rest")
+	}
+
+	rec = serveAs(r, http.MethodGet, "/api/v1/incidents/"+created.ID, "", "staff-1", RoleSafety)
+	var fetched IncidentResponse
+	json.NewDecoder(rec.Body).Decode(&fetched)
+	if rec.Code != http.StatusOK || fetched.Description != description {
+		t.Errorf("get: status = %d, description = %q", rec.Code, fetched.Description)
+	}
+
+	for _, status := range []string{services.IncidentInvestigating, services.IncidentResolved, services.IncidentClosed} {
+		rec = serveAs(r, http.MethodPut, "/api/v1/incidents/"+created.ID+"/status", `{"status": "`+status+`"}`, "staff-1", RoleSafety)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("move to %s: status = %d, body = %s", status, rec.Code, rec.Body.String())
+		}
+	}
+	if rec = serveAs(r, http.MethodPut, "/api/v1/incidents/"+created.ID+"/status", `{"status": "open"}`, "staff-1", RoleSafety); rec.Code != http.StatusConflict {
+		t.Errorf("reopen closed incident: status = %d, want 409", rec.Code)
+	}
+}
+
+func TestIncidentValidation(t *testing.T) {
+	r := newIncidentTestRouter(t, services.NewIncidentStore())
+
+	for _, body := range []string{
+		`{"reporter_id": "rider-1", "category": "accident", "description": "Auffahrunfall"}`,
+		`{"ride_id": "ride-1", "category": "accident", "description": "Auffahrunfall"}`,
+		`{"ride_id": "ride-1", "reporter_id": "rider-1", "category": "rudeness", "description": "Unfreundlich"}`,
+		`{"ride_id": "ride-1", "reporter_id": "rider-1", "category": "other", "description": "  "}`,
+		`not json`,
+	} {
+		if rec := serveRequest(r, http.MethodPost, "/api/v1/incidents", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	if rec := serveAs(r, http.MethodGet, "/api/v1/incidents/unknown", "", "staff-1", RoleSafety); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown incident: status = %d, want 404", rec.Code)
+	}
+	if rec := serveAs(r, http.MethodPut, "/api/v1/incidents/unknown/status", `{"status": "closed"}`, "staff-1", RoleSafety); rec.Code != http.StatusNotFound {
+		t.Errorf("update unknown incident: status = %d, want 404", rec.Code)
+	}
+	if rec := serveAs(r, http.MethodPut, "/api/v1/incidents/unknown/status", `{"status": "escalated"}`, "staff-1", RoleSafety); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestIncidentAccess(t *testing.T) {
+	r := newIncidentTestRouter(t, services.NewIncidentStore())
+	rec := serveRequest(r, http.MethodPost, "/api/v1/incidents", `{"ride_id": "ride-1", "reporter_id": "rider-1", "category": "harassment", "description": "Belästigung"}`)
+	var created IncidentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode incident: %v", err)
+	}
+	path := "/api/v1/incidents/" + created.ID
+
+	for _, c := range []struct {
+		name, userID, role string
+		read               int
+		update             bool
+	}{
+		{"reporter", "rider-1", "rider", http.StatusOK, false},
+		{"driver of the ride", "driver-1", "driver", http.StatusForbidden, false},
+		{"no identity", "", "", http.StatusForbidden, false},
+		{"safety staff", "staff-1", RoleSafety, http.StatusOK, true},
+		{"admin", "admin-1", RoleAdmin, http.StatusOK, true},
+	} {
+		rec := serveAs(r, http.MethodGet, path, "", c.userID, c.role)
+		if rec.Code != c.read {
+			t.Errorf("%s: read status = %d, want %d", c.name, rec.Code, c.read)
+		}
+		if c.read == http.StatusForbidden && strings.Contains(rec.Body.String(), "Belästigung") {
+			t.Errorf("%s: forbidden response leaks the description", c.name)
+		}
+		// Staff may be refused the move itself, e.g. investigating again
+		rec = serveAs(r, http.MethodPut, path+"/status", `{"status": "investigating"}`, c.userID, c.role)
+		if forbidden := rec.Code == http.StatusForbidden; forbidden == c.update {
+			t.Errorf("%s: update status = %d, want forbidden = %v", c.name, rec.Code, !c.update)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+func submitPSchein(t *testing.T, expiry string) (int, PScheinVerificationResponse) {
+	t.Helper()
+	h := NewVerificationHandler(log.New(io.Discard, "", 0), testEncryption(t, testKey), services.NewDocumentStore(0), piiaudit.New(nil), services.NewIdentityCaseStore(), "", 0)
+	rec := serveIdentity(http.HandlerFunc(h.VerifyPSchein), http.MethodPost, "/api/v1/verify/p-schein",
+		fmt.Sprintf(`{"user_id": "driver-1", "p_schein_number": "PS-123", "expiry_date": %q}`, expiry))
+
+	var resp PScheinVerificationResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestVerifyPScheinExpiry(t *testing.T) {
+	date := func(days int) string { return timeutil.Now().AddDate(0, 0, days).Format("2006-01-02") }
+
+	code, resp := submitPSchein(t, date(-3))
+	if code != http.StatusOK || resp.Status != "REJECTED" {
+		t.Errorf("expired: status %d, %+v, want REJECTED", code, resp)
+	}
+
+	code, resp = submitPSchein(t, date(10))
+	if code != http.StatusOK || resp.Status != "PENDING" || !strings.Contains(resp.Message, "Warning") {
+		t.Errorf("expiring soon: status %d, %+v, want PENDING with a renewal warning", code, resp)
+	}
+
+	code, resp = submitPSchein(t, date(365))
+	if code != http.StatusOK || resp.Status != "PENDING" || strings.Contains(resp.Message, "Warning") {
+		t.Errorf("valid: status %d, %+v, want PENDING without warning", code, resp)
+	}
+}
+
+func TestVerifyPScheinMalformedExpiry(t *testing.T) {
+	for _, expiry := range []string{"", "31.12.2030", "2030-02-30", timeutil.Now().Add(48 * time.Hour).Format(time.RFC3339)} {
+		if code, _ := submitPSchein(t, expiry); code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", expiry, code)
+		}
+	}
+}
+
+func TestVerifyPScheinLogsMaskedNumber(t *testing.T) {
+	var logs bytes.Buffer
+	h := NewVerificationHandler(log.New(&logs, "", 0), testEncryption(t, testKey), services.NewDocumentStore(0), piiaudit.New(nil), services.NewIdentityCaseStore(), "", 0)
+	serveIdentity(http.HandlerFunc(h.VerifyPSchein), http.MethodPost, "/api/v1/verify/p-schein",
+		`{"user_id": "driver-1", "p_schein_number": "PS-123456", "expiry_date": "2099-12-31"}`)
+
+	if strings.Contains(logs.String(), "PS-123456") || !strings.Contains(logs.String(), "*****3456") {
+		t.Errorf("log does not mask the P-Schein number: %s", logs.String())
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+// SOSHandler serves the panic-button endpoints.
+type SOSHandler struct {
+	logger *log.Logger
+	events *services.SOSStore
+}
+
+// NewSOSHandler constructs an SOSHandler.
+func NewSOSHandler(logger *log.Logger, events *services.SOSStore) *SOSHandler {
+	return &SOSHandler{logger: logger, events: events}
+}
+
+// SOSRequest is the payload for POST /sos. Lat and Lng are the rider's last
+// known location.
+type SOSRequest struct {
+	RideID string   `json:"ride_id"`
+	UserID string   `json:"user_id"`
+	Lat    *float64 `json:"lat"`
+	Lng    *float64 `json:"lng"`
+}
+
+// TriggerSOS handles POST /sos
+func (h *SOSHandler) TriggerSOS(w http.ResponseWriter, r *http.Request) {
+	var req SOSRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request payload"})
+		return
+	}
+
+	if req.RideID == "" || req.UserID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ride_id and user_id are required"})
+		return
+	}
+	if req.Lat == nil || req.Lng == nil || !validCoordinates(*req.Lat, *req.Lng) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "lat and lng are required; lat must be within [-90, 90] and lng within [-180, 180]"})
+		return
+	}
+
+	event := h.events.Record(req.RideID, req.UserID, *req.Lat, *req.Lng, timeutil.Now())
+
+	h.logger.Printf("[ALERT][PRIORITY=HIGH] SOS %s on ride %s, user %s at %.6f,%.6f", event.CaseID, event.RideID, event.UserID, event.Lat, event.Lng)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(event)
+}
+
+// GetSOS handles GET /sos/{case_id}
+//
+// The event holds the user's location, so only that user and safety staff
+// may read it.
+func (h *SOSHandler) GetSOS(w http.ResponseWriter, r *http.Request) {
+	event, found := h.events.Get(mux.Vars(r)["case_id"])
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "SOS case not found"})
+		return
+	}
+	if !mayAccessSubject(r, event.UserID) {
+		writeForbidden(w)
+		return
+	}
+
+	json.NewEncoder(w).Encode(event)
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return !math.IsNaN(lat) && !math.IsNaN(lng) && lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+func serveRequest(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func newSOSTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	h := NewSOSHandler(log.New(io.Discard, "", 0), services.NewSOSStore())
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/sos", h.TriggerSOS).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/sos/{case_id}", h.GetSOS).Methods(http.MethodGet)
+	return r
+}
+
+func TestTriggerSOS(t *testing.T) {
+	r := newSOSTestRouter(t)
+
+	rec := serveRequest(r, http.MethodPost, "/api/v1/sos", `{"ride_id": "ride-1", "user_id": "rider-1", "lat": 52.5200, "lng": 13.4050}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var created services.SOSEvent
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if created.CaseID == "" || created.TriggeredAt.IsZero() || created.Lat != 52.52 || created.Lng != 13.405 {
+		t.Errorf("unexpected event %+v", created)
+	}
+
+	rec = serveAs(r, http.MethodGet, "/api/v1/sos/"+created.CaseID, "", "staff-1", RoleSafety)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: status = %d", rec.Code)
+	}
+	var stored services.SOSEvent
+	if err := json.NewDecoder(rec.Body).Decode(&stored); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if stored != created {
+		t.Errorf("stored event %+v differs from created %+v", stored, created)
+	}
+}
+
+func TestTriggerSOSValidation(t *testing.T) {
+	r := newSOSTestRouter(t)
+
+	for _, body := range []string{
+		`{"user_id": "rider-1", "lat": 52.52, "lng": 13.405}`,
+		`{"ride_id": "ride-1", "lat": 52.52, "lng": 13.405}`,
+		`{"ride_id": "ride-1", "user_id": "rider-1", "lat": 52.52}`,
+		`{"ride_id": "ride-1", "user_id": "rider-1", "lat": 95, "lng": 13.405}`,
+		`not json`,
+	} {
+		if rec := serveRequest(r, http.MethodPost, "/api/v1/sos", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	if rec := serveAs(r, http.MethodGet, "/api/v1/sos/unknown", "", "staff-1", RoleSafety); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown case: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestGetSOSAccess(t *testing.T) {
+	r := newSOSTestRouter(t)
+	rec := serveRequest(r, http.MethodPost, "/api/v1/sos", `{"ride_id": "ride-1", "user_id": "rider-1", "lat": 52.5200, "lng": 13.4050}`)
+	var created services.SOSEvent
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+
+	for _, c := range []struct {
+		name, userID, role string
+		want               int
+	}{
+		{"user who raised it", "rider-1", "rider", http.StatusOK},
+		{"safety staff", "staff-1", RoleSafety, http.StatusOK},
+		{"admin", "admin-1", RoleAdmin, http.StatusOK},
+		{"other user", "driver-1", "driver", http.StatusForbidden},
+		{"no identity", "", "", http.StatusForbidden},
+	} {
+		if rec := serveAs(r, http.MethodGet, "/api/v1/sos/"+created.CaseID, "", c.userID, c.role); rec.Code != c.want {
+			t.Errorf("%s: status = %d, want %d", c.name, rec.Code, c.want)
+		}
+	}
+}
@@ -1,34 +1,79 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 
-	"safety-service/services"
+	"github.com/rideshare/safety-service/services"
 )
 
 // VerificationHandler holds dependencies for verification endpoints.
 type VerificationHandler struct {
 	logger        *log.Logger
 	encryptionSvc *services.EncryptionService
+	documents     *services.DocumentStore
+	accessLog     *piiaudit.Log
+	cases         *services.IdentityCaseStore
+
+	// postidentSecret is the shared secret POSTIDENT signs callbacks with
+	postidentSecret string
+
+	// maxDocumentSize is the largest accepted document file, in bytes
+	maxDocumentSize int64
 }
 
-// NewVerificationHandler constructs a VerificationHandler.
-func NewVerificationHandler(logger *log.Logger, aesKey string) *VerificationHandler {
-	encSvc, err := services.NewEncryptionService(aesKey)
-	if err != nil {
-		logger.Fatalf("Failed to initialize encryption service: %v", err)
+// DefaultMaxDocumentSize is the upload limit when none is configured.
+const DefaultMaxDocumentSize = 10 << 20
+
+// multipartOverhead is allowed on top of the document size for the other
+// form fields and multipart framing.
+const multipartOverhead = 1 << 20
+
+// allowedDocTypes are the documents drivers can upload.
+var allowedDocTypes = map[string]bool{
+	"P-Schein":            true,
+	"ID":                  true,
+	"Insurance":           true,
+	"VehicleRegistration": true,
+}
+
+// allowedContentTypes are the file types accepted on upload, as detected by
+// http.DetectContentType. The client-declared type is not trusted.
+var allowedContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// NewVerificationHandler constructs a VerificationHandler. A maxDocumentSize
+// of zero uses DefaultMaxDocumentSize. With an empty postidentSecret every
+// identity callback is rejected. Every document read is recorded in
+// accessLog.
+func NewVerificationHandler(logger *log.Logger, encSvc *services.EncryptionService, documents *services.DocumentStore, accessLog *piiaudit.Log, cases *services.IdentityCaseStore, postidentSecret string, maxDocumentSize int64) *VerificationHandler {
+	if maxDocumentSize <= 0 {
+		maxDocumentSize = DefaultMaxDocumentSize
 	}
 	return &VerificationHandler{
-		logger:        logger,
-		encryptionSvc: encSvc,
+		logger:          logger,
+		encryptionSvc:   encSvc,
+		documents:       documents,
+		accessLog:       accessLog,
+		cases:           cases,
+		postidentSecret: postidentSecret,
+		maxDocumentSize: maxDocumentSize,
 	}
 }
 
@@ -50,6 +95,15 @@ type IdentityVerificationResponse struct {
 	Message      string `json:"message"`
 }
 
+// IdentityCallbackRequest is the POSTIDENT result payload for
+// POST /verify/identity/callback. Signature is the hex HMAC-SHA256 of
+// "<case_id>:<status>" under the shared secret.
+type IdentityCallbackRequest struct {
+	CaseID    string `json:"case_id"`
+	Status    string `json:"status"`
+	Signature string `json:"signature"`
+}
+
 // PScheinVerificationRequest is the payload for POST /verify/p-schein.
 type PScheinVerificationRequest struct {
 	UserID        string `json:"user_id"`
@@ -68,71 +122,159 @@ type PScheinVerificationResponse struct {
 // --------------------------------------------------------------------------
 
 // VerifyIdentity handles POST /verify/identity
-func (h *VerificationHandler) VerifyIdentity(w Http.ResponseWriter, r *Http.Request) {
+func (h *VerificationHandler) VerifyIdentity(w http.ResponseWriter, r *http.Request) {
 	var req IdentityVerificationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.BadRequest)
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request payload"})
 		return
 	}
 
 	if req.UserID == "" {
 		log.Println("ERROR: user_id is required")
-		json.NewEncryption(w).Encode(map[string]string
-{"error": "user_id is required"})
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user_id is required"})
 		return
 	}
 
 	// Mock POSTIDENT case creation
 	caseID := uuid.New().String()
 	postidentURL := fmt.Sprintf("https://postident.de/api/v1/identify/%s", caseID)
+	identityCase := h.cases.Create(caseID, req.UserID, timeutil.Now())
 
-	h .logger.Printf("Identity verification initiated for user: %s, caseID: %s", req.UserID, caseID)
+	h.logger.Printf("Identity verification initiated for user: %s, caseID: %s", req.UserID, caseID)
 
 	resp := IdentityVerificationResponse{
 		UserID:       req.UserID,
-		CaseID;       caseID,
+		CaseID:       caseID,
 		PostidentURL: postidentURL,
-		Status:        "INITIATED",
-		Message:       "POSTIDENT identification case created successfully.",
+		Status:       identityCase.Status,
+		Message:      "POSTIDENT identification case created successfully.",
 	}
 
 	json.NewEncoder(w).Encode(resp)
 }
 
+// IdentityCallback handles POST /verify/identity/callback
+//
+// POSTIDENT reports the result of a case here. The signature is checked
+// before anything else, so unsigned callbacks reveal nothing about cases.
+func (h *VerificationHandler) IdentityCallback(w http.ResponseWriter, r *http.Request) {
+	var req IdentityCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request payload"})
+		return
+	}
+
+	if !services.VerifyIdentityCallback(h.postidentSecret, req.CaseID, req.Status, req.Signature) {
+		h.logger.Printf("WARNING: rejected identity callback for case %s: missing or invalid signature", req.CaseID)
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	if !services.IdentityFinalStatus(req.Status) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "status must be SUCCESS, FAILED or CANCELLED"})
+		return
+	}
+
+	identityCase, err := h.cases.Complete(req.CaseID, req.Status, timeutil.Now())
+	switch {
+	case errors.Is(err, services.ErrCaseNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "case not found"})
+		return
+	case errors.Is(err, services.ErrCaseAlreadyDecided):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("case already has status %s", identityCase.Status)})
+		return
+	}
+
+	h.logger.Printf("Identity verification for user: %s, caseID: %s is %s", identityCase.UserID, identityCase.CaseID, identityCase.Status)
+
+	json.NewEncoder(w).Encode(identityCase)
+}
+
+// GetIdentityCase handles GET /verify/identity/{case_id}
+func (h *VerificationHandler) GetIdentityCase(w http.ResponseWriter, r *http.Request) {
+	identityCase, found := h.cases.Get(mux.Vars(r)["case_id"])
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "case not found"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(identityCase)
+}
+
 // VerifyPSchein handles POST /verify/p-schein
-func (h *VerificationHandler) VerifyPSchein(w Http.ResponseWriter, r *Http.Request) {
+func (h *VerificationHandler) VerifyPSchein(w http.ResponseWriter, r *http.Request) {
 	var req PScheinVerificationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncryption(w).Encode(map[string]string
-{"error": "invalid request payload"})
-		return ()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request payload"})
+		return
 	}
 
-	h .logger.Printf("P-Schein verification requested for user: %s, number: %s", req.UserID, req.PScheinNumber)
+	if req.ExpiryDate == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "expiry_date is required"})
+		return
+	}
+	expiry, err := services.CheckPScheinExpiry(req.ExpiryDate, timeutil.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
 
-	// In a real system, this would update the database and potentially trigger a manual review workflow.
+	h.logger.Printf("P-Schein verification requested for user: %s, number: %s", req.UserID, maskPScheinNumber(req.PScheinNumber))
+
+	if expiry.Expired {
+		h.logger.Printf("P-Schein for user: %s rejected: expired on %s", req.UserID, req.ExpiryDate)
+		json.NewEncoder(w).Encode(PScheinVerificationResponse{
+			Status:  "REJECTED",
+			Message: fmt.Sprintf("P-Schein expired on %s. Please submit a renewed P-Schein.", req.ExpiryDate),
+		})
+		return
+	}
+
+	// The manual review itself is recorded by the user-service
 	resp := PScheinVerificationResponse{
-BStatus:  "PENDING",
+		Status:  "PENDING",
 		Message: "P-Schein details received. Manual verification in progress.",
 	}
+	if expiry.RenewSoon {
+		resp.Message += fmt.Sprintf(" Warning: the P-Schein expires on %s (in %d days); the driver should renew it.", req.ExpiryDate, expiry.DaysRemaining)
+	}
 
-	json.NewEncrypter(w).Encode(resp)
+	json.NewEncoder(w).Encode(resp)
 }
 
 // UploadDocument handles POST /upload-document
 func (h *VerificationHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	// Bound the whole request so an oversized upload is not even spooled to disk
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxDocumentSize+multipartOverhead)
+
 	// Parse multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10MB max
+	err := r.ParseMultipartForm(10 << 20) // 10MB held in memory, the rest on disk
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("document must not exceed %d bytes", h.maxDocumentSize)})
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.BadRequest)
-		json.NewEncrypter(w).Encode(map[string]string{"error": "failed to parse form"})
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to parse form"})
 		return
 	}
 
 	file, header, err := r.FormFile("document")
 	if err != nil {
-		w.WriteHeader(http.BadRequest)
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "document file is required"})
 		return
 	}
@@ -141,36 +283,202 @@ func (h *VerificationHandler) UploadDocument(w http.ResponseWriter, r *http.Requ
 	userID := r.FormValue("user_id")
 	docType := r.FormValue("doc_type")
 
-	h .logger.Printf("Received document upload: %s (%s) for user: %s", header.Filename, docType, userID)
+	if userID == "" || docType == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user_id and doc_type are required"})
+		return
+	}
 
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		w.WriteHeader(http.InternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read file"})
+	if !allowedDocTypes[docType] {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "doc_type must be one of P-Schein, ID, Insurance, VehicleRegistration"})
 		return
 	}
 
-	// Encrypt content using AES-256
-	encryptedContent, err := h.encryptionSvc.Encrypt(fileContent)
-	if err := nil {
-		w.WriteHeader(http.InternalServerError)
-		json.NewEncoder(w).Encode(map[string]string${"error": "failed to encrypt document"})
-		return ()
+	if header.Size > h.maxDocumentSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("document must not exceed %d bytes", h.maxDocumentSize)})
+		return
 	}
 
-	// Mock storage
-	docID := uuid.New().String()
-	storagePath := fmt.Sprintf("/data/storage/%s.enc", docID)
+	// Detect the file type from its content; only the first 512 bytes are used
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "document file is empty or unreadable"})
+		return
+	}
+	sniff = sniff[:n]
+	contentType := http.DetectContentType(sniff)
+	if !allowedContentTypes[contentType] {
+		h.logger.Printf("Rejected document upload %s for user %s: detected content type %s", header.Filename, userID, contentType)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("document must be a PDF, JPEG or PNG file, got %s", contentType)})
+		return
+	}
+
+	h.logger.Printf("Received document upload: %s (%s, %s) for user: %s", header.Filename, docType, contentType, userID)
+
+	// Encrypt content using AES-256, chunk by chunk. The ciphertext is
+	// buffered whole because the document store keeps it in memory.
+	var encrypted bytes.Buffer
+	if err := h.encryptionSvc.EncryptStream(io.MultiReader(bytes.NewReader(sniff), file), &encrypted); err != nil {
+		h.logger.Printf("ERROR: failed to encrypt document %s for user %s: %v", header.Filename, userID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to encrypt document"})
+		return
+	}
+	encryptedContent := encrypted.Bytes()
+
+	// Store as a new version; earlier uploads of the same doc type are retained.
+	doc := h.documents.Save(userID, docType, header.Filename, contentType, encryptedContent, timeutil.Now())
 
-	h .logger.Printf("Document encrypted and stored at: %s", storagePath)
+	h.logger.Printf("Document encrypted and stored: %s (version %d of %s for user: %s)", doc.DocumentID, doc.Version, docType, userID)
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":        "success",
-		"document_id":  docID,
-	
-		"storage_path": storagePath,
-		"message":      "Document uploaded and encrypted successfully.",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "success",
+		"document_id": doc.DocumentID,
+		"version":     doc.Version,
+		"message":     "Document uploaded and encrypted successfully.",
 	})
 }
+
+// GetDocumentVersion handles GET /documents/users/{user_id}/{doc_type}
+//
+// Returns the metadata of the active version, or of a specific version when
+// the "version" query parameter is given. Only the user themselves and
+// safety staff may read it.
+func (h *VerificationHandler) GetDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, docType := vars["user_id"], vars["doc_type"]
+
+	if !mayAccessSubject(r, userID) {
+		writeForbidden(w)
+		return
+	}
+
+	var (
+		doc   services.DocumentVersion
+		found bool
+	)
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "version must be a positive integer"})
+			return
+		}
+		doc, found = h.documents.Version(userID, docType, version)
+	} else {
+		doc, found = h.documents.Latest(userID, docType)
+	}
+
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "document not found"})
+		return
+	}
+
+	h.recordDocumentAccess(r, userID, "filename")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// DownloadDocument handles GET /documents/{id}
+//
+// Decrypts the stored version and returns the plaintext with the content type
+// it was uploaded with. Only the document's owner and safety staff may
+// download it.
+func (h *VerificationHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	doc, found := h.documents.Get(id)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "document not found"})
+		return
+	}
+	if !mayAccessSubject(r, doc.UserID) {
+		h.logger.Printf("Refused download of document %s for user %s to caller %q", id, doc.UserID, r.Header.Get("X-User-ID"))
+		writeForbidden(w)
+		return
+	}
+
+	// Decrypt fully before responding, so a tampered or truncated stream never
+	// reaches the client as a partial 200 response.
+	var plaintext bytes.Buffer
+	if err := h.encryptionSvc.DecryptStream(bytes.NewReader(doc.Ciphertext), &plaintext); err != nil {
+		// A failed GCM open means the ciphertext was altered or the key changed;
+		// the details stay in the log.
+		h.logger.Printf("ERROR: failed to decrypt document %s: %v", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "document could not be decrypted"})
+		return
+	}
+
+	contentType := doc.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(plaintext.Len()))
+	if doc.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.Filename}))
+	}
+
+	h.logger.Printf("Document %s (%s version %d) downloaded for user: %s", id, doc.DocType, doc.Version, doc.UserID)
+	h.recordDocumentAccess(r, doc.UserID, "document", "filename")
+
+	w.WriteHeader(http.StatusOK)
+	plaintext.WriteTo(w)
+}
+
+// ListDocumentVersions handles GET /documents/users/{user_id}/{doc_type}/versions
+//
+// Only the user themselves and safety staff may list the versions.
+func (h *VerificationHandler) ListDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, docType := vars["user_id"], vars["doc_type"]
+
+	if !mayAccessSubject(r, userID) {
+		writeForbidden(w)
+		return
+	}
+
+	versions := h.documents.History(userID, docType)
+	if len(versions) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "document not found"})
+		return
+	}
+
+	h.recordDocumentAccess(r, userID, "filename")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":  userID,
+		"doc_type": docType,
+		"versions": versions,
+	})
+}
+
+// recordDocumentAccess writes a read of userID's documents to the PII access
+// log. fields names what was disclosed: "document" for the decrypted content,
+// "filename" for the metadata a listing includes.
+func (h *VerificationHandler) recordDocumentAccess(r *http.Request, userID string, fields ...string) {
+	if err := h.accessLog.Record(piiaudit.NewEvent(r, piiaudit.Read, userID, fields...)); err != nil {
+		h.logger.Printf("ERROR: failed to write PII access event for user %s: %v", userID, err)
+	}
+}
+
+// maskPScheinNumber hides all but the last four characters, as the
+// user-service does. Numbers of four characters or fewer are masked
+// completely.
+func maskPScheinNumber(number string) string {
+	if number == "" {
+		return "(none)"
+	}
+	if len(number) <= 4 {
+		return strings.Repeat("*", len(number))
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
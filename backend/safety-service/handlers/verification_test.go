@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+
+	"github.com/rideshare/safety-service/services"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+// testEncryption returns a keyring EncryptionService whose active key is key
+func testEncryption(t *testing.T, key string) *services.EncryptionService {
+	t.Helper()
+	encSvc, err := services.NewEncryptionServiceWithKeyring(map[string]string{"test": key}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+	return encSvc
+}
+
+func newTestRouter(t *testing.T, key string, documents *services.DocumentStore) *mux.Router {
+	return newTestRouterWithLimit(t, key, documents, 0)
+}
+
+func newTestRouterWithLimit(t *testing.T, key string, documents *services.DocumentStore, maxDocumentSize int64) *mux.Router {
+	t.Helper()
+	return newTestRouterWithEncryption(t, testEncryption(t, key), documents, maxDocumentSize)
+}
+
+func newTestRouterWithEncryption(t *testing.T, encSvc *services.EncryptionService, documents *services.DocumentStore, maxDocumentSize int64) *mux.Router {
+	t.Helper()
+	h := NewVerificationHandler(log.New(io.Discard, "", 0), encSvc, documents, piiaudit.New(nil), services.NewIdentityCaseStore(), "", maxDocumentSize)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/upload-document", h.UploadDocument).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/documents/{id}", h.DownloadDocument).Methods(http.MethodGet)
+	return r
+}
+
+func postDocument(t *testing.T, r http.Handler, docType string, content []byte, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("user_id", "user-1")
+	form.WriteField("doc_type", docType)
+	part, err := form.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="document"; filename="pschein.pdf"`},
+		"Content-Type":        {contentType},
+	})
+	if err != nil {
+		t.Fatalf("failed to create form part: %v", err)
+	}
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload-document", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func uploadTestDocument(t *testing.T, r http.Handler, content []byte, contentType string) string {
+	t.Helper()
+
+	rec := postDocument(t, r, "P-Schein", content, contentType)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		DocumentID string `json:"document_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode upload response: %v", err)
+	}
+	return resp.DocumentID
+}
+
+// ownerRequest is a GET of path by user-1, the owner of the test documents
+func ownerRequest(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-User-ID", "user-1")
+	req.Header.Set("X-User-Role", "driver")
+	return req
+}
+
+func TestDownloadDocumentRoundTrip(t *testing.T) {
+	r := newTestRouter(t, testKey, services.NewDocumentStore(0))
+	content := []byte("%PDF-1.4 P-Schein scan")
+	id := uploadTestDocument(t, r, content, "application/pdf")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, ownerRequest("/api/v1/documents/"+id))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Content-Type = %q, want application/pdf", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "pschein.pdf") {
+		t.Errorf("Content-Disposition = %q, want the original filename", cd)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content) {
+		t.Errorf("body = %q, want the uploaded plaintext", rec.Body.String())
+	}
+}
+
+func TestDownloadDocumentUnknownID(t *testing.T) {
+	r := newTestRouter(t, testKey, services.NewDocumentStore(0))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDownloadDocumentDecryptionFailure(t *testing.T) {
+	documents := services.NewDocumentStore(0)
+	id := uploadTestDocument(t, newTestRouter(t, testKey, documents), []byte("%PDF-1.4 secret"), "application/pdf")
+
+	// Same store, different key: the GCM tag no longer verifies.
+	r := newTestRouter(t, "ffffffffffffffffffffffffffffffff", documents)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, ownerRequest("/api/v1/documents/"+id))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "tamper") || strings.Contains(body, "cipher") {
+		t.Errorf("error body leaks decryption details: %s", body)
+	}
+}
+
+func TestDownloadDocumentAfterKeyRotation(t *testing.T) {
+	const rotatedKey = "ffffffffffffffffffffffffffffffff"
+	documents := services.NewDocumentStore(0)
+	content := []byte("%PDF-1.4 secret")
+	id := uploadTestDocument(t, newTestRouter(t, testKey, documents), content, "application/pdf")
+
+	// The old key is retired, not removed: documents it encrypted stay readable.
+	encSvc, err := services.NewEncryptionServiceWithKeyring(map[string]string{"test": testKey, "rotated": rotatedKey}, "rotated")
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	newTestRouterWithEncryption(t, encSvc, documents, 0).ServeHTTP(rec, ownerRequest("/api/v1/documents/"+id))
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), content) {
+		t.Errorf("status = %d, body = %q; want the uploaded plaintext", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDocumentReadsAreRecordedInPIIAccessLog(t *testing.T) {
+	accessLog := piiaudit.New(nil)
+	h := NewVerificationHandler(log.New(io.Discard, "", 0), testEncryption(t, testKey), services.NewDocumentStore(0), accessLog, services.NewIdentityCaseStore(), "", 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/upload-document", h.UploadDocument).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/documents/users/{user_id}/{doc_type}", h.GetDocumentVersion).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/documents/users/{user_id}/{doc_type}/versions", h.ListDocumentVersions).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/documents/{id}", h.DownloadDocument).Methods(http.MethodGet)
+
+	id := uploadTestDocument(t, r, []byte("%PDF-1.4 secret"), "application/pdf")
+	if events := accessLog.ForSubject("user-1"); len(events) != 0 {
+		t.Fatalf("upload recorded as a read: %+v", events)
+	}
+
+	reads := []struct {
+		path   string
+		fields string
+	}{
+		{"/api/v1/documents/" + id, "document,filename"},
+		{"/api/v1/documents/users/user-1/P-Schein", "filename"},
+		{"/api/v1/documents/users/user-1/P-Schein/versions", "filename"},
+	}
+	for _, read := range reads {
+		req := httptest.NewRequest(http.MethodGet, read.path, nil)
+		req.Header.Set("X-User-ID", "admin-1")
+		req.Header.Set("X-User-Role", "admin")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body = %s", read.path, rec.Code, rec.Body.String())
+		}
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown document: status = %d, want 404", rec.Code)
+	}
+
+	events := accessLog.ForSubject("user-1")
+	if len(events) != len(reads) {
+		t.Fatalf("recorded %d events, want %d: %+v", len(events), len(reads), events)
+	}
+	for i, read := range reads {
+		event := events[i]
+		if event.ActorID != "admin-1" || event.ActorRole != "admin" || event.Action != piiaudit.Read {
+			t.Errorf("event %d: unexpected actor or action %+v", i, event)
+		}
+		if event.Resource != "GET "+read.path {
+			t.Errorf("event %d: resource = %q, want %q", i, event.Resource, "GET "+read.path)
+		}
+		if got := strings.Join(event.Fields, ","); got != read.fields {
+			t.Errorf("event %d: fields = %q, want %q", i, got, read.fields)
+		}
+	}
+}
+
+func TestDocumentReadsRequireOwnerOrSafetyStaff(t *testing.T) {
+	h := NewVerificationHandler(log.New(io.Discard, "", 0), testEncryption(t, testKey), services.NewDocumentStore(0), piiaudit.New(nil), services.NewIdentityCaseStore(), "", 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/upload-document", h.UploadDocument).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/documents/users/{user_id}/{doc_type}", h.GetDocumentVersion).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/documents/users/{user_id}/{doc_type}/versions", h.ListDocumentVersions).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/documents/{id}", h.DownloadDocument).Methods(http.MethodGet)
+	id := uploadTestDocument(t, r, []byte("%PDF-1.4 secret"), "application/pdf")
+
+	callers := []struct {
+		name, userID, role string
+		want               int
+	}{
+		{"owner", "user-1", "driver", http.StatusOK},
+		{"admin", "admin-1", RoleAdmin, http.StatusOK},
+		{"safety staff", "staff-1", RoleSafety, http.StatusOK},
+		{"other rider", "rider-2", "rider", http.StatusForbidden},
+		{"no identity", "", "", http.StatusForbidden},
+	}
+	paths := []string{
+		"/api/v1/documents/" + id,
+		"/api/v1/documents/users/user-1/P-Schein",
+		"/api/v1/documents/users/user-1/P-Schein/versions",
+	}
+	for _, c := range callers {
+		for _, path := range paths {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if c.userID != "" {
+				req.Header.Set("X-User-ID", c.userID)
+				req.Header.Set("X-User-Role", c.role)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != c.want {
+				t.Errorf("%s: GET %s: status = %d, want %d", c.name, path, rec.Code, c.want)
+			}
+			if c.want == http.StatusForbidden && strings.Contains(rec.Body.String(), "secret") {
+				t.Errorf("%s: GET %s: forbidden response leaks the document", c.name, path)
+			}
+		}
+	}
+}
+
+func TestUploadDocumentValidation(t *testing.T) {
+	pdf := []byte("%PDF-1.4 P-Schein scan")
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	exe := append([]byte("MZ\x90\x00"), make([]byte, 64)...)
+
+	for name, tc := range map[string]struct {
+		docType     string
+		content     []byte
+		contentType string
+		want        int
+	}{
+		"pdf":                      {"P-Schein", pdf, "application/pdf", http.StatusOK},
+		"png":                      {"VehicleRegistration", png, "image/png", http.StatusOK},
+		"unknown doc type":         {"Passport", pdf, "application/pdf", http.StatusBadRequest},
+		"executable":               {"ID", exe, "application/octet-stream", http.StatusBadRequest},
+		"executable claiming pdf":  {"Insurance", exe, "application/pdf", http.StatusBadRequest},
+		"plain text claiming jpeg": {"ID", []byte("not an image"), "image/jpeg", http.StatusBadRequest},
+		"empty file":               {"ID", nil, "application/pdf", http.StatusBadRequest},
+	} {
+		r := newTestRouter(t, testKey, services.NewDocumentStore(0))
+		if rec := postDocument(t, r, tc.docType, tc.content, tc.contentType); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (body %s)", name, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
+
+func TestUploadDocumentStoresDetectedContentType(t *testing.T) {
+	documents := services.NewDocumentStore(0)
+	r := newTestRouter(t, testKey, documents)
+	id := uploadTestDocument(t, r, []byte("%PDF-1.4 scan"), "application/x-whatever")
+
+	doc, ok := documents.Get(id)
+	if !ok {
+		t.Fatal("uploaded document not stored")
+	}
+	if doc.ContentType != "application/pdf" {
+		t.Errorf("content type = %q, want the detected application/pdf", doc.ContentType)
+	}
+}
+
+func TestUploadDocumentMaxSize(t *testing.T) {
+	r := newTestRouterWithLimit(t, testKey, services.NewDocumentStore(0), 1024)
+
+	small := append([]byte("%PDF-1.4 "), make([]byte, 512)...)
+	if rec := postDocument(t, r, "P-Schein", small, "application/pdf"); rec.Code != http.StatusOK {
+		t.Errorf("within limit: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	large := append([]byte("%PDF-1.4 "), make([]byte, 2048)...)
+	if rec := postDocument(t, r, "P-Schein", large, "application/pdf"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("over limit: status = %d, want 413", rec.Code)
+	}
+
+	huge := append([]byte("%PDF-1.4 "), make([]byte, 2<<20)...)
+	if rec := postDocument(t, r, "P-Schein", huge, "application/pdf"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("over request limit: status = %d, want 413", rec.Code)
+	}
+}
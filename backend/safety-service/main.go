@@ -2,30 +2,74 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 
-	"safety-service/handlers"
+	"github.com/rideshare/safety-service/handlers"
+	"github.com/rideshare/safety-service/services"
 )
 
+// defaultAESKeyID names the active key when AES_KEY_ID is not set
+const defaultAESKeyID = "primary"
+
 func main() {
-	logger := log.New(os.Stdout, "[SAFETY-SERVICE] ", log.LstdFlags|log.Lshortfile)
+	logger := timeutil.NewLogger(os.Stdout, "[SAFETY-SERVICE] ", log.Lshortfile)
+
+	// AES_KEY_SOURCE selects where the key comes from: "env" (default), "file" or "kms".
+	keyCfg := services.KeySourceConfig{
+		Type:     os.Getenv("AES_KEY_SOURCE"),
+		EnvVar:   "AES_ENCRYPTION_KEY",
+		FilePath: os.Getenv("AES_KEY_FILE"),
+		KMSRef:   os.Getenv("AES_KEY_KMS_REF"),
+	}
+	if vaultAddr := os.Getenv("VAULT_ADDR"); vaultAddr != "" {
+		keyCfg.KMSClient = services.NewVaultClient(vaultAddr, os.Getenv("VAULT_TOKEN"))
+	}
+
+	keySource, err := services.NewKeySource(keyCfg)
+	if err != nil {
+		logger.Fatalf("FATAL: invalid AES key source configuration: %v", err)
+	}
 
-	encryptionKey := os.Getenv("AES_ENCRYPTION_KEY")
-	if encryptionKey == "" {
+	keyCtx, keyCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	encryptionKey, err := services.LoadEncryptionKey(keyCtx, keySource)
+	keyCancel()
+	if errors.Is(err, services.ErrKeyNotSet) {
 		// 32-byte key for AES-256. In production, this MUST come from a secrets manager (e.g., AWS Secrets Manager, HashiCorp Vault).
 		encryptionKey = "a-very-secret-32-byte-key-here!!"
-		logger.Println("WARNING: Using default AES encryption key. Set AES_ENCRYPTION_KEY in production.")
+		logger.Println("WARNING: Using default AES encryption key. Set AES_ENCRYPTION_KEY or AES_KEY_SOURCE in production.")
+	} else if err != nil {
+		logger.Fatalf("FATAL: %v", err)
 	}
 
-	if len(encryptionKey) != 32 {
-		logger.Fatalf("FATAL: AES_ENCRYPTION_KEY must be exactly 32 bytes for AES-256. Got %d bytes.", len(encryptionKey))
+	// The loaded key is the active key AES_KEY_ID; AES_RETIRED_KEYS keeps
+	// rotated-out keys so documents encrypted with them stay readable.
+	keyID := os.Getenv("AES_KEY_ID")
+	if keyID == "" {
+		keyID = defaultAESKeyID
+	}
+	keys, err := services.ParseRetiredKeys(os.Getenv("AES_RETIRED_KEYS"))
+	if err != nil {
+		logger.Fatalf("FATAL: invalid AES_RETIRED_KEYS: %v", err)
+	}
+	if _, ok := keys[keyID]; ok {
+		logger.Fatalf("FATAL: AES_RETIRED_KEYS must not contain the active key ID %q", keyID)
+	}
+	keys[keyID] = encryptionKey
+	encryption, err := services.NewEncryptionServiceWithKeyring(keys, keyID)
+	if err != nil {
+		logger.Fatalf("FATAL: failed to initialize encryption service: %v", err)
 	}
 
 	port := os.Getenv("PORT")
@@ -33,7 +77,60 @@ func main() {
 		port = "8080"
 	}
 
-	h := handlers.NewVerificationHandler(logger, encryptionKey)
+	retentionDays := 1095 // 3 years
+	if v := os.Getenv("DOCUMENT_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			logger.Fatalf("FATAL: DOCUMENT_RETENTION_DAYS must be a non-negative integer, got %q", v)
+		}
+		retentionDays = days
+	}
+	documents := services.NewDocumentStore(time.Duration(retentionDays) * 24 * time.Hour)
+
+	maxDocumentSize := int64(handlers.DefaultMaxDocumentSize)
+	if v := os.Getenv("MAX_DOCUMENT_SIZE_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil || mb < 1 {
+			logger.Fatalf("FATAL: MAX_DOCUMENT_SIZE_MB must be a positive integer, got %q", v)
+		}
+		maxDocumentSize = int64(mb) << 20
+	}
+
+	postidentSecret := os.Getenv("POSTIDENT_WEBHOOK_SECRET")
+	if postidentSecret == "" {
+		logger.Println("WARNING: POSTIDENT_WEBHOOK_SECRET is not set. Identity callbacks will be rejected.")
+	}
+
+	// REQUIRED_DRIVER_DOCUMENTS lists the uploads a driver needs to be cleared to drive.
+	var requiredDocs []string
+	if v := os.Getenv("REQUIRED_DRIVER_DOCUMENTS"); v != "" {
+		requiredDocs, err = handlers.ParseRequiredDocuments(v)
+		if err != nil {
+			logger.Fatalf("FATAL: invalid REQUIRED_DRIVER_DOCUMENTS: %v", err)
+		}
+	}
+
+	accessLog, err := piiaudit.NewFromEnv()
+	if err != nil {
+		logger.Fatalf("FATAL: invalid PII access log configuration: %v", err)
+	}
+
+	// The user-service owns the P-Schein review; clearance asks it for the status.
+	userServiceURL := os.Getenv("USER_SERVICE_URL")
+	if userServiceURL == "" {
+		logger.Println("WARNING: USER_SERVICE_URL is not set. Clearance checks will fail.")
+	}
+	pscheins := services.NewHTTPPScheinStatusSource(userServiceURL, 5*time.Second)
+
+	identityCases := services.NewIdentityCaseStore()
+
+	h := handlers.NewVerificationHandler(logger, encryption, documents, accessLog, identityCases, postidentSecret, maxDocumentSize)
+
+	clearance := handlers.NewClearanceHandler(logger, identityCases, pscheins, documents, requiredDocs)
+
+	sos := handlers.NewSOSHandler(logger, services.NewSOSStore())
+
+	incidents := handlers.NewIncidentHandler(logger, encryption, services.NewIncidentStore())
 
 	r := mux.NewRouter()
 
@@ -44,14 +141,26 @@ func main() {
 	// Routes
 	v1 := r.PathPrefix("/api/v1").Subrouter()
 	v1.HandleFunc("/verify/identity", h.VerifyIdentity).Methods(http.MethodPost)
+	v1.HandleFunc("/verify/identity/callback", h.IdentityCallback).Methods(http.MethodPost)
+	v1.HandleFunc("/verify/identity/{case_id}", h.GetIdentityCase).Methods(http.MethodGet)
 	v1.HandleFunc("/verify/p-schein", h.VerifyPSchein).Methods(http.MethodPost)
 	v1.HandleFunc("/upload-document", h.UploadDocument).Methods(http.MethodPost)
+	v1.HandleFunc("/documents/users/{user_id}/{doc_type}", h.GetDocumentVersion).Methods(http.MethodGet)
+	v1.HandleFunc("/documents/users/{user_id}/{doc_type}/versions", h.ListDocumentVersions).Methods(http.MethodGet)
+	v1.HandleFunc("/documents/{id}", h.DownloadDocument).Methods(http.MethodGet)
+	v1.HandleFunc("/drivers/{id}/clearance", clearance.GetClearance).Methods(http.MethodGet)
+	v1.HandleFunc("/sos", sos.TriggerSOS).Methods(http.MethodPost)
+	v1.HandleFunc("/sos/{case_id}", sos.GetSOS).Methods(http.MethodGet)
+	v1.HandleFunc("/incidents", incidents.CreateIncident).Methods(http.MethodPost)
+	v1.HandleFunc("/incidents/{id}", incidents.GetIncident).Methods(http.MethodGet)
+	v1.HandleFunc("/incidents/{id}/status", incidents.UpdateIncidentStatus).Methods(http.MethodPut)
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok"}`))
 	}).Methods(http.MethodGet)
+	r.HandleFunc("/version", buildinfo.Handler("safety-service")).Methods(http.MethodGet)
 
 	srv := &http.Server{
 		Addr:         ":" + port,
@@ -64,44 +173,64 @@ func main() {
 	// Start server in a goroutine
 	go func() {
 		logger.Printf("Starting safety-service on port %s", port)
-		if err := srv.ListenandServe(); err != http.ErrServerShutdown {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatalf("Fatal error starting server: %v", err)
 		}
 	}()
 
+	// Purge superseded document versions once their retention period has elapsed.
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-retentionCtx.Done():
+				return
+			case now := <-ticker.C:
+				if n := documents.PurgeExpired(now.UTC()); n > 0 {
+					logger.Printf("Retention: purged %d superseded document versions", n)
+				}
+			}
+		}
+	}()
+
 	// Channel to listen for interrupt signals to gracefully shutdown the server
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	// Block until a signal is received
 	<-stop
+	stopRetention()
 
-	logger.Println "Shutting server down..."
+	logger.Println("Shutting server down...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5* time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
 
-	logger.Println "Server exiting"
+	logger.Println("Server exiting")
 }
 
 func loggingMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
-	return http.HandlerFunc&func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		logger.Printf("START %s %s", r.Method, r.URL.Path)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			logger.Printf("START %s %s", r.Method, r.URL.Path)
 
-		next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r)
 
-		logger.Printf("COMPLETE %s %s in %v", r.Method, r.URL.Path, time.Since(start))
-	})
+			logger.Printf("COMPLETE %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+		})
+	}
 }
 
 func contentTypeMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc&func(w http.ResponseWriter, r *http.Request) {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		next.ServHTTP(w, r)
+		next.ServeHTTP(w, r)
 	})
 }
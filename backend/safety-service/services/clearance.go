@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// DefaultRequiredDocuments are the documents a driver must have uploaded to
+// be cleared to drive when no other set is configured.
+var DefaultRequiredDocuments = []string{"P-Schein", "ID", "Insurance", "VehicleRegistration"}
+
+// Clearance items reported as missing.
+const (
+	ClearanceIdentity = "identity_verification"
+	ClearancePSchein  = "p_schein_verification"
+)
+
+// MissingItem is one requirement a driver does not yet meet.
+type MissingItem struct {
+	Item   string `json:"item"`
+	Reason string `json:"reason"`
+}
+
+// Clearance is the combined verification state of a driver.
+type Clearance struct {
+	DriverID       string        `json:"driver_id"`
+	ClearedToDrive bool          `json:"cleared_to_drive"`
+	Missing        []MissingItem `json:"missing"`
+	CheckedAt      time.Time     `json:"checked_at"`
+}
+
+// CheckClearance combines identity verification, the user-service's P-Schein
+// review and document uploads for driverID. A driver is cleared to drive only
+// with a successful POSTIDENT identification, a verified P-Schein that has not
+// expired by now, and an active version of every document in requiredDocs. An
+// error means the P-Schein status could not be read.
+func CheckClearance(ctx context.Context, driverID string, cases *IdentityCaseStore, pscheins PScheinStatusSource, documents *DocumentStore, requiredDocs []string, now time.Time) (Clearance, error) {
+	missing := []MissingItem{}
+
+	if !cases.Verified(driverID) {
+		missing = append(missing, MissingItem{Item: ClearanceIdentity, Reason: "no successful POSTIDENT identification"})
+	}
+
+	pschein, err := pscheins.PScheinStatus(ctx, driverID)
+	if err != nil && !errors.Is(err, ErrDriverNotFound) {
+		return Clearance{}, err
+	}
+	switch {
+	case err != nil:
+		missing = append(missing, MissingItem{Item: ClearancePSchein, Reason: "driver not registered"})
+	case pschein.Status == "":
+		missing = append(missing, MissingItem{Item: ClearancePSchein, Reason: "no P-Schein submitted"})
+	case pschein.Status != PScheinVerified:
+		missing = append(missing, MissingItem{Item: ClearancePSchein, Reason: fmt.Sprintf("P-Schein is %s", pschein.Status)})
+	case pschein.ExpiresAt != nil && now.After(*pschein.ExpiresAt):
+		missing = append(missing, MissingItem{Item: ClearancePSchein, Reason: fmt.Sprintf("P-Schein expired on %s", pschein.ExpiresAt.In(timeutil.Berlin()).Format("2006-01-02"))})
+	}
+
+	for _, docType := range requiredDocs {
+		if _, ok := documents.Latest(driverID, docType); !ok {
+			missing = append(missing, MissingItem{Item: "document:" + docType, Reason: fmt.Sprintf("%s document not uploaded", docType)})
+		}
+	}
+
+	return Clearance{
+		DriverID:       driverID,
+		ClearedToDrive: len(missing) == 0,
+		Missing:        missing,
+		CheckedAt:      now,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubPScheinSource answers PScheinStatus from a map; users not in it are
+// unknown to the user-service.
+type stubPScheinSource struct {
+	statuses map[string]PScheinStatus
+	err      error
+}
+
+func (s stubPScheinSource) PScheinStatus(ctx context.Context, userID string) (PScheinStatus, error) {
+	if s.err != nil {
+		return PScheinStatus{}, s.err
+	}
+	status, ok := s.statuses[userID]
+	if !ok {
+		return PScheinStatus{}, ErrDriverNotFound
+	}
+	return status, nil
+}
+
+func missingItems(c Clearance) []string {
+	items := []string{}
+	for _, m := range c.Missing {
+		items = append(items, m.Item)
+	}
+	return items
+}
+
+func TestCheckClearance(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cases := NewIdentityCaseStore()
+	pscheins := stubPScheinSource{statuses: map[string]PScheinStatus{}}
+	documents := NewDocumentStore(0)
+	required := []string{"P-Schein", "ID"}
+	check := func(at time.Time) Clearance {
+		t.Helper()
+		c, err := CheckClearance(context.Background(), "driver-1", cases, pscheins, documents, required, at)
+		if err != nil {
+			t.Fatalf("CheckClearance: %v", err)
+		}
+		return c
+	}
+
+	got := check(now)
+	if got.ClearedToDrive || !reflect.DeepEqual(missingItems(got), []string{ClearanceIdentity, ClearancePSchein, "document:P-Schein", "document:ID"}) || got.Missing[1].Reason != "driver not registered" {
+		t.Errorf("new driver: %+v", got)
+	}
+
+	cases.Create("case-1", "driver-1", now)
+	cases.Complete("case-1", IdentitySuccess, now)
+	expires := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	pscheins.statuses["driver-1"] = PScheinStatus{Status: "PENDING", ExpiresAt: &expires}
+	documents.Save("driver-1", "P-Schein", "pschein.pdf", "application/pdf", []byte("ciphertext"), now)
+	documents.Save("driver-1", "ID", "id.png", "image/png", []byte("ciphertext"), now)
+
+	got = check(now)
+	if got.ClearedToDrive || !reflect.DeepEqual(missingItems(got), []string{ClearancePSchein}) || got.Missing[0].Reason != "P-Schein is PENDING" {
+		t.Errorf("pending P-Schein: %+v", got)
+	}
+
+	pscheins.statuses["driver-1"] = PScheinStatus{Status: PScheinVerified, ExpiresAt: &expires}
+	if got = check(now); !got.ClearedToDrive || len(got.Missing) != 0 {
+		t.Errorf("fully verified driver: %+v", got)
+	}
+
+	// The verified P-Schein runs out; 23:00 UTC is already 1 February in Berlin
+	got = check(now.AddDate(1, 0, 0))
+	if got.ClearedToDrive || !reflect.DeepEqual(missingItems(got), []string{ClearancePSchein}) || got.Missing[0].Reason != "P-Schein expired on 2026-02-01" {
+		t.Errorf("expired P-Schein: %+v", got)
+	}
+}
+
+func TestCheckClearanceStatusUnavailable(t *testing.T) {
+	unavailable := errors.New("user-service unreachable")
+	_, err := CheckClearance(context.Background(), "driver-1", NewIdentityCaseStore(), stubPScheinSource{err: unavailable}, NewDocumentStore(0), nil, time.Now())
+	if !errors.Is(err, unavailable) {
+		t.Errorf("err = %v, want the lookup error", err)
+	}
+}
+
+func TestHTTPPScheinStatusSource(t *testing.T) {
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/driver-1":
+			w.Write([]byte(`{"id": "driver-1", "p_schein_number": "PS-123", "p_schein_status": "VERIFIED", "p_schein_expires_at": "2030-01-31T23:00:00Z"}`))
+		case "/users/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer userService.Close()
+	source := NewHTTPPScheinStatusSource(userService.URL+"/", time.Second)
+
+	status, err := source.PScheinStatus(context.Background(), "driver-1")
+	if err != nil || status.Status != PScheinVerified || status.ExpiresAt == nil || !status.ExpiresAt.Equal(time.Date(2030, 1, 31, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("driver-1: %+v, %v", status, err)
+	}
+	if _, err := source.PScheinStatus(context.Background(), "unknown"); err != ErrDriverNotFound {
+		t.Errorf("unknown: err = %v, want ErrDriverNotFound", err)
+	}
+	if _, err := source.PScheinStatus(context.Background(), "broken"); err == nil || err == ErrDriverNotFound {
+		t.Errorf("broken: err = %v, want a lookup error", err)
+	}
+	if _, err := NewHTTPPScheinStatusSource("", time.Second).PScheinStatus(context.Background(), "driver-1"); err == nil {
+		t.Error("unconfigured source: expected an error")
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentVersion is one stored revision of a driver document.
+//
+// Versions are kept per (user, doc type) so that a re-upload of, e.g., a
+// P-Schein does not destroy the previous scan, which may still be needed as
+// audit evidence. Only the newest version is Active.
+type DocumentVersion struct {
+	DocumentID   string     `json:"document_id"`
+	UserID       string     `json:"user_id"`
+	DocType      string     `json:"doc_type"`
+	Version      int        `json:"version"`
+	Filename     string     `json:"filename"`
+	ContentType  string     `json:"content_type"`
+	UploadedAt   time.Time  `json:"uploaded_at"`
+	SupersededAt *time.Time `json:"superseded_at,omitempty"`
+	Active       bool       `json:"active"`
+
+	// Ciphertext is the AES-256-GCM encrypted document in the chunked
+	// EncryptStream format. It is never serialised and never stored in
+	// plaintext.
+	Ciphertext []byte `json:"-"`
+}
+
+type documentKey struct {
+	userID  string
+	docType string
+}
+
+// DocumentStore is an in-memory, versioned store for encrypted documents.
+//
+// Retention: superseded versions are kept for the configured retention period
+// after they were replaced and are then purged by PurgeExpired (GDPR Art. 5(1)(e),
+// storage limitation). The active version is never purged by retention.
+type DocumentStore struct {
+	mu        sync.RWMutex
+	history   map[documentKey][]*DocumentVersion
+	byID      map[string]*DocumentVersion
+	retention time.Duration
+}
+
+// NewDocumentStore creates a DocumentStore. A retention of zero keeps
+// superseded versions indefinitely.
+func NewDocumentStore(retention time.Duration) *DocumentStore {
+	return &DocumentStore{
+		history:   make(map[documentKey][]*DocumentVersion),
+		byID:      make(map[string]*DocumentVersion),
+		retention: retention,
+	}
+}
+
+// Save stores ciphertext as the new active version for (userID, docType) and
+// marks the previously active version as superseded.
+func (s *DocumentStore) Save(userID, docType, filename, contentType string, ciphertext []byte, now time.Time) DocumentVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := documentKey{userID: userID, docType: docType}
+	versions := s.history[key]
+
+	next := 1
+	if n := len(versions); n > 0 {
+		prev := versions[n-1]
+		prev.Active = false
+		superseded := now
+		prev.SupersededAt = &superseded
+		next = prev.Version + 1
+	}
+
+	doc := &DocumentVersion{
+		DocumentID:  uuid.New().String(),
+		UserID:      userID,
+		DocType:     docType,
+		Version:     next,
+		Filename:    filename,
+		ContentType: contentType,
+		UploadedAt:  now,
+		Active:      true,
+		Ciphertext:  ciphertext,
+	}
+
+	s.history[key] = append(versions, doc)
+	s.byID[doc.DocumentID] = doc
+
+	return *doc
+}
+
+// Latest returns the active version for (userID, docType).
+func (s *DocumentStore) Latest(userID, docType string) (DocumentVersion, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.history[documentKey{userID: userID, docType: docType}]
+	if len(versions) == 0 {
+		return DocumentVersion{}, false
+	}
+	return *versions[len(versions)-1], true
+}
+
+// Version returns a specific version for (userID, docType).
+func (s *DocumentStore) Version(userID, docType string, version int) (DocumentVersion, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, doc := range s.history[documentKey{userID: userID, docType: docType}] {
+		if doc.Version == version {
+			return *doc, true
+		}
+	}
+	return DocumentVersion{}, false
+}
+
+// History returns all retained versions for (userID, docType), oldest first.
+func (s *DocumentStore) History(userID, docType string) []DocumentVersion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.history[documentKey{userID: userID, docType: docType}]
+	out := make([]DocumentVersion, 0, len(versions))
+	for _, doc := range versions {
+		out = append(out, *doc)
+	}
+	return out
+}
+
+// Get returns a version by its document ID.
+func (s *DocumentStore) Get(documentID string) (DocumentVersion, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.byID[documentID]
+	if !ok {
+		return DocumentVersion{}, false
+	}
+	return *doc, true
+}
+
+// PurgeExpired removes superseded versions whose retention period has elapsed
+// and returns how many were removed.
+func (s *DocumentStore) PurgeExpired(now time.Time) int {
+	if s.retention <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for key, versions := range s.history {
+		kept := versions[:0]
+		for _, doc := range versions {
+			if !doc.Active && doc.SupersededAt != nil && now.Sub(*doc.SupersededAt) >= s.retention {
+				delete(s.byID, doc.DocumentID)
+				purged++
+				continue
+			}
+			kept = append(kept, doc)
+		}
+		s.history[key] = kept
+	}
+	return purged
+}
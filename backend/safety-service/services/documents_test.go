@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDocumentStoreVersionsReuploads(t *testing.T) {
+	store := NewDocumentStore(0)
+	t0 := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	v1 := store.Save("user-1", "P-Schein", "pschein.pdf", "application/pdf", []byte("cipher-1"), t0)
+	v2 := store.Save("user-1", "P-Schein", "pschein-new.pdf", "application/pdf", []byte("cipher-2"), t0.Add(time.Hour))
+
+	if v1.Version != 1 || v2.Version != 2 {
+		t.Fatalf("got versions %d and %d, want 1 and 2", v1.Version, v2.Version)
+	}
+
+	latest, ok := store.Latest("user-1", "P-Schein")
+	if !ok || latest.DocumentID != v2.DocumentID || !latest.Active {
+		t.Fatalf("latest = %+v, want active version 2", latest)
+	}
+
+	old, ok := store.Version("user-1", "P-Schein", 1)
+	if !ok {
+		t.Fatal("version 1 should still be retrievable")
+	}
+	if old.Active {
+		t.Error("version 1 should no longer be active")
+	}
+	if old.SupersededAt == nil || !old.SupersededAt.Equal(t0.Add(time.Hour)) {
+		t.Errorf("version 1 superseded_at = %v, want %v", old.SupersededAt, t0.Add(time.Hour))
+	}
+	if !bytes.Equal(old.Ciphertext, []byte("cipher-1")) {
+		t.Error("version 1 ciphertext should be retained unchanged")
+	}
+
+	history := store.History("user-1", "P-Schein")
+	if len(history) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(history))
+	}
+}
+
+func TestDocumentStoreKeysByUserAndType(t *testing.T) {
+	store := NewDocumentStore(0)
+	now := time.Now().UTC()
+
+	store.Save("user-1", "P-Schein", "a.pdf", "application/pdf", []byte("a"), now)
+	store.Save("user-1", "Insurance", "b.pdf", "application/pdf", []byte("b"), now)
+	store.Save("user-2", "P-Schein", "c.pdf", "application/pdf", []byte("c"), now)
+
+	for _, key := range [][2]string{{"user-1", "P-Schein"}, {"user-1", "Insurance"}, {"user-2", "P-Schein"}} {
+		doc, ok := store.Latest(key[0], key[1])
+		if !ok {
+			t.Fatalf("no document for %v", key)
+		}
+		if doc.Version != 1 {
+			t.Errorf("%v: version = %d, want 1", key, doc.Version)
+		}
+	}
+
+	if _, ok := store.Latest("user-3", "P-Schein"); ok {
+		t.Error("expected no document for unknown user")
+	}
+}
+
+func TestDocumentStorePurgeExpired(t *testing.T) {
+	retention := 30 * 24 * time.Hour
+	store := NewDocumentStore(retention)
+	t0 := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	v1 := store.Save("user-1", "P-Schein", "a.pdf", "application/pdf", []byte("a"), t0)
+	v2 := store.Save("user-1", "P-Schein", "b.pdf", "application/pdf", []byte("b"), t0.Add(24*time.Hour))
+
+	if n := store.PurgeExpired(t0.Add(24 * time.Hour).Add(retention - time.Second)); n != 0 {
+		t.Fatalf("purged %d versions before retention elapsed", n)
+	}
+
+	if n := store.PurgeExpired(t0.Add(24 * time.Hour).Add(retention)); n != 1 {
+		t.Fatalf("purged %d versions, want 1", n)
+	}
+	if _, ok := store.Get(v1.DocumentID); ok {
+		t.Error("superseded version should be purged after retention")
+	}
+	if _, ok := store.Get(v2.DocumentID); !ok {
+		t.Error("active version must never be purged")
+	}
+
+	// Far in the future, the active version is still kept.
+	if n := store.PurgeExpired(t0.AddDate(10, 0, 0)); n != 0 {
+		t.Errorf("purged %d active versions", n)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -24,12 +25,60 @@ import (
 //   - The encryption key must be stored separately from the data
 //     (e.g., in a KMS like AWS KMS or HashiCorp Vault).
 type EncryptionService struct {
-	gcm cipher.AEAD
+	// gcm and keyID belong to the active key, used for all new ciphertext.
+	// keyID is empty for a single-key service, which writes no key header.
+	gcm   cipher.AEAD
+	keyID string
+
+	// keyring holds every known key by ID, the active one included.
+	keyring map[string]cipher.AEAD
 }
 
+// keyHeaderMagic starts the key header of ciphertext written by a keyring
+// service:
+//
+//	[ magic (4 bytes) | key ID length (1 byte) | key ID ]
+//
+// Ciphertext without it predates key rotation and is tried against every key.
+// The first byte is never zero, so a header cannot be mistaken for the length
+// prefix of an EncryptStream frame.
+var keyHeaderMagic = []byte{'R', 'S', 'K', 1}
+
 // NewEncryptionService creates a new EncryptionService.
 // key must be exactly 32 bytes for AES-256.
 func NewEncryptionService(key string) (*EncryptionService, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptionService{gcm: gcm, keyring: map[string]cipher.AEAD{"": gcm}}, nil
+}
+
+// NewEncryptionServiceWithKeyring creates an EncryptionService that encrypts
+// with keys[activeKeyID] and decrypts with whichever key the ciphertext header
+// names, so documents stored before a key rotation remain readable. Every key
+// must be exactly 32 bytes for AES-256.
+func NewEncryptionServiceWithKeyring(keys map[string]string, activeKeyID string) (*EncryptionService, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeKeyID)
+	}
+
+	keyring := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("key ID %q must be between 1 and 255 bytes", id)
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		keyring[id] = gcm
+	}
+
+	return &EncryptionService{gcm: keyring[activeKeyID], keyID: activeKeyID, keyring: keyring}, nil
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
 	if len(key) != 32 {
 		return nil, fmt.Errorf("encryption key must be exactly 32 bytes for AES-256, got %d bytes", len(key))
 	}
@@ -44,12 +93,60 @@ func NewEncryptionService(key string) (*EncryptionService, error) {
 		return nil, fmt.Errorf("failed to create GCM wrapper: %w", err)
 	}
 
-	return &EncryptionService{gcm: gcm}, nil
+	return gcm, nil
+}
+
+// keyHeader returns the key header for new ciphertext, or nil for a
+// single-key service.
+func (e *EncryptionService) keyHeader() []byte {
+	if e.keyID == "" {
+		return nil
+	}
+	header := append([]byte{}, keyHeaderMagic...)
+	header = append(header, byte(len(e.keyID)))
+	return append(header, e.keyID...)
+}
+
+// ErrUnknownKeyID is returned when ciphertext names a key that is not in the
+// keyring.
+var ErrUnknownKeyID = errors.New("ciphertext was encrypted with an unknown key")
+
+// allKeys returns every key in the keyring, the active one first
+func (e *EncryptionService) allKeys() []cipher.AEAD {
+	keys := []cipher.AEAD{e.gcm}
+	for _, gcm := range e.keyring {
+		if gcm != e.gcm {
+			keys = append(keys, gcm)
+		}
+	}
+	return keys
+}
+
+// parseKeyHeader splits a key header off data. It returns the keys to try:
+// the named key, or every key (active first) when there is no header.
+func (e *EncryptionService) parseKeyHeader(data []byte) ([]cipher.AEAD, []byte, error) {
+	if !bytes.HasPrefix(data, keyHeaderMagic) {
+		return e.allKeys(), data, nil
+	}
+
+	rest := data[len(keyHeaderMagic):]
+	if len(rest) == 0 || len(rest) < 1+int(rest[0]) {
+		return nil, nil, errors.New("ciphertext key header is truncated")
+	}
+	id := string(rest[1 : 1+int(rest[0])])
+	gcm, ok := e.keyring[id]
+	if !ok || id == "" {
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownKeyID, id)
+	}
+	return []cipher.AEAD{gcm}, rest[1+int(rest[0]):], nil
 }
 
 // Encrypt encrypts plaintext using AES-256-GCM.
 //
-// Output format: [ nonce (12 bytes) | ciphertext+tag ]
+// Output format: [ key header | nonce (12 bytes) | ciphertext+tag ]
+//
+// The key header names the active key and is only written by a keyring
+// service.
 //
 // The nonce is randomly generated per encryption call, ensuring that
 // encrypting the same plaintext twice produces different ciphertexts
@@ -68,20 +165,26 @@ func (e *EncryptionService) Encrypt(plaintext []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	// Seal appends the encrypted ciphertext and GCM authentication tag to nonce.
-	// dst is nonce (pre-allocated), so the final layout is: nonce || ciphertext+tag
-	ciphertext := e.gcm.Seal(nonce, nonce, plaintext, nil)
+	// Seal appends the encrypted ciphertext and GCM authentication tag to the
+	// header and nonce, so the final layout is: header || nonce || ciphertext+tag
+	ciphertext := e.gcm.Seal(append(e.keyHeader(), nonce...), nonce, plaintext, nil)
 
 	return ciphertext, nil
 }
 
 // Decrypt decrypts a ciphertext produced by Encrypt.
 //
-// Input format: [ nonce (12 bytes) | ciphertext+tag ]
+// Input format: [ key header (optional) | nonce (12 bytes) | ciphertext+tag ]
 //
 // Returns an error if the ciphertext has been tampered with (GCM authentication
-// failure), is too short, or any other decryption failure occurs.
+// failure), names a key that is not in the keyring, is too short, or any other
+// decryption failure occurs.
 func (e *EncryptionService) Decrypt(ciphertext []byte) ([]byte, error) {
+	candidates, ciphertext, err := e.parseKeyHeader(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
 	nonceSize := e.gcm.NonceSize()
 
 	// Minimum valid ciphertext: nonce + GCM overhead (tag = 16 bytes)
@@ -95,10 +198,12 @@ func (e *EncryptionService) Decrypt(ciphertext []byte) ([]byte, error) {
 
 	// Open decrypts and authenticates the payload.
 	// If the tag does not match, Open returns an error — this is the tamper-detection mechanism.
-	plaintext, err := e.gcm.Open(nil, nonce, encryptedPayload, nil)
-	if err != nil {
-		return nil, fmt.Errorf("decryption failed (possible data tampering or wrong key): %w", err)
+	// Without a key header each key is tried; only the right one authenticates.
+	for _, gcm := range candidates {
+		var plaintext []byte
+		if plaintext, err = gcm.Open(nil, nonce, encryptedPayload, nil); err == nil {
+			return plaintext, nil
+		}
 	}
-
-	return plaintext, nil
+	return nil, fmt.Errorf("decryption failed (possible data tampering or wrong key): %w", err)
 }
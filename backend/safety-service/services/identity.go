@@ -0,0 +1,131 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Identity verification case statuses. A case starts INITIATED and is moved
+// to one of the final statuses by the POSTIDENT callback.
+const (
+	IdentityInitiated = "INITIATED"
+	IdentitySuccess   = "SUCCESS"
+	IdentityFailed    = "FAILED"
+	IdentityCancelled = "CANCELLED"
+)
+
+// IdentityFinalStatus reports whether status is a result POSTIDENT can
+// report for a case.
+func IdentityFinalStatus(status string) bool {
+	return status == IdentitySuccess || status == IdentityFailed || status == IdentityCancelled
+}
+
+// Errors returned by IdentityCaseStore.Complete.
+var (
+	ErrCaseNotFound       = errors.New("identity case not found")
+	ErrCaseAlreadyDecided = errors.New("identity case already has a different result")
+)
+
+// IdentityCase is one POSTIDENT identification of a user.
+type IdentityCase struct {
+	CaseID    string    `json:"case_id"`
+	UserID    string    `json:"user_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IdentityCaseStore is an in-memory store of identity verification cases.
+type IdentityCaseStore struct {
+	mu    sync.RWMutex
+	cases map[string]*IdentityCase
+}
+
+// NewIdentityCaseStore creates an empty IdentityCaseStore.
+func NewIdentityCaseStore() *IdentityCaseStore {
+	return &IdentityCaseStore{cases: make(map[string]*IdentityCase)}
+}
+
+// Create records a new INITIATED case.
+func (s *IdentityCaseStore) Create(caseID, userID string, now time.Time) IdentityCase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &IdentityCase{CaseID: caseID, UserID: userID, Status: IdentityInitiated, CreatedAt: now, UpdatedAt: now}
+	s.cases[caseID] = c
+	return *c
+}
+
+// Get returns the case with the given ID.
+func (s *IdentityCaseStore) Get(caseID string) (IdentityCase, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.cases[caseID]
+	if !ok {
+		return IdentityCase{}, false
+	}
+	return *c, true
+}
+
+// Verified reports whether userID has a successful identification.
+func (s *IdentityCaseStore) Verified(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.cases {
+		if c.UserID == userID && c.Status == IdentitySuccess {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete records the POSTIDENT result for a case. Repeating the same result
+// is accepted, since webhooks may be delivered more than once; a different
+// result for a decided case is refused.
+func (s *IdentityCaseStore) Complete(caseID, status string, now time.Time) (IdentityCase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.cases[caseID]
+	if !ok {
+		return IdentityCase{}, ErrCaseNotFound
+	}
+	if c.Status == status {
+		return *c, nil
+	}
+	if c.Status != IdentityInitiated {
+		return *c, ErrCaseAlreadyDecided
+	}
+
+	c.Status = status
+	c.UpdatedAt = now
+	return *c, nil
+}
+
+// SignIdentityCallback returns the hex-encoded HMAC-SHA256 over
+// "<case_id>:<status>" that POSTIDENT sends with a callback.
+func SignIdentityCallback(secret, caseID, status string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(caseID + ":" + status))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyIdentityCallback reports whether signature is valid for the callback.
+// An empty secret or signature never verifies.
+func VerifyIdentityCallback(secret, caseID, status, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(SignIdentityCallback(secret, caseID, status))
+	return hmac.Equal(got, want)
+}
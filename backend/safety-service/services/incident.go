@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Incident report categories.
+const (
+	IncidentHarassment = "harassment"
+	IncidentAccident   = "accident"
+	IncidentLostItem   = "lost_item"
+	IncidentOther      = "other"
+)
+
+// ValidIncidentCategory reports whether category is a known incident category.
+func ValidIncidentCategory(category string) bool {
+	switch category {
+	case IncidentHarassment, IncidentAccident, IncidentLostItem, IncidentOther:
+		return true
+	}
+	return false
+}
+
+// Incident report statuses. A report starts open; the safety team moves it to
+// investigating and then resolved, and closes it when no further action is
+// needed.
+const (
+	IncidentOpen          = "open"
+	IncidentInvestigating = "investigating"
+	IncidentResolved      = "resolved"
+	IncidentClosed        = "closed"
+)
+
+// incidentTransitions lists the statuses each status may move to. Resolved
+// reports can be reopened; closed ones are final.
+var incidentTransitions = map[string][]string{
+	IncidentOpen:          {IncidentInvestigating, IncidentClosed},
+	IncidentInvestigating: {IncidentResolved, IncidentClosed},
+	IncidentResolved:      {IncidentInvestigating, IncidentClosed},
+}
+
+// ValidIncidentStatus reports whether status is a known incident status.
+func ValidIncidentStatus(status string) bool {
+	switch status {
+	case IncidentOpen, IncidentInvestigating, IncidentResolved, IncidentClosed:
+		return true
+	}
+	return false
+}
+
+// Errors returned by IncidentStore.UpdateStatus.
+var (
+	ErrIncidentNotFound          = errors.New("incident not found")
+	ErrInvalidIncidentTransition = errors.New("invalid incident status transition")
+)
+
+// Incident is a post-trip incident report filed by a rider or driver.
+type Incident struct {
+	ID         string    `json:"id"`
+	RideID     string    `json:"ride_id"`
+	ReporterID string    `json:"reporter_id"`
+	Category   string    `json:"category"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// EncryptedDescription is the reporter's free-text account, encrypted
+	// with the EncryptionService since it may contain personal data. It is
+	// never serialised and never stored in plaintext.
+	EncryptedDescription []byte `json:"-"`
+}
+
+// IncidentStore is an in-memory store of incident reports.
+type IncidentStore struct {
+	mu        sync.RWMutex
+	incidents map[string]*Incident
+}
+
+// NewIncidentStore creates an empty IncidentStore.
+func NewIncidentStore() *IncidentStore {
+	return &IncidentStore{incidents: make(map[string]*Incident)}
+}
+
+// Create stores a new open incident under a fresh ID.
+func (s *IncidentStore) Create(rideID, reporterID, category string, encryptedDescription []byte, now time.Time) Incident {
+	incident := &Incident{
+		ID:                   uuid.New().String(),
+		RideID:               rideID,
+		ReporterID:           reporterID,
+		Category:             category,
+		Status:               IncidentOpen,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		EncryptedDescription: encryptedDescription,
+	}
+
+	s.mu.Lock()
+	s.incidents[incident.ID] = incident
+	s.mu.Unlock()
+
+	return *incident
+}
+
+// Get returns the incident with the given ID.
+func (s *IncidentStore) Get(id string) (Incident, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	incident, ok := s.incidents[id]
+	if !ok {
+		return Incident{}, false
+	}
+	return *incident, true
+}
+
+// UpdateStatus moves an incident to status. Moves not allowed from the
+// current status, including to the same status, are refused with
+// ErrInvalidIncidentTransition and the unchanged incident.
+func (s *IncidentStore) UpdateStatus(id, status string, now time.Time) (Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, ok := s.incidents[id]
+	if !ok {
+		return Incident{}, ErrIncidentNotFound
+	}
+	for _, next := range incidentTransitions[incident.Status] {
+		if next == status {
+			incident.Status = status
+			incident.UpdatedAt = now
+			return *incident, nil
+		}
+	}
+	return *incident, ErrInvalidIncidentTransition
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const (
+	oldTestKey = "0123456789abcdef0123456789abcdef"
+	newTestKey = "fedcba9876543210fedcba9876543210"
+)
+
+func TestKeyringDecryptsCiphertextFromRotatedKey(t *testing.T) {
+	before, err := NewEncryptionServiceWithKeyring(map[string]string{"2024-01": oldTestKey}, "2024-01")
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	plaintext := []byte("Führungszeugnis")
+	old, err := before.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	// Rotate: 2025-01 becomes active, 2024-01 stays for decryption.
+	after, err := NewEncryptionServiceWithKeyring(map[string]string{"2024-01": oldTestKey, "2025-01": newTestKey}, "2025-01")
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	decrypted, err := after.Decrypt(old)
+	if err != nil {
+		t.Fatalf("Decryption with historical key failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted %q, want %q", decrypted, plaintext)
+	}
+
+	fresh, err := after.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	if !bytes.HasPrefix(fresh, append(append([]byte{}, keyHeaderMagic...), byte(len("2025-01")))) {
+		t.Error("new ciphertext does not name the active key")
+	}
+	if _, err := before.Decrypt(fresh); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("decrypting with a keyring lacking the new key: err = %v, want ErrUnknownKeyID", err)
+	}
+}
+
+func TestKeyringDecryptsLegacyCiphertext(t *testing.T) {
+	legacy, err := NewEncryptionService(oldTestKey)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	plaintext := []byte("P-Schein scan")
+	blob, err := legacy.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	var stream bytes.Buffer
+	if err := legacy.EncryptStream(bytes.NewReader(plaintext), &stream); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+
+	keyring, err := NewEncryptionServiceWithKeyring(map[string]string{"legacy": oldTestKey, "2025-01": newTestKey}, "2025-01")
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	if decrypted, err := keyring.Decrypt(blob); err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("legacy ciphertext: got %q, %v", decrypted, err)
+	}
+	var decrypted bytes.Buffer
+	if err := keyring.DecryptStream(&stream, &decrypted); err != nil || !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Errorf("legacy stream: got %q, %v", decrypted.Bytes(), err)
+	}
+}
+
+func TestKeyringStreamRoundTripAcrossRotation(t *testing.T) {
+	before, _ := NewEncryptionServiceWithKeyring(map[string]string{"2024-01": oldTestKey}, "2024-01")
+	after, _ := NewEncryptionServiceWithKeyring(map[string]string{"2024-01": oldTestKey, "2025-01": newTestKey}, "2025-01")
+
+	plaintext := bytes.Repeat([]byte("insurance "), StreamChunkSize/4)
+	var stream bytes.Buffer
+	if err := before.EncryptStream(bytes.NewReader(plaintext), &stream); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+
+	var decrypted bytes.Buffer
+	if err := after.DecryptStream(&stream, &decrypted); err != nil {
+		t.Fatalf("DecryptStream failed: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Error("decrypted stream does not match the original")
+	}
+}
+
+func TestKeyringRejectsUnknownKeyID(t *testing.T) {
+	other, _ := NewEncryptionServiceWithKeyring(map[string]string{"other": newTestKey}, "other")
+	svc, _ := NewEncryptionServiceWithKeyring(map[string]string{"2024-01": oldTestKey}, "2024-01")
+
+	blob, err := other.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	if _, err := svc.Decrypt(blob); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("Decrypt: err = %v, want ErrUnknownKeyID", err)
+	}
+
+	var stream bytes.Buffer
+	if err := other.EncryptStream(bytes.NewReader([]byte("secret")), &stream); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+	if err := svc.DecryptStream(&stream, &bytes.Buffer{}); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("DecryptStream: err = %v, want ErrUnknownKeyID", err)
+	}
+}
+
+func TestNewEncryptionServiceWithKeyringValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		keys   map[string]string
+		active string
+	}{
+		"active key missing": {map[string]string{"a": oldTestKey}, "b"},
+		"short key":          {map[string]string{"a": oldTestKey, "b": "short"}, "a"},
+		"empty key ID":       {map[string]string{"": oldTestKey}, ""},
+	} {
+		if _, err := NewEncryptionServiceWithKeyring(tc.keys, tc.active); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
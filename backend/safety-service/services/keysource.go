@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// AESKeySize is the required key length in bytes for AES-256.
+const AESKeySize = 32
+
+// KeySource provides the AES-256 key used by EncryptionService.
+//
+// GDPR Art. 32 recommends that encryption keys are stored separately from the
+// data they protect. Implementations let operators move the key out of the
+// process environment into a mounted secret file or a KMS such as HashiCorp
+// Vault without changing the service code.
+type KeySource interface {
+	// Name identifies the source in log and error messages. It must never
+	// contain key material.
+	Name() string
+	// LoadKey returns the raw key material.
+	LoadKey(ctx context.Context) (string, error)
+}
+
+// KeySourceConfig selects and configures a KeySource.
+type KeySourceConfig struct {
+	// Type is one of "env" (default), "file" or "kms".
+	Type string
+	// EnvVar is the environment variable read by the env source.
+	EnvVar string
+	// FilePath is the path read by the file source.
+	FilePath string
+	// KMSRef is the secret reference resolved by the kms source,
+	// e.g. "vault://secret/data/safety-service#aes_key".
+	KMSRef string
+	// KMSClient resolves KMSRef. Required for the kms source.
+	KMSClient SecretFetcher
+}
+
+// NewKeySource builds the KeySource described by cfg.
+func NewKeySource(cfg KeySourceConfig) (KeySource, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "", "env":
+		envVar := cfg.EnvVar
+		if envVar == "" {
+			envVar = "AES_ENCRYPTION_KEY"
+		}
+		return &EnvKeySource{Var: envVar}, nil
+	case "file":
+		if cfg.FilePath == "" {
+			return nil, errors.New("file key source requires a file path")
+		}
+		return &FileKeySource{Path: cfg.FilePath}, nil
+	case "kms":
+		if cfg.KMSRef == "" {
+			return nil, errors.New("kms key source requires a key reference")
+		}
+		if cfg.KMSClient == nil {
+			return nil, errors.New("kms key source requires a KMS client")
+		}
+		return &KMSKeySource{Ref: cfg.KMSRef, Client: cfg.KMSClient}, nil
+	default:
+		return nil, fmt.Errorf("unknown key source %q (expected env, file or kms)", cfg.Type)
+	}
+}
+
+// LoadEncryptionKey fetches the key from src and validates that it is exactly
+// AESKeySize bytes long.
+func LoadEncryptionKey(ctx context.Context, src KeySource) (string, error) {
+	key, err := src.LoadKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load AES key from %s: %w", src.Name(), err)
+	}
+	if len(key) != AESKeySize {
+		return "", fmt.Errorf("AES key from %s must be exactly %d bytes for AES-256, got %d bytes", src.Name(), AESKeySize, len(key))
+	}
+	return key, nil
+}
+
+// ParseRetiredKeys parses a list of retired keys kept in the keyring so that
+// ciphertext written before a rotation stays readable. Entries are separated
+// by commas and have the form "<key ID>:<key>"; the key may contain colons
+// but not commas.
+func ParseRetiredKeys(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return keys, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		id, key, ok := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("retired key entry must have the form <key ID>:<key>")
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("retired key %q is listed twice", id)
+		}
+		if len(key) != AESKeySize {
+			return nil, fmt.Errorf("retired key %q must be exactly %d bytes for AES-256, got %d bytes", id, AESKeySize, len(key))
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// ErrKeyNotSet is returned by EnvKeySource when the variable is unset or empty.
+var ErrKeyNotSet = errors.New("key not set")
+
+// EnvKeySource reads the key from an environment variable.
+type EnvKeySource struct {
+	Var string
+}
+
+// Name implements KeySource.
+func (s *EnvKeySource) Name() string {
+	return "env:" + s.Var
+}
+
+// LoadKey implements KeySource.
+func (s *EnvKeySource) LoadKey(ctx context.Context) (string, error) {
+	key := os.Getenv(s.Var)
+	if key == "" {
+		return "", ErrKeyNotSet
+	}
+	return key, nil
+}
+
+// FileKeySource reads the key from a file, e.g. a Kubernetes secret mount.
+// A single trailing newline is stripped since most tooling appends one.
+type FileKeySource struct {
+	Path string
+}
+
+// Name implements KeySource.
+func (s *FileKeySource) Name() string {
+	return "file:" + s.Path
+}
+
+// LoadKey implements KeySource.
+func (s *FileKeySource) LoadKey(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file: %w", err)
+	}
+	key := strings.TrimSuffix(string(data), "\n")
+	key = strings.TrimSuffix(key, "\r")
+	return key, nil
+}
+
+// SecretFetcher resolves a secret reference against a KMS or secrets manager.
+type SecretFetcher interface {
+	FetchSecret(ctx context.Context, ref string) (string, error)
+}
+
+// KMSKeySource reads the key from a KMS via a SecretFetcher.
+type KMSKeySource struct {
+	Ref    string
+	Client SecretFetcher
+}
+
+// Name implements KeySource.
+func (s *KMSKeySource) Name() string {
+	return "kms:" + s.Ref
+}
+
+// LoadKey implements KeySource.
+func (s *KMSKeySource) LoadKey(ctx context.Context) (string, error) {
+	return s.Client.FetchSecret(ctx, s.Ref)
+}
+
+// VaultClient fetches secrets from the HashiCorp Vault KV v2 HTTP API.
+//
+// References have the form "vault://<mount>/data/<path>#<field>".
+type VaultClient struct {
+	Addr       string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewVaultClient creates a VaultClient with a bounded request timeout.
+func NewVaultClient(addr, token string) *VaultClient {
+	return &VaultClient{
+		Addr:       strings.TrimSuffix(addr, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// FetchSecret implements SecretFetcher.
+func (c *VaultClient) FetchSecret(ctx context.Context, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "vault" {
+		return "", fmt.Errorf("invalid vault reference %q", ref)
+	}
+	field := u.Fragment
+	if field == "" {
+		return "", fmt.Errorf("vault reference %q is missing a #field", ref)
+	}
+	path := strings.TrimPrefix(u.Host+u.Path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", c.Token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	secret, ok := body.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found in vault secret", field)
+	}
+	return secret, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef" // 32 bytes
+
+type fakeFetcher struct {
+	secret string
+	err    error
+}
+
+func (f *fakeFetcher) FetchSecret(ctx context.Context, ref string) (string, error) {
+	return f.secret, f.err
+}
+
+func TestEnvKeySource(t *testing.T) {
+	t.Setenv("TEST_AES_KEY", testKey)
+
+	src, err := NewKeySource(KeySourceConfig{Type: "env", EnvVar: "TEST_AES_KEY"})
+	if err != nil {
+		t.Fatalf("NewKeySource failed: %v", err)
+	}
+
+	key, err := LoadEncryptionKey(context.Background(), src)
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey failed: %v", err)
+	}
+	if key != testKey {
+		t.Errorf("got key %q, want %q", key, testKey)
+	}
+}
+
+func TestEnvKeySourceUnset(t *testing.T) {
+	t.Setenv("TEST_AES_KEY", "")
+
+	_, err := LoadEncryptionKey(context.Background(), &EnvKeySource{Var: "TEST_AES_KEY"})
+	if !errors.Is(err, ErrKeyNotSet) {
+		t.Errorf("expected ErrKeyNotSet, got %v", err)
+	}
+}
+
+func TestFileKeySourceStripsTrailingNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aes.key")
+	if err := os.WriteFile(path, []byte(testKey+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	src, err := NewKeySource(KeySourceConfig{Type: "file", FilePath: path})
+	if err != nil {
+		t.Fatalf("NewKeySource failed: %v", err)
+	}
+
+	key, err := LoadEncryptionKey(context.Background(), src)
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey failed: %v", err)
+	}
+	if key != testKey {
+		t.Errorf("got key %q, want %q", key, testKey)
+	}
+}
+
+func TestFileKeySourceMissingFile(t *testing.T) {
+	src := &FileKeySource{Path: filepath.Join(t.TempDir(), "missing.key")}
+
+	if _, err := LoadEncryptionKey(context.Background(), src); err == nil {
+		t.Error("expected an error for a missing key file")
+	}
+}
+
+func TestLoadEncryptionKeyRejectsWrongLength(t *testing.T) {
+	src := &KMSKeySource{Ref: "vault://secret/data/test#aes_key", Client: &fakeFetcher{secret: "short-key"}}
+
+	if _, err := LoadEncryptionKey(context.Background(), src); err == nil {
+		t.Error("expected an error for a key that is not 32 bytes")
+	}
+}
+
+func TestKMSKeySource(t *testing.T) {
+	src, err := NewKeySource(KeySourceConfig{
+		Type:      "kms",
+		KMSRef:    "vault://secret/data/test#aes_key",
+		KMSClient: &fakeFetcher{secret: testKey},
+	})
+	if err != nil {
+		t.Fatalf("NewKeySource failed: %v", err)
+	}
+
+	key, err := LoadEncryptionKey(context.Background(), src)
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey failed: %v", err)
+	}
+	if key != testKey {
+		t.Errorf("got key %q, want %q", key, testKey)
+	}
+}
+
+func TestNewKeySourceInvalidConfig(t *testing.T) {
+	cases := map[string]KeySourceConfig{
+		"unknown type":   {Type: "s3"},
+		"file w/o path":  {Type: "file"},
+		"kms w/o ref":    {Type: "kms", KMSClient: &fakeFetcher{}},
+		"kms w/o client": {Type: "kms", KMSRef: "vault://secret/data/test#aes_key"},
+	}
+	for name, cfg := range cases {
+		if _, err := NewKeySource(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestVaultClientFetchSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/safety-service" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"aes_key":"` + testKey + `"}}}`))
+	}))
+	defer srv.Close()
+
+	client := NewVaultClient(srv.URL, "test-token")
+
+	secret, err := client.FetchSecret(context.Background(), "vault://secret/data/safety-service#aes_key")
+	if err != nil {
+		t.Fatalf("FetchSecret failed: %v", err)
+	}
+	if secret != testKey {
+		t.Errorf("got secret %q, want %q", secret, testKey)
+	}
+
+	if _, err := client.FetchSecret(context.Background(), "vault://secret/data/safety-service#other"); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+	if _, err := client.FetchSecret(context.Background(), "vault://secret/data/safety-service"); err == nil {
+		t.Error("expected an error for a reference without a field")
+	}
+
+	client.Token = "wrong-token"
+	if _, err := client.FetchSecret(context.Background(), "vault://secret/data/safety-service#aes_key"); err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+}
+
+func TestParseRetiredKeys(t *testing.T) {
+	keys, err := ParseRetiredKeys("2024-01:" + testKey + ", 2023-07:" + "a:b:c:d:e:f:g:h:i:j:k:l:m:n:o:pq")
+	if err != nil {
+		t.Fatalf("ParseRetiredKeys failed: %v", err)
+	}
+	if len(keys) != 2 || keys["2024-01"] != testKey || keys["2023-07"] != "a:b:c:d:e:f:g:h:i:j:k:l:m:n:o:pq" {
+		t.Errorf("got %v", keys)
+	}
+
+	if keys, err := ParseRetiredKeys(""); err != nil || len(keys) != 0 {
+		t.Errorf("empty list: %v, %v", keys, err)
+	}
+
+	for _, invalid := range []string{
+		testKey,
+		":" + testKey,
+		"2024-01:short",
+		"2024-01:" + testKey + ",2024-01:" + testKey,
+	} {
+		if _, err := ParseRetiredKeys(invalid); err == nil {
+			t.Errorf("ParseRetiredKeys(%q) succeeded, want an error", invalid)
+		}
+	}
+}
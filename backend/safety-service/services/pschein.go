@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// PScheinRenewalWarningDays is how close to its expiry a P-Schein is flagged
+// for renewal.
+const PScheinRenewalWarningDays = 30
+
+// PScheinExpiry is the result of checking a P-Schein expiry date.
+type PScheinExpiry struct {
+	ExpiryDate    time.Time
+	DaysRemaining int
+	Expired       bool
+	RenewSoon     bool
+}
+
+// CheckPScheinExpiry parses a YYYY-MM-DD expiry date and compares it with the
+// current day in Europe/Berlin. A P-Schein is valid through its expiry date.
+func CheckPScheinExpiry(expiryDate string, now time.Time) (PScheinExpiry, error) {
+	expiry, err := time.ParseInLocation("2006-01-02", expiryDate, timeutil.Berlin())
+	if err != nil {
+		return PScheinExpiry{}, fmt.Errorf("expiry_date must be a date in YYYY-MM-DD format, got %q", expiryDate)
+	}
+
+	local := now.In(timeutil.Berlin())
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, timeutil.Berlin())
+	// Round, because days across a DST change are not exactly 24h long.
+	days := int(expiry.Sub(today).Round(24*time.Hour) / (24 * time.Hour))
+
+	return PScheinExpiry{
+		ExpiryDate:    expiry,
+		DaysRemaining: days,
+		Expired:       days < 0,
+		RenewSoon:     days >= 0 && days <= PScheinRenewalWarningDays,
+	}, nil
+}
+
+// PScheinVerified is the user-service status of a P-Schein that passed
+// manual review.
+const PScheinVerified = "VERIFIED"
+
+// ErrDriverNotFound is returned by a PScheinStatusSource for a user the
+// user-service does not know.
+var ErrDriverNotFound = errors.New("driver not found")
+
+// PScheinStatus is a driver's P-Schein as recorded by the user-service, which
+// owns the review workflow.
+type PScheinStatus struct {
+	Status    string     `json:"p_schein_status"`
+	ExpiresAt *time.Time `json:"p_schein_expires_at"`
+}
+
+// PScheinStatusSource looks up a driver's P-Schein review status.
+type PScheinStatusSource interface {
+	PScheinStatus(ctx context.Context, userID string) (PScheinStatus, error)
+}
+
+// HTTPPScheinStatusSource reads the P-Schein status from the user-service at
+// BaseURL.
+type HTTPPScheinStatusSource struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewHTTPPScheinStatusSource creates a source asking the user-service at
+// baseURL, bounding each request by timeout.
+func NewHTTPPScheinStatusSource(baseURL string, timeout time.Duration) *HTTPPScheinStatusSource {
+	return &HTTPPScheinStatusSource{BaseURL: strings.TrimRight(baseURL, "/"), Client: &http.Client{Timeout: timeout}}
+}
+
+// PScheinStatus fetches GET /users/{id} from the user-service.
+func (s *HTTPPScheinStatusSource) PScheinStatus(ctx context.Context, userID string) (PScheinStatus, error) {
+	if s.BaseURL == "" {
+		return PScheinStatus{}, errors.New("user-service URL is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/users/"+url.PathEscape(userID), nil)
+	if err != nil {
+		return PScheinStatus{}, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return PScheinStatus{}, fmt.Errorf("user-service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return PScheinStatus{}, ErrDriverNotFound
+	default:
+		return PScheinStatus{}, fmt.Errorf("user-service answered %s", resp.Status)
+	}
+
+	var status PScheinStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return PScheinStatus{}, fmt.Errorf("invalid user from user-service: %w", err)
+	}
+	return status, nil
+}
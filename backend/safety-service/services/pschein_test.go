@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckPScheinExpiry(t *testing.T) {
+	// 23:30 UTC is already 1 March in Berlin
+	now := time.Date(2025, 2, 28, 23, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		expiry    string
+		days      int
+		expired   bool
+		renewSoon bool
+	}{
+		{"2025-02-28", -1, true, false},
+		{"2024-06-01", -273, true, false},
+		{"2025-03-01", 0, false, true},
+		{"2025-03-31", 30, false, true}, // across the DST change
+		{"2025-04-01", 31, false, false},
+		{"2027-12-31", 1035, false, false},
+	} {
+		got, err := CheckPScheinExpiry(tc.expiry, now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.expiry, err)
+		}
+		if got.DaysRemaining != tc.days || got.Expired != tc.expired || got.RenewSoon != tc.renewSoon {
+			t.Errorf("%s: got %+v, want days=%d expired=%v renewSoon=%v", tc.expiry, got, tc.days, tc.expired, tc.renewSoon)
+		}
+	}
+}
+
+func TestCheckPScheinExpiryMalformed(t *testing.T) {
+	for _, expiry := range []string{"31.12.2027", "2027-13-01", "2027-02-30", "tomorrow", "2027-1-1"} {
+		if _, err := CheckPScheinExpiry(expiry, time.Now()); err == nil {
+			t.Errorf("%q: expected an error", expiry)
+		}
+	}
+}
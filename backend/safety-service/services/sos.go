@@ -0,0 +1,59 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SOSEvent is a panic-button alert raised from the mobile app. Events are
+// evidence for safety staff and the authorities, so they are never modified
+// after they are recorded.
+type SOSEvent struct {
+	CaseID      string    `json:"case_id"`
+	RideID      string    `json:"ride_id"`
+	UserID      string    `json:"user_id"`
+	Lat         float64   `json:"lat"`
+	Lng         float64   `json:"lng"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// SOSStore is an append-only, in-memory store of SOS events. It has no update
+// or delete operations, and callers only ever receive copies.
+type SOSStore struct {
+	mu     sync.RWMutex
+	events map[string]SOSEvent
+}
+
+// NewSOSStore creates an empty SOSStore.
+func NewSOSStore() *SOSStore {
+	return &SOSStore{events: make(map[string]SOSEvent)}
+}
+
+// Record stores a new SOS event under a fresh case ID.
+func (s *SOSStore) Record(rideID, userID string, lat, lng float64, now time.Time) SOSEvent {
+	event := SOSEvent{
+		CaseID:      uuid.New().String(),
+		RideID:      rideID,
+		UserID:      userID,
+		Lat:         lat,
+		Lng:         lng,
+		TriggeredAt: now,
+	}
+
+	s.mu.Lock()
+	s.events[event.CaseID] = event
+	s.mu.Unlock()
+
+	return event
+}
+
+// Get returns the SOS event with the given case ID.
+func (s *SOSStore) Get(caseID string) (SOSEvent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[caseID]
+	return event, ok
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamChunkSize is the plaintext size of every chunk but the last.
+const StreamChunkSize = 64 << 10
+
+// ErrTruncatedStream is returned by DecryptStream when the input ends before
+// the final chunk.
+var ErrTruncatedStream = errors.New("encrypted stream is truncated")
+
+// EncryptStream encrypts src chunk by chunk and writes the framed result to
+// dst. It buffers two chunks of plaintext; whether the whole document is held
+// in memory depends on src and dst.
+//
+// A keyring service first writes the key header described at
+// keyHeaderMagic. Then, repeated once per chunk:
+//
+//	[ length (4 bytes, big-endian) | nonce (12 bytes) | ciphertext+tag ]
+//
+// length counts the nonce and ciphertext+tag that follow it. Plaintext chunks
+// are StreamChunkSize bytes, except the last, which may be shorter.
+//
+// Each chunk is sealed with its own random nonce. The additional data binds
+// the chunk to its position and marks the last chunk:
+//
+//	[ chunk index (8 bytes, big-endian) | final flag (1 byte) ]
+//
+// so reordered, dropped or appended chunks fail authentication, and a stream
+// cut off at a chunk boundary is detected because no final chunk was seen.
+func (e *EncryptionService) EncryptStream(src io.Reader, dst io.Writer) error {
+	current := make([]byte, StreamChunkSize)
+	next := make([]byte, StreamChunkSize)
+
+	n, err := readChunk(src, current)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("plaintext must not be empty")
+	}
+
+	if header := e.keyHeader(); header != nil {
+		if _, err := dst.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for index := uint64(0); ; index++ {
+		// Read one chunk ahead to know whether the current one is the last.
+		m := 0
+		if n == StreamChunkSize {
+			if m, err = readChunk(src, next); err != nil {
+				return err
+			}
+		}
+		final := m == 0
+
+		if err := e.writeFrame(dst, current[:n], index, final); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		current, next = next, current
+		n = m
+	}
+}
+
+// DecryptStream reverses EncryptStream, writing plaintext to dst as each chunk
+// is authenticated. On error, dst may already hold the authenticated prefix of
+// the document; callers that must not release partial output should decrypt
+// into a buffer first.
+func (e *EncryptionService) DecryptStream(src io.Reader, dst io.Writer) error {
+	nonceSize := e.gcm.NonceSize()
+	maxFrame := nonceSize + StreamChunkSize + e.gcm.Overhead()
+	minFrame := nonceSize + e.gcm.Overhead()
+
+	// The first four bytes are either the key header magic or the length
+	// prefix of the first frame.
+	var header [4]byte
+	if err := readStreamFull(src, header[:]); err != nil {
+		return err
+	}
+	candidates := e.allKeys()
+	if bytes.Equal(header[:], keyHeaderMagic) {
+		var idLen [1]byte
+		if err := readStreamFull(src, idLen[:]); err != nil {
+			return err
+		}
+		keyHeader := make([]byte, len(keyHeaderMagic)+1+int(idLen[0]))
+		copy(keyHeader, keyHeaderMagic)
+		keyHeader[len(keyHeaderMagic)] = idLen[0]
+		if err := readStreamFull(src, keyHeader[len(keyHeaderMagic)+1:]); err != nil {
+			return err
+		}
+		var err error
+		if candidates, _, err = e.parseKeyHeader(keyHeader); err != nil {
+			return err
+		}
+		if err := readStreamFull(src, header[:]); err != nil {
+			return err
+		}
+	}
+
+	frame := make([]byte, maxFrame)
+	// Open clears its output on failure, so it must not decrypt in place:
+	// the frame is needed again for the final-chunk attempt.
+	out := make([]byte, 0, StreamChunkSize)
+	for index := uint64(0); ; index++ {
+		// header holds this frame's length prefix, read before the loop for
+		// the first frame and at the end of the previous iteration otherwise
+		length := int(binary.BigEndian.Uint32(header[:]))
+		if length < minFrame || length > maxFrame {
+			return fmt.Errorf("invalid frame length %d for chunk %d", length, index)
+		}
+		if err := readStreamFull(src, frame[:length]); err != nil {
+			return err
+		}
+
+		nonce, payload := frame[:nonceSize], frame[nonceSize:length]
+
+		// The final flag is not stored in the frame; try the common case
+		// first and fall back to the final-chunk additional data. Without a
+		// key header the first chunk also decides which key the stream uses.
+		var (
+			plaintext []byte
+			final     bool
+			err       error
+		)
+		for _, gcm := range candidates {
+			if plaintext, err = gcm.Open(out[:0], nonce, payload, chunkAAD(index, false)); err == nil {
+				final = false
+			} else if plaintext, err = gcm.Open(out[:0], nonce, payload, chunkAAD(index, true)); err == nil {
+				final = true
+			}
+			if err == nil {
+				candidates = []cipher.AEAD{gcm}
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("decryption of chunk %d failed (possible data tampering or wrong key): %w", index, err)
+		}
+
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+
+		if final {
+			var extra [1]byte
+			if n, _ := io.ReadFull(src, extra[:]); n > 0 {
+				return errors.New("unexpected data after final chunk")
+			}
+			return nil
+		}
+
+		if err := readStreamFull(src, header[:]); err != nil {
+			return err
+		}
+	}
+}
+
+// readStreamFull fills buf from an encrypted stream; running out of input is
+// reported as ErrTruncatedStream.
+func readStreamFull(src io.Reader, buf []byte) error {
+	if _, err := io.ReadFull(src, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncatedStream
+		}
+		return fmt.Errorf("failed to read encrypted stream: %w", err)
+	}
+	return nil
+}
+
+func (e *EncryptionService) writeFrame(dst io.Writer, plaintext []byte, index uint64, final bool) error {
+	nonceSize := e.gcm.NonceSize()
+	frame := make([]byte, 4+nonceSize, 4+nonceSize+len(plaintext)+e.gcm.Overhead())
+
+	nonce := frame[4 : 4+nonceSize]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	frame = e.gcm.Seal(frame, nonce, plaintext, chunkAAD(index, final))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(frame)-4))
+
+	_, err := dst.Write(frame)
+	return err
+}
+
+func chunkAAD(index uint64, final bool) []byte {
+	aad := make([]byte, 9)
+	binary.BigEndian.PutUint64(aad, index)
+	if final {
+		aad[8] = 1
+	}
+	return aad
+}
+
+// readChunk fills buf from r and returns how many bytes were read; a short
+// count means r is exhausted.
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, nil
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to read plaintext: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func newTestEncryptionService(t *testing.T) *EncryptionService {
+	t.Helper()
+	svc, err := NewEncryptionService("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	return svc
+}
+
+func TestEncryptStreamRoundTrip(t *testing.T) {
+	svc := newTestEncryptionService(t)
+
+	plaintext := make([]byte, 5<<20)
+	if _, err := rand.Read(plaintext); err != nil {
+		t.Fatalf("failed to generate plaintext: %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	if err := svc.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+	if bytes.Contains(encrypted.Bytes(), plaintext[:64]) {
+		t.Error("encrypted stream contains plaintext")
+	}
+
+	var decrypted bytes.Buffer
+	if err := svc.DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+		t.Fatalf("DecryptStream failed: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Error("decrypted stream does not match the original")
+	}
+}
+
+func TestEncryptStreamChunkBoundaries(t *testing.T) {
+	svc := newTestEncryptionService(t)
+
+	for _, size := range []int{1, StreamChunkSize - 1, StreamChunkSize, StreamChunkSize + 1, 3 * StreamChunkSize} {
+		plaintext := bytes.Repeat([]byte{'x'}, size)
+		var encrypted, decrypted bytes.Buffer
+		if err := svc.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+			t.Fatalf("size %d: EncryptStream failed: %v", size, err)
+		}
+		if err := svc.DecryptStream(&encrypted, &decrypted); err != nil {
+			t.Fatalf("size %d: DecryptStream failed: %v", size, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plaintext) {
+			t.Errorf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestEncryptStreamRejectsEmptyInput(t *testing.T) {
+	svc := newTestEncryptionService(t)
+	if err := svc.EncryptStream(bytes.NewReader(nil), &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for empty plaintext")
+	}
+}
+
+func TestDecryptStreamRejectsTruncation(t *testing.T) {
+	svc := newTestEncryptionService(t)
+
+	plaintext := bytes.Repeat([]byte("P-Schein "), StreamChunkSize/2) // several chunks
+	var encrypted bytes.Buffer
+	if err := svc.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+	stream := encrypted.Bytes()
+	frameLen := 4 + 12 + StreamChunkSize + 16
+
+	for name, cut := range map[string]int{
+		"mid-frame":      len(stream) - 10,
+		"chunk boundary": frameLen,
+		"header only":    frameLen + 2,
+		"empty":          0,
+	} {
+		err := svc.DecryptStream(bytes.NewReader(stream[:cut]), &bytes.Buffer{})
+		if !errors.Is(err, ErrTruncatedStream) {
+			t.Errorf("%s: err = %v, want ErrTruncatedStream", name, err)
+		}
+	}
+}
+
+func TestDecryptStreamRejectsTampering(t *testing.T) {
+	svc := newTestEncryptionService(t)
+
+	var encrypted bytes.Buffer
+	if err := svc.EncryptStream(bytes.NewReader(bytes.Repeat([]byte{'a'}, 2*StreamChunkSize+5)), &encrypted); err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+	frameLen := 4 + 12 + StreamChunkSize + 16
+
+	tampered := bytes.Clone(encrypted.Bytes())
+	tampered[frameLen/2] ^= 0x01
+	if err := svc.DecryptStream(bytes.NewReader(tampered), &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a modified chunk")
+	}
+
+	// Swapping the first two (equal-length) chunks must fail: each is bound to its index.
+	stream := encrypted.Bytes()
+	swapped := append(append(bytes.Clone(stream[frameLen:2*frameLen]), stream[:frameLen]...), stream[2*frameLen:]...)
+	if err := svc.DecryptStream(bytes.NewReader(swapped), &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for reordered chunks")
+	}
+}
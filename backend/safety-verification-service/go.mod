@@ -4,5 +4,10 @@ go 1.21
 
 require (
 	github.com/gorilla/mux v1.8.1
+	github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
 	github.com/sirupsen/logrus v1.9.3
 )
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,13 +27,24 @@ var log = logrus.New()
 
 func init() {
 	log.Out = os.Stdout
-	log.SetFormatter(&logrus.JSONFormatter{})
+	log.SetFormatter(utcFormatter{&logrus.JSONFormatter{TimestampFormat: timeutil.Layout}})
+}
+
+// utcFormatter renders entries in UTC per the platform timestamp policy.
+type utcFormatter struct {
+	logrus.Formatter
+}
+
+func (f utcFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry.Time = entry.Time.UTC()
+	return f.Formatter.Format(entry)
 }
 
 func main() {
 	r := mux.NewRouter()
 
 	r.HandleFunc("/health", HealthHandler).Methods("GET")
+	r.HandleFunc("/version", buildinfo.Handler("safety-verification-service")).Methods("GET")
 	r.HandleFunc("/verify", VerifyHandler).Methods("POST")
 	r.HandleFunc("/status/{driver_id}", StatusHandler).Methods("GET")
 
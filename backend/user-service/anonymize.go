@@ -0,0 +1,28 @@
+package main
+
+import "time"
+
+// Tombstone values written over a user's personal data on anonymization
+const (
+	anonymizedName        = "Deleted user"
+	anonymizedEmailDomain = "anonymized.invalid"
+)
+
+// anonymizeUser replaces the personal data of user with tombstones. The ID,
+// user type and P-Schein status are kept so rides and audit history still
+// resolve.
+func anonymizeUser(user *User, now time.Time) {
+	// The tombstone email embeds the ID so it stays unique; the .invalid
+	// TLD (RFC 2606) guarantees it can never receive mail
+	user.Email = "deleted-" + user.ID + "@" + anonymizedEmailDomain
+	user.Name = anonymizedName
+	user.Phone = ""
+	user.PScheinNumber = ""
+	// The plate links the record back to the registered keeper
+	user.Vehicle = nil
+	if user.Available {
+		setAvailability(user, false, now)
+	}
+	user.AnonymizedAt = &now
+	user.UpdatedAt = now
+}
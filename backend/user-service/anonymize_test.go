@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func addTestDriver(t *testing.T, id, email string) *UserStore {
+	t.Helper()
+	return useMemoryUsers(t, User{ID: id, Email: email, Name: "Jonas", Phone: "+4915123456789", UserType: Driver, PScheinNumber: "PS-123", PScheinStatus: PScheinVerified})
+}
+
+func deleteUser(id, mode string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", deleteUserHandler).Methods("DELETE")
+	path := "/users/" + id
+	if mode != "" {
+		path += "?mode=" + mode
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	return rec
+}
+
+func TestDeleteUserAnonymize(t *testing.T) {
+	store := addTestDriver(t, "anon-1", "jonas@example.de")
+
+	if rec := deleteUser("anon-1", "anonymize"); rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	user, err := store.Get(context.Background(), "anon-1")
+	if err != nil {
+		t.Fatalf("anonymized user was removed: %v", err)
+	}
+	_, indexed := store.byEmail["jonas@example.de"]
+	if user.Name != anonymizedName || user.Email != "deleted-anon-1@anonymized.invalid" || user.Phone != "" || user.PScheinNumber != "" {
+		t.Errorf("personal data not scrubbed: %+v", user)
+	}
+	if user.ID != "anon-1" || user.UserType != Driver || user.AnonymizedAt == nil {
+		t.Errorf("ID, type or AnonymizedAt not kept: %+v", user)
+	}
+	if indexed {
+		t.Error("original email still indexed, it could not be registered again")
+	}
+
+	first := *user.AnonymizedAt
+	if rec := deleteUser("anon-1", "anonymize"); rec.Code != http.StatusNoContent {
+		t.Errorf("second anonymize: status = %d, want 204", rec.Code)
+	}
+	if again, _ := store.Get(context.Background(), "anon-1"); !again.AnonymizedAt.Equal(first) {
+		t.Errorf("second anonymize changed AnonymizedAt from %s to %s", first, again.AnonymizedAt)
+	}
+}
+
+func TestDeleteUserHard(t *testing.T) {
+	for _, mode := range []string{"hard", ""} {
+		store := addTestDriver(t, "hard-1", "hard@example.de")
+
+		if rec := deleteUser("hard-1", mode); rec.Code != http.StatusNoContent {
+			t.Fatalf("mode %q: status = %d", mode, rec.Code)
+		}
+		_, err := store.Get(context.Background(), "hard-1")
+		_, indexed := store.byEmail["hard@example.de"]
+		if !errors.Is(err, ErrUserNotFound) || indexed {
+			t.Errorf("mode %q: user or email index entry left behind", mode)
+		}
+	}
+
+	if rec := deleteUser("missing", "hard"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rec.Code)
+	}
+	if rec := deleteUser("missing", "soft"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid mode: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// setAvailability records a driver going online or offline. AvailabilitySince
+// only moves when the state actually changes.
+func setAvailability(user *User, available bool, now time.Time) {
+	if user.Available == available && user.AvailabilitySince != nil {
+		return
+	}
+	user.Available = available
+	user.AvailabilitySince = &now
+	user.UpdatedAt = now
+}
+
+// updateAvailabilityHandler serves PUT /users/{id}/availability with
+// {"available": true|false}. Only drivers with a verified P-Schein may go
+// online; going offline is always allowed.
+func updateAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var req struct {
+		Available *bool `json:"available"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Available == nil {
+		http.Error(w, "Invalid request payload, expected {\"available\": true|false}", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := userRepo.Update(r.Context(), id, func(user *User) error {
+		if user.UserType != Driver {
+			return rejectUpdate(http.StatusBadRequest, "User is not a driver")
+		}
+
+		if *req.Available && user.PScheinStatus != PScheinVerified {
+			return rejectUpdate(http.StatusConflict, "Driver cannot go online without a verified P-Schein (status: %s)", user.PScheinStatus)
+		}
+
+		setAvailability(user, *req.Available, timeutil.Now())
+		return nil
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Driver availability changed", "driver_id", id, "available", snapshot.Available)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func putAvailability(id, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/availability", updateAvailabilityHandler).Methods("PUT")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/users/"+id+"/availability", strings.NewReader(body)))
+	return rec
+}
+
+func TestUpdateAvailability(t *testing.T) {
+	useMemoryUsers(t,
+		User{ID: "avail-driver", UserType: Driver, PScheinStatus: PScheinVerified},
+		User{ID: "avail-pending", UserType: Driver, PScheinStatus: PScheinPending},
+		User{ID: "avail-rider", UserType: Rider},
+	)
+
+	rec := putAvailability("avail-driver", `{"available": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var user User
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatalf("failed to decode user: %v", err)
+	}
+	if !user.Available || user.AvailabilitySince == nil {
+		t.Errorf("driver not online: %+v", user)
+	}
+
+	if rec := putAvailability("avail-pending", `{"available": true}`); rec.Code != http.StatusConflict {
+		t.Errorf("unverified P-Schein: status = %d, want 409", rec.Code)
+	}
+	if rec := putAvailability("avail-pending", `{"available": false}`); rec.Code != http.StatusOK {
+		t.Errorf("going offline without verified P-Schein: status = %d, want 200", rec.Code)
+	}
+	if rec := putAvailability("avail-rider", `{"available": true}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("rider: status = %d, want 400", rec.Code)
+	}
+	if rec := putAvailability("avail-driver", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing available: status = %d, want 400", rec.Code)
+	}
+	if rec := putAvailability("missing", `{"available": true}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rec.Code)
+	}
+}
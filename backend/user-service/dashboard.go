@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// defaultDashboardSourceTimeout bounds each downstream call made for the
+// driver dashboard. A source that does not answer in time is reported as
+// unavailable instead of failing the whole dashboard.
+const defaultDashboardSourceTimeout = 2 * time.Second
+
+// Dashboard sources that can be unavailable
+const (
+	DashboardSourceAvailability = "availability"
+	DashboardSourceRides        = "rides"
+	DashboardSourceInsurance    = "insurance"
+)
+
+// DriverDashboard is a driver's complete status in one response. Partial is
+// set when a source could not be read; Unavailable names each such source
+// with the reason, and the corresponding section is omitted.
+type DriverDashboard struct {
+	DriverID     string              `json:"driver_id"`
+	GeneratedAt  time.Time           `json:"generated_at"`
+	Onboarding   OnboardingStatus    `json:"onboarding"`
+	PSchein      PScheinSummary      `json:"p_schein"`
+	Availability *DriverAvailability `json:"availability,omitempty"`
+	ActiveRide   *DashboardRide      `json:"active_ride,omitempty"`
+	Today        *DriverDaySummary   `json:"today,omitempty"`
+	Partial      bool                `json:"partial"`
+	Unavailable  map[string]string   `json:"unavailable,omitempty"`
+}
+
+// OnboardingStatus lists the onboarding steps the driver still has to finish
+type OnboardingStatus struct {
+	Complete     bool     `json:"complete"`
+	MissingSteps []string `json:"missing_steps"`
+}
+
+type PScheinSummary struct {
+	Status          PScheinStatus `json:"status,omitempty"`
+	ExpiresAt       *time.Time    `json:"expires_at,omitempty"`
+	DaysUntilExpiry *int          `json:"days_until_expiry,omitempty"`
+}
+
+// DriverAvailability is the driver's state in the matching-service
+type DriverAvailability struct {
+	Available bool      `json:"available"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type DashboardRide struct {
+	ID        string `json:"id"`
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+}
+
+// DriverDaySummary covers the current calendar day in Europe/Berlin
+type DriverDaySummary struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	RideCount   int       `json:"ride_count"`
+	EarningsEUR float64   `json:"earnings_eur"`
+}
+
+var (
+	dashboardClient        = &http.Client{}
+	dashboardSourceTimeout = defaultDashboardSourceTimeout
+)
+
+// loadDashboardSourceTimeout reads DASHBOARD_SOURCE_TIMEOUT, e.g. "1500ms"
+func loadDashboardSourceTimeout() (time.Duration, error) {
+	v := os.Getenv("DASHBOARD_SOURCE_TIMEOUT")
+	if v == "" {
+		return defaultDashboardSourceTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("DASHBOARD_SOURCE_TIMEOUT must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// onboardingStatus derives the missing onboarding steps from the user record
+func onboardingStatus(user *User, now time.Time) OnboardingStatus {
+	missing := []string{}
+	if user.Email == "" || user.Name == "" || user.Phone == "" {
+		missing = append(missing, "profile")
+	}
+	if user.PScheinNumber == "" {
+		missing = append(missing, "p_schein_submitted")
+	} else if user.PScheinStatus != PScheinVerified {
+		missing = append(missing, "p_schein_verified")
+	}
+	if user.PScheinExpiresAt != nil && now.After(*user.PScheinExpiresAt) {
+		missing = append(missing, "p_schein_valid")
+	}
+	return OnboardingStatus{Complete: len(missing) == 0, MissingSteps: missing}
+}
+
+func pScheinSummary(user *User, now time.Time) PScheinSummary {
+	summary := PScheinSummary{Status: user.PScheinStatus, ExpiresAt: user.PScheinExpiresAt}
+	if user.PScheinExpiresAt != nil {
+		days := int(user.PScheinExpiresAt.Sub(now).Hours() / 24)
+		summary.DaysUntilExpiry = &days
+	}
+	return summary
+}
+
+// getJSON fetches rawURL into v within the dashboard source timeout
+func getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, dashboardSourceTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := dashboardClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// unavailableReason is the client-facing reason for a failed source. Details
+// such as downstream URLs are only logged.
+func unavailableReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("no response within %s", dashboardSourceTimeout)
+	}
+	return "source unavailable"
+}
+
+// fetchAvailability reads the driver's state from the matching-service
+func fetchAvailability(ctx context.Context, driverID string) (*DriverAvailability, error) {
+	base := os.Getenv("MATCHING_SERVICE_URL")
+	if base == "" {
+		return nil, fmt.Errorf("MATCHING_SERVICE_URL is not configured")
+	}
+	var status struct {
+		Available bool      `json:"available"`
+		LastSeen  time.Time `json:"last_seen"`
+	}
+	if err := getJSON(ctx, base+"/api/v1/drivers/"+url.PathEscape(driverID), &status); err != nil {
+		return nil, err
+	}
+	return &DriverAvailability{Available: status.Available, LastSeen: status.LastSeen}, nil
+}
+
+// fetchRideSummary reads the active ride and today's completed rides from
+// the ride-service
+func fetchRideSummary(ctx context.Context, driverID string, now time.Time) (*DashboardRide, *DriverDaySummary, error) {
+	base := os.Getenv("RIDE_SERVICE_URL")
+	if base == "" {
+		return nil, nil, fmt.Errorf("RIDE_SERVICE_URL is not configured")
+	}
+
+	local := now.In(timeutil.Berlin())
+	from := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, timeutil.Berlin())
+	to := from.AddDate(0, 0, 1)
+	query := url.Values{"from": {from.Format(time.RFC3339)}, "to": {to.Format(time.RFC3339)}}
+
+	var summary struct {
+		ActiveRide     *DashboardRide `json:"active_ride"`
+		CompletedRides int            `json:"completed_rides"`
+		EarningsEUR    float64        `json:"earnings_eur"`
+	}
+	if err := getJSON(ctx, base+"/rides/driver/"+url.PathEscape(driverID)+"/summary?"+query.Encode(), &summary); err != nil {
+		return nil, nil, err
+	}
+	return summary.ActiveRide, &DriverDaySummary{
+		From:        from.UTC(),
+		To:          to.UTC(),
+		RideCount:   summary.CompletedRides,
+		EarningsEUR: summary.EarningsEUR,
+	}, nil
+}
+
+// driverDashboardHandler serves GET /users/{id}/dashboard to the driver
+// themselves or an admin. Profile and P-Schein data come from this service;
+// availability and rides are fetched concurrently from the matching- and
+// ride-service.
+func driverDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if r.Header.Get("X-User-ID") != id && r.Header.Get("X-User-Role") != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	now := timeutil.Now()
+
+	user, err := userRepo.Get(r.Context(), id)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	if user.UserType != Driver {
+		http.Error(w, "User is not a driver", http.StatusBadRequest)
+		return
+	}
+
+	dashboard := DriverDashboard{
+		DriverID:    user.ID,
+		GeneratedAt: now,
+		Onboarding:  onboardingStatus(&user, now),
+		PSchein:     pScheinSummary(&user, now),
+		Unavailable: map[string]string{
+			DashboardSourceInsurance: "insurance records are not stored by the platform yet",
+		},
+	}
+
+	var (
+		wg                 sync.WaitGroup
+		availability       *DriverAvailability
+		activeRide         *DashboardRide
+		today              *DriverDaySummary
+		availErr, ridesErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		availability, availErr = fetchAvailability(r.Context(), id)
+	}()
+	go func() {
+		defer wg.Done()
+		activeRide, today, ridesErr = fetchRideSummary(r.Context(), id, now)
+	}()
+	wg.Wait()
+
+	if availErr != nil {
+		requestLogger(r.Context()).Warn("Dashboard availability unavailable", "driver_id", id, "error", availErr)
+		dashboard.Unavailable[DashboardSourceAvailability] = unavailableReason(availErr)
+	}
+	if ridesErr != nil {
+		requestLogger(r.Context()).Warn("Dashboard rides unavailable", "driver_id", id, "error", ridesErr)
+		dashboard.Unavailable[DashboardSourceRides] = unavailableReason(ridesErr)
+	}
+	dashboard.Availability = availability
+	dashboard.ActiveRide = activeRide
+	dashboard.Today = today
+	dashboard.Partial = len(dashboard.Unavailable) > 0
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
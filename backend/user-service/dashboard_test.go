@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// dashboardSources starts stub matching- and ride-services and points the
+// dashboard at them
+func dashboardSources(t *testing.T, matching, rides http.HandlerFunc) {
+	t.Helper()
+	for env, handler := range map[string]http.HandlerFunc{"MATCHING_SERVICE_URL": matching, "RIDE_SERVICE_URL": rides} {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		t.Setenv(env, server.URL)
+	}
+}
+
+func getDashboard(id, actor, role string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/dashboard", driverDashboardHandler).Methods("GET")
+	req := httptest.NewRequest(http.MethodGet, "/users/"+id+"/dashboard", nil)
+	req.Header.Set("X-User-ID", actor)
+	req.Header.Set("X-User-Role", role)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDriverDashboard(t *testing.T) {
+	useMemoryUsers(t,
+		User{ID: "dash-driver", Email: "dora@example.de", Name: "Dora", Phone: "+4915123456789", UserType: Driver, PScheinNumber: "B-123456", PScheinStatus: PScheinVerified},
+		User{ID: "dash-rider", UserType: Rider},
+	)
+	lastSeen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	dashboardSources(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/drivers/dash-driver" {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"available": true, "last_seen": lastSeen})
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/rides/driver/dash-driver/summary" {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"active_ride":     map[string]string{"id": "ride-1", "reference": "R-0001", "status": "IN_PROGRESS"},
+				"completed_rides": 3,
+				"earnings_eur":    42.5,
+			})
+		},
+	)
+
+	rec := getDashboard("dash-driver", "dash-driver", "driver")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var dashboard DriverDashboard
+	if err := json.NewDecoder(rec.Body).Decode(&dashboard); err != nil {
+		t.Fatalf("failed to decode dashboard: %v", err)
+	}
+	if !dashboard.Onboarding.Complete {
+		t.Errorf("onboarding incomplete: %+v", dashboard.Onboarding)
+	}
+	if dashboard.Availability == nil || !dashboard.Availability.Available || !dashboard.Availability.LastSeen.Equal(lastSeen) {
+		t.Errorf("availability = %+v", dashboard.Availability)
+	}
+	if dashboard.ActiveRide == nil || dashboard.ActiveRide.ID != "ride-1" {
+		t.Errorf("active ride = %+v", dashboard.ActiveRide)
+	}
+	if dashboard.Today == nil || dashboard.Today.RideCount != 3 || dashboard.Today.EarningsEUR != 42.5 {
+		t.Errorf("today = %+v", dashboard.Today)
+	}
+	// Insurance records are not held anywhere yet, so the dashboard is
+	// always partial.
+	if !dashboard.Partial || len(dashboard.Unavailable) != 1 || dashboard.Unavailable[DashboardSourceInsurance] == "" {
+		t.Errorf("partial = %v, unavailable = %v", dashboard.Partial, dashboard.Unavailable)
+	}
+
+	if rec := getDashboard("dash-driver", "admin-1", "admin"); rec.Code != http.StatusOK {
+		t.Errorf("admin: status = %d, want 200", rec.Code)
+	}
+	if rec := getDashboard("dash-driver", "someone-else", "driver"); rec.Code != http.StatusForbidden {
+		t.Errorf("other user: status = %d, want 403", rec.Code)
+	}
+	if rec := getDashboard("dash-rider", "dash-rider", "rider"); rec.Code != http.StatusBadRequest {
+		t.Errorf("rider: status = %d, want 400", rec.Code)
+	}
+	if rec := getDashboard("missing", "missing", "driver"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDriverDashboardSourcesUnavailable(t *testing.T) {
+	useMemoryUsers(t, User{ID: "dash-partial", UserType: Driver})
+	defer func(d time.Duration) { dashboardSourceTimeout = d }(dashboardSourceTimeout)
+	dashboardSourceTimeout = 20 * time.Millisecond
+
+	release := make(chan struct{})
+	defer close(release)
+	dashboardSources(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		},
+	)
+
+	rec := getDashboard("dash-partial", "dash-partial", "driver")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var dashboard DriverDashboard
+	if err := json.NewDecoder(rec.Body).Decode(&dashboard); err != nil {
+		t.Fatalf("failed to decode dashboard: %v", err)
+	}
+	if dashboard.Availability != nil || dashboard.ActiveRide != nil || dashboard.Today != nil {
+		t.Errorf("sections from failed sources included: %+v", dashboard)
+	}
+	if got := dashboard.Unavailable[DashboardSourceAvailability]; got != "source unavailable" {
+		t.Errorf("availability reason = %q", got)
+	}
+	if got := dashboard.Unavailable[DashboardSourceRides]; got != "no response within 20ms" {
+		t.Errorf("rides reason = %q", got)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "127.0.0.1") {
+		t.Errorf("dashboard leaks downstream address: %s", body)
+	}
+}
+
+func TestFetchRideSummaryUsesBerlinDay(t *testing.T) {
+	var query map[string][]string
+	dashboardSources(t,
+		func(w http.ResponseWriter, r *http.Request) {},
+		func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			json.NewEncoder(w).Encode(map[string]interface{}{"completed_rides": 0})
+		},
+	)
+
+	// 23:30 UTC on 30 March 2024 is already 31 March in Berlin, the day the
+	// clocks go forward, so the day is 23 hours long.
+	now := time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)
+	_, today, err := fetchRideSummary(context.Background(), "driver-1", now)
+	if err != nil {
+		t.Fatalf("fetchRideSummary: %v", err)
+	}
+
+	want := map[string][]string{"from": {"2024-03-31T00:00:00+01:00"}, "to": {"2024-04-01T00:00:00+02:00"}}
+	if !reflect.DeepEqual(query, want) {
+		t.Errorf("query = %v, want %v", query, want)
+	}
+	if !today.From.Equal(time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC)) || today.To.Sub(today.From) != 23*time.Hour {
+		t.Errorf("today = %s .. %s", today.From, today.To)
+	}
+}
+
+func TestOnboardingStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	complete := User{Email: "e@example.de", Name: "E", Phone: "+4915123456789", PScheinNumber: "B-1", PScheinStatus: PScheinVerified}
+
+	tests := []struct {
+		name string
+		edit func(u *User)
+		want []string
+	}{
+		{"complete", func(u *User) {}, []string{}},
+		{"no phone", func(u *User) { u.Phone = "" }, []string{"profile"}},
+		{"no P-Schein", func(u *User) { u.PScheinNumber = "" }, []string{"p_schein_submitted"}},
+		{"pending P-Schein", func(u *User) { u.PScheinStatus = PScheinPending }, []string{"p_schein_verified"}},
+		{"expired P-Schein", func(u *User) { u.PScheinExpiresAt = &expired }, []string{"p_schein_valid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := complete
+			tt.edit(&user)
+			got := onboardingStatus(&user, now)
+			if !reflect.DeepEqual(got.MissingSteps, tt.want) || got.Complete != (len(tt.want) == 0) {
+				t.Errorf("onboardingStatus = %+v, want missing %v", got, tt.want)
+			}
+		})
+	}
+}
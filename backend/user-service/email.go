@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// maxEmailLength is the RFC 5321 limit on a forward path
+const maxEmailLength = 254
+
+// validEmail accepts a bare address such as "anna@example.de". Display names
+// ("Anna <anna@example.de>") and domains without a dot are rejected.
+func validEmail(email string) bool {
+	if len(email) > maxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// emailKey is the form an address is indexed under. Mail providers treat the
+// whole address case-insensitively in practice, so "Anna@Example.de" and
+// "anna@example.de" are the same user.
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+// emailTakenLocked reports whether another user already has email. Users
+// without an email never conflict. The caller must hold s.mu.
+func (s *UserStore) emailTakenLocked(email, userID string) bool {
+	if email == "" {
+		return false
+	}
+	id, ok := s.byEmail[emailKey(email)]
+	return ok && id != userID
+}
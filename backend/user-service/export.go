@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// userExportFormatVersion is bumped whenever the export document changes shape
+const userExportFormatVersion = 1
+
+// UserExport is the Art. 15 / Art. 20 GDPR export of everything the
+// user-service holds about a user. Ride history lives in the ride-service and
+// will be referenced here once it can be queried per user.
+type UserExport struct {
+	FormatVersion int              `json:"format_version"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	User          User             `json:"user"`
+	PIIAccessLog  []piiaudit.Event `json:"pii_access_log"`
+}
+
+// exportUserHandler serves GET /users/{id}/export as a downloadable JSON
+// file. Like the access log, only the subject themselves or an admin may
+// export it.
+func exportUserHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if r.Header.Get("X-User-ID") != id && r.Header.Get("X-User-Role") != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	snapshot, err := userRepo.Get(r.Context(), id)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	recordPIIAccess(r, PIIAccessRead, &snapshot)
+
+	export := UserExport{
+		FormatVersion: userExportFormatVersion,
+		ExportedAt:    timeutil.Now(),
+		User:          snapshot,
+		PIIAccessLog:  piiAccessLog.ForSubject(id),
+	}
+
+	requestLogger(r.Context()).Info("User data exported", "user_id", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, id))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(export)
+}
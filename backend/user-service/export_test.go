@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestExportUser(t *testing.T) {
+	useMemoryUsers(t, User{ID: "export-1", Email: "anna@example.de", Name: "Anna", Phone: "+4915123456789", UserType: Rider})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/export", exportUserHandler).Methods("GET")
+	request := func(path, actor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User-ID", actor)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("/users/export-1/export", "export-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="user-export-1-export.json"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	var export UserExport
+	if err := json.NewDecoder(rec.Body).Decode(&export); err != nil {
+		t.Fatalf("failed to decode export: %v", err)
+	}
+	if export.User.Email != "anna@example.de" || export.FormatVersion != userExportFormatVersion {
+		t.Errorf("unexpected export %+v", export)
+	}
+	if len(export.PIIAccessLog) == 0 || export.PIIAccessLog[len(export.PIIAccessLog)-1].Resource != "GET /users/export-1/export" {
+		t.Errorf("export access not in the included access log: %+v", export.PIIAccessLog)
+	}
+
+	if rec := request("/users/export-1/export", "someone-else"); rec.Code != http.StatusForbidden {
+		t.Errorf("other user: status = %d, want 403", rec.Code)
+	}
+	if rec := request("/users/missing/export", "missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rec.Code)
+	}
+}
@@ -5,4 +5,8 @@ go 1.21
 require (
 	github.com/google/uuid v1.4.0
 	github.com/gorilla/mux v1.8.1
+	github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg v0.0.0
+	github.com/lib/pq v1.10.9
 )
+
+replace github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg => ../pkg
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultUserListLimit = 20
+	maxUserListLimit     = 100
+)
+
+// UserListResponse is a page of users. Total counts all matches, not just
+// this page.
+type UserListResponse struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// listUsersHandler serves GET /users?type=&q=&limit=&offset= for admin
+// tooling. q matches name or email case-insensitively; results are ordered by
+// creation time. Every returned user is recorded in the PII access log.
+func listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	userType := UserType(query.Get("type"))
+	if userType != "" && userType != Rider && userType != Driver {
+		http.Error(w, "Invalid user type. Must be RIDER or DRIVER", http.StatusBadRequest)
+		return
+	}
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+
+	limit, ok := queryInt(query.Get("limit"), defaultUserListLimit)
+	if !ok || limit < 1 {
+		http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if limit > maxUserListLimit {
+		limit = maxUserListLimit
+	}
+	offset, ok := queryInt(query.Get("offset"), 0)
+	if !ok || offset < 0 {
+		http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := userRepo.List(r.Context())
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	matches := snapshot[:0]
+	for _, user := range snapshot {
+		if userType != "" && user.UserType != userType {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(user.Name), q) && !strings.Contains(strings.ToLower(user.Email), q) {
+			continue
+		}
+		matches = append(matches, user)
+	}
+
+	page := []User{}
+	if offset < len(matches) {
+		end := offset + limit
+		if end > len(matches) {
+			end = len(matches)
+		}
+		page = matches[offset:end]
+	}
+	for i := range page {
+		recordPIIAccess(r, PIIAccessRead, &page[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserListResponse{
+		Users:  page,
+		Total:  len(matches),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// queryInt parses an optional integer query parameter
+func queryInt(v string, def int) (int, bool) {
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil
+}
+
+// sortUsersByCreation orders users oldest first, breaking ties by ID so pages
+// are stable
+func sortUsersByCreation(users []User) {
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListUsers(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	useMemoryUsers(t,
+		User{ID: "list-3", Name: "Carla Weber", Email: "carla@example.de", UserType: Driver, CreatedAt: base.Add(3 * time.Hour)},
+		User{ID: "list-1", Name: "Anna Schmidt", Email: "anna@example.de", UserType: Rider, CreatedAt: base.Add(1 * time.Hour)},
+		User{ID: "list-2", Name: "Ben Müller", Email: "ben@schmidt-mail.de", UserType: Rider, CreatedAt: base.Add(2 * time.Hour)},
+	)
+
+	list := func(query, role string) (*httptest.ResponseRecorder, UserListResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/users"+query, nil)
+		req.Header.Set("X-User-Role", role)
+		rec := httptest.NewRecorder()
+		listUsersHandler(rec, req)
+		var resp UserListResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("%s: failed to decode response: %v", query, err)
+			}
+		}
+		return rec, resp
+	}
+	ids := func(resp UserListResponse) []string {
+		var out []string
+		for _, u := range resp.Users {
+			out = append(out, u.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+		total int
+	}{
+		{"", []string{"list-1", "list-2", "list-3"}, 3},
+		{"?type=RIDER", []string{"list-1", "list-2"}, 2},
+		{"?q=SCHMIDT", []string{"list-1", "list-2"}, 2},
+		{"?type=DRIVER&q=carla", []string{"list-3"}, 1},
+		{"?limit=1&offset=1", []string{"list-2"}, 3},
+		{"?offset=5", nil, 3},
+	}
+	for _, tt := range tests {
+		rec, resp := list(tt.query, "admin")
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d", tt.query, rec.Code)
+			continue
+		}
+		if got := ids(resp); len(got) != len(tt.want) || resp.Total != tt.total {
+			t.Errorf("%s: got %v (total %d), want %v (total %d)", tt.query, got, resp.Total, tt.want, tt.total)
+			continue
+		}
+		for i, id := range ids(resp) {
+			if id != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.query, ids(resp), tt.want)
+				break
+			}
+		}
+	}
+
+	if _, resp := list("?limit=500", "admin"); resp.Limit != maxUserListLimit {
+		t.Errorf("limit = %d, want it capped at %d", resp.Limit, maxUserListLimit)
+	}
+	for _, query := range []string{"?limit=0", "?offset=-1", "?limit=x", "?type=ADMIN"} {
+		if rec, _ := list(query, "admin"); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+	if rec, _ := list("", "rider"); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+}
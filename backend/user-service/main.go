@@ -3,16 +3,20 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/buildinfo"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/logging"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
 )
 
 type UserType string
@@ -42,23 +46,53 @@ type User struct {
 	PScheinIssuedAt *time.Time     `json:"p_schein_issued_at,omitempty"`
 	PScheinExpiresAt *time.Time    `json:"p_schein_expires_at,omitempty"`
 	PScheinVerifiedAt *time.Time   `json:"p_schein_verified_at,omitempty"`
+	AnonymizedAt    *time.Time     `json:"anonymized_at,omitempty"`
+	// Available is whether a driver is online and can be matched
+	Available       bool           `json:"available,omitempty"`
+	AvailabilitySince *time.Time   `json:"availability_since,omitempty"`
+	Vehicle         *Vehicle       `json:"vehicle,omitempty"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 }
 
-type UserStore struct {
-	mu    sync.RWMutex
-	users map[string]*User
-}
-
 var (
-	userStore *UserStore
-	logger    *log.Logger
+	userRepo              UserRepository
+	logger                *slog.Logger
+	piiAccessLog          *piiaudit.Log
+	pScheinExpiryInterval time.Duration
 )
 
+// requestLogger returns the logger for the request ctx belongs to, or the
+// service logger outside of a request
+func requestLogger(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, logger)
+}
+
 func init() {
-	userStore = &UserStore{users: make(map[string]*User)}
-	logger = log.New(os.Stdout, "[USER-SERVICE] ", log.LstdFlags|log.Lshortfile)
+	userRepo = NewUserStore()
+	logger = logging.New(os.Stdout, "user-service")
+	slog.SetDefault(logger)
+
+	accessLog, err := piiaudit.NewFromEnv()
+	if err != nil {
+		logger.Error("Invalid PII access log configuration", "error", err)
+		os.Exit(1)
+	}
+	piiAccessLog = accessLog
+
+	timeout, err := loadDashboardSourceTimeout()
+	if err != nil {
+		logger.Error("Invalid dashboard configuration", "error", err)
+		os.Exit(1)
+	}
+	dashboardSourceTimeout = timeout
+
+	interval, err := loadPScheinExpiryInterval()
+	if err != nil {
+		logger.Error("Invalid P-Schein expiry configuration", "error", err)
+		os.Exit(1)
+	}
+	pScheinExpiryInterval = interval
 }
 
 func main() {
@@ -67,15 +101,36 @@ func main() {
 		port = "8080"
 	}
 
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		repo, err := openPostgresUserRepository(dsn)
+		if err != nil {
+			logger.Error("Failed to open user database", "error", err)
+			os.Exit(1)
+		}
+		defer repo.Close()
+		userRepo = repo
+		logger.Info("Storing users in PostgreSQL")
+	} else {
+		logger.Warn("DATABASE_URL not set, users are kept in memory and lost on restart.")
+	}
+
 	router := mux.NewRouter()
+	router.Use(logging.Middleware(logger))
 	router.HandleFunc("/health", healthHandler).Methods("GET")
+	router.HandleFunc("/version", buildinfo.Handler("user-service")).Methods("GET")
+	router.HandleFunc("/users", listUsersHandler).Methods("GET")
 	router.HandleFunc("/users", createUserHandler).Methods("POST")
 	router.HandleFunc("/users/{id}", getUserHandler).Methods("GET")
+	router.HandleFunc("/users/{id}/pii-access-log", getPIIAccessLogHandler).Methods("GET")
+	router.HandleFunc("/users/{id}/export", exportUserHandler).Methods("GET")
+	router.HandleFunc("/users/{id}/dashboard", driverDashboardHandler).Methods("GET")
 	router.HandleFunc("/users/{id}/onboarding", updateOnboardingHandler).Methods("PUT")
 	router.HandleFunc("/users/{id}", updateUserHandler).Methods("PUT")
 	router.HandleFunc("/users/{id}", deleteUserHandler).Methods("DELETE")
 	router.HandleFunc("/users/{id}/p-schein", updatePScheinHandler).Methods("PUT")
 	router.HandleFunc("/users/{id}/p-schein/verify", verifyPScheinHandler).Methods("POST")
+	router.HandleFunc("/users/{id}/availability", updateAvailabilityHandler).Methods("PUT")
+	router.HandleFunc("/users/{id}/vehicle", updateVehicleHandler).Methods("PUT")
 
 	srv := &http.Server{
 		Addr:         ":" + port,
@@ -85,10 +140,15 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go runPScheinExpiryJob(jobCtx, userRepo, pScheinExpiryInterval)
+
 	go func() {
-		logger.Printf("Starting user-service on port %s", port)
+		logger.Info("Starting user-service", "port", port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("Server failed to start: %v", err)
+			logger.Error("Server failed to start", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -96,22 +156,24 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Println("Shutting down server...")
+	logger.Info("Shutting down server")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatalf("Server forced to shutdown: %v", err)
+		logger.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
-	logger.Println("Server exited")
+	logger.Info("Server exited")
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status": "healthy",
-		"time":   time.Now().Format(time.RFC3339),
+		"time":   timeutil.Format(timeutil.Now()),
 	})
 }
 
@@ -122,25 +184,39 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 		Phone           string    `json:"phone"`
 		UserType        UserType  `json:"user_type"`
 		PScheinNumber   string    `json:"p_schein_number,omitempty"`
+		Vehicle         *Vehicle  `json:"vehicle,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Printf("Error decoding request: %v", err)
+		requestLogger(r.Context()).Warn("Error decoding request", "error", err)
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
+	req.Email = strings.TrimSpace(req.Email)
 	if req.Email == "" || req.Name == "" || req.Phone == "" {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
 
+	if !validEmail(req.Email) {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	phone, err := normalizePhone(req.Phone)
+	if err != nil {
+		http.Error(w, "Invalid phone number: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Phone = phone
+
 	if req.UserType != Rider && req.UserType != Driver {
 		http.Error(w, "Invalid user type. Must be RIDER or DRIVER", http.StatusBadRequest)
 		return
 	}
 
-	now := time.Now()
+	now := timeutil.Now()
 	user := &User{
 		ID:        uuid.New().String(),
 		Email:     req.Email,
@@ -156,15 +232,36 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "P-Schein number required for drivers", http.StatusBadRequest)
 			return
 		}
-		user.PScheinNumber = req.PScheinNumber
+		number, err := normalizePScheinNumber(req.PScheinNumber)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		user.PScheinNumber = number
 		user.PScheinStatus = PScheinPending
 	}
 
-	userStore.mu.Lock()
-	userStore.users[user.ID] = user
-	userStore.mu.Unlock()
+	if req.Vehicle != nil {
+		if req.UserType != Driver {
+			http.Error(w, "Vehicle details are only accepted for drivers", http.StatusBadRequest)
+			return
+		}
+		if err := validateVehicle(req.Vehicle); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		user.Vehicle = req.Vehicle
+	}
+
+	if err := userRepo.Create(r.Context(), *user); err != nil {
+		writeUserError(w, err)
+		return
+	}
 
-	logger.Printf("User created: %s (%s) - %s", user.ID, user.UserType, user.Email)
+	requestLogger(r.Context()).Info("User created", "user_id", user.ID, "user_type", user.UserType, "email", user.Email)
+	if user.UserType == Driver {
+		auditPScheinNumberChange(r, user.ID, "", user.PScheinNumber)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -175,15 +272,14 @@ func getUserHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	userStore.mu.RLock()
-	user, exists := userStore.users[id]
-	userStore.mu.RUnlock()
-
-	if !exists {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, err := userRepo.Get(r.Context(), id)
+	if err != nil {
+		writeUserError(w, err)
 		return
 	}
 
+	recordPIIAccess(r, PIIAccessRead, &user)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
@@ -203,48 +299,91 @@ func updateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userStore.mu.Lock()
-	user, exists := userStore.users[id]
-	if !exists {
-		userStore.mu.Unlock()
-		http.Error(w, "User not found", http.StatusNotFound)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !validEmail(req.Email) {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
 		return
 	}
 
-	if req.Email != "" {
-		user.Email = req.Email
-	}
-	if req.Name != "" {
-		user.Name = req.Name
-	}
 	if req.Phone != "" {
-		user.Phone = req.Phone
+		phone, err := normalizePhone(req.Phone)
+		if err != nil {
+			http.Error(w, "Invalid phone number: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Phone = phone
 	}
-	user.UpdatedAt = time.Now()
-	userStore.mu.Unlock()
 
-	logger.Printf("User updated: %s", user.ID)
+	user, err := userRepo.Update(r.Context(), id, func(user *User) error {
+		if req.Email != "" {
+			user.Email = req.Email
+		}
+		if req.Name != "" {
+			user.Name = req.Name
+		}
+		if req.Phone != "" {
+			user.Phone = req.Phone
+		}
+		user.UpdatedAt = timeutil.Now()
+		return nil
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("User updated", "user_id", user.ID)
+	recordPIIAccess(r, PIIAccessUpdate, &user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
 
+// deleteUserHandler erases a user. mode=hard (the default) removes the
+// record; mode=anonymize keeps it for referential history but scrubs the
+// personal data (Art. 17 GDPR).
 func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	userStore.mu.Lock()
-	_, exists := userStore.users[id]
-	if !exists {
-		userStore.mu.Unlock()
-		http.Error(w, "User not found", http.StatusNotFound)
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "hard" && mode != "anonymize" {
+		http.Error(w, "Invalid mode. Must be hard or anonymize", http.StatusBadRequest)
+		return
+	}
+
+	if mode == "anonymize" {
+		alreadyAnonymized := false
+		user, err := userRepo.Update(r.Context(), id, func(user *User) error {
+			if user.AnonymizedAt != nil {
+				alreadyAnonymized = true
+				return nil
+			}
+			anonymizeUser(user, timeutil.Now())
+			return nil
+		})
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		if !alreadyAnonymized {
+			requestLogger(r.Context()).Info("User anonymized", "user_id", id)
+			recordPIIAccess(r, PIIAccessDelete, &user)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	delete(userStore.users, id)
-	userStore.mu.Unlock()
+	user, err := userRepo.Delete(r.Context(), id)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
 
-	logger.Printf("User deleted: %s", id)
+	requestLogger(r.Context()).Info("User deleted", "user_id", id)
+	recordPIIAccess(r, PIIAccessDelete, &user)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -264,41 +403,57 @@ func updatePScheinHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userStore.mu.Lock()
-	user, exists := userStore.users[id]
-	if !exists {
-		userStore.mu.Unlock()
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+	if req.PScheinNumber != "" {
+		number, err := normalizePScheinNumber(req.PScheinNumber)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.PScheinNumber = number
 	}
 
-	if user.UserType != Driver {
-		userStore.mu.Unlock()
-		http.Error(w, "User is not a driver", http.StatusBadRequest)
-		return
-	}
+	var oldNumber string
+	user, err := userRepo.Update(r.Context(), id, func(user *User) error {
+		if user.UserType != Driver {
+			return rejectUpdate(http.StatusBadRequest, "User is not a driver")
+		}
 
-	if req.PScheinNumber != "" {
-		user.PScheinNumber = req.PScheinNumber
-		user.PScheinStatus = PScheinPending
-		user.PScheinVerifiedAt = nil
-	}
+		oldNumber = user.PScheinNumber
+		if req.PScheinNumber != "" {
+			user.PScheinNumber = req.PScheinNumber
+			user.PScheinStatus = PScheinPending
+			user.PScheinVerifiedAt = nil
+		}
 
-	if req.PScheinIssuedAt != nil {
-		user.PScheinIssuedAt = req.PScheinIssuedAt
-	}
+		if req.PScheinIssuedAt != nil {
+			user.PScheinIssuedAt = req.PScheinIssuedAt
+		}
 
-	if req.PScheinExpiresAt != nil {
-		user.PScheinExpiresAt = req.PScheinExpiresAt
-		if time.Now().After(*req.PScheinExpiresAt) {
-			user.PScheinStatus = PScheinExpired
+		if req.PScheinExpiresAt != nil {
+			user.PScheinExpiresAt = req.PScheinExpiresAt
+			if timeutil.Now().After(*req.PScheinExpiresAt) {
+				user.PScheinStatus = PScheinExpired
+			}
 		}
-	}
 
-	user.UpdatedAt = time.Now()
-	userStore.mu.Unlock()
+		// A driver whose P-Schein is no longer verified may not stay online
+		if user.PScheinStatus != PScheinVerified && user.Available {
+			setAvailability(user, false, timeutil.Now())
+		}
+
+		user.UpdatedAt = timeutil.Now()
+		return nil
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
 
-	logger.Printf("P-Schein updated for user: %s", user.ID)
+	requestLogger(r.Context()).Info("P-Schein updated", "user_id", user.ID)
+	if req.PScheinNumber != "" {
+		auditPScheinNumberChange(r, user.ID, oldNumber, req.PScheinNumber)
+	}
+	recordPIIAccess(r, PIIAccessUpdate, &user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
@@ -318,42 +473,41 @@ func verifyPScheinHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userStore.mu.Lock()
-	user, exists := userStore.users[id]
-	if !exists {
-		userStore.mu.Unlock()
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
+	user, err := userRepo.Update(r.Context(), id, func(user *User) error {
+		if user.UserType != Driver {
+			return rejectUpdate(http.StatusBadRequest, "User is not a driver")
+		}
 
-	if user.UserType != Driver {
-		userStore.mu.Unlock()
-		http.Error(w, "User is not a driver", http.StatusBadRequest)
-		return
-	}
+		if user.PScheinStatus != PScheinPending {
+			return rejectUpdate(http.StatusBadRequest, "P-Schein is not in pending status")
+		}
 
-	if user.PScheinStatus != PScheinPending {
-		userStore.mu.Unlock()
-		http.Error(w, "P-Schein is not in pending status", http.StatusBadRequest)
+		now := timeutil.Now()
+		if req.Verified {
+			user.PScheinStatus = PScheinVerified
+			user.PScheinVerifiedAt = &now
+		} else {
+			user.PScheinStatus = PScheinRejected
+		}
+		user.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		writeUserError(w, err)
 		return
 	}
 
-	now := time.Now()
 	if req.Verified {
-		user.PScheinStatus = PScheinVerified
-		user.PScheinVerifiedAt = &now
-		logger.Printf("P-Schein verified for user: %s", user.ID)
+		requestLogger(r.Context()).Info("P-Schein verified", "user_id", user.ID)
 	} else {
-		user.PScheinStatus = PScheinRejected
-		logger.Printf("P-Schein rejected for user: %s, reason: %s", user.ID, req.Reason)
+		requestLogger(r.Context()).Info("P-Schein rejected", "user_id", user.ID, "reason", req.Reason)
 	}
-
-	user.UpdatedAt = now
-	userStore.mu.Unlock()
+	recordPIIAccess(r, PIIAccessUpdate, &user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
+
 func updateOnboardingHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -367,21 +521,32 @@ func updateOnboardingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userStore.mu.Lock()
-	user, ok := userStore.users[id]
-	if !ok {
-		userStore.mu.Unlock()
-		http.Error(w, "User not found", http.StatusNotFound)
+	number, err := normalizePScheinNumber(input.PScheinNumber)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	user.PScheinNumber = input.PScheinNumber
-	user.PScheinStatus = PScheinPending
-	now := time.Now()
-	user.UpdatedAt = now
-	userStore.mu.Unlock()
+	var oldNumber string
+	user, err := userRepo.Update(r.Context(), id, func(user *User) error {
+		oldNumber = user.PScheinNumber
+		user.PScheinNumber = number
+		user.PScheinStatus = PScheinPending
+		now := timeutil.Now()
+		if user.Available {
+			setAvailability(user, false, now)
+		}
+		user.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
 
-	logger.Printf("User %s submitted P-Schein %s for verification", id, input.PScheinNumber)
+	requestLogger(r.Context()).Info("P-Schein submitted for verification", "user_id", id, "p_schein_number", maskPScheinNumber(number))
+	auditPScheinNumberChange(r, id, oldNumber, number)
+	recordPIIAccess(r, PIIAccessUpdate, &user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
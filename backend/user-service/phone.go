@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// German national significant numbers (area code + subscriber number, without
+// the trunk prefix 0) are at most 13 digits, so the E.164 form "+49" + NSN
+// stays within the 15-digit limit. Shorter than 6 digits is not a dialable
+// number.
+const (
+	minGermanNSNDigits = 6
+	maxGermanNSNDigits = 13
+)
+
+var errInvalidPhone = errors.New("phone must be a German number: +49..., 0049... or 0...")
+
+// normalizePhone converts a German phone number to E.164, e.g.
+// "0151 2345-6789", "+49 (0)151 23456789" and "0049 151 23456789" all become
+// "+4915123456789". Spaces, dashes, slashes, dots and parentheses are ignored.
+func normalizePhone(raw string) (string, error) {
+	number := strings.TrimSpace(raw)
+	// "+49 (0)30 ..." marks the trunk prefix that is dropped when dialling
+	// from abroad
+	number = strings.Replace(number, "(0)", "", 1)
+	number = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '/', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, number)
+
+	var nsn string
+	switch {
+	case strings.HasPrefix(number, "+49"):
+		nsn = number[len("+49"):]
+	case strings.HasPrefix(number, "0049"):
+		nsn = number[len("0049"):]
+	case strings.HasPrefix(number, "0") && !strings.HasPrefix(number, "00"):
+		nsn = number[1:]
+	default:
+		return "", errInvalidPhone
+	}
+
+	if len(nsn) < minGermanNSNDigits || len(nsn) > maxGermanNSNDigits || nsn[0] == '0' {
+		return "", errInvalidPhone
+	}
+	for _, c := range nsn {
+		if c < '0' || c > '9' {
+			return "", errInvalidPhone
+		}
+	}
+	return "+49" + nsn, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"e164", "+4915123456789", "+4915123456789"},
+		{"international with spaces", "+49 151 23456789", "+4915123456789"},
+		{"international with dashes", "+49-151-2345-6789", "+4915123456789"},
+		{"international with trunk zero", "+49 (0)30 1234567", "+49301234567"},
+		{"0049 prefix", "0049 151 23456789", "+4915123456789"},
+		{"national mobile", "0151 23456789", "+4915123456789"},
+		{"national with slash", "030/1234567", "+49301234567"},
+		{"national with dashes and dots", "089-123.456", "+4989123456"},
+		{"surrounding whitespace", "  01512345678  ", "+491512345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizePhone(tt.input)
+			if err != nil {
+				t.Fatalf("normalizePhone(%q) failed: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizePhone(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneRejectsInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"15123456789",      // no prefix
+		"+43 660 1234567",  // Austrian
+		"0043 660 1234567", // Austrian, international prefix
+		"+49 0151 2345678", // trunk zero after the country code
+		"0151 2345678x",
+		"030 12",
+		"+49 151 2345678901234",
+	} {
+		if got, err := normalizePhone(input); err == nil {
+			t.Errorf("normalizePhone(%q) = %q, want an error", input, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+)
+
+// PII access actions
+const (
+	PIIAccessRead   = piiaudit.Read
+	PIIAccessUpdate = piiaudit.Update
+	PIIAccessDelete = piiaudit.Delete
+)
+
+// piiFields lists the names of the personal data fields disclosed when user
+// is returned in a response
+func piiFields(user *User) []string {
+	fields := []string{"email", "name", "phone"}
+	if user.PScheinNumber != "" {
+		fields = append(fields, "p_schein_number")
+	}
+	if user.Vehicle != nil {
+		fields = append(fields, "vehicle.plate")
+	}
+	return fields
+}
+
+// recordPIIAccess logs an access to subject's personal data by the actor the
+// gateway injected in X-User-ID and X-User-Role
+func recordPIIAccess(r *http.Request, action string, subject *User) {
+	var fields []string
+	if action != PIIAccessDelete {
+		fields = piiFields(subject)
+	}
+	if err := piiAccessLog.Record(piiaudit.NewEvent(r, action, subject.ID, fields...)); err != nil {
+		logger.Error("Failed to write PII access event", "category", "audit.pii", "subject_id", subject.ID, "error", err)
+	}
+}
+
+// getPIIAccessLogHandler returns the access log for a data subject. Only the
+// subject themselves or an admin may read it.
+func getPIIAccessLogHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if r.Header.Get("X-User-ID") != id && r.Header.Get("X-User-Role") != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(piiAccessLog.ForSubject(id))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+)
+
+// useMemoryPIIAccessLog swaps piiAccessLog for a log writing to the returned
+// buffer for the duration of the test
+func useMemoryPIIAccessLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	previous := piiAccessLog
+	var out bytes.Buffer
+	piiAccessLog = piiaudit.New(&out)
+	t.Cleanup(func() { piiAccessLog = previous })
+	return &out
+}
+
+func piiAccessRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", getUserHandler).Methods("GET")
+	router.HandleFunc("/users/{id}", deleteUserHandler).Methods("DELETE")
+	router.HandleFunc("/users/{id}/pii-access-log", getPIIAccessLogHandler).Methods("GET")
+	return router
+}
+
+func servePIIRequest(router *mux.Router, method, path, actor, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if actor != "" {
+		req.Header.Set("X-User-ID", actor)
+	}
+	if role != "" {
+		req.Header.Set("X-User-Role", role)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetUserRecordsPIIRead(t *testing.T) {
+	useMemoryUsers(t, User{ID: "pii-1", Email: "anna@example.de", Name: "Anna", Phone: "+4915123456789", UserType: Driver, PScheinNumber: "B-123456"})
+	out := useMemoryPIIAccessLog(t)
+
+	if rec := servePIIRequest(piiAccessRouter(), "GET", "/users/pii-1", "admin-1", "admin"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	events := piiAccessLog.ForSubject("pii-1")
+	if len(events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.ActorID != "admin-1" || event.ActorRole != "admin" || event.Action != PIIAccessRead || event.Resource != "GET /users/pii-1" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if got := strings.Join(event.Fields, ","); got != "email,name,phone,p_schein_number" {
+		t.Errorf("fields = %q", got)
+	}
+
+	// The sink holds field names only, never the values disclosed.
+	for _, value := range []string{"anna@example.de", "Anna", "+4915123456789", "B-123456"} {
+		if strings.Contains(out.String(), value) {
+			t.Errorf("access log output contains PII value %q: %s", value, out.String())
+		}
+	}
+}
+
+func TestGetUnknownUserRecordsNothing(t *testing.T) {
+	useMemoryUsers(t)
+	useMemoryPIIAccessLog(t)
+
+	if rec := servePIIRequest(piiAccessRouter(), "GET", "/users/missing", "admin-1", "admin"); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if events := piiAccessLog.ForSubject("missing"); len(events) != 0 {
+		t.Errorf("recorded %+v for a user that does not exist", events)
+	}
+}
+
+func TestDeleteUserRecordsPIIDeleteWithoutFields(t *testing.T) {
+	useMemoryUsers(t, User{ID: "pii-2", Email: "ben@example.de", Name: "Ben", UserType: Rider})
+	useMemoryPIIAccessLog(t)
+
+	if rec := servePIIRequest(piiAccessRouter(), "DELETE", "/users/pii-2", "", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	events := piiAccessLog.ForSubject("pii-2")
+	if len(events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events))
+	}
+	if events[0].Action != PIIAccessDelete || events[0].ActorID != piiaudit.UnknownActor || len(events[0].Fields) != 0 {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
+
+func TestGetPIIAccessLog(t *testing.T) {
+	useMemoryUsers(t, User{ID: "pii-3", Email: "cora@example.de", Name: "Cora", UserType: Rider})
+	useMemoryPIIAccessLog(t)
+	router := piiAccessRouter()
+
+	servePIIRequest(router, "GET", "/users/pii-3", "support-1", "admin")
+
+	tests := []struct {
+		name, actor, role string
+		want              int
+	}{
+		{"subject", "pii-3", "rider", http.StatusOK},
+		{"admin", "admin-1", "admin", http.StatusOK},
+		{"other user", "pii-4", "rider", http.StatusForbidden},
+		{"anonymous", "", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := servePIIRequest(router, "GET", "/users/pii-3/pii-access-log", tt.actor, tt.role)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var events []piiaudit.Event
+			if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+				t.Fatalf("failed to decode access log: %v", err)
+			}
+			if len(events) == 0 || events[0].ActorID != "support-1" {
+				t.Errorf("access log = %+v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//go:embed schema.sql
+var userSchema string
+
+// PostgresUserRepository is the UserRepository backed by PostgreSQL, see
+// schema.sql. Updates run in a transaction that locks the user row, so a
+// crash mid-update leaves the user as it was before.
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+// openPostgresUserRepository connects to dsn and applies schema.sql
+func openPostgresUserRepository(dsn string) (*PostgresUserRepository, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, userSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying schema: %w", err)
+	}
+	return NewPostgresUserRepository(db), nil
+}
+
+func (p *PostgresUserRepository) Close() error {
+	return p.db.Close()
+}
+
+func (p *PostgresUserRepository) Create(ctx context.Context, user User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO users (id, email_key, user_type, created_at, data)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)`,
+		user.ID, emailKey(user.Email), user.UserType, user.CreatedAt, string(data))
+	return userWriteError(err)
+}
+
+func (p *PostgresUserRepository) Get(ctx context.Context, id string) (User, error) {
+	return scanUser(p.db.QueryRowContext(ctx, `SELECT data FROM users WHERE id = $1`, id))
+}
+
+func (p *PostgresUserRepository) List(ctx context.Context) ([]User, error) {
+	return queryUsers(ctx, p.db, `SELECT data FROM users ORDER BY created_at, id`)
+}
+
+func (p *PostgresUserRepository) Update(ctx context.Context, id string, fn func(user *User) error) (User, error) {
+	var updated User
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		user, err := scanUser(tx.QueryRowContext(ctx, `SELECT data FROM users WHERE id = $1 FOR UPDATE`, id))
+		if err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+		if err := updateUser(ctx, tx, user); err != nil {
+			return err
+		}
+		updated = user
+		return nil
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return updated, nil
+}
+
+func (p *PostgresUserRepository) Delete(ctx context.Context, id string) (User, error) {
+	return scanUser(p.db.QueryRowContext(ctx, `DELETE FROM users WHERE id = $1 RETURNING data`, id))
+}
+
+func (p *PostgresUserRepository) ExpirePScheins(ctx context.Context, now time.Time) ([]User, error) {
+	var expired []User
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		candidates, err := queryUsers(ctx, tx, `
+			SELECT data FROM users
+			WHERE user_type = $1 AND data->>'p_schein_status' = $2 AND data ? 'p_schein_expires_at'
+			FOR UPDATE`,
+			Driver, PScheinVerified)
+		if err != nil {
+			return err
+		}
+		for i := range candidates {
+			if !expirePSchein(&candidates[i], now) {
+				continue
+			}
+			if err := updateUser(ctx, tx, candidates[i]); err != nil {
+				return err
+			}
+			expired = append(expired, candidates[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// inTx runs fn in a transaction that is committed if fn returns nil and
+// rolled back otherwise
+func (p *PostgresUserRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateUser writes the user and the columns copied from it
+func updateUser(ctx context.Context, tx *sql.Tx, user User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email_key = NULLIF($2, ''), data = $3 WHERE id = $1`,
+		user.ID, emailKey(user.Email), string(data))
+	return userWriteError(err)
+}
+
+// usersEmailKeyConstraint is the unique constraint on users.email_key
+const usersEmailKeyConstraint = "users_email_key_key"
+
+// userWriteError turns a violated email uniqueness into ErrEmailTaken
+func userWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == usersEmailKeyConstraint {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+type userQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryUsers(ctx context.Context, q userQuerier, query string, args ...interface{}) ([]User, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (User, error) {
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrUserNotFound
+		}
+		return User{}, err
+	}
+	var user User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return User{}, fmt.Errorf("decoding stored user: %w", err)
+	}
+	return user, nil
+}
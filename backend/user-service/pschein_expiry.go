@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+const defaultPScheinExpiryInterval = time.Hour
+
+// loadPScheinExpiryInterval reads PSCHEIN_EXPIRY_SCAN_INTERVAL, e.g. "15m"
+func loadPScheinExpiryInterval() (time.Duration, error) {
+	v := os.Getenv("PSCHEIN_EXPIRY_SCAN_INTERVAL")
+	if v == "" {
+		return defaultPScheinExpiryInterval, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("PSCHEIN_EXPIRY_SCAN_INTERVAL must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// runPScheinExpiryJob expires P-Scheine in repo every interval until ctx is
+// cancelled, see UserRepository.ExpirePScheins
+func runPScheinExpiryJob(ctx context.Context, repo UserRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := expirePScheins(ctx, repo, timeutil.Now()); n > 0 {
+				logger.Info("P-Schein expiry scan", "expired", n)
+			}
+		}
+	}
+}
+
+// expirePScheins runs one expiry scan and returns how many P-Scheine expired
+func expirePScheins(ctx context.Context, repo UserRepository, now time.Time) int {
+	expired, err := repo.ExpirePScheins(ctx, now)
+	if err != nil {
+		logger.Error("P-Schein expiry scan failed", "error", err)
+		return 0
+	}
+	for _, user := range expired {
+		logger.Info("P-Schein expired", "user_id", user.ID, "expired_at", timeutil.Format(*user.PScheinExpiresAt))
+		if user.AvailabilitySince != nil && user.AvailabilitySince.Equal(now) {
+			logger.Info("Driver taken offline: P-Schein expired", "driver_id", user.ID)
+		}
+	}
+	return len(expired)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExpirePScheins(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+
+	store := NewUserStore()
+	for _, user := range []User{
+		{ID: "expired", UserType: Driver, PScheinStatus: PScheinVerified, PScheinExpiresAt: &past},
+		{ID: "valid", UserType: Driver, PScheinStatus: PScheinVerified, PScheinExpiresAt: &future},
+		{ID: "pending", UserType: Driver, PScheinStatus: PScheinPending, PScheinExpiresAt: &past},
+		{ID: "no-dates", UserType: Driver, PScheinStatus: PScheinVerified},
+	} {
+		mustCreateUser(t, store, user)
+	}
+
+	if n := expirePScheins(context.Background(), store, now); n != 1 {
+		t.Errorf("expired %d P-Scheine, want 1", n)
+	}
+
+	want := map[string]PScheinStatus{
+		"expired":  PScheinExpired,
+		"valid":    PScheinVerified,
+		"pending":  PScheinPending,
+		"no-dates": PScheinVerified,
+	}
+	for id, status := range want {
+		if got := mustGetUser(t, store, id).PScheinStatus; got != status {
+			t.Errorf("%s: status = %s, want %s", id, got, status)
+		}
+	}
+	if updated := mustGetUser(t, store, "expired").UpdatedAt; !updated.Equal(now) {
+		t.Errorf("expired user UpdatedAt = %s, want %s", updated, now)
+	}
+
+	if n := expirePScheins(context.Background(), store, now); n != 0 {
+		t.Errorf("second scan expired %d, want 0", n)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/piiaudit"
+)
+
+// There is no nationwide format for P-Schein numbers (Fahrerlaubnis zur
+// Fahrgastbeförderung, §48 FeV); each Fahrerlaubnisbehörde assigns its own,
+// e.g. "B-123456", "M 2024/00815" or the holder's 11-character
+// Führerscheinnummer "J0103456781". We accept what these have in common:
+// 5 to 20 characters of letters and digits, groups separated by single
+// dashes, slashes or spaces, at least one digit. Numbers are stored
+// upper-cased.
+var pScheinNumberPattern = regexp.MustCompile(`^[A-Z0-9]+([-/ ][A-Z0-9]+)*$`)
+
+const (
+	minPScheinNumberLength = 5
+	maxPScheinNumberLength = 20
+)
+
+var errInvalidPScheinNumber = errors.New("P-Schein number must be 5-20 letters and digits, optionally grouped by '-', '/' or spaces")
+
+// normalizePScheinNumber validates a P-Schein number and returns it trimmed
+// and upper-cased
+func normalizePScheinNumber(raw string) (string, error) {
+	number := strings.ToUpper(strings.TrimSpace(raw))
+	if len(number) < minPScheinNumberLength || len(number) > maxPScheinNumberLength {
+		return "", errInvalidPScheinNumber
+	}
+	if !pScheinNumberPattern.MatchString(number) || !strings.ContainsAny(number, "0123456789") {
+		return "", errInvalidPScheinNumber
+	}
+	return number, nil
+}
+
+// maskPScheinNumber hides all but the last four characters. Numbers of four
+// characters or fewer are masked completely.
+func maskPScheinNumber(number string) string {
+	if number == "" {
+		return "(none)"
+	}
+	if len(number) <= 4 {
+		return strings.Repeat("*", len(number))
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+// auditPScheinNumberChange writes the compliance trail entry for a P-Schein
+// number change. Only masked values are logged.
+func auditPScheinNumberChange(r *http.Request, userID, oldNumber, newNumber string) {
+	if oldNumber == newNumber {
+		return
+	}
+	actor := r.Header.Get("X-User-ID")
+	if actor == "" {
+		actor = piiaudit.UnknownActor
+	}
+	requestLogger(r.Context()).Info("P-Schein number changed", "category", "audit.p_schein", "user_id", userID, "actor", actor, "old_number", maskPScheinNumber(oldNumber), "new_number", maskPScheinNumber(newNumber))
+}
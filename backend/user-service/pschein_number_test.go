@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestNormalizePScheinNumber(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"B-123456", "B-123456"},
+		{"m 2024/00815", "M 2024/00815"},
+		{" J0103456781 ", "J0103456781"},
+		{"12345", "12345"},
+	}
+	for _, tt := range tests {
+		got, err := normalizePScheinNumber(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("normalizePScheinNumber(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{
+		"",
+		"1234",                  // too short
+		"ABCDEFGH",              // no digit
+		"B--123456",             // doubled separator
+		"-B123456",              // leading separator
+		"B_123456",              // unsupported separator
+		"PS-123456789012345678", // too long
+		"B-12345ä",
+	} {
+		if got, err := normalizePScheinNumber(input); err == nil {
+			t.Errorf("normalizePScheinNumber(%q) = %q, want an error", input, got)
+		}
+	}
+}
+
+func TestMaskPScheinNumber(t *testing.T) {
+	tests := map[string]string{
+		"B-123456": "****3456",
+		"12345":    "*2345",
+		"1234":     "****",
+		"":         "(none)",
+	}
+	for input, want := range tests {
+		if got := maskPScheinNumber(input); got != want {
+			t.Errorf("maskPScheinNumber(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUserNotFound is returned by UserRepository methods for unknown user IDs
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrEmailTaken is returned when a user would get an email address another
+// user already has
+var ErrEmailTaken = errors.New("email already taken")
+
+// UserRepository stores users. Every method returns snapshots; a caller never
+// holds a user that another request can change underneath it.
+//
+// UserStore keeps users in memory and PostgresUserRepository in PostgreSQL.
+// repository_test.go runs the same contract tests against both.
+type UserRepository interface {
+	// Create stores a new user. Emails are unique by emailKey; a second
+	// user with the same address fails with ErrEmailTaken.
+	Create(ctx context.Context, user User) error
+	Get(ctx context.Context, id string) (User, error)
+	// List returns all users, oldest first
+	List(ctx context.Context) ([]User, error)
+	// Update reads the user, passes a copy to fn and stores the copy if fn
+	// returns nil. The read and the write are one atomic step: concurrent
+	// updates of the same user are serialised, and if fn fails, the new
+	// email is taken or the write does not complete, the stored user is left
+	// unchanged.
+	Update(ctx context.Context, id string, fn func(user *User) error) (User, error)
+	// Delete removes the user and returns it as it was stored
+	Delete(ctx context.Context, id string) (User, error)
+	// ExpirePScheins marks every verified P-Schein that expired before now
+	// as EXPIRED, takes those drivers offline and returns the changed users
+	ExpirePScheins(ctx context.Context, now time.Time) ([]User, error)
+}
+
+// userUpdateError rejects a user update with the HTTP status and message the
+// handler should answer with
+type userUpdateError struct {
+	status  int
+	message string
+}
+
+func (e *userUpdateError) Error() string {
+	return e.message
+}
+
+// rejectUpdate is returned from a UserRepository.Update callback to abort the
+// update; writeUserError turns it into the response
+func rejectUpdate(status int, format string, args ...interface{}) error {
+	return &userUpdateError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// writeUserError answers a request whose repository call failed
+func writeUserError(w http.ResponseWriter, err error) {
+	var rejected *userUpdateError
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, ErrEmailTaken):
+		http.Error(w, "A user with this email already exists", http.StatusConflict)
+	case errors.As(err, &rejected):
+		http.Error(w, rejected.message, rejected.status)
+	default:
+		logger.Error("User repository error", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// expirePSchein applies ExpirePScheins to one user and reports whether it
+// changed. Without a valid P-Schein a driver may not carry passengers
+// (§48 FeV).
+func expirePSchein(user *User, now time.Time) bool {
+	if user.UserType != Driver || user.PScheinStatus != PScheinVerified || user.PScheinExpiresAt == nil {
+		return false
+	}
+	if !now.After(*user.PScheinExpiresAt) {
+		return false
+	}
+	user.PScheinStatus = PScheinExpired
+	user.UpdatedAt = now
+	if user.Available {
+		setAvailability(user, false, now)
+	}
+	return true
+}
+
+// UserStore is the in-memory UserRepository. Users are lost on restart; set
+// DATABASE_URL to keep them in PostgreSQL instead.
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[string]*User
+	// byEmail maps emailKey(email) to the user ID, keeping emails unique
+	byEmail map[string]string
+}
+
+// NewUserStore returns an empty in-memory repository
+func NewUserStore() *UserStore {
+	return &UserStore{users: make(map[string]*User), byEmail: make(map[string]string)}
+}
+
+func (s *UserStore) Create(ctx context.Context, user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTakenLocked(user.Email, user.ID) {
+		return ErrEmailTaken
+	}
+	stored := user
+	s.users[user.ID] = &stored
+	s.indexEmailLocked("", user)
+	return nil
+}
+
+// Get returns a snapshot of the user. Handlers encode snapshots taken under
+// the lock, never the stored pointer, so a concurrent update cannot change a
+// user while it is being written out.
+func (s *UserStore) Get(ctx context.Context, id string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, exists := s.users[id]
+	if !exists {
+		return User{}, ErrUserNotFound
+	}
+	return *user, nil
+}
+
+func (s *UserStore) List(ctx context.Context) ([]User, error) {
+	s.mu.RLock()
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, *user)
+	}
+	s.mu.RUnlock()
+
+	sortUsersByCreation(users)
+	return users, nil
+}
+
+func (s *UserStore) Update(ctx context.Context, id string, fn func(user *User) error) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.users[id]
+	if !exists {
+		return User{}, ErrUserNotFound
+	}
+	updated := *stored
+	if err := fn(&updated); err != nil {
+		return User{}, err
+	}
+	if emailKey(updated.Email) != emailKey(stored.Email) && s.emailTakenLocked(updated.Email, id) {
+		return User{}, ErrEmailTaken
+	}
+	s.indexEmailLocked(stored.Email, updated)
+	*stored = updated
+	return updated, nil
+}
+
+func (s *UserStore) Delete(ctx context.Context, id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return User{}, ErrUserNotFound
+	}
+	delete(s.users, id)
+	if s.byEmail[emailKey(user.Email)] == id {
+		delete(s.byEmail, emailKey(user.Email))
+	}
+	return *user, nil
+}
+
+func (s *UserStore) ExpirePScheins(ctx context.Context, now time.Time) ([]User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []User
+	for _, user := range s.users {
+		if expirePSchein(user, now) {
+			expired = append(expired, *user)
+		}
+	}
+	return expired, nil
+}
+
+// indexEmailLocked moves the email index entry of user from oldEmail to its
+// current email. Users without an email are not indexed. The caller must
+// hold s.mu.
+func (s *UserStore) indexEmailLocked(oldEmail string, user User) {
+	if oldEmail != "" && s.byEmail[emailKey(oldEmail)] == user.ID {
+		delete(s.byEmail, emailKey(oldEmail))
+	}
+	if user.Email != "" {
+		s.byEmail[emailKey(user.Email)] = user.ID
+	}
+}
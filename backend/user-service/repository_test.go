@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The contract tests run against every UserRepository. The PostgreSQL
+// implementation is tested when USER_TEST_DATABASE_URL points at a scratch
+// database; its users table is emptied before each test.
+
+func TestUserStoreContract(t *testing.T) {
+	testUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		return NewUserStore()
+	})
+}
+
+func TestPostgresUserRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("USER_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("USER_TEST_DATABASE_URL not set")
+	}
+	testUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		repo, err := openPostgresUserRepository(dsn)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		if _, err := repo.db.Exec(`TRUNCATE users`); err != nil {
+			t.Fatalf("failed to empty users: %v", err)
+		}
+		return repo
+	})
+}
+
+// useMemoryUsers points userRepo at a fresh in-memory store holding users for
+// the duration of the test
+func useMemoryUsers(t *testing.T, users ...User) *UserStore {
+	t.Helper()
+	previous := userRepo
+	store := NewUserStore()
+	userRepo = store
+	t.Cleanup(func() { userRepo = previous })
+	for _, user := range users {
+		mustCreateUser(t, store, user)
+	}
+	return store
+}
+
+func testUserRepositoryContract(t *testing.T, newRepo func(t *testing.T) UserRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo UserRepository)
+	}{
+		{"CreateAndGet", testRepoCreateAndGet},
+		{"EmailUnique", testRepoEmailUnique},
+		{"NotFound", testRepoNotFound},
+		{"UpdateStoresChange", testRepoUpdateStoresChange},
+		{"RejectedUpdateLeavesUser", testRepoRejectedUpdateLeavesUser},
+		{"UpdateToTakenEmail", testRepoUpdateToTakenEmail},
+		{"ConcurrentUpdatesSerialised", testRepoConcurrentUpdatesSerialised},
+		{"ListOldestFirst", testRepoListOldestFirst},
+		{"DeleteFreesEmail", testRepoDeleteFreesEmail},
+		{"ExpirePScheins", testRepoExpirePScheins},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+// repoTestTime is a fixed creation time so ordering is predictable
+var repoTestTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newRepoTestUser(email string, createdAt time.Time) User {
+	return User{
+		ID:        uuid.New().String(),
+		Email:     email,
+		Name:      "Anna Schmidt",
+		Phone:     "+4915123456789",
+		UserType:  Rider,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+func mustCreateUser(t *testing.T, repo UserRepository, user User) User {
+	t.Helper()
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return user
+}
+
+func mustGetUser(t *testing.T, repo UserRepository, id string) User {
+	t.Helper()
+	user, err := repo.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get %s failed: %v", id, err)
+	}
+	return user
+}
+
+func testRepoCreateAndGet(t *testing.T, repo UserRepository) {
+	user := mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+
+	got := mustGetUser(t, repo, user.ID)
+	if got.Email != user.Email || got.Name != user.Name || got.UserType != Rider || !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("Get = %+v, want %+v", got, user)
+	}
+}
+
+func testRepoEmailUnique(t *testing.T, repo UserRepository) {
+	mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+
+	err := repo.Create(context.Background(), newRepoTestUser("Anna@Example.DE", repoTestTime))
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Create with a taken email: err = %v, want ErrEmailTaken", err)
+	}
+
+	// Users without an email never conflict with each other
+	mustCreateUser(t, repo, newRepoTestUser("", repoTestTime))
+	mustCreateUser(t, repo, newRepoTestUser("", repoTestTime))
+}
+
+func testRepoNotFound(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Get: err = %v, want ErrUserNotFound", err)
+	}
+	if _, err := repo.Update(ctx, "missing", func(user *User) error { return nil }); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Update: err = %v, want ErrUserNotFound", err)
+	}
+	if _, err := repo.Delete(ctx, "missing"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Delete: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func testRepoUpdateStoresChange(t *testing.T, repo UserRepository) {
+	user := mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+
+	updated, err := repo.Update(context.Background(), user.ID, func(user *User) error {
+		user.Email = "anna.schmidt@example.de"
+		user.Name = "Anna Weber"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Name != "Anna Weber" {
+		t.Errorf("Update returned %+v", updated)
+	}
+
+	if got := mustGetUser(t, repo, user.ID); got.Email != "anna.schmidt@example.de" || got.Name != "Anna Weber" {
+		t.Errorf("stored user = %+v", got)
+	}
+	// The old address is free again
+	mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+}
+
+func testRepoRejectedUpdateLeavesUser(t *testing.T, repo UserRepository) {
+	user := mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+
+	rejected := errors.New("rejected")
+	_, err := repo.Update(context.Background(), user.ID, func(user *User) error {
+		user.Name = "Anna Weber"
+		return rejected
+	})
+	if !errors.Is(err, rejected) {
+		t.Fatalf("Update: err = %v, want the callback's error", err)
+	}
+	if got := mustGetUser(t, repo, user.ID); got.Name != user.Name {
+		t.Errorf("rejected update was stored: %+v", got)
+	}
+}
+
+func testRepoUpdateToTakenEmail(t *testing.T, repo UserRepository) {
+	mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+	ben := mustCreateUser(t, repo, newRepoTestUser("ben@example.de", repoTestTime))
+
+	_, err := repo.Update(context.Background(), ben.ID, func(user *User) error {
+		user.Email = "ANNA@example.de"
+		user.Name = "Ben Müller"
+		return nil
+	})
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("Update: err = %v, want ErrEmailTaken", err)
+	}
+	if got := mustGetUser(t, repo, ben.ID); got.Email != "ben@example.de" || got.Name != ben.Name {
+		t.Errorf("update with a taken email was stored: %+v", got)
+	}
+
+	// Changing only the case of one's own address is not a conflict
+	if _, err := repo.Update(context.Background(), ben.ID, func(user *User) error {
+		user.Email = "Ben@example.de"
+		return nil
+	}); err != nil {
+		t.Errorf("Update of own email case: %v", err)
+	}
+}
+
+func testRepoConcurrentUpdatesSerialised(t *testing.T, repo UserRepository) {
+	user := mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+
+	// Each update appends one character; lost updates would drop some of them
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Update(context.Background(), user.ID, func(user *User) error {
+				user.Phone += "0"
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := mustGetUser(t, repo, user.ID); len(got.Phone) != len(user.Phone)+10 {
+		t.Errorf("phone = %q, want 10 digits appended to %q", got.Phone, user.Phone)
+	}
+}
+
+func testRepoListOldestFirst(t *testing.T, repo UserRepository) {
+	third := mustCreateUser(t, repo, newRepoTestUser("carla@example.de", repoTestTime.Add(2*time.Hour)))
+	first := mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+	second := mustCreateUser(t, repo, newRepoTestUser("ben@example.de", repoTestTime.Add(time.Hour)))
+
+	users, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	want := []string{first.ID, second.ID, third.ID}
+	if len(users) != len(want) {
+		t.Fatalf("List returned %d users, want %d", len(users), len(want))
+	}
+	for i, id := range want {
+		if users[i].ID != id {
+			t.Errorf("users[%d] = %s, want %s", i, users[i].ID, id)
+		}
+	}
+}
+
+func testRepoDeleteFreesEmail(t *testing.T, repo UserRepository) {
+	user := mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+
+	deleted, err := repo.Delete(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if deleted.ID != user.ID || deleted.Email != user.Email {
+		t.Errorf("Delete returned %+v", deleted)
+	}
+	if _, err := repo.Get(context.Background(), user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrUserNotFound", err)
+	}
+	mustCreateUser(t, repo, newRepoTestUser("anna@example.de", repoTestTime))
+}
+
+func testRepoExpirePScheins(t *testing.T, repo UserRepository) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+	since := now.Add(-time.Hour)
+
+	driver := func(status PScheinStatus, expiresAt *time.Time) User {
+		user := newRepoTestUser("", repoTestTime)
+		user.UserType = Driver
+		user.PScheinStatus = status
+		user.PScheinExpiresAt = expiresAt
+		user.Available = status == PScheinVerified
+		user.AvailabilitySince = &since
+		return mustCreateUser(t, repo, user)
+	}
+	expired := driver(PScheinVerified, &past)
+	valid := driver(PScheinVerified, &future)
+	pending := driver(PScheinPending, &past)
+	noDates := driver(PScheinVerified, nil)
+
+	changed, err := repo.ExpirePScheins(context.Background(), now)
+	if err != nil {
+		t.Fatalf("ExpirePScheins failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0].ID != expired.ID {
+		t.Fatalf("ExpirePScheins returned %+v, want only %s", changed, expired.ID)
+	}
+
+	want := map[string]PScheinStatus{
+		expired.ID: PScheinExpired,
+		valid.ID:   PScheinVerified,
+		pending.ID: PScheinPending,
+		noDates.ID: PScheinVerified,
+	}
+	for id, status := range want {
+		if got := mustGetUser(t, repo, id).PScheinStatus; got != status {
+			t.Errorf("%s: status = %s, want %s", id, got, status)
+		}
+	}
+	got := mustGetUser(t, repo, expired.ID)
+	if got.Available || !got.UpdatedAt.Equal(now) {
+		t.Errorf("expired driver: available = %t, UpdatedAt = %s, want offline at %s", got.Available, got.UpdatedAt, now)
+	}
+
+	if again, err := repo.ExpirePScheins(context.Background(), now); err != nil || len(again) != 0 {
+		t.Errorf("second scan returned %d users, err = %v, want none", len(again), err)
+	}
+}
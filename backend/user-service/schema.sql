@@ -0,0 +1,22 @@
+-- User storage for PostgresUserRepository. The statements are idempotent and
+-- applied on start-up.
+--
+-- data holds the full user as JSON and is the source of truth; the other
+-- columns copy the fields users are looked up and filtered by, and are
+-- written in the same statement as data.
+
+CREATE TABLE IF NOT EXISTS users (
+    id         TEXT PRIMARY KEY,
+    -- emailKey(email); emails are unique regardless of case
+    email_key  TEXT UNIQUE,
+    user_type  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    data       JSONB NOT NULL
+);
+
+-- Admin listing: GET /users
+CREATE INDEX IF NOT EXISTS users_created_idx ON users (created_at, id);
+
+-- P-Schein expiry job
+CREATE INDEX IF NOT EXISTS users_verified_drivers_idx ON users (id)
+    WHERE user_type = 'DRIVER' AND data->>'p_schein_status' = 'VERIFIED';
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/khiamazizi2802-design/ride-share-platform-germany/backend/pkg/timeutil"
+)
+
+// Vehicle types, matching the matching-service's vehicle_type values
+const (
+	VehicleStandard   = "standard"
+	VehicleXL         = "xl"
+	VehicleWheelchair = "wheelchair"
+)
+
+// maxVehicleSeats is the passenger limit of a car licensed for passenger
+// transport: at most nine seats including the driver
+const maxVehicleSeats = 8
+
+// Vehicle is the car a driver is registered with
+type Vehicle struct {
+	Make  string `json:"make"`
+	Model string `json:"model"`
+	Plate string `json:"plate"`
+	// Seats is the number of passenger seats, excluding the driver
+	Seats                int    `json:"seats"`
+	Type                 string `json:"type"`
+	WheelchairAccessible bool   `json:"wheelchair_accessible"`
+}
+
+// germanPlatePattern matches a German licence plate: a district code of one
+// to three letters, one or two recognition letters, a number of one to four
+// digits without leading zero and an optional E (electric) or H (historic)
+// suffix. The district code must be separated by a dash or space; the
+// separator before the number is optional, e.g. "B-AB 1234", "M XY123E".
+var germanPlatePattern = regexp.MustCompile(`^([A-ZÄÖÜ]{1,3})[- ]([A-Z]{1,2})[- ]?([1-9][0-9]{0,3})([EH]?)$`)
+
+// maxPlateCharacters is the most letters and digits a plate may carry,
+// excluding the E/H suffix
+const maxPlateCharacters = 8
+
+var errInvalidPlate = errors.New("plate must be a German licence plate such as B-AB 1234")
+
+// normalizePlate validates a German licence plate and returns it in the
+// canonical "B-AB 1234" form
+func normalizePlate(raw string) (string, error) {
+	m := germanPlatePattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(raw)))
+	if m == nil {
+		return "", errInvalidPlate
+	}
+	district, letters, number, suffix := m[1], m[2], m[3], m[4]
+	if len([]rune(district))+len(letters)+len(number) > maxPlateCharacters {
+		return "", errInvalidPlate
+	}
+	return district + "-" + letters + " " + number + suffix, nil
+}
+
+// validateVehicle checks v and normalizes its plate and type in place. The
+// type defaults to standard.
+func validateVehicle(v *Vehicle) error {
+	v.Make = strings.TrimSpace(v.Make)
+	v.Model = strings.TrimSpace(v.Model)
+	if v.Make == "" || v.Model == "" {
+		return errors.New("vehicle make and model are required")
+	}
+
+	plate, err := normalizePlate(v.Plate)
+	if err != nil {
+		return err
+	}
+	v.Plate = plate
+
+	if v.Seats < 1 || v.Seats > maxVehicleSeats {
+		return fmt.Errorf("vehicle seats must be between 1 and %d", maxVehicleSeats)
+	}
+
+	if v.Type == "" {
+		v.Type = VehicleStandard
+	}
+	switch v.Type {
+	case VehicleStandard, VehicleXL:
+	case VehicleWheelchair:
+		if !v.WheelchairAccessible {
+			return errors.New("wheelchair vehicles must be wheelchair_accessible")
+		}
+	default:
+		return fmt.Errorf("vehicle type must be one of %s, %s, %s", VehicleStandard, VehicleXL, VehicleWheelchair)
+	}
+	return nil
+}
+
+// updateVehicleHandler serves PUT /users/{id}/vehicle, replacing the
+// driver's vehicle
+func updateVehicleHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var vehicle Vehicle
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := validateVehicle(&vehicle); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := userRepo.Update(r.Context(), id, func(user *User) error {
+		if user.UserType != Driver {
+			return rejectUpdate(http.StatusBadRequest, "User is not a driver")
+		}
+
+		user.Vehicle = &vehicle
+		user.UpdatedAt = timeutil.Now()
+		return nil
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	requestLogger(r.Context()).Info("Vehicle updated", "driver_id", id, "make", vehicle.Make, "model", vehicle.Model, "vehicle_type", vehicle.Type)
+	recordPIIAccess(r, PIIAccessUpdate, &snapshot)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
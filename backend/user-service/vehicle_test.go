@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestNormalizePlate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"B-AB 1234", "B-AB 1234"},
+		{"b ab 1234", "B-AB 1234"},
+		{"M-X1", "M-X 1"},
+		{"HH-AB 123E", "HH-AB 123E"},
+		{"MÜ-K 99H", "MÜ-K 99H"},
+		{"BIN-AB 12", "BIN-AB 12"},
+	}
+	for _, tt := range tests {
+		got, err := normalizePlate(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("normalizePlate(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{
+		"",
+		"BAB1234",     // district code not separated
+		"B-AB 0123",   // leading zero
+		"B-ABC 123",   // three recognition letters
+		"ABCD-A 1",    // four-letter district code
+		"BIN-AB 1234", // nine characters
+		"B-AB 12345",
+		"B-AB 12X",
+	} {
+		if got, err := normalizePlate(input); err == nil {
+			t.Errorf("normalizePlate(%q) = %q, want an error", input, got)
+		}
+	}
+}
+
+func TestValidateVehicle(t *testing.T) {
+	v := Vehicle{Make: "VW", Model: "Caddy", Plate: "b-ab 1234", Seats: 4}
+	if err := validateVehicle(&v); err != nil {
+		t.Fatalf("valid vehicle rejected: %v", err)
+	}
+	if v.Plate != "B-AB 1234" || v.Type != VehicleStandard {
+		t.Errorf("plate/type not normalized: %+v", v)
+	}
+
+	for name, v := range map[string]Vehicle{
+		"missing model":           {Make: "VW", Plate: "B-AB 1234", Seats: 4},
+		"no seats":                {Make: "VW", Model: "Caddy", Plate: "B-AB 1234"},
+		"too many seats":          {Make: "VW", Model: "Crafter", Plate: "B-AB 1234", Seats: 12},
+		"unknown type":            {Make: "VW", Model: "Caddy", Plate: "B-AB 1234", Seats: 4, Type: "limo"},
+		"wheelchair inaccessible": {Make: "VW", Model: "Caddy", Plate: "B-AB 1234", Seats: 4, Type: VehicleWheelchair},
+	} {
+		if err := validateVehicle(&v); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestUpdateVehicle(t *testing.T) {
+	useMemoryUsers(t,
+		User{ID: "vehicle-driver", UserType: Driver},
+		User{ID: "vehicle-rider", UserType: Rider},
+	)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/vehicle", updateVehicleHandler).Methods("PUT")
+	put := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/users/"+id+"/vehicle", strings.NewReader(body)))
+		return rec
+	}
+
+	body := `{"make": "Toyota", "model": "Proace", "plate": "B-RS 2024E", "seats": 6, "type": "wheelchair", "wheelchair_accessible": true}`
+	rec := put("vehicle-driver", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"plate":"B-RS 2024E"`) {
+		t.Errorf("vehicle missing from user JSON: %s", rec.Body.String())
+	}
+
+	if rec := put("vehicle-rider", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("rider: status = %d, want 400", rec.Code)
+	}
+	if rec := put("vehicle-driver", `{"make": "VW", "model": "Caddy", "plate": "not a plate", "seats": 4}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid plate: status = %d, want 400", rec.Code)
+	}
+	if rec := put("missing", body); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rec.Code)
+	}
+}